@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"parascan/pkg/parascan"
+)
+
+// promptAccounts asks for the url_template placeholders of detected services the
+// project hasn't answered yet, e.g. the Sentry organization, and turns their results
+// into deep links. Answers are stored in the accounts of .parascan.yml so they are
+// asked once per project. An empty answer skips the service until the next run.
+func promptAccounts(projectPath string, catalog *parascan.Catalog, settings *parascan.ProjectSettings, results map[string]string, in io.Reader, out io.Writer) error {
+	keys := make([]string, 0, len(results))
+	for key := range results {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	reader := bufio.NewReader(in)
+	answered := false
+	for _, key := range keys {
+		service, ok := catalog.Services[key]
+		if !ok || service.URLTemplate == "" {
+			continue
+		}
+		if _, ok := service.AccountURL(settings.Accounts[key]); ok {
+			continue
+		}
+
+		answers := make(map[string]string)
+		for prompt, answer := range settings.Accounts[key] {
+			answers[prompt] = answer
+		}
+		for _, prompt := range service.AccountPrompts() {
+			if strings.TrimSpace(answers[prompt]) != "" {
+				continue
+			}
+			question := service.Prompts[prompt]
+			if question == "" {
+				question = prompt
+			}
+			fmt.Fprintf(out, "🔑 %s for a link to your %s account (empty to skip): ", question, service.Name)
+			line, err := reader.ReadString('\n')
+			answers[prompt] = strings.TrimSpace(line)
+			if answers[prompt] == "" || err != nil {
+				break
+			}
+		}
+
+		url, ok := service.AccountURL(answers)
+		if !ok {
+			continue
+		}
+		if settings.Accounts == nil {
+			settings.Accounts = make(map[string]map[string]string)
+		}
+		settings.Accounts[key] = answers
+		results[key] = url
+		answered = true
+	}
+
+	if !answered {
+		return nil
+	}
+	return updateProjectSettings(projectPath, "accounts", settings.Accounts)
+}
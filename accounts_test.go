@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"parascan/pkg/parascan"
+)
+
+func TestAccountPrompts(t *testing.T) {
+	skipWithoutPack(t, "requirements.txt")
+	projectPath := t.TempDir()
+	if err := os.WriteFile(filepath.Join(projectPath, "requirements.txt"), []byte("sentry-sdk==1.40.0\nstripe==7.0.0\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	catalog, err := parascan.EmbeddedCatalog()
+	if err != nil {
+		t.Fatalf("Failed to load catalog: %v", err)
+	}
+
+	// Answers are added to hand-written settings without losing their comments
+	settingsYAML := "# Reviewed by the platform team\n\nmin_evidence: 1 # packages are enough here\n"
+	if err := os.WriteFile(filepath.Join(projectPath, parascan.SettingsFile), []byte(settingsYAML), 0644); err != nil {
+		t.Fatal(err)
+	}
+	settings := &parascan.ProjectSettings{}
+	scan := runScan(projectPath, catalog, ScanOptions{SkipGit: true, Settings: settings})
+	var out bytes.Buffer
+	if err := promptAccounts(projectPath, catalog, settings, scan.Results, strings.NewReader("acme\n"), &out); err != nil {
+		t.Fatalf("Prompting failed: %v", err)
+	}
+	if !strings.Contains(out.String(), "Sentry organization slug") || strings.Contains(out.String(), "Stripe") {
+		t.Errorf("Expected only Sentry's prompt, got %q", out.String())
+	}
+	if scan.Results["sentry"] != "https://acme.sentry.io/issues/" {
+		t.Errorf("Expected a deep link into the acme organization, got %s", scan.Results["sentry"])
+	}
+
+	// The answer is kept in the project settings, later scans link without asking
+	settings, err = parascan.LoadProjectSettings(projectPath)
+	if err != nil || settings.Accounts["sentry"]["org"] != "acme" {
+		t.Fatalf("Expected the answer in %s, got %+v (%v)", parascan.SettingsFile, settings.Accounts, err)
+	}
+	if content, _ := os.ReadFile(filepath.Join(projectPath, parascan.SettingsFile)); !strings.HasPrefix(string(content), settingsYAML) {
+		t.Errorf("Expected the comments of %s kept, got:\n%s", parascan.SettingsFile, content)
+	}
+	scan = runScan(projectPath, catalog, ScanOptions{SkipGit: true, Settings: settings})
+	if scan.Results["sentry"] != "https://acme.sentry.io/issues/" {
+		t.Errorf("Expected the deep link from the settings, got %s", scan.Results["sentry"])
+	}
+	out.Reset()
+	if err := promptAccounts(projectPath, catalog, settings, scan.Results, strings.NewReader(""), &out); err != nil || out.Len() > 0 {
+		t.Errorf("Expected no more prompts, got %q (%v)", out.String(), err)
+	}
+
+	// The deep link replaces the homepage in an existing config
+	configPath := filepath.Join(projectPath, "parascope.yml")
+	if err := os.WriteFile(configPath, []byte("billing:\n  Sentry: https://sentry.com\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	createConfigFromDetectorResults(configPath, map[string]string{"sentry": scan.Results["sentry"]}, "billing", "", nil)
+	config, _ := os.ReadFile(configPath)
+	if string(config) != "billing:\n  Sentry: https://acme.sentry.io/issues\n" {
+		t.Errorf("Expected Sentry's URL updated in place, got:\n%s", config)
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"parascan/pkg/parascan"
+)
+
+// Annotation marks what a scan changes in an existing parascope.yml, for reviewers
+// of the config change: each entry it adds gets a comment saying where it came from,
+// and a changelog line after the section's root key lists the changes of each scan:
+//
+//	billing:
+//	  # changelog: 2026-10-15 v0.8.0 added Sentry, Stripe; updated Datadog
+//	  Stripe: https://dashboard.stripe.com # added by parascan v0.8.0 on 2026-10-15 from package.json
+type Annotation struct {
+	Tool    string
+	Date    time.Time
+	Sources map[string]string // file or detector behind each result, by result key
+}
+
+// maxChangelogLines is how many scans the changelog of a section keeps, the oldest
+// are dropped first
+const maxChangelogLines = 10
+
+var changelogComment = regexp.MustCompile(`^\s*# changelog: `)
+
+// withSources returns the annotation for results with the given evidence
+func (a *Annotation) withSources(evidence map[string][]parascan.ServiceEvidence) *Annotation {
+	if a == nil {
+		return nil
+	}
+	annotated := *a
+	annotated.Sources = make(map[string]string)
+	for key, items := range evidence {
+		if source := evidenceSource(items); source != "" {
+			annotated.Sources[key] = source
+		}
+	}
+	return &annotated
+}
+
+// evidenceSource names where a result was found: the first file of its evidence,
+// or the detector of results without one, such as the repository URL
+func evidenceSource(evidence []parascan.ServiceEvidence) string {
+	for _, e := range evidence {
+		if e.File != "" {
+			return e.File
+		}
+	}
+	if len(evidence) > 0 {
+		return evidence[0].Detector
+	}
+	return ""
+}
+
+func (a *Annotation) day() string {
+	return a.Date.Format("2006-01-02")
+}
+
+// entryComment is the comment ending an entry line the scan added for a result key
+func (a *Annotation) entryComment(key string) string {
+	comment := fmt.Sprintf(" # added by parascan %s on %s", a.Tool, a.day())
+	if source := a.Sources[key]; source != "" {
+		comment += " from " + source
+	}
+	return comment
+}
+
+// logChanges records the scan's changes in the changelog after the section's root
+// key, keeping the last maxChangelogLines scans
+func (a *Annotation) logChanges(section string, changes []string) string {
+	if len(changes) == 0 {
+		return section
+	}
+	lines := strings.Split(section, "\n")
+	var changelog, rest []string
+	for _, line := range lines[1:] {
+		if changelogComment.MatchString(line) {
+			changelog = append(changelog, line)
+		} else {
+			rest = append(rest, line)
+		}
+	}
+	changelog = append(changelog, fmt.Sprintf("  # changelog: %s %s %s", a.day(), a.Tool, strings.Join(changes, "; ")))
+	if len(changelog) > maxChangelogLines {
+		changelog = changelog[len(changelog)-maxChangelogLines:]
+	}
+	return strings.Join(append(append([]string{lines[0]}, changelog...), rest...), "\n")
+}
+
+// configChanges words the changes of a scan to a section for the changelog
+func configChanges(added, updated []string, merged bool, duplicates []string) []string {
+	var changes []string
+	if len(added) > 0 {
+		sorted := append([]string(nil), added...)
+		sort.Strings(sorted)
+		changes = append(changes, "added "+strings.Join(sorted, ", "))
+	}
+	if len(updated) > 0 {
+		changes = append(changes, "updated "+strings.Join(updated, ", "))
+	}
+	if merged {
+		changes = append(changes, "merged repository keys")
+	}
+	if len(duplicates) > 0 {
+		changes = append(changes, "removed duplicate "+strings.Join(duplicates, ", "))
+	}
+	return changes
+}
+
+// splitLineComment splits the comment off the value of an entry line, e.g.
+// "https://sentry.io # added by ..." into the URL and " # added by ...". A # is
+// only a comment after a space and outside quotes, as in YAML.
+func splitLineComment(value string) (string, string) {
+	start := 0
+	if trimmed := strings.TrimLeft(value, " "); trimmed != "" && (trimmed[0] == '"' || trimmed[0] == '\'') {
+		offset := len(value) - len(trimmed)
+		if end := strings.IndexByte(trimmed[1:], trimmed[0]); end >= 0 {
+			start = offset + end + 2
+		}
+	}
+	if i := strings.Index(value[start:], " #"); i >= 0 {
+		i += start
+		cut := strings.TrimRight(value[:i], " ")
+		return cut, value[len(cut):]
+	}
+	return value, ""
+}
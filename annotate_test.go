@@ -0,0 +1,64 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAnnotatedConfig(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "parascope.yml")
+	if err := os.WriteFile(configPath, []byte("billing:\n  Repository: https://github.com/acme/billing # ours\n  Datadog: https://app.datadoghq.com\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	annotation := &Annotation{Tool: "v0.8.0", Date: time.Date(2026, 10, 15, 9, 30, 0, 0, time.UTC), Sources: map[string]string{"stripe": "package.json"}}
+
+	// Added entries say where they came from, the changelog follows the root key
+	results := map[string]string{"repo": "https://github.com/acme/billing", "stripe": "https://dashboard.stripe.com", "Datadog": "https://app.datadoghq.eu"}
+	createConfigFromDetectorResults(configPath, results, "billing", "", annotation)
+	content, _ := os.ReadFile(configPath)
+	expected := "billing:\n" +
+		"  # changelog: 2026-10-15 v0.8.0 added Stripe; updated Datadog\n" +
+		"  Repository: https://github.com/acme/billing # ours\n" +
+		"  Datadog: https://app.datadoghq.eu\n" +
+		"  Stripe: https://dashboard.stripe.com # added by parascan v0.8.0 on 2026-10-15 from package.json\n"
+	if string(content) != expected {
+		t.Errorf("Expected annotated config:\n%s\ngot:\n%s", expected, content)
+	}
+
+	// Comments don't count as changed values, a rescan leaves the config alone
+	createConfigFromDetectorResults(configPath, results, "billing", "", annotation)
+	if rescanned, _ := os.ReadFile(configPath); string(rescanned) != expected {
+		t.Errorf("Expected the rescan to keep the config, got:\n%s", rescanned)
+	}
+
+	// The changelog keeps the last scans
+	for i := 0; i < maxChangelogLines+2; i++ {
+		annotation.Date = annotation.Date.AddDate(0, 0, 1)
+		createConfigFromDetectorResults(configPath, map[string]string{"sentry": "https://sentry.io/" + strings.Repeat("x", i)}, "billing", "", annotation)
+	}
+	content, _ = os.ReadFile(configPath)
+	if count := strings.Count(string(content), "# changelog:"); count != maxChangelogLines {
+		t.Errorf("Expected %d changelog lines, got %d:\n%s", maxChangelogLines, count, content)
+	}
+	if strings.Contains(string(content), "2026-10-15 v0.8.0 added Stripe") {
+		t.Errorf("Expected the oldest changelog line to be dropped, got:\n%s", content)
+	}
+	if entries := sectionEntries(string(content)); entries["Stripe"] != "https://dashboard.stripe.com" {
+		t.Errorf("Expected comments to stay out of the entries, got %v", entries)
+	}
+}
+
+func TestSplitLineComment(t *testing.T) {
+	for value, expected := range map[string][2]string{
+		" https://sentry.io # added":      {" https://sentry.io", " # added"},
+		" https://app.storyblok.com/#/me": {" https://app.storyblok.com/#/me", ""},
+		` "a # b" # c`:                    {` "a # b"`, " # c"},
+	} {
+		if got, comment := splitLineComment(value); got != expected[0] || comment != expected[1] {
+			t.Errorf("Expected %q to split into %q, got %q and %q", value, expected, got, comment)
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"parascan/detectors"
+	"parascan/pkg/parascan"
+)
+
+// findApps lists the directories matching the app patterns (e.g. apps/*), relative
+// to the project root and sorted. Each app keeps its own parascope.yml.
+func findApps(projectPath string, patterns []string, filter *detectors.PathFilter) []string {
+	var apps []string
+	seen := make(map[string]bool)
+	for _, pattern := range patterns {
+		for _, match := range filter.Glob(projectPath, strings.TrimSuffix(pattern, "/")) {
+			if info, err := os.Stat(match); err != nil || !info.IsDir() {
+				continue
+			}
+			rel, err := filepath.Rel(projectPath, match)
+			if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+				continue
+			}
+			if rel = filepath.ToSlash(rel); !seen[rel] {
+				seen[rel] = true
+				apps = append(apps, rel)
+			}
+		}
+	}
+	sort.Strings(apps)
+	return apps
+}
+
+// appConfigPath is the parascope.yml of an app
+func appConfigPath(projectPath, app string) string {
+	return filepath.Join(projectPath, filepath.FromSlash(app), "parascope.yml")
+}
+
+// scanApps runs detection in each app for its own parascope.yml. Apps are named
+// after their directory like any project, and list the repository of the root
+// scan. Scanning stops at the first app going over --max-memory.
+func scanApps(projectPath string, catalog *parascan.Catalog, settings *parascan.ProjectSettings, apps []string, repoURL string, opts ScanOptions) ([]*ScanReport, error) {
+	var reports []*ScanReport
+	for _, app := range apps {
+		appSettings := subprojectSettings(settings, app, apps)
+		appPath := filepath.Join(projectPath, filepath.FromSlash(app))
+
+		appOpts := opts
+		appOpts.SkipGit, appOpts.RepoURL = true, repoURL
+		appOpts.Settings = appSettings
+		scan := runScan(appPath, catalog, appOpts)
+		if err := memoryLimitError(scan); err != nil {
+			return reports, fmt.Errorf("%s: %v", app, err)
+		}
+
+		report := subprojectReport(appPath, appConfigPath(projectPath, app), "", scan, appSettings, catalog)
+		report.AppDir = app
+		reports = append(reports, report)
+	}
+	return reports, nil
+}
+
+// AppConfig is a project section of an app's parascope.yml as para apps shows it
+type AppConfig struct {
+	App     string            `json:"app"`               // directory, relative to the repository root
+	Config  string            `json:"config"`            // path of the app's parascope.yml
+	Project string            `json:"project,omitempty"` // root key of the section
+	Entries map[string]string `json:"entries,omitempty"` // URLs by name
+	Error   string            `json:"error,omitempty"`   // why the config couldn't be read
+}
+
+// readAppConfigs reads the project sections of each app's parascope.yml. An app
+// without a readable config has a single entry with the error.
+func readAppConfigs(projectPath string, apps []string) []AppConfig {
+	var configs []AppConfig
+	for _, app := range apps {
+		configPath := appConfigPath(projectPath, app)
+		content, err := os.ReadFile(configPath)
+		if err != nil {
+			message := err.Error()
+			if os.IsNotExist(err) {
+				message = "no parascope.yml, run para scan --per-app"
+			}
+			configs = append(configs, AppConfig{App: app, Config: configPath, Error: message})
+			continue
+		}
+		content = readRecoveredConfig(configPath, content)
+
+		found := false
+		for _, section := range splitConfigSections(string(content)) {
+			if project := sectionRootKey(section); project != "" {
+				section, _ = sectionProvenance(section)
+				configs = append(configs, AppConfig{App: app, Config: configPath, Project: project, Entries: sectionEntries(section)})
+				found = true
+			}
+		}
+		if !found {
+			configs = append(configs, AppConfig{App: app, Config: configPath, Error: "no project sections"})
+		}
+	}
+	return configs
+}
+
+// sharedEntries lists the entries found in more than one app, with the apps
+// listing them, by name
+func sharedEntries(configs []AppConfig, repositoryName string) map[string][]string {
+	apps := make(map[string][]string)
+	for _, config := range configs {
+		for name := range config.Entries {
+			// Every app lists the repository
+			if isRepositoryKey(name, repositoryName) {
+				continue
+			}
+			if list := apps[name]; len(list) == 0 || list[len(list)-1] != config.App {
+				apps[name] = append(list, config.App)
+			}
+		}
+	}
+	for name, list := range apps {
+		if len(list) < 2 {
+			delete(apps, name)
+		}
+	}
+	return apps
+}
+
+func writeAppsTable(w io.Writer, configs []AppConfig, shared map[string][]string) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "APP\tPROJECT\tNAME\tURL")
+	for _, config := range configs {
+		if config.Error != "" {
+			fmt.Fprintf(tw, "%s\t\t(%s)\t\n", config.App, config.Error)
+			continue
+		}
+		app, project := config.App, config.Project
+		for _, name := range sortedKeys(config.Entries) {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", app, project, name, config.Entries[name])
+			app, project = "", ""
+		}
+		if len(config.Entries) == 0 {
+			fmt.Fprintf(tw, "%s\t%s\t\t\n", app, project)
+		}
+	}
+	tw.Flush()
+
+	if len(shared) > 0 {
+		fmt.Fprintln(w, "\nUsed by several apps:")
+		for _, name := range sortedListKeys(shared) {
+			fmt.Fprintf(w, "  %s: %s\n", name, strings.Join(shared[name], ", "))
+		}
+	}
+}
+
+// handleApps shows the parascope.yml of every app of the repository side by side
+func handleApps() {
+	format := "table"
+	var patterns stringsFlag
+	flags := newCommandFlags("apps", showAppsHelp)
+	flags.StringVar(&format, "format", format, "")
+	flags.StringVar(&format, "f", format, "")
+	flags.Var(&patterns, "per-app", "")
+	args := flags.parse(os.Args[2:])
+	flags.maxArgs(args, 1)
+	if format != "table" && format != "json" {
+		flags.fail("Unknown format: %s. Supported formats: table, json", format)
+	}
+	projectPath := "."
+	if len(args) == 1 {
+		projectPath = args[0]
+	}
+
+	settings, err := parascan.LoadProjectSettings(projectPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		os.Exit(1)
+	}
+	if len(patterns) == 0 {
+		patterns = settings.Apps
+	}
+	if len(patterns) == 0 {
+		flags.fail("No apps: set apps in %s or give --per-app 'apps/*'", parascan.SettingsFile)
+	}
+	apps := findApps(projectPath, patterns, settings.PathFilter())
+	if len(apps) == 0 {
+		fmt.Fprintf(os.Stderr, "❌ No directory matches %s\n", strings.Join(patterns, ", "))
+		os.Exit(1)
+	}
+
+	configs := readAppConfigs(projectPath, apps)
+	shared := sharedEntries(configs, settings.RepositoryName())
+	if format == "table" {
+		writeAppsTable(os.Stdout, configs, shared)
+		return
+	}
+	data, _ := json.MarshalIndent(map[string]interface{}{"apps": configs, "shared": shared}, "", "  ")
+	fmt.Println(string(data))
+}
+
+func showAppsHelp() {
+	fmt.Println(`Usage: para apps [path] [--per-app <pattern>] [--format table|json]
+
+Show the parascope.yml of every app of a repository side by side, for repos
+keeping one config per app. Apps are the directories matching the apps patterns
+of .parascan.yml (e.g. apps: ["apps/*"]) or --per-app, as for para scan --per-app.
+Entries listed by several apps are summed up after the table.
+
+Options:
+  --per-app        App directories as a glob pattern, repeatable (default: apps of .parascan.yml)
+  --format, -f     table (default) or json
+
+Examples:
+  para apps
+  para apps --per-app 'apps/*' --per-app 'services/*'
+  para apps -f json | jq '.shared'`)
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"parascan/pkg/parascan"
+)
+
+func TestPerAppConfigs(t *testing.T) {
+	skipWithoutPack(t, "package.json", "go.mod")
+	projectPath := filepath.Join("testdata", "apps-project")
+	catalog, err := parascan.EmbeddedCatalog()
+	if err != nil {
+		t.Fatalf("Failed to load catalog: %v", err)
+	}
+	settings, err := parascan.LoadProjectSettings(projectPath)
+	if err != nil {
+		t.Fatalf("Failed to load settings: %v", err)
+	}
+
+	apps := findApps(projectPath, settings.Apps, settings.PathFilter())
+	if !equalStringSlices(apps, []string{"apps/api", "apps/docs", "apps/web"}) {
+		t.Fatalf("Expected a directory per app, got %v", apps)
+	}
+	if excluded := findApps(projectPath, []string{"apps/*"}, (&parascan.ProjectSettings{Ignore: []string{"docs"}}).PathFilter()); !equalStringSlices(excluded, []string{"apps/api", "apps/web"}) {
+		t.Errorf("Expected ignored directories to be no apps, got %v", excluded)
+	}
+
+	// The root scan leaves apps to their own configs, which list the root's repository
+	rootSettings := subprojectSettings(settings, ".", apps)
+	scan := runScan(projectPath, catalog, ScanOptions{SkipGit: true, Settings: rootSettings})
+	if _, found := scan.Results["stripe"]; found {
+		t.Errorf("Expected stripe only in apps, got root results %v", scan.Results)
+	}
+	reports, err := scanApps(projectPath, catalog, settings, apps, "https://github.com/acme/platform", ScanOptions{})
+	if err != nil {
+		t.Fatalf("Failed to scan apps: %v", err)
+	}
+	if len(reports) != len(apps) {
+		t.Fatalf("Expected a report per app, got %d", len(reports))
+	}
+
+	// Each app is written to its own parascope.yml, named after its directory
+	dir := t.TempDir()
+	for _, report := range reports {
+		if report.ConfigPath != appConfigPath(projectPath, report.AppDir) {
+			t.Errorf("Expected %s to be written to its own config, got %s", report.AppDir, report.ConfigPath)
+		}
+		report.ConfigPath = appConfigPath(dir, report.AppDir)
+		if err := os.MkdirAll(filepath.Dir(report.ConfigPath), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	report := &ScanReport{
+		ProjectPath: projectPath,
+		ConfigPath:  filepath.Join(dir, "parascope.yml"),
+		ProjectName: "platform",
+		Results:     scan.Results,
+		Stack:       catalog.Stack,
+		Apps:        reports,
+	}
+	if err := writeOutput(OutputTarget{Format: "yml-config", Path: "-"}, report); err != nil {
+		t.Fatalf("Failed to write configs: %v", err)
+	}
+	web, _ := os.ReadFile(appConfigPath(dir, "apps/web"))
+	if !strings.HasPrefix(string(web), "web:\n  Repository: https://github.com/acme/platform\n") || !strings.Contains(string(web), "Stripe: https://dashboard.stripe.com") {
+		t.Errorf("Expected the web section with the repository and Stripe, got %s", web)
+	}
+	if response := report.response(); len(response.Apps) != 3 || response.Apps["apps/api"].Services["stripe"] == "" {
+		t.Errorf("Expected the apps by directory in JSON, got %v", response.Apps)
+	}
+
+	// para apps reads them side by side
+	os.Remove(appConfigPath(dir, "apps/docs"))
+	configs := readAppConfigs(dir, apps)
+	if len(configs) != 3 || configs[0].Project != "api" || configs[1].Error == "" || configs[2].Entries["Stripe"] != "https://dashboard.stripe.com" {
+		t.Errorf("Expected api and web configs and an error for docs, got %+v", configs)
+	}
+	shared := sharedEntries(configs, parascan.DefaultRepositoryName)
+	if expected := map[string][]string{"Sentry": {"apps/api", "apps/web"}, "Stripe": {"apps/api", "apps/web"}}; !reflect.DeepEqual(shared, expected) {
+		t.Errorf("Expected Sentry and Stripe shared by api and web, got %v", shared)
+	}
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"parascan/detectors"
+)
+
+// bareMaxFileSize is the largest file exported from a bare repository. Manifests
+// and config are small, larger files are assets or generated, as in deep mode.
+const bareMaxFileSize = 1 << 20
+
+// scpLikeURL is a remote such as git@github.com:acme/shop.git
+var scpLikeURL = regexp.MustCompile(`^[\w.-]+@[\w.-]+:`)
+
+// BareTree is the default branch of a bare repository, exported to a temporary
+// directory so the detectors can read it like a worktree
+type BareTree struct {
+	Dir     string // the exported files
+	Name    string // repository name, e.g. shop for /srv/git/shop.git
+	Branch  string // default branch, empty for a detached HEAD
+	Commit  string
+	RepoURL string // web address of the remote, empty for repositories without one
+	tmp     string
+}
+
+// Remove deletes the exported files, nil trees have none
+func (t *BareTree) Remove() {
+	if t != nil {
+		os.RemoveAll(t.tmp)
+	}
+}
+
+// Revision is the exported branch and abbreviated commit, e.g. main at 3f1c2a9e0b7d
+func (t *BareTree) Revision() string {
+	commit := t.Commit
+	if len(commit) > 12 {
+		commit = commit[:12]
+	}
+	if t.Branch == "" {
+		return commit
+	}
+	return t.Branch + " at " + commit
+}
+
+// isRemoteRepository reports whether source is a URL rather than a local path
+func isRemoteRepository(source string) bool {
+	return strings.Contains(source, "://") || scpLikeURL.MatchString(source)
+}
+
+// exportBareRepository writes the tree of the default branch of a bare repository,
+// local or remote, to a temporary directory with git plumbing. Remote repositories
+// are cloned bare with only their last commit first. Symlinks, submodules and files
+// over bareMaxFileSize are left out. The repository URL of a local repository is
+// read from remote, origin when empty.
+func exportBareRepository(source, remote string) (*BareTree, error) {
+	tmp, err := os.MkdirTemp("", "para-bare-*")
+	if err != nil {
+		return nil, err
+	}
+	tree := &BareTree{Dir: filepath.Join(tmp, "tree"), tmp: tmp}
+	tree.Name = strings.TrimSuffix(filepath.Base(strings.TrimRight(source, "/")), ".git")
+	if err := tree.export(source, remote); err != nil {
+		tree.Remove()
+		return nil, err
+	}
+	return tree, nil
+}
+
+func (t *BareTree) export(source, remote string) error {
+	gitDir := source
+	if isRemoteRepository(source) {
+		if !networkEnabled {
+			return errNetworkDisabled
+		}
+		gitDir = filepath.Join(t.tmp, "repo.git")
+		if _, err := gitOutput("", "clone", "--quiet", "--bare", "--depth", "1", "--no-tags", source, gitDir); err != nil {
+			return fmt.Errorf("could not clone %s: %v", source, err)
+		}
+		t.RepoURL = detectors.RepositoryURL(source)
+	} else {
+		bare, err := gitOutput(source, "rev-parse", "--is-bare-repository")
+		if _, statErr := os.Stat(filepath.Join(source, ".git")); err != nil && statErr != nil {
+			return fmt.Errorf("%s is not a git repository", source)
+		} else if bare != "true" {
+			return fmt.Errorf("%s is not a bare repository, scan its worktree instead", source)
+		}
+		if remote == "" {
+			remote = detectors.DefaultGitRemote
+		}
+		if repo := detectors.OpenGitRepository(source); repo != nil {
+			if origin, err := repo.RemoteURL(remote); err == nil && origin != "" {
+				t.RepoURL = detectors.RepositoryURL(origin)
+			}
+		}
+	}
+
+	commit, err := gitOutput(gitDir, "rev-parse", "--verify", "--quiet", "HEAD^{commit}")
+	if err != nil {
+		return fmt.Errorf("%s has no commits on its default branch", source)
+	}
+	t.Commit = commit
+	if repo := detectors.OpenGitRepository(gitDir); repo != nil {
+		t.Branch = repo.Branch()
+	}
+
+	blobs, err := treeBlobs(gitDir, commit)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(t.Dir, 0755); err != nil {
+		return err
+	}
+	return writeBlobs(gitDir, t.Dir, blobs)
+}
+
+// treeBlob is a file of a git tree
+type treeBlob struct {
+	object string
+	path   string
+	mode   os.FileMode
+}
+
+// treeBlobs lists the regular files of a commit's tree, without those over
+// bareMaxFileSize
+func treeBlobs(gitDir, commit string) ([]treeBlob, error) {
+	output, err := gitOutput(gitDir, "ls-tree", "-r", "-z", "-l", "--full-tree", commit)
+	if err != nil {
+		return nil, err
+	}
+	var blobs []treeBlob
+	for _, entry := range strings.Split(output, "\x00") {
+		// <mode> SP <type> SP <object> SP+ <size> TAB <path>
+		info, path, found := strings.Cut(entry, "\t")
+		fields := strings.Fields(info)
+		if !found || len(fields) != 4 || fields[1] != "blob" {
+			continue
+		}
+		if fields[0] != "100644" && fields[0] != "100755" {
+			continue // symlinks
+		}
+		if size, err := strconv.ParseInt(fields[3], 10, 64); err != nil || size > bareMaxFileSize {
+			continue
+		}
+		mode := os.FileMode(0644)
+		if fields[0] == "100755" {
+			mode = 0755
+		}
+		blobs = append(blobs, treeBlob{object: fields[2], path: path, mode: mode})
+	}
+	return blobs, nil
+}
+
+// writeBlobs reads the blobs with one git cat-file --batch and writes them below dir
+func writeBlobs(gitDir, dir string, blobs []treeBlob) error {
+	if len(blobs) == 0 {
+		return nil
+	}
+	cmd := exec.Command("git", "--git-dir", gitDir, "cat-file", "--batch")
+	var objects bytes.Buffer
+	for _, blob := range blobs {
+		objects.WriteString(blob.object + "\n")
+	}
+	cmd.Stdin = &objects
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	if err := copyBlobs(bufio.NewReader(stdout), dir, blobs); err != nil {
+		cmd.Process.Kill()
+		cmd.Wait()
+		return err
+	}
+	return cmd.Wait()
+}
+
+// copyBlobs writes the blobs of git cat-file --batch output in the order they were asked for
+func copyBlobs(reader *bufio.Reader, dir string, blobs []treeBlob) error {
+	for _, blob := range blobs {
+		// <object> SP blob SP <size> LF <content> LF
+		header, err := reader.ReadString('\n')
+		if err != nil {
+			return err
+		}
+		fields := strings.Fields(header)
+		if len(fields) != 3 {
+			return fmt.Errorf("could not read %s: %s", blob.path, strings.TrimSpace(header))
+		}
+		size, err := strconv.ParseInt(fields[2], 10, 64)
+		if err != nil {
+			return err
+		}
+		content := make([]byte, size+1)
+		if _, err := io.ReadFull(reader, content); err != nil {
+			return err
+		}
+		target := filepath.Join(dir, filepath.FromSlash(blob.path))
+		if !strings.HasPrefix(target, dir+string(filepath.Separator)) {
+			continue // a path escaping the tree, git doesn't write those either
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(target, content[:size], blob.mode); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// gitOutput runs git on a git directory ("" for none) and returns its trimmed output
+func gitOutput(gitDir string, args ...string) (string, error) {
+	if gitDir != "" {
+		args = append([]string{"--git-dir", gitDir}, args...)
+	}
+	var stderr bytes.Buffer
+	cmd := exec.Command("git", args...)
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return "", fmt.Errorf("%s", message)
+		}
+		return "", err
+	}
+	return strings.TrimSpace(string(output)), nil
+}
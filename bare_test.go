@@ -0,0 +1,101 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"parascan/pkg/parascan"
+)
+
+func TestBareRepository(t *testing.T) {
+	skipWithoutPack(t, "package.json")
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	worktree := filepath.Join(dir, "shop")
+	files := map[string]string{
+		"package.json":      `{"dependencies": {"stripe": "^14.0.0", "@sentry/node": "^7.0.0"}}`,
+		"scripts/deploy.sh": "#!/bin/sh\n",
+		"assets/large.bin":  strings.Repeat("x", bareMaxFileSize+1),
+	}
+	for name, content := range files {
+		path := filepath.Join(worktree, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink("package.json", filepath.Join(worktree, "link.json")); err != nil {
+		t.Fatal(err)
+	}
+	bareDir := filepath.Join(dir, "shop.git")
+	for _, args := range [][]string{
+		{"-C", worktree, "init", "-q", "-b", "main"},
+		{"-C", worktree, "add", "-A"},
+		{"-C", worktree, "update-index", "--chmod=+x", "scripts/deploy.sh"},
+		{"-C", worktree, "-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "-m", "init"},
+		{"clone", "-q", "--bare", worktree, bareDir},
+		{"--git-dir", bareDir, "config", "remote.origin.url", "git@github.com:acme/shop.git"},
+	} {
+		if output, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+			t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, output)
+		}
+	}
+
+	tree, err := exportBareRepository(bareDir, "")
+	if err != nil {
+		t.Fatalf("Failed to export bare repository: %v", err)
+	}
+	defer tree.Remove()
+	if tree.Name != "shop" || tree.Branch != "main" || tree.RepoURL != "https://github.com/acme/shop" || !strings.HasPrefix(tree.Revision(), "main at ") {
+		t.Errorf("Expected shop on main from github.com/acme/shop, got %+v", tree)
+	}
+
+	// Regular files keep their mode, symlinks and large files are left out
+	if info, err := os.Stat(filepath.Join(tree.Dir, "scripts", "deploy.sh")); err != nil || info.Mode().Perm()&0100 == 0 {
+		t.Errorf("Expected an executable deploy.sh, got %v (%v)", info, err)
+	}
+	for _, name := range []string{"link.json", "assets/large.bin"} {
+		if _, err := os.Lstat(filepath.Join(tree.Dir, filepath.FromSlash(name))); err == nil {
+			t.Errorf("Did not expect %s in the exported tree", name)
+		}
+	}
+
+	catalog, err := parascan.EmbeddedCatalog()
+	if err != nil {
+		t.Fatalf("Failed to load catalog: %v", err)
+	}
+	scan := runScan(tree.Dir, catalog, ScanOptions{SkipGit: true, RepoURL: tree.RepoURL})
+	for key, want := range map[string]string{"stripe": "https://dashboard.stripe.com", "repo": "https://github.com/acme/shop"} {
+		if scan.Results[key] != want {
+			t.Errorf("Expected %s: %s, got %v", key, want, scan.Results)
+		}
+	}
+
+	// Remote repositories are cloned bare first, the URL is the repository's
+	remote, err := exportBareRepository("file://"+filepath.ToSlash(bareDir), "")
+	if !networkEnabled {
+		if !errors.Is(err, errNetworkDisabled) {
+			t.Errorf("Expected remote repositories refused without network, got %v", err)
+		}
+	} else if err != nil {
+		t.Fatalf("Failed to export remote repository: %v", err)
+	} else {
+		defer remote.Remove()
+		if _, err := os.Stat(filepath.Join(remote.Dir, "package.json")); err != nil || remote.Commit != tree.Commit {
+			t.Errorf("Expected the remote's default branch at %s, got %+v (%v)", tree.Commit, remote, err)
+		}
+	}
+
+	// A worktree isn't bare
+	if _, err := exportBareRepository(worktree, ""); err == nil || !strings.Contains(err.Error(), "not a bare repository") {
+		t.Errorf("Expected the worktree to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"parascan/pkg/parascan"
+)
+
+// Bootstrap formats accepted by --format
+var bootstrapFormats = []string{"brewfile", "tool-versions"}
+
+// RequiredTool is a tool the scanned project needs, with the version it pins if any
+type RequiredTool struct {
+	Name    string
+	Tool    parascan.Tool
+	Version string
+}
+
+// requiredTools maps the scan's languages and results to catalog tools, sorted by name
+func requiredTools(projectPath string, catalog *parascan.Catalog, scan *parascan.Report, settings *parascan.ProjectSettings) []RequiredTool {
+	detected := make(map[string]bool)
+	for _, language := range scan.Languages {
+		detected[language] = true
+	}
+	for key := range scan.Results {
+		detected[strings.ToLower(key)] = true
+	}
+	// File detector results are keyed by display name
+	if catalog.FileDetectors != nil {
+		for techKey, tech := range catalog.FileDetectors.Technologies {
+			if detected[strings.ToLower(tech.DisplayName)] {
+				detected[strings.ToLower(techKey)] = true
+			}
+		}
+	}
+
+	var tools []RequiredTool
+	for name, tool := range catalog.Tooling.Tools {
+		for _, key := range tool.DetectedBy {
+			if detected[strings.ToLower(key)] {
+				tools = append(tools, RequiredTool{Name: name, Tool: tool, Version: toolVersion(projectPath, tool.Version, settings)})
+				break
+			}
+		}
+	}
+	sort.Slice(tools, func(i, j int) bool { return tools[i].Name < tools[j].Name })
+	return tools
+}
+
+// toolVersion finds the pinned version in the project's files, searched like dependency files
+func toolVersion(projectPath string, version *parascan.ToolVersion, settings *parascan.ProjectSettings) string {
+	if version == nil {
+		return ""
+	}
+	re, err := regexp.Compile(version.Pattern)
+	if err != nil {
+		return ""
+	}
+	filter := settings.PathFilter()
+	dirs := parascan.ProjectDirs(projectPath, filter, 0)
+	for _, pattern := range version.Files {
+		for _, path := range parascan.FindDependencyFiles(projectPath, dirs, pattern, filter) {
+			content, err := os.ReadFile(path)
+			if err != nil {
+				continue
+			}
+			if match := re.FindSubmatch(content); len(match) > 1 && len(match[1]) > 0 {
+				return string(match[1])
+			}
+		}
+	}
+	return ""
+}
+
+// writeBrewfile writes a Brewfile for `brew bundle`
+func writeBrewfile(w io.Writer, projectName string, tools []RequiredTool) {
+	fmt.Fprintf(w, "# Brewfile for %s generated by para bootstrap, install with `brew bundle`\n", projectName)
+	for _, tool := range tools {
+		if tool.Tool.Brew != "" {
+			fmt.Fprintf(w, "brew %q\n", tool.Tool.Brew)
+		} else if tool.Tool.Cask != "" {
+			fmt.Fprintf(w, "cask %q\n", tool.Tool.Cask)
+		}
+	}
+}
+
+// writeToolVersions writes an asdf .tool-versions. Tools without a version in the
+// project are commented out for the contributor to pick one.
+func writeToolVersions(w io.Writer, projectName string, tools []RequiredTool) {
+	fmt.Fprintf(w, "# .tool-versions for %s generated by para bootstrap, install with `asdf install`\n", projectName)
+	for _, tool := range tools {
+		if tool.Tool.Asdf == "" {
+			continue
+		}
+		if tool.Version != "" {
+			fmt.Fprintf(w, "%s %s\n", tool.Tool.Asdf, tool.Version)
+		} else {
+			fmt.Fprintf(w, "# %s <version>  (no version found in the project)\n", tool.Tool.Asdf)
+		}
+	}
+}
+
+func handleBootstrap() {
+	projectPath := "."
+	format := "brewfile"
+	var outputPath string
+
+	flags := newCommandFlags("bootstrap", showBootstrapHelp)
+	flags.StringVar(&format, "format", format, "")
+	flags.StringVar(&format, "f", format, "")
+	flags.StringVar(&outputPath, "output", "", "")
+	flags.StringVar(&outputPath, "o", "", "")
+	args := flags.parse(os.Args[2:])
+	flags.maxArgs(args, 1)
+	if len(args) == 1 {
+		projectPath = args[0]
+	}
+
+	settings, err := parascan.LoadProjectSettings(projectPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		os.Exit(1)
+	}
+	globalConfig, err := loadGlobalConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		os.Exit(1)
+	}
+	catalog, _, err := resolveCatalog(projectPath, "", settings, globalConfig.Trust, false)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		os.Exit(1)
+	}
+
+	projectName := settings.Output.ProjectName
+	if projectName == "" {
+		if abs, err := filepath.Abs(projectPath); err == nil {
+			projectName = filepath.Base(abs)
+		}
+	}
+
+	scan := runScan(projectPath, catalog, ScanOptions{SkipGit: true, Settings: settings})
+	tools := requiredTools(projectPath, catalog, scan, settings)
+
+	var w io.Writer = os.Stdout
+	if outputPath != "" {
+		file, err := os.Create(outputPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+			os.Exit(1)
+		}
+		defer file.Close()
+		w = file
+	}
+
+	switch format {
+	case "brewfile":
+		writeBrewfile(w, projectName, tools)
+	case "tool-versions":
+		writeToolVersions(w, projectName, tools)
+	default:
+		fmt.Fprintf(os.Stderr, "❌ Unknown format: %s. Supported formats: %s\n", format, strings.Join(bootstrapFormats, ", "))
+		os.Exit(1)
+	}
+
+	if outputPath != "" {
+		fmt.Printf("📝 Wrote %s for %d tool(s) to %s\n", format, len(tools), outputPath)
+	}
+}
+
+func showBootstrapHelp() {
+	fmt.Println(`Usage: para bootstrap [path] [--format brewfile|tool-versions] [--output file]
+
+Scaffold machine setup for contributors from the tools the project's detections imply
+(language runtimes, terraform, cloud and service CLIs). Versions pinned in the project,
+e.g. in .nvmrc or go.mod, are used for .tool-versions.
+
+Options:
+  --format, -f     brewfile (default) or tool-versions
+  --output, -o     Write to a file instead of stdout
+
+Examples:
+  para bootstrap -o Brewfile && brew bundle
+  para bootstrap ./my-project --format tool-versions -o .tool-versions`)
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"parascan/pkg/parascan"
+)
+
+func TestBootstrapTools(t *testing.T) {
+	skipWithoutPack(t, "package.json", "go.mod")
+	projectPath := filepath.Join("testdata", "monorepo-project")
+	catalog, err := parascan.EmbeddedCatalog()
+	if err != nil {
+		t.Fatalf("Failed to load catalog: %v", err)
+	}
+	settings := &parascan.ProjectSettings{}
+	scan := runScan(projectPath, catalog, ScanOptions{SkipGit: true, Settings: settings})
+	tools := requiredTools(projectPath, catalog, scan, settings)
+
+	var brewfile bytes.Buffer
+	writeBrewfile(&brewfile, "acme", tools)
+	expected := "# Brewfile for acme generated by para bootstrap, install with `brew bundle`\n" +
+		"brew \"go\"\n" +
+		"brew \"node\"\n" +
+		"brew \"stripe/stripe-cli/stripe\"\n"
+	if brewfile.String() != expected {
+		t.Errorf("Expected Brewfile\n%s\ngot\n%s", expected, brewfile.String())
+	}
+
+	// go.mod pins the Go version, nothing pins Node
+	var toolVersions bytes.Buffer
+	writeToolVersions(&toolVersions, "acme", tools)
+	expected = "# .tool-versions for acme generated by para bootstrap, install with `asdf install`\n" +
+		"golang 1.21\n" +
+		"# nodejs <version>  (no version found in the project)\n"
+	if toolVersions.String() != expected {
+		t.Errorf("Expected .tool-versions\n%s\ngot\n%s", expected, toolVersions.String())
+	}
+}
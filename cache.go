@@ -0,0 +1,292 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"parascan/data"
+	"parascan/pkg/parascan"
+)
+
+// The catalog cache is shared by every para process of the user, from terminals,
+// editors and CI jobs on the same runner. Entries are written under a lock into a
+// temporary directory and renamed into place, so readers never see half a catalog.
+const (
+	cacheLockSuffix  = ".lock"
+	cacheLockTimeout = 30 * time.Second
+	cacheLockStale   = 2 * time.Minute // older locks were left by a killed process
+	cacheLockRetry   = 50 * time.Millisecond
+)
+
+// lockCacheEntry takes the lock of a cache entry, waiting while another process
+// holds it. Locks older than cacheLockStale are taken over.
+func lockCacheEntry(entry string) (unlock func(), err error) {
+	if err := os.MkdirAll(filepath.Dir(entry), 0755); err != nil {
+		return nil, err
+	}
+	lockPath := entry + cacheLockSuffix
+	deadline := time.Now().Add(cacheLockTimeout)
+	for {
+		file, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			fmt.Fprintf(file, "%d\n", os.Getpid())
+			file.Close()
+			return func() { os.Remove(lockPath) }, nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return nil, err
+		}
+		if info, err := os.Stat(lockPath); err == nil && time.Since(info.ModTime()) > cacheLockStale {
+			os.Remove(lockPath)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("%s is locked by another para process, remove %s if none is running", entry, lockPath)
+		}
+		time.Sleep(cacheLockRetry)
+	}
+}
+
+// cacheCatalog exports the catalog files of fsys to the cache entry of version and
+// returns the entry's directory. A previous copy is replaced.
+func cacheCatalog(fsys fs.FS, version string) (string, error) {
+	cacheDir, err := catalogCacheDir()
+	if err != nil {
+		return "", err
+	}
+	entry := filepath.Join(cacheDir, version)
+	unlock, err := lockCacheEntry(entry)
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+	return entry, writeCacheEntry(fsys, entry)
+}
+
+// writeCacheEntry exports a catalog next to the entry and swaps it in, the caller
+// holds the entry's lock
+func writeCacheEntry(fsys fs.FS, entry string) error {
+	tmp, err := os.MkdirTemp(filepath.Dir(entry), "."+filepath.Base(entry)+".tmp-")
+	if err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp, 0755); err == nil {
+		err = exportCatalog(fsys, tmp)
+	}
+	if err == nil {
+		if err = removeCacheEntry(entry); err == nil {
+			err = os.Rename(tmp, entry)
+		}
+	}
+	if err != nil {
+		os.RemoveAll(tmp)
+	}
+	return err
+}
+
+// removeCacheEntry moves an entry out of the way before deleting it, so a reader
+// never loads a directory that is half deleted
+func removeCacheEntry(entry string) error {
+	if _, err := os.Stat(entry); os.IsNotExist(err) {
+		return nil
+	}
+	trash, err := os.MkdirTemp(filepath.Dir(entry), "."+filepath.Base(entry)+".old-")
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(entry, filepath.Join(trash, "catalog")); err != nil {
+		os.RemoveAll(trash)
+		return err
+	}
+	return os.RemoveAll(trash)
+}
+
+// checkCacheEntry reports why a cache entry can't be used: a missing entry, missing
+// checksums (exports before atomic writes could be interrupted half way) or files
+// that don't match them
+func checkCacheEntry(entry string) error {
+	if _, err := os.Stat(entry); err != nil {
+		return err
+	}
+	if _, err := os.Stat(filepath.Join(entry, checksumsFile)); err != nil {
+		return fmt.Errorf("%s: incomplete catalog, no %s", entry, checksumsFile)
+	}
+	return verifyCatalogDir(entry, TrustPolicy{})
+}
+
+// healCacheEntry repairs an entry that failed checkCacheEntry. Another process may
+// just have been replacing it; otherwise the damaged entry is removed and, when
+// it's the version embedded in this binary, exported again, which repaired reports.
+// The error says why the entry is still unusable.
+func healCacheEntry(entry string) (repaired bool, err error) {
+	unlock, err := lockCacheEntry(entry)
+	if err != nil {
+		return false, err
+	}
+	defer unlock()
+
+	checkErr := checkCacheEntry(entry)
+	if checkErr == nil || os.IsNotExist(checkErr) {
+		return false, checkErr
+	}
+	if err := removeCacheEntry(entry); err != nil {
+		return false, err
+	}
+	embedded, err := parascan.EmbeddedCatalog()
+	if err != nil || embedded.Version != filepath.Base(entry) {
+		return false, fmt.Errorf("%v, removed it", checkErr)
+	}
+	if err := writeCacheEntry(data.FS, entry); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// cacheEntryStatus is the state of one cached catalog
+type cacheEntryStatus struct {
+	Version string
+	Err     error // why the entry can't be used, nil when it's sound
+}
+
+// verifyCache checks every catalog in the cache, by version. Temporary directories
+// of exports in progress aren't entries.
+func verifyCache(cacheDir string) ([]cacheEntryStatus, error) {
+	entries, err := os.ReadDir(cacheDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var statuses []cacheEntryStatus
+	for _, entry := range entries {
+		if entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") {
+			statuses = append(statuses, cacheEntryStatus{Version: entry.Name(), Err: checkCacheEntry(filepath.Join(cacheDir, entry.Name()))})
+		}
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return compareCatalogVersions(statuses[i].Version, statuses[j].Version) < 0
+	})
+	return statuses, nil
+}
+
+// cacheLeftovers lists what killed processes left in the cache: stale locks and
+// temporary directories of exports and removals
+func cacheLeftovers(cacheDir string) []string {
+	entries, err := os.ReadDir(cacheDir)
+	if err != nil {
+		return nil
+	}
+	var leftovers []string
+	for _, entry := range entries {
+		name := entry.Name()
+		temporary := strings.HasPrefix(name, ".") && (strings.Contains(name, ".tmp-") || strings.Contains(name, ".old-"))
+		if !temporary && !strings.HasSuffix(name, cacheLockSuffix) {
+			continue
+		}
+		if info, err := entry.Info(); err == nil && time.Since(info.ModTime()) > cacheLockStale {
+			leftovers = append(leftovers, filepath.Join(cacheDir, name))
+		}
+	}
+	return leftovers
+}
+
+func handleCache() {
+	if len(os.Args) < 3 {
+		showCacheHelp()
+		return
+	}
+
+	switch os.Args[2] {
+	case "verify":
+		handleCacheVerify(os.Args[3:])
+	case "help", "--help", "-h":
+		showCacheHelp()
+	default:
+		fmt.Println("Unknown cache command:", os.Args[2])
+		showCacheHelp()
+		os.Exit(1)
+	}
+}
+
+// handleCacheVerify checks the cached catalogs against their checksums, and with
+// --fix repairs or removes the damaged ones and cleans up after killed processes
+func handleCacheVerify(args []string) {
+	fix := false
+	flags := newCommandFlags("cache verify", showCacheHelp)
+	flags.BoolVar(&fix, "fix", false, "")
+	flags.maxArgs(flags.parse(args), 0)
+
+	cacheDir, err := catalogCacheDir()
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		os.Exit(1)
+	}
+	statuses, err := verifyCache(cacheDir)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		os.Exit(1)
+	}
+	if len(statuses) == 0 {
+		fmt.Printf("No cached catalogs in %s\n", cacheDir)
+	}
+
+	damaged := 0
+	for _, status := range statuses {
+		if status.Err == nil {
+			fmt.Printf("✅ %s\n", status.Version)
+			continue
+		}
+		if !fix {
+			fmt.Printf("❌ %s: %v\n", status.Version, status.Err)
+			damaged++
+			continue
+		}
+		repaired, err := healCacheEntry(filepath.Join(cacheDir, status.Version))
+		switch {
+		case repaired:
+			fmt.Printf("🔧 %s: %v, exported it again\n", status.Version, status.Err)
+		case err == nil:
+			fmt.Printf("✅ %s\n", status.Version)
+		default:
+			fmt.Printf("🗑️  %s: %v\n", status.Version, err)
+		}
+	}
+
+	for _, leftover := range cacheLeftovers(cacheDir) {
+		if !fix {
+			fmt.Printf("⚠️  Left over by an interrupted process: %s\n", leftover)
+			continue
+		}
+		if err := os.RemoveAll(leftover); err != nil {
+			fmt.Printf("⚠️  %v\n", err)
+		} else {
+			fmt.Printf("🧹 Removed %s\n", leftover)
+		}
+	}
+
+	if damaged > 0 {
+		fmt.Println("Run `para cache verify --fix` to repair or remove the damaged catalogs")
+		os.Exit(1)
+	}
+}
+
+func showCacheHelp() {
+	fmt.Println(`Usage: para cache <command>
+
+Commands:
+  verify   Check the cached catalogs (~/.config/parascope/catalogs) against their checksums
+
+Options:
+  --fix    Export damaged catalogs of the embedded version again, remove other damaged
+           ones, and clean up locks and temporary directories left by killed processes
+
+Concurrent para processes share the cache safely: entries are written under a lock
+and swapped in whole, and scans repair an entry they find damaged.`)
+}
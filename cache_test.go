@@ -0,0 +1,88 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"parascan/data"
+	"parascan/pkg/parascan"
+)
+
+func TestCatalogCache(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	cacheDir, _ := catalogCacheDir()
+	embedded, err := parascan.EmbeddedCatalog()
+	if err != nil {
+		t.Fatalf("Failed to load embedded catalog: %v", err)
+	}
+
+	// Concurrent exports of the same version each swap in a complete copy
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := cacheCatalog(data.FS, embedded.Version)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("Failed to cache catalog: %v", err)
+		}
+	}
+	entry := filepath.Join(cacheDir, embedded.Version)
+	if err := checkCacheEntry(entry); err != nil {
+		t.Fatalf("Expected a sound cache entry, got %v", err)
+	}
+	if entries, _ := os.ReadDir(cacheDir); len(entries) != 1 {
+		t.Errorf("Expected only the cached catalog, no locks or temporary directories, got %v", entries)
+	}
+
+	// A damaged entry of the embedded version is exported again
+	os.WriteFile(filepath.Join(entry, "tooling.yml"), []byte("truncated"), 0644)
+	statuses, err := verifyCache(cacheDir)
+	if err != nil || len(statuses) != 1 || statuses[0].Err == nil {
+		t.Fatalf("Expected the damaged catalog to fail verification, got %+v, %v", statuses, err)
+	}
+	if repaired, err := healCacheEntry(entry); !repaired || err != nil {
+		t.Errorf("Expected the catalog to be exported again, got %v, %v", repaired, err)
+	}
+	if err := checkCacheEntry(entry); err != nil {
+		t.Errorf("Expected a sound cache entry after repair, got %v", err)
+	}
+
+	// Other versions can't be exported again, interrupted exports have no checksums
+	old := filepath.Join(cacheDir, "2000.1.1")
+	os.MkdirAll(filepath.Join(old, "services"), 0755)
+	os.WriteFile(filepath.Join(old, "services", "stripe.yml"), []byte("name: Stripe\n"), 0644)
+	if repaired, err := healCacheEntry(old); repaired || err == nil {
+		t.Errorf("Expected the incomplete catalog to be removed, got %v, %v", repaired, err)
+	}
+	if _, err := os.Stat(old); !os.IsNotExist(err) {
+		t.Errorf("Expected %s to be removed", old)
+	}
+
+	// A lock left by a killed process is taken over, not waited for
+	lockPath := entry + cacheLockSuffix
+	os.WriteFile(lockPath, []byte("1\n"), 0644)
+	stale := time.Now().Add(-2 * cacheLockStale)
+	os.Chtimes(lockPath, stale, stale)
+	if leftovers := cacheLeftovers(cacheDir); len(leftovers) != 1 || leftovers[0] != lockPath {
+		t.Errorf("Expected the stale lock as a leftover, got %v", leftovers)
+	}
+	unlock, err := lockCacheEntry(entry)
+	if err != nil {
+		t.Fatalf("Expected the stale lock to be taken over, got %v", err)
+	}
+	unlock()
+	if _, err := os.Stat(lockPath); !os.IsNotExist(err) {
+		t.Error("Expected unlock to remove the lock")
+	}
+}
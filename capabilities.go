@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"parascan/pkg/parascan"
+	"parascan/pluginsdk"
+)
+
+// Capabilities is what this build of para supports, for orchestration layers that
+// check before relying on a command, format or feature
+type Capabilities struct {
+	Version        string            `json:"version"`
+	CatalogVersion string            `json:"catalog_version"`
+	PluginProtocol int               `json:"plugin_protocol"`
+	Commands       []string          `json:"commands"`
+	Formats        []string          `json:"formats"` // of para scan --format and --output
+	Languages      []LanguageListing `json:"languages"`
+	Packs          []string          `json:"packs"`          // language packs with dependency file parsers, see the slim build tag
+	Detectors      []string          `json:"detectors"`      // built-in, by the names .parascan.yml switches them with
+	FileDetectors  []string          `json:"file_detectors"` // technologies of the file detector by key
+	Features       map[string]bool   `json:"features"`       // network and plugins, see the build tags in features.go
+}
+
+// buildCapabilities lists the capabilities of this build with the embedded catalog
+func buildCapabilities(catalog *parascan.Catalog) Capabilities {
+	capabilities := Capabilities{
+		Version:        Version,
+		CatalogVersion: catalog.Version,
+		PluginProtocol: pluginsdk.ProtocolVersion,
+		Formats:        outputFormats,
+		Languages:      listLanguages(catalog),
+		Packs:          parascan.LanguagePacks(),
+		Features:       map[string]bool{"network": networkEnabled, "plugins": pluginsEnabled},
+	}
+	for _, command := range commands {
+		if command != "help" {
+			capabilities.Commands = append(capabilities.Commands, command)
+		}
+	}
+	for _, detector := range builtinDetectors {
+		capabilities.Detectors = append(capabilities.Detectors, detector.Name)
+	}
+	for _, detector := range listFileDetectors(catalog) {
+		capabilities.FileDetectors = append(capabilities.FileDetectors, detector.Name)
+	}
+	return capabilities
+}
+
+func writeCapabilities(w io.Writer, capabilities Capabilities) {
+	var languages []string
+	for _, language := range capabilities.Languages {
+		if len(language.PackageManagers) == 0 {
+			languages = append(languages, language.Name)
+			continue
+		}
+		languages = append(languages, fmt.Sprintf("%s (%s)", language.Name, strings.Join(sortedListKeys(language.PackageManagers), ", ")))
+	}
+	var features []string
+	for _, feature := range sortedFeatures(capabilities.Features) {
+		state := "off"
+		if capabilities.Features[feature] {
+			state = "on"
+		}
+		features = append(features, feature+" "+state)
+	}
+
+	fmt.Fprintf(w, "para %s, catalog %s, plugin protocol %d\n", capabilities.Version, capabilities.CatalogVersion, capabilities.PluginProtocol)
+	fmt.Fprintf(w, "Commands:       %s\n", strings.Join(capabilities.Commands, ", "))
+	fmt.Fprintf(w, "Formats:        %s\n", strings.Join(capabilities.Formats, ", "))
+	fmt.Fprintf(w, "Languages:      %s\n", strings.Join(languages, ", "))
+	fmt.Fprintf(w, "Packs:          %s\n", strings.Join(capabilities.Packs, ", "))
+	fmt.Fprintf(w, "Detectors:      %s\n", strings.Join(capabilities.Detectors, ", "))
+	fmt.Fprintf(w, "File detectors: %d, see para list detectors\n", len(capabilities.FileDetectors))
+	fmt.Fprintf(w, "Features:       %s\n", strings.Join(features, ", "))
+}
+
+// sortedFeatures returns the feature names in order
+func sortedFeatures(features map[string]bool) []string {
+	names := make([]string, 0, len(features))
+	for name := range features {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// handleCapabilities shows what this build supports, as text or JSON
+func handleCapabilities() {
+	format := "text"
+	flags := newCommandFlags("capabilities", showCapabilitiesHelp)
+	flags.StringVar(&format, "format", format, "")
+	flags.StringVar(&format, "f", format, "")
+	flags.maxArgs(flags.parse(os.Args[2:]), 0)
+	if format != "text" && format != "json" {
+		flags.fail("Unknown format: %s. Supported formats: text, json", format)
+	}
+
+	catalog, err := parascan.EmbeddedCatalog()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		os.Exit(1)
+	}
+	capabilities := buildCapabilities(catalog)
+	if format == "text" {
+		writeCapabilities(os.Stdout, capabilities)
+		return
+	}
+	data, _ := json.MarshalIndent(capabilities, "", "  ")
+	fmt.Println(string(data))
+}
+
+func showCapabilitiesHelp() {
+	fmt.Println(`Usage: para capabilities [--format text|json]
+
+Show what this build of para supports: commands, scan output formats, languages
+with their package managers, language packs, detectors and features such as
+network access and plugins, which builds can leave out with the slim, nonetwork
+and noplugins build tags.
+
+Options:
+  --format, -f     text (default) or json
+
+Examples:
+  para capabilities
+  para capabilities -f json | jq '.features.plugins'`)
+}
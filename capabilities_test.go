@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"parascan/pkg/parascan"
+)
+
+func TestCapabilities(t *testing.T) {
+	catalog, err := parascan.EmbeddedCatalog()
+	if err != nil {
+		t.Fatalf("Failed to load catalog: %v", err)
+	}
+
+	capabilities := buildCapabilities(catalog)
+	if capabilities.Features["network"] != networkEnabled || capabilities.Features["plugins"] != pluginsEnabled {
+		t.Errorf("Expected the features of this build's tags, got %v", capabilities.Features)
+	}
+	if !containsFold(capabilities.Commands, "capabilities") || containsFold(capabilities.Commands, "help") {
+		t.Errorf("Expected the commands without help, got %v", capabilities.Commands)
+	}
+	if strings.Join(capabilities.Packs, ",") != strings.Join(parascan.LanguagePacks(), ",") {
+		t.Errorf("Expected the language packs of this build, got %v", capabilities.Packs)
+	}
+	if len(capabilities.FileDetectors) != len(catalog.FileDetectors.Technologies) {
+		t.Errorf("Expected every file detector, got %d", len(capabilities.FileDetectors))
+	}
+
+	// JSON is what orchestration layers read
+	data, _ := json.Marshal(capabilities)
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Failed to decode %s: %v", data, err)
+	}
+	for _, key := range []string{"version", "catalog_version", "plugin_protocol", "commands", "formats", "languages", "packs", "detectors", "file_detectors", "features"} {
+		if _, ok := decoded[key]; !ok {
+			t.Errorf("Expected %s in %s", key, data)
+		}
+	}
+
+	var buf strings.Builder
+	writeCapabilities(&buf, capabilities)
+	features := fmt.Sprintf("network %s, plugins %s", onOff(networkEnabled), onOff(pluginsEnabled))
+	for _, expected := range []string{"nodejs (npm, pnpm, yarn)", "sarif", "Packs:          " + strings.Join(parascan.LanguagePacks(), ", ") + "\n", features} {
+		if !strings.Contains(buf.String(), expected) {
+			t.Errorf("Expected %q in %s", expected, buf.String())
+		}
+	}
+}
+
+// onOff is how capabilities print a feature's state
+func onOff(enabled bool) string {
+	if enabled {
+		return "on"
+	}
+	return "off"
+}
+
+// TestCapabilitiesLanguagePacks checks the packs of the build against the files they
+// parse, all of them by default and those picked with tags in a slim build:
+// go test -tags slim,pack_node,pack_python
+func TestCapabilitiesLanguagePacks(t *testing.T) {
+	compiled := make(map[string]bool)
+	for _, pack := range parascan.LanguagePacks() {
+		compiled[pack] = true
+	}
+	if len(compiled) == len(parascan.AllLanguagePacks()) {
+		t.Logf("Every language pack is in this build, go test -tags slim checks a slim one")
+	}
+
+	files := map[string]string{
+		"node":   "web/package.json",
+		"go":     "go.mod",
+		"jvm":    "build.gradle.kts",
+		"dotnet": "Billing.csproj",
+		"php":    "composer.lock",
+		"ruby":   "billing.gemspec",
+		"python": "dev-requirements.txt",
+	}
+	for _, pack := range parascan.AllLanguagePacks() {
+		file, ok := files[pack]
+		if !ok {
+			t.Errorf("Expected a dependency file of the %s pack in the test", pack)
+			continue
+		}
+		expected := pack
+		if compiled[pack] {
+			expected = ""
+		}
+		if missing := parascan.MissingLanguagePack(file); missing != expected {
+			t.Errorf("Expected %s missing the %q pack, got %q", file, expected, missing)
+		}
+	}
+
+	// Files read by the line search need no pack
+	for _, file := range []string{"Pipfile", "pyproject.toml", "Pipfile.lock"} {
+		if missing := parascan.MissingLanguagePack(file); missing != "" {
+			t.Errorf("Expected no pack needed for %s, got %q", file, missing)
+		}
+	}
+}
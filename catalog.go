@@ -0,0 +1,452 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"parascan/data"
+	"parascan/pkg/parascan"
+)
+
+// loadCatalogDir loads a catalog directory with the same layout as data/
+// (stack-dependency-files.yml, file-detectors.yml, services/*.yml, optional tooling.yml, attention.yml and health.yml).
+// The directory is verified against the trust policy unless skipVerify is set.
+func loadCatalogDir(dir string, policy TrustPolicy, skipVerify bool) (*parascan.Catalog, error) {
+	if !skipVerify {
+		if err := verifyCatalogDir(dir, policy); err != nil {
+			return nil, fmt.Errorf("Catalog verification failed: %v", err)
+		}
+	}
+	return parascan.LoadCatalogFS(os.DirFS(dir), dir)
+}
+
+// compareCatalogVersions compares dotted numeric versions like 2025.10.1
+func compareCatalogVersions(a, b string) int {
+	partsA, partsB := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(partsA) || i < len(partsB); i++ {
+		var numA, numB int
+		if i < len(partsA) {
+			numA, _ = strconv.Atoi(partsA[i])
+		}
+		if i < len(partsB) {
+			numB, _ = strconv.Atoi(partsB[i])
+		}
+		if numA != numB {
+			if numA < numB {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// resolveCatalog picks the catalog for a scan: an explicit --catalog directory,
+// the catalog pinned in the project settings, or the embedded one, and merges
+// the user's and the project's rules over it. Rules directories are verified like
+// catalog directories, unsigned ones are refused when signatures are required.
+// Warnings are returned for the caller to display.
+func resolveCatalog(projectPath, catalogDir string, settings *parascan.ProjectSettings, policy TrustPolicy, skipVerify bool) (*parascan.Catalog, []string, error) {
+	catalog, warnings, err := pickCatalog(projectPath, catalogDir, settings, policy, skipVerify)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, dir := range rulesDirs(projectPath) {
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			continue
+		}
+		if !skipVerify {
+			if err := verifyCatalogDir(dir, policy); err != nil {
+				return nil, nil, err
+			}
+		}
+		if catalog, err = catalog.WithRules(os.DirFS(dir), dir); err != nil {
+			return nil, nil, err
+		}
+	}
+	return catalog, warnings, nil
+}
+
+// rulesDirs lists the rules directories in the order they're merged: the user's
+// (~/.config/parascope/rules), then the project's (.parascope/rules), which wins
+func rulesDirs(projectPath string) []string {
+	var dirs []string
+	if configDir, err := parascopeConfigDir(); err == nil {
+		dirs = append(dirs, filepath.Join(configDir, "rules"))
+	}
+	return append(dirs, filepath.Join(projectPath, ".parascope", "rules"))
+}
+
+// pickCatalog is resolveCatalog without the rules, pins apply to the picked catalog
+func pickCatalog(projectPath, catalogDir string, settings *parascan.ProjectSettings, policy TrustPolicy, skipVerify bool) (*parascan.Catalog, []string, error) {
+	if catalogDir != "" {
+		catalog, err := loadCatalogDir(catalogDir, policy, skipVerify)
+		return catalog, nil, err
+	}
+
+	embedded, err := parascan.EmbeddedCatalog()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pin := settings.Catalog
+	if pin.Version == "" && pin.Hash == "" && pin.Path == "" {
+		return embedded, nil, nil
+	}
+
+	var candidates []string
+	var cacheEntry string
+	if pin.Path != "" {
+		path := pin.Path
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(projectPath, path)
+		}
+		candidates = append(candidates, path)
+	} else if pin.Matches(embedded) {
+		return embedded, nil, nil
+	} else if pin.Version != "" {
+		if cacheDir, err := catalogCacheDir(); err == nil {
+			cacheEntry = filepath.Join(cacheDir, pin.Version)
+			candidates = append(candidates, cacheEntry)
+		}
+	}
+
+	var warnings []string
+	var damaged error
+	for _, dir := range candidates {
+		if dir == cacheEntry {
+			if err := checkCacheEntry(dir); err != nil {
+				repaired, err := healCacheEntry(dir)
+				if err != nil {
+					if !os.IsNotExist(err) {
+						damaged = err
+					}
+					continue
+				}
+				if repaired {
+					warnings = append(warnings, fmt.Sprintf("Cached catalog %s was damaged and has been exported again", pin.Version))
+				}
+			}
+		} else if _, err := os.Stat(dir); err != nil {
+			continue
+		}
+		catalog, err := loadCatalogDir(dir, policy, skipVerify)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !pin.Matches(catalog) {
+			return nil, nil, fmt.Errorf("catalog in %s doesn't match the pin in %s (version %s, %s)", dir, parascan.SettingsFile, catalog.Version, catalog.Hash)
+		}
+
+		if catalog.Version != "" && compareCatalogVersions(catalog.Version, embedded.Version) < 0 {
+			warnings = append(warnings, fmt.Sprintf("Pinned catalog %s is older than the embedded catalog %s, run `para catalog pin` to update", catalog.Version, embedded.Version))
+		}
+		return catalog, warnings, nil
+	}
+
+	if damaged != nil {
+		return nil, nil, fmt.Errorf("pinned catalog %s %s is not available, the cached copy was damaged (%v): run `para catalog export` on a machine with that version or update the pin with `para catalog pin`", pin.Version, pin.Hash, damaged)
+	}
+	return nil, nil, fmt.Errorf("pinned catalog %s %s is not available, run `para catalog export` on a machine with that version or update the pin with `para catalog pin`", pin.Version, pin.Hash)
+}
+
+// catalogCacheDir holds exported catalogs by version (~/.config/parascope/catalogs)
+func catalogCacheDir() (string, error) {
+	configDir, err := parascopeConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "catalogs"), nil
+}
+
+// exportCatalog writes the catalog files of fsys to dir together with SHA256SUMS,
+// ready to be signed and used with --catalog or a pin
+func exportCatalog(fsys fs.FS, dir string) error {
+	files, err := parascan.CatalogFiles(fsys)
+	if err != nil {
+		return err
+	}
+
+	var sums strings.Builder
+	for _, name := range files {
+		content, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			if parascan.OptionalCatalogFiles[name] && errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return err
+		}
+		target := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(target, content, 0644); err != nil {
+			return err
+		}
+		sum := sha256.Sum256(content)
+		sums.WriteString(hex.EncodeToString(sum[:]) + "  " + name + "\n")
+	}
+
+	return os.WriteFile(filepath.Join(dir, checksumsFile), []byte(sums.String()), 0644)
+}
+
+func handleCatalog() {
+	if len(os.Args) < 3 {
+		showCatalogHelp()
+		return
+	}
+
+	switch os.Args[2] {
+	case "test":
+		handleCatalogTest(os.Args[3:])
+	case "version":
+		handleCatalogVersion()
+	case "export":
+		handleCatalogExport(os.Args[3:])
+	case "pin":
+		handleCatalogPin(os.Args[3:])
+	case "usage":
+		handleCatalogUsage(os.Args[3:])
+	case "help", "--help", "-h":
+		showCatalogHelp()
+	default:
+		fmt.Println("Unknown catalog command:", os.Args[2])
+		showCatalogHelp()
+		os.Exit(1)
+	}
+}
+
+func showCatalogHelp() {
+	fmt.Println(`Usage: para catalog <command>
+
+Commands:
+  version
+          Show version and content hash of the embedded catalog.
+  export [dir]
+          Write the embedded catalog with SHA256SUMS to dir
+          (default: ~/.config/parascope/catalogs/<version>).
+  pin [project] [--vendor <dir>]
+          Pin the embedded catalog in the project's .parascan.yml so rescans are reproducible.
+          --vendor also exports the catalog into <dir> inside the project for CI.
+  test --catalog <dir> [--against <fixtures-dir>] [project...]
+          Show which detections change when the catalog in <dir> replaces the embedded one.
+          Every subdirectory of --against is scanned as a separate project.
+  usage --batch <repos.txt> [--catalog <dir>] [--format json|markdown] [-o <file>]
+          Scan every repository of the batch file (one path per line) with the catalog,
+          the embedded one by default, and report the entries that were detected, those
+          never detected and the packages of detected services that never matched.
+          --parallel sets how many repositories are scanned at a time.
+
+Examples:
+  para catalog pin --vendor .parascan/catalog
+  para catalog test --catalog ./my-catalog --against testdata/
+  para catalog test --catalog ./my-catalog ~/src/app1 ~/src/app2
+  para catalog usage --batch repos.txt -f markdown -o catalog-usage.md`)
+}
+
+func handleCatalogVersion() {
+	catalog, err := parascan.EmbeddedCatalog()
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Catalog %s (%s)\n", catalog.Version, catalog.Hash)
+}
+
+func handleCatalogExport(args []string) {
+	catalog, err := parascan.EmbeddedCatalog()
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		os.Exit(1)
+	}
+
+	flags := newCommandFlags("catalog export", showCatalogHelp)
+	args = flags.parse(args)
+	flags.maxArgs(args, 1)
+	var dir string
+	if len(args) > 0 {
+		dir = args[0]
+		err = exportCatalog(data.FS, dir)
+	} else {
+		dir, err = cacheCatalog(data.FS, catalog.Version)
+	}
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("✨ Exported catalog %s to %s\n", catalog.Version, dir)
+}
+
+// handleCatalogPin records the embedded catalog in .parascan.yml and keeps a copy
+// of it in the catalog cache so the pin still resolves after a tool upgrade
+func handleCatalogPin(args []string) {
+	projectPath := "."
+	var vendorDir string
+	flags := newCommandFlags("catalog pin", showCatalogHelp)
+	flags.StringVar(&vendorDir, "vendor", "", "")
+	args = flags.parse(args)
+	flags.maxArgs(args, 1)
+	if len(args) == 1 {
+		projectPath = args[0]
+	}
+
+	catalog, err := parascan.EmbeddedCatalog()
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		os.Exit(1)
+	}
+
+	pin := parascan.CatalogPin{Version: catalog.Version, Hash: catalog.Hash}
+	if vendorDir != "" {
+		if err := exportCatalog(data.FS, filepath.Join(projectPath, vendorDir)); err != nil {
+			fmt.Printf("❌ %v\n", err)
+			os.Exit(1)
+		}
+		pin.Path = filepath.ToSlash(vendorDir)
+	} else if _, err := cacheCatalog(data.FS, catalog.Version); err != nil {
+		fmt.Printf("⚠️  Could not cache catalog: %v\n", err)
+	}
+
+	if err := updateProjectSettings(projectPath, "catalog", pin); err != nil {
+		fmt.Printf("❌ %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("📌 Pinned catalog %s (%s) in %s\n", catalog.Version, catalog.Hash, filepath.Join(projectPath, parascan.SettingsFile))
+}
+
+// handleCatalogTest shows the blast radius of a modified catalog per project
+func handleCatalogTest(args []string) {
+	var catalogDir string
+	var against stringsFlag
+	skipVerify := false
+
+	flags := newCommandFlags("catalog test", showCatalogHelp)
+	flags.StringVar(&catalogDir, "catalog", "", "")
+	flags.Var(&against, "against", "")
+	flags.BoolVar(&skipVerify, "insecure-skip-verify", false, "")
+	args = flags.parse(args)
+
+	projects, err := catalogTestProjects(against, args)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		os.Exit(1)
+	}
+	if catalogDir == "" || len(projects) == 0 {
+		showCatalogHelp()
+		os.Exit(1)
+	}
+
+	globalConfig, err := loadGlobalConfig()
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		os.Exit(1)
+	}
+	embedded, err := parascan.EmbeddedCatalog()
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		os.Exit(1)
+	}
+	modified, err := loadCatalogDir(catalogDir, globalConfig.Trust, skipVerify)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		os.Exit(1)
+	}
+
+	changedProjects := 0
+	for _, diff := range testCatalog(os.Stdout, embedded, modified, projects) {
+		if !diff.empty() {
+			changedProjects++
+		}
+	}
+
+	fmt.Printf("\n✨ %d of %d project(s) would change with catalog %s\n", changedProjects, len(projects), catalogDir)
+}
+
+// catalogTestProjects lists the projects of para catalog test: every subdirectory of
+// the --against directories, then the projects given as arguments
+func catalogTestProjects(against, args []string) ([]string, error) {
+	var projects []string
+	for _, dir := range against {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if entry.IsDir() {
+				projects = append(projects, filepath.Join(dir, entry.Name()))
+			}
+		}
+	}
+	return append(projects, args...), nil
+}
+
+// testCatalog scans each project with the embedded and the modified catalog and
+// prints what the modified one adds, removes and changes. It returns the diffs in
+// the order of the projects.
+func testCatalog(w io.Writer, embedded, modified *parascan.Catalog, projects []string) []ResultsDiff {
+	diffs := make([]ResultsDiff, 0, len(projects))
+	for _, project := range projects {
+		before := runScan(project, embedded, ScanOptions{SkipGit: true}).Results
+		after := runScan(project, modified, ScanOptions{SkipGit: true}).Results
+		diff := diffResults(before, after)
+		diffs = append(diffs, diff)
+
+		if diff.empty() {
+			fmt.Fprintf(w, "📦 %s: no changes\n", project)
+			continue
+		}
+
+		fmt.Fprintf(w, "📦 %s:\n", project)
+		for _, key := range diff.Added {
+			fmt.Fprintf(w, "  + %s → %s\n", key, after[key])
+		}
+		for _, key := range diff.Removed {
+			fmt.Fprintf(w, "  - %s → %s\n", key, before[key])
+		}
+		for _, key := range diff.Changed {
+			fmt.Fprintf(w, "  ~ %s: %s → %s\n", key, before[key], after[key])
+		}
+	}
+	return diffs
+}
+
+// ResultsDiff lists keys that differ between two detection results
+type ResultsDiff struct {
+	Added   []string `json:"added,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+	Changed []string `json:"changed,omitempty"`
+}
+
+func (d ResultsDiff) empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// diffResults compares detection results by key, sorted for stable output
+func diffResults(before, after map[string]string) ResultsDiff {
+	var diff ResultsDiff
+	for key, value := range after {
+		if oldValue, exists := before[key]; !exists {
+			diff.Added = append(diff.Added, key)
+		} else if oldValue != value {
+			diff.Changed = append(diff.Changed, key)
+		}
+	}
+	for key := range before {
+		if _, exists := after[key]; !exists {
+			diff.Removed = append(diff.Removed, key)
+		}
+	}
+	sort.Strings(diff.Added)
+	sort.Strings(diff.Removed)
+	sort.Strings(diff.Changed)
+	return diff
+}
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"parascan/data"
+	"parascan/pkg/parascan"
+)
+
+func TestCatalogExportAndPin(t *testing.T) {
+	embedded, err := parascan.EmbeddedCatalog()
+	if err != nil {
+		t.Fatalf("Failed to load embedded catalog: %v", err)
+	}
+	if embedded.Version == "" {
+		t.Error("Expected embedded catalog to have a version")
+	}
+
+	// An exported catalog has the same content hash as the embedded one
+	projectPath := t.TempDir()
+	if err := exportCatalog(data.FS, filepath.Join(projectPath, "vendor-catalog")); err != nil {
+		t.Fatalf("Failed to export catalog: %v", err)
+	}
+	exported, err := loadCatalogDir(filepath.Join(projectPath, "vendor-catalog"), TrustPolicy{}, false)
+	if err != nil {
+		t.Fatalf("Failed to load exported catalog: %v", err)
+	}
+	if exported.Hash != embedded.Hash || exported.Version != embedded.Version {
+		t.Errorf("Expected exported catalog %s (%s), got %s (%s)", embedded.Version, embedded.Hash, exported.Version, exported.Hash)
+	}
+
+	settings := &parascan.ProjectSettings{Catalog: parascan.CatalogPin{Version: embedded.Version, Hash: embedded.Hash, Path: "vendor-catalog"}}
+	catalog, warnings, err := resolveCatalog(projectPath, "", settings, TrustPolicy{}, false)
+	if err != nil || len(warnings) > 0 {
+		t.Fatalf("Expected pinned catalog to resolve cleanly, got %v %v", warnings, err)
+	}
+	if catalog.Source != filepath.Join(projectPath, "vendor-catalog") {
+		t.Errorf("Expected vendored catalog to be used, got %s", catalog.Source)
+	}
+
+	// A modified vendored catalog no longer matches the pinned hash
+	os.WriteFile(filepath.Join(projectPath, "vendor-catalog", "services", "stripe.yml"), []byte("name: Stripe\n"), 0644)
+	if _, _, err := resolveCatalog(projectPath, "", settings, TrustPolicy{}, true); err == nil {
+		t.Error("Expected pin mismatch error for modified catalog")
+	}
+}
+
+// embeddedCatalogHash is the content hash of the embedded catalog at
+// embeddedCatalogVersion. Cached catalogs and pins are keyed by version, so data
+// changes have to bump the version in data/catalog.yml; update both constants then.
+const (
+	embeddedCatalogVersion = "2026.10.1"
+	embeddedCatalogHash    = "sha256:8dccd24e261bb419777a2b4044d2e87f088c75ef59c7fd3e678dde7ad0aebd16"
+)
+
+func TestEmbeddedCatalogVersionBumped(t *testing.T) {
+	embedded, err := parascan.EmbeddedCatalog()
+	if err != nil {
+		t.Fatalf("Failed to load embedded catalog: %v", err)
+	}
+	if embedded.Hash != embeddedCatalogHash && embedded.Version == embeddedCatalogVersion {
+		t.Errorf("Catalog data changed (%s) without a version bump, bump version in data/catalog.yml past %s", embedded.Hash, embeddedCatalogVersion)
+	} else if embedded.Version != embeddedCatalogVersion || embedded.Hash != embeddedCatalogHash {
+		t.Errorf("Expected catalog %s (%s), got %s (%s), update the pinned version and hash", embeddedCatalogVersion, embeddedCatalogHash, embedded.Version, embedded.Hash)
+	}
+}
+
+func TestCompareCatalogVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"2025.10.1", "2025.10.1", 0},
+		{"2025.9.0", "2025.10.1", -1},
+		{"2026.1", "2025.12.3", 1},
+		{"2025.10", "2025.10.0", 0},
+	}
+	for _, tt := range tests {
+		if got := compareCatalogVersions(tt.a, tt.b); got != tt.want {
+			t.Errorf("compareCatalogVersions(%s, %s) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestFindDeprecations(t *testing.T) {
+	catalog, err := parascan.EmbeddedCatalog()
+	if err != nil {
+		t.Fatalf("Failed to load catalog: %v", err)
+	}
+
+	results := map[string]string{
+		"Travis CI": "https://travis-ci.com",
+		"opsgenie":  "https://app.opsgenie.com",
+		"stripe":    "https://dashboard.stripe.com",
+	}
+	deprecations := parascan.FindDeprecations(catalog, results)
+	if len(deprecations) != 2 || deprecations[0].Service != "Opsgenie" || deprecations[1].Successor != "GitHub Actions" {
+		t.Errorf("Expected Opsgenie and Travis CI deprecations, got %+v", deprecations)
+	}
+
+	// Disabled services aren't flagged, renamed ones use the project's name
+	projectPath := filepath.Join("testdata", "oncall-project")
+	scan := runScan(projectPath, catalog, ScanOptions{SkipGit: true, Settings: &parascan.ProjectSettings{Names: map[string]string{"Opsgenie": "Paging (legacy)"}}})
+	if len(scan.Deprecations) != 1 || scan.Deprecations[0].Key != "Paging (legacy)" {
+		t.Errorf("Expected renamed Opsgenie deprecation, got %+v", scan.Deprecations)
+	}
+	scan = runScan(projectPath, catalog, ScanOptions{SkipGit: true, Settings: &parascan.ProjectSettings{DisabledServices: []string{"opsgenie"}}})
+	if len(scan.Deprecations) != 0 {
+		t.Errorf("Expected no deprecations for disabled services, got %+v", scan.Deprecations)
+	}
+}
+
+func TestCatalogRules(t *testing.T) {
+	skipWithoutPack(t, "package.json")
+	projectPath := filepath.Join("testdata", "rules-project")
+
+	// The user's rules are merged first, the project's win
+	configHome := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configHome)
+	userRules := filepath.Join(configHome, "parascope", "rules", "services")
+	os.MkdirAll(userRules, 0755)
+	os.WriteFile(filepath.Join(userRules, "acme-billing.yml"), []byte("name: Acme Billing\nurl: https://billing.example.com\nstacks:\n  nodejs:\n  - \"@acme/billing-client\"\n"), 0644)
+	os.WriteFile(filepath.Join(userRules, "acme-pager.yml"), []byte("name: Acme Pager\nurl: https://pager.acme.internal\nstacks:\n  nodejs:\n  - \"@acme/pager\"\n"), 0644)
+
+	catalog, _, err := resolveCatalog(projectPath, "", &parascan.ProjectSettings{}, TrustPolicy{}, false)
+	if err != nil {
+		t.Fatalf("Failed to resolve catalog: %v", err)
+	}
+	if len(catalog.Rules) != 2 || catalog.Rules[1] != filepath.Join(projectPath, ".parascope", "rules") {
+		t.Errorf("Expected user and project rules, got %v", catalog.Rules)
+	}
+	if _, found := catalog.Services["acme-pager"]; !found {
+		t.Errorf("Expected the user's acme-pager service in the catalog")
+	}
+	embedded, _ := parascan.EmbeddedCatalog()
+	if catalog.Hash != embedded.Hash || len(embedded.Rules) > 0 || embedded.Services["stripe"].URL != "https://dashboard.stripe.com" {
+		t.Errorf("Expected rules to leave the embedded catalog and its hash alone")
+	}
+
+	scan := runScan(projectPath, catalog, ScanOptions{SkipGit: true})
+	expected := map[string]string{
+		"acme-billing": "https://billing.acme.internal",     // project rule over the user's
+		"acme-ledger":  "https://ledger.acme.internal",      // dependency file added to nodejs
+		"stripe":       "https://dashboard.stripe.com/acme", // overridden catalog service
+		"Acme Flags":   "https://flags.acme.internal",       // added file detector
+	}
+	for key, url := range expected {
+		if scan.Results[key] != url {
+			t.Errorf("Expected %s for %s, got %v", url, key, scan.Results)
+		}
+	}
+
+	// Unsigned rules are refused when signatures are required, like catalogs
+	policy := TrustPolicy{RequireSignatures: true}
+	if _, _, err := resolveCatalog(projectPath, "", &parascan.ProjectSettings{}, policy, false); err == nil || !strings.Contains(err.Error(), checksumsFile) {
+		t.Errorf("Expected unsigned rules refused, got %v", err)
+	}
+	if _, _, err := resolveCatalog(projectPath, "", &parascan.ProjectSettings{}, policy, true); err != nil {
+		t.Errorf("Expected --insecure-skip-verify to merge unsigned rules, got %v", err)
+	}
+
+	// A broken rule fails instead of silently detecting less
+	os.WriteFile(filepath.Join(userRules, "broken.yml"), []byte("name: [unclosed\n"), 0644)
+	if _, _, err := resolveCatalog(projectPath, "", &parascan.ProjectSettings{}, TrustPolicy{}, false); err == nil {
+		t.Error("Expected an error for a broken rule")
+	}
+}
+
+func TestCatalogTest(t *testing.T) {
+	skipWithoutPack(t, "package.json")
+	embedded, err := parascan.EmbeddedCatalog()
+	if err != nil {
+		t.Fatalf("Failed to load embedded catalog: %v", err)
+	}
+
+	// Stripe loses its stripe package, Twilio moves, a new service claims axios
+	catalogDir := filepath.Join(t.TempDir(), "catalog")
+	if err := exportCatalog(data.FS, catalogDir); err != nil {
+		t.Fatalf("Failed to export catalog: %v", err)
+	}
+	edit := func(name, old, new string) {
+		t.Helper()
+		path := filepath.Join(catalogDir, "services", name)
+		content, err := os.ReadFile(path)
+		if err != nil || !strings.Contains(string(content), old) {
+			t.Fatalf("Expected %q in %s (%v)", old, name, err)
+		}
+		if err := os.WriteFile(path, []byte(strings.ReplaceAll(string(content), old, new)), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	edit("stripe.yml", "  - stripe\n", "")
+	edit("twilio.yml", "url: https://console.twilio.com\n", "url: https://console.twilio.com/us1\n")
+	acmePay := "name: AcmePay\nurl: https://pay.acme.example.com\nstacks:\n  nodejs:\n  - axios\n"
+	if err := os.WriteFile(filepath.Join(catalogDir, "services", "acmepay.yml"), []byte(acmePay), 0644); err != nil {
+		t.Fatal(err)
+	}
+	modified, err := loadCatalogDir(catalogDir, TrustPolicy{}, true)
+	if err != nil {
+		t.Fatalf("Failed to load the modified catalog: %v", err)
+	}
+
+	// Subdirectories of --against are projects, files in it aren't, arguments follow
+	against := t.TempDir()
+	for _, dir := range []string{"docs", "shop"} {
+		if err := os.MkdirAll(filepath.Join(against, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	packageJSON, err := os.ReadFile(filepath.Join("testdata", "nodejs-project", "package.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(against, "shop", "package.json"), packageJSON, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(against, "README.md"), []byte("# Fixtures\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	nodejsProject := filepath.Join("testdata", "nodejs-project")
+	projects, err := catalogTestProjects([]string{against}, []string{nodejsProject})
+	expectedProjects := []string{filepath.Join(against, "docs"), filepath.Join(against, "shop"), nodejsProject}
+	if err != nil || !reflect.DeepEqual(projects, expectedProjects) {
+		t.Fatalf("Expected projects %v, got %v (%v)", expectedProjects, projects, err)
+	}
+	if _, err := catalogTestProjects([]string{filepath.Join(against, "missing")}, nil); err == nil {
+		t.Errorf("Expected a missing --against directory to fail")
+	}
+
+	var output bytes.Buffer
+	diffs := testCatalog(&output, embedded, modified, projects)
+	changed := ResultsDiff{Added: []string{"acmepay"}, Removed: []string{"stripe"}, Changed: []string{"twilio"}}
+	expected := []ResultsDiff{{}, changed, changed}
+	if !reflect.DeepEqual(diffs, expected) {
+		t.Fatalf("Expected diffs %+v, got %+v\n%s", expected, diffs, output.String())
+	}
+	for _, line := range []string{
+		filepath.Join(against, "docs") + ": no changes",
+		"  + acmepay → https://pay.acme.example.com",
+		"  - stripe → https://dashboard.stripe.com",
+		"  ~ twilio: https://console.twilio.com → https://console.twilio.com/us1",
+	} {
+		if !strings.Contains(output.String(), line) {
+			t.Errorf("Expected %q in the report, got:\n%s", line, output.String())
+		}
+	}
+}
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"sort"
+
+	"parascan/pkg/parascan"
+)
+
+// CatalogUsage is the catalog-usage report of para catalog usage: which catalog
+// entries fired across a batch of repositories, to prune dead entries and spot gaps
+type CatalogUsage struct {
+	Catalog           string              `json:"catalog"` // version of the measured catalog
+	Repos             int                 `json:"repos"`   // repositories scanned
+	Errors            map[string]string   `json:"errors,omitempty"`
+	Entries           []EntryUsage        `json:"entries"`            // most detected first
+	NeverDetected     []string            `json:"never_detected"`     // keys of entries no repository has
+	UnmatchedPackages []PackageUsage      `json:"unmatched_packages"` // packages of detected services no repository depends on
+	Packages          map[string][]string `json:"-"`                  // matched packages by service key, for the Markdown report
+}
+
+// EntryUsage counts the repositories a catalog entry was detected in
+type EntryUsage struct {
+	Key   string `json:"key"`
+	Name  string `json:"name"`
+	Kind  string `json:"kind"` // service, or file for the technologies of file detectors
+	Repos int    `json:"repos"`
+}
+
+// PackageUsage is a package the catalog lists for a service
+type PackageUsage struct {
+	Service  string `json:"service"`
+	Language string `json:"language"`
+	Package  string `json:"package"`
+}
+
+// repoDetections is what a repository's scan tells about catalog usage: its
+// results and the evidence behind them
+type repoDetections struct {
+	Results  map[string]string
+	Evidence map[string][]parascan.ServiceEvidence
+	Error    string
+}
+
+// detectRepoUsage scans a repository with the measured catalog rather than its own
+// pin, so every repository counts against the same entries. Its settings still
+// exclude paths.
+func detectRepoUsage(repo string, catalog *parascan.Catalog) (detections repoDetections) {
+	// A panicking detector fails its repository, not the whole run
+	defer func() {
+		if r := recover(); r != nil {
+			detections = repoDetections{Error: fmt.Sprint(r)}
+		}
+	}()
+	if info, err := os.Stat(repo); err != nil {
+		return repoDetections{Error: err.Error()}
+	} else if !info.IsDir() {
+		return repoDetections{Error: fmt.Sprintf("%s is not a directory", repo)}
+	}
+	settings, err := parascan.LoadProjectSettings(repo)
+	if err != nil {
+		return repoDetections{Error: err.Error()}
+	}
+	scan := runScan(repo, catalog, ScanOptions{Settings: settings, SkipGit: true})
+	if err := memoryLimitError(scan); err != nil {
+		return repoDetections{Error: err.Error()}
+	}
+	for _, detectorErr := range scan.Errors {
+		if detectorErr.Detector == "scan" {
+			return repoDetections{Error: detectorErr.Err.Error()}
+		}
+	}
+	return repoDetections{Results: scan.Results, Evidence: scan.Evidence}
+}
+
+// catalogUsage counts the repositories each catalog entry was detected in and the
+// packages each service was matched by. Packages are the catalog's names found in
+// dependency files or source imports, framework config hints don't count.
+func catalogUsage(catalog *parascan.Catalog, repos []string, detections []repoDetections) CatalogUsage {
+	usage := CatalogUsage{Catalog: catalog.Version, Packages: make(map[string][]string)}
+	counts := make(map[string]int)          // by service key, or "file:" and technology key
+	matched := make(map[PackageUsage]bool)  // packages of any repository
+	technologies := make(map[string]string) // technology key by result key
+	if catalog.FileDetectors != nil {
+		for key, tech := range catalog.FileDetectors.Technologies {
+			technologies[tech.DisplayName] = key
+			technologies[key] = key
+		}
+	}
+
+	for i, repo := range detections {
+		if repo.Error != "" {
+			if usage.Errors == nil {
+				usage.Errors = make(map[string]string)
+			}
+			usage.Errors[repos[i]] = repo.Error
+			continue
+		}
+		usage.Repos++
+		fired := make(map[string]bool)
+		// Results don't tell which detector reported them, a key that is both a
+		// service and a technology counts for both
+		for key := range repo.Results {
+			if _, ok := catalog.Services[key]; ok {
+				fired[key] = true
+			}
+			if tech, ok := technologies[key]; ok {
+				fired["file:"+tech] = true
+			}
+		}
+		for entry := range fired {
+			counts[entry]++
+		}
+		for key, evidence := range repo.Evidence {
+			for _, e := range evidence {
+				if _, ok := catalog.Services[key]; ok && e.Language != "" && e.Package != "" {
+					matched[PackageUsage{Service: key, Language: e.Language, Package: e.Package}] = true
+				}
+			}
+		}
+	}
+
+	for key, service := range catalog.Services {
+		usage.Entries = append(usage.Entries, EntryUsage{Key: key, Name: service.Name, Kind: "service", Repos: counts[key]})
+	}
+	if catalog.FileDetectors != nil {
+		for key, tech := range catalog.FileDetectors.Technologies {
+			usage.Entries = append(usage.Entries, EntryUsage{Key: key, Name: tech.DisplayName, Kind: "file", Repos: counts["file:"+key]})
+		}
+	}
+	sort.Slice(usage.Entries, func(i, j int) bool {
+		a, b := usage.Entries[i], usage.Entries[j]
+		if a.Repos != b.Repos {
+			return a.Repos > b.Repos
+		}
+		if a.Kind != b.Kind {
+			return a.Kind > b.Kind
+		}
+		return a.Key < b.Key
+	})
+
+	usage.NeverDetected = []string{}
+	usage.UnmatchedPackages = []PackageUsage{}
+	for _, entry := range usage.Entries {
+		if entry.Repos == 0 {
+			usage.NeverDetected = append(usage.NeverDetected, entry.Key)
+			continue
+		}
+		if entry.Kind != "service" {
+			continue
+		}
+		service := catalog.Services[entry.Key]
+		languages := make([]string, 0, len(service.Stacks))
+		for language := range service.Stacks {
+			languages = append(languages, language)
+		}
+		sort.Strings(languages)
+		listed := make(map[string]bool) // a package matched in several languages
+		for _, language := range languages {
+			for _, pkg := range service.Stacks[language] {
+				candidate := PackageUsage{Service: entry.Key, Language: language, Package: pkg}
+				if !matched[candidate] {
+					usage.UnmatchedPackages = append(usage.UnmatchedPackages, candidate)
+				} else if !listed[pkg] {
+					listed[pkg] = true
+					usage.Packages[entry.Key] = append(usage.Packages[entry.Key], pkg)
+				}
+			}
+		}
+	}
+	sort.Strings(usage.NeverDetected)
+	return usage
+}
+
+// writeCatalogUsageMarkdown writes the report for maintainers: entries by number
+// of repositories, then what never fired
+func writeCatalogUsageMarkdown(w io.Writer, usage CatalogUsage) {
+	fmt.Fprintf(w, "# Catalog usage\n\nCatalog %s across %d repositories", usage.Catalog, usage.Repos)
+	if len(usage.Errors) > 0 {
+		fmt.Fprintf(w, " (%d couldn't be scanned)", len(usage.Errors))
+	}
+	fmt.Fprintln(w, ".")
+
+	fmt.Fprintln(w, "\n## Detected\n\n| Entry | Kind | Repositories | Packages matched |\n| --- | --- | --- | --- |")
+	for _, entry := range usage.Entries {
+		if entry.Repos > 0 {
+			fmt.Fprintf(w, "| %s (`%s`) | %s | %d | %s |\n", entry.Name, entry.Key, entry.Kind, entry.Repos, joinCode(usage.Packages[entry.Key]))
+		}
+	}
+
+	fmt.Fprintf(w, "\n## Never detected\n\n%d entries no repository has:\n\n", len(usage.NeverDetected))
+	for _, key := range usage.NeverDetected {
+		fmt.Fprintf(w, "- `%s`\n", key)
+	}
+
+	fmt.Fprintf(w, "\n## Packages never matched\n\n%d packages of detected services no repository depends on:\n\n", len(usage.UnmatchedPackages))
+	for _, pkg := range usage.UnmatchedPackages {
+		fmt.Fprintf(w, "- `%s` (%s) for `%s`\n", pkg.Package, pkg.Language, pkg.Service)
+	}
+}
+
+// joinCode lists names as inline code
+func joinCode(names []string) string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = "`" + name + "`"
+	}
+	return joinNames(quoted)
+}
+
+// handleCatalogUsage scans a batch of repositories with one catalog and reports
+// which of its entries fired
+func handleCatalogUsage(args []string) {
+	var batchPath, catalogDir, outputPath string
+	format := "json"
+	parallel := runtime.NumCPU()
+	skipVerify := false
+
+	flags := newCommandFlags("catalog usage", showCatalogHelp)
+	flags.StringVar(&batchPath, "batch", "", "")
+	flags.StringVar(&catalogDir, "catalog", "", "")
+	flags.StringVar(&format, "format", format, "")
+	flags.StringVar(&format, "f", format, "")
+	flags.StringVar(&outputPath, "output", "", "")
+	flags.StringVar(&outputPath, "o", "", "")
+	flags.IntVar(&parallel, "parallel", parallel, "")
+	flags.BoolVar(&skipVerify, "insecure-skip-verify", false, "")
+	flags.maxArgs(flags.parse(args), 0)
+	if parallel < 1 {
+		flags.fail("Invalid --parallel: %d, expected a number of at least 1", parallel)
+	}
+	if batchPath == "" {
+		flags.fail("para catalog usage needs --batch")
+	}
+	if format != "json" && format != "markdown" {
+		flags.fail("Unknown format: %s. Supported formats: json, markdown", format)
+	}
+
+	repos, err := readBatch(batchPath)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		os.Exit(1)
+	}
+	catalog, err := parascan.EmbeddedCatalog()
+	if catalogDir != "" && err == nil {
+		var globalConfig *GlobalConfig
+		if globalConfig, err = loadGlobalConfig(); err == nil {
+			catalog, err = loadCatalogDir(catalogDir, globalConfig.Trust, skipVerify)
+		}
+	}
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		os.Exit(1)
+	}
+
+	detections := make([]repoDetections, len(repos))
+	forEachRepo(len(repos), parallel, func(i int) {
+		detections[i] = detectRepoUsage(repos[i], catalog)
+	})
+	usage := catalogUsage(catalog, repos, detections)
+
+	w := os.Stdout
+	if outputPath != "" && outputPath != "-" {
+		if w, err = os.Create(outputPath); err != nil {
+			fmt.Printf("❌ %v\n", err)
+			os.Exit(1)
+		}
+		defer w.Close()
+	}
+	if format == "markdown" {
+		writeCatalogUsageMarkdown(w, usage)
+	} else {
+		data, _ := json.MarshalIndent(usage, "", "  ")
+		fmt.Fprintln(w, string(data))
+	}
+	for repo, reason := range usage.Errors {
+		fmt.Fprintf(os.Stderr, "❌ %s: %s\n", repo, reason)
+	}
+}
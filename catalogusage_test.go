@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"parascan/pkg/parascan"
+)
+
+func TestCatalogUsage(t *testing.T) {
+	skipWithoutPack(t, "package.json", "requirements.txt")
+	catalog, err := parascan.EmbeddedCatalog()
+	if err != nil {
+		t.Fatal(err)
+	}
+	repos := []string{
+		filepath.Join("testdata", "nodejs-project"),
+		filepath.Join("testdata", "python-project"),
+		filepath.Join("testdata", "missing-project"),
+	}
+	detections := make([]repoDetections, len(repos))
+	forEachRepo(len(repos), 2, func(i int) {
+		detections[i] = detectRepoUsage(repos[i], catalog)
+	})
+	usage := catalogUsage(catalog, repos, detections)
+
+	if usage.Repos != 2 || len(usage.Errors) != 1 || usage.Errors[repos[2]] == "" {
+		t.Fatalf("Expected two scanned repositories and an error for the missing one, got %d and %v", usage.Repos, usage.Errors)
+	}
+	repoCount := make(map[string]int)
+	for _, entry := range usage.Entries {
+		if entry.Kind == "service" {
+			repoCount[entry.Key] = entry.Repos
+		}
+	}
+	if repoCount["stripe"] != 2 || repoCount["firebase"] != 1 {
+		t.Errorf("Expected Stripe in both repositories and Firebase in one, got %d and %d", repoCount["stripe"], repoCount["firebase"])
+	}
+	if usage.Entries[0].Repos != 2 || usage.Entries[len(usage.Entries)-1].Repos != 0 {
+		t.Errorf("Expected entries to be sorted most detected first")
+	}
+
+	// Packages are only reported unmatched for services that were detected
+	unmatched := make(map[PackageUsage]bool)
+	for _, pkg := range usage.UnmatchedPackages {
+		unmatched[pkg] = true
+		if repoCount[pkg.Service] == 0 {
+			t.Errorf("Did not expect packages of %s, it was never detected", pkg.Service)
+		}
+	}
+	if unmatched[PackageUsage{Service: "stripe", Language: "nodejs", Package: "stripe"}] {
+		t.Errorf("Expected the stripe npm package to be matched")
+	}
+	if !unmatched[PackageUsage{Service: "stripe", Language: "go", Package: "github.com/stripe/stripe-go"}] {
+		t.Errorf("Expected the Go package of Stripe to be unmatched, got %v", usage.UnmatchedPackages)
+	}
+
+	var markdown bytes.Buffer
+	writeCatalogUsageMarkdown(&markdown, usage)
+	for _, want := range []string{"across 2 repositories (1 couldn't be scanned)", "| Stripe (`stripe`) | service | 2 |", "## Never detected", "## Packages never matched"} {
+		if !strings.Contains(markdown.String(), want) {
+			t.Errorf("Expected the report to contain %q, got:\n%s", want, markdown.String())
+		}
+	}
+}
@@ -0,0 +1,482 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v2"
+
+	"parascan/detectors"
+	"parascan/pkg/parascan"
+)
+
+// Exit codes of para check-org: 1 when repositories couldn't be scanned, the
+// matrix is written anyway
+const (
+	checkOrgExitCompliant = 0
+	checkOrgExitViolation = 2
+)
+
+// OrgPolicy is the policy file of para check-org, rules every repository is held to
+type OrgPolicy struct {
+	Rules []PolicyRule `yaml:"rules"`
+}
+
+// PolicyRule is a check of a repository's detected services, by result key or name
+// (sentry, github-actions, repo) or category. A rule with several conditions needs
+// all of them.
+type PolicyRule struct {
+	ID              string   `yaml:"id"`
+	Description     string   `yaml:"description,omitempty"`
+	RequireAny      []string `yaml:"require_any,omitempty"`      // one of them is detected, e.g. an error tracker
+	RequireAll      []string `yaml:"require_all,omitempty"`      // all of them are detected
+	RequireCategory string   `yaml:"require_category,omitempty"` // a service of the category is detected, e.g. ci
+	Forbid          []string `yaml:"forbid,omitempty"`           // none of them is detected, e.g. travis
+}
+
+// loadOrgPolicy reads a policy file, rejecting rules without an id or condition
+func loadOrgPolicy(path string) (*OrgPolicy, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var policy OrgPolicy
+	if err := yaml.UnmarshalStrict(content, &policy); err != nil {
+		return nil, fmt.Errorf("invalid policy %s: %v", path, err)
+	}
+	if len(policy.Rules) == 0 {
+		return nil, fmt.Errorf("policy %s has no rules", path)
+	}
+	ids := make(map[string]bool)
+	for i, rule := range policy.Rules {
+		switch {
+		case rule.ID == "":
+			return nil, fmt.Errorf("policy %s: rule %d has no id", path, i+1)
+		case ids[rule.ID]:
+			return nil, fmt.Errorf("policy %s: duplicate rule %s", path, rule.ID)
+		case len(rule.RequireAny) == 0 && len(rule.RequireAll) == 0 && rule.RequireCategory == "" && len(rule.Forbid) == 0:
+			return nil, fmt.Errorf("policy %s: rule %s has no condition", path, rule.ID)
+		}
+		ids[rule.ID] = true
+	}
+	return &policy, nil
+}
+
+// detectedFacts lists what a repository's results tell about it for rules, in lower
+// case: result keys, service and file detector names, and categories. File
+// detector results are keyed by display name, so Cloudflare Workers is also
+// cloudflare-workers.
+func detectedFacts(results map[string]string, catalog *parascan.Catalog) (keys, categories map[string]bool) {
+	keys, categories = make(map[string]bool), make(map[string]bool)
+	for key := range results {
+		keys[strings.ToLower(key)] = true
+		if service, ok := catalog.Services[key]; ok {
+			keys[strings.ToLower(service.Name)] = true
+			categories[service.Category] = true
+		}
+		if catalog.FileDetectors == nil {
+			continue
+		}
+		for techKey, tech := range catalog.FileDetectors.Technologies {
+			if strings.EqualFold(key, techKey) || strings.EqualFold(key, tech.DisplayName) {
+				keys[strings.ToLower(techKey)] = true
+				keys[strings.ToLower(tech.DisplayName)] = true
+				categories[tech.Category] = true
+			}
+		}
+	}
+	delete(keys, "")
+	delete(categories, "")
+	return keys, categories
+}
+
+// passes evaluates the rule against what a repository's results tell, see detectedFacts
+func (rule PolicyRule) passes(keys, categories map[string]bool) bool {
+	if len(rule.RequireAny) > 0 {
+		found := false
+		for _, key := range rule.RequireAny {
+			found = found || keys[strings.ToLower(key)]
+		}
+		if !found {
+			return false
+		}
+	}
+	for _, key := range rule.RequireAll {
+		if !keys[strings.ToLower(key)] {
+			return false
+		}
+	}
+	for _, key := range rule.Forbid {
+		if keys[strings.ToLower(key)] {
+			return false
+		}
+	}
+	return rule.RequireCategory == "" || categories[rule.RequireCategory]
+}
+
+// readBatch reads the repositories of a batch file, one path per line. Empty
+// lines and # comments are skipped.
+func readBatch(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var repos []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" && !strings.HasPrefix(line, "#") {
+			repos = append(repos, line)
+		}
+	}
+	return repos, scanner.Err()
+}
+
+// RepoCompliance is a row of the compliance matrix: whether the repository passes
+// each rule, or why it couldn't be scanned
+type RepoCompliance struct {
+	Repo   string
+	Passed map[string]bool // by rule id
+	Health *parascan.HealthScore
+	Error  string
+}
+
+// repoScan is what check-org learns from scanning a repository: its results, the
+// catalog they were detected with and, with --health, its inventory completeness
+type repoScan struct {
+	Results map[string]string
+	Catalog *parascan.Catalog
+	Health  *parascan.HealthScore
+}
+
+// repoScanner scans a repository for check-org
+type repoScanner func(repo string) (*repoScan, error)
+
+// checkOrg evaluates the policy for every repository, scanning up to parallel of
+// them at a time. A repository that fails to scan is reported in its row and
+// doesn't stop the others. Rows are in batch order.
+func checkOrg(repos []string, policy *OrgPolicy, parallel int, scan repoScanner) []RepoCompliance {
+	matrix := make([]RepoCompliance, len(repos))
+	forEachRepo(len(repos), parallel, func(i int) {
+		matrix[i] = checkRepo(repos[i], policy, scan)
+	})
+	return matrix
+}
+
+// forEachRepo calls do with the index of each of count repositories, up to parallel
+// at a time, and returns when all are done
+func forEachRepo(count, parallel int, do func(i int)) {
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < parallel; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				do(i)
+			}
+		}()
+	}
+	for i := 0; i < count; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+func checkRepo(repo string, policy *OrgPolicy, scan repoScanner) (row RepoCompliance) {
+	row.Repo = repo
+	// A panicking detector fails its repository, not the whole run
+	defer func() {
+		if r := recover(); r != nil {
+			row.Passed, row.Error = nil, fmt.Sprint(r)
+		}
+	}()
+	scanned, err := scan(repo)
+	if err != nil {
+		row.Error = err.Error()
+		return row
+	}
+	row.Health = scanned.Health
+	keys, categories := detectedFacts(scanned.Results, scanned.Catalog)
+	row.Passed = make(map[string]bool, len(policy.Rules))
+	for _, rule := range policy.Rules {
+		row.Passed[rule.ID] = rule.passes(keys, categories)
+	}
+	return row
+}
+
+// scanRepoForPolicy scans a repository the way para scan does, with its own settings
+// and catalog, and scores its inventory completeness with health
+func scanRepoForPolicy(trust TrustPolicy, plugins []*detectors.ExecDetector, skipGit, health bool) repoScanner {
+	return func(repo string) (*repoScan, error) {
+		if info, err := os.Stat(repo); err != nil {
+			return nil, err
+		} else if !info.IsDir() {
+			return nil, fmt.Errorf("%s is not a directory", repo)
+		}
+		settings, err := parascan.LoadProjectSettings(repo)
+		if err != nil {
+			return nil, err
+		}
+		catalog, _, err := resolveCatalog(repo, "", settings, trust, false)
+		if err != nil {
+			return nil, err
+		}
+		scan := runScan(repo, catalog, ScanOptions{Plugins: plugins, Settings: settings, SkipGit: skipGit})
+		if err := memoryLimitError(scan); err != nil {
+			return nil, err
+		}
+		for _, detectorErr := range scan.Errors {
+			if detectorErr.Detector == "scan" {
+				return nil, detectorErr.Err
+			}
+		}
+		scanned := &repoScan{Results: settings.ApplyToResults(scan.Results), Catalog: catalog}
+		if health {
+			configPath := filepath.Join(repo, "parascope.yml")
+			if settings.Output.ConfigPath != "" {
+				configPath = filepath.Join(repo, settings.Output.ConfigPath)
+			}
+			current := configCurrent(configPath, settings.Output.ProjectName, settings.RepositoryName(), scanned.Results)
+			scanned.Health = parascan.ScoreHealth(catalog, scanned.Results, current)
+		}
+		return scanned, nil
+	}
+}
+
+// complianceCell is the value of a matrix cell: pass, fail or error
+func complianceCell(row RepoCompliance, ruleID string) string {
+	switch {
+	case row.Error != "":
+		return "error"
+	case row.Passed[ruleID]:
+		return "pass"
+	default:
+		return "fail"
+	}
+}
+
+// healthCell is the inventory completeness score of a row, empty without one
+func healthCell(row RepoCompliance) string {
+	if row.Health == nil {
+		return ""
+	}
+	return strconv.Itoa(row.Health.Score)
+}
+
+// scoredHealth reports whether rows of the matrix have a health score, for the
+// column of --health
+func scoredHealth(matrix []RepoCompliance) bool {
+	for _, row := range matrix {
+		if row.Health != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// writeComplianceCSV writes one line per repository and one column per rule, then
+// the health score with --health, the last column holds scan errors
+func writeComplianceCSV(w io.Writer, policy *OrgPolicy, matrix []RepoCompliance) error {
+	writer := csv.NewWriter(w)
+	health := scoredHealth(matrix)
+	header := []string{"repo"}
+	for _, rule := range policy.Rules {
+		header = append(header, rule.ID)
+	}
+	if health {
+		header = append(header, "health")
+	}
+	if err := writer.Write(append(header, "error")); err != nil {
+		return err
+	}
+	for _, row := range matrix {
+		record := []string{row.Repo}
+		for _, rule := range policy.Rules {
+			record = append(record, complianceCell(row, rule.ID))
+		}
+		if health {
+			record = append(record, healthCell(row))
+		}
+		if err := writer.Write(append(record, row.Error)); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+var complianceHTML = template.Must(template.New("compliance").Funcs(template.FuncMap{"cell": complianceCell, "health": healthCell, "missing": missingHealth}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Compliance matrix</title>
+<style>
+body { font-family: sans-serif; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; }
+.pass { background: #d4f7d4; }
+.fail { background: #f7d4d4; }
+.error { background: #eee; color: #666; }
+.score { text-align: right; }
+</style>
+</head>
+<body>
+<h1>Compliance matrix</h1>
+<table>
+<tr><th>Repository</th>{{range .Rules}}<th title="{{.Description}}">{{.ID}}</th>{{end}}{{if .Health}}<th title="Inventory completeness, 0 to 100">health</th>{{end}}</tr>
+{{range $row := .Matrix}}<tr><td>{{$row.Repo}}</td>{{range $.Rules}}{{$cell := cell $row .ID}}<td class="{{$cell}}"{{if $row.Error}} title="{{$row.Error}}"{{end}}>{{$cell}}</td>{{end}}{{if $.Health}}<td class="score"{{with $row.Health}} title="{{missing .}}"{{end}}>{{health $row}}</td>{{end}}</tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+// missingHealth lists the criteria a health score misses, for the title of its cell
+func missingHealth(score *parascan.HealthScore) string {
+	var missing []string
+	for _, check := range score.Criteria {
+		if !check.Met {
+			missing = append(missing, check.Description)
+		}
+	}
+	if len(missing) == 0 {
+		return "Every criterion met"
+	}
+	return "Missing: " + strings.Join(missing, "; ")
+}
+
+// writeComplianceHTML writes the matrix as a standalone HTML page
+func writeComplianceHTML(w io.Writer, policy *OrgPolicy, matrix []RepoCompliance) error {
+	return complianceHTML.Execute(w, map[string]interface{}{"Rules": policy.Rules, "Matrix": matrix, "Health": scoredHealth(matrix)})
+}
+
+func handleCheckOrg() {
+	var policyPath, batchPath, outputPath string
+	var health bool
+	format := "csv"
+	parallel := runtime.NumCPU()
+
+	flags := newCommandFlags("check-org", showCheckOrgHelp)
+	flags.StringVar(&policyPath, "policy", "", "")
+	flags.StringVar(&batchPath, "batch", "", "")
+	flags.StringVar(&format, "format", format, "")
+	flags.StringVar(&format, "f", format, "")
+	flags.StringVar(&outputPath, "output", "", "")
+	flags.StringVar(&outputPath, "o", "", "")
+	flags.IntVar(&parallel, "parallel", parallel, "")
+	flags.BoolVar(&health, "health", false, "")
+	flags.maxArgs(flags.parse(os.Args[2:]), 0)
+	if parallel < 1 {
+		flags.fail("Invalid --parallel: %d, expected a number of at least 1", parallel)
+	}
+	if policyPath == "" || batchPath == "" {
+		flags.fail("para check-org needs --policy and --batch")
+	}
+	if format != "csv" && format != "html" {
+		flags.fail("Unknown format: %s. Supported formats: csv, html", format)
+	}
+
+	policy, err := loadOrgPolicy(policyPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		os.Exit(1)
+	}
+	repos, err := readBatch(batchPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		os.Exit(1)
+	}
+	globalConfig, err := loadGlobalConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		os.Exit(1)
+	}
+	plugins, err := loadPluginDetectors(globalConfig.Trust, false)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Could not load plugins: %v\n", err)
+	}
+
+	matrix := checkOrg(repos, policy, parallel, scanRepoForPolicy(globalConfig.Trust, plugins, false, health))
+
+	w := os.Stdout
+	if outputPath != "" && outputPath != "-" {
+		if w, err = os.Create(outputPath); err != nil {
+			fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if format == "html" {
+		err = writeComplianceHTML(w, policy, matrix)
+	} else {
+		err = writeComplianceCSV(w, policy, matrix)
+	}
+	if w != os.Stdout {
+		if closeErr := w.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Could not write the compliance matrix: %v\n", err)
+		os.Exit(1)
+	}
+
+	exitCode := checkOrgExitCompliant
+	for _, row := range matrix {
+		if row.Error != "" {
+			fmt.Fprintf(os.Stderr, "❌ %s: %s\n", row.Repo, row.Error)
+			exitCode = 1
+			continue
+		}
+		for _, passed := range row.Passed {
+			if !passed && exitCode == checkOrgExitCompliant {
+				exitCode = checkOrgExitViolation
+			}
+		}
+	}
+	os.Exit(exitCode)
+}
+
+func showCheckOrgHelp() {
+	fmt.Println(`Usage: para check-org --policy <policy.yml> --batch <repos.txt> [options]
+
+Scan every repository of the batch file (one path per line, # comments) and
+evaluate the policy's rules against each, writing a compliance matrix with a row
+per repository and a column per rule: pass, fail, or error when the repository
+couldn't be scanned. Repositories are scanned in parallel and one failing doesn't
+stop the others.
+
+Policy rules check detected services by key or category:
+
+  rules:
+    - id: error-tracking
+      description: Errors are reported to an error tracker
+      require_any: [sentry, rollbar, bugsnag]
+    - id: ci
+      require_category: ci
+    - id: no-travis
+      forbid: [travis]
+
+Exit codes: 0 when every repository passes, 2 when a rule fails, 1 when a
+repository couldn't be scanned or on errors.
+
+Options:
+  --format, -f     csv (default) or html
+  --output, -o     Write the matrix to a file instead of stdout
+  --parallel       Repositories scanned at a time (default: number of CPUs)
+  --health         Add a column with each repository's inventory completeness score
+
+Examples:
+  para check-org --policy policy.yml --batch repos.txt
+  para check-org --policy policy.yml --batch repos.txt -f html -o compliance.html`)
+}
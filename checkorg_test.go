@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"parascan/detectors"
+	"parascan/pluginsdk"
+)
+
+func TestCheckOrg(t *testing.T) {
+	skipWithoutPack(t, "package.json")
+	dir := t.TempDir()
+	policyPath := filepath.Join(dir, "policy.yml")
+	policy := `rules:
+  - id: payments
+    description: Payments go through Stripe
+    require_any: [stripe, paddle]
+  - id: edge
+    require_category: edge
+  - id: no-twilio
+    forbid: [twilio]
+  - id: workers
+    require_all: [cloudflare-workers, cloudflare d1]
+`
+	if err := os.WriteFile(policyPath, []byte(policy), 0644); err != nil {
+		t.Fatal(err)
+	}
+	batchPath := filepath.Join(dir, "repos.txt")
+	batch := "# payments team\n" + filepath.Join("testdata", "nodejs-project") + "\n\n" +
+		filepath.Join("testdata", "workers-project") + "\n" + filepath.Join("testdata", "missing-project") + "\n"
+	if err := os.WriteFile(batchPath, []byte(batch), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	rules, err := loadOrgPolicy(policyPath)
+	if err != nil {
+		t.Fatalf("Failed to load policy: %v", err)
+	}
+	repos, err := readBatch(batchPath)
+	if err != nil || len(repos) != 3 {
+		t.Fatalf("Expected three repositories in the batch, got %v (%v)", repos, err)
+	}
+	globalConfig, err := loadGlobalConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	matrix := checkOrg(repos, rules, 2, scanRepoForPolicy(globalConfig.Trust, nil, true, false))
+
+	// File detector results match by key or display name, regardless of case. The
+	// missing repository fails on its own, rows keep the batch order.
+	var csv bytes.Buffer
+	if err := writeComplianceCSV(&csv, rules, matrix); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(csv.String()), "\n")
+	expected := []string{
+		"repo,payments,edge,no-twilio,workers,error",
+		filepath.Join("testdata", "nodejs-project") + ",pass,fail,fail,fail,",
+		filepath.Join("testdata", "workers-project") + ",fail,pass,pass,pass,",
+	}
+	if len(lines) != 4 || strings.Join(lines[:3], "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("Expected compliance matrix:\n%s\ngot:\n%s", strings.Join(expected, "\n"), csv.String())
+	}
+	if !strings.HasPrefix(lines[3], filepath.Join("testdata", "missing-project")+",error,error,error,error,") {
+		t.Errorf("Expected error cells for the missing repository, got %s", lines[3])
+	}
+
+	var html bytes.Buffer
+	if err := writeComplianceHTML(&html, rules, matrix); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(html.String(), `<th title="Payments go through Stripe">payments</th>`) || strings.Count(html.String(), `<td class="error"`) != 4 {
+		t.Errorf("Expected an HTML matrix with rule descriptions and error cells, got:\n%s", html.String())
+	}
+
+	// With --health the scores go before the errors, the HTML cell names what's missing
+	matrix = checkOrg(repos[:2], rules, 2, scanRepoForPolicy(globalConfig.Trust, nil, true, true))
+	csv.Reset()
+	if err := writeComplianceCSV(&csv, rules, matrix); err != nil {
+		t.Fatal(err)
+	}
+	expected = []string{
+		"repo,payments,edge,no-twilio,workers,health,error",
+		filepath.Join("testdata", "nodejs-project") + ",pass,fail,fail,fail,0,",
+		filepath.Join("testdata", "workers-project") + ",fail,pass,pass,pass,0,",
+	}
+	if strings.TrimSpace(csv.String()) != strings.Join(expected, "\n") {
+		t.Errorf("Expected health scores:\n%s\ngot:\n%s", strings.Join(expected, "\n"), csv.String())
+	}
+	html.Reset()
+	if err := writeComplianceHTML(&html, rules, matrix); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(html.String(), `<th title="Inventory completeness, 0 to 100">health</th>`) || !strings.Contains(html.String(), `title="Missing: A CI system builds the project; `) {
+		t.Errorf("Expected a health column, got:\n%s", html.String())
+	}
+
+	// The config is current when it lists everything detected, hand-written entries
+	// don't matter
+	configPath := filepath.Join(dir, "parascope.yml")
+	results := map[string]string{"stripe": "https://dashboard.stripe.com"}
+	if configCurrent(configPath, "billing", "", results) {
+		t.Errorf("Expected a missing config not to be current")
+	}
+	if err := os.WriteFile(configPath, []byte("billing:\n  Payments: https://dashboard.stripe.com/\n  Notion: https://notion.so\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if !configCurrent(configPath, "billing", "", results) {
+		t.Errorf("Expected the config listing Stripe to be current")
+	}
+	results["sentry"] = "https://sentry.io"
+	if configCurrent(configPath, "billing", "", results) {
+		t.Errorf("Expected the config without Sentry not to be current")
+	}
+
+	// Rules need an id and a condition
+	for _, invalid := range []string{"rules: []\n", "rules:\n  - require_any: [stripe]\n", "rules:\n  - id: empty\n", "rules:\n  - id: typo\n    requires: [stripe]\n"} {
+		if err := os.WriteFile(policyPath, []byte(invalid), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := loadOrgPolicy(policyPath); err == nil {
+			t.Errorf("Expected policy %q to be rejected", invalid)
+		}
+	}
+}
+
+func TestCheckOrgPluginOptions(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("plugin scripts need a POSIX shell")
+	}
+	dir := t.TempDir()
+
+	// The plugin detects "owned" when the project's options say so, slowly enough
+	// for the parallel scans to overlap
+	script := `#!/bin/sh
+request=$(cat)
+sleep 0.05
+case "$request" in
+  *'"team":"owned"'*) echo '{"results": {"owned": "https://owned.example.com"}}' ;;
+  *) echo '{"results": {}}' ;;
+esac
+`
+	if err := os.WriteFile(filepath.Join(dir, "team.sh"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	plugins := []*detectors.ExecDetector{detectors.NewExecDetector(pluginsdk.Manifest{Name: "team", Command: "./team.sh"}, dir)}
+
+	var repos, expected []string
+	for i := 0; i < 16; i++ {
+		repo := filepath.Join(dir, fmt.Sprintf("repo%02d", i))
+		team, cell := "other", "fail"
+		if i%2 == 0 {
+			team, cell = "owned", "pass"
+		}
+		settings := "detectors:\n  team:\n    options:\n      team: " + team + "\n"
+		if err := os.MkdirAll(repo, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(repo, ".parascan.yml"), []byte(settings), 0644); err != nil {
+			t.Fatal(err)
+		}
+		repos = append(repos, repo)
+		expected = append(expected, repo+","+cell+",")
+	}
+	policyPath := filepath.Join(dir, "policy.yml")
+	if err := os.WriteFile(policyPath, []byte("rules:\n  - id: owned\n    require_any: [owned]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	rules, err := loadOrgPolicy(policyPath)
+	if err != nil {
+		t.Fatalf("Failed to load policy: %v", err)
+	}
+
+	// Every repository is checked with its own options, whatever is scanned alongside
+	matrix := checkOrg(repos, rules, 8, scanRepoForPolicy(TrustPolicy{}, plugins, true, false))
+	var csv bytes.Buffer
+	if err := writeComplianceCSV(&csv, rules, matrix); err != nil {
+		t.Fatal(err)
+	}
+	expected = append([]string{"repo,owned,error"}, expected...)
+	if strings.TrimSpace(csv.String()) != strings.Join(expected, "\n") {
+		t.Errorf("Expected each repository checked with its own options:\n%s\ngot:\n%s", strings.Join(expected, "\n"), csv.String())
+	}
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// commands are the subcommands of para, flags given before one are its flags
+var commands = []string{"scan", "plugin", "catalog", "cache", "lsp", "bootstrap", "onboard", "status", "diff", "validate", "list", "fix-config", "check-org", "review", "apps", "capabilities", "help"}
+
+// commandArgs moves flags given before the subcommand after it, so para -v scan .
+// is para scan -v . Arguments without a subcommand are left alone.
+func commandArgs(args []string) []string {
+	if len(args) == 0 || !strings.HasPrefix(args[0], "-") {
+		return args
+	}
+	for i, arg := range args {
+		if arg == "--" {
+			break
+		}
+		for _, command := range commands {
+			if arg == command {
+				reordered := append([]string{command}, args[:i]...)
+				return append(reordered, args[i+1:]...)
+			}
+		}
+	}
+	return args
+}
+
+// commandFlags parses the flags of a subcommand with the flag package: --name value,
+// --name=value and -n value, anywhere among the arguments. -- ends the flags.
+type commandFlags struct {
+	*flag.FlagSet
+	usage func()
+}
+
+// newCommandFlags returns the flags of a subcommand, usage shows its help
+func newCommandFlags(name string, usage func()) *commandFlags {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	flags.Usage = func() {}
+	return &commandFlags{FlagSet: flags, usage: usage}
+}
+
+// parseArgs returns the positional arguments among args, flags in between are
+// parsed. --help, -h and a help argument return flag.ErrHelp.
+func (c *commandFlags) parseArgs(args []string) ([]string, error) {
+	var positional, rest []string
+	for i, arg := range args {
+		if arg == "--" {
+			args, rest = args[:i], args[i+1:]
+			break
+		}
+	}
+	for {
+		if err := c.Parse(args); err != nil {
+			return nil, err
+		}
+		args = c.Args()
+		if len(args) == 0 {
+			break
+		}
+		if args[0] == "help" {
+			return nil, flag.ErrHelp
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+	return append(positional, rest...), nil
+}
+
+// parse is parseArgs for command handlers: help is shown and exits, bad flags
+// are reported with a pointer to the help
+func (c *commandFlags) parse(args []string) []string {
+	positional, err := c.parseArgs(args)
+	if errors.Is(err, flag.ErrHelp) {
+		c.usage()
+		os.Exit(0)
+	}
+	if err != nil {
+		c.fail("%s", flagError(err))
+	}
+	return positional
+}
+
+// fail reports wrong usage of the command and exits
+func (c *commandFlags) fail(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, "❌ "+format+"\n", args...)
+	fmt.Fprintf(os.Stderr, "Run para %s --help for usage\n", c.Name())
+	os.Exit(1)
+}
+
+// maxArgs fails the command when it got more positional arguments than it takes
+func (c *commandFlags) maxArgs(positional []string, max int) {
+	if len(positional) > max {
+		c.fail("Unexpected argument: %s", positional[max])
+	}
+}
+
+// isSet reports whether one of the names was given on the command line
+func (c *commandFlags) isSet(names ...string) bool {
+	set := false
+	c.Visit(func(f *flag.Flag) {
+		for _, name := range names {
+			if f.Name == name {
+				set = true
+			}
+		}
+	})
+	return set
+}
+
+// flagError words the errors of the flag package with the dashes flags are
+// documented with, e.g. flag provided but not defined: -fromat
+func flagError(err error) string {
+	message := err.Error()
+	for _, known := range []struct{ prefix, replacement string }{
+		{"flag provided but not defined: ", "Unknown flag: "},
+		{"flag needs an argument: ", "Missing value for "},
+	} {
+		if name := strings.TrimPrefix(message, known.prefix); name != message {
+			return known.replacement + dashed(strings.TrimLeft(name, "-"))
+		}
+	}
+	// invalid value "x" for flag -name: reason
+	if strings.HasPrefix(message, "invalid ") {
+		if value, reason, found := strings.Cut(message, ": "); found {
+			if i := strings.LastIndex(value, " -"); i >= 0 {
+				value = value[:i+1] + dashed(value[i+2:])
+			}
+			return strings.ToUpper(value[:1]) + value[1:] + ": " + reason
+		}
+	}
+	return message
+}
+
+// dashed writes a flag name as documented: -f for short names, --format for long ones
+func dashed(name string) string {
+	if len(name) == 1 {
+		return "-" + name
+	}
+	return "--" + name
+}
+
+// stringsFlag is a repeatable string flag
+type stringsFlag []string
+
+func (s *stringsFlag) String() string { return strings.Join(*s, ", ") }
+
+func (s *stringsFlag) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+// outputsFlag is the repeatable --output of para scan
+type outputsFlag []OutputTarget
+
+func (o *outputsFlag) String() string { return "" }
+
+func (o *outputsFlag) Set(value string) error {
+	target, err := parseOutputTarget(value)
+	if err != nil {
+		return err
+	}
+	*o = append(*o, target)
+	return nil
+}
+
+// byteSizeFlag is a size such as 512MB
+type byteSizeFlag uint64
+
+func (b *byteSizeFlag) String() string { return "" }
+
+func (b *byteSizeFlag) Set(value string) error {
+	size, err := parseByteSize(value)
+	if err != nil {
+		return err
+	}
+	*b = byteSizeFlag(size)
+	return nil
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"strings"
+	"testing"
+)
+
+func TestCommandArgs(t *testing.T) {
+	for _, tc := range []struct{ args, expected string }{
+		{"scan -v .", "scan -v ."},
+		{"-v scan .", "scan -v ."},
+		{"--format json scan ./app", "scan --format json ./app"},
+		{"--format=json scan", "scan --format=json"},
+		{"--stdio", "--stdio"},
+		{"-h", "-h"},
+	} {
+		if got := strings.Join(commandArgs(strings.Fields(tc.args)), " "); got != tc.expected {
+			t.Errorf("Expected %q to become %q, got %q", tc.args, tc.expected, got)
+		}
+	}
+}
+
+func TestCommandFlags(t *testing.T) {
+	var verbose bool
+	var format string
+	var excludes stringsFlag
+	flags := newCommandFlags("scan", func() {})
+	flags.BoolVar(&verbose, "verbose", false, "")
+	flags.BoolVar(&verbose, "v", false, "")
+	flags.StringVar(&format, "format", "yml-config", "")
+	flags.Var(&excludes, "exclude", "")
+
+	// Flags between arguments, --flag=value and values that look like paths
+	positional, err := flags.parseArgs([]string{"./my project", "--format=json", "-v", "--exclude", "-tmp/*", "--exclude=a=b", "--", "--not-a-flag"})
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+	if strings.Join(positional, "|") != "./my project|--not-a-flag" {
+		t.Errorf("Expected the path and the argument after --, got %q", positional)
+	}
+	if !verbose || format != "json" || strings.Join(excludes, "|") != "-tmp/*|a=b" {
+		t.Errorf("Expected all flags parsed, got %v %q %q", verbose, format, excludes)
+	}
+	if !flags.isSet("format", "f") || flags.isSet("exclude-all") {
+		t.Errorf("Expected --format to be set")
+	}
+
+	for _, args := range [][]string{{"--help"}, {"-h"}, {".", "help"}} {
+		if _, err := flags.parseArgs(args); !errors.Is(err, flag.ErrHelp) {
+			t.Errorf("Expected help for %q, got %v", args, err)
+		}
+	}
+
+	for args, message := range map[string]string{
+		"--fromat json": "Unknown flag: --fromat",
+		"--format":      "Missing value for --format",
+		"-v=maybe":      `Invalid boolean value "maybe" for -v: parse error`,
+	} {
+		_, err := flags.parseArgs(strings.Fields(args))
+		if err == nil || flagError(err) != message {
+			t.Errorf("Expected %q for %q, got %v", message, args, err)
+		}
+	}
+}
@@ -0,0 +1,8 @@
+// Package data holds the embedded catalog: stack dependency files, file detectors,
+// services, developer tooling, attention rules, the health rubric and catalog metadata
+package data
+
+import "embed"
+
+//go:embed *.yml services/*.yml
+var FS embed.FS
@@ -0,0 +1,200 @@
+package detectors
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Documentation files scanned for service mentions and badges
+var docsPatterns = []string{"README*", "Readme*", "readme*", "docs/*.md", "docs/*/*.md", "doc/*.md"}
+
+// docFile is a documentation file with its path relative to the project
+type docFile struct {
+	Path    string
+	Content string
+}
+
+// readDocs reads the project's documentation files sorted by path
+func readDocs(projectPath string, filter *PathFilter) []docFile {
+	seen := make(map[string]bool)
+	var docs []docFile
+	for _, pattern := range docsPatterns {
+		for _, path := range filter.Glob(projectPath, pattern) {
+			if seen[path] {
+				continue
+			}
+			seen[path] = true
+			content, err := os.ReadFile(path)
+			if err != nil {
+				continue
+			}
+			rel, err := filepath.Rel(projectPath, path)
+			if err != nil {
+				rel = path
+			}
+			docs = append(docs, docFile{Path: filepath.ToSlash(rel), Content: string(content)})
+		}
+	}
+	sort.Slice(docs, func(i, j int) bool { return docs[i].Path < docs[j].Path })
+	return docs
+}
+
+// DocsFinding is a service mentioned in documentation. Docs describe the stack
+// but prove nothing, so findings are low confidence on their own.
+type DocsFinding struct {
+	Key     string // result key
+	File    string // relative to the project
+	Keyword string // service name or badge URL that matched
+}
+
+// DocsDetector infers services from README and docs: catalog service names and
+// badges of file-detected technologies (e.g. Codecov coverage badges).
+// It is meant for repos without manifests, e.g. archived ones.
+type DocsDetector struct {
+	services map[string]*ServiceInfo
+	files    *FilesDetector
+	findings []DocsFinding
+}
+
+func NewDocsDetector(services map[string]*ServiceInfo, files *FilesDetector) *DocsDetector {
+	return &DocsDetector{
+		services: services,
+		files:    files,
+	}
+}
+
+func (d *DocsDetector) Name() string {
+	return "docs"
+}
+
+// Findings returns what the last Detect call matched, sorted by key
+func (d *DocsDetector) Findings() []DocsFinding {
+	return d.findings
+}
+
+func (d *DocsDetector) Detect(ctx *DetectionContext) (map[string]string, error) {
+	results := make(map[string]string)
+	d.findings = nil
+
+	docs := readDocs(ctx.ProjectPath, ctx.Filter)
+	if len(docs) == 0 {
+		return results, nil
+	}
+
+	found := func(key, value, path, keyword string) {
+		if _, exists := findResultKey(ctx.Results, key); exists {
+			return
+		}
+		if _, exists := results[key]; exists {
+			return
+		}
+		results[key] = value
+		d.findings = append(d.findings, DocsFinding{Key: key, File: path, Keyword: keyword})
+	}
+
+	// Badges are the strongest hint docs can give: they link to the project's dashboard
+	if d.files != nil {
+		techKeys := make([]string, 0, len(d.files.data.Technologies))
+		for techKey := range d.files.data.Technologies {
+			techKeys = append(techKeys, techKey)
+		}
+		sort.Strings(techKeys)
+
+		for _, techKey := range techKeys {
+			tech := d.files.data.Technologies[techKey]
+			displayName := tech.DisplayName
+			if displayName == "" {
+				displayName = techKey
+			}
+			if _, exists := findResultKey(ctx.Results, techKey); exists {
+				continue
+			}
+			if badge, ok := matchBadge(tech.Badges, docs); ok {
+				url := badge.URL
+				if url == "" {
+					url, _ = d.files.buildURL(tech, techKey, ctx)
+				}
+				found(displayName, url, badge.File, badge.Match)
+			}
+		}
+	}
+
+	for key, service := range d.services {
+		if _, exists := findResultKey(ctx.Results, service.Name); exists {
+			continue
+		}
+		re := serviceNamePattern(service.Name)
+		if re == nil {
+			continue
+		}
+		for _, doc := range docs {
+			if match := re.FindString(doc.Content); match != "" {
+				found(key, service.URL, doc.Path, match)
+				break
+			}
+		}
+	}
+
+	sort.Slice(d.findings, func(i, j int) bool { return d.findings[i].Key < d.findings[j].Key })
+	return results, nil
+}
+
+// serviceNamePattern matches a service name as a whole word, ignoring case and
+// separators ("Hugging_face" matches "Hugging Face")
+func serviceNamePattern(name string) *regexp.Regexp {
+	words := strings.FieldsFunc(name, func(r rune) bool {
+		return r == '_' || r == '-' || r == ' '
+	})
+	if len(words) == 0 || len(strings.Join(words, "")) < 3 {
+		return nil
+	}
+	for i, word := range words {
+		words[i] = regexp.QuoteMeta(word)
+	}
+	return regexp.MustCompile(`(?i)\b` + strings.Join(words, `[\s_-]?`) + `\b`)
+}
+
+// badgeMatch is a badge found in the docs
+type badgeMatch struct {
+	File  string
+	Match string // matched badge URL
+	URL   string // deep link built from the badge, empty if the badge has no url
+}
+
+// matchBadge finds the first badge in the docs, preferring badges that yield a deep link
+func matchBadge(badges []Badge, docs []docFile) (badgeMatch, bool) {
+	var first badgeMatch
+	matched := false
+	for _, badge := range badges {
+		re, err := regexp.Compile(badge.Pattern)
+		if err != nil {
+			continue
+		}
+		for _, doc := range docs {
+			groups := re.FindStringSubmatch(doc.Content)
+			if groups == nil {
+				continue
+			}
+			result := badgeMatch{File: doc.Path, Match: groups[0]}
+			if badge.URL != "" {
+				values := make(map[string]string)
+				for i, name := range re.SubexpNames() {
+					if name != "" && groups[i] != "" {
+						values[name] = groups[i]
+					}
+				}
+				if url, ok := expandTemplate(badge.URL, values); ok {
+					result.URL = url
+					return result, true
+				}
+			}
+			if !matched {
+				first, matched = result, true
+			}
+		}
+	}
+	return first, matched
+}
@@ -0,0 +1,186 @@
+package detectors
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// DocsSiteKey is the result key of the project's published documentation site
+const DocsSiteKey = "docs_site"
+
+// GitHubPagesName is the result key of GitHub Pages, as the github-pages file detector names it
+const GitHubPagesName = "GitHub Pages"
+
+// CNAME files of GitHub Pages sites: the repository root, /docs as the publishing
+// source, and the static directories of Docusaurus and Vite builds
+var cnameFiles = []string{"CNAME", "docs/CNAME", "static/CNAME", "public/CNAME", "website/static/CNAME"}
+
+var (
+	docusaurusConfigs = []string{"docusaurus.config.js", "docusaurus.config.ts", "docusaurus.config.mjs", "website/docusaurus.config.js", "website/docusaurus.config.ts"}
+	docusaurusURL     = regexp.MustCompile(`(?m)^\s*url:\s*['"](https?://[^'"]+)['"]`)
+	docusaurusBaseURL = regexp.MustCompile(`(?m)^\s*baseUrl:\s*['"]([^'"]+)['"]`)
+	readTheDocsFiles  = []string{".readthedocs.yaml", ".readthedocs.yml", "readthedocs.yml"}
+	readTheDocsSlug   = regexp.MustCompile(`(?:https?://)?([\w-]+)\.readthedocs\.io|readthedocs\.org/projects/([\w-]+)`)
+)
+
+// DocsSiteDetector finds where the project's documentation is published, from the
+// first source that tells: a CNAME file, the url of the Docusaurus config, the
+// github.io address of a repository publishing GitHub Pages (gh-pages branch or
+// pages workflow) or a Read the Docs project named in the README.
+type DocsSiteDetector struct{}
+
+var _ Detector = (*DocsSiteDetector)(nil)
+
+func NewDocsSiteDetector() *DocsSiteDetector {
+	return &DocsSiteDetector{}
+}
+
+func (d *DocsSiteDetector) Name() string {
+	return DocsSiteKey
+}
+
+func (d *DocsSiteDetector) Detect(ctx *DetectionContext) (map[string]string, error) {
+	results := make(map[string]string)
+	repoURL := ctx.Results[RepoKey]
+
+	// A branch isn't a file the github-pages file detector can match
+	ghPagesBranch := hasGitBranch(ctx.ProjectPath, "gh-pages")
+	if ghPagesBranch && strings.Contains(repoURL, "github.com") {
+		results[GitHubPagesName] = repoURL + "/settings/pages"
+	}
+
+	if site := cnameSite(ctx.ProjectPath, ctx.Filter); site != "" {
+		results[DocsSiteKey] = site
+	} else if site := docusaurusSite(ctx.ProjectPath, ctx.Filter); site != "" {
+		results[DocsSiteKey] = site
+	} else if site := githubPagesSite(repoURL); site != "" && (ghPagesBranch || hasPagesWorkflow(ctx.ProjectPath, ctx.Filter)) {
+		results[DocsSiteKey] = site
+	} else if site := readTheDocsSite(ctx.ProjectPath, ctx.Filter); site != "" {
+		results[DocsSiteKey] = site
+	}
+	return results, nil
+}
+
+// cnameSite returns the custom domain of a CNAME file as an https URL
+func cnameSite(projectPath string, filter *PathFilter) string {
+	for _, name := range cnameFiles {
+		if filter.Skip(name) {
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(projectPath, filepath.FromSlash(name)))
+		if err != nil {
+			continue
+		}
+		domain, _, _ := strings.Cut(strings.TrimSpace(string(content)), "\n")
+		domain = strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(domain), "https://"), "http://"), "/")
+		if domain != "" && !strings.ContainsAny(domain, " /") {
+			return "https://" + domain
+		}
+	}
+	return ""
+}
+
+// docusaurusSite returns the url and baseUrl of a Docusaurus config
+func docusaurusSite(projectPath string, filter *PathFilter) string {
+	for _, name := range docusaurusConfigs {
+		if filter.Skip(name) {
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(projectPath, filepath.FromSlash(name)))
+		if err != nil {
+			continue
+		}
+		match := docusaurusURL.FindSubmatch(content)
+		if match == nil {
+			continue
+		}
+		site := strings.TrimSuffix(string(match[1]), "/")
+		if base := docusaurusBaseURL.FindSubmatch(content); base != nil {
+			site += "/" + strings.Trim(string(base[1]), "/")
+		}
+		return strings.TrimSuffix(site, "/")
+	}
+	return ""
+}
+
+// githubPagesSite returns the github.io address of a GitHub repository's Pages:
+// https://acme.github.io/widgets, or https://acme.github.io for acme/acme.github.io
+func githubPagesSite(repoURL string) string {
+	if !strings.Contains(repoURL, "github.com") {
+		return ""
+	}
+	owner, name, ok := strings.Cut(repoSlug(repoURL), "/")
+	if !ok || owner == "" || name == "" {
+		return ""
+	}
+	owner = strings.ToLower(owner)
+	if strings.EqualFold(name, owner+".github.io") {
+		return "https://" + owner + ".github.io"
+	}
+	return "https://" + owner + ".github.io/" + name
+}
+
+// hasPagesWorkflow reports whether a GitHub Actions workflow deploys to Pages
+func hasPagesWorkflow(projectPath string, filter *PathFilter) bool {
+	for _, pattern := range []string{".github/workflows/*.yml", ".github/workflows/*.yaml"} {
+		for _, path := range filter.Glob(projectPath, pattern) {
+			if fileContainsAny(path, []string{"actions/deploy-pages", "peaceiris/actions-gh-pages", "JamesIves/github-pages-deploy-action"}) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// readTheDocsSite returns the readthedocs.io address of a project configured for
+// Read the Docs whose docs link to it or show its badge
+func readTheDocsSite(projectPath string, filter *PathFilter) string {
+	configured := false
+	for _, name := range readTheDocsFiles {
+		if _, err := os.Stat(filepath.Join(projectPath, name)); err == nil && !filter.Skip(name) {
+			configured = true
+		}
+	}
+	if !configured {
+		return ""
+	}
+	for _, doc := range readDocs(projectPath, filter) {
+		if match := readTheDocsSlug.FindStringSubmatch(doc.Content); match != nil {
+			return "https://" + strings.ToLower(match[1]+match[2]) + ".readthedocs.io"
+		}
+	}
+	return ""
+}
+
+// hasGitBranch reports whether the project's git repository has a local or
+// remote-tracking branch, loose or packed, without running git
+func hasGitBranch(projectPath, branch string) bool {
+	gitDir := filepath.Join(projectPath, ".git")
+	if _, err := os.Stat(filepath.Join(gitDir, "refs", "heads", branch)); err == nil {
+		return true
+	}
+	if matches, _ := filepath.Glob(filepath.Join(gitDir, "refs", "remotes", "*", branch)); len(matches) > 0 {
+		return true
+	}
+
+	file, err := os.Open(filepath.Join(gitDir, "packed-refs"))
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		ref := fields[1]
+		if ref == "refs/heads/"+branch || (strings.HasPrefix(ref, "refs/remotes/") && strings.HasSuffix(ref, "/"+branch)) {
+			return true
+		}
+	}
+	return false
+}
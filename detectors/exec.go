@@ -0,0 +1,118 @@
+package detectors
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"parascan/pluginsdk"
+)
+
+const execDetectorTimeout = 30 * time.Second
+
+// ExecDetector runs an external plugin speaking the pluginsdk stdio protocol
+type ExecDetector struct {
+	manifest pluginsdk.Manifest
+	command  string
+	options  map[string]string
+}
+
+// Ensure ExecDetector implements Detector
+var _ Detector = (*ExecDetector)(nil)
+
+// NewExecDetector creates a detector for a plugin whose manifest lives in manifestDir
+func NewExecDetector(manifest pluginsdk.Manifest, manifestDir string) *ExecDetector {
+	// Relative paths are resolved against the manifest, bare names are looked up on PATH
+	command := manifest.Command
+	if !filepath.IsAbs(command) && strings.Contains(command, "/") {
+		command = filepath.Join(manifestDir, filepath.FromSlash(command))
+	}
+	return &ExecDetector{
+		manifest: manifest,
+		command:  command,
+	}
+}
+
+// WithOptions returns a copy of the detector passing project-level options to the
+// plugin with every request. The detector itself is left as it is, so scans of
+// projects with different options can share it.
+func (e *ExecDetector) WithOptions(options map[string]string) *ExecDetector {
+	copied := *e
+	copied.options = options
+	return &copied
+}
+
+// Command returns the resolved plugin executable
+func (e *ExecDetector) Command() string {
+	return e.command
+}
+
+func (e *ExecDetector) Name() string {
+	return e.manifest.Name
+}
+
+// Phase returns 2 for plugins that need results of other detectors, 1 otherwise
+func (e *ExecDetector) Phase() int {
+	if e.manifest.Phase != 0 {
+		return e.manifest.Phase
+	}
+	if len(e.manifest.Needs) > 0 {
+		return 2
+	}
+	return 1
+}
+
+func (e *ExecDetector) Detect(ctx *DetectionContext) (map[string]string, error) {
+	req := pluginsdk.Request{
+		Protocol:    pluginsdk.ProtocolVersion,
+		ProjectPath: ctx.ProjectPath,
+		Languages:   ctx.Languages,
+		Options:     e.options,
+	}
+	if absPath, err := filepath.Abs(ctx.ProjectPath); err == nil {
+		req.ProjectPath = absPath
+	}
+
+	// Only pass the results the plugin declared it needs
+	for _, key := range e.manifest.Needs {
+		if value, ok := ctx.Results[key]; ok {
+			if req.Results == nil {
+				req.Results = make(map[string]string)
+			}
+			req.Results[key] = value
+		}
+	}
+
+	input, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	runCtx, cancel := context.WithTimeout(context.Background(), execDetectorTimeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(runCtx, e.command)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	runErr := cmd.Run()
+
+	var resp pluginsdk.Response
+	if err := json.Unmarshal(stdout.Bytes(), &resp); err != nil {
+		if runErr != nil {
+			return nil, fmt.Errorf("plugin failed: %v %s", runErr, strings.TrimSpace(stderr.String()))
+		}
+		return nil, fmt.Errorf("invalid plugin response: %v", err)
+	}
+	if resp.Error != "" {
+		return nil, fmt.Errorf("plugin error: %s", resp.Error)
+	}
+
+	return resp.Results, nil
+}
@@ -0,0 +1,221 @@
+package detectors
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FileRule is an entry of a technology's files: a path pattern, or patterns combined
+// with AND, OR, NOT and parentheses for technologies a single file would detect
+// too eagerly, e.g. "Dockerfile AND fly.toml" or "*.tf AND NOT .terraform.lock.hcl".
+// NOT binds tightest and AND tighter than OR.
+type FileRule struct {
+	op       string // AND, OR or NOT, empty for a pattern
+	pattern  string
+	operands []*FileRule
+}
+
+// ParseFileRule parses an entry of a technology's files
+func ParseFileRule(rule string) (*FileRule, error) {
+	var tokens []string
+	for _, field := range strings.Fields(rule) {
+		for strings.HasPrefix(field, "(") {
+			tokens = append(tokens, "(")
+			field = field[1:]
+		}
+		closing := 0
+		for strings.HasSuffix(field, ")") {
+			closing++
+			field = field[:len(field)-1]
+		}
+		if field != "" {
+			tokens = append(tokens, field)
+		}
+		for ; closing > 0; closing-- {
+			tokens = append(tokens, ")")
+		}
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("empty file rule")
+	}
+
+	p := &fileRuleParser{tokens: tokens}
+	parsed, err := p.or()
+	if err != nil {
+		return nil, fmt.Errorf("file rule %q: %v", rule, err)
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("file rule %q: unexpected %s", rule, p.tokens[p.pos])
+	}
+	if !parsed.findsFile() {
+		return nil, fmt.Errorf("file rule %q: every alternative needs a pattern outside NOT", rule)
+	}
+	return parsed, nil
+}
+
+// findsFile reports whether every match of the rule has a file to report, that is
+// a pattern outside NOT
+func (r *FileRule) findsFile() bool {
+	switch r.op {
+	case "":
+		return true
+	case "NOT":
+		return false
+	case "OR":
+		for _, operand := range r.operands {
+			if !operand.findsFile() {
+				return false
+			}
+		}
+		return true
+	}
+	for _, operand := range r.operands {
+		if operand.findsFile() {
+			return true
+		}
+	}
+	return false
+}
+
+type fileRuleParser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *fileRuleParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+// or parses operands joined by OR
+func (p *fileRuleParser) or() (*FileRule, error) {
+	return p.joined("OR", p.and)
+}
+
+// and parses operands joined by AND
+func (p *fileRuleParser) and() (*FileRule, error) {
+	return p.joined("AND", p.operand)
+}
+
+func (p *fileRuleParser) joined(op string, next func() (*FileRule, error)) (*FileRule, error) {
+	first, err := next()
+	if err != nil {
+		return nil, err
+	}
+	operands := []*FileRule{first}
+	for p.peek() == op {
+		p.pos++
+		operand, err := next()
+		if err != nil {
+			return nil, err
+		}
+		operands = append(operands, operand)
+	}
+	if len(operands) == 1 {
+		return first, nil
+	}
+	return &FileRule{op: op, operands: operands}, nil
+}
+
+// operand parses a pattern, a negated operand or a parenthesized rule
+func (p *fileRuleParser) operand() (*FileRule, error) {
+	token := p.peek()
+	p.pos++
+	switch token {
+	case "":
+		return nil, fmt.Errorf("missing a pattern at the end")
+	case "NOT":
+		operand, err := p.operand()
+		if err != nil {
+			return nil, err
+		}
+		return &FileRule{op: "NOT", operands: []*FileRule{operand}}, nil
+	case "(":
+		rule, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ")" {
+			return nil, fmt.Errorf("missing )")
+		}
+		p.pos++
+		return rule, nil
+	case "AND", "OR", ")":
+		return nil, fmt.Errorf("expected a pattern, got %s", token)
+	}
+	return &FileRule{pattern: token}, nil
+}
+
+// Patterns returns the patterns a match of the rule may be found by, those not
+// under NOT
+func (r *FileRule) Patterns() []string {
+	switch r.op {
+	case "":
+		return []string{r.pattern}
+	case "NOT":
+		return nil
+	}
+	var patterns []string
+	for _, operand := range r.operands {
+		patterns = append(patterns, operand.Patterns()...)
+	}
+	return patterns
+}
+
+// Match evaluates the rule with find, which returns the file a pattern matches.
+// It returns the file and pattern of the match: the first matched one not under
+// NOT. find is told whether the pattern is negated.
+func (r *FileRule) Match(find func(pattern string, negated bool) (string, bool)) (string, string, bool) {
+	return r.match(find, false)
+}
+
+func (r *FileRule) match(find func(pattern string, negated bool) (string, bool), negated bool) (string, string, bool) {
+	switch r.op {
+	case "":
+		file, ok := find(r.pattern, negated)
+		return file, r.pattern, ok
+	case "NOT":
+		_, _, ok := r.operands[0].match(find, !negated)
+		return "", "", !ok
+	case "OR":
+		for _, operand := range r.operands {
+			if file, pattern, ok := operand.match(find, negated); ok {
+				return file, pattern, true
+			}
+		}
+		return "", "", false
+	}
+	file, pattern := "", ""
+	for _, operand := range r.operands {
+		operandFile, operandPattern, ok := operand.match(find, negated)
+		if !ok {
+			return "", "", false
+		}
+		if file == "" {
+			file, pattern = operandFile, operandPattern
+		}
+	}
+	return file, pattern, true
+}
+
+// RulePatterns returns the patterns of a technology's files, each rule's patterns
+// not under NOT, in order and without duplicates. Invalid rules have none.
+func RulePatterns(files []string) []string {
+	seen := make(map[string]bool)
+	var patterns []string
+	for _, file := range files {
+		rule, err := ParseFileRule(file)
+		if err != nil {
+			continue
+		}
+		for _, pattern := range rule.Patterns() {
+			if !seen[pattern] {
+				seen[pattern] = true
+				patterns = append(patterns, pattern)
+			}
+		}
+	}
+	return patterns
+}
@@ -1,10 +1,13 @@
 package detectors
 
 import (
+	"gopkg.in/yaml.v3"
+	"net/url"
 	"os"
 	"path/filepath"
+	"regexp"
+	"sort"
 	"strings"
-	"gopkg.in/yaml.v3"
 )
 
 // FileDetectors содержит конфигурацию для детекции технологий по файлам
@@ -14,17 +17,68 @@ type FileDetectors struct {
 
 // TechnologyConfig описывает конфигурацию детекции технологии
 type TechnologyConfig struct {
-	DisplayName  string   `yaml:"display_name"`
-	Category     string   `yaml:"category,omitempty"`
-	HostingMatch string   `yaml:"hosting_match,omitempty"`
-	Files        []string `yaml:"files"`
-	URLTemplate  string   `yaml:"url_template,omitempty"`
-	FallbackURL  string   `yaml:"fallback_url,omitempty"`
+	DisplayName  string         `yaml:"display_name"`
+	Category     string         `yaml:"category,omitempty"`
+	HostingMatch string         `yaml:"hosting_match,omitempty"`
+	Files        []string       `yaml:"files"`               // path patterns, or rules combining them such as "Dockerfile AND fly.toml", see FileRule
+	Contains     []string       `yaml:"contains,omitempty"`  // matched files (not directories) must include one of these strings
+	Languages    []string       `yaml:"languages,omitempty"` // detected only in projects of one of these languages, e.g. ruby for *.gemspec
+	Variables    []URLVariable  `yaml:"variables,omitempty"`
+	URLTemplate  string         `yaml:"url_template,omitempty"` // {repo}, {org} and other RepositoryVariables and variables, used when all of them are known
+	FallbackURL  string         `yaml:"fallback_url,omitempty"`
+	Deprecated   bool           `yaml:"deprecated,omitempty"`
+	Successor    string         `yaml:"successor,omitempty"` // what to migrate to when deprecated
+	Badges       []Badge        `yaml:"badges,omitempty"`    // status badges in README and docs
+	Outdated     []OutdatedRule `yaml:"outdated,omitempty"`  // configuration the technology no longer supports
+}
+
+// OutdatedRule flags configuration a technology has removed or deprecated, e.g. a
+// CI runner image that no longer exists. Rules are checked once the technology is
+// detected.
+type OutdatedRule struct {
+	Files   []string `yaml:"files,omitempty"`   // defaults to the technology's files
+	Pattern string   `yaml:"pattern,omitempty"` // regexp matched line by line, the file itself is outdated without one
+	Message string   `yaml:"message"`
+}
+
+// ConfigWarning is an outdated rule matched in a project file
+type ConfigWarning struct {
+	Key     string // result key
+	File    string // relative to the project
+	Line    int    // 1-based, 0 for the file as a whole
+	Message string
+}
+
+// URLVariable extracts a value for url_template from project files (e.g. a CMS space ID).
+// Several variables may share a name as alternative sources, the first found is used.
+type URLVariable struct {
+	Name    string   `yaml:"name"`
+	Files   []string `yaml:"files,omitempty"` // defaults to the technology's files
+	Pattern string   `yaml:"pattern"`         // regexp, the first group is the value
+}
+
+// Badge is a README status badge. Badges corroborate file detection and often
+// carry the exact project slug, so they are a URL source for deep links.
+type Badge struct {
+	Pattern string `yaml:"pattern"`       // regexp, named groups are url variables
+	URL     string `yaml:"url,omitempty"` // e.g. https://app.codecov.io/gh/{slug}
+}
+
+var templatePlaceholder = regexp.MustCompile(`\{(\w+)\}`)
+
+// FileMatch is the file a technology was detected by and the catalog pattern it matched
+type FileMatch struct {
+	Key     string // result key
+	File    string // relative to the project
+	Pattern string
 }
 
 // FilesDetector detects technologies based on file presence
 type FilesDetector struct {
-	data *FileDetectors
+	data     *FileDetectors
+	badges   []DocsFinding
+	warnings []ConfigWarning
+	matches  []FileMatch
 }
 
 func NewFilesDetector(data *FileDetectors) *FilesDetector {
@@ -37,86 +91,381 @@ func (f *FilesDetector) Name() string {
 	return "files"
 }
 
+// Badges returns the badges that corroborated results of the last Detect call, sorted by key
+func (f *FilesDetector) Badges() []DocsFinding {
+	return f.badges
+}
+
+// Matches returns the files that detected the results of the last Detect call, one
+// per result, sorted by key
+func (f *FilesDetector) Matches() []FileMatch {
+	return f.matches
+}
+
+// Warnings returns the outdated configuration found by the last Detect call, sorted
+// by file and line
+func (f *FilesDetector) Warnings() []ConfigWarning {
+	return f.warnings
+}
+
 func (f *FilesDetector) Detect(ctx *DetectionContext) (map[string]string, error) {
 	results := make(map[string]string)
+	f.badges, f.warnings, f.matches = nil, nil, nil
+	docs := readDocs(ctx.ProjectPath, ctx.Filter)
 
 	// Детектируем все технологии
 	for techKey, techConfig := range f.data.Technologies {
-		if f.hasMatchingFiles(ctx.ProjectPath, techConfig.Files) {
-			url := f.buildURL(techConfig, techKey, ctx.Results)
+		if !hasLanguage(techConfig.Languages, ctx.Languages) {
+			continue
+		}
+		if file, pattern, ok := f.matchingFile(ctx.ProjectPath, techConfig.Files, techConfig.Contains, ctx.Filter); ok {
+			url, specific := f.buildURL(techConfig, techKey, ctx)
 			// Используем display_name как ключ для унификации
 			displayName := techConfig.DisplayName
 			if displayName == "" {
 				displayName = techKey
 			}
-			results[displayName] = url
+
+			// A badge in the README confirms the detection and may carry the project slug
+			badge, hasBadge := matchBadge(techConfig.Badges, docs)
+			if hasBadge && !specific && badge.URL != "" {
+				url, specific = badge.URL, true
+			}
+
+			// Services already found by packages keep their key,
+			// a project-specific URL from config files replaces the generic one
+			key := displayName
+			existingKey, found := findResultKey(ctx.Results, displayName)
+			if !found {
+				existingKey, found = findResultKey(ctx.Results, techKey)
+			}
+			if found {
+				key = existingKey
+				if specific {
+					results[key] = url
+				}
+			} else if _, detected := results[key]; !detected || specific {
+				results[key] = url
+			}
+			if hasBadge {
+				f.addBadge(DocsFinding{Key: key, File: badge.File, Keyword: badge.Match})
+			}
+			f.addMatch(FileMatch{Key: key, File: file, Pattern: pattern})
+			f.checkOutdated(techConfig, key, ctx)
 		}
 	}
 
+	sort.Slice(f.badges, func(i, j int) bool { return f.badges[i].Key < f.badges[j].Key })
+	sort.Slice(f.matches, func(i, j int) bool { return f.matches[i].Key < f.matches[j].Key })
+	sort.Slice(f.warnings, func(i, j int) bool {
+		if f.warnings[i].File != f.warnings[j].File {
+			return f.warnings[i].File < f.warnings[j].File
+		}
+		return f.warnings[i].Line < f.warnings[j].Line
+	})
 	return results, nil
 }
 
-func (f *FilesDetector) buildURL(config TechnologyConfig, technology string, contextResults map[string]string) string {
+// checkOutdated records the outdated rules of a detected technology matched in the
+// project's files, once per rule and file at the first matching line
+func (f *FilesDetector) checkOutdated(config TechnologyConfig, key string, ctx *DetectionContext) {
+	for _, rule := range config.Outdated {
+		var re *regexp.Regexp
+		if rule.Pattern != "" {
+			var err error
+			if re, err = regexp.Compile(rule.Pattern); err != nil {
+				continue
+			}
+		}
+		patterns := rule.Files
+		if len(patterns) == 0 {
+			patterns = RulePatterns(config.Files)
+		}
+		for _, pattern := range patterns {
+			for _, path := range f.matchingFiles(ctx.ProjectPath, pattern, ctx.Filter) {
+				line, ok := MatchingLine(path, re)
+				if !ok {
+					continue
+				}
+				rel, err := filepath.Rel(ctx.ProjectPath, path)
+				if err != nil {
+					continue
+				}
+				f.addWarning(ConfigWarning{Key: key, File: filepath.ToSlash(rel), Line: line, Message: rule.Message})
+			}
+		}
+	}
+}
+
+// addWarning records a warning once, entries sharing a display name check the same files
+func (f *FilesDetector) addWarning(warning ConfigWarning) {
+	for _, existing := range f.warnings {
+		if existing == warning {
+			return
+		}
+	}
+	f.warnings = append(f.warnings, warning)
+}
+
+// MatchingLine returns the 1-based line of a regular file that matches re, or 0
+// when re is nil and any file matches
+func MatchingLine(path string, re *regexp.Regexp) (int, bool) {
+	info, err := os.Stat(path)
+	if err != nil || !info.Mode().IsRegular() {
+		return 0, false
+	}
+	if re == nil {
+		return 0, true
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return 0, false
+	}
+	for i, line := range strings.Split(string(content), "\n") {
+		if re.MatchString(line) {
+			return i + 1, true
+		}
+	}
+	return 0, false
+}
+
+// addBadge records a badge once per result key, several entries can share a display name
+func (f *FilesDetector) addBadge(finding DocsFinding) {
+	for _, existing := range f.badges {
+		if existing.Key == finding.Key {
+			return
+		}
+	}
+	f.badges = append(f.badges, finding)
+}
+
+// addMatch records the first file matched for a result key
+func (f *FilesDetector) addMatch(match FileMatch) {
+	for _, existing := range f.matches {
+		if existing.Key == match.Key {
+			return
+		}
+	}
+	f.matches = append(f.matches, match)
+}
+
+// hasLanguage reports whether a technology limited to languages applies to a project
+// of the detected ones. Technologies without languages apply to every project.
+func hasLanguage(languages, detected []string) bool {
+	if len(languages) == 0 {
+		return true
+	}
+	for _, language := range languages {
+		for _, name := range detected {
+			if strings.EqualFold(language, name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// buildURL returns the technology URL and whether it was built from the template
+func (f *FilesDetector) buildURL(config TechnologyConfig, technology string, ctx *DetectionContext) (string, bool) {
 	// Get repo URL from context
-	repoURL, hasRepo := contextResults["repo"]
+	repoURL, hasRepo := ctx.Results[RepoKey]
 
 	// Try to build dynamic URL
-	if hasRepo && config.URLTemplate != "" {
+	if config.URLTemplate != "" {
 		// Check if technology matches repo hosting (data-driven)
-		if config.HostingMatch != "" && !strings.Contains(repoURL, config.HostingMatch) {
-			// Hosting doesn't match - use fallback
-			if config.FallbackURL != "" {
-				return config.FallbackURL
+		if config.HostingMatch == "" || (hasRepo && strings.Contains(repoURL, config.HostingMatch)) {
+			// Values of the project's settings win over those read from its files,
+			// which win over the repository's
+			values := make(map[string]string)
+			if hasRepo {
+				values = RepositoryVariables(repoURL, ctx.DefaultBranch)
+			}
+			for name, value := range f.extractVariables(config, ctx) {
+				values[name] = value
+			}
+			for name, value := range ctx.Variables {
+				values[name] = value
+			}
+			if url, ok := expandTemplate(config.URLTemplate, values); ok {
+				return url, true
 			}
-			return technology
 		}
-
-		// Use template if hosting matches or no hosting requirement
-		return strings.ReplaceAll(config.URLTemplate, "{repo}", repoURL)
 	}
 
 	// Fallback to documentation URL or technology name
 	if config.FallbackURL != "" {
-		return config.FallbackURL
+		return config.FallbackURL, false
 	}
 
-	return technology
+	return technology, false
 }
 
-func (f *FilesDetector) hasMatchingFiles(projectPath string, patterns []string) bool {
-	for _, pattern := range patterns {
-		if f.hasMatchingFile(projectPath, pattern) {
-			return true
+// findResultKey looks up a result key case-insensitively (e.g. "Sanity" for "sanity")
+func findResultKey(results map[string]string, name string) (string, bool) {
+	for key := range results {
+		if strings.EqualFold(key, name) {
+			return key, true
 		}
 	}
-	return false
+	return "", false
+}
+
+// extractVariables reads url_template values from the project's config files
+func (f *FilesDetector) extractVariables(config TechnologyConfig, ctx *DetectionContext) map[string]string {
+	values := make(map[string]string)
+	for _, variable := range config.Variables {
+		if _, found := values[variable.Name]; found {
+			continue
+		}
+		re, err := regexp.Compile(variable.Pattern)
+		if err != nil {
+			continue
+		}
+		patterns := variable.Files
+		if len(patterns) == 0 {
+			patterns = RulePatterns(config.Files)
+		}
+		for _, pattern := range patterns {
+			for _, path := range f.matchingFiles(ctx.ProjectPath, pattern, ctx.Filter) {
+				content, err := os.ReadFile(path)
+				if err != nil {
+					continue
+				}
+				if match := re.FindSubmatch(content); len(match) > 1 && len(match[1]) > 0 {
+					values[variable.Name] = string(match[1])
+					break
+				}
+			}
+			if _, found := values[variable.Name]; found {
+				break
+			}
+		}
+	}
+	return values
+}
+
+// RepositoryVariables are the url_template values a repository URL gives: repo (the
+// URL), repo_slug (acme/widgets), org (acme), repo_name (widgets), host
+// (github.com) and default_branch when it's known. The org is the first segment of
+// the path and the name the last, e.g. for a GitLab subgroup or an Azure project.
+func RepositoryVariables(repoURL, defaultBranch string) map[string]string {
+	values := map[string]string{RepoKey: repoURL}
+	if parsed, err := url.Parse(repoURL); err == nil && parsed.Hostname() != "" {
+		values["host"] = strings.ToLower(parsed.Hostname())
+	}
+	if slug := repoSlug(repoURL); slug != "" {
+		values["repo_slug"] = slug
+		segments := strings.Split(slug, "/")
+		if len(segments) > 1 {
+			values["org"], values["repo_name"] = segments[0], segments[len(segments)-1]
+		}
+	}
+	if defaultBranch != "" {
+		values["default_branch"] = defaultBranch
+	}
+	return values
+}
+
+// repoSlug returns the owner/name path of a repository URL (e.g. "acme/widgets")
+func repoSlug(repoURL string) string {
+	path := repoURL
+	if i := strings.Index(path, "://"); i >= 0 {
+		path = path[i+3:]
+	}
+	i := strings.Index(path, "/")
+	if i < 0 {
+		return ""
+	}
+	return strings.Trim(strings.TrimSuffix(path[i+1:], ".git"), "/")
 }
 
-func (f *FilesDetector) hasMatchingFile(dir, pattern string) bool {
+// expandTemplate replaces {name} placeholders, failing if any value is missing
+func expandTemplate(template string, values map[string]string) (string, bool) {
+	ok := true
+	url := templatePlaceholder.ReplaceAllStringFunc(template, func(placeholder string) string {
+		value, found := values[placeholder[1:len(placeholder)-1]]
+		if !found {
+			ok = false
+		}
+		return value
+	})
+	return url, ok
+}
+
+// matchingFile returns the first file of the project that matches one of the
+// rules of a technology's files, relative to the project, and the pattern it
+// matched. contains applies to the files of patterns that aren't negated.
+func (f *FilesDetector) matchingFile(projectPath string, rules, contains []string, filter *PathFilter) (string, string, bool) {
+	find := func(pattern string, negated bool) (string, bool) {
+		for _, path := range f.matchingFiles(projectPath, pattern, filter) {
+			// Directory patterns match on their own, content checks apply to files
+			if negated || len(contains) == 0 || strings.HasSuffix(pattern, "/") || fileContainsAny(path, contains) {
+				if rel, err := filepath.Rel(projectPath, path); err == nil {
+					path = filepath.ToSlash(rel)
+				}
+				return path, true
+			}
+		}
+		return "", false
+	}
+	for _, entry := range rules {
+		rule, err := ParseFileRule(entry)
+		if err != nil {
+			continue
+		}
+		if file, pattern, ok := rule.Match(find); ok {
+			return file, pattern, true
+		}
+	}
+	return "", "", false
+}
+
+func (f *FilesDetector) matchingFiles(dir, pattern string, filter *PathFilter) []string {
 	// If pattern ends with /, it's a directory check
 	if strings.HasSuffix(pattern, "/") {
+		if filter.Skip(pattern) {
+			return nil
+		}
 		dirPath := filepath.Join(dir, strings.TrimSuffix(pattern, "/"))
 		if info, err := os.Stat(dirPath); err == nil && info.IsDir() {
-			return true
+			return []string{dirPath}
 		}
-		return false
+		return nil
 	}
 
-	// If pattern contains subdirectories (e.g. "k8s/*.yml")
-	if strings.Contains(pattern, "/") {
-		matches, err := filepath.Glob(filepath.Join(dir, pattern))
-		return err == nil && len(matches) > 0
+	// If pattern contains subdirectories (e.g. "k8s/*.yml"), wildcards (e.g. "*.tf")
+	// or alternatives (e.g. "manifest{,.v3}.json")
+	if strings.ContainsAny(pattern, "/*{") {
+		return filter.Glob(dir, pattern)
 	}
 
-	// If pattern contains wildcards (e.g. "*.tf")
-	if strings.Contains(pattern, "*") {
-		matches, err := filepath.Glob(filepath.Join(dir, pattern))
-		return err == nil && len(matches) > 0
+	// Regular file
+	if filter.Skip(pattern) {
+		return nil
+	}
+	path := filepath.Join(dir, pattern)
+	if _, err := os.Stat(path); err != nil {
+		return nil
 	}
+	return []string{path}
+}
 
-	// Regular file
-	_, err := os.Stat(filepath.Join(dir, pattern))
-	return err == nil
+// fileContainsAny reports whether a regular file includes one of the strings
+func fileContainsAny(path string, needles []string) bool {
+	info, err := os.Stat(path)
+	if err != nil || info.IsDir() {
+		return false
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	for _, needle := range needles {
+		if strings.Contains(string(content), needle) {
+			return true
+		}
+	}
+	return false
 }
 
 // loadFileDetectors загружает конфигурацию детекторов из YAML файла
@@ -132,4 +481,4 @@ func loadFileDetectors(path string) (*FileDetectors, error) {
 	}
 
 	return &detectors, nil
-}
\ No newline at end of file
+}
@@ -0,0 +1,222 @@
+package detectors
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// PathFilter decides which project paths are skipped during detection
+type PathFilter struct {
+	Ignore    []string // gitignore-like patterns relative to the project root
+	Include   []string // patterns like Ignore, when set only matching paths are scanned
+	GlobDepth int      // directory levels a ** in Glob patterns spans, 0 is DefaultGlobDepth
+}
+
+// Skip reports whether the path (relative to the project root) is ignored.
+// Patterns without a slash match any path component ("fixtures"),
+// patterns with a slash match from the root ("examples/*", "legacy/").
+// As in .gitignore, "**/" matches any directory and patterns starting with
+// "!" re-include paths, the last matching pattern wins.
+// With Include, paths matching none of its patterns are skipped as well.
+// A nil filter skips nothing.
+func (f *PathFilter) Skip(rel string) bool {
+	if f == nil {
+		return false
+	}
+	components := pathComponents(rel)
+	return !f.included(components) || f.ignored(components)
+}
+
+// pathComponents splits a relative path into its slash-separated names
+func pathComponents(rel string) []string {
+	return strings.Split(filepath.ToSlash(filepath.Clean(rel)), "/")
+}
+
+// ignored reports whether the last Ignore pattern matching the path skips it
+func (f *PathFilter) ignored(components []string) bool {
+	skip := false
+	for _, pattern := range f.Ignore {
+		negate := strings.HasPrefix(pattern, "!")
+		pattern = strings.TrimPrefix(pattern, "!")
+		pattern = strings.TrimSuffix(strings.TrimPrefix(pattern, "./"), "/")
+		if pattern == "" || skip == !negate {
+			continue
+		}
+		if matchPattern(pattern, components) {
+			skip = !negate
+		}
+	}
+	return skip
+}
+
+// included reports whether the path is the project root or within Include,
+// every path is without Include patterns
+func (f *PathFilter) included(components []string) bool {
+	if len(f.Include) == 0 || (len(components) == 1 && components[0] == ".") {
+		return true
+	}
+	for _, pattern := range f.Include {
+		pattern = strings.TrimSuffix(strings.TrimPrefix(pattern, "./"), "/")
+		if pattern != "" && matchPattern(pattern, components) {
+			return true
+		}
+	}
+	return false
+}
+
+// leadsToInclude reports whether a directory outside Include may hold included
+// paths, e.g. services for services/payments/**, so walks enter it
+func (f *PathFilter) leadsToInclude(components []string) bool {
+	for _, pattern := range f.Include {
+		pattern = strings.TrimSuffix(strings.TrimPrefix(pattern, "./"), "/")
+		if !strings.Contains(pattern, "/") {
+			return true // matches a name at any depth
+		}
+		segments := strings.Split(strings.TrimPrefix(pattern, "/"), "/")
+		if leadsTo(segments, components) {
+			return true
+		}
+	}
+	return false
+}
+
+// leadsTo reports whether the pattern segments may match below the directory
+func leadsTo(segments, components []string) bool {
+	for i, component := range components {
+		if i >= len(segments) {
+			return false
+		}
+		if segments[i] == "**" {
+			return true
+		}
+		if matched, _ := path.Match(segments[i], component); !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// matchPattern matches a single ignore pattern against the path components
+func matchPattern(pattern string, components []string) bool {
+	if !strings.Contains(pattern, "/") {
+		for _, component := range components {
+			if matched, _ := path.Match(pattern, component); matched {
+				return true
+			}
+		}
+		return false
+	}
+
+	// Match the pattern against the path and each of its parent directories,
+	// from the root or, with "**/", at any directory
+	segments := strings.Split(strings.TrimPrefix(pattern, "/"), "/")
+	for i := range components {
+		if matchSegments(segments, components[:i+1]) {
+			return true
+		}
+	}
+	return false
+}
+
+// Within returns the filter for a subdirectory of the project ("." for the root):
+// patterns anchored at the root are made relative to the subdirectory or dropped
+// when they point elsewhere, the others apply as they are. Include patterns are
+// dropped when the subdirectory is included as a whole.
+func (f *PathFilter) Within(dir string) *PathFilter {
+	dir = filepath.ToSlash(filepath.Clean(dir))
+	if f == nil || dir == "." {
+		return f
+	}
+
+	prefix := dir + "/"
+	scoped := &PathFilter{GlobDepth: f.GlobDepth}
+	for _, pattern := range f.Ignore {
+		negation := ""
+		if strings.HasPrefix(pattern, "!") {
+			negation, pattern = "!", strings.TrimPrefix(pattern, "!")
+		}
+		trimmed := strings.TrimPrefix(strings.TrimPrefix(pattern, "./"), "/")
+		if strings.HasPrefix(trimmed, "**/") || !strings.Contains(strings.TrimSuffix(trimmed, "/"), "/") {
+			scoped.Ignore = append(scoped.Ignore, negation+pattern)
+		} else if strings.HasPrefix(trimmed, prefix) {
+			// Still anchored, "backend/tmp" within backend is "/tmp"
+			scoped.Ignore = append(scoped.Ignore, negation+"/"+strings.TrimPrefix(trimmed, prefix))
+		}
+	}
+
+	components := pathComponents(dir)
+	if f.included(components) {
+		return scoped
+	}
+	for _, pattern := range f.Include {
+		trimmed := strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(pattern, "./"), "/"), "/")
+		if !strings.Contains(trimmed, "/") {
+			scoped.Include = append(scoped.Include, pattern)
+			continue
+		}
+		// "services/*/api" within services/payments is "/api", from a ** on it stays as it is
+		segments := strings.Split(trimmed, "/")
+		for i, segment := range segments {
+			if segment == "**" {
+				scoped.Include = append(scoped.Include, "/"+strings.Join(segments[i:], "/"))
+				break
+			}
+			if matched, _ := path.Match(segment, components[i]); !matched {
+				break
+			}
+			if i == len(components)-1 {
+				scoped.Include = append(scoped.Include, "/"+strings.Join(segments[i+1:], "/"))
+				break
+			}
+		}
+	}
+	return scoped
+}
+
+// SkipPath is Skip for an absolute or project-prefixed path. Directories
+// leading to Include patterns aren't skipped unless ignored, their files are.
+func (f *PathFilter) SkipPath(projectPath, path string) bool {
+	if f == nil {
+		return false
+	}
+	rel, err := filepath.Rel(projectPath, path)
+	if err != nil {
+		return false
+	}
+	components := pathComponents(rel)
+	if !f.included(components) && f.leadsToInclude(components) {
+		if info, err := os.Stat(path); err == nil && info.IsDir() {
+			return f.ignored(components)
+		}
+	}
+	return f.Skip(rel)
+}
+
+// Glob matches a pattern relative to projectPath without ignored matches.
+// Patterns may use {a,b} alternatives and ** for any number of directories.
+func (f *PathFilter) Glob(projectPath, pattern string) []string {
+	depth := DefaultGlobDepth
+	if f != nil && f.GlobDepth > 0 {
+		depth = f.GlobDepth
+	}
+
+	var kept []string
+	seen := make(map[string]bool)
+	for _, expanded := range ExpandBraces(pattern) {
+		var matches []string
+		if strings.Contains(expanded, "**") {
+			matches = globStar(projectPath, expanded, depth, func(path string) bool { return f.SkipPath(projectPath, path) })
+		} else {
+			matches, _ = filepath.Glob(filepath.Join(projectPath, expanded))
+		}
+		for _, match := range matches {
+			if !seen[match] && !f.SkipPath(projectPath, match) {
+				seen[match] = true
+				kept = append(kept, match)
+			}
+		}
+	}
+	return kept
+}
@@ -0,0 +1,254 @@
+package detectors
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FrameworkHint is a service name found in a framework's own configuration
+// (e.g. config/initializers/stripe.rb). Hints are higher-confidence than package
+// matches: the service is actually configured, not just installed.
+type FrameworkHint struct {
+	Framework string
+	Hint      string // service identifier as written in the config (stripe, sentry_sdk, ses)
+	File      string
+}
+
+// FrameworkAdapter extracts service hints from a framework's canonical config
+type FrameworkAdapter interface {
+	Name() string
+	Hints(projectPath string, filter *PathFilter) []FrameworkHint
+}
+
+// DefaultFrameworkAdapters returns adapters for all supported frameworks
+func DefaultFrameworkAdapters() []FrameworkAdapter {
+	return []FrameworkAdapter{
+		&RailsAdapter{},
+		&DjangoAdapter{},
+		&LaravelAdapter{},
+		&SpringAdapter{},
+	}
+}
+
+// DetectFrameworkHints runs every adapter against the project
+func DetectFrameworkHints(projectPath string, filter *PathFilter, adapters []FrameworkAdapter) []FrameworkHint {
+	var hints []FrameworkHint
+	for _, adapter := range adapters {
+		hints = append(hints, adapter.Hints(projectPath, filter)...)
+	}
+	return hints
+}
+
+// MatchFrameworkHint finds the service a hint refers to by key, name or alias.
+// Comparison ignores case and punctuation, so "sentry-sdk" matches "sentry_sdk".
+func MatchFrameworkHint(hint string, services map[string]*ServiceInfo) (string, bool) {
+	normalized := normalizeHint(hint)
+	if normalized == "" {
+		return "", false
+	}
+
+	keys := make([]string, 0, len(services))
+	for key := range services {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		service := services[key]
+		if normalizeHint(key) == normalized || normalizeHint(service.Name) == normalized {
+			return key, true
+		}
+		for _, alias := range service.Aliases {
+			if normalizeHint(alias) == normalized {
+				return key, true
+			}
+		}
+	}
+	return "", false
+}
+
+var nonAlphanumeric = regexp.MustCompile(`[^a-z0-9]+`)
+
+func normalizeHint(hint string) string {
+	return nonAlphanumeric.ReplaceAllString(strings.ToLower(hint), "")
+}
+
+// RailsAdapter reads config/initializers/*.rb and Active Storage services
+type RailsAdapter struct{}
+
+func (r *RailsAdapter) Name() string {
+	return "rails"
+}
+
+var railsStorageService = regexp.MustCompile(`(?m)^\s*service:\s*(\w+)`)
+
+func (r *RailsAdapter) Hints(projectPath string, filter *PathFilter) []FrameworkHint {
+	if !fileExists(filepath.Join(projectPath, "config", "application.rb")) {
+		return nil
+	}
+
+	var hints []FrameworkHint
+	for _, path := range filter.Glob(projectPath, "config/initializers/*.rb") {
+		name := strings.TrimSuffix(filepath.Base(path), ".rb")
+		hints = append(hints, FrameworkHint{Framework: r.Name(), Hint: name, File: path})
+	}
+
+	storagePath := filepath.Join(projectPath, "config", "storage.yml")
+	if content, err := os.ReadFile(storagePath); err == nil && !filter.SkipPath(projectPath, storagePath) {
+		for _, match := range railsStorageService.FindAllStringSubmatch(string(content), -1) {
+			if match[1] != "Disk" && match[1] != "Mirror" {
+				hints = append(hints, FrameworkHint{Framework: r.Name(), Hint: match[1], File: storagePath})
+			}
+		}
+	}
+
+	return hints
+}
+
+// DjangoAdapter reads INSTALLED_APPS and imports of settings modules
+type DjangoAdapter struct{}
+
+func (d *DjangoAdapter) Name() string {
+	return "django"
+}
+
+var (
+	djangoInstalledApps = regexp.MustCompile(`(?s)INSTALLED_APPS\s*\+?=\s*[\[(](.*?)[\])]`)
+	pythonStringLiteral = regexp.MustCompile(`["']([\w.]+)["']`)
+	pythonImport        = regexp.MustCompile(`(?m)^\s*(?:import|from)\s+(\w+)`)
+)
+
+func (d *DjangoAdapter) Hints(projectPath string, filter *PathFilter) []FrameworkHint {
+	if !fileExists(filepath.Join(projectPath, "manage.py")) {
+		return nil
+	}
+
+	var settingsFiles []string
+	for _, pattern := range []string{"settings.py", "*/settings.py", "*/settings/*.py"} {
+		settingsFiles = append(settingsFiles, filter.Glob(projectPath, pattern)...)
+	}
+
+	var hints []FrameworkHint
+	for _, path := range settingsFiles {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		for _, block := range djangoInstalledApps.FindAllStringSubmatch(string(content), -1) {
+			for _, app := range pythonStringLiteral.FindAllStringSubmatch(block[1], -1) {
+				// django.contrib.* and other framework apps never name a service
+				if strings.HasPrefix(app[1], "django.") {
+					continue
+				}
+				hints = append(hints, FrameworkHint{Framework: d.Name(), Hint: strings.Split(app[1], ".")[0], File: path})
+			}
+		}
+		for _, module := range pythonImport.FindAllStringSubmatch(string(content), -1) {
+			hints = append(hints, FrameworkHint{Framework: d.Name(), Hint: module[1], File: path})
+		}
+	}
+	return hints
+}
+
+// LaravelAdapter reads service keys of config/services.php and filesystem drivers
+type LaravelAdapter struct{}
+
+func (l *LaravelAdapter) Name() string {
+	return "laravel"
+}
+
+var (
+	laravelServiceKey = regexp.MustCompile(`(?m)^\s{4}'([\w-]+)'\s*=>\s*\[`)
+	laravelDriver     = regexp.MustCompile(`'driver'\s*=>\s*'(\w+)'`)
+)
+
+func (l *LaravelAdapter) Hints(projectPath string, filter *PathFilter) []FrameworkHint {
+	if !fileExists(filepath.Join(projectPath, "artisan")) {
+		return nil
+	}
+
+	var hints []FrameworkHint
+	servicesPath := filepath.Join(projectPath, "config", "services.php")
+	if content, err := os.ReadFile(servicesPath); err == nil && !filter.SkipPath(projectPath, servicesPath) {
+		for _, match := range laravelServiceKey.FindAllStringSubmatch(string(content), -1) {
+			hints = append(hints, FrameworkHint{Framework: l.Name(), Hint: match[1], File: servicesPath})
+		}
+	}
+
+	filesystemsPath := filepath.Join(projectPath, "config", "filesystems.php")
+	if content, err := os.ReadFile(filesystemsPath); err == nil && !filter.SkipPath(projectPath, filesystemsPath) {
+		for _, match := range laravelDriver.FindAllStringSubmatch(string(content), -1) {
+			if match[1] != "local" && match[1] != "scoped" {
+				hints = append(hints, FrameworkHint{Framework: l.Name(), Hint: match[1], File: filesystemsPath})
+			}
+		}
+	}
+
+	return hints
+}
+
+// SpringAdapter reads top-level keys of application.yml / application.properties
+type SpringAdapter struct{}
+
+func (s *SpringAdapter) Name() string {
+	return "spring"
+}
+
+// Top-level keys owned by Spring Boot itself
+var springBuiltinKeys = map[string]bool{
+	"spring": true, "server": true, "management": true, "logging": true, "info": true, "debug": true, "trace": true,
+}
+
+func (s *SpringAdapter) Hints(projectPath string, filter *PathFilter) []FrameworkHint {
+	var hints []FrameworkHint
+	for _, pattern := range []string{"src/main/resources/application*.yml", "src/main/resources/application*.yaml"} {
+		for _, path := range filter.Glob(projectPath, pattern) {
+			content, err := os.ReadFile(path)
+			if err != nil {
+				continue
+			}
+			var config map[string]interface{}
+			if yaml.Unmarshal(content, &config) != nil {
+				continue
+			}
+			for key := range config {
+				if !springBuiltinKeys[key] {
+					hints = append(hints, FrameworkHint{Framework: s.Name(), Hint: key, File: path})
+				}
+			}
+		}
+	}
+
+	for _, path := range filter.Glob(projectPath, "src/main/resources/application*.properties") {
+		file, err := os.Open(path)
+		if err != nil {
+			continue
+		}
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") || !strings.ContainsAny(line, "=:") {
+				continue
+			}
+			key := strings.SplitN(line, ".", 2)[0]
+			if !springBuiltinKeys[key] && !strings.ContainsAny(key, "=:") {
+				hints = append(hints, FrameworkHint{Framework: s.Name(), Hint: key, File: path})
+			}
+		}
+		file.Close()
+	}
+
+	sort.Slice(hints, func(i, j int) bool { return hints[i].Hint < hints[j].Hint })
+	return hints
+}
+
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
@@ -1,13 +1,37 @@
 package detectors
 
 import (
-	"os/exec"
+	"net/url"
 	"regexp"
 	"strings"
 )
 
-// GitRepositoryDetector detects git repository information
-type GitRepositoryDetector struct{}
+// RepoKey is the result key of the repository URL. Configs written before it was
+// canonical may spell it Repository; updates merge both into one entry.
+const RepoKey = "repo"
+
+// UpstreamKey is the result key of the upstream remote's URL, e.g. the repository
+// a fork was made from
+const UpstreamKey = "upstream"
+
+// DefaultGitRemote is the remote the repository URL is read from by default
+const DefaultGitRemote = "origin"
+
+// RepositoryInfo is what the git remotes of a project tell about its repository
+type RepositoryInfo struct {
+	URL           string            // web address of the remote RepoKey reports
+	Upstream      string            // web address of the upstream remote
+	Host          string            // hosting type of URL, see RepositoryHost
+	DefaultBranch string            // of the remote, when the clone recorded it
+	Remotes       map[string]string // web address by remote name
+}
+
+// GitRepositoryDetector detects the repository URL from a git remote of the
+// scanned project, and the upstream remote's URL when there's one
+type GitRepositoryDetector struct {
+	Remote string // remote to read the URL from, DefaultGitRemote when empty
+	info   *RepositoryInfo
+}
 
 // Ensure GitRepositoryDetector implements SimpleDetector
 var _ SimpleDetector = (*GitRepositoryDetector)(nil)
@@ -16,69 +40,132 @@ func (g *GitRepositoryDetector) Name() string {
 	return "git"
 }
 
+// Detect reads the remotes from the repository files, the git binary isn't needed
 func (g *GitRepositoryDetector) Detect(projectPath string) (map[string]string, error) {
 	results := make(map[string]string)
 
-	if !isGitRepository() {
+	repo := OpenGitRepository(projectPath)
+	if repo == nil {
 		return results, nil
 	}
-
-	originURL, err := getGitOriginURL()
+	remotes, err := repo.Remotes()
 	if err != nil {
 		return results, err
 	}
 
-	if originURL != "" {
-		repoURL := convertToHTTPSURL(originURL)
-		results["repo"] = repoURL
+	remote := g.Remote
+	if remote == "" {
+		remote = DefaultGitRemote
+	}
+	info := &RepositoryInfo{Remotes: make(map[string]string)}
+	for name, remoteURL := range remotes {
+		info.Remotes[name] = convertToHTTPSURL(remoteURL)
+	}
+	g.info = info
+
+	repoURL, found := info.Remotes[remote]
+	if !found {
+		return results, errNoRemote(remote)
+	}
+	results[RepoKey] = repoURL
+	info.URL, info.Host = repoURL, RepositoryHost(repoURL)
+	info.DefaultBranch = repo.DefaultBranch(remote)
+	if upstream := info.Remotes[UpstreamKey]; upstream != "" && remote != UpstreamKey && upstream != repoURL {
+		results[UpstreamKey] = upstream
+		info.Upstream = upstream
 	}
 
 	return results, nil
 }
 
-// Helper functions for git operations
-func isGitRepository() bool {
-	cmd := exec.Command("git", "rev-parse", "--git-dir")
-	err := cmd.Run()
-	return err == nil
+// Repository returns what the last Detect read from the remotes, nil outside a
+// repository or without a detector
+func (g *GitRepositoryDetector) Repository() *RepositoryInfo {
+	if g == nil {
+		return nil
+	}
+	return g.info
 }
 
-func getGitOriginURL() (string, error) {
-	cmd := exec.Command("git", "remote", "get-url", "origin")
-	output, err := cmd.Output()
+// RepositoryURL is the web address of a git remote URL, without credentials
+func RepositoryURL(gitURL string) string {
+	return convertToHTTPSURL(gitURL)
+}
+
+// RepositoryHost names the hosting of a repository web address: github, gitlab,
+// bitbucket, codeberg, gitea or azure, "" for others. Self-hosted instances are
+// recognized by their host name, e.g. gitlab.acme.com.
+func RepositoryHost(repoURL string) string {
+	parsed, err := url.Parse(repoURL)
 	if err != nil {
-		return "", err
+		return ""
+	}
+	host := strings.ToLower(parsed.Hostname())
+	switch {
+	case host == "dev.azure.com" || strings.HasSuffix(host, ".visualstudio.com"):
+		return "azure"
+	case host == "codeberg.org":
+		return "codeberg"
 	}
-	return strings.TrimSpace(string(output)), nil
+	for _, name := range []string{"github", "gitlab", "bitbucket", "gitea"} {
+		if strings.Contains(host, name) {
+			return name
+		}
+	}
+	return ""
 }
 
+// scpLikeURL matches the short SSH form [user@]host:path, e.g. git@github.com:acme/shop.git.
+// A single letter host is a Windows drive.
+var scpLikeURL = regexp.MustCompile(`^(?:[^@/]+@)?([^:/]+):(.+)$`)
+
+// convertToHTTPSURL turns the SSH, git and HTTP(S) URLs of a remote into the web
+// address of the repository: credentials, ports of SSH and .git are dropped, and
+// Azure Repos and Bitbucket Server SSH paths are mapped to their web paths
 func convertToHTTPSURL(gitURL string) string {
 	// First sanitize the URL to remove credentials
 	gitURL = sanitizeGitURL(gitURL)
 
-	// Pattern for SSH URLs like git@github.com:user/repo.git
-	sshPattern := regexp.MustCompile(`^git@([^:]+):(.+)\.git$`)
-	if matches := sshPattern.FindStringSubmatch(gitURL); len(matches) == 3 {
-		return "https://" + matches[1] + "/" + matches[2]
+	if strings.HasPrefix(gitURL, "http://") || strings.HasPrefix(gitURL, "https://") {
+		return strings.TrimSuffix(strings.TrimSuffix(gitURL, "/"), ".git")
 	}
 
-	// Pattern for SSH URLs like git@github.com:user/repo (without .git)
-	sshPatternNoGit := regexp.MustCompile(`^git@([^:]+):(.+)$`)
-	if matches := sshPatternNoGit.FindStringSubmatch(gitURL); len(matches) == 3 {
-		return "https://" + matches[1] + "/" + matches[2]
+	var host, path string
+	if parsed, err := url.Parse(gitURL); err == nil && strings.Contains(gitURL, "://") {
+		switch parsed.Scheme {
+		case "ssh", "git+ssh", "ssh+git", "git":
+			host, path = parsed.Hostname(), parsed.Path
+		default:
+			return gitURL // local paths and file:// URLs have no web address
+		}
+	} else if matches := scpLikeURL.FindStringSubmatch(gitURL); len(matches) == 3 && len(matches[1]) > 1 {
+		host, path = matches[1], matches[2]
+	} else {
+		return gitURL
 	}
-
-	// If it's already HTTPS or HTTP, remove .git suffix if present
-	if strings.HasPrefix(gitURL, "http://") || strings.HasPrefix(gitURL, "https://") {
-		return strings.TrimSuffix(gitURL, ".git")
+	path = strings.TrimSuffix(strings.Trim(path, "/"), ".git")
+
+	lowerHost := strings.ToLower(host)
+	switch {
+	// Azure Repos: git@ssh.dev.azure.com:v3/org/project/repo, or the older
+	// org@vs-ssh.visualstudio.com:v3/org/project/repo
+	case lowerHost == "ssh.dev.azure.com" || strings.HasSuffix(lowerHost, "vs-ssh.visualstudio.com"):
+		if parts := strings.Split(path, "/"); len(parts) == 4 && parts[0] == "v3" {
+			return "https://dev.azure.com/" + parts[1] + "/" + parts[2] + "/_git/" + parts[3]
+		}
+	// Bitbucket Server and Data Center: ssh://git@bitbucket.acme.com:7999/proj/repo.git
+	case strings.Contains(lowerHost, "bitbucket") && lowerHost != "bitbucket.org":
+		if parts := strings.Split(path, "/"); len(parts) == 2 && !strings.HasPrefix(parts[0], "~") {
+			return "https://" + host + "/projects/" + strings.ToUpper(parts[0]) + "/repos/" + parts[1]
+		}
 	}
-
-	return gitURL
+	return "https://" + host + "/" + path
 }
 
+// credentials matches the user info of an HTTP(S) URL, e.g. x-access-token:TOKEN@
+var credentials = regexp.MustCompile(`^(https?://)[^@/]+@`)
+
 // sanitizeGitURL removes credentials from git URLs
 func sanitizeGitURL(gitURL string) string {
-	// Remove x-access-token:TOKEN@ from URL
-	re := regexp.MustCompile(`https://[^@]+@github\.com/`)
-	return re.ReplaceAllString(gitURL, "https://github.com/")
-}
\ No newline at end of file
+	return credentials.ReplaceAllString(gitURL, "$1")
+}
@@ -0,0 +1,253 @@
+package detectors
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// GitRepository reads the metadata of a git repository from its files, so scans
+// don't need the git binary, e.g. in containers and CI runners without it
+type GitRepository struct {
+	Dir    string // git directory: .git of a worktree, or the bare repository
+	common string // directory holding config and refs, shared by linked worktrees
+}
+
+// OpenGitRepository finds the repository holding path like git does, looking for
+// .git in path and the directories above it. path may also be a git directory
+// itself, e.g. a bare repository. Nil when path isn't in a repository.
+func OpenGitRepository(path string) *GitRepository {
+	dir, err := filepath.Abs(path)
+	if err != nil {
+		return nil
+	}
+	if isGitDir(dir) {
+		return newGitRepository(dir)
+	}
+	for {
+		dotGit := filepath.Join(dir, ".git")
+		if info, err := os.Stat(dotGit); err == nil {
+			if info.IsDir() && isGitDir(dotGit) {
+				return newGitRepository(dotGit)
+			}
+			// Linked worktrees and submodules point to their git directory
+			if gitDir := readGitDirFile(dotGit); gitDir != "" && isGitDir(gitDir) {
+				return newGitRepository(gitDir)
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil
+		}
+		dir = parent
+	}
+}
+
+func newGitRepository(gitDir string) *GitRepository {
+	repo := &GitRepository{Dir: gitDir, common: gitDir}
+	// Linked worktrees keep config and refs in the main repository's git directory
+	if data, err := os.ReadFile(filepath.Join(gitDir, "commondir")); err == nil {
+		common := strings.TrimSpace(string(data))
+		if !filepath.IsAbs(common) {
+			common = filepath.Join(gitDir, common)
+		}
+		repo.common = filepath.Clean(common)
+	}
+	return repo
+}
+
+// isGitDir reports whether dir looks like a git directory: HEAD, objects and refs
+func isGitDir(dir string) bool {
+	for _, name := range []string{"HEAD", "objects", "refs"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// readGitDirFile returns the git directory a .git file points to with "gitdir: <path>"
+func readGitDirFile(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	gitDir, found := strings.CutPrefix(strings.TrimSpace(string(data)), "gitdir:")
+	if !found {
+		return ""
+	}
+	gitDir = strings.TrimSpace(gitDir)
+	if !filepath.IsAbs(gitDir) {
+		gitDir = filepath.Join(filepath.Dir(path), gitDir)
+	}
+	return filepath.Clean(gitDir)
+}
+
+// Branch returns the branch HEAD points to, e.g. main, "" when HEAD is detached
+func (r *GitRepository) Branch() string {
+	data, err := os.ReadFile(filepath.Join(r.Dir, "HEAD"))
+	if err != nil {
+		return ""
+	}
+	ref, found := strings.CutPrefix(strings.TrimSpace(string(data)), "ref:")
+	if !found {
+		return ""
+	}
+	return strings.TrimPrefix(strings.TrimSpace(ref), "refs/heads/")
+}
+
+// RemoteURL returns the URL of a remote as git remote get-url does: the first url
+// of the remote with the url.<base>.insteadOf rewrites of the config applied
+func (r *GitRepository) RemoteURL(remote string) (string, error) {
+	remotes, err := r.Remotes()
+	if err != nil {
+		return "", err
+	}
+	remoteURL, found := remotes[remote]
+	if !found {
+		return "", errNoRemote(remote)
+	}
+	return remoteURL, nil
+}
+
+// Remotes returns the URL of every remote by name, as RemoteURL does
+func (r *GitRepository) Remotes() (map[string]string, error) {
+	config, err := readGitConfig(filepath.Join(r.common, "config"))
+	if err != nil {
+		return nil, err
+	}
+	remotes := make(map[string]string)
+	for name, urls := range config {
+		if remote, found := strings.CutPrefix(name, "remote."); found && strings.HasSuffix(remote, ".url") && len(urls) > 0 {
+			remotes[strings.TrimSuffix(remote, ".url")] = config.rewriteURL(urls[0])
+		}
+	}
+	return remotes, nil
+}
+
+// DefaultBranch returns the default branch of a remote as the clone recorded it in
+// refs/remotes/<remote>/HEAD. A bare repository's own branch is its default.
+func (r *GitRepository) DefaultBranch(remote string) string {
+	data, err := os.ReadFile(filepath.Join(r.common, "refs", "remotes", remote, "HEAD"))
+	if err != nil {
+		if config, err := readGitConfig(filepath.Join(r.common, "config")); err == nil && len(config["core.bare"]) > 0 && config["core.bare"][0] == "true" {
+			return r.Branch()
+		}
+		return ""
+	}
+	ref, found := strings.CutPrefix(strings.TrimSpace(string(data)), "ref:")
+	if !found {
+		return ""
+	}
+	return strings.TrimPrefix(strings.TrimSpace(ref), "refs/remotes/"+remote+"/")
+}
+
+func errNoRemote(remote string) error {
+	return fmt.Errorf("no such git remote: %s", remote)
+}
+
+// gitConfig holds the values of a git config file by section.subsection.key,
+// with section and key names lowercased as git compares them
+type gitConfig map[string][]string
+
+// rewriteURL applies the longest url.<base>.insteadOf prefix matching url
+func (c gitConfig) rewriteURL(url string) string {
+	base, longest := "", ""
+	for name, prefixes := range c {
+		if !strings.HasPrefix(name, "url.") || !strings.HasSuffix(name, ".insteadof") {
+			continue
+		}
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(url, prefix) && len(prefix) > len(longest) {
+				base, longest = strings.TrimSuffix(strings.TrimPrefix(name, "url."), ".insteadof"), prefix
+			}
+		}
+	}
+	if longest == "" {
+		return url
+	}
+	return base + strings.TrimPrefix(url, longest)
+}
+
+// readGitConfig parses the sections and values of a git config file. Includes
+// aren't followed, remotes and URL rewrites live in the repository's own config.
+func readGitConfig(path string) (gitConfig, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	config := make(gitConfig)
+	section := ""
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		if line[0] == '[' {
+			header, rest, found := strings.Cut(line[1:], "]")
+			if !found {
+				continue
+			}
+			section = gitConfigSection(header)
+			if line = strings.TrimSpace(rest); line == "" || line[0] == '#' || line[0] == ';' {
+				continue
+			}
+		}
+		key, value, found := strings.Cut(line, "=")
+		if !found {
+			value = "true" // a key without value is a boolean
+		}
+		name := section + "." + strings.ToLower(strings.TrimSpace(key))
+		config[name] = append(config[name], gitConfigValue(value))
+	}
+	return config, scanner.Err()
+}
+
+// gitConfigSection names a section header: remote "origin" is remote.origin, the
+// subsection keeps its case. The older remote.origin spelling is read as well.
+func gitConfigSection(header string) string {
+	name, subsection, found := strings.Cut(strings.TrimSpace(header), " ")
+	if !found {
+		if dot := strings.Index(name, "."); dot >= 0 {
+			return strings.ToLower(name[:dot]) + name[dot:]
+		}
+		return strings.ToLower(name)
+	}
+	subsection = strings.TrimSpace(subsection)
+	subsection = strings.TrimSuffix(strings.TrimPrefix(subsection, `"`), `"`)
+	subsection = strings.NewReplacer(`\"`, `"`, `\\`, `\`).Replace(subsection)
+	return strings.ToLower(name) + "." + subsection
+}
+
+// gitConfigValue unquotes a value and drops its trailing comment
+func gitConfigValue(value string) string {
+	var b strings.Builder
+	quoted := false
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case c == '"':
+			quoted = !quoted
+		case c == '\\' && i+1 < len(value):
+			i++
+			switch value[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			default:
+				b.WriteByte(value[i])
+			}
+		case (c == '#' || c == ';') && !quoted:
+			return strings.TrimSpace(b.String())
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
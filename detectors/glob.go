@@ -0,0 +1,168 @@
+package detectors
+
+import (
+	"io/fs"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultGlobDepth is how many directory levels a ** in a pattern spans by default
+const DefaultGlobDepth = 8
+
+// skippedDirs hold dependencies and build output, never the project's own files
+var skippedDirs = []string{"node_modules", "vendor", "target", "dist"}
+
+// SkipWalkDir reports whether a directory never holds the project's own files.
+// Hidden directories (.git, .venv) are skipped as well.
+func SkipWalkDir(name string) bool {
+	if strings.HasPrefix(name, ".") {
+		return true
+	}
+	for _, skipped := range skippedDirs {
+		if name == skipped {
+			return true
+		}
+	}
+	return false
+}
+
+// ExpandBraces expands {a,b} alternatives, nested ones included:
+// "*.{yml,yaml}" gives "*.yml" and "*.yaml". Unbalanced braces are kept as they are.
+func ExpandBraces(pattern string) []string {
+	start := strings.IndexByte(pattern, '{')
+	if start < 0 {
+		return []string{pattern}
+	}
+
+	depth := 0
+	var commas []int
+	for i := start; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '{':
+			depth++
+		case ',':
+			if depth == 1 {
+				commas = append(commas, i)
+			}
+		case '}':
+			depth--
+			if depth > 0 {
+				continue
+			}
+			// Without alternatives "{a}" is literal, expand what follows it
+			if len(commas) == 0 {
+				var expanded []string
+				for _, rest := range ExpandBraces(pattern[i+1:]) {
+					expanded = append(expanded, pattern[:i+1]+rest)
+				}
+				return expanded
+			}
+			var expanded []string
+			from := start + 1
+			for _, end := range append(commas, i) {
+				expanded = append(expanded, ExpandBraces(pattern[:start]+pattern[from:end]+pattern[i+1:])...)
+				from = end + 1
+			}
+			return expanded
+		}
+	}
+	return []string{pattern}
+}
+
+// matchSegments matches slash-separated path segments against pattern segments,
+// a "**" segment matches any number of directories
+func matchSegments(pattern, segments []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for len(pattern) > 0 && pattern[0] == "**" {
+				pattern = pattern[1:]
+			}
+			if len(pattern) == 0 {
+				return true
+			}
+			for i := 0; i <= len(segments); i++ {
+				if matchSegments(pattern, segments[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(segments) == 0 {
+			return false
+		}
+		if matched, _ := path.Match(pattern[0], segments[0]); !matched {
+			return false
+		}
+		pattern, segments = pattern[1:], segments[1:]
+	}
+	return len(segments) == 0
+}
+
+// globStar finds the files and directories below root matching a pattern with **,
+// searching at most depth directory levels further than the pattern's other segments.
+// Hidden and dependency directories are only entered when the pattern names them.
+func globStar(root, pattern string, depth int, skipDir func(path string) bool) []string {
+	segments := strings.Split(filepath.ToSlash(pattern), "/")
+
+	// Walk from the directory the pattern's literal segments lead to
+	base := 0
+	for base < len(segments)-1 && !strings.ContainsAny(segments[base], `*?[\`) {
+		base++
+	}
+	start := filepath.Join(root, filepath.FromSlash(strings.Join(segments[:base], "/")))
+	segments = segments[base:]
+
+	maxLevels := depth
+	named := make(map[string]bool)
+	for _, segment := range segments {
+		if segment != "**" {
+			maxLevels++
+			named[segment] = true
+		}
+	}
+
+	var matches []string
+	filepath.WalkDir(start, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || path == start {
+			return nil
+		}
+		rel, err := filepath.Rel(start, path)
+		if err != nil {
+			return nil
+		}
+		relSegments := strings.Split(filepath.ToSlash(rel), "/")
+		if d.IsDir() && ((SkipWalkDir(d.Name()) && !named[d.Name()]) || skipDir(path)) {
+			return filepath.SkipDir
+		}
+		if matchSegments(segments, relSegments) {
+			matches = append(matches, path)
+		}
+		// Nothing deeper can match within the depth
+		if d.IsDir() && len(relSegments) >= maxLevels {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	return matches
+}
+
+// MatchPath matches a slash-separated path relative to the project against a
+// pattern as ignore files do: a pattern without a slash matches the file name,
+// e.g. *.py, one with a slash the whole path, e.g. config/**/*.yml. Patterns may
+// use {a,b} alternatives.
+func MatchPath(pattern, rel string) bool {
+	segments := strings.Split(rel, "/")
+	for _, expanded := range ExpandBraces(pattern) {
+		if !strings.Contains(expanded, "/") {
+			if matched, _ := path.Match(expanded, segments[len(segments)-1]); matched {
+				return true
+			}
+			continue
+		}
+		if matchSegments(strings.Split(strings.TrimPrefix(expanded, "/"), "/"), segments) {
+			return true
+		}
+	}
+	return false
+}
@@ -2,8 +2,12 @@ package detectors
 
 // DetectionContext provides context for detectors
 type DetectionContext struct {
-	ProjectPath string
-	Results     map[string]string // results from previous detectors
+	ProjectPath   string
+	Languages     []string          // detected languages, may be guessed from source files
+	Filter        *PathFilter       // ignored paths, nil means nothing is ignored
+	Results       map[string]string // results from previous detectors
+	DefaultBranch string            // of the repository, when the clone recorded it
+	Variables     map[string]string // url_template values set by the project, over those of RepositoryVariables
 }
 
 // Detector interface for all detection plugins
@@ -0,0 +1,274 @@
+package detectors
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// KubernetesName is the result key of Kubernetes, as the kubernetes file detector names it
+const KubernetesName = "Kubernetes"
+
+// IngressPrefix starts the result keys of hosts served through an Ingress, e.g.
+// "Ingress api.acme.com"
+const IngressPrefix = "Ingress "
+
+// kubernetesMaxDepth bounds the search for manifests: overlays of Kustomize bases
+// nest a few levels deep, e.g. deploy/k8s/overlays/prod/kustomization.yaml
+const kubernetesMaxDepth = 5
+
+var (
+	manifestAPIVersion = regexp.MustCompile(`(?m)^apiVersion:`)
+	manifestKind       = regexp.MustCompile(`(?m)^kind:\s*\w`)
+)
+
+// KubernetesManifests is what the project's manifests tell about its deployment
+type KubernetesManifests struct {
+	Files      []string // manifests and kustomizations, relative to the project
+	Images     []string // container images, e.g. ghcr.io/acme/api:1.4.2
+	Hosts      []string // Ingress and HTTPRoute hostnames
+	Secrets    []string // Secrets referenced by workloads or generated by Kustomize
+	ConfigMaps []string // ConfigMaps referenced by workloads or generated by Kustomize
+}
+
+// KubernetesDetector reads raw Kubernetes manifests (YAML documents with apiVersion
+// and kind, in k8s/, manifests/ or anywhere else) and Kustomize kustomizations for
+// the images they run, the hosts their Ingresses serve and the Secrets and
+// ConfigMaps they need. Hosts other than wildcards are results of their own. Helm
+// templates aren't YAML before rendering and are skipped.
+type KubernetesDetector struct {
+	manifests KubernetesManifests
+}
+
+var _ Detector = (*KubernetesDetector)(nil)
+
+func NewKubernetesDetector() *KubernetesDetector {
+	return &KubernetesDetector{}
+}
+
+func (d *KubernetesDetector) Name() string {
+	return "kubernetes"
+}
+
+// Manifests returns what the last Detect call found, each list sorted
+func (d *KubernetesDetector) Manifests() KubernetesManifests {
+	return d.manifests
+}
+
+func (d *KubernetesDetector) Detect(ctx *DetectionContext) (map[string]string, error) {
+	results := make(map[string]string)
+	found := newManifestSet()
+
+	filepath.WalkDir(ctx.ProjectPath, func(path string, entry os.DirEntry, err error) error {
+		if err != nil || path == ctx.ProjectPath {
+			return nil
+		}
+		rel, err := filepath.Rel(ctx.ProjectPath, path)
+		if err != nil {
+			return nil
+		}
+		if entry.IsDir() {
+			if SkipWalkDir(entry.Name()) || ctx.Filter.SkipPath(ctx.ProjectPath, path) || strings.Count(rel, string(filepath.Separator))+2 > kubernetesMaxDepth {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if ext := filepath.Ext(path); (ext != ".yaml" && ext != ".yml") || ctx.Filter.SkipPath(ctx.ProjectPath, path) {
+			return nil
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil
+		}
+
+		kustomization := strings.TrimSuffix(entry.Name(), filepath.Ext(path)) == "kustomization"
+		if !kustomization && !(manifestAPIVersion.Match(content) && manifestKind.Match(content)) {
+			return nil
+		}
+		if found.read(content, kustomization) {
+			found.files[filepath.ToSlash(rel)] = true
+		}
+		return nil
+	})
+
+	d.manifests = found.manifests()
+	if len(d.manifests.Files) == 0 {
+		return results, nil
+	}
+	results[KubernetesName] = "https://kubernetes.io"
+	for _, host := range d.manifests.Hosts {
+		if !strings.HasPrefix(host, "*") {
+			results[IngressPrefix+host] = "https://" + host
+		}
+	}
+	return results, nil
+}
+
+// manifestSet collects the findings of several manifests without duplicates
+type manifestSet struct {
+	files, images, hosts, secrets, configMaps map[string]bool
+}
+
+func newManifestSet() *manifestSet {
+	return &manifestSet{
+		files:      make(map[string]bool),
+		images:     make(map[string]bool),
+		hosts:      make(map[string]bool),
+		secrets:    make(map[string]bool),
+		configMaps: make(map[string]bool),
+	}
+}
+
+func (s *manifestSet) manifests() KubernetesManifests {
+	return KubernetesManifests{
+		Files:      sortedKeys(s.files),
+		Images:     sortedKeys(s.images),
+		Hosts:      sortedKeys(s.hosts),
+		Secrets:    sortedKeys(s.secrets),
+		ConfigMaps: sortedKeys(s.configMaps),
+	}
+}
+
+// read collects the objects of a multi-document YAML file and reports whether it
+// held any. Documents after one that doesn't parse, e.g. a Helm template, are skipped.
+func (s *manifestSet) read(content []byte, kustomization bool) bool {
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	read := false
+	for {
+		var object map[string]interface{}
+		if err := decoder.Decode(&object); err != nil {
+			return read
+		}
+		if object == nil {
+			continue
+		}
+		if kustomization {
+			s.readKustomization(object)
+			read = true
+		} else if kind, _ := object["kind"].(string); kind != "" {
+			s.readObject(kind, object)
+			read = true
+		}
+	}
+}
+
+// readObject collects an object's hosts and, anywhere in its spec, the images of
+// containers and the Secrets and ConfigMaps it mounts or reads variables from
+func (s *manifestSet) readObject(kind string, object map[string]interface{}) {
+	spec, _ := object["spec"].(map[string]interface{})
+	switch kind {
+	case "Ingress":
+		for _, rule := range listOf(spec["rules"]) {
+			s.addHost(rule["host"])
+		}
+		for _, tls := range listOf(spec["tls"]) {
+			for _, host := range anyList(tls["hosts"]) {
+				s.addHost(host)
+			}
+		}
+	case "HTTPRoute", "GRPCRoute":
+		for _, host := range anyList(spec["hostnames"]) {
+			s.addHost(host)
+		}
+	}
+	s.walk(spec)
+}
+
+// walk looks for containers and Secret and ConfigMap references in a nested value
+func (s *manifestSet) walk(value interface{}) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			switch key {
+			case "containers", "initContainers":
+				for _, container := range listOf(child) {
+					if image, _ := container["image"].(string); image != "" && !templated(image) {
+						s.images[image] = true
+					}
+				}
+			case "secretRef", "secretKeyRef":
+				s.addName(s.secrets, child, "name")
+			case "configMapRef", "configMapKeyRef", "configMap":
+				s.addName(s.configMaps, child, "name")
+			case "secret":
+				s.addName(s.secrets, child, "secretName")
+			}
+			s.walk(child)
+		}
+	case []interface{}:
+		for _, child := range v {
+			s.walk(child)
+		}
+	}
+}
+
+// readKustomization collects the images a kustomization sets and the Secrets and
+// ConfigMaps it generates
+func (s *manifestSet) readKustomization(object map[string]interface{}) {
+	for _, image := range listOf(object["images"]) {
+		name, _ := image["newName"].(string)
+		if name == "" {
+			name, _ = image["name"].(string)
+		}
+		if tag, _ := image["newTag"].(string); tag != "" && name != "" {
+			name += ":" + tag
+		}
+		if name != "" {
+			s.images[name] = true
+		}
+	}
+	for _, generator := range listOf(object["secretGenerator"]) {
+		s.addName(s.secrets, generator, "name")
+	}
+	for _, generator := range listOf(object["configMapGenerator"]) {
+		s.addName(s.configMaps, generator, "name")
+	}
+}
+
+func (s *manifestSet) addHost(host interface{}) {
+	if name, _ := host.(string); name != "" && !templated(name) {
+		s.hosts[strings.ToLower(name)] = true
+	}
+}
+
+func (s *manifestSet) addName(names map[string]bool, value interface{}, field string) {
+	object, _ := value.(map[string]interface{})
+	if name, _ := object[field].(string); name != "" && !templated(name) {
+		names[name] = true
+	}
+}
+
+// templated reports whether a value is a Helm or envsubst placeholder rather than a name
+func templated(value string) bool {
+	return strings.Contains(value, "{{") || strings.Contains(value, "${")
+}
+
+// listOf returns the objects of a YAML list, skipping other items
+func listOf(value interface{}) []map[string]interface{} {
+	var objects []map[string]interface{}
+	for _, item := range anyList(value) {
+		if object, ok := item.(map[string]interface{}); ok {
+			objects = append(objects, object)
+		}
+	}
+	return objects
+}
+
+func anyList(value interface{}) []interface{} {
+	list, _ := value.([]interface{})
+	return list
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
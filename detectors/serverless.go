@@ -0,0 +1,407 @@
+package detectors
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Result keys of the deployment tools and of the function platforms they deploy to
+const (
+	ServerlessFrameworkName = "Serverless Framework"
+	AWSSAMName              = "AWS SAM"
+	AWSCDKName              = "AWS CDK"
+	PulumiName              = "Pulumi"
+)
+
+// serverlessMaxDepth bounds the search for configs: monorepos keep a service per
+// directory, e.g. services/api/serverless.yml
+const serverlessMaxDepth = 3
+
+// Function platforms by Serverless Framework provider name
+var functionPlatforms = map[string]string{
+	"aws":    "AWS Lambda",
+	"azure":  "Azure Functions",
+	"google": "Google Cloud Functions",
+}
+
+// Clouds of Pulumi providers, named like Serverless Framework providers
+var pulumiClouds = map[string]string{
+	"aws":           "aws",
+	"aws-native":    "aws",
+	"gcp":           "google",
+	"google-native": "google",
+	"azure":         "azure",
+	"azure-native":  "azure",
+}
+
+// region = "eu-west-1" and stack_name = "shop-api" in samconfig.toml
+var samconfigValue = regexp.MustCompile(`^\s*(region|stack_name)\s*=\s*"([^"]*)"`)
+
+// ServerlessProject is a project deployed with a serverless framework or
+// infrastructure toolkit, and where it deploys to
+type ServerlessProject struct {
+	Tool      string   // ServerlessFrameworkName, AWSSAMName, AWSCDKName or PulumiName
+	File      string   // config file, relative to the project
+	Name      string   // service, stack or project name
+	Provider  string   // cloud: aws, azure, google, ...
+	Region    string   // or location, as configured
+	Project   string   // Google Cloud project
+	Runtimes  []string // of functions, e.g. nodejs20.x, or the language of CDK and Pulumi programs
+	Functions int      // declared with Serverless Framework and SAM
+}
+
+// ServerlessDetector reads the configs of Serverless Framework (serverless.yml),
+// AWS SAM (template.yaml with the serverless transform, and samconfig.toml), AWS
+// CDK (cdk.json) and Pulumi (Pulumi.yaml and its stack files) for the cloud,
+// region and runtimes they deploy to. Tools and the function platforms of
+// declared functions, such as AWS Lambda, are results.
+type ServerlessDetector struct {
+	projects []ServerlessProject
+}
+
+var _ Detector = (*ServerlessDetector)(nil)
+
+func NewServerlessDetector() *ServerlessDetector {
+	return &ServerlessDetector{}
+}
+
+func (d *ServerlessDetector) Name() string {
+	return "serverless"
+}
+
+// Projects returns what the last Detect call found, by file
+func (d *ServerlessDetector) Projects() []ServerlessProject {
+	return d.projects
+}
+
+func (d *ServerlessDetector) Detect(ctx *DetectionContext) (map[string]string, error) {
+	d.projects = nil
+	filepath.WalkDir(ctx.ProjectPath, func(path string, entry os.DirEntry, err error) error {
+		if err != nil || path == ctx.ProjectPath {
+			return nil
+		}
+		rel, err := filepath.Rel(ctx.ProjectPath, path)
+		if err != nil {
+			return nil
+		}
+		if entry.IsDir() {
+			if SkipWalkDir(entry.Name()) || ctx.Filter.SkipPath(ctx.ProjectPath, path) || strings.Count(rel, string(filepath.Separator))+2 > serverlessMaxDepth {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if ctx.Filter.SkipPath(ctx.ProjectPath, path) {
+			return nil
+		}
+		if project, ok := readServerlessProject(path); ok {
+			project.File = filepath.ToSlash(rel)
+			d.projects = append(d.projects, project)
+		}
+		return nil
+	})
+	sort.Slice(d.projects, func(i, j int) bool {
+		return d.projects[i].File < d.projects[j].File
+	})
+
+	results := make(map[string]string)
+	for _, project := range d.projects {
+		if _, found := results[project.Tool]; !found {
+			results[project.Tool] = project.toolURL()
+		}
+		if platform := functionPlatforms[project.Provider]; platform != "" && project.Functions > 0 {
+			if _, found := results[platform]; !found {
+				results[platform] = project.functionsURL()
+			}
+		}
+	}
+	return results, nil
+}
+
+// readServerlessProject reads a config file of one of the tools, ok is false for
+// other files
+func readServerlessProject(path string) (project ServerlessProject, ok bool) {
+	switch name := filepath.Base(path); name {
+	case "serverless.yml", "serverless.yaml", "serverless.json":
+		return readServerlessFramework(path)
+	case "template.yaml", "template.yml":
+		return readSAMTemplate(path)
+	case "cdk.json":
+		return readCDKApp(path)
+	case "Pulumi.yaml", "Pulumi.yml":
+		return readPulumiProject(path)
+	}
+	return project, false
+}
+
+// literal is a string written out in the config, left empty for values resolved at
+// deploy time such as ${opt:region} or !Ref Runtime
+type literal string
+
+func (l *literal) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode && node.Tag == "!!str" && !templated(node.Value) {
+		*l = literal(node.Value)
+	}
+	return nil
+}
+
+// named is a name given on its own or as the name field of a map, like the
+// provider of serverless.yml or the runtime of Pulumi.yaml
+type named struct {
+	Name    literal `yaml:"name"`
+	Runtime literal `yaml:"runtime"`
+	Region  literal `yaml:"region"`
+	Project literal `yaml:"project"`
+}
+
+func (n *named) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		return node.Decode(&n.Name)
+	}
+	type plain named
+	return node.Decode((*plain)(n))
+}
+
+// serverlessConfig is the part of serverless.yml that tells where functions deploy
+type serverlessConfig struct {
+	Service   named `yaml:"service"`
+	Provider  named `yaml:"provider"`
+	Functions map[string]struct {
+		Runtime *literal `yaml:"runtime"` // nil for the provider's runtime
+	} `yaml:"functions"`
+}
+
+func readServerlessFramework(path string) (ServerlessProject, bool) {
+	var config serverlessConfig
+	if !readYAMLConfig(path, &config) {
+		return ServerlessProject{}, false
+	}
+	project := ServerlessProject{
+		Tool:      ServerlessFrameworkName,
+		Name:      string(config.Service.Name),
+		Provider:  string(config.Provider.Name),
+		Region:    string(config.Provider.Region),
+		Project:   string(config.Provider.Project),
+		Functions: len(config.Functions),
+	}
+	runtimes := map[string]bool{}
+	for _, function := range config.Functions {
+		runtime := config.Provider.Runtime
+		if function.Runtime != nil {
+			runtime = *function.Runtime
+		}
+		runtimes[string(runtime)] = true
+	}
+	if len(config.Functions) == 0 {
+		runtimes[string(config.Provider.Runtime)] = true
+	}
+	project.Runtimes = nonEmptyKeys(runtimes)
+	return project, true
+}
+
+// samTemplate is the part of a SAM template that tells its functions' runtimes
+type samTemplate struct {
+	Transform interface{} `yaml:"Transform"` // a name or a list of them
+	Globals   struct {
+		Function struct {
+			Runtime literal `yaml:"Runtime"`
+		} `yaml:"Function"`
+	} `yaml:"Globals"`
+	Resources map[string]struct {
+		Type       string `yaml:"Type"`
+		Properties struct {
+			Runtime *literal `yaml:"Runtime"` // nil for the global runtime
+		} `yaml:"Properties"`
+	} `yaml:"Resources"`
+}
+
+// readSAMTemplate reads CloudFormation templates with the serverless transform,
+// and the stack name and region of samconfig.toml next to them
+func readSAMTemplate(path string) (ServerlessProject, bool) {
+	var template samTemplate
+	if !readYAMLConfig(path, &template) {
+		return ServerlessProject{}, false
+	}
+	transforms := anyList(template.Transform)
+	if name, ok := template.Transform.(string); ok {
+		transforms = append(transforms, name)
+	}
+	serverless := false
+	for _, transform := range transforms {
+		if name, _ := transform.(string); strings.HasPrefix(name, "AWS::Serverless") {
+			serverless = true
+		}
+	}
+	if !serverless {
+		return ServerlessProject{}, false
+	}
+
+	project := ServerlessProject{Tool: AWSSAMName, Provider: "aws"}
+	runtimes := map[string]bool{}
+	for _, resource := range template.Resources {
+		if resource.Type != "AWS::Serverless::Function" && resource.Type != "AWS::Lambda::Function" {
+			continue
+		}
+		project.Functions++
+		runtime := template.Globals.Function.Runtime
+		if resource.Properties.Runtime != nil {
+			runtime = *resource.Properties.Runtime
+		}
+		runtimes[string(runtime)] = true
+	}
+	project.Runtimes = nonEmptyKeys(runtimes)
+
+	if file, err := os.Open(filepath.Join(filepath.Dir(path), "samconfig.toml")); err == nil {
+		defer file.Close()
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			if match := samconfigValue.FindStringSubmatch(scanner.Text()); match != nil {
+				if match[1] == "region" && project.Region == "" {
+					project.Region = match[2]
+				} else if match[1] == "stack_name" && project.Name == "" {
+					project.Name = match[2]
+				}
+			}
+		}
+	}
+	return project, true
+}
+
+// Languages of CDK apps by a word of the command running them
+var cdkLanguages = []struct{ word, language string }{
+	{"ts-node", "typescript"},
+	{".ts", "typescript"},
+	{"python", "python"},
+	{"mvn", "java"},
+	{"gradle", "java"},
+	{"dotnet", "csharp"},
+	{"go ", "go"},
+	{"node ", "javascript"},
+}
+
+func readCDKApp(path string) (ServerlessProject, bool) {
+	var config struct {
+		App literal `yaml:"app"`
+	}
+	if !readYAMLConfig(path, &config) || config.App == "" {
+		return ServerlessProject{}, false
+	}
+	project := ServerlessProject{Tool: AWSCDKName, Provider: "aws"}
+	for _, candidate := range cdkLanguages {
+		if strings.Contains(string(config.App), candidate.word) {
+			project.Runtimes = []string{candidate.language}
+			break
+		}
+	}
+	return project, true
+}
+
+// readPulumiProject reads Pulumi.yaml, and the cloud and region of the first stack
+// that configures a provider, e.g. aws:region in Pulumi.prod.yaml
+func readPulumiProject(path string) (ServerlessProject, bool) {
+	var config struct {
+		Name    literal `yaml:"name"`
+		Runtime named   `yaml:"runtime"`
+	}
+	if !readYAMLConfig(path, &config) || config.Name == "" {
+		return ServerlessProject{}, false
+	}
+	project := ServerlessProject{Tool: PulumiName, Name: string(config.Name)}
+	if config.Runtime.Name != "" {
+		project.Runtimes = []string{string(config.Runtime.Name)}
+	}
+
+	stacks, _ := filepath.Glob(filepath.Join(filepath.Dir(path), "Pulumi.*.y*ml"))
+	sort.Strings(stacks)
+	for _, stack := range stacks {
+		var stackConfig struct {
+			Config map[string]literal `yaml:"config"`
+		}
+		if !readYAMLConfig(stack, &stackConfig) {
+			continue
+		}
+		keys := make([]string, 0, len(stackConfig.Config))
+		for key := range stackConfig.Config {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			provider, setting, _ := strings.Cut(key, ":")
+			cloud := pulumiClouds[provider]
+			if cloud == "" || (project.Provider != "" && cloud != project.Provider) {
+				continue
+			}
+			project.Provider = cloud
+			switch value := string(stackConfig.Config[key]); setting {
+			case "region", "location":
+				if project.Region == "" {
+					project.Region = value
+				}
+			case "project":
+				project.Project = value
+			}
+		}
+		if project.Provider != "" {
+			break
+		}
+	}
+	return project, true
+}
+
+// readYAMLConfig decodes the first document of a YAML or JSON config
+func readYAMLConfig(path string, config interface{}) bool {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	return yaml.NewDecoder(bytes.NewReader(content)).Decode(config) == nil
+}
+
+// toolURL is the tool's dashboard, or the CloudFormation stacks of the region for
+// the AWS tools
+func (p ServerlessProject) toolURL() string {
+	switch p.Tool {
+	case ServerlessFrameworkName:
+		return "https://app.serverless.com"
+	case PulumiName:
+		return "https://app.pulumi.com"
+	}
+	if p.Region == "" {
+		return "https://console.aws.amazon.com/cloudformation/home#/stacks"
+	}
+	url := "https://" + p.Region + ".console.aws.amazon.com/cloudformation/home?region=" + p.Region + "#/stacks"
+	if p.Name != "" {
+		url += "?filteringText=" + p.Name
+	}
+	return url
+}
+
+// functionsURL lists the functions in the console of the provider, in the region
+// or project when known
+func (p ServerlessProject) functionsURL() string {
+	switch p.Provider {
+	case "aws":
+		if p.Region != "" {
+			return "https://" + p.Region + ".console.aws.amazon.com/lambda/home?region=" + p.Region + "#/functions"
+		}
+		return "https://console.aws.amazon.com/lambda/home#/functions"
+	case "google":
+		if p.Project != "" {
+			return "https://console.cloud.google.com/functions/list?project=" + p.Project
+		}
+		return "https://console.cloud.google.com/functions/list"
+	}
+	return "https://portal.azure.com/#browse/Microsoft.Web%2Fsites/kind/functionapp"
+}
+
+// nonEmptyKeys returns the keys of a set but the empty one, sorted
+func nonEmptyKeys(set map[string]bool) []string {
+	delete(set, "")
+	return sortedKeys(set)
+}
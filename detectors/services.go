@@ -5,12 +5,14 @@ type ServicesDependencies interface {
 	DetectProjectLanguages(projectPath string) []string
 	AnalyzeProjectDependencies(projectPath string, languages []string) []ProjectResult
 	GetServicesData() map[string]*ServiceInfo
+	FrameworkHints(projectPath string) []FrameworkHint
 }
 
 // ServiceInfo represents service metadata
 type ServiceInfo struct {
-	Name string
-	URL  string
+	Name    string
+	URL     string
+	Aliases []string // other names used in framework configs (e.g. ses for aws)
 }
 
 // ProjectResult represents analysis result for a project
@@ -45,6 +47,15 @@ func (s *ServicesDetector) Name() string {
 func (s *ServicesDetector) Detect(projectPath string) (map[string]string, error) {
 	results := make(map[string]string)
 
+	servicesData := s.deps.GetServicesData()
+
+	// Services configured in framework config are found even without a known package
+	for _, hint := range s.deps.FrameworkHints(projectPath) {
+		if key, ok := MatchFrameworkHint(hint.Hint, servicesData); ok {
+			results[key] = servicesData[key].URL
+		}
+	}
+
 	// Use existing logic through interface
 	detectedLanguages := s.deps.DetectProjectLanguages(projectPath)
 	if len(detectedLanguages) == 0 {
@@ -52,7 +63,6 @@ func (s *ServicesDetector) Detect(projectPath string) (map[string]string, error)
 	}
 
 	projectResults := s.deps.AnalyzeProjectDependencies(projectPath, detectedLanguages)
-	servicesData := s.deps.GetServicesData()
 
 	// Convert to simple key-value pairs
 	for _, result := range projectResults {
@@ -0,0 +1,36 @@
+package detectortest
+
+import (
+	"io/fs"
+	"testing"
+
+	"parascan/detectors"
+)
+
+// Detect runs a detector against the project and returns its results. The test
+// fails if the detector does. Plugin executables run through
+// detectors.NewExecDetector.
+func Detect(t testing.TB, det detectors.Detector, fsys fs.FS, opts ...Option) map[string]string {
+	t.Helper()
+	req := newRequest(t, fsys, opts)
+	results := req.Results
+	if results == nil {
+		results = make(map[string]string)
+	}
+	got, err := det.Detect(&detectors.DetectionContext{
+		ProjectPath: req.ProjectPath,
+		Languages:   req.Languages,
+		Results:     results,
+	})
+	if err != nil {
+		t.Fatalf("%s detector failed: %v", det.Name(), err)
+	}
+	return got
+}
+
+// RunDetector runs a detector against the project and checks that its results are
+// exactly want
+func RunDetector(t testing.TB, det detectors.Detector, fsys fs.FS, want map[string]string, opts ...Option) {
+	t.Helper()
+	CheckResults(t, Detect(t, det, fsys, opts...), want)
+}
@@ -0,0 +1,147 @@
+// Package detectortest runs detectors against small projects written to a
+// temporary directory, so the built-in detectors and plugins are tested the same
+// way:
+//
+//	detectortest.RunPlugin(t, detect, fstest.MapFS{
+//		".billing.yml": {Data: []byte("team: payments\n")},
+//	}, map[string]string{"billing": "https://billing.example.com"})
+//
+// Projects are any fs.FS: an fstest.MapFS for a few files, os.DirFS for a fixture.
+// This file only needs the standard library and pluginsdk, plugins scaffolded with
+// para plugin init get a copy of it.
+package detectortest
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"parascan/pluginsdk"
+)
+
+// Option sets what the scanner passes to a detector besides the project
+type Option func(*pluginsdk.Request)
+
+// WithResults passes results of previous detectors, e.g. repo
+func WithResults(results map[string]string) Option {
+	return func(req *pluginsdk.Request) {
+		req.Results = results
+	}
+}
+
+// WithLanguages passes the languages detected in the project
+func WithLanguages(languages ...string) Option {
+	return func(req *pluginsdk.Request) {
+		req.Languages = languages
+	}
+}
+
+// WithOptions passes a plugin's options from .parascan.yml
+func WithOptions(options map[string]string) Option {
+	return func(req *pluginsdk.Request) {
+		req.Options = options
+	}
+}
+
+// Project writes the files of fsys to a temporary directory, removed when the test
+// ends, and returns its path. Files without a mode are written with 0644.
+func Project(t testing.TB, fsys fs.FS) string {
+	t.Helper()
+	dir := t.TempDir()
+	err := fs.WalkDir(fsys, ".", func(name string, entry fs.DirEntry, err error) error {
+		if err != nil || name == "." {
+			return err
+		}
+		target := filepath.Join(dir, filepath.FromSlash(name))
+		if entry.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		content, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		mode := fs.FileMode(0644)
+		if info, err := entry.Info(); err == nil && info.Mode().Perm() != 0 {
+			mode = info.Mode().Perm()
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		return os.WriteFile(target, content, mode)
+	})
+	if err != nil {
+		t.Fatalf("Failed to write project: %v", err)
+	}
+	return dir
+}
+
+// newRequest writes the project and the request a scan would send for it
+func newRequest(t testing.TB, fsys fs.FS, opts []Option) *pluginsdk.Request {
+	t.Helper()
+	req := &pluginsdk.Request{Protocol: pluginsdk.ProtocolVersion, ProjectPath: Project(t, fsys)}
+	for _, opt := range opts {
+		opt(req)
+	}
+	return req
+}
+
+// DetectPlugin runs a plugin's detect function against the project through the
+// stdio protocol, as para scan would, and returns its results. The test fails if the
+// plugin reports an error.
+func DetectPlugin(t testing.TB, detect pluginsdk.DetectFunc, fsys fs.FS, opts ...Option) map[string]string {
+	t.Helper()
+	var in, out bytes.Buffer
+	if err := json.NewEncoder(&in).Encode(newRequest(t, fsys, opts)); err != nil {
+		t.Fatalf("Failed to encode request: %v", err)
+	}
+	pluginsdk.ServeIO(&in, &out, detect)
+
+	var resp pluginsdk.Response
+	if err := json.NewDecoder(&out).Decode(&resp); err != nil {
+		t.Fatalf("Plugin wrote an invalid response: %v", err)
+	}
+	if resp.Error != "" {
+		t.Fatalf("Plugin failed: %s", resp.Error)
+	}
+	return resp.Results
+}
+
+// RunPlugin runs a plugin's detect function against the project and checks that its
+// results are exactly want
+func RunPlugin(t testing.TB, detect pluginsdk.DetectFunc, fsys fs.FS, want map[string]string, opts ...Option) {
+	t.Helper()
+	CheckResults(t, DetectPlugin(t, detect, fsys, opts...), want)
+}
+
+// CheckResults reports each result that is missing, unexpected or has another value
+// than in want
+func CheckResults(t testing.TB, got, want map[string]string) {
+	t.Helper()
+	keys := make([]string, 0, len(got)+len(want))
+	for key := range want {
+		keys = append(keys, key)
+	}
+	for key := range got {
+		if _, ok := want[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value, found := got[key]
+		expected, wanted := want[key]
+		switch {
+		case !found:
+			t.Errorf("Expected %s: %s, got nothing", key, expected)
+		case !wanted:
+			t.Errorf("Did not expect %s: %s", key, value)
+		case value != expected:
+			t.Errorf("Expected %s: %s, got %s", key, expected, value)
+		}
+	}
+}
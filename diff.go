@@ -0,0 +1,296 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"parascan/detectors"
+	"parascan/pkg/parascan"
+)
+
+// Exit codes of para diff, 1 is an error
+const (
+	diffExitInSync = 0
+	diffExitDrift  = 2
+)
+
+// ConfigEntry is a service as written in parascope.yml
+type ConfigEntry struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+// ConfigChange is a service whose URL in parascope.yml differs from the scan's
+type ConfigChange struct {
+	Name   string `json:"name"`
+	Config string `json:"config"`
+	Scan   string `json:"scan"`
+}
+
+// ConfigDiff is the drift between parascope.yml and a fresh scan
+type ConfigDiff struct {
+	Status  string         `json:"status"` // in_sync, drift or fail
+	Error   string         `json:"error,omitempty"`
+	Config  string         `json:"config"`
+	Project string         `json:"project"`
+	Added   []ConfigEntry  `json:"added,omitempty"`   // detected, not in the config
+	Removed []ConfigEntry  `json:"removed,omitempty"` // in the config, no longer detected
+	Changed []ConfigChange `json:"changed,omitempty"`
+	// Provenance tells detection output from manual edits: verified when the entries
+	// are as the scan recorded with --provenance wrote them, edited when they aren't
+	Provenance string     `json:"provenance,omitempty"`
+	Scanned    *time.Time `json:"scanned,omitempty"`
+}
+
+// readConfigSection reads the project's entries from parascope.yml, found by its
+// root key or else by its repository URL, like scans updating the config. Repository
+// keys are read under repositoryName. A missing config has no entries.
+func readConfigSection(configPath, projectName, repoURL, repositoryName string) (map[string]string, error) {
+	content, err := os.ReadFile(configPath)
+	if os.IsNotExist(err) {
+		return map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var config map[string]map[string]interface{}
+	if err := yaml.Unmarshal(readRecoveredConfig(configPath, content), &config); err != nil {
+		return nil, fmt.Errorf("%s: %v", configPath, err)
+	}
+
+	section, found := config[projectName]
+	if !found && repoURL != "" {
+		rootKeys := make([]string, 0, len(config))
+		for key := range config {
+			rootKeys = append(rootKeys, key)
+		}
+		sort.Strings(rootKeys)
+		for _, key := range rootKeys {
+			for name, value := range config[key] {
+				if isRepositoryKey(name, repositoryName) && value == repoURL {
+					section = config[key]
+				}
+			}
+		}
+	}
+
+	entries := make(map[string]string)
+	for name, value := range section {
+		if name == ignoreKey {
+			continue
+		}
+		if isRepositoryKey(name, repositoryName) {
+			name = repositoryName
+		}
+		entries[name] = fmt.Sprint(value)
+	}
+	return entries, nil
+}
+
+// diffConfig compares the entries a scan would write with the config's. As when
+// the config is updated, a URL already in the config under another name isn't
+// drift: the entry was renamed. URLs are compared by urlKey.
+func diffConfig(config, scanned map[string]string) ConfigDiff {
+	configURLs := make(map[string]bool)
+	for _, url := range config {
+		configURLs[urlKey(url)] = true
+	}
+	scannedURLs := make(map[string]bool)
+	for _, url := range scanned {
+		scannedURLs[urlKey(url)] = true
+	}
+
+	var diff ConfigDiff
+	for _, name := range sortedKeys(scanned) {
+		url := scanned[name]
+		configURL, inConfig := config[name]
+		switch {
+		case inConfig && urlKey(configURL) != urlKey(url) && !configURLs[urlKey(url)]:
+			diff.Changed = append(diff.Changed, ConfigChange{Name: name, Config: configURL, Scan: url})
+		case !inConfig && !configURLs[urlKey(url)]:
+			diff.Added = append(diff.Added, ConfigEntry{Name: name, URL: url})
+		}
+	}
+	for _, name := range sortedKeys(config) {
+		if _, scannedName := scanned[name]; !scannedName && !scannedURLs[urlKey(config[name])] {
+			diff.Removed = append(diff.Removed, ConfigEntry{Name: name, URL: config[name]})
+		}
+	}
+
+	diff.Status = "in_sync"
+	if len(diff.Added) > 0 || len(diff.Removed) > 0 || len(diff.Changed) > 0 {
+		diff.Status = "drift"
+	}
+	return diff
+}
+
+func sortedKeys(values map[string]string) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// writeDiff prints the drift for people
+func writeDiff(w io.Writer, diff ConfigDiff) {
+	if diff.Status == "in_sync" {
+		fmt.Fprintf(w, "✅ %s (%s) is in sync with the scan\n", diff.Config, diff.Project)
+		return
+	}
+	fmt.Fprintf(w, "🔀 %s (%s) has drifted from the scan:\n", diff.Config, diff.Project)
+	switch diff.Provenance {
+	case "verified":
+		fmt.Fprintf(w, "   Entries are as the scan of %s wrote them, the project changed since\n", diff.Scanned.Format(time.RFC3339))
+	case "edited":
+		fmt.Fprintf(w, "   Entries were edited by hand since the scan of %s\n", diff.Scanned.Format(time.RFC3339))
+	}
+	for _, entry := range diff.Added {
+		fmt.Fprintf(w, "  + %s → %s\n", entry.Name, entry.URL)
+	}
+	for _, entry := range diff.Removed {
+		fmt.Fprintf(w, "  - %s → %s\n", entry.Name, entry.URL)
+	}
+	for _, change := range diff.Changed {
+		fmt.Fprintf(w, "  ~ %s: %s → %s\n", change.Name, change.Config, change.Scan)
+	}
+	fmt.Fprintf(w, "\nRun `para scan` to add new services, removed ones are left for you to delete.\n")
+}
+
+func handleDiff() {
+	projectPath := "."
+	configPath := ""
+	format := "text"
+	var fromDocs bool
+
+	flags := newCommandFlags("diff", showDiffHelp)
+	flags.StringVar(&format, "format", format, "")
+	flags.StringVar(&format, "f", format, "")
+	flags.BoolVar(&fromDocs, "docs", false, "")
+	args := flags.parse(os.Args[2:])
+	flags.maxArgs(args, 1)
+	if len(args) == 1 {
+		// A config file path diffs against its directory, like para scan
+		if strings.HasSuffix(args[0], ".yml") || strings.HasSuffix(args[0], ".yaml") {
+			configPath = args[0]
+			projectPath = filepath.Dir(args[0])
+		} else {
+			projectPath = args[0]
+		}
+	}
+	if format != "text" && format != "json" {
+		flags.fail("Unknown format: %s. Supported formats: text, json", format)
+	}
+
+	diff, err := diffProject(projectPath, configPath, fromDocs)
+	if err != nil {
+		if format == "json" {
+			data, _ := json.MarshalIndent(ConfigDiff{Status: "fail", Error: err.Error(), Config: diff.Config, Project: diff.Project}, "", "  ")
+			fmt.Println(string(data))
+		} else {
+			fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		}
+		os.Exit(1)
+	}
+
+	if format == "json" {
+		data, _ := json.MarshalIndent(diff, "", "  ")
+		fmt.Println(string(data))
+	} else {
+		writeDiff(os.Stdout, diff)
+	}
+	if diff.Status == "drift" {
+		os.Exit(diffExitDrift)
+	}
+	os.Exit(diffExitInSync)
+}
+
+// diffProject scans the project the way para scan does and compares the result
+// with its parascope.yml ("" for the project's default config)
+func diffProject(projectPath, configPath string, fromDocs bool) (ConfigDiff, error) {
+	settings, err := parascan.LoadProjectSettings(projectPath)
+	if err != nil {
+		return ConfigDiff{}, err
+	}
+	if configPath == "" {
+		configPath = filepath.Join(projectPath, "parascope.yml")
+		if settings.Output.ConfigPath != "" {
+			configPath = filepath.Join(projectPath, settings.Output.ConfigPath)
+		}
+	}
+	repositoryName := settings.RepositoryName()
+	diff := ConfigDiff{Config: configPath, Project: configProjectName(configPath, settings.Output.ProjectName)}
+
+	globalConfig, err := loadGlobalConfig()
+	if err != nil {
+		return diff, err
+	}
+	catalog, _, err := resolveCatalog(projectPath, "", settings, globalConfig.Trust, false)
+	if err != nil {
+		return diff, err
+	}
+	ignoreConfigServices(settings, catalog, configPath, diff.Project)
+	plugins, err := loadPluginDetectors(globalConfig.Trust, false)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Could not load plugins: %v\n", err)
+	}
+
+	scan := runScan(projectPath, catalog, ScanOptions{Plugins: plugins, Settings: settings, Docs: fromDocs, LinkType: settings.Output.LinkType})
+	if err := memoryLimitError(scan); err != nil {
+		return diff, err
+	}
+	for _, detectorErr := range scan.Errors {
+		if detectorErr.Detector == "scan" {
+			return diff, detectorErr.Err
+		}
+	}
+
+	results := dedupeResults(filterGitHubByRepository(settings.ApplyToResults(scan.Results)))
+	scanned := make(map[string]string)
+	for key, value := range results {
+		scanned[configEntryName(key, value, repositoryName)] = value
+	}
+	config, err := readConfigSection(configPath, diff.Project, results[detectors.RepoKey], repositoryName)
+	if err != nil {
+		return diff, err
+	}
+
+	configDiff := diffConfig(config, scanned)
+	configDiff.Config, configDiff.Project = diff.Config, diff.Project
+	if content, err := os.ReadFile(configPath); err == nil {
+		sections := splitConfigSections(string(content))
+		if i := findConfigSection(sections, diff.Project, results[detectors.RepoKey], repositoryName); i >= 0 {
+			if validation := validateSection(diff.Project, sections[i]); validation.Provenance != nil {
+				configDiff.Provenance, configDiff.Scanned = validation.Status, &validation.Provenance.Scanned
+			}
+		}
+	}
+	return configDiff, nil
+}
+
+func showDiffHelp() {
+	fmt.Println(`Usage: para diff [path] [--format text|json] [--docs]
+
+Scan the project and compare the result with its parascope.yml, without changing
+it. Lists services the scan found that the config lacks (+), config entries the
+scan no longer finds (-) and services whose URL changed (~).
+
+Exit codes: 0 when the config is in sync, 2 when it has drifted, 1 on errors.
+
+Options:
+  --format, -f     text (default) or json
+  --docs           Also infer services from README and docs, as para scan --docs
+
+Examples:
+  para diff
+  para diff ./my-project/parascope.yml -f json`)
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConfigDiff(t *testing.T) {
+	skipWithoutPack(t, "requirements.txt")
+	projectPath := filepath.Join("testdata", "settings-project")
+	configPath := filepath.Join(t.TempDir(), "parascope.yml")
+
+	// Stripe is renamed through the project's names, its URL stays the dashboard's
+	config := "billing:\n  Stripe Payments: https://dashboard.stripe.com\n  Mailgun: https://app.mailgun.com\n"
+	if err := os.WriteFile(configPath, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+	diff, err := diffProject(projectPath, configPath, false)
+	if err != nil {
+		t.Fatalf("Diff failed: %v", err)
+	}
+	if diff.Status != "drift" || diff.Project != "billing" {
+		t.Fatalf("Expected drift of billing, got %+v", diff)
+	}
+	if len(diff.Added) != 1 || diff.Added[0].Name != "Sentry" || len(diff.Changed) != 0 {
+		t.Errorf("Expected Sentry added, got %+v", diff)
+	}
+	if len(diff.Removed) != 1 || diff.Removed[0].Name != "Mailgun" {
+		t.Errorf("Expected Mailgun removed, got %+v", diff)
+	}
+
+	// The config written by a scan is in sync
+	os.Remove(configPath)
+	createConfigFromDetectorResults(configPath, map[string]string{"Stripe Payments": "https://dashboard.stripe.com", "sentry": "https://sentry.com"}, "billing", "", nil)
+	if diff, err := diffProject(projectPath, configPath, false); err != nil || diff.Status != "in_sync" {
+		t.Errorf("Expected the scan's own config in sync, got %+v (%v)", diff, err)
+	}
+
+	// Services the section ignores aren't drift, by key or display name
+	for _, ignore := range []string{"[sentry]", "\n    - Sentry"} {
+		config := "billing:\n  ignore: " + ignore + "\n  Stripe Payments: https://dashboard.stripe.com\n"
+		if err := os.WriteFile(configPath, []byte(config), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if diff, err := diffProject(projectPath, configPath, false); err != nil || diff.Status != "in_sync" {
+			t.Errorf("Expected Sentry ignored with %q, got %+v (%v)", ignore, diff, err)
+		}
+	}
+
+	// Renamed entries aren't drift, changed URLs are
+	configured := map[string]string{"Payments": "https://dashboard.stripe.com", "Repository": "https://github.com/acme/old", "Sentry": "https://sentry.io"}
+	scanned := map[string]string{"Stripe": "https://dashboard.stripe.com", "Repository": "https://github.com/acme/billing", "Sentry": "https://sentry.io"}
+	diff = diffConfig(configured, scanned)
+	if len(diff.Changed) != 1 || diff.Changed[0].Name != "Repository" || diff.Changed[0].Scan != "https://github.com/acme/billing" {
+		t.Errorf("Expected only the repository changed, got %+v", diff)
+	}
+	if len(diff.Added) != 0 || len(diff.Removed) != 0 {
+		t.Errorf("Expected the renamed Stripe entry in sync, got %+v", diff)
+	}
+
+	// Legacy repository keys are read under the configured name, the section is found by URL
+	if err := os.WriteFile(configPath, []byte("acme:\n  repo: https://github.com/acme/billing\n  Stripe: https://dashboard.stripe.com\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	section, err := readConfigSection(configPath, "billing", "https://github.com/acme/billing", "Repository")
+	if err != nil || section["Repository"] != "https://github.com/acme/billing" || section["Stripe"] == "" {
+		t.Errorf("Expected the section found by repository URL, got %v (%v)", section, err)
+	}
+}
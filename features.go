@@ -0,0 +1,13 @@
+package main
+
+import "errors"
+
+// The nonetwork and noplugins build tags leave network access (para status,
+// para review --pr, remote plugins and repositories) and external detector
+// plugins out of a build, e.g. for sandboxed CI runners:
+//
+//	go build -tags nonetwork,noplugins
+var (
+	errNetworkDisabled = errors.New("this build of para has no network access (built with -tags nonetwork)")
+	errPluginsDisabled = errors.New("this build of para has no plugin support (built with -tags noplugins)")
+)
@@ -0,0 +1,6 @@
+//go:build !nonetwork
+
+package main
+
+// networkEnabled reports whether commands may reach the network
+const networkEnabled = true
@@ -0,0 +1,6 @@
+//go:build !noplugins
+
+package main
+
+// pluginsEnabled reports whether external detector plugins are loaded and installed
+const pluginsEnabled = true
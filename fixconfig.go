@@ -0,0 +1,227 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// ConfigProblem is a line of parascope.yml that keeps it from parsing
+type ConfigProblem struct {
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+}
+
+// yamlErrorLine is the line and message of a yaml.v2 error, e.g.
+// "yaml: line 5: mapping values are not allowed in this context"
+var yamlErrorLine = regexp.MustCompile(`line (\d+): (.*)`)
+
+// recoveredSection is a project section of a malformed config, with the lines that
+// parse on their own
+type recoveredSection struct {
+	project string
+	line    int
+	lines   []string
+	entries map[string]int // line of each entry name
+}
+
+// repairConfig parses parascope.yml line by line when it isn't valid as a whole. Each
+// root key starts a section and each indented line is a name: value entry on its own.
+// Lines that don't parse are kept as comments, so nothing is lost, and reported as
+// problems. A valid config is returned as is, without problems.
+func repairConfig(content string) (string, []ConfigProblem) {
+	var config map[string]map[string]interface{}
+	err := yaml.Unmarshal([]byte(content), &config)
+	if err == nil {
+		return content, nil
+	}
+
+	var problems []ConfigProblem
+	var header []string // comments and invalid lines before the first section
+	var sections []*recoveredSection
+	var current *recoveredSection
+	invalid := func(number int, line, message string) {
+		problems = append(problems, ConfigProblem{Line: number, Message: message})
+		comment := "# fix-config: " + strings.TrimSpace(line)
+		if current == nil {
+			header = append(header, comment)
+		} else {
+			current.lines = append(current.lines, "  "+comment)
+		}
+	}
+
+	for i, line := range strings.Split(strings.TrimRight(content, "\n"), "\n") {
+		number := i + 1
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+			continue
+		case strings.HasPrefix(trimmed, "#"):
+			if current == nil {
+				header = append(header, trimmed)
+			} else {
+				current.lines = append(current.lines, "  "+trimmed)
+			}
+		case line[0] != ' ' && line[0] != '\t':
+			var root map[string]interface{}
+			if err := yaml.Unmarshal([]byte(line), &root); err != nil || len(root) != 1 {
+				invalid(number, line, "expected a project name followed by a colon")
+				continue
+			}
+			for project, value := range root {
+				if value != nil {
+					invalid(number, line, "entry outside of a project section, indent it under one")
+					continue
+				}
+				current = nil
+				for _, section := range sections {
+					if section.project == project {
+						problems = append(problems, ConfigProblem{Line: number, Message: fmt.Sprintf("project %s is already defined on line %d, entries were merged into it", project, section.line)})
+						current = section
+					}
+				}
+				if current == nil {
+					current = &recoveredSection{project: project, line: number, entries: make(map[string]int)}
+					sections = append(sections, current)
+				}
+			}
+		case current == nil:
+			invalid(number, line, "entry before the first project section")
+		default:
+			var entry map[string]string
+			if err := yaml.Unmarshal([]byte(trimmed), &entry); err != nil || len(entry) != 1 {
+				message := "expected a name: value entry"
+				if err != nil {
+					message = yamlErrorMessage(err)
+				}
+				invalid(number, line, message)
+				continue
+			}
+			for name := range entry {
+				if previous, found := current.entries[name]; found {
+					invalid(number, line, fmt.Sprintf("%s is already set on line %d", name, previous))
+					continue
+				}
+				current.entries[name] = number
+				if indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]; strings.Contains(indent, "\t") {
+					problems = append(problems, ConfigProblem{Line: number, Message: "indented with a tab, YAML only allows spaces"})
+				}
+				current.lines = append(current.lines, "  "+trimmed)
+			}
+		}
+	}
+
+	// Errors line by line parsing doesn't find, e.g. in multi-line values
+	if len(problems) == 0 {
+		problem := ConfigProblem{Line: 1, Message: yamlErrorMessage(err)}
+		if match := yamlErrorLine.FindStringSubmatch(err.Error()); match != nil {
+			problem.Line, _ = strconv.Atoi(match[1])
+		}
+		problems = append(problems, problem)
+	}
+
+	parts := []string{strings.Join(header, "\n")}
+	for _, section := range sections {
+		parts = append(parts, section.project+":\n"+strings.Join(section.lines, "\n"))
+	}
+	return joinConfigSections(parts), problems
+}
+
+// yamlErrorMessage is a yaml.v2 error without its prefix and line
+func yamlErrorMessage(err error) string {
+	if match := yamlErrorLine.FindStringSubmatch(err.Error()); match != nil {
+		return match[2]
+	}
+	return strings.TrimPrefix(err.Error(), "yaml: ")
+}
+
+// readRecoveredConfig returns the config as far as it parses, warning about each
+// line that doesn't
+func readRecoveredConfig(configPath string, content []byte) []byte {
+	repaired, problems := repairConfig(string(content))
+	for _, problem := range problems {
+		fmt.Fprintf(os.Stderr, "⚠️  %s:%d: %s\n", configPath, problem.Line, problem.Message)
+	}
+	if len(problems) > 0 {
+		fmt.Fprintf(os.Stderr, "⚠️  Skipped the invalid lines of %s, run para fix-config to repair it\n", configPath)
+	}
+	return []byte(repaired)
+}
+
+// writeConfigProblems lists the problems of a config for people
+func writeConfigProblems(w io.Writer, configPath string, problems []ConfigProblem) {
+	for _, problem := range problems {
+		fmt.Fprintf(w, "  %s:%d: %s\n", configPath, problem.Line, problem.Message)
+	}
+}
+
+func handleFixConfig() {
+	configPath := "parascope.yml"
+	outputPath := ""
+
+	flags := newCommandFlags("fix-config", showFixConfigHelp)
+	flags.StringVar(&outputPath, "output", "", "")
+	flags.StringVar(&outputPath, "o", "", "")
+	args := flags.parse(os.Args[2:])
+	flags.maxArgs(args, 1)
+	if len(args) == 1 {
+		// A directory is repaired through its parascope.yml, like para scan
+		if strings.HasSuffix(args[0], ".yml") || strings.HasSuffix(args[0], ".yaml") {
+			configPath = args[0]
+		} else {
+			configPath = filepath.Join(args[0], "parascope.yml")
+		}
+	}
+	if outputPath == "" {
+		ext := filepath.Ext(configPath)
+		outputPath = strings.TrimSuffix(configPath, ext) + ".fixed" + ext
+	}
+	if filepath.Clean(outputPath) == filepath.Clean(configPath) {
+		fmt.Fprintf(os.Stderr, "❌ Won't overwrite %s, write the repaired config to another file for review\n", configPath)
+		os.Exit(1)
+	}
+
+	content, err := os.ReadFile(configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		os.Exit(1)
+	}
+	repaired, problems := repairConfig(string(content))
+	if len(problems) == 0 {
+		fmt.Printf("✅ %s is valid, nothing to fix\n", configPath)
+		return
+	}
+	if err := os.WriteFile(outputPath, []byte(repaired), 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("🔧 Found %d problems in %s:\n", len(problems), configPath)
+	writeConfigProblems(os.Stdout, configPath, problems)
+	fmt.Printf("\n✨ Wrote %s, lines that couldn't be repaired are commented out with fix-config.\n", outputPath)
+	fmt.Printf("Review it and move it over %s.\n", configPath)
+}
+
+func showFixConfigHelp() {
+	fmt.Println(`Usage: para fix-config [path] [-o <file>]
+
+Repair a parascope.yml that isn't valid YAML. Every section and entry that parses
+on its own is kept, entries indented with tabs are re-indented and sections
+defined twice are merged. Lines that can't be repaired are kept as comments
+starting with "# fix-config:". The problems are listed with their line.
+
+The repaired config is written to a new file, parascope.fixed.yml by default,
+parascope.yml itself is never changed.
+
+Options:
+  --output, -o     Where to write the repaired config
+
+Examples:
+  para fix-config
+  para fix-config ./my-project -o /tmp/parascope.yml`)
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRepairConfig(t *testing.T) {
+	malformed := "# services\n" +
+		"billing:\n" +
+		"\tStripe: https://dashboard.stripe.com\n" +
+		"  Sentry: https://sentry.io\n" +
+		"  Docs: https://docs.acme.com: v2\n" +
+		"billing:\n" +
+		"  Twilio: https://console.twilio.com\n" +
+		"  Sentry: https://acme.sentry.io\n"
+
+	repaired, problems := repairConfig(malformed)
+	expected := "# services\n\n" +
+		"billing:\n" +
+		"  Stripe: https://dashboard.stripe.com\n" +
+		"  Sentry: https://sentry.io\n" +
+		"  # fix-config: Docs: https://docs.acme.com: v2\n" +
+		"  Twilio: https://console.twilio.com\n" +
+		"  # fix-config: Sentry: https://acme.sentry.io\n"
+	if repaired != expected {
+		t.Errorf("Expected repaired config:\n%s\ngot:\n%s", expected, repaired)
+	}
+	lines := []int{3, 5, 6, 8}
+	if len(problems) != len(lines) {
+		t.Fatalf("Expected problems on lines %v, got %+v", lines, problems)
+	}
+	for i, line := range lines {
+		if problems[i].Line != line {
+			t.Errorf("Expected problems on lines %v, got %+v", lines, problems)
+			break
+		}
+	}
+	if repairedAgain, problems := repairConfig(repaired); len(problems) != 0 || repairedAgain != repaired {
+		t.Errorf("Expected the repaired config to be valid, got %+v", problems)
+	}
+
+	// Scans leave a malformed config alone, diffs read what parses
+	configPath := filepath.Join(t.TempDir(), "parascope.yml")
+	if err := os.WriteFile(configPath, []byte(malformed), 0644); err != nil {
+		t.Fatal(err)
+	}
+	createConfigFromDetectorResults(configPath, map[string]string{"openai": "https://platform.openai.com"}, "billing", "", nil)
+	if content, _ := os.ReadFile(configPath); string(content) != malformed {
+		t.Errorf("Expected the malformed config unchanged, got:\n%s", content)
+	}
+	entries, err := readConfigSection(configPath, "billing", "", "Repository")
+	if err != nil || len(entries) != 3 || entries["Sentry"] != "https://sentry.io" || entries["Twilio"] != "https://console.twilio.com" {
+		t.Errorf("Expected the entries that parse, got %v (%v)", entries, err)
+	}
+}
@@ -1,14 +1,21 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
+	"io/fs"
 	"io/ioutil"
+	"os"
 	"path/filepath"
 	"sort"
+	"strings"
 	"testing"
+	"testing/fstest"
 
 	"gopkg.in/yaml.v2"
 	"parascan/detectors"
+	"parascan/detectortest"
+	"parascan/pkg/parascan"
 )
 
 type ExpectedResults struct {
@@ -17,7 +24,19 @@ type ExpectedResults struct {
 	Description       string   `yaml:"description"`
 }
 
+// skipWithoutPack skips tests whose fixtures need the language pack of one of the
+// dependency files when it isn't in this build
+func skipWithoutPack(t *testing.T, files ...string) {
+	t.Helper()
+	for _, file := range files {
+		if pack := parascan.MissingLanguagePack(file); pack != "" {
+			t.Skipf("the %s language pack isn't in this build", pack)
+		}
+	}
+}
+
 func TestServiceDetectionWithFixtures(t *testing.T) {
+	skipWithoutPack(t, "package.json", "Gemfile", "requirements.txt")
 	// Load test data and services data
 	stackData, err := loadStackDependencyFiles()
 	if err != nil {
@@ -35,6 +54,12 @@ func TestServiceDetectionWithFixtures(t *testing.T) {
 		"python-project",
 		"multi-language",
 		"empty-project",
+		"api-gateway-project",
+		"ecommerce-project",
+		"cms-project",
+		"oncall-project",
+		"storage-project",
+		"testing-project",
 	}
 
 	for _, testCase := range testCases {
@@ -55,7 +80,7 @@ func TestServiceDetectionWithFixtures(t *testing.T) {
 			}
 
 			// Test language detection
-			detectedLanguages := detectProjectLanguages(projectPath, stackData)
+			detectedLanguages := parascan.DetectProjectLanguages(projectPath, stackData, nil, 0)
 			sort.Strings(detectedLanguages)
 			sort.Strings(expected.ExpectedLanguages)
 
@@ -66,7 +91,7 @@ func TestServiceDetectionWithFixtures(t *testing.T) {
 
 			// Test service detection
 			if len(detectedLanguages) > 0 {
-				results := analyzeProjectDependencies(projectPath, detectedLanguages, stackData, servicesData)
+				results := parascan.AnalyzeProjectDependencies(projectPath, detectedLanguages, stackData, servicesData, nil, 0)
 
 				// Collect all detected services
 				var detectedServices []string
@@ -98,10 +123,11 @@ func TestServiceDetectionWithFixtures(t *testing.T) {
 }
 
 func TestEndToEndServiceDetection(t *testing.T) {
+	skipWithoutPack(t, "package.json", "Gemfile", "requirements.txt")
 	// Test the complete sniff workflow using fixtures
 	testCases := []struct {
-		name     string
-		project  string
+		name        string
+		project     string
 		minServices int
 	}{
 		{"Ruby project", "ruby-project", 8},
@@ -127,10 +153,7 @@ func TestEndToEndServiceDetection(t *testing.T) {
 			}
 
 			// Create adapter (same as in handleSniff)
-			adapter := &ServicesDependenciesAdapter{
-				stackData:    stackData,
-				servicesData: servicesData,
-			}
+			adapter := parascan.NewServicesAdapter(stackData, servicesData)
 
 			// Test services detector
 			servicesDetector := detectors.NewServicesDetector(adapter)
@@ -186,11 +209,12 @@ func removeDuplicates(slice []string) []string {
 }
 
 func TestSpecificServiceDetection(t *testing.T) {
+	skipWithoutPack(t, "package.json", "Gemfile", "requirements.txt")
 	// Test specific services that were problematic
 	tests := []struct {
-		project     string
-		service     string
-		shouldFind  bool
+		project    string
+		service    string
+		shouldFind bool
 	}{
 		{"ruby-project", "stripe", true},
 		{"ruby-project", "twilio", true},
@@ -212,22 +236,16 @@ func TestSpecificServiceDetection(t *testing.T) {
 		t.Fatalf("Failed to load services data: %v", err)
 	}
 
-
-
 	for _, tt := range tests {
 		t.Run(fmt.Sprintf("%s_%s", tt.project, tt.service), func(t *testing.T) {
 			projectPath := filepath.Join("testdata", tt.project)
 
-
-
-			detectedLanguages := detectProjectLanguages(projectPath, stackData)
+			detectedLanguages := parascan.DetectProjectLanguages(projectPath, stackData, nil, 0)
 			if len(detectedLanguages) == 0 && tt.shouldFind {
 				t.Fatalf("No languages detected for %s", tt.project)
 			}
 
-			results := analyzeProjectDependencies(projectPath, detectedLanguages, stackData, servicesData)
-
-
+			results := parascan.AnalyzeProjectDependencies(projectPath, detectedLanguages, stackData, servicesData, nil, 0)
 
 			found := false
 			for _, result := range results {
@@ -242,7 +260,7 @@ func TestSpecificServiceDetection(t *testing.T) {
 				}
 			}
 
-						if found != tt.shouldFind {
+			if found != tt.shouldFind {
 				if tt.shouldFind {
 					t.Errorf("Expected to find service %s in %s, but it was not detected", tt.service, tt.project)
 				} else {
@@ -251,4 +269,1137 @@ func TestSpecificServiceDetection(t *testing.T) {
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+
+func TestLanguageFallbackBySourceExtensions(t *testing.T) {
+	stackData, err := loadStackDependencyFiles()
+	if err != nil {
+		t.Fatalf("Failed to load stack data: %v", err)
+	}
+
+	projectPath := filepath.Join("testdata", "scripts-project")
+
+	if languages := parascan.DetectProjectLanguages(projectPath, stackData, nil, 0); len(languages) != 0 {
+		t.Fatalf("Expected no manifest-based languages, got %v", languages)
+	}
+
+	languages := parascan.DetectLanguagesByExtension(projectPath, stackData, nil)
+	expected := []string{"shell", "python", "terraform"}
+	if !equalStringSlices(languages, expected) {
+		t.Errorf("Expected languages %v (ordered by file count), got %v", expected, languages)
+	}
+
+	// Projects with manifests still produce an extension-based guess, but it is only used as a fallback
+	if languages := parascan.DetectLanguagesByExtension(filepath.Join("testdata", "empty-project"), stackData, nil); len(languages) != 0 {
+		t.Errorf("Expected no source-file languages in empty-project, got %v", languages)
+	}
+}
+
+func TestComponentGraph(t *testing.T) {
+	graph := buildComponentGraph(filepath.Join("testdata", "monorepo-project"), nil)
+
+	if len(graph.Components) != 9 {
+		t.Errorf("Expected 9 components, got %d: %v", len(graph.Components), graph.Components)
+	}
+
+	var edges []string
+	for _, edge := range graph.Edges {
+		edges = append(edges, fmt.Sprintf("%s -> %s (%s)", edge.From, edge.To, edge.Type))
+	}
+	expected := []string{
+		"@acme/web -> @acme/ui (workspace)",
+		"api -> cache (depends_on)",
+		"api -> db (depends_on)",
+		"example.com/acme/api -> example.com/acme/shared (replace)",
+		"web -> api (depends_on)",
+	}
+	if !equalStringSlices(edges, expected) {
+		t.Errorf("Expected edges %v, got %v", expected, edges)
+	}
+
+	// Single-component projects have no internal structure to report
+	if graph := buildComponentGraph(filepath.Join("testdata", "nodejs-project"), nil); graph.hasInternalStructure() {
+		t.Errorf("Expected no internal structure for nodejs-project, got %v", graph)
+	}
+
+	// Names are only unique per kind: depends_on names compose services, workspace
+	// dependencies npm packages, even when the other kind has a component of that name
+	projectPath := t.TempDir()
+	os.WriteFile(filepath.Join(projectPath, "docker-compose.yml"), []byte("services:\n  web:\n    depends_on: [api]\n  worker:\n    depends_on: [db]\n  db: {}\n"), 0644)
+	os.MkdirAll(filepath.Join(projectPath, "packages", "api"), 0755)
+	os.WriteFile(filepath.Join(projectPath, "packages", "api", "package.json"), []byte(`{"name": "api", "dependencies": {"db": "workspace:*"}}`), 0644)
+	graph = buildComponentGraph(projectPath, nil)
+	edges = nil
+	for _, edge := range graph.Edges {
+		edges = append(edges, fmt.Sprintf("%s -> %s (%s)", edge.From, edge.To, edge.Type))
+	}
+	if expected := []string{"worker -> db (depends_on)"}; !equalStringSlices(edges, expected) {
+		t.Errorf("Expected edges %v, got %v", expected, edges)
+	}
+	var dot strings.Builder
+	outputDotFormat(&dot, graph)
+	for _, expected := range []string{`"npm:api" [label="api\n(npm)"]`, `"compose:worker" -> "compose:db"`} {
+		if !strings.Contains(dot.String(), expected) {
+			t.Errorf("Expected %s in\n%s", expected, dot.String())
+		}
+	}
+}
+
+func TestProjectSettings(t *testing.T) {
+	skipWithoutPack(t, "requirements.txt")
+	projectPath := filepath.Join("testdata", "settings-project")
+
+	settings, err := parascan.LoadProjectSettings(projectPath)
+	if err != nil {
+		t.Fatalf("Failed to load project settings: %v", err)
+	}
+	catalog, err := parascan.EmbeddedCatalog()
+	if err != nil {
+		t.Fatalf("Failed to load catalog: %v", err)
+	}
+
+	scan := runScan(projectPath, catalog, ScanOptions{Settings: settings})
+	results := settings.ApplyToResults(scan.Results)
+
+	var keys []string
+	for key := range results {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	// twilio is only in the ignored requirements file, openai is disabled, stripe is renamed
+	// and the git detector is switched off so no repo is reported
+	expected := []string{"Stripe Payments", "sentry"}
+	if !equalStringSlices(keys, expected) {
+		t.Errorf("Expected results %v, got %v", expected, keys)
+	}
+	if settings.Output.ProjectName != "billing" {
+		t.Errorf("Expected project name override billing, got %q", settings.Output.ProjectName)
+	}
+}
+
+func TestCategoryFileDetection(t *testing.T) {
+	fileData, err := loadFileDetectorsData()
+	if err != nil {
+		t.Fatalf("Failed to load file detectors: %v", err)
+	}
+	filesDetector := detectors.NewFilesDetector(fileData)
+
+	detect := func(project string) map[string]string {
+		results, err := filesDetector.Detect(&detectors.DetectionContext{
+			ProjectPath: filepath.Join("testdata", project),
+			Results:     make(map[string]string),
+		})
+		if err != nil {
+			t.Fatalf("Files detector failed for %s: %v", project, err)
+		}
+		return results
+	}
+
+	tests := []struct {
+		project  string
+		category string
+		expected []string
+		absent   []string
+	}{
+		{"api-gateway-project", "api_gateway", []string{"Apollo GraphQL", "Hasura", "Kong", "AWS API Gateway"}, []string{"Tyk"}},
+		{"ecommerce-project", "ecommerce", []string{"Shopify App", "Medusa", "WooCommerce"}, []string{"Saleor"}},
+		{"cms-project", "cms", []string{"Sanity", "Contentful", "Prismic"}, []string{"Strapi", "Storyblok"}},
+		{"i18n-project", "localization", []string{"Crowdin", "Lokalise", "Transifex"}, []string{"Phrase"}},
+		{"oncall-project", "oncall", []string{"PagerDuty", "Opsgenie", "incident.io"}, nil},
+		{"storage-project", "storage", []string{"MinIO", "Cloudinary", "ImageKit"}, []string{"Amazon S3", "Google Cloud Storage", "Azure Blob Storage"}},
+		{"testing-project", "testing", []string{"Cypress", "Playwright", "Selenium", "BrowserStack"}, []string{"Sauce Labs", "Percy"}},
+		{"quality-project", "quality", []string{"Codecov", "SonarCloud"}, []string{"Coveralls", "SonarQube"}},
+		{"baas-project", "baas", []string{"Supabase", "Firebase", "Appwrite", "AWS Amplify"}, nil},
+		{"workers-project", "edge", []string{"Cloudflare Workers", "Cloudflare Workers KV", "Cloudflare R2", "Cloudflare D1", "Cloudflare Durable Objects"}, []string{"Cloudflare Queues"}},
+		{"docs-site-project", "docs", []string{"Docusaurus", "GitBook", "GitHub Pages"}, []string{"Read the Docs"}},
+		{"security-project", "security", []string{"Snyk", "Trivy", "Dependency audit"}, []string{"Grype", "OWASP Dependency-Check"}},
+		{"hosting-project", "hosting", []string{"Fly.io", "Render", "Vercel", "Heroku"}, []string{"Netlify", "Railway"}},
+		// Terraform and PHP files without gateway resources or plugin headers don't count
+		{"scripts-project", "", nil, []string{"AWS API Gateway", "WooCommerce", "PagerDuty", "Opsgenie"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.project, func(t *testing.T) {
+			results := detect(tt.project)
+			for _, name := range tt.expected {
+				if results[name] == "" {
+					t.Errorf("Expected %s in %s, got %v", name, tt.project, results)
+				}
+			}
+			for _, name := range tt.absent {
+				if _, found := results[name]; found {
+					t.Errorf("Did not expect %s in %s", name, tt.project)
+				}
+			}
+			for _, tech := range fileData.Technologies {
+				for _, name := range tt.expected {
+					if tech.DisplayName == name && tech.Category != tt.category {
+						t.Errorf("Expected %s in %s category, got %q", name, tt.category, tech.Category)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestDocsSite(t *testing.T) {
+	detect := func(fsys fs.FS, repoURL string) map[string]string {
+		results := map[string]string{}
+		if repoURL != "" {
+			results[detectors.RepoKey] = repoURL
+		}
+		return detectortest.Detect(t, detectors.NewDocsSiteDetector(), fsys, detectortest.WithResults(results))
+	}
+
+	// The Docusaurus config tells the published URL, with or without a repository
+	fixture := os.DirFS(filepath.Join("testdata", "docs-site-project"))
+	for _, repoURL := range []string{"", "https://github.com/acme/widgets"} {
+		if site := detect(fixture, repoURL)[detectors.DocsSiteKey]; site != "https://acme.github.io/widgets" {
+			t.Errorf("Expected the Docusaurus url and baseUrl, got %q", site)
+		}
+	}
+
+	// A custom domain wins over the github.io address of the gh-pages branch
+	project := fstest.MapFS{
+		".git/packed-refs": {Data: []byte("# pack-refs with: peeled fully-peeled sorted\n1a2b3c refs/remotes/origin/gh-pages\n4d5e6f refs/remotes/origin/main\n")},
+	}
+	detectortest.RunDetector(t, detectors.NewDocsSiteDetector(), project, map[string]string{
+		detectors.DocsSiteKey: "https://acme.github.io/widgets",
+		"GitHub Pages":        "https://github.com/Acme/widgets/settings/pages",
+	}, detectortest.WithResults(map[string]string{detectors.RepoKey: "https://github.com/Acme/widgets"}))
+	project["docs/CNAME"] = &fstest.MapFile{Data: []byte("docs.acme.dev\n")}
+	if site := detect(project, "https://github.com/acme/widgets")[detectors.DocsSiteKey]; site != "https://docs.acme.dev" {
+		t.Errorf("Expected the CNAME domain, got %q", site)
+	}
+
+	// A user site is served from the root, other hosts don't publish to github.io
+	project = fstest.MapFS{".git/refs/heads/gh-pages": {Data: []byte("1a2b3c\n")}}
+	if site := detect(project, "https://github.com/acme/acme.github.io")[detectors.DocsSiteKey]; site != "https://acme.github.io" {
+		t.Errorf("Expected the user site at the root, got %q", site)
+	}
+	detectortest.RunDetector(t, detectors.NewDocsSiteDetector(), project, nil,
+		detectortest.WithResults(map[string]string{detectors.RepoKey: "https://gitlab.com/acme/widgets"}))
+
+	// Read the Docs needs its config and the project named in the docs
+	project = fstest.MapFS{
+		"README.md": {Data: []byte("[![Docs](https://readthedocs.org/projects/acme-widgets/badge/?version=latest)](https://acme-widgets.readthedocs.io)\n")},
+	}
+	detectortest.RunDetector(t, detectors.NewDocsSiteDetector(), project, nil)
+	project[".readthedocs.yaml"] = &fstest.MapFile{Data: []byte("version: 2\n")}
+	if site := detect(project, "")[detectors.DocsSiteKey]; site != "https://acme-widgets.readthedocs.io" {
+		t.Errorf("Expected the readthedocs.io site, got %q", site)
+	}
+
+	// The site is written under docs_site
+	if name := configEntryName(detectors.DocsSiteKey, "https://docs.acme.dev", "Repository"); name != "docs_site" {
+		t.Errorf("Expected the docs_site config entry, got %s", name)
+	}
+}
+
+func TestTemplatedFileURLs(t *testing.T) {
+	fileData, err := loadFileDetectorsData()
+	if err != nil {
+		t.Fatalf("Failed to load file detectors: %v", err)
+	}
+
+	// Space and project IDs come from config files
+	expected := map[string]map[string]string{
+		"cms-project": {
+			"Sanity":     "https://www.sanity.io/manage/project/x7k2m9qp",
+			"Contentful": "https://app.contentful.com/spaces/abc123space",
+			"Prismic":    "https://acme-marketing.prismic.io",
+		},
+		"i18n-project": {
+			"Lokalise":  "https://app.lokalise.com/project/3216542605f2c3d2a51c63.59731412/",
+			"Transifex": "https://app.transifex.com/acme/mobile-app/dashboard/",
+			"Crowdin":   "https://crowdin.com/projects",
+		},
+		"testing-project": {
+			"Cypress":      "https://cloud.cypress.io/projects/a7bq2k",
+			"BrowserStack": "https://automate.browserstack.com/dashboard",
+		},
+		"baas-project": {
+			"Supabase":    "https://supabase.com/dashboard/project/qwertyuiopasdfghjklz",
+			"Firebase":    "https://console.firebase.google.com/project/field-notes-prod/overview",
+			"Appwrite":    "https://cloud.appwrite.io/console/project-field-notes-6512ab/overview",
+			"AWS Amplify": "https://eu-west-1.console.aws.amazon.com/amplify/apps/d2x7k9q1fieldn/overview",
+		},
+		"workers-project": {
+			"Cloudflare Workers": "https://dash.cloudflare.com/3f4b8c1d2e5a6b7c8d9e0f1a2b3c4d5e/workers/services/view/edge-api/production",
+			"Cloudflare D1":      "https://dash.cloudflare.com/3f4b8c1d2e5a6b7c8d9e0f1a2b3c4d5e/workers/d1",
+		},
+	}
+
+	for project, urls := range expected {
+		results, err := detectors.NewFilesDetector(fileData).Detect(&detectors.DetectionContext{
+			ProjectPath: filepath.Join("testdata", project),
+			Results:     make(map[string]string),
+		})
+		if err != nil {
+			t.Fatalf("Files detector failed for %s: %v", project, err)
+		}
+		for name, url := range urls {
+			if results[name] != url {
+				t.Errorf("%s: expected %s URL %s, got %q", project, name, url, results[name])
+			}
+		}
+	}
+
+	// Without `supabase link`, the project ref comes from the Supabase URL in env templates
+	detectortest.RunDetector(t, detectors.NewFilesDetector(fileData), fstest.MapFS{
+		"supabase":     {Mode: fs.ModeDir},
+		".env.example": {Data: []byte("NEXT_PUBLIC_SUPABASE_URL=https://abcdefghijklmnopqrst.supabase.co\n")},
+	}, map[string]string{"Supabase": "https://supabase.com/dashboard/project/abcdefghijklmnopqrst"})
+}
+
+func TestRepoSlugFileURLs(t *testing.T) {
+	fileData, err := loadFileDetectorsData()
+	if err != nil {
+		t.Fatalf("Failed to load file detectors: %v", err)
+	}
+	filesDetector := detectors.NewFilesDetector(fileData)
+	projectPath := filepath.Join("testdata", "quality-project")
+
+	// Dashboards are templated from the repo slug when the hosting matches
+	results, err := filesDetector.Detect(&detectors.DetectionContext{
+		ProjectPath: projectPath,
+		Results:     map[string]string{"repo": "https://github.com/acme/widgets"},
+	})
+	if err != nil {
+		t.Fatalf("Files detector failed: %v", err)
+	}
+	expected := map[string]string{
+		"Codecov":    "https://app.codecov.io/gh/acme/widgets",
+		"SonarCloud": "https://sonarcloud.io/project/overview?id=acme_widgets",
+	}
+	for name, url := range expected {
+		if results[name] != url {
+			t.Errorf("Expected %s URL %s, got %q", name, url, results[name])
+		}
+	}
+
+	results, _ = filesDetector.Detect(&detectors.DetectionContext{
+		ProjectPath: projectPath,
+		Results:     map[string]string{"repo": "https://gitlab.com/acme/widgets"},
+	})
+	if results["Codecov"] != "https://app.codecov.io" {
+		t.Errorf("Expected Codecov fallback URL for GitLab repo, got %q", results["Codecov"])
+	}
+}
+
+func TestFrameworkServiceHints(t *testing.T) {
+	catalog, err := parascan.EmbeddedCatalog()
+	if err != nil {
+		t.Fatalf("Failed to load catalog: %v", err)
+	}
+	servicesDetector := detectors.NewServicesDetector(parascan.NewServicesAdapter(catalog.Stack, catalog.Services))
+
+	// None of these services are in the dependency files, only in framework config
+	tests := []struct {
+		project  string
+		expected []string
+	}{
+		{"rails-project", []string{"aws_s3", "sentry", "stripe"}},
+		{"django-project", []string{"cloudinary", "sentry", "stripe"}},
+		{"laravel-project", []string{"aws", "aws_s3", "postmark", "slack"}},
+		{"spring-project", []string{"sentry", "stripe", "twilio"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.project, func(t *testing.T) {
+			results, err := servicesDetector.Detect(filepath.Join("testdata", tt.project))
+			if err != nil {
+				t.Fatalf("Services detector failed: %v", err)
+			}
+
+			var keys []string
+			for key := range results {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			if !equalStringSlices(keys, tt.expected) {
+				t.Errorf("Expected services %v, got %v", tt.expected, keys)
+			}
+		})
+	}
+}
+
+func TestServiceEvidence(t *testing.T) {
+	skipWithoutPack(t, "Gemfile", "requirements.txt")
+	catalog, err := parascan.EmbeddedCatalog()
+	if err != nil {
+		t.Fatalf("Failed to load catalog: %v", err)
+	}
+
+	// stripe is in both the Gemfile and requirements.txt: one result, two languages of evidence
+	scan := runScan(filepath.Join("testdata", "multi-language"), catalog, ScanOptions{SkipGit: true})
+	if _, found := scan.Results["stripe"]; !found {
+		t.Fatalf("Expected stripe in results, got %v", scan.Results)
+	}
+	expected := []parascan.ServiceEvidence{
+		{Detector: "services", Language: "ruby", Package: "stripe", File: "Gemfile"},
+		{Detector: "services", Language: "python", Package: "stripe", File: "requirements.txt"},
+	}
+	evidence := scan.Evidence["stripe"]
+	if len(evidence) != len(expected) {
+		t.Fatalf("Expected stripe evidence %v, got %v", expected, evidence)
+	}
+	for i := range expected {
+		if evidence[i] != expected[i] {
+			t.Errorf("Expected stripe evidence %v, got %v", expected, evidence)
+			break
+		}
+	}
+
+	// Framework config hints are evidence too
+	scan = runScan(filepath.Join("testdata", "rails-project"), catalog, ScanOptions{SkipGit: true})
+	if evidence := scan.Evidence["stripe"]; len(evidence) != 1 || evidence[0].Framework != "rails" || evidence[0].File != "config/initializers/stripe.rb" {
+		t.Errorf("Expected stripe evidence from rails initializer, got %v", evidence)
+	}
+
+	// Every result names its detector: files with the pattern and file that matched,
+	// chart dependencies with their chart, ingress hosts only the detector
+	scan = runScan(filepath.Join("testdata", "kubernetes-project"), catalog, ScanOptions{SkipGit: true})
+	expectedEvidence := map[string]parascan.ServiceEvidence{
+		"Helm":                 {Detector: "files", Pattern: "*/Chart.yaml", File: "chart/Chart.yaml"},
+		"redis":                {Detector: "helm", Chart: "redis", File: "chart/Chart.yaml"},
+		"Ingress api.acme.com": {Detector: "kubernetes"},
+	}
+	for key, want := range expectedEvidence {
+		if evidence := scan.Evidence[key]; len(evidence) != 1 || evidence[0] != want {
+			t.Errorf("Expected %s evidence %v, got %v", key, want, evidence)
+		}
+	}
+	for key := range scan.Results {
+		if evidence := scan.Evidence[key]; len(evidence) == 0 || evidence[0].Detector == "" {
+			t.Errorf("Expected %s evidence to name its detector, got %v", key, evidence)
+		}
+	}
+}
+
+func TestMinEvidenceThreshold(t *testing.T) {
+	skipWithoutPack(t, "package.json", "Gemfile")
+	projectPath := filepath.Join("testdata", "evidence-project")
+
+	settings, err := parascan.LoadProjectSettings(projectPath)
+	if err != nil {
+		t.Fatalf("Failed to load project settings: %v", err)
+	}
+	catalog, err := parascan.EmbeddedCatalog()
+	if err != nil {
+		t.Fatalf("Failed to load catalog: %v", err)
+	}
+
+	scan := runScan(projectPath, catalog, ScanOptions{Settings: settings})
+
+	var keys []string
+	for key := range scan.Results {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	// stripe is in two dependency files, sentry is configured in a rails initializer,
+	// twilio only appears in the Gemfile
+	expected := []string{"sentry", "stripe"}
+	if !equalStringSlices(keys, expected) {
+		t.Errorf("Expected results %v, got %v", expected, keys)
+	}
+	if !equalStringSlices(scan.Skipped, []string{"twilio"}) {
+		t.Errorf("Expected twilio to be skipped, got %v", scan.Skipped)
+	}
+}
+
+func TestDocsInference(t *testing.T) {
+	projectPath := filepath.Join("testdata", "docs-project")
+	catalog, err := parascan.EmbeddedCatalog()
+	if err != nil {
+		t.Fatalf("Failed to load catalog: %v", err)
+	}
+
+	// Docs are opt-in, the archived project has no manifests to go by
+	if scan := runScan(projectPath, catalog, ScanOptions{SkipGit: true}); len(scan.Results) != 0 {
+		t.Errorf("Expected no results without docs inference, got %v", scan.Results)
+	}
+
+	scan := runScan(projectPath, catalog, ScanOptions{SkipGit: true, Docs: true})
+	expected := []string{"Codecov", "Netlify", "sentry", "slack", "stripe"}
+	if !equalStringSlices(scan.Inferred, expected) {
+		t.Errorf("Expected inferred services %v, got %v", expected, scan.Inferred)
+	}
+	if evidence := scan.Evidence["slack"]; len(evidence) != 1 || evidence[0].File != "docs/operations.md" {
+		t.Errorf("Expected slack evidence from docs/operations.md, got %v", evidence)
+	}
+}
+
+func TestIaCState(t *testing.T) {
+	projectPath := filepath.Join("testdata", "iac-project")
+	catalog, err := parascan.EmbeddedCatalog()
+	if err != nil {
+		t.Fatalf("Failed to load catalog: %v", err)
+	}
+
+	// State files hold secrets, they're only read on request
+	if scan := runScan(projectPath, catalog, ScanOptions{SkipGit: true}); len(scan.Results) != 0 {
+		t.Errorf("Expected no results without reading state, got %v", scan.Results)
+	}
+
+	scan := runScan(projectPath, catalog, ScanOptions{SkipGit: true, IaCState: true})
+	var keys []string
+	for key := range scan.Results {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	expected := []string{"aws", "aws_s3", "datadog", "sentry"}
+	if !equalStringSlices(keys, expected) {
+		t.Errorf("Expected results %v, got %v", expected, keys)
+	}
+	if evidence := scan.Evidence["datadog"]; len(evidence) != 1 || evidence[0].Resource != "datadog_monitor" || evidence[0].File != "infra/terraform.tfstate" {
+		t.Errorf("Expected datadog evidence from the Terraform state, got %v", evidence)
+	}
+	if evidence := scan.Evidence["sentry"]; len(evidence) != 1 || evidence[0].File != ".pulumi/stacks/billing/dev.json" {
+		t.Errorf("Expected sentry evidence from the Pulumi stack, got %v", evidence)
+	}
+
+	// Nothing but resource types leaves the state
+	report, _ := json.Marshal(scan)
+	if strings.Contains(string(report), "tfstate-secret-do-not-read") {
+		t.Errorf("Expected no state values in the report, got %s", report)
+	}
+}
+
+func TestBadgeURLs(t *testing.T) {
+	catalog, err := parascan.EmbeddedCatalog()
+	if err != nil {
+		t.Fatalf("Failed to load catalog: %v", err)
+	}
+
+	// Without a git remote the project slug comes from the README badges
+	scan := runScan(filepath.Join("testdata", "badges-project"), catalog, ScanOptions{SkipGit: true})
+	expected := map[string]string{
+		"Codecov":        "https://app.codecov.io/gh/acme/widgets",
+		"GitHub Actions": "https://github.com/acme/widgets/actions",
+		"Travis CI":      "https://app.travis-ci.com/github/acme/widgets",
+	}
+	for key, url := range expected {
+		if scan.Results[key] != url {
+			t.Errorf("Expected %s URL %s, got %q", key, url, scan.Results[key])
+		}
+		// The badge comes before the file that detected the technology
+		if evidence := scan.Evidence[key]; len(evidence) != 2 || evidence[0].File != "README.md" || evidence[1].Pattern == "" {
+			t.Errorf("Expected %s badge evidence from README.md and a matched file, got %v", key, evidence)
+		}
+	}
+
+	// Badges of undetected technologies only count when docs inference is on
+	scan = runScan(filepath.Join("testdata", "docs-project"), catalog, ScanOptions{SkipGit: true, Docs: true})
+	if url := scan.Results["Netlify"]; url != "https://app.netlify.com/sites/legacy-billing" {
+		t.Errorf("Expected Netlify site URL from badge, got %q", url)
+	}
+}
+
+func TestOutdatedCIConfig(t *testing.T) {
+	catalog, err := parascan.EmbeddedCatalog()
+	if err != nil {
+		t.Fatalf("Failed to load catalog: %v", err)
+	}
+
+	scan := runScan(filepath.Join("testdata", "legacy-ci-project"), catalog, ScanOptions{SkipGit: true})
+	var locations []string
+	for _, warning := range scan.Warnings {
+		locations = append(locations, warning.Key+" "+warning.Location())
+	}
+	expected := []string{
+		"GitHub Actions .github/workflows/release.yml:11",
+		"GitHub Actions .github/workflows/release.yml:13",
+		"GitHub Actions .github/workflows/release.yml:18",
+		"Travis CI .travis.yml:4",
+		"Travis CI .travis.yml:5",
+		"Travis CI README.md:3",
+		"CircleCI circle.yml",
+	}
+	if strings.Join(locations, ", ") != strings.Join(expected, ", ") {
+		t.Errorf("Expected warnings at %v, got %v", expected, locations)
+	}
+
+	// Current configuration has nothing outdated
+	scan = runScan(filepath.Join("testdata", "badges-project"), catalog, ScanOptions{SkipGit: true})
+	if len(scan.Warnings) > 0 {
+		t.Errorf("Expected no warnings, got %v", scan.Warnings)
+	}
+}
+
+func TestKubernetesManifests(t *testing.T) {
+	catalog, err := parascan.EmbeddedCatalog()
+	if err != nil {
+		t.Fatalf("Failed to load catalog: %v", err)
+	}
+
+	scan := runScan(filepath.Join("testdata", "kubernetes-project"), catalog, ScanOptions{SkipGit: true})
+	manifests := scan.Kubernetes
+	if manifests == nil {
+		t.Fatalf("Expected Kubernetes manifests, got %v", scan.Results)
+	}
+
+	// Helm templates and docker-compose.yml aren't manifests, kustomizations are
+	expected := map[string][]string{
+		"files":       {"deploy/base/deployment.yaml", "deploy/base/ingress.yaml", "deploy/base/kustomization.yaml", "deploy/overlays/prod/kustomization.yaml"},
+		"images":      {"ghcr.io/acme/api-migrations:1.4.2", "ghcr.io/acme/api:1.4.2", "ghcr.io/acme/api:1.5.0"},
+		"hosts":       {"*.preview.acme.com", "api.acme.com"},
+		"secrets":     {"api-tls", "sentry", "stripe"},
+		"config maps": {"api-config"},
+	}
+	got := map[string][]string{
+		"files":       manifests.Files,
+		"images":      manifests.Images,
+		"hosts":       manifests.Hosts,
+		"secrets":     manifests.Secrets,
+		"config maps": manifests.ConfigMaps,
+	}
+	for name, values := range expected {
+		if strings.Join(got[name], ", ") != strings.Join(values, ", ") {
+			t.Errorf("Expected %s %v, got %v", name, values, got[name])
+		}
+	}
+
+	// Hosts are entries of their own, wildcards can't be visited
+	if url := scan.Results["Ingress api.acme.com"]; url != "https://api.acme.com" {
+		t.Errorf("Expected the Ingress host as a result, got %v", scan.Results)
+	}
+	if _, found := scan.Results["Ingress *.preview.acme.com"]; found || scan.Results["Kubernetes"] == "" {
+		t.Errorf("Expected Kubernetes without wildcard hosts, got %v", scan.Results)
+	}
+	if name := configEntryName("Ingress api.acme.com", "https://api.acme.com", "Repository"); name != "Ingress api.acme.com" {
+		t.Errorf("Expected the host kept in the config entry name, got %s", name)
+	}
+}
+
+func TestHelmCharts(t *testing.T) {
+	catalog, err := parascan.EmbeddedCatalog()
+	if err != nil {
+		t.Fatalf("Failed to load catalog: %v", err)
+	}
+
+	scan := runScan(filepath.Join("testdata", "kubernetes-project"), catalog, ScanOptions{SkipGit: true})
+	charts := scan.Helm
+	if charts == nil || strings.Join(charts.Charts, ", ") != "chart/Chart.yaml" {
+		t.Fatalf("Expected the chart in chart/, got %v", charts)
+	}
+	if scan.Results["Helm"] == "" {
+		t.Errorf("Expected Helm for a chart below the root, got %v", scan.Results)
+	}
+
+	// Chart dependencies the catalog knows are services, others are only listed
+	var dependencies []string
+	for _, dependency := range charts.Dependencies {
+		dependencies = append(dependencies, dependency.Name+" "+dependency.Repository)
+	}
+	expected := "common https://charts.bitnami.com/bitnami, postgresql oci://registry-1.docker.io/bitnamicharts, redis https://charts.bitnami.com/bitnami"
+	if strings.Join(dependencies, ", ") != expected {
+		t.Errorf("Expected dependencies %s, got %v", expected, dependencies)
+	}
+	for _, key := range []string{"redis", "postgres"} {
+		if evidence := scan.Evidence[key]; len(evidence) != 1 || evidence[0].File != "chart/Chart.yaml" || evidence[0].Chart == "" {
+			t.Errorf("Expected %s from a chart dependency, got %v", key, evidence)
+		}
+	}
+
+	// Templated image repositories are left out
+	images := "ghcr.io/acme/api:1.4.2, ghcr.io/acme/worker:1.4.2, prom/statsd-exporter:0.26"
+	if strings.Join(charts.Images, ", ") != images {
+		t.Errorf("Expected images %s, got %v", images, charts.Images)
+	}
+}
+
+func TestEnvVariables(t *testing.T) {
+	catalog, err := parascan.EmbeddedCatalog()
+	if err != nil {
+		t.Fatalf("Failed to load catalog: %v", err)
+	}
+	projectPath := filepath.Join("testdata", "env-project")
+
+	// Templates in any project directory count, commented out variables and
+	// .env don't
+	scan := runScan(projectPath, catalog, ScanOptions{SkipGit: true})
+	for _, key := range []string{"stripe", "sentry", "twilio", "aws"} {
+		if scan.Results[key] == "" {
+			t.Errorf("Expected %s from environment variables, got %v", key, scan.Results)
+		}
+	}
+	for _, key := range []string{"mailgun", "openai"} {
+		if _, found := scan.Results[key]; found {
+			t.Errorf("Expected no %s, got %v", key, scan.Results)
+		}
+	}
+	variables := func(key string) string {
+		var found []string
+		for _, evidence := range scan.Evidence[key] {
+			found = append(found, evidence.Variable+" ("+evidence.File+")")
+		}
+		return strings.Join(found, ", ")
+	}
+	if got := variables("stripe"); got != "NEXT_PUBLIC_STRIPE_PUBLISHABLE_KEY (.env.example), STRIPE_SECRET_KEY (.env.example)" {
+		t.Errorf("Unexpected Stripe evidence: %s", got)
+	}
+	if got := variables("aws"); got != "AWS_ACCESS_KEY_ID (worker/.env.template), AWS_REGION (worker/.env.template), AWS_SECRET_ACCESS_KEY (worker/.env.template)" {
+		t.Errorf("Unexpected AWS evidence: %s", got)
+	}
+
+	// With .env, names are read and values never are
+	scan = runScan(projectPath, catalog, ScanOptions{SkipGit: true, Dotenv: true})
+	if scan.Results["openai"] == "" {
+		t.Errorf("Expected openai from .env, got %v", scan.Results)
+	}
+	data, _ := json.Marshal(scan.Evidence)
+	if strings.Contains(string(data), "sk-not-a-real-key") || strings.Contains(string(data), "sk_test") {
+		t.Errorf("Expected no values in the evidence, got %s", data)
+	}
+
+	// The detector can be switched off
+	disabled := false
+	settings := &parascan.ProjectSettings{Detectors: map[string]parascan.DetectorSettings{parascan.EnvDetector: {Enabled: &disabled}}}
+	scan = runScan(projectPath, catalog, ScanOptions{SkipGit: true, Settings: settings})
+	if _, found := scan.Results["twilio"]; found {
+		t.Errorf("Expected no services from variables with the env detector disabled, got %v", scan.Results)
+	}
+}
+
+func TestDeepImports(t *testing.T) {
+	catalog, err := parascan.EmbeddedCatalog()
+	if err != nil {
+		t.Fatalf("Failed to load catalog: %v", err)
+	}
+	projectPath := filepath.Join("testdata", "deep-project")
+	services := []string{"stripe", "sentry", "twilio", "algolia", "posthog"}
+
+	// No dependency file lists the SDKs
+	scan := runScan(projectPath, catalog, ScanOptions{SkipGit: true})
+	for _, key := range services {
+		if _, found := scan.Results[key]; found {
+			t.Errorf("Expected no %s without --deep, got %v", key, scan.Results)
+		}
+	}
+
+	// Go imports match modules by prefix and major version, Python modules their
+	// distribution names, JavaScript specifiers their package. Vendored and
+	// installed packages' own imports don't count.
+	scan = runScan(projectPath, catalog, ScanOptions{SkipGit: true, Deep: true})
+	expected := map[string]string{
+		"stripe":  "go github.com/stripe/stripe-go github.com/stripe/stripe-go/v76 cmd/billing/main.go",
+		"sentry":  "python sentry-sdk sentry_sdk worker/tasks.py",
+		"twilio":  "python twilio twilio.rest worker/tasks.py",
+		"algolia": "nodejs algoliasearch algoliasearch/lite web/src/search.ts",
+		"posthog": "nodejs posthog-js posthog-js web/src/search.ts",
+	}
+	for _, key := range services {
+		var evidence []string
+		for _, item := range scan.Evidence[key] {
+			evidence = append(evidence, strings.Join([]string{item.Language, item.Package, item.Import, item.File}, " "))
+		}
+		if strings.Join(evidence, ", ") != expected[key] {
+			t.Errorf("Expected %s from %s, got %v", key, expected[key], evidence)
+		}
+	}
+	if _, found := scan.Results["openai"]; found {
+		t.Errorf("Expected no openai from node_modules, got %v", scan.Results)
+	}
+}
+
+func TestHostingPlatforms(t *testing.T) {
+	fileData, err := loadFileDetectorsData()
+	if err != nil {
+		t.Fatalf("Failed to load file detectors: %v", err)
+	}
+	detect := func(projectPath string) map[string]string {
+		results, err := detectors.NewFilesDetector(fileData).Detect(&detectors.DetectionContext{
+			ProjectPath: projectPath,
+			Results:     make(map[string]string),
+		})
+		if err != nil {
+			t.Fatalf("Files detector failed for %s: %v", projectPath, err)
+		}
+		return results
+	}
+
+	// App names from the platforms' config: the first web service of a Render
+	// blueprint, Heroku's app.json
+	results := detect(filepath.Join("testdata", "hosting-project"))
+	for name, url := range map[string]string{
+		"Fly.io": "https://fly.io/apps/acme-api",
+		"Render": "https://acme-web.onrender.com",
+		"Vercel": "https://acme-site.vercel.app",
+		"Heroku": "https://dashboard.heroku.com/apps/acme-api",
+	} {
+		if results[name] != url {
+			t.Errorf("Expected %s at %s, got %q", name, url, results[name])
+		}
+	}
+
+	// An Expo app.json isn't Heroku's, a display name isn't an app name
+	detectortest.RunDetector(t, detectors.NewFilesDetector(fileData), fstest.MapFS{
+		"app.json": {Data: []byte(`{"expo": {"name": "Acme", "slug": "acme", "web": {"bundler": "metro"}}}`)},
+	}, nil)
+	detectortest.RunDetector(t, detectors.NewFilesDetector(fileData), fstest.MapFS{
+		"app.json": {Data: []byte(`{"name": "Acme API", "addons": ["heroku-postgresql"]}`)},
+	}, map[string]string{"Heroku": "https://dashboard.heroku.com/apps"})
+}
+
+func TestServerlessDeployments(t *testing.T) {
+	catalog, err := parascan.EmbeddedCatalog()
+	if err != nil {
+		t.Fatalf("Failed to load catalog: %v", err)
+	}
+
+	// Values resolved at deploy time aren't taken, SAM functions default to the
+	// global runtime, the Pulumi cloud comes from the stack config
+	scan := runScan(filepath.Join("testdata", "serverless-project"), catalog, ScanOptions{SkipGit: true})
+	expected := []parascan.ServerlessProject{
+		{Tool: "AWS CDK", File: "infra/cdk.json", Provider: "aws", Runtimes: []string{"typescript"}},
+		{Tool: "Pulumi", File: "pulumi/Pulumi.yaml", Name: "shop-infra", Provider: "google", Region: "europe-west1", Project: "acme-shop-prod", Runtimes: []string{"python"}},
+		{Tool: "AWS SAM", File: "sam/template.yaml", Name: "shop-orders", Provider: "aws", Region: "us-east-1", Runtimes: []string{"python3.12"}, Functions: 2},
+		{Tool: "Serverless Framework", File: "serverless.yml", Name: "shop-api", Provider: "aws", Region: "eu-west-1", Runtimes: []string{"nodejs20.x", "python3.12"}, Functions: 2},
+	}
+	if fmt.Sprint(scan.Serverless) != fmt.Sprint(expected) {
+		t.Errorf("Expected %+v, got %+v", expected, scan.Serverless)
+	}
+	for key, url := range map[string]string{
+		"Serverless Framework": "https://app.serverless.com",
+		"AWS SAM":              "https://us-east-1.console.aws.amazon.com/cloudformation/home?region=us-east-1#/stacks?filteringText=shop-orders",
+		"AWS CDK":              "https://console.aws.amazon.com/cloudformation/home#/stacks",
+		"Pulumi":               "https://app.pulumi.com",
+		"AWS Lambda":           "https://us-east-1.console.aws.amazon.com/lambda/home?region=us-east-1#/functions",
+	} {
+		if scan.Results[key] != url {
+			t.Errorf("Expected %s at %s, got %q", key, url, scan.Results[key])
+		}
+	}
+
+	// Plain CloudFormation isn't SAM, functions deploy to the provider's platform
+	detectortest.RunDetector(t, detectors.NewServerlessDetector(), fstest.MapFS{
+		"template.yaml": {Data: []byte("Resources:\n  Bucket:\n    Type: AWS::S3::Bucket\n")},
+	}, nil)
+	detectortest.RunDetector(t, detectors.NewServerlessDetector(), fstest.MapFS{
+		"serverless.yml": {Data: []byte("service: reports\nprovider:\n  name: google\n  project: acme-reports\n  runtime: nodejs18\nfunctions:\n  nightly:\n    handler: nightly\n")},
+	}, map[string]string{
+		"Serverless Framework":   "https://app.serverless.com",
+		"Google Cloud Functions": "https://console.cloud.google.com/functions/list?project=acme-reports",
+	})
+}
+
+func TestContentRules(t *testing.T) {
+	projectPath := filepath.Join("testdata", "content-project")
+	configHome := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configHome)
+	catalog, _, err := resolveCatalog(projectPath, "", &parascan.ProjectSettings{}, TrustPolicy{}, false)
+	if err != nil {
+		t.Fatalf("Failed to resolve catalog: %v", err)
+	}
+
+	// Source files of the project's languages by default, a rule's own files
+	// otherwise; docs aren't read
+	scan := runScan(projectPath, catalog, ScanOptions{SkipGit: true})
+	for _, key := range []string{"stripe", "sentry", "acme-search"} {
+		if scan.Results[key] == "" {
+			t.Errorf("Expected %s from content rules, got %v", key, scan.Results)
+		}
+	}
+	if _, found := scan.Results["slack"]; found {
+		t.Errorf("Expected no Slack from docs, got %v", scan.Results)
+	}
+	evidence := scan.Evidence["stripe"]
+	if len(evidence) != 1 || evidence[0].Detector != parascan.ContentDetector || evidence[0].Pattern != `api\.stripe\.com` || evidence[0].File != "src/payments.js" || evidence[0].Line != 2 {
+		t.Errorf("Unexpected Stripe evidence: %+v", evidence)
+	}
+	if evidence := scan.Evidence["sentry"]; len(evidence) != 1 || evidence[0].File != "config/monitoring.yml" || evidence[0].Line != 2 {
+		t.Errorf("Expected the DSN rule scoped to YAML to match the config, got %+v", evidence)
+	}
+	data, _ := json.Marshal(scan.Evidence)
+	if strings.Contains(string(data), "0123456789abcdef") {
+		t.Errorf("Expected the rules rather than the matched text in the evidence, got %s", data)
+	}
+
+	// The detector can be switched off
+	disabled := false
+	settings := &parascan.ProjectSettings{Detectors: map[string]parascan.DetectorSettings{parascan.ContentDetector: {Enabled: &disabled}}}
+	scan = runScan(projectPath, catalog, ScanOptions{SkipGit: true, Settings: settings})
+	if _, found := scan.Results["stripe"]; found {
+		t.Errorf("Expected no services from content with the content detector disabled, got %v", scan.Results)
+	}
+
+	// A rule that doesn't compile fails the catalog
+	userRules := filepath.Join(configHome, "parascope", "rules", "services")
+	os.MkdirAll(userRules, 0755)
+	os.WriteFile(filepath.Join(userRules, "broken.yml"), []byte("name: Broken\ncontent:\n- match: 'api.(broken'\n"), 0644)
+	if _, _, err := resolveCatalog(projectPath, "", &parascan.ProjectSettings{}, TrustPolicy{}, false); err == nil || !strings.Contains(err.Error(), "content rule") {
+		t.Errorf("Expected an error for a broken content rule, got %v", err)
+	}
+}
+
+func TestRecursiveDependencyFiles(t *testing.T) {
+	skipWithoutPack(t, "package.json", "go.mod")
+	projectPath := filepath.Join("testdata", "monorepo-project")
+	catalog, err := parascan.EmbeddedCatalog()
+	if err != nil {
+		t.Fatalf("Failed to load catalog: %v", err)
+	}
+
+	// Go modules only live in services/ and libs/, stripe is used by packages/web and services/api
+	scan := runScan(projectPath, catalog, ScanOptions{SkipGit: true})
+	languages := append([]string(nil), scan.Languages...)
+	sort.Strings(languages)
+	if !equalStringSlices(languages, []string{"go", "nodejs"}) {
+		t.Errorf("Expected go and nodejs from nested dependency files, got %v", scan.Languages)
+	}
+	var files []string
+	for _, evidence := range scan.Evidence["stripe"] {
+		files = append(files, evidence.File)
+	}
+	sort.Strings(files)
+	if !equalStringSlices(files, []string{"packages/web/package.json", "services/api/go.mod"}) {
+		t.Errorf("Expected stripe evidence from packages/web/package.json and services/api/go.mod, got %v", scan.Evidence["stripe"])
+	}
+
+	// A depth of 1 only looks at the root
+	scan = runScan(projectPath, catalog, ScanOptions{SkipGit: true, MaxDepth: 1})
+	if _, found := scan.Results["stripe"]; found {
+		t.Errorf("Expected no stripe with max depth 1, got %v", scan.Results)
+	}
+	if !equalStringSlices(scan.Languages, []string{"nodejs"}) {
+		t.Errorf("Expected only nodejs at the root, got %v", scan.Languages)
+	}
+}
+
+func TestMonorepoSubprojects(t *testing.T) {
+	skipWithoutPack(t, "package.json", "go.mod")
+	projectPath := filepath.Join("testdata", "monorepo-project")
+	catalog, err := parascan.EmbeddedCatalog()
+	if err != nil {
+		t.Fatalf("Failed to load catalog: %v", err)
+	}
+
+	settings := &parascan.ProjectSettings{}
+	subprojects := findSubprojects(projectPath, catalog.Stack, nil, 0)
+	if !equalStringSlices(subprojects, []string{"libs/shared", "packages/ui", "packages/web", "services/api"}) {
+		t.Fatalf("Expected a sub-project per dependency file directory, got %v", subprojects)
+	}
+
+	// The root scan leaves sub-project services to their own sections
+	rootSettings := subprojectSettings(settings, ".", subprojects)
+	scan := runScan(projectPath, catalog, ScanOptions{SkipGit: true, Settings: rootSettings})
+	if _, found := scan.Results["stripe"]; found {
+		t.Errorf("Expected stripe only in sub-projects, got root results %v", scan.Results)
+	}
+
+	configPath := filepath.Join(t.TempDir(), "parascope.yml")
+	reports, err := scanSubprojects(projectPath, configPath, catalog, settings, subprojects, ScanOptions{})
+	if err != nil {
+		t.Fatalf("Failed to scan sub-projects: %v", err)
+	}
+	if len(reports) != len(subprojects) {
+		t.Fatalf("Expected a report per sub-project, got %d", len(reports))
+	}
+	for _, report := range reports {
+		_, found := report.Results["stripe"]
+		if found != (report.ProjectName == "packages/web" || report.ProjectName == "services/api") {
+			t.Errorf("Expected stripe only in packages/web and services/api, got %v for %s", report.Results, report.ProjectName)
+		}
+	}
+
+	report := &ScanReport{
+		ProjectPath: projectPath,
+		ConfigPath:  configPath,
+		ProjectName: "acme",
+		Results:     scan.Results,
+		Stack:       catalog.Stack,
+		Filter:      rootSettings.PathFilter(),
+		Subprojects: reports,
+	}
+	if err := writeOutput(OutputTarget{Format: "yml-config", Path: "-"}, report); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	config, _ := ioutil.ReadFile(configPath)
+	if !strings.Contains(string(config), "acme:") || !strings.Contains(string(config), "packages/web:\n  Stripe: https://dashboard.stripe.com") {
+		t.Errorf("Expected root and packages/web sections, got %s", config)
+	}
+	if strings.Contains(string(config), "packages/ui:") {
+		t.Errorf("Expected no section for packages/ui without detections, got %s", config)
+	}
+}
+
+func TestExcludedPaths(t *testing.T) {
+	skipWithoutPack(t, "package.json")
+	projectPath := filepath.Join("testdata", "exclude-project")
+	catalog, err := parascan.EmbeddedCatalog()
+	if err != nil {
+		t.Fatalf("Failed to load catalog: %v", err)
+	}
+
+	// .parascopeignore skips the examples except shop, dist and target are never scanned
+	settings, err := parascan.LoadProjectSettings(projectPath)
+	if err != nil {
+		t.Fatalf("Failed to load settings: %v", err)
+	}
+	scan := runScan(projectPath, catalog, ScanOptions{SkipGit: true, Settings: settings})
+	if _, found := scan.Results["stripe"]; !found {
+		t.Errorf("Expected stripe from the re-included examples/shop, got %v", scan.Results)
+	}
+	for _, key := range []string{"sentry", "openai", "twilio"} {
+		if _, found := scan.Results[key]; found {
+			t.Errorf("Expected %s from an excluded directory to be skipped, got %v", key, scan.Results)
+		}
+	}
+	if !equalStringSlices(scan.Languages, []string{"nodejs"}) {
+		t.Errorf("Expected only nodejs with target/ skipped, got %v", scan.Languages)
+	}
+
+	// --exclude patterns add to the settings
+	settings.Ignore = append(settings.Ignore, "**/shop")
+	scan = runScan(projectPath, catalog, ScanOptions{SkipGit: true, Settings: settings})
+	if _, found := scan.Results["stripe"]; found {
+		t.Errorf("Expected stripe to be excluded, got %v", scan.Results)
+	}
+
+	filter := &detectors.PathFilter{Ignore: []string{"**/testdata/*.json", "*.generated.*"}}
+	for path, skipped := range map[string]bool{
+		"testdata/a.json":         true,
+		"pkg/api/testdata/b.json": true,
+		"pkg/api/testdata/b.yml":  false,
+		"web/client.generated.ts": true,
+		"web/client.ts":           false,
+	} {
+		if filter.Skip(path) != skipped {
+			t.Errorf("Expected Skip(%q) to be %v", path, skipped)
+		}
+	}
+}
+
+func TestGlobPatterns(t *testing.T) {
+	projectPath := filepath.Join("testdata", "glob-project")
+	relative := func(matches []string) []string {
+		var paths []string
+		for _, match := range matches {
+			rel, _ := filepath.Rel(projectPath, match)
+			paths = append(paths, filepath.ToSlash(rel))
+		}
+		sort.Strings(paths)
+		return paths
+	}
+
+	// ** spans any directories but vendored ones, up to the glob depth
+	var filter *detectors.PathFilter
+	expected := []string{"a/b/c/requirements-deep.txt", "requirements.txt", "services/api/requirements-dev.txt"}
+	if matches := relative(filter.Glob(projectPath, "**/requirements*.txt")); !equalStringSlices(matches, expected) {
+		t.Errorf("Expected %v, got %v", expected, matches)
+	}
+	filter = &detectors.PathFilter{GlobDepth: 2}
+	expected = []string{"requirements.txt", "services/api/requirements-dev.txt"}
+	if matches := relative(filter.Glob(projectPath, "**/requirements*.txt")); !equalStringSlices(matches, expected) {
+		t.Errorf("Expected %v with a glob depth of 2, got %v", expected, matches)
+	}
+	expected = []string{"services/api/vendor/requirements.txt"}
+	if matches := relative(filter.Glob(projectPath, "**/vendor/*.txt")); !equalStringSlices(matches, expected) {
+		t.Errorf("Expected vendor entered when named, got %v", matches)
+	}
+
+	// Braces expand to alternatives, nested ones included
+	expected = []string{".github/workflows/ci.yaml", ".github/workflows/release.yml"}
+	if matches := relative(filter.Glob(projectPath, ".github/workflows/*.{yml,yaml}")); !equalStringSlices(matches, expected) {
+		t.Errorf("Expected %v, got %v", expected, matches)
+	}
+	expected = []string{"a.yml", "b.json", "b.yaml", "b.yml"}
+	expanded := detectors.ExpandBraces("{a.yml,b.{json,y{a,}ml}}")
+	sort.Strings(expanded)
+	if !equalStringSlices(expanded, expected) {
+		t.Errorf("Expected %v, got %v", expected, expanded)
+	}
+
+	// Dependency files found with ** stay within the scanned directories
+	dirs := parascan.ProjectDirs(projectPath, nil, 2)
+	if matches := relative(parascan.FindDependencyFiles(projectPath, dirs, "**/requirements*.txt", nil)); !equalStringSlices(matches, []string{"requirements.txt"}) {
+		t.Errorf("Expected only the root file within depth 2, got %v", matches)
+	}
+
+	ignore := &detectors.PathFilter{Ignore: []string{"services/**/vendor"}}
+	if !ignore.Skip("services/api/vendor/requirements.txt") || ignore.Skip("services/api/requirements-dev.txt") {
+		t.Errorf("Expected ** in the middle of ignore patterns to match directories")
+	}
+}
+
+func TestNestedProjectDirectories(t *testing.T) {
+	skipWithoutPack(t, "package.json")
+	projectPath := filepath.Join("testdata", "fullstack-project")
+	catalog, err := parascan.EmbeddedCatalog()
+	if err != nil {
+		t.Fatalf("Failed to load catalog: %v", err)
+	}
+
+	// Manifests and framework config of backend/ and frontend/ count for the root
+	scan := runScan(projectPath, catalog, ScanOptions{SkipGit: true})
+	languages := append([]string(nil), scan.Languages...)
+	sort.Strings(languages)
+	if !equalStringSlices(languages, []string{"nodejs", "ruby"}) {
+		t.Errorf("Expected nodejs and ruby from nested manifests, got %v", scan.Languages)
+	}
+	for key, file := range map[string]string{"stripe": "backend/config/initializers/stripe.rb", "sentry": "frontend/package.json"} {
+		if evidence := scan.Evidence[key]; len(evidence) != 1 || evidence[0].File != file {
+			t.Errorf("Expected %s evidence from %s, got %v", key, file, evidence)
+		}
+	}
+
+	// Ignore patterns anchored at the root apply within the subdirectory
+	settings := &parascan.ProjectSettings{Ignore: []string{"backend/config/initializers"}}
+	scan = runScan(projectPath, catalog, ScanOptions{SkipGit: true, Settings: settings})
+	if _, found := scan.Results["stripe"]; found {
+		t.Errorf("Expected the ignored initializer to be skipped, got %v", scan.Results)
+	}
+}
+
+func TestIncludedPaths(t *testing.T) {
+	projectPath := filepath.Join("testdata", "fullstack-project")
+	catalog, err := parascan.EmbeddedCatalog()
+	if err != nil {
+		t.Fatalf("Failed to load catalog: %v", err)
+	}
+
+	// Only the included subtree is scanned, from the project root
+	settings := &parascan.ProjectSettings{Include: []string{"backend/**"}}
+	scan := runScan(projectPath, catalog, ScanOptions{SkipGit: true, Settings: settings})
+	if !equalStringSlices(scan.Languages, []string{"ruby"}) {
+		t.Errorf("Expected only ruby from backend, got %v", scan.Languages)
+	}
+	if _, found := scan.Results["sentry"]; found {
+		t.Errorf("Expected frontend to be skipped, got %v", scan.Results)
+	}
+	if evidence := scan.Evidence["stripe"]; len(evidence) != 1 || evidence[0].File != "backend/config/initializers/stripe.rb" {
+		t.Errorf("Expected stripe evidence relative to the root, got %v", evidence)
+	}
+
+	// Directories leading to included paths are entered, their files skipped
+	filter := &detectors.PathFilter{Include: []string{"services/*/api"}, Ignore: []string{"services/legacy"}}
+	for rel, skipped := range map[string]bool{
+		"package.json":                       true,
+		"services/payments/api/package.json": false,
+		"services/payments/web/package.json": true,
+	} {
+		if filter.Skip(rel) != skipped {
+			t.Errorf("Expected Skip(%q) to be %v", rel, skipped)
+		}
+	}
+	root := t.TempDir()
+	for _, dir := range []string{"services/payments", "services/legacy", "docs"} {
+		os.MkdirAll(filepath.Join(root, dir), 0755)
+	}
+	for dir, skipped := range map[string]bool{"services": false, "services/payments": false, "services/legacy": true, "docs": true} {
+		if filter.SkipPath(root, filepath.Join(root, dir)) != skipped {
+			t.Errorf("Expected SkipPath(%q) to be %v", dir, skipped)
+		}
+	}
+
+	// Sub-projects get the include patterns relative to them
+	if scoped := filter.Within("services/payments"); !equalStringSlices(scoped.Include, []string{"/api"}) {
+		t.Errorf("Expected /api within services/payments, got %v", scoped.Include)
+	}
+	if scoped := filter.Within("services/payments/api"); len(scoped.Include) != 0 {
+		t.Errorf("Expected no include patterns within an included directory, got %v", scoped.Include)
+	}
+}
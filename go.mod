@@ -5,6 +5,9 @@ go 1.21
 toolchain go1.24.0
 
 require (
+	golang.org/x/crypto v0.31.0
 	gopkg.in/yaml.v2 v2.4.0
 	gopkg.in/yaml.v3 v3.0.1
 )
+
+require golang.org/x/sys v0.28.0 // indirect
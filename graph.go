@@ -0,0 +1,280 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+
+	"parascan/detectors"
+	"parascan/pkg/parascan"
+)
+
+// ComponentGraph describes how sub-projects of a repository depend on each other
+type ComponentGraph struct {
+	Components []Component     `json:"components"`
+	Edges      []ComponentEdge `json:"edges,omitempty"`
+}
+
+// Component is a sub-project found in the repository (npm package, Go module, compose service)
+type Component struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+	Kind string `json:"kind"`
+}
+
+// ComponentEdge is an internal dependency between two components
+type ComponentEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	Type string `json:"type"`
+}
+
+var composeFileNames = []string{"docker-compose.yml", "docker-compose.yaml", "compose.yml", "compose.yaml"}
+
+// componentGraphDepth is how many directory levels below the root are searched for components
+const componentGraphDepth = 4
+
+// edgeKinds is the kind of the components an edge type connects, e.g. depends_on
+// only names compose services even when an npm package has the same name
+var edgeKinds = map[string]string{"workspace": "npm", "replace": "go", "depends_on": "compose"}
+
+// componentKey identifies a component by kind and name, names are only unique per kind
+func componentKey(kind, name string) string {
+	return kind + ":" + name
+}
+
+// buildComponentGraph finds sub-projects in the tree and the references between them:
+// workspace/file deps in package.json, go.mod replace directives pointing to sibling
+// modules and depends_on in compose files.
+func buildComponentGraph(projectPath string, filter *detectors.PathFilter) *ComponentGraph {
+	graph := &ComponentGraph{}
+	type pendingEdge struct {
+		from, target, edgeType string
+		isPath                 bool
+	}
+	var pending []pendingEdge
+	// Both by componentKey, directories keyed by kind and cleaned path
+	componentsByDir := make(map[string]string)
+	componentNames := make(map[string]bool)
+
+	addComponent := func(name, dir, kind string) {
+		rel, err := filepath.Rel(projectPath, dir)
+		if err != nil {
+			rel = dir
+		}
+		graph.Components = append(graph.Components, Component{Name: name, Path: filepath.ToSlash(rel), Kind: kind})
+		componentNames[componentKey(kind, name)] = true
+		if dirKey := componentKey(kind, filepath.Clean(dir)); componentsByDir[dirKey] == "" {
+			componentsByDir[dirKey] = name
+		}
+	}
+
+	filepath.WalkDir(projectPath, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			if path == projectPath {
+				return nil
+			}
+			if parascan.SkipWalkDir(d.Name()) || filter.SkipPath(projectPath, path) {
+				return filepath.SkipDir
+			}
+			if rel, err := filepath.Rel(projectPath, path); err == nil && strings.Count(rel, string(filepath.Separator)) >= componentGraphDepth-1 {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		dir := filepath.Dir(path)
+		switch d.Name() {
+		case "package.json":
+			var pkg struct {
+				Name            string            `json:"name"`
+				Dependencies    map[string]string `json:"dependencies"`
+				DevDependencies map[string]string `json:"devDependencies"`
+			}
+			content, err := os.ReadFile(path)
+			if err != nil || json.Unmarshal(content, &pkg) != nil || pkg.Name == "" {
+				return nil
+			}
+			addComponent(pkg.Name, dir, "npm")
+			for _, deps := range []map[string]string{pkg.Dependencies, pkg.DevDependencies} {
+				for depName, version := range deps {
+					switch {
+					case strings.HasPrefix(version, "workspace:"):
+						pending = append(pending, pendingEdge{from: pkg.Name, target: depName, edgeType: "workspace"})
+					case strings.HasPrefix(version, "file:") || strings.HasPrefix(version, "link:"):
+						target := version[strings.Index(version, ":")+1:]
+						pending = append(pending, pendingEdge{from: pkg.Name, target: filepath.Join(dir, target), edgeType: "workspace", isPath: true})
+					}
+				}
+			}
+		case "go.mod":
+			modulePath, replaces := parseGoModReplaces(path)
+			if modulePath == "" {
+				return nil
+			}
+			addComponent(modulePath, dir, "go")
+			for _, target := range replaces {
+				pending = append(pending, pendingEdge{from: modulePath, target: filepath.Join(dir, target), edgeType: "replace", isPath: true})
+			}
+		default:
+			if !isComposeFile(d.Name()) {
+				return nil
+			}
+			content, err := os.ReadFile(path)
+			if err != nil {
+				return nil
+			}
+			var compose struct {
+				Services map[string]struct {
+					DependsOn interface{} `yaml:"depends_on"`
+				} `yaml:"services"`
+			}
+			if yaml.Unmarshal(content, &compose) != nil {
+				return nil
+			}
+			for serviceName, service := range compose.Services {
+				addComponent(serviceName, dir, "compose")
+				for _, dep := range composeDependsOn(service.DependsOn) {
+					pending = append(pending, pendingEdge{from: serviceName, target: dep, edgeType: "depends_on"})
+				}
+			}
+		}
+		return nil
+	})
+
+	seen := make(map[string]bool)
+	for _, edge := range pending {
+		kind := edgeKinds[edge.edgeType]
+		to := edge.target
+		if edge.isPath {
+			name, ok := componentsByDir[componentKey(kind, filepath.Clean(edge.target))]
+			if !ok {
+				continue
+			}
+			to = name
+		} else if !componentNames[componentKey(kind, to)] {
+			continue
+		}
+		key := edge.from + "\x00" + to + "\x00" + edge.edgeType
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		graph.Edges = append(graph.Edges, ComponentEdge{From: edge.from, To: to, Type: edge.edgeType})
+	}
+
+	sort.Slice(graph.Components, func(i, j int) bool {
+		if graph.Components[i].Path != graph.Components[j].Path {
+			return graph.Components[i].Path < graph.Components[j].Path
+		}
+		return graph.Components[i].Name < graph.Components[j].Name
+	})
+	sort.Slice(graph.Edges, func(i, j int) bool {
+		if graph.Edges[i].From != graph.Edges[j].From {
+			return graph.Edges[i].From < graph.Edges[j].From
+		}
+		if graph.Edges[i].To != graph.Edges[j].To {
+			return graph.Edges[i].To < graph.Edges[j].To
+		}
+		return graph.Edges[i].Type < graph.Edges[j].Type
+	})
+
+	return graph
+}
+
+// parseGoModReplaces returns the module path and local directories of replace directives
+func parseGoModReplaces(path string) (string, []string) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", nil
+	}
+	defer file.Close()
+
+	var modulePath string
+	var targets []string
+	inReplaceBlock := false
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case strings.HasPrefix(line, "module "):
+			modulePath = strings.Trim(strings.TrimSpace(strings.TrimPrefix(line, "module ")), `"`)
+		case line == "replace (":
+			inReplaceBlock = true
+		case inReplaceBlock && line == ")":
+			inReplaceBlock = false
+		case inReplaceBlock || strings.HasPrefix(line, "replace "):
+			parts := strings.SplitN(strings.TrimPrefix(line, "replace "), "=>", 2)
+			if len(parts) != 2 {
+				continue
+			}
+			fields := strings.Fields(parts[1])
+			if len(fields) > 0 && (strings.HasPrefix(fields[0], "./") || strings.HasPrefix(fields[0], "../")) {
+				targets = append(targets, fields[0])
+			}
+		}
+	}
+
+	return modulePath, targets
+}
+
+// composeDependsOn supports both list and map forms of depends_on
+func composeDependsOn(value interface{}) []string {
+	var deps []string
+	switch v := value.(type) {
+	case []interface{}:
+		for _, item := range v {
+			if name, ok := item.(string); ok {
+				deps = append(deps, name)
+			}
+		}
+	case map[interface{}]interface{}:
+		for key := range v {
+			if name, ok := key.(string); ok {
+				deps = append(deps, name)
+			}
+		}
+	}
+	sort.Strings(deps)
+	return deps
+}
+
+func isComposeFile(name string) bool {
+	for _, composeName := range composeFileNames {
+		if name == composeName {
+			return true
+		}
+	}
+	return false
+}
+
+// hasInternalStructure reports whether the graph is worth including in output
+func (g *ComponentGraph) hasInternalStructure() bool {
+	return len(g.Components) > 1 || len(g.Edges) > 0
+}
+
+// outputDotFormat writes the component graph in Graphviz dot format, nodes are
+// identified by kind and name so a compose service and a package can share a name
+func outputDotFormat(w io.Writer, graph *ComponentGraph) {
+	fmt.Fprintln(w, "digraph components {")
+	fmt.Fprintln(w, "  rankdir=LR;")
+	for _, component := range graph.Components {
+		fmt.Fprintf(w, "  %q [label=%q];\n", componentKey(component.Kind, component.Name), fmt.Sprintf("%s\n(%s)", component.Name, component.Kind))
+	}
+	for _, edge := range graph.Edges {
+		kind := edgeKinds[edge.Type]
+		fmt.Fprintf(w, "  %q -> %q [label=%q];\n", componentKey(kind, edge.From), componentKey(kind, edge.To), edge.Type)
+	}
+	fmt.Fprintln(w, "}")
+}
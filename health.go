@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"parascan/detectors"
+	"parascan/pkg/parascan"
+)
+
+// configCurrent reports whether the project's section of parascope.yml lists every
+// entry a scan with the results would write, by name or URL as para diff compares
+// them. Entries the scan doesn't find don't matter, a missing section is never
+// current.
+func configCurrent(configPath, projectName, repositoryName string, results map[string]string) bool {
+	filtered := dedupeResults(filterGitHubByRepository(results))
+	scanned := make(map[string]string)
+	for key, value := range filtered {
+		scanned[configEntryName(key, value, repositoryName)] = value
+	}
+	config, err := readConfigSection(configPath, configProjectName(configPath, projectName), filtered[detectors.RepoKey], repositoryName)
+	if err != nil || len(config) == 0 {
+		return false
+	}
+	diff := diffConfig(config, scanned)
+	return len(diff.Added) == 0 && len(diff.Changed) == 0
+}
+
+// writeHealthScore prints the inventory completeness score for people, with the
+// criteria the project misses
+func writeHealthScore(w io.Writer, score *parascan.HealthScore) {
+	fmt.Fprintf(w, "\n🩺 Inventory completeness: %d/100\n", score.Score)
+	for _, check := range score.Criteria {
+		if !check.Met {
+			fmt.Fprintf(w, "  ✗ %s (%d points)\n", check.Description, check.Weight)
+		}
+	}
+}
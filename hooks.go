@@ -0,0 +1,42 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+
+	"parascan/pkg/parascan"
+)
+
+// execHook runs a shell command with the JSON result of the report on stdin, redacted
+// with --redact. The command's output goes to out so it doesn't mix with
+// machine-readable stdout. It's run with parascan.RunHooks once the outputs are
+// written, the command gets the report as they have it rather than the scan's.
+func execHook(command string, out io.Writer, report *ScanReport) parascan.Hook {
+	return func(ctx context.Context, _ *parascan.Report) error {
+		input, err := json.MarshalIndent(report.shared().response(), "", "  ")
+		if err != nil {
+			return fmt.Errorf("hook %q failed: %w", command, err)
+		}
+
+		var cmd *exec.Cmd
+		if runtime.GOOS == "windows" {
+			cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+		} else {
+			cmd = exec.CommandContext(ctx, "sh", "-c", command)
+		}
+		cmd.Env = append(os.Environ(), "PARASCAN_PROJECT_PATH="+report.ProjectPath, "PARASCAN_CONFIG_PATH="+report.ConfigPath)
+		cmd.Stdin = bytes.NewReader(input)
+		cmd.Stdout = out
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("hook %q failed: %w", command, err)
+		}
+		return nil
+	}
+}
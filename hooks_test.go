@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"parascan/pkg/parascan"
+)
+
+func TestRunHooks(t *testing.T) {
+	stackData, err := loadStackDependencyFiles()
+	if err != nil {
+		t.Fatalf("Failed to load stack dependency files: %v", err)
+	}
+	report := &ScanReport{
+		ProjectPath: filepath.Join("testdata", "settings-project"),
+		Languages:   []string{"python"},
+		Results:     map[string]string{"stripe": "https://dashboard.stripe.com"},
+		Stack:       stackData,
+	}
+	scan := &parascan.Report{Results: map[string]string{"stripe": "https://dashboard.stripe.com"}}
+
+	var seen map[string]string
+	hooks := []parascan.Hook{
+		func(ctx context.Context, r *parascan.Report) error {
+			seen = r.Results
+			return nil
+		},
+		func(ctx context.Context, r *parascan.Report) error {
+			return errors.New("ticket tracker unavailable")
+		},
+	}
+
+	var out bytes.Buffer
+	if runtime.GOOS != "windows" {
+		hooks = append(hooks, execHook(`grep -o '"stripe": "[^"]*"'`, &out, report), execHook("exit 3", &out, report))
+	}
+
+	errs := parascan.RunHooks(context.Background(), scan, hooks...)
+	if runtime.GOOS != "windows" {
+		if len(errs) != 2 || !strings.Contains(errs[1].Error(), `hook "exit 3" failed`) {
+			t.Errorf("Expected the broken hook and the failing command to fail, got %v", errs)
+		}
+	}
+	if len(errs) == 0 || !strings.Contains(errs[0].Error(), "ticket tracker unavailable") {
+		t.Errorf("Expected the broken hook to fail, got %v", errs)
+	}
+	if seen["stripe"] == "" {
+		t.Errorf("Expected library hook to receive results, got %v", seen)
+	}
+	if runtime.GOOS != "windows" && strings.TrimSpace(out.String()) != `"stripe": "https://dashboard.stripe.com"` {
+		t.Errorf("Expected exec hook to receive JSON result on stdin, got %q", out.String())
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+
+	"parascan/pkg/parascan"
+)
+
+// ignoreKey lists the services a project section of parascope.yml never gets from
+// scans, by result key or display name, e.g. false positives of the catalog:
+//
+//	billing:
+//	  ignore: [aws, redis]
+//	  Repository: https://github.com/acme/billing
+const ignoreKey = "ignore"
+
+// configIgnores returns the services the project's section of parascope.yml ignores,
+// as written. A missing config or section ignores none.
+func configIgnores(configPath, projectName string) []string {
+	content, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil
+	}
+	sections := splitConfigSections(string(content))
+	i := findConfigSection(sections, projectName, "", "")
+	if i < 0 {
+		return nil
+	}
+	var data map[string]map[string]interface{}
+	if err := yaml.Unmarshal([]byte(sections[i]), &data); err != nil {
+		return nil
+	}
+
+	var ignores []string
+	for _, values := range data {
+		switch value := values[ignoreKey].(type) {
+		case []interface{}:
+			for _, item := range value {
+				ignores = append(ignores, fmt.Sprint(item))
+			}
+		case string:
+			ignores = append(ignores, value)
+		}
+	}
+	return ignores
+}
+
+// ignoreConfigServices disables the services the project's section of parascope.yml
+// ignores for the scan, display names by the key of their catalog service
+func ignoreConfigServices(settings *parascan.ProjectSettings, catalog *parascan.Catalog, configPath, projectName string) {
+	for _, name := range configIgnores(configPath, projectName) {
+		for key, service := range catalog.Services {
+			if strings.EqualFold(service.Name, name) {
+				name = key
+				break
+			}
+		}
+		settings.DisabledServices = append(settings.DisabledServices, name)
+	}
+}
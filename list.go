@@ -0,0 +1,270 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"parascan/detectors"
+	"parascan/pkg/parascan"
+)
+
+// ServiceListing is a catalog service as para list services shows it
+type ServiceListing struct {
+	Key         string              `json:"key"`
+	Name        string              `json:"name"`
+	Category    string              `json:"category,omitempty"`
+	URL         string              `json:"url"`
+	URLTemplate string              `json:"url_template,omitempty"`
+	Packages    map[string][]string `json:"packages,omitempty"` // by language
+	Deprecated  bool                `json:"deprecated,omitempty"`
+}
+
+// LanguageListing is a language with the dependency files of its package managers
+// and the extensions of its source files
+type LanguageListing struct {
+	Name             string              `json:"name"`
+	PackageManagers  map[string][]string `json:"package_managers,omitempty"` // dependency file patterns by package manager
+	SourceExtensions []string            `json:"source_extensions,omitempty"`
+}
+
+// DetectorListing is a built-in detector, or with Files a technology of the file detector
+type DetectorListing struct {
+	Name        string   `json:"name"`
+	DisplayName string   `json:"display_name,omitempty"`
+	Description string   `json:"description,omitempty"`
+	OptIn       string   `json:"opt_in,omitempty"` // scan flag switching it on, for detectors off by default
+	Category    string   `json:"category,omitempty"`
+	Files       []string `json:"files,omitempty"` // patterns the technology is detected by
+	URL         string   `json:"url,omitempty"`   // url_template, or fallback_url without one
+}
+
+// builtinDetectors are the detectors of para scan by the names .parascan.yml
+// switches them with, in the order they run
+var builtinDetectors = []DetectorListing{
+	{Name: parascan.ServicesDetector, Description: "packages of dependency files and framework config"},
+	{Name: "git", Description: "repository URL of the origin remote, or the one of --git-remote, and of upstream"},
+	{Name: parascan.IaCStateDetector, Description: "resource types of Terraform and Pulumi state", OptIn: "--iac-state"},
+	{Name: parascan.EnvDetector, Description: "variable names of .env.example and other env templates"},
+	{Name: parascan.DotenvDetector, Description: "variable names of .env files", OptIn: "--dotenv"},
+	{Name: parascan.ContentDetector, Description: "content rules of services, e.g. API hosts in source files"},
+	{Name: parascan.DeepDetector, Description: "imports of Go, Python and JavaScript/TypeScript sources", OptIn: "--deep"},
+	{Name: parascan.HelmDetector, Description: "chart dependencies of Helm charts"},
+	{Name: "files", Description: "technologies by their files, the file detectors below"},
+	{Name: detectors.DocsSiteKey, Description: "published documentation sites"},
+	{Name: "kubernetes", Description: "images, hosts, Secrets and ConfigMaps of Kubernetes manifests"},
+	{Name: "serverless", Description: "Serverless Framework, SAM, CDK and Pulumi deployments"},
+	{Name: "docs", Description: "services mentioned in README and docs, low confidence", OptIn: "--docs"},
+	{Name: parascan.LanguageStatsDetector, Description: "share of each language in source files", OptIn: "--language-stats"},
+}
+
+// listServices lists the catalog's services by key
+func listServices(catalog *parascan.Catalog) []ServiceListing {
+	listings := make([]ServiceListing, 0, len(catalog.Services))
+	for key, service := range catalog.Services {
+		listings = append(listings, ServiceListing{
+			Key:         key,
+			Name:        service.Name,
+			Category:    service.Category,
+			URL:         service.URL,
+			URLTemplate: service.URLTemplate,
+			Packages:    service.Stacks,
+			Deprecated:  service.Deprecated,
+		})
+	}
+	sort.Slice(listings, func(i, j int) bool { return listings[i].Key < listings[j].Key })
+	return listings
+}
+
+// listLanguages lists the languages with package managers or source extensions
+func listLanguages(catalog *parascan.Catalog) []LanguageListing {
+	byName := make(map[string]*LanguageListing)
+	language := func(name string) *LanguageListing {
+		if byName[name] == nil {
+			byName[name] = &LanguageListing{Name: name}
+		}
+		return byName[name]
+	}
+	for name, lang := range catalog.Stack.Languages {
+		listing := language(name)
+		for pmName, pm := range lang.PackageManagers {
+			if listing.PackageManagers == nil {
+				listing.PackageManagers = make(map[string][]string)
+			}
+			listing.PackageManagers[pmName] = pm.Files
+		}
+	}
+	for name, extensions := range catalog.Stack.SourceExtensions {
+		language(name).SourceExtensions = extensions
+	}
+
+	listings := make([]LanguageListing, 0, len(byName))
+	for _, listing := range byName {
+		listings = append(listings, *listing)
+	}
+	sort.Slice(listings, func(i, j int) bool { return listings[i].Name < listings[j].Name })
+	return listings
+}
+
+// listFileDetectors lists the technologies of the file detector by key
+func listFileDetectors(catalog *parascan.Catalog) []DetectorListing {
+	var listings []DetectorListing
+	if catalog.FileDetectors == nil {
+		return listings
+	}
+	for key, tech := range catalog.FileDetectors.Technologies {
+		url := tech.URLTemplate
+		if url == "" {
+			url = tech.FallbackURL
+		}
+		listings = append(listings, DetectorListing{Name: key, DisplayName: tech.DisplayName, Category: tech.Category, Files: tech.Files, URL: url})
+	}
+	sort.Slice(listings, func(i, j int) bool { return listings[i].Name < listings[j].Name })
+	return listings
+}
+
+// sortedListKeys returns the keys of a map of lists in order
+func sortedListKeys(m map[string][]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func writeServicesTable(w io.Writer, services []ServiceListing) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "KEY\tNAME\tCATEGORY\tURL\tPACKAGES")
+	for _, service := range services {
+		url := service.URL
+		if service.URLTemplate != "" {
+			url = service.URLTemplate
+		}
+		name := service.Name
+		if service.Deprecated {
+			name += " (deprecated)"
+		}
+		var packages []string
+		for _, language := range sortedListKeys(service.Packages) {
+			packages = append(packages, language+": "+strings.Join(service.Packages[language], ", "))
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", service.Key, name, service.Category, url, strings.Join(packages, "; "))
+	}
+	tw.Flush()
+}
+
+func writeLanguagesTable(w io.Writer, languages []LanguageListing) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "LANGUAGE\tEXTENSIONS\tPACKAGE MANAGER\tDEPENDENCY FILES")
+	for _, language := range languages {
+		extensions := strings.Join(language.SourceExtensions, " ")
+		if len(language.PackageManagers) == 0 {
+			fmt.Fprintf(tw, "%s\t%s\t\t\n", language.Name, extensions)
+		}
+		// The language and its extensions head the rows of its package managers
+		for i, pm := range sortedListKeys(language.PackageManagers) {
+			name := language.Name
+			if i > 0 {
+				name, extensions = "", ""
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", name, extensions, pm, strings.Join(language.PackageManagers[pm], ", "))
+		}
+	}
+	tw.Flush()
+}
+
+func writeDetectorsTable(w io.Writer, builtin, files []DetectorListing) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "DETECTOR\tOPT-IN\tDETECTS")
+	for _, detector := range builtin {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", detector.Name, detector.OptIn, detector.Description)
+	}
+	tw.Flush()
+
+	fmt.Fprintln(w)
+	tw = tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "FILE DETECTOR\tNAME\tCATEGORY\tFILES\tURL")
+	for _, detector := range files {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", detector.Name, detector.DisplayName, detector.Category, strings.Join(detector.Files, ", "), detector.URL)
+	}
+	tw.Flush()
+}
+
+// handleList shows what the embedded catalog detects, as a table or JSON
+func handleList() {
+	format := "table"
+	flags := newCommandFlags("list", showListHelp)
+	flags.StringVar(&format, "format", format, "")
+	flags.StringVar(&format, "f", format, "")
+	args := flags.parse(os.Args[2:])
+	flags.maxArgs(args, 1)
+	if len(args) == 0 {
+		showListHelp()
+		os.Exit(1)
+	}
+	what := args[0]
+	if what != "services" && what != "languages" && what != "detectors" {
+		flags.fail("Unknown list: %s. Use services, languages or detectors", what)
+	}
+	if format != "table" && format != "json" {
+		flags.fail("Unknown format: %s. Supported formats: table, json", format)
+	}
+
+	catalog, err := parascan.EmbeddedCatalog()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		os.Exit(1)
+	}
+
+	var listing interface{}
+	switch what {
+	case "services":
+		services := listServices(catalog)
+		if format == "table" {
+			writeServicesTable(os.Stdout, services)
+			return
+		}
+		listing = services
+	case "languages":
+		languages := listLanguages(catalog)
+		if format == "table" {
+			writeLanguagesTable(os.Stdout, languages)
+			return
+		}
+		listing = languages
+	case "detectors":
+		files := listFileDetectors(catalog)
+		if format == "table" {
+			writeDetectorsTable(os.Stdout, builtinDetectors, files)
+			return
+		}
+		listing = map[string][]DetectorListing{"detectors": builtinDetectors, "file_detectors": files}
+	}
+	data, _ := json.MarshalIndent(listing, "", "  ")
+	fmt.Println(string(data))
+}
+
+func showListHelp() {
+	fmt.Println(`Usage: para list <services|languages|detectors> [--format table|json]
+
+Show what parascan detects, from the embedded catalog:
+
+  services   Services with their category, dashboard URL and packages per language
+  languages  Languages with the dependency files of their package managers and
+             the extensions of their source files
+  detectors  Built-in detectors by the names .parascan.yml switches them with,
+             and the technologies of the file detector with their file patterns
+
+Options:
+  --format, -f     table (default) or json
+
+Examples:
+  para list services
+  para list languages -f json
+  para list detectors | grep ci`)
+}
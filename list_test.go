@@ -0,0 +1,46 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"parascan/pkg/parascan"
+)
+
+func TestListCatalog(t *testing.T) {
+	catalog, err := parascan.EmbeddedCatalog()
+	if err != nil {
+		t.Fatalf("Failed to load catalog: %v", err)
+	}
+
+	services := listServices(catalog)
+	if len(services) != len(catalog.Services) {
+		t.Errorf("Expected every service, got %d of %d", len(services), len(catalog.Services))
+	}
+	var buf strings.Builder
+	writeServicesTable(&buf, services)
+	if !strings.Contains(buf.String(), "go: github.com/stripe/stripe-go; java: com.stripe:stripe-java") {
+		t.Errorf("Expected stripe's packages by language, got %s", buf.String())
+	}
+
+	// Languages only known by their source extensions are listed too
+	languages := listLanguages(catalog)
+	byName := make(map[string]LanguageListing)
+	for _, language := range languages {
+		byName[language.Name] = language
+	}
+	if files := byName["go"].PackageManagers["go_modules"]; len(files) != 1 || files[0] != "go.mod" {
+		t.Errorf("Expected go.mod for go modules, got %+v", byName["go"])
+	}
+	if shell, ok := byName["shell"]; !ok || len(shell.PackageManagers) != 0 || len(shell.SourceExtensions) == 0 {
+		t.Errorf("Expected shell with extensions only, got %+v", shell)
+	}
+
+	buf.Reset()
+	writeDetectorsTable(&buf, builtinDetectors, listFileDetectors(catalog))
+	for _, row := range []string{"dotenv          --dotenv", "github-actions", ".gitlab-ci.yml"} {
+		if !strings.Contains(buf.String(), row) {
+			t.Errorf("Expected %q in the detectors table, got %s", row, buf.String())
+		}
+	}
+}
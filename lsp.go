@@ -0,0 +1,428 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"parascan/detectors"
+	"parascan/pkg/parascan"
+)
+
+// Long-running mode for editor plugins: JSON-RPC 2.0 over stdio with LSP-style
+// Content-Length framing. Plugins and scan results stay warm between requests,
+// file change notifications invalidate and rescan the affected projects.
+
+// JSON-RPC error codes
+const (
+	rpcParseError     = -32700
+	rpcInvalidParams  = -32602
+	rpcMethodNotFound = -32601
+	rpcInternalError  = -32603
+)
+
+type rpcRequest struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id,omitempty"` // nil for notifications
+	Method  string           `json:"method"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+}
+
+type rpcResponse struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id"`
+	Result  interface{}      `json:"result"`
+	Error   *rpcError        `json:"error,omitempty"`
+}
+
+type rpcNotification struct {
+	JSONRPC string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// initializeParams carry what the client expects of the server. A client that reads
+// scans of a newer catalog, e.g. a backend aggregating a fleet, sends its data version.
+type initializeParams struct {
+	InitializationOptions struct {
+		DataVersion string `json:"dataVersion"`
+	} `json:"initializationOptions"`
+}
+
+// showMessageParams is an LSP window/showMessage notification
+type showMessageParams struct {
+	Type    int    `json:"type"` // 1 error, 2 warning, 3 info
+	Message string `json:"message"`
+}
+
+// scanParams select the project of scan and catalog requests, the working directory by default
+type scanParams struct {
+	Path    string `json:"path"`
+	Refresh bool   `json:"refresh"` // ignore the warm result
+}
+
+type fileEvent struct {
+	URI  string `json:"uri"`
+	Type int    `json:"type"` // 1 created, 2 changed, 3 deleted
+}
+
+type didChangeWatchedFilesParams struct {
+	Changes []fileEvent `json:"changes"`
+}
+
+// scanNotification is pushed after a file change rescanned a project
+type scanNotification struct {
+	Path   string        `json:"path"`
+	Result SniffResponse `json:"result"`
+}
+
+// catalogInfo answers catalog requests
+type catalogInfo struct {
+	Version      string   `json:"version,omitempty"`
+	Hash         string   `json:"hash"`
+	Source       string   `json:"source"`
+	Rules        []string `json:"rules,omitempty"` // rules directories merged over the catalog
+	Services     int      `json:"services"`
+	Technologies int      `json:"technologies"`
+}
+
+// stdioServer keeps plugins and scan results warm between requests
+type stdioServer struct {
+	in      *bufio.Reader
+	out     io.Writer
+	trust   TrustPolicy
+	plugins []*detectors.ExecDetector
+	scans   map[string]*ScanReport // by absolute project path
+}
+
+func newStdioServer(in io.Reader, out io.Writer, trust TrustPolicy, plugins []*detectors.ExecDetector) *stdioServer {
+	return &stdioServer{
+		in:      bufio.NewReader(in),
+		out:     out,
+		trust:   trust,
+		plugins: plugins,
+		scans:   make(map[string]*ScanReport),
+	}
+}
+
+func handleStdio() {
+	globalConfig, err := loadGlobalConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		os.Exit(1)
+	}
+	plugins, err := loadPluginDetectors(globalConfig.Trust, false)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Could not load plugins: %v\n", err)
+	}
+
+	server := newStdioServer(os.Stdin, os.Stdout, globalConfig.Trust, plugins)
+	if err := server.serve(); err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// serve handles messages until exit or the end of input
+func (s *stdioServer) serve() error {
+	for {
+		body, err := s.readMessage()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var request rpcRequest
+		if err := json.Unmarshal(body, &request); err != nil {
+			if err := s.reply(nil, nil, &rpcError{Code: rpcParseError, Message: err.Error()}); err != nil {
+				return err
+			}
+			continue
+		}
+		if request.Method == "exit" {
+			return nil
+		}
+
+		result, rpcErr := s.handle(request)
+		if request.ID == nil {
+			continue // notifications get no response
+		}
+		if err := s.reply(request.ID, result, rpcErr); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *stdioServer) handle(request rpcRequest) (interface{}, *rpcError) {
+	switch request.Method {
+	case "initialize":
+		var params initializeParams
+		if err := decodeParams(request.Params, &params); err != nil {
+			return nil, &rpcError{Code: rpcInvalidParams, Message: err.Error()}
+		}
+		catalog, err := parascan.EmbeddedCatalog()
+		if err != nil {
+			return nil, &rpcError{Code: rpcInternalError, Message: err.Error()}
+		}
+		if err := s.negotiateDataVersion(params.InitializationOptions.DataVersion, catalog.Version); err != nil {
+			return nil, &rpcError{Code: rpcInternalError, Message: err.Error()}
+		}
+		return map[string]interface{}{
+			"serverInfo": map[string]string{"name": "parascan", "version": Version, "dataVersion": catalog.Version},
+			"capabilities": map[string]bool{
+				"scan":                  true,
+				"catalog":               true,
+				"didChangeWatchedFiles": true,
+			},
+		}, nil
+	case "initialized":
+		return nil, nil
+	case "shutdown":
+		s.scans = make(map[string]*ScanReport)
+		return nil, nil
+	case "scan":
+		var params scanParams
+		if err := decodeParams(request.Params, &params); err != nil {
+			return nil, &rpcError{Code: rpcInvalidParams, Message: err.Error()}
+		}
+		report, err := s.scan(params.Path, params.Refresh)
+		if err != nil {
+			return nil, &rpcError{Code: rpcInternalError, Message: err.Error()}
+		}
+		return report.response(), nil
+	case "catalog":
+		var params scanParams
+		if err := decodeParams(request.Params, &params); err != nil {
+			return nil, &rpcError{Code: rpcInvalidParams, Message: err.Error()}
+		}
+		catalog, err := s.catalog(params.Path)
+		if err != nil {
+			return nil, &rpcError{Code: rpcInternalError, Message: err.Error()}
+		}
+		info := catalogInfo{Version: catalog.Version, Hash: catalog.Hash, Source: catalog.Source, Rules: catalog.Rules, Services: len(catalog.Services)}
+		if catalog.FileDetectors != nil {
+			info.Technologies = len(catalog.FileDetectors.Technologies)
+		}
+		return info, nil
+	case "workspace/didChangeWatchedFiles":
+		var params didChangeWatchedFilesParams
+		if err := decodeParams(request.Params, &params); err != nil {
+			return nil, &rpcError{Code: rpcInvalidParams, Message: err.Error()}
+		}
+		if err := s.filesChanged(params.Changes); err != nil {
+			return nil, &rpcError{Code: rpcInternalError, Message: err.Error()}
+		}
+		return nil, nil
+	default:
+		return nil, &rpcError{Code: rpcMethodNotFound, Message: "unknown method: " + request.Method}
+	}
+}
+
+// negotiateDataVersion warns the client with a window/showMessage notification when
+// it expects a newer catalog than the one embedded in this build. Scans still run,
+// their data_version tells them apart.
+func (s *stdioServer) negotiateDataVersion(expected, embedded string) error {
+	if expected == "" || compareCatalogVersions(expected, embedded) <= 0 {
+		return nil
+	}
+	return s.writeMessage(rpcNotification{
+		JSONRPC: "2.0",
+		Method:  "window/showMessage",
+		Params: showMessageParams{
+			Type:    2,
+			Message: fmt.Sprintf("para %s embeds catalog %s, older than the expected %s: update para for the same detections", Version, embedded, expected),
+		},
+	})
+}
+
+// scan returns the warm result for the project, scanning it on first use
+func (s *stdioServer) scan(path string, refresh bool) (*ScanReport, error) {
+	projectPath, err := projectRoot(path)
+	if err != nil {
+		return nil, err
+	}
+	if report, ok := s.scans[projectPath]; ok && !refresh {
+		return report, nil
+	}
+
+	settings, err := parascan.LoadProjectSettings(projectPath)
+	if err != nil {
+		return nil, err
+	}
+	catalog, err := s.catalog(projectPath)
+	if err != nil {
+		return nil, err
+	}
+
+	configPath := filepath.Join(projectPath, "parascope.yml")
+	if settings.Output.ConfigPath != "" {
+		configPath = filepath.Join(projectPath, settings.Output.ConfigPath)
+	}
+
+	scan := runScan(projectPath, catalog, ScanOptions{Plugins: s.plugins, Settings: settings})
+	report := &ScanReport{
+		ProjectPath:   projectPath,
+		ConfigPath:    configPath,
+		ProjectName:   settings.Output.ProjectName,
+		Languages:     scan.Languages,
+		LowConfidence: scan.LowConfidence,
+		LanguageFiles: scan.LanguageFiles,
+		Results:       settings.ApplyToResults(scan.Results),
+		Evidence:      settings.ApplyToEvidence(scan.Evidence),
+		Inferred:      scan.Inferred,
+		Deprecations:  scan.Deprecations,
+		Warnings:      scan.Warnings,
+		Attention:     scan.Attention,
+		Kubernetes:    scan.Kubernetes,
+		Repository:    scan.Repository,
+		Helm:          scan.Helm,
+		Serverless:    scan.Serverless,
+		Fingerprint:   scan.Fingerprint,
+		Tasks:         scan.Tasks,
+		LanguageStats: scan.LanguageStats,
+		DataVersion:   catalog.Version,
+		Stack:         catalog.Stack,
+		Filter:        settings.PathFilter(),
+	}
+	s.scans[projectPath] = report
+	return report, nil
+}
+
+// catalog resolves the catalog the project is pinned to, the embedded one without a path
+func (s *stdioServer) catalog(path string) (*parascan.Catalog, error) {
+	if path == "" {
+		return parascan.EmbeddedCatalog()
+	}
+	projectPath, err := projectRoot(path)
+	if err != nil {
+		return nil, err
+	}
+	settings, err := parascan.LoadProjectSettings(projectPath)
+	if err != nil {
+		return nil, err
+	}
+	catalog, _, err := resolveCatalog(projectPath, "", settings, s.trust, false)
+	return catalog, err
+}
+
+// filesChanged rescans warm projects containing a changed file and pushes the new results
+func (s *stdioServer) filesChanged(changes []fileEvent) error {
+	stale := make(map[string]bool)
+	for _, change := range changes {
+		path, err := filepath.Abs(uriToPath(change.URI))
+		if err != nil {
+			continue
+		}
+		for projectPath := range s.scans {
+			if path == projectPath || strings.HasPrefix(path, projectPath+string(filepath.Separator)) {
+				stale[projectPath] = true
+			}
+		}
+	}
+
+	for projectPath := range stale {
+		report, err := s.scan(projectPath, true)
+		if err != nil {
+			return err
+		}
+		if err := s.writeMessage(rpcNotification{
+			JSONRPC: "2.0",
+			Method:  "parascan/didScan",
+			Params:  scanNotification{Path: projectPath, Result: report.response()},
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *stdioServer) reply(id *json.RawMessage, result interface{}, rpcErr *rpcError) error {
+	response := rpcResponse{JSONRPC: "2.0", ID: id, Result: result, Error: rpcErr}
+	if rpcErr != nil {
+		response.Result = nil
+	}
+	return s.writeMessage(response)
+}
+
+// readMessage reads one Content-Length framed message body
+func (s *stdioServer) readMessage() ([]byte, error) {
+	length := -1
+	for {
+		line, err := s.in.ReadString('\n')
+		if err != nil {
+			if err == io.EOF && line == "" && length < 0 {
+				return nil, io.EOF
+			}
+			return nil, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			break
+		}
+		name, value, found := strings.Cut(line, ":")
+		if found && strings.EqualFold(strings.TrimSpace(name), "Content-Length") {
+			length, err = strconv.Atoi(strings.TrimSpace(value))
+			if err != nil {
+				return nil, fmt.Errorf("invalid Content-Length: %v", err)
+			}
+		}
+	}
+	if length < 0 {
+		return nil, fmt.Errorf("message without Content-Length header")
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(s.in, body); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+func (s *stdioServer) writeMessage(message interface{}) error {
+	body, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(s.out, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	return err
+}
+
+func decodeParams(raw json.RawMessage, params interface{}) error {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil
+	}
+	return json.Unmarshal(raw, params)
+}
+
+// projectRoot returns the absolute project path, the working directory when empty
+func projectRoot(path string) (string, error) {
+	if path == "" {
+		path = "."
+	}
+	return filepath.Abs(uriToPath(path))
+}
+
+// uriToPath converts file:// URIs sent by editors to paths, other values are paths already
+func uriToPath(uri string) string {
+	if !strings.HasPrefix(uri, "file://") {
+		return uri
+	}
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		return strings.TrimPrefix(uri, "file://")
+	}
+	return filepath.FromSlash(parsed.Path)
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"parascan/pkg/parascan"
+)
+
+func TestStdioServer(t *testing.T) {
+	skipWithoutPack(t, "package.json", "Gemfile", "requirements.txt")
+	projectPath, err := filepath.Abs(filepath.Join("testdata", "multi-language"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var input bytes.Buffer
+	send := func(message string) {
+		fmt.Fprintf(&input, "Content-Length: %d\r\n\r\n%s", len(message), message)
+	}
+	send(`{"jsonrpc":"2.0","id":1,"method":"initialize"}`)
+	send(fmt.Sprintf(`{"jsonrpc":"2.0","id":2,"method":"scan","params":{"path":%q}}`, projectPath))
+	send(`{"jsonrpc":"2.0","id":3,"method":"catalog"}`)
+	send(fmt.Sprintf(`{"jsonrpc":"2.0","method":"workspace/didChangeWatchedFiles","params":{"changes":[{"uri":"file://%s/Gemfile","type":2}]}}`, filepath.ToSlash(projectPath)))
+	send(`{"jsonrpc":"2.0","id":4,"method":"unknown"}`)
+	send(`{"jsonrpc":"2.0","id":5,"method":"shutdown"}`)
+	send(`{"jsonrpc":"2.0","method":"exit"}`)
+
+	var output bytes.Buffer
+	server := newStdioServer(&input, &output, TrustPolicy{}, nil)
+	if err := server.serve(); err != nil {
+		t.Fatalf("Server failed: %v", err)
+	}
+
+	client := newStdioServer(&output, nil, TrustPolicy{}, nil)
+	var messages []map[string]json.RawMessage
+	for {
+		body, err := client.readMessage()
+		if err != nil {
+			break
+		}
+		var message map[string]json.RawMessage
+		if err := json.Unmarshal(body, &message); err != nil {
+			t.Fatalf("Invalid message %s: %v", body, err)
+		}
+		messages = append(messages, message)
+	}
+
+	// Responses for the 5 requests and one rescan notification, in order
+	if len(messages) != 6 {
+		t.Fatalf("Expected 6 messages, got %d", len(messages))
+	}
+
+	var scan SniffResponse
+	if err := json.Unmarshal(messages[1]["result"], &scan); err != nil || scan.Services["stripe"] == "" {
+		t.Errorf("Expected scan result with stripe, got %s (%v)", messages[1]["result"], err)
+	}
+	if scan.DataVersion == "" {
+		t.Errorf("Expected the catalog's data version in the scan result, got %s", messages[1]["result"])
+	}
+
+	var catalog catalogInfo
+	if err := json.Unmarshal(messages[2]["result"], &catalog); err != nil || catalog.Source != "embedded" || catalog.Services == 0 {
+		t.Errorf("Expected embedded catalog info, got %s (%v)", messages[2]["result"], err)
+	}
+
+	if string(messages[3]["method"]) != `"parascan/didScan"` {
+		t.Errorf("Expected rescan notification after the Gemfile changed, got %v", messages[3])
+	}
+
+	var rpcErr rpcError
+	if err := json.Unmarshal(messages[4]["error"], &rpcErr); err != nil || rpcErr.Code != rpcMethodNotFound {
+		t.Errorf("Expected method not found error, got %s", messages[4]["error"])
+	}
+
+	if _, ok := messages[5]["result"]; !ok {
+		t.Errorf("Expected shutdown response with null result, got %v", messages[5])
+	}
+}
+
+func TestStdioServerDataVersion(t *testing.T) {
+	catalog, err := parascan.EmbeddedCatalog()
+	if err != nil {
+		t.Fatal(err)
+	}
+	initialize := func(dataVersion string) []map[string]json.RawMessage {
+		t.Helper()
+		var input bytes.Buffer
+		message := fmt.Sprintf(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"initializationOptions":{"dataVersion":%q}}}`, dataVersion)
+		fmt.Fprintf(&input, "Content-Length: %d\r\n\r\n%s", len(message), message)
+
+		var output bytes.Buffer
+		if err := newStdioServer(&input, &output, TrustPolicy{}, nil).serve(); err != nil {
+			t.Fatalf("Server failed: %v", err)
+		}
+		client := newStdioServer(&output, nil, TrustPolicy{}, nil)
+		var messages []map[string]json.RawMessage
+		for {
+			body, err := client.readMessage()
+			if err != nil {
+				return messages
+			}
+			var message map[string]json.RawMessage
+			if err := json.Unmarshal(body, &message); err != nil {
+				t.Fatalf("Invalid message %s: %v", body, err)
+			}
+			messages = append(messages, message)
+		}
+	}
+
+	// The same or an older catalog is fine, the server tells its own
+	messages := initialize(catalog.Version)
+	if len(messages) != 1 {
+		t.Fatalf("Expected only the response, got %v", messages)
+	}
+	var result struct {
+		ServerInfo map[string]string `json:"serverInfo"`
+	}
+	if err := json.Unmarshal(messages[0]["result"], &result); err != nil || result.ServerInfo["dataVersion"] != catalog.Version {
+		t.Errorf("Expected data version %s in the server info, got %s (%v)", catalog.Version, messages[0]["result"], err)
+	}
+
+	// A client expecting a newer catalog is warned ahead of the response
+	messages = initialize("9999.1.1")
+	if len(messages) != 2 || string(messages[0]["method"]) != `"window/showMessage"` {
+		t.Fatalf("Expected a warning and the response, got %v", messages)
+	}
+	var warning showMessageParams
+	if err := json.Unmarshal(messages[0]["params"], &warning); err != nil || warning.Type != 2 || !strings.Contains(warning.Message, "9999.1.1") {
+		t.Errorf("Expected a warning naming the expected version, got %s (%v)", messages[0]["params"], err)
+	}
+	if _, ok := messages[1]["result"]; !ok {
+		t.Errorf("Expected the initialize response, got %v", messages[1])
+	}
+}
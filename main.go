@@ -1,29 +1,24 @@
 package main
 
 import (
-	"embed"
+	"context"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
+	"io"
 	"os"
 	"path/filepath"
+	"runtime/debug"
 	"sort"
 	"strings"
+	"time"
 
 	"gopkg.in/yaml.v2"
 
+	"parascan/data"
 	"parascan/detectors"
+	"parascan/pkg/parascan"
 )
 
-//go:embed data/stack-dependency-files.yml
-var stackDependencyData []byte
-
-//go:embed data/file-detectors.yml
-var fileDetectorsData []byte
-
-//go:embed data/services/*.yml
-var servicesFS embed.FS
-
 const (
 	defaultConfigPath = "./parascope.yml"
 	Version           = "v0.8.0"
@@ -34,14 +29,47 @@ func main() {
 		showHelp()
 		return
 	}
+	// Flags before the subcommand are its flags, para -v scan is para scan -v
+	os.Args = append(os.Args[:1], commandArgs(os.Args[1:])...)
 	switch os.Args[1] {
 	case "scan":
 		handleScan()
-	case "help":
+	case "plugin":
+		handlePlugin()
+	case "catalog":
+		handleCatalog()
+	case "cache":
+		handleCache()
+	case "lsp", "--stdio":
+		handleStdio()
+	case "bootstrap":
+		handleBootstrap()
+	case "onboard":
+		handleOnboard()
+	case "status":
+		handleStatus()
+	case "diff":
+		handleDiff()
+	case "validate":
+		handleValidate()
+	case "list":
+		handleList()
+	case "fix-config":
+		handleFixConfig()
+	case "check-org":
+		handleCheckOrg()
+	case "review":
+		handleReview()
+	case "apps":
+		handleApps()
+	case "capabilities":
+		handleCapabilities()
+	case "help", "--help", "-h":
 		showHelp()
 	default:
-		fmt.Println("Unknown command:", os.Args[1])
-		showHelp()
+		fmt.Fprintln(os.Stderr, "❌ Unknown command:", os.Args[1])
+		fmt.Fprintln(os.Stderr, "Run para help for the list of commands")
+		os.Exit(1)
 	}
 }
 
@@ -50,109 +78,233 @@ func showHelp() {
 
 Commands:
   scan    Detect your stack and create parascope.yml
+  plugin  Scaffold, install and list external detector plugins
+  catalog Test catalog changes against fixtures and projects
+  cache   Verify and repair the catalog cache shared by concurrent runs
+  lsp     Serve scans to editor plugins as JSON-RPC over stdio (also --stdio)
+  bootstrap  Scaffold a Brewfile or .tool-versions from detected tooling
+  onboard    Draft an ONBOARDING.md for new joiners from the scan
+  status     Show the current health of detected services from their status pages
+  diff       Compare a fresh scan with parascope.yml, exit 2 when it has drifted
+  validate   Check parascope.yml against the provenance of the scan that wrote it
+  list       Show the services, languages and detectors parascan knows
+  fix-config Repair a malformed parascope.yml into a new file for review
+  check-org  Evaluate a policy across many repositories into a compliance matrix
+  review     Summarize the services a diff or pull request adds, as a PR comment
+  apps       Show the parascope.yml of every app of a repository side by side
+  capabilities  Show the languages, formats and features of this build, for scripts
   help    Show this help message
 
-Options for scan:
-  --verbose, -v    Show detailed detection information
+Run para <command> --help for the options of a command. Flags may come before or
+after arguments, as --flag value or --flag=value.
 
+Options for scan:
+` + scanOptionsHelp + `
 Examples:
-  para scan                          # detect stack and create parascope.yml
-  para scan ./my-project             # detect stack in directory and create config
-  para scan --verbose                # show detailed detection process
-  para scan -v ./my-project          # verbose analysis of specific directory`)
-}
-
-// Data structures for working with dependency analysis
-
-type StackDependencyFiles struct {
-	Languages map[string]Language `yaml:"languages"`
+` + scanExamplesHelp)
 }
 
-type Language struct {
-	API             API                       `yaml:"api"`
-	PackageManagers map[string]PackageManager `yaml:"package_managers"`
-}
-
-type API struct {
-	CheckURL     string  `yaml:"check_url"`
-	DelaySeconds float64 `yaml:"delay_seconds"`
-}
+// showScanHelp shows the options of para scan
+func showScanHelp() {
+	fmt.Println(`Usage: para scan [path|config.yml] [options]
 
-type PackageManager struct {
-	Files []string `yaml:"files"`
-}
+Detect the project's stack and services in path (the current directory by default)
+and write them to its parascope.yml, or to the given config file.
 
-type ServiceData struct {
-	Name   string              `yaml:"name"`
-	URL    string              `yaml:"url"`
-	Stacks map[string][]string `yaml:"stacks"`
+Options:
+` + scanOptionsHelp + `
+Examples:
+` + scanExamplesHelp)
 }
 
-type DetectionResult struct {
-	Language string
-	Files    []string
-	Services []ServiceDetection
-}
+// scanOptionsHelp and scanExamplesHelp are shared by para help and para scan --help
+const scanOptionsHelp = `  --verbose, -v    Show detailed detection information
+  --format, -f     Output format: yml-config (default), json-stdout, dot, sarif, vscode, patch
+  --output, -o     Write an output as format=path, repeatable (e.g. -o json=report.json -o sarif=scan.sarif)
+  --set-name       Project name of the parascope.yml section (default: the config's directory name)
+  --catalog        Use a catalog directory instead of the embedded data
+  --exec-after     Run a command with the JSON result on stdin after the scan, repeatable
+  --docs           Also infer services from README and docs (low confidence)
+  --iac-state      Also detect services from resource types in Terraform and Pulumi state files
+  --dotenv         Also read variable names from .env files, not only from .env.example and other templates
+  --deep           Also read imports of Go, Python and JavaScript/TypeScript sources for SDKs no dependency file lists
+  --max-depth      Directory levels searched for dependency files (default 4, 1 is the root only)
+  --monorepo       Scan each sub-project separately, one parascope.yml section per sub-project
+  --per-app        Scan each app matching a glob pattern into its own parascope.yml, repeatable (e.g. --per-app 'apps/*')
+  --exclude        Skip paths matching a glob pattern, repeatable (e.g. --exclude 'examples/*'), alias --exclude-path
+  --include        Only scan paths matching a glob pattern, repeatable (e.g. --include 'services/payments/**')
+  --budget         Skip expensive steps to finish in about this time (e.g. --budget 500ms)
+  --max-memory     Fail the scan when it needs more memory (e.g. --max-memory 512MB)
+  --stats          Show the scan's duration, dependency files and peak memory
+  --language-stats Measure the share of each language in tracked source files, e.g. 70% Go, 25% TypeScript
+  --link-type      Service URL to write: dashboard (default), docs, status or admin
+  --transitive     include (default) or exclude services only found as dependencies of dependencies
+  --provenance     Record the tool and catalog version, time and entries hash in parascope.yml
+  --annotate       Comment entries added to an existing parascope.yml and log each scan's changes
+  --verify         Re-check every entry of parascope.yml and mark those the scan no longer finds as unverified
+  --patch          Print the changes to parascope.yml as a unified diff instead of writing them, alias -f patch
+  --redact         Hash names, file paths and project URLs in JSON, SARIF, dot and hook output
+  --health         Score the inventory's completeness from 0 to 100, e.g. CI and error tracking found
+  --interactive, -i  Ask for account names (e.g. Sentry organization) to link to dashboards
+  --bare           Scan the default branch of a bare repository, a path or a remote URL, without a worktree
+  --git-remote     Git remote to read the repository URL from (default origin)
+  --insecure-skip-verify  Load unsigned or untrusted plugins and catalogs
+`
+
+const scanExamplesHelp = `  para scan                          # detect stack and create parascope.yml
+  para scan ./my-project             # detect stack in directory and create config
+  para scan --verbose                # show detailed detection process
+  para scan -v ./my-project          # verbose analysis of specific directory
+  para scan -o yml-config -o json=report.json -o sarif=scan.sarif  # several outputs from one scan
+  para scan --patch > parascope.patch                             # review the config changes before applying them
+  para scan --bare /srv/git/shop.git -f json-stdout               # inventory straight from git storage`
 
-type ServiceDetection struct {
-	Name     string
-	Language string
-	Packages []PackageInfo
+// JSON response structures for rich format output
+type SniffResponse struct {
+	Status         string                                `json:"status"`
+	DataVersion    string                                `json:"data_version,omitempty"` // of the catalog, to tell scans by builds with other detection data apart
+	ErrorDetails   string                                `json:"error_details,omitempty"`
+	Lang           string                                `json:"lang,omitempty"` // the first of langs
+	LangConfidence string                                `json:"lang_confidence,omitempty"`
+	PackageManager string                                `json:"package_manager,omitempty"` // of lang
+	Langs          []parascan.ProjectLanguage            `json:"langs,omitempty"`           // every language, the one with the most dependency files first
+	Services       map[string]string                     `json:"services,omitempty"`
+	Evidence       map[string][]parascan.ServiceEvidence `json:"evidence,omitempty"` // why each service was detected, per language
+	Inferred       []string                              `json:"inferred,omitempty"` // services only mentioned in docs, low confidence
+	Deprecations   []parascan.Deprecation                `json:"deprecations,omitempty"`
+	Warnings       []parascan.ConfigWarning              `json:"warnings,omitempty"`       // outdated configuration, e.g. removed CI runners
+	Attention      []parascan.AttentionFinding           `json:"attention,omitempty"`      // findings to act on, most pressing first
+	Kubernetes     *parascan.KubernetesManifests         `json:"kubernetes,omitempty"`     // images, hosts, Secrets and ConfigMaps of manifests
+	Repository     *parascan.Repository                  `json:"repository,omitempty"`     // remotes, hosting and default branch
+	Helm           *parascan.HelmCharts                  `json:"helm,omitempty"`           // charts, their dependencies and images
+	Serverless     []parascan.ServerlessProject          `json:"serverless,omitempty"`     // Serverless Framework, SAM, CDK and Pulumi deployments
+	Fingerprint    string                                `json:"fingerprint,omitempty"`    // same for every clone of the project, to dedupe scans
+	Tasks          []parascan.Task                       `json:"tasks,omitempty"`          // how to build, test and run the project
+	LanguageStats  []parascan.LanguageShare              `json:"language_stats,omitempty"` // with --language-stats, largest first
+	BudgetSkipped  []string                              `json:"budget_skipped,omitempty"` // steps left out to meet --budget
+	Health         *parascan.HealthScore                 `json:"health,omitempty"`         // with --health
+	Stats          *ScanStats                            `json:"stats,omitempty"`          // with --stats
+	Graph          *ComponentGraph                       `json:"graph,omitempty"`
+	Subprojects    map[string]SniffResponse              `json:"subprojects,omitempty"` // by path, in --monorepo mode
+	Apps           map[string]SniffResponse              `json:"apps,omitempty"`        // by path, with --per-app
 }
 
-type PackageInfo struct {
-	Name string
-	File string
+// ScanStats is the JSON form of the scan's statistics
+type ScanStats struct {
+	DurationMS      int64  `json:"duration_ms"`
+	DependencyFiles int    `json:"dependency_files"`
+	PeakMemory      uint64 `json:"peak_memory_bytes"`
 }
 
-// JSON response structures for rich format output
-type SniffResponse struct {
-	Status         string            `json:"status"`
-	ErrorDetails   string            `json:"error_details,omitempty"`
-	Lang           string            `json:"lang,omitempty"`
-	PackageManager string            `json:"package_manager,omitempty"`
-	Services       map[string]string `json:"services,omitempty"`
+// handleScan runs para scan. os.Exit skips deferred calls, so the scan returns its
+// exit code and cleans up, e.g. an exported bare repository, before the process exits.
+func handleScan() {
+	if code := scanCommand(); code != 0 {
+		os.Exit(code)
+	}
 }
 
-func handleScan() {
-	// Parse arguments - path can be positional argument and flags
-	var projectPath, configPath string
+// scanCommand is para scan, returning its exit code
+func scanCommand() int {
 	var verbose bool
 	var format string = "yml-config" // default format
 	var customProjectName string
-
-	// Parse flags first and collect non-flag arguments
-	args := os.Args[2:] // Skip 'para' and 'scan'
-	var pathArgs []string
-
-	for i, arg := range args {
-		if arg == "--verbose" || arg == "-v" {
-			verbose = true
-		} else if arg == "--format" || arg == "-f" {
-			// Get format value from next argument
-			if i+1 < len(args) {
-				format = args[i+1]
-				// Skip the next argument in the next iteration
-				args[i+1] = ""
-			}
-		} else if arg == "--set-name" {
-			// Get custom project name from next argument
-			if i+1 < len(args) {
-				customProjectName = args[i+1]
-				// Skip the next argument in the next iteration
-				args[i+1] = ""
-			}
-		} else if arg != "" {
-			// This is a path argument, not a flag
-			pathArgs = append(pathArgs, arg)
-		}
+	var catalogDir string
+	var skipVerify bool
+	var fromDocs bool
+	var iacState bool
+	var dotenv bool
+	var languageStats bool
+	var deep bool
+	var monorepo bool
+	var maxDepth int
+	var budget time.Duration
+	var maxMemory byteSizeFlag
+	var showStats bool
+	var linkType string
+	var transitive string
+	var interactive bool
+	var provenance bool
+	var annotate bool
+	var verify bool
+	var patch bool
+	var health bool
+	var redact bool
+	var excludes stringsFlag
+	var includes stringsFlag
+	var outputs outputsFlag
+	var execAfter stringsFlag
+	var bareSource string
+	var appPatterns stringsFlag
+	var gitRemote string
+
+	flags := newCommandFlags("scan", showScanHelp)
+	flags.BoolVar(&verbose, "verbose", false, "")
+	flags.BoolVar(&verbose, "v", false, "")
+	flags.StringVar(&format, "format", format, "")
+	flags.StringVar(&format, "f", format, "")
+	flags.StringVar(&customProjectName, "set-name", "", "")
+	flags.StringVar(&catalogDir, "catalog", "", "")
+	flags.Var(&outputs, "output", "")
+	flags.Var(&outputs, "o", "")
+	flags.Var(&execAfter, "exec-after", "")
+	flags.StringVar(&bareSource, "bare", "", "")
+	flags.StringVar(&gitRemote, "git-remote", "", "")
+	flags.BoolVar(&skipVerify, "insecure-skip-verify", false, "")
+	flags.BoolVar(&fromDocs, "docs", false, "")
+	flags.BoolVar(&iacState, "iac-state", false, "")
+	flags.BoolVar(&dotenv, "dotenv", false, "")
+	flags.BoolVar(&languageStats, "language-stats", false, "")
+	flags.BoolVar(&deep, "deep", false, "")
+	flags.BoolVar(&monorepo, "monorepo", false, "")
+	flags.Var(&appPatterns, "per-app", "")
+	flags.Var(&excludes, "exclude", "")
+	flags.Var(&excludes, "exclude-path", "")
+	flags.Var(&includes, "include", "")
+	flags.IntVar(&maxDepth, "max-depth", 0, "")
+	flags.Var(&maxMemory, "max-memory", "")
+	flags.BoolVar(&showStats, "stats", false, "")
+	flags.BoolVar(&provenance, "provenance", false, "")
+	flags.BoolVar(&annotate, "annotate", false, "")
+	flags.BoolVar(&verify, "verify", false, "")
+	flags.BoolVar(&patch, "patch", false, "")
+	flags.BoolVar(&health, "health", false, "")
+	flags.BoolVar(&redact, "redact", false, "")
+	flags.BoolVar(&interactive, "interactive", false, "")
+	flags.BoolVar(&interactive, "i", false, "")
+	flags.StringVar(&linkType, "link-type", "", "")
+	flags.StringVar(&transitive, "transitive", "", "")
+	flags.DurationVar(&budget, "budget", 0, "")
+	pathArgs := flags.parse(os.Args[2:])
+	flags.maxArgs(pathArgs, 1)
+	formatSet := flags.isSet("format", "f")
+	if patch {
+		if formatSet && format != "patch" {
+			flags.fail("--patch can't be combined with --format %s", format)
+		}
+		format, formatSet = "patch", true
+	}
+	if len(appPatterns) > 0 && (monorepo || bareSource != "") {
+		flags.fail("--per-app can't be combined with --monorepo or --bare")
+	}
+
+	// How deep dependency files are searched for, 1 is the project root only
+	if flags.isSet("max-depth") && maxDepth < 1 {
+		flags.fail("Invalid --max-depth: %d, expected a number of at least 1", maxDepth)
+	}
+	// Latency target for editors and pre-commit hooks, expensive steps are skipped to meet it
+	if flags.isSet("budget") && budget <= 0 {
+		flags.fail("Invalid --budget: %s, expected a duration such as 500ms", budget)
 	}
 
+	var projectPath, configPath string
+	configPathSet := false
 	if len(pathArgs) >= 1 {
 		argPath := pathArgs[0]
 		if strings.HasSuffix(argPath, ".yml") || strings.HasSuffix(argPath, ".yaml") {
 			// Argument is a config file path - analyze parent directory, save to specified file
 			configPath = argPath
+			configPathSet = true
 			projectPath = filepath.Dir(argPath)
 			if projectPath == "." {
 				projectPath = "."
@@ -167,10 +319,90 @@ func handleScan() {
 		configPath = "parascope.yml"
 	}
 
-	// Only show analysis message for yml-config format
-	if format == "yml-config" {
+	// A bare repository has no worktree, its default branch is exported instead. The
+	// config path argument, parascope.yml by default, stays where it was given.
+	var bare *BareTree
+	if bareSource != "" {
+		var err error
+		if bare, err = exportBareRepository(bareSource, gitRemote); err != nil {
+			reportScanError(format, err.Error())
+			return 1
+		}
+		defer bare.Remove()
+		projectPath = bare.Dir
+	}
+
+	// Project settings from .parascan.yml provide defaults for flags
+	settings, err := parascan.LoadProjectSettings(projectPath)
+	if err != nil {
+		reportScanError(format, err.Error())
+		return 1
+	}
+	if !formatSet && settings.Output.Format != "" {
+		format = settings.Output.Format
+	}
+	if !configPathSet && settings.Output.ConfigPath != "" && bare == nil {
+		configPath = filepath.Join(projectPath, settings.Output.ConfigPath)
+	}
+	if customProjectName == "" {
+		customProjectName = settings.Output.ProjectName
+	}
+	if customProjectName == "" && bare != nil {
+		customProjectName = bare.Name
+	}
+	verbose = verbose || settings.Output.Verbose
+	if linkType == "" {
+		linkType = settings.Output.LinkType
+	}
+	if linkType != "" && !parascan.IsLinkType(linkType) {
+		reportScanError(format, fmt.Sprintf("Unknown link type: %s. Supported link types: %s", linkType, strings.Join(parascan.LinkTypes, ", ")))
+		return 1
+	}
+	switch transitive {
+	case "":
+	case "include", "exclude":
+		settings.ExcludeTransitive = transitive == "exclude"
+	default:
+		reportScanError(format, fmt.Sprintf("Unknown --transitive: %s. Use include or exclude", transitive))
+		return 1
+	}
+	settings.Ignore = append(settings.Ignore, excludes...)
+	if len(includes) > 0 {
+		settings.Include = includes
+	}
+	// Apps of .parascan.yml are scanned by default, the configs of an exported bare
+	// repository would be removed with it
+	if len(appPatterns) == 0 && !monorepo && bare == nil {
+		appPatterns = settings.Apps
+	}
+
+	// --format is the stdout output, used by default or when given alongside --output
+	if len(outputs) == 0 || formatSet {
+		if !isOutputFormat(format) {
+			fmt.Printf("❌ Unknown format: %s. Supported formats: %s\n", format, strings.Join(outputFormats, ", "))
+			return 1
+		}
+		outputs = append([]OutputTarget{{Format: format, Path: "-"}}, outputs...)
+	}
+
+	// Human-readable progress is shown unless machine-readable output goes to stdout
+	console := true
+	for _, target := range outputs {
+		if target.toStdout() {
+			console = false
+			format = target.Format
+		}
+	}
+	if console {
+		format = "yml-config"
+	}
+
+	// Only show analysis message for console output
+	if console {
 		displayPath := projectPath
-		if projectPath == "." {
+		if bare != nil {
+			displayPath = fmt.Sprintf("%s (%s)", bareSource, bare.Revision())
+		} else if projectPath == "." {
 			if cwd, err := os.Getwd(); err == nil {
 				displayPath = "current directory (" + filepath.Base(cwd) + ")"
 			} else {
@@ -180,127 +412,112 @@ func handleScan() {
 		fmt.Printf("🔍 Analyzing project in %s...\n\n", displayPath)
 	}
 
-	// Load stack dependency files data
-	stackData, err := loadStackDependencyFiles()
+	globalConfig, err := loadGlobalConfig()
 	if err != nil {
-		if format == "yml-config" {
-			fmt.Printf("❌ Error loading stack data: %v\n", err)
-		} else {
-			// For JSON format, output error in JSON
-			errorResponse := SniffResponse{
-				Status:       "fail",
-				ErrorDetails: fmt.Sprintf("Error loading stack data: %v", err),
-			}
-			jsonData, _ := json.MarshalIndent(errorResponse, "", "  ")
-			fmt.Println(string(jsonData))
-		}
-		return
+		reportScanError(format, err.Error())
+		return 1
 	}
 
-	// Load services data
-	servicesData, err := loadServicesData()
+	// Load the embedded catalog, the one pinned by the project, or a verified external one
+	catalog, warnings, err := resolveCatalog(projectPath, catalogDir, settings, globalConfig.Trust, skipVerify)
 	if err != nil {
-		if format == "yml-config" {
-			fmt.Printf("❌ Error loading services data: %v\n", err)
-		} else {
-			// For JSON format, output error in JSON
-			errorResponse := SniffResponse{
-				Status:       "fail",
-				ErrorDetails: fmt.Sprintf("Error loading services data: %v", err),
-			}
-			jsonData, _ := json.MarshalIndent(errorResponse, "", "  ")
-			fmt.Println(string(jsonData))
-		}
-		return
+		reportScanError(format, err.Error())
+		return 1
 	}
-
-	// Load file detectors data
-	fileDetectorsData, err := loadFileDetectorsData()
-	if err != nil {
-		if format == "yml-config" {
-			fmt.Printf("❌ Error loading file detectors data: %v\n", err)
-		} else {
-			// For JSON format, output error in JSON
-			errorResponse := SniffResponse{
-				Status:       "fail",
-				ErrorDetails: fmt.Sprintf("Error loading file detectors data: %v", err),
-			}
-			jsonData, _ := json.MarshalIndent(errorResponse, "", "  ")
-			fmt.Println(string(jsonData))
+	if console {
+		for _, warning := range warnings {
+			fmt.Printf("⚠️  %s\n", warning)
+		}
+		if iacState || settings.DetectorOptedIn(parascan.IaCStateDetector) {
+			fmt.Printf("🔐 Reading resource types from IaC state files, other values in them are never read\n")
+		}
+		if dotenv || settings.DetectorOptedIn(parascan.DotenvDetector) {
+			fmt.Printf("🔐 Reading variable names from .env files, their values are never read\n")
+		}
+		for _, rules := range catalog.Rules {
+			fmt.Printf("📐 Using rules from %s\n", rules)
 		}
-		return
 	}
+	ignoreConfigServices(settings, catalog, configPath, configProjectName(configPath, customProjectName))
+	stackData, servicesData := catalog.Stack, catalog.Services
 
-	// Create detectors in two phases:
-	// Phase 1: Simple detectors (don't need context)
-	var phase1Detectors []detectors.Detector
-
-	// Create adapter for services dependencies
-	adapter := &ServicesDependenciesAdapter{
-		stackData:    stackData,
-		servicesData: servicesData,
+	// Add installed external plugins
+	plugins, err := loadPluginDetectors(globalConfig.Trust, skipVerify)
+	if err != nil && console {
+		fmt.Printf("⚠️  Could not load plugins: %v\n", err)
 	}
 
-	// Add Services detector (simple)
-	servicesDetector := detectors.NewServicesDetector(adapter)
-	phase1Detectors = append(phase1Detectors, detectors.NewSimpleDetectorAdapter(servicesDetector))
-
-	// Add Git detector (simple)
-	gitDetector := &detectors.GitRepositoryDetector{}
-	phase1Detectors = append(phase1Detectors, detectors.NewSimpleDetectorAdapter(gitDetector))
-
-	// Phase 2: Context-aware detectors
-	var phase2Detectors []detectors.Detector
-
-	// Add Files detector (needs context for URL building)
-	filesDetector := detectors.NewFilesDetector(fileDetectorsData)
-	phase2Detectors = append(phase2Detectors, filesDetector)
-
-	// Run phase 1 detectors
-	allResults := make(map[string]string)
-	ctx := &detectors.DetectionContext{
-		ProjectPath: projectPath,
-		Results:     make(map[string]string),
+	// In a monorepo the root scan leaves sub-projects to their own scans
+	var subprojects []string
+	repoSettings := settings
+	if monorepo {
+		subprojects = findSubprojects(projectPath, stackData, settings.PathFilter(), maxDepth)
+		settings = subprojectSettings(repoSettings, ".", subprojects)
 	}
-
-	for _, detector := range phase1Detectors {
-		results, err := detector.Detect(ctx)
-		if err != nil {
-			if format == "yml-config" {
-				fmt.Printf("❌ Error running %s detector: %v\n", detector.Name(), err)
-			}
-			continue
-		}
-
-		// Merge results
-		for key, value := range results {
-			allResults[key] = value
-			ctx.Results[key] = value // Update context for next phase
+	// With apps, the root scan leaves them to their own configs
+	var apps []string
+	if len(appPatterns) > 0 {
+		apps = findApps(projectPath, appPatterns, settings.PathFilter())
+		if len(apps) == 0 {
+			reportScanError(format, fmt.Sprintf("No app directory matches %s", strings.Join(appPatterns, ", ")))
+			return 1
 		}
+		settings = subprojectSettings(repoSettings, ".", apps)
 	}
 
-	// Run phase 2 detectors with context
-	for _, detector := range phase2Detectors {
-		results, err := detector.Detect(ctx)
-		if err != nil {
-			if format == "yml-config" {
-				fmt.Printf("❌ Error running %s detector: %v\n", detector.Name(), err)
-			}
-			continue
+	scanOpts := ScanOptions{Plugins: plugins, Settings: settings, Docs: fromDocs, MaxDepth: maxDepth, Budget: budget, MaxMemory: uint64(maxMemory), LinkType: linkType, IaCState: iacState, Dotenv: dotenv, LangStats: languageStats, Deep: deep, GitRemote: gitRemote}
+	if bare != nil {
+		scanOpts.SkipGit, scanOpts.RepoURL = true, bare.RepoURL
+	}
+	if maxMemory > 0 {
+		// Let the garbage collector work harder before the limit fails the scan
+		debug.SetMemoryLimit(int64(maxMemory))
+	}
+	scan := runScan(projectPath, catalog, scanOpts)
+	if err := memoryLimitError(scan); err != nil {
+		reportScanError(format, err.Error())
+		return 1
+	}
+	subprojectReports, err := scanSubprojects(projectPath, configPath, catalog, repoSettings, subprojects, scanOpts)
+	if err != nil {
+		reportScanError(format, err.Error())
+		return 1
+	}
+	appReports, err := scanApps(projectPath, catalog, repoSettings, apps, scan.Results[detectors.RepoKey], scanOpts)
+	if err != nil {
+		reportScanError(format, err.Error())
+		return 1
+	}
+	// Deep links into the project's accounts need answers only a person can give
+	if interactive && console && (linkType == "" || linkType == "dashboard") {
+		if err := promptAccounts(projectPath, catalog, settings, scan.Results, os.Stdin, os.Stdout); err != nil {
+			fmt.Printf("⚠️  Could not save account answers: %v\n", err)
 		}
-
-		// Merge results
-		for key, value := range results {
-			allResults[key] = value
+	}
+	detectedLanguages, lowConfidence := scan.Languages, scan.LowConfidence
+	allResults := settings.ApplyToResults(scan.Results)
+	evidence := settings.ApplyToEvidence(scan.Evidence)
+	// Scored before the config is written, which would always list everything
+	var healthScore *parascan.HealthScore
+	if health || settings.Output.Health {
+		healthScore = parascan.ScoreHealth(catalog, allResults, configCurrent(configPath, customProjectName, settings.RepositoryName(), allResults))
+	}
+	if console {
+		for _, detectorErr := range scan.Errors {
+			fmt.Printf("❌ Error running %s detector: %v\n", detectorErr.Detector, detectorErr.Err)
 		}
 	}
 
-	// Show language detection for user feedback (keep existing behavior)
-	detectedLanguages := detectProjectLanguages(projectPath, stackData)
-
-	// Only show language detection messages for yml-config format
-	if format == "yml-config" {
-		if len(detectedLanguages) > 0 {
+	// Only show language detection messages for console output
+	if console {
+		if lowConfidence {
+			var titleLanguages []string
+			for _, lang := range detectedLanguages {
+				titleLanguages = append(titleLanguages, strings.Title(lang))
+			}
+			fmt.Printf("👃 No dependency files, but it faintly smells like %s (guessed from source files)\n", strings.Join(titleLanguages, ", "))
+			fmt.Println()
+		} else if len(detectedLanguages) > 0 {
 			if len(detectedLanguages) == 1 {
 				fmt.Printf("👃 Smells like %s in here!\n", strings.Title(detectedLanguages[0]))
 			} else {
@@ -315,374 +532,224 @@ func handleScan() {
 
 		// Display results
 		if verbose {
-			displayDetailedResults(projectPath, detectedLanguages, stackData, servicesData, allResults)
-		} else {
-			displayDetectorResults(allResults)
-		}
-	}
-
-	// Handle different output formats
-	switch format {
-	case "yml-config":
-		// Create or update configuration (default behavior)
-		createConfigFromDetectorResults(configPath, allResults, customProjectName)
-	case "json-stdout":
-		// Output rich JSON format to stdout
-		outputJSONFormat(allResults, detectedLanguages, stackData)
-	default:
-		fmt.Printf("❌ Unknown format: %s. Supported formats: yml-config, json-stdout\n", format)
-		os.Exit(1)
-	}
-}
-
-func loadStackDependencyFiles() (*StackDependencyFiles, error) {
-	var stackData StackDependencyFiles
-	err := yaml.Unmarshal(stackDependencyData, &stackData)
-	if err != nil {
-		return nil, err
-	}
-
-	return &stackData, nil
-}
-
-func loadServicesData() (map[string]*ServiceData, error) {
-	servicesData := make(map[string]*ServiceData)
-
-	entries, err := servicesFS.ReadDir("data/services")
-	if err != nil {
-		return nil, err
-	}
-
-	for _, entry := range entries {
-		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".yml") {
-			data, err := servicesFS.ReadFile("data/services/" + entry.Name())
-			if err != nil {
-				continue
-			}
-
-			var service ServiceData
-			err = yaml.Unmarshal(data, &service)
-			if err != nil {
-				continue
+			displayDetailedResults(projectPath, detectedLanguages, lowConfidence, stackData, servicesData, settings.PathFilter(), maxDepth, allResults, evidence)
+			if len(scan.Skipped) > 0 {
+				fmt.Printf("\n🔕 Skipped with fewer than %d signals: %s\n", settings.MinEvidence, strings.Join(scan.Skipped, ", "))
 			}
-
-			serviceName := entry.Name()[:len(entry.Name())-4] // remove .yml extension
-			servicesData[serviceName] = &service
-		}
-	}
-
-	return servicesData, nil
-}
-
-func loadFileDetectorsData() (*detectors.FileDetectors, error) {
-	var fileData detectors.FileDetectors
-	err := yaml.Unmarshal(fileDetectorsData, &fileData)
-	if err != nil {
-		return nil, err
-	}
-
-	return &fileData, nil
-}
-
-func detectProjectLanguages(projectPath string, stackData *StackDependencyFiles) []string {
-	var technologies []string
-
-	for tech, lang := range stackData.Languages {
-		found := false
-		for _, pm := range lang.PackageManagers {
-			for _, filePattern := range pm.Files {
-				if hasMatchingFiles(projectPath, filePattern) {
-					found = true
-					break
+			if len(scan.Tasks) > 0 {
+				fmt.Printf("\n🛠️  Tasks:\n")
+				for _, task := range scan.Tasks {
+					fmt.Printf("  %-24s (%s)\n", task.Command, task.Source)
 				}
 			}
-			if found {
-				break
-			}
-		}
-		if found {
-			technologies = append(technologies, tech)
-		}
-	}
-
-	return technologies
-}
-
-func hasMatchingFiles(dir, pattern string) bool {
-	// If pattern contains subdirectories (e.g. "requirements/*.txt")
-	if strings.Contains(pattern, "/") {
-		matches, err := filepath.Glob(filepath.Join(dir, pattern))
-		return err == nil && len(matches) > 0
-	}
-
-	// If pattern contains wildcards (e.g. "*.txt")
-	if strings.Contains(pattern, "*") {
-		matches, err := filepath.Glob(filepath.Join(dir, pattern))
-		return err == nil && len(matches) > 0
-	}
-
-	// Regular file
-	_, err := os.Stat(filepath.Join(dir, pattern))
-	return err == nil
-}
-
-func analyzeProjectDependencies(projectPath string, languages []string, stackData *StackDependencyFiles, servicesData map[string]*ServiceData) []DetectionResult {
-	var results []DetectionResult
-
-	for _, language := range languages {
-		langData := stackData.Languages[language]
-		foundFilesMap := make(map[string]bool)
-		servicesMap := make(map[string]*ServiceDetection)
-
-		// Collect all dependency files for this language (without duplicates)
-		for _, packageManager := range langData.PackageManagers {
-			for _, filePattern := range packageManager.Files {
-				matches, err := filepath.Glob(filepath.Join(projectPath, filePattern))
-				if err != nil {
-					continue
+			if charts := scan.Helm; charts != nil {
+				fmt.Printf("\n⎈  Helm charts: %s\n", strings.Join(charts.Charts, ", "))
+				for _, dependency := range charts.Dependencies {
+					fmt.Printf("  %-24s %s %s\n", dependency.Name, dependency.Version, dependency.Repository)
 				}
-				for _, match := range matches {
-					foundFilesMap[match] = true
+				if len(charts.Images) > 0 {
+					fmt.Printf("  Images: %s\n", strings.Join(charts.Images, ", "))
 				}
 			}
-		}
-
-		// Convert map to slice
-		var foundFiles []string
-		for file := range foundFilesMap {
-			foundFiles = append(foundFiles, file)
-		}
-
-		// Analyze found files only once each
-		analyzedFiles := make(map[string]bool)
-		for _, file := range foundFiles {
-			if !analyzedFiles[file] {
-				analyzedFiles[file] = true
-				fileServices := analyzeFile(file, language, servicesData)
-				for _, service := range fileServices {
-					if existing, exists := servicesMap[service.Name]; exists {
-						// Merge packages, avoiding duplicates
-						packageMap := make(map[string]PackageInfo)
-						for _, pkg := range existing.Packages {
-							packageMap[pkg.Name] = pkg
-						}
-						for _, pkg := range service.Packages {
-							packageMap[pkg.Name] = pkg
-						}
-
-						var mergedPackages []PackageInfo
-						for _, pkg := range packageMap {
-							mergedPackages = append(mergedPackages, pkg)
-						}
-						existing.Packages = mergedPackages
-					} else {
-						// Create a copy to avoid pointer issues
-						serviceCopy := ServiceDetection{
-							Name:     service.Name,
-							Language: service.Language,
-							Packages: service.Packages,
+			if manifests := scan.Kubernetes; manifests != nil {
+				fmt.Printf("\n☸️  Kubernetes manifests: %s\n", strings.Join(manifests.Files, ", "))
+				for _, list := range []struct {
+					label string
+					names []string
+				}{{"Images", manifests.Images}, {"Hosts", manifests.Hosts}, {"Secrets", manifests.Secrets}, {"ConfigMaps", manifests.ConfigMaps}} {
+					if len(list.names) > 0 {
+						fmt.Printf("  %-11s %s\n", list.label+":", strings.Join(list.names, ", "))
+					}
+				}
+			}
+			if len(scan.Serverless) > 0 {
+				fmt.Printf("\nλ  Serverless deployments:\n")
+				for _, project := range scan.Serverless {
+					var target []string
+					for _, value := range []string{project.Provider, project.Region, project.Project, strings.Join(project.Runtimes, ", ")} {
+						if value != "" {
+							target = append(target, value)
 						}
-						servicesMap[service.Name] = &serviceCopy
 					}
+					if project.Functions > 0 {
+						target = append(target, fmt.Sprintf("%d functions", project.Functions))
+					}
+					fmt.Printf("  %-22s %-24s %s\n", project.Tool, project.File, strings.Join(target, ", "))
 				}
 			}
+		} else {
+			displayDetectorResults(allResults)
 		}
-
-		// Convert map to slice
-		var services []ServiceDetection
-		for _, service := range servicesMap {
-			services = append(services, *service)
-		}
-
-		if len(foundFiles) > 0 || len(services) > 0 {
-			result := DetectionResult{
-				Language: language,
-				Files:    foundFiles,
-				Services: services,
+		if len(scan.LanguageStats) > 0 {
+			var shares []string
+			for _, share := range scan.LanguageStats {
+				shares = append(shares, fmt.Sprintf("%.1f%% %s", share.Percent, share.Language))
 			}
-			results = append(results, result)
+			fmt.Printf("🧮 Languages: %s\n", strings.Join(shares, ", "))
 		}
-	}
-
-	return results
-}
-
-func analyzeFile(filePath, language string, servicesData map[string]*ServiceData) []ServiceDetection {
-	var detections []ServiceDetection
-
-	content, err := ioutil.ReadFile(filePath)
-	if err != nil {
-		return detections
-	}
-
-	fileName := filepath.Base(filePath)
-
-	for serviceName, serviceData := range servicesData {
-		if packages, exists := serviceData.Stacks[language]; exists {
-			var foundPackages []PackageInfo
-
-			for _, pkg := range packages {
-				if isPackageInFile(string(content), fileName, pkg, language) {
-					foundPackages = append(foundPackages, PackageInfo{
-						Name: pkg,
-						File: filePath,
-					})
-				}
-			}
-
-			if len(foundPackages) > 0 {
-				detection := ServiceDetection{
-					Name:     serviceName,
-					Language: language,
-					Packages: foundPackages,
-				}
-				detections = append(detections, detection)
-			}
+		if len(scan.Inferred) > 0 {
+			fmt.Printf("📄 Only mentioned in docs (low confidence): %s\n", strings.Join(scan.Inferred, ", "))
 		}
-	}
-
-	return detections
-}
-
-// Improved package search with proper parsing for different file types
-func isPackageInFile(content, fileName, packageName, language string) bool {
-	baseFileName := filepath.Base(fileName)
-
-	switch {
-	case baseFileName == "package.json":
-		return isPackageInPackageJson(content, packageName)
-	case baseFileName == "Gemfile":
-		return isPackageInGemfile(content, packageName)
-	case strings.HasSuffix(baseFileName, "requirements.txt"):
-		return isPackageInRequirements(content, packageName)
-	case baseFileName == "yarn.lock":
-		return isPackageInYarnLock(content, packageName)
-	case strings.HasSuffix(baseFileName, ".gemspec"):
-		return isPackageInGemspec(content, packageName)
-	default:
-		// For other files, use line-based search with word boundaries
-		return isPackageInGenericFile(content, packageName)
-	}
-}
-
-// Parse package.json to find dependencies
-func isPackageInPackageJson(content, packageName string) bool {
-	// Parse JSON structure
-	var pkg struct {
-		Dependencies    map[string]interface{} `json:"dependencies"`
-		DevDependencies map[string]interface{} `json:"devDependencies"`
-	}
-
-	if err := json.Unmarshal([]byte(content), &pkg); err != nil {
-		// Fallback to simple search if JSON parsing fails
-		return strings.Contains(content, `"`+packageName+`"`)
-	}
-
-	// Check dependencies and devDependencies
-	if pkg.Dependencies != nil {
-		if _, exists := pkg.Dependencies[packageName]; exists {
-			return true
+		if len(scan.BudgetSkipped) > 0 {
+			fmt.Printf("⏱️  Skipped to stay within %s: %s\n", budget, strings.Join(scan.BudgetSkipped, ", "))
 		}
-	}
-	if pkg.DevDependencies != nil {
-		if _, exists := pkg.DevDependencies[packageName]; exists {
-			return true
+		if showStats {
+			fmt.Printf("📊 Analyzed %d dependency files in %s, peak memory %s\n", scan.Stats.DependencyFiles, scan.Stats.Duration.Round(time.Millisecond), parascan.FormatBytes(scan.Stats.PeakMemory))
 		}
-	}
-
-	return false
-}
-
-// Parse Gemfile to find gems
-func isPackageInGemfile(content, packageName string) bool {
-	lines := strings.Split(content, "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		// Look for gem declarations: gem 'package-name' or gem "package-name"
-		if strings.HasPrefix(line, "gem ") {
-			// Extract gem name from quotes
-			if strings.Contains(line, `'`+packageName+`'`) || strings.Contains(line, `"`+packageName+`"`) {
-				return true
-			}
+		for _, subproject := range subprojectReports {
+			fmt.Printf("\n📦 %s (%s)\n", subproject.ProjectName, strings.Join(subproject.Languages, ", "))
+			displayDetectorResults(subproject.Results)
+		}
+		for _, app := range appReports {
+			fmt.Printf("\n📦 %s (%s) → %s\n", app.AppDir, strings.Join(app.Languages, ", "), app.ConfigPath)
+			displayDetectorResults(app.Results)
 		}
-	}
-	return false
-}
 
-// Parse requirements.txt to find packages
-func isPackageInRequirements(content, packageName string) bool {
-	lines := strings.Split(content, "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		// Skip comments and empty lines
-		if line == "" || strings.HasPrefix(line, "#") {
-			continue
+		// Deprecated catalog entries are migration candidates
+		for _, deprecation := range scan.Deprecations {
+			if deprecation.Successor != "" {
+				fmt.Printf("⚠️  %s is deprecated, consider migrating to %s\n", deprecation.Service, deprecation.Successor)
+			} else {
+				fmt.Printf("⚠️  %s is deprecated\n", deprecation.Service)
+			}
 		}
-		// Package name should be at the beginning of line (before version specifiers)
-		parts := strings.FieldsFunc(line, func(r rune) bool {
-			return r == '=' || r == '>' || r == '<' || r == '!' || r == ' ' || r == '~'
-		})
-		if len(parts) > 0 && parts[0] == packageName {
-			return true
+		for _, warning := range scan.Warnings {
+			fmt.Printf("⚠️  %s: %s\n", warning.Location(), warning.Message)
 		}
-	}
-	return false
-}
-
-// Parse yarn.lock to find real dependencies (not in hashes)
-func isPackageInYarnLock(content, packageName string) bool {
-	lines := strings.Split(content, "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		// Look for package declarations at the beginning of sections
-		if strings.Contains(line, "@") && strings.HasSuffix(line, ":") {
-			// Extract package name from yarn.lock entry like "package@version:"
-			parts := strings.Split(line, "@")
-			if len(parts) > 0 {
-				pkgName := strings.Trim(parts[0], `"'`)
-				if pkgName == packageName {
-					return true
+		if len(scan.Attention) > 0 {
+			fmt.Printf("\n🚨 Needs attention:\n")
+			color := useColor(os.Stdout)
+			for _, finding := range scan.Attention {
+				if location := finding.Location(); location != "" {
+					fmt.Printf("  %s %s: %s\n", severityLabel(finding.Severity, color), location, attentionMessage(finding))
+				} else {
+					fmt.Printf("  %s %s\n", severityLabel(finding.Severity, color), attentionMessage(finding))
 				}
 			}
 		}
+		if healthScore != nil {
+			writeHealthScore(os.Stdout, healthScore)
+		}
+	}
+
+	// Write every requested output from the same detection run
+	report := &ScanReport{
+		ProjectPath:    projectPath,
+		ConfigPath:     configPath,
+		ProjectName:    customProjectName,
+		RepositoryName: settings.RepositoryName(),
+		Languages:      detectedLanguages,
+		LowConfidence:  lowConfidence,
+		LanguageFiles:  scan.LanguageFiles,
+		Results:        allResults,
+		Evidence:       evidence,
+		Inferred:       scan.Inferred,
+		Deprecations:   scan.Deprecations,
+		Warnings:       scan.Warnings,
+		Attention:      scan.Attention,
+		Kubernetes:     scan.Kubernetes,
+		Repository:     scan.Repository,
+		Helm:           scan.Helm,
+		Serverless:     scan.Serverless,
+		Fingerprint:    scan.Fingerprint,
+		Tasks:          scan.Tasks,
+		LanguageStats:  scan.LanguageStats,
+		BudgetSkipped:  scan.BudgetSkipped,
+		Health:         healthScore,
+		DataVersion:    catalog.Version,
+		Stack:          stackData,
+		Filter:         settings.PathFilter(),
+		Subprojects:    subprojectReports,
+		Apps:           appReports,
+	}
+	if showStats {
+		report.Stats = &scan.Stats
+	}
+	if provenance || settings.Output.Provenance {
+		report.Provenance = &Provenance{Tool: Version, Catalog: catalog.Version, Scanned: time.Now()}
+	}
+	if annotate || settings.Output.Annotate {
+		report.Annotation = &Annotation{Tool: Version, Date: time.Now()}
+	}
+	if verify || settings.Output.Verify {
+		report.Verification = &Verification{Date: time.Now(), PruneAfter: defaultPruneAfter}
+		if settings.Output.PruneAfter > 0 {
+			report.Verification.PruneAfter = settings.Output.PruneAfter
+		}
+	}
+	if redact || settings.Output.Redact {
+		report.Redactor = newRedactor(catalog)
+	}
+	for _, app := range report.Apps {
+		app.Provenance, app.Annotation, app.Verification = report.Provenance, report.Annotation, report.Verification
+	}
+	failed := false
+	for _, target := range outputs {
+		if err := writeOutput(target, report); err != nil {
+			fmt.Fprintf(os.Stderr, "❌ Could not write %s output to %s: %v\n", target.Format, target.Path, err)
+			failed = true
+		} else if console && target.Format != "yml-config" {
+			fmt.Printf("📝 Wrote %s output to %s\n", target.Format, target.Path)
+		}
+	}
+
+	// Post-processing hooks see the same result as the JSON output
+	var hookOutput io.Writer = os.Stdout
+	if !console {
+		hookOutput = os.Stderr
+	}
+	var hooks []parascan.Hook
+	for _, command := range execAfter {
+		hooks = append(hooks, execHook(command, hookOutput, report))
+	}
+	for _, err := range parascan.RunHooks(context.Background(), scan, hooks...) {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		failed = true
+	}
+
+	if failed {
+		return 1
+	}
+	return 0
+}
+
+// reportScanError prints a fatal scan error in the requested format
+func reportScanError(format, details string) {
+	if format == "yml-config" {
+		fmt.Printf("❌ %s\n", details)
+		return
+	}
+	// For JSON format, output error in JSON
+	errorResponse := SniffResponse{
+		Status:       "fail",
+		ErrorDetails: details,
 	}
-	return false
+	jsonData, _ := json.MarshalIndent(errorResponse, "", "  ")
+	fmt.Println(string(jsonData))
 }
 
-// Parse gemspec files
-func isPackageInGemspec(content, packageName string) bool {
-	lines := strings.Split(content, "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		// Look for dependency declarations
-		if strings.Contains(line, "add_dependency") || strings.Contains(line, "add_development_dependency") {
-			if strings.Contains(line, `'`+packageName+`'`) || strings.Contains(line, `"`+packageName+`"`) {
-				return true
-			}
-		}
+func loadStackDependencyFiles() (*parascan.StackDependencyFiles, error) {
+	content, err := data.FS.ReadFile("stack-dependency-files.yml")
+	if err != nil {
+		return nil, err
 	}
-	return false
+	return parascan.ParseStackDependencyFiles(content)
 }
 
-// Generic file search with word boundaries
-func isPackageInGenericFile(content, packageName string) bool {
-	// Use word boundaries to avoid matching substrings
-	lines := strings.Split(content, "\n")
-	for _, line := range lines {
-		words := strings.Fields(line)
-		for _, word := range words {
-			// Clean word from common punctuation
-			cleanWord := strings.Trim(word, `"',:;()[]{}`)
-			if cleanWord == packageName {
-				return true
-			}
-		}
+func loadServicesData() (map[string]*parascan.ServiceData, error) {
+	return parascan.LoadServicesFS(data.FS, "services")
+}
+
+func loadFileDetectorsData() (*detectors.FileDetectors, error) {
+	content, err := data.FS.ReadFile("file-detectors.yml")
+	if err != nil {
+		return nil, err
 	}
-	return false
+	return parascan.ParseFileDetectors(content)
 }
 
 // Create parascope.yml configuration based on detected technologies and services
-func createConfigFromDetection(configPath string, languages []string, results []DetectionResult, servicesData map[string]*ServiceData) {
+func createConfigFromDetection(configPath string, languages []string, results []parascan.DetectionResult, servicesData map[string]*parascan.ServiceData) {
 	var config strings.Builder
 
 	// Get project name from directory
@@ -728,7 +795,7 @@ func createConfigFromDetection(configPath string, languages []string, results []
 	fmt.Printf("\n✨ Created %s with detected services\n", configPath)
 }
 
-func displayResults(results []DetectionResult, servicesData map[string]*ServiceData) {
+func displayResults(results []parascan.DetectionResult, servicesData map[string]*parascan.ServiceData) {
 	// Collect all unique services across all languages
 	allServices := make(map[string]bool)
 
@@ -766,10 +833,12 @@ func displayDetectorResults(results map[string]string) {
 	// Filter out GitHub if it's only detected by repository URL
 	filteredResults := filterGitHubByRepository(results)
 
-	serviceCount := len(filteredResults)
-	// Don't count 'repo' as a service
-	if _, hasRepo := filteredResults["repo"]; hasRepo {
-		serviceCount--
+	serviceCount := 0
+	// Don't count the repository as a service
+	for key := range filteredResults {
+		if !isRepositoryResult(key) {
+			serviceCount++
+		}
 	}
 
 	if serviceCount > 0 {
@@ -784,7 +853,7 @@ func displayDetectorResults(results map[string]string) {
 		// Собираем и сортируем ключи (кроме repo)
 		var keys []string
 		for key := range filteredResults {
-			if key != "repo" {
+			if !isRepositoryResult(key) {
 				keys = append(keys, key)
 			}
 		}
@@ -811,9 +880,12 @@ func displayDetectorResults(results map[string]string) {
 		}
 	}
 
-	if repo, hasRepo := filteredResults["repo"]; hasRepo {
+	if repo, hasRepo := filteredResults[detectors.RepoKey]; hasRepo {
 		fmt.Printf("📁 Repository: %s\n", repo)
 	}
+	if upstream, hasUpstream := filteredResults[detectors.UpstreamKey]; hasUpstream {
+		fmt.Printf("📁 Upstream: %s\n", upstream)
+	}
 }
 
 // filterGitHubByRepository removes GitHub from results if it's only detected by repository URL
@@ -821,16 +893,26 @@ func filterGitHubByRepository(results map[string]string) map[string]string {
 	filtered := make(map[string]string)
 
 	// Check if we have a GitHub repository URL
-	repoURL, hasRepo := results["repo"]
+	repoURL, hasRepo := results[detectors.RepoKey]
 	hasGitHubRepo := hasRepo && strings.Contains(repoURL, "github.com")
 
 	// Check if GitHub is detected as a service
 	_, hasGitHubService := results["github"]
 
 	// If GitHub is detected as service but we only have repo URL, exclude it
-	if hasGitHubService && hasGitHubRepo && len(results) == 2 {
-		// Only repo and github detected - likely false positive
-		filtered["repo"] = results["repo"]
+	services := 0
+	for key := range results {
+		if !isRepositoryResult(key) {
+			services++
+		}
+	}
+	if hasGitHubService && hasGitHubRepo && services == 1 {
+		// Only the repository and github detected - likely false positive
+		for key, value := range results {
+			if key != "github" {
+				filtered[key] = value
+			}
+		}
 		return filtered
 	}
 
@@ -854,38 +936,56 @@ func getTechnologyDisplayName(techKey, url string) string {
 	}
 
 	// Special case for repository
-	if techKey == "repo" {
-		return "Repository"
+	if techKey == detectors.RepoKey {
+		return parascan.DefaultRepositoryName
+	}
+	if techKey == detectors.DocsSiteKey {
+		return "Docs site"
+	}
+	if strings.HasPrefix(techKey, detectors.IngressPrefix) {
+		return techKey
 	}
 
 	// Fallback: convert key to title case
 	return strings.Title(techKey)
 }
 
-func createConfigFromDetectorResults(configPath string, results map[string]string, customProjectName string) {
-	// Filter out GitHub if it's only detected by repository URL
-	filteredResults := filterGitHubByRepository(results)
+// createConfigFromDetectorResults writes the results under the project's root key in
+// parascope.yml, the repository URL under repositoryName ("" for Repository). Other
+// spellings of the repository key in an existing section are merged into that one.
+// With an annotation, entries added to an existing file are commented and the
+// section's changelog updated.
+func createConfigFromDetectorResults(configPath string, results map[string]string, customProjectName, repositoryName string, annotation *Annotation) {
+	writeConfigResults(os.Stdout, configPath, results, customProjectName, repositoryName, annotation)
+}
 
-	// Get project name - use custom name if provided, otherwise derive from directory
-	var projectName string
-	if customProjectName != "" {
-		projectName = customProjectName
-	} else {
-		projectDir := filepath.Dir(configPath)
-		projectName = filepath.Base(projectDir)
-		if projectDir == "." {
-			if cwd, err := os.Getwd(); err == nil {
-				projectName = filepath.Base(cwd)
-			}
-		}
+// writeConfigResults is createConfigFromDetectorResults with its messages for people
+// written to w
+func writeConfigResults(w io.Writer, configPath string, results map[string]string, customProjectName, repositoryName string, annotation *Annotation) {
+	if repositoryName == "" {
+		repositoryName = parascan.DefaultRepositoryName
 	}
 
+	// Filter out GitHub if it's only detected by repository URL, write each address once
+	filteredResults := dedupeResults(filterGitHubByRepository(results))
+
+	projectName := configProjectName(configPath, customProjectName)
+
 	var existingValues []string
 	configExists := false
 
 	if content, err := os.ReadFile(configPath); err == nil {
 		configExists = true
 
+		// Entries written into a config that doesn't parse could end up anywhere,
+		// it's left alone until it's repaired
+		if _, problems := repairConfig(string(content)); len(problems) > 0 {
+			fmt.Fprintf(w, "\n⚠️  %s isn't valid YAML, not updating it:\n", configPath)
+			writeConfigProblems(w, configPath, problems)
+			fmt.Fprintf(w, "Run `para fix-config %s` to write a repaired copy for review.\n", configPath)
+			return
+		}
+
 		// Extract existing values to check for duplicates
 		var existingData map[string]interface{}
 		if err := yaml.Unmarshal(content, &existingData); err == nil {
@@ -903,18 +1003,16 @@ func createConfigFromDetectorResults(configPath string, results map[string]strin
 
 	// Find new services that don't already exist (by value)
 	newData := make(map[string]string)
+	resultKeys := make(map[string]string) // result key of each new entry, for annotations
 	newServices := 0
 
 	for key, value := range filteredResults {
-		displayName := getTechnologyDisplayName(key, value)
-		if key == "repo" {
-			displayName = "Repository"
-		}
+		displayName := configEntryName(key, value, repositoryName)
 
-		// Check if this value already exists
+		// Check if this value already exists, e.g. with a trailing slash
 		valueExists := false
 		for _, existingValue := range existingValues {
-			if existingValue == value {
+			if urlKey(existingValue) == urlKey(value) {
 				valueExists = true
 				break
 			}
@@ -922,144 +1020,101 @@ func createConfigFromDetectorResults(configPath string, results map[string]strin
 
 		if !valueExists {
 			newData[displayName] = value
+			resultKeys[displayName] = key
 			newServices++
 		}
 	}
 
 	if configExists {
-		if len(newData) == 0 {
-			fmt.Printf("\n✨ Config %s is up to date, no new services detected\n", configPath)
-			return
-		}
-
 		// Read existing content and split by root keys
 		content, err := os.ReadFile(configPath)
 		if err != nil {
-			fmt.Printf("⚠️  Could not read %s: %v\n", configPath, err)
+			fmt.Fprintf(w, "⚠️  Could not read %s: %v\n", configPath, err)
 			return
 		}
 
-		lines := strings.Split(string(content), "\n")
-		var sections []string
-		var currentSection []string
-		var foundProjectSection = false
-		var projectSectionIndex = -1
-
-		// Get our repo URL for fallback search
-		ourRepoURL := ""
-		if repoURL, exists := filteredResults["repo"]; exists {
-			ourRepoURL = repoURL
-		}
-
-		for _, line := range lines {
-			// Check if this is a root key (starts without indentation and ends with :)
-			if len(line) > 0 && line[0] != ' ' && line[0] != '\t' && strings.HasSuffix(strings.TrimSpace(line), ":") {
-				// Save previous section if exists
-				if len(currentSection) > 0 {
-					sections = append(sections, strings.Join(currentSection, "\n"))
-				}
-
-				// Check if this is our project section by name
-				rootKey := strings.TrimSuffix(strings.TrimSpace(line), ":")
-				if rootKey == projectName {
-					foundProjectSection = true
-					projectSectionIndex = len(sections)
-				}
+		sections := splitConfigSections(string(content))
+		projectSectionIndex := findConfigSection(sections, projectName, filteredResults[detectors.RepoKey], repositoryName)
+		foundProjectSection := projectSectionIndex >= 0
 
-				// Start new section
-				currentSection = []string{line}
-			} else {
-				// Add line to current section
-				currentSection = append(currentSection, line)
+		// One repository entry under the canonical name, whatever the section spelled it
+		merged := false
+		var updated, duplicates []string
+		if foundProjectSection {
+			section, found := mergeRepositoryKeys(sections[projectSectionIndex], repositoryName, filteredResults[detectors.RepoKey])
+			if _, isNew := newData[repositoryName]; found && isNew {
+				delete(newData, repositoryName)
+				newServices--
 			}
-		}
+			merged = section != sections[projectSectionIndex]
 
-		// Add last section
-		if len(currentSection) > 0 {
-			sections = append(sections, strings.Join(currentSection, "\n"))
-		}
-
-		// If not found by name and we have repo URL, search by repo URL
-		if !foundProjectSection && ourRepoURL != "" {
-			for i, section := range sections {
-				// Parse section to check for repo URL
-				var sectionData map[string]interface{}
-				// Try to parse just this section as YAML
-				lines := strings.Split(section, "\n")
-				if len(lines) > 0 {
-					// Create a temporary YAML with root key
-					tempYaml := section
-					if err := yaml.Unmarshal([]byte(tempYaml), &sectionData); err == nil {
-						// Get the first (and should be only) root key
-						for _, projectData := range sectionData {
-							if pd, ok := projectData.(map[interface{}]interface{}); ok {
-								// Check for repo or Repository fields
-								for k, v := range pd {
-									if kStr, ok := k.(string); ok && (kStr == "repo" || kStr == "Repository") {
-										if vStr, ok := v.(string); ok && vStr == ourRepoURL {
-											foundProjectSection = true
-											projectSectionIndex = i
-											break
-										}
-									}
-								}
-							}
-							break // Only check first root key
-						}
-					}
-				}
-				if foundProjectSection {
-					break
-				}
+			// Entries pointing to the same address, e.g. added by older versions, are kept once
+			section, duplicates = dedupeSectionValues(section)
+
+			// Services listed with another URL, e.g. a deep link into the account, are updated in place
+			section, updated = replaceSectionValues(section, newData)
+			for _, name := range updated {
+				delete(newData, name)
+				newServices--
 			}
+			sections[projectSectionIndex] = section
 		}
 
-		// Create YAML for new entries
-		newYaml, err := yaml.Marshal(newData)
-		if err != nil {
-			fmt.Printf("⚠️  Could not marshal new data to YAML: %v\n", err)
+		if len(newData) == 0 && !merged && len(updated) == 0 && len(duplicates) == 0 {
+			fmt.Fprintf(w, "\n✨ Config %s is up to date, no new services detected\n", configPath)
 			return
 		}
 
-		// Add proper indentation (2 spaces)
+		// Create YAML for new entries, with proper indentation (2 spaces)
 		indentedYaml := ""
-		for _, line := range strings.Split(string(newYaml), "\n") {
-			if strings.TrimSpace(line) != "" {
-				indentedYaml += "  " + line + "\n"
+		for _, name := range sortedKeys(newData) {
+			entry, err := yaml.Marshal(map[string]string{name: newData[name]})
+			if err != nil {
+				fmt.Fprintf(w, "⚠️  Could not marshal new data to YAML: %v\n", err)
+				return
+			}
+			indentedYaml += "  " + strings.TrimSpace(string(entry))
+			if annotation != nil {
+				indentedYaml += annotation.entryComment(resultKeys[name])
 			}
+			indentedYaml += "\n"
 		}
 
 		if foundProjectSection {
 			// Add to existing project section
-			sections[projectSectionIndex] = strings.TrimSuffix(sections[projectSectionIndex], "\n") + "\n" + strings.TrimSuffix(indentedYaml, "\n")
+			if indentedYaml != "" {
+				sections[projectSectionIndex] = strings.TrimSuffix(sections[projectSectionIndex], "\n") + "\n" + strings.TrimSuffix(indentedYaml, "\n")
+			}
 		} else {
 			// Create new project section
 			newSection := fmt.Sprintf("%s:\n%s", projectName, strings.TrimSuffix(indentedYaml, "\n"))
 			sections = append(sections, newSection)
+			projectSectionIndex = len(sections) - 1
 		}
-
-		// Filter out empty sections and join with empty lines between them
-		var nonEmptySections []string
-		for _, section := range sections {
-			trimmed := strings.TrimSpace(section)
-			if trimmed != "" {
-				nonEmptySections = append(nonEmptySections, trimmed)
-			}
-		}
-
-		var finalContent string
-		if len(nonEmptySections) > 0 {
-			finalContent = strings.Join(nonEmptySections, "\n\n") + "\n"
-		} else {
-			finalContent = ""
+		if annotation != nil {
+			changes := configChanges(sortedKeys(newData), updated, merged, duplicates)
+			sections[projectSectionIndex] = annotation.logChanges(sections[projectSectionIndex], changes)
 		}
 
-		if err := os.WriteFile(configPath, []byte(finalContent), 0644); err != nil {
-			fmt.Printf("⚠️  Could not write %s: %v\n", configPath, err)
+		if err := os.WriteFile(configPath, []byte(joinConfigSections(sections)), 0644); err != nil {
+			fmt.Fprintf(w, "⚠️  Could not write %s: %v\n", configPath, err)
 			return
 		}
 
-		fmt.Printf("\n✨ Updated %s with %d new detected services\n", configPath, newServices)
+		var changes []string
+		if newServices > 0 {
+			changes = append(changes, fmt.Sprintf("%d new detected services", newServices))
+		}
+		if len(updated) > 0 {
+			changes = append(changes, "new URLs for "+strings.Join(updated, ", "))
+		}
+		if merged {
+			changes = append(changes, "repository keys merged into "+repositoryName)
+		}
+		if len(duplicates) > 0 {
+			changes = append(changes, "duplicate "+strings.Join(duplicates, ", ")+" removed")
+		}
+		fmt.Fprintf(w, "\n✨ Updated %s with %s\n", configPath, strings.Join(changes, ", "))
 	} else {
 		// Create new file with project name as root key
 		fullData := map[string]interface{}{
@@ -1068,7 +1123,7 @@ func createConfigFromDetectorResults(configPath string, results map[string]strin
 
 		yamlData, err := yaml.Marshal(fullData)
 		if err != nil {
-			fmt.Printf("⚠️  Could not marshal config to YAML: %v\n", err)
+			fmt.Fprintf(w, "⚠️  Could not marshal config to YAML: %v\n", err)
 			return
 		}
 
@@ -1076,141 +1131,247 @@ func createConfigFromDetectorResults(configPath string, results map[string]strin
 		cleanedContent := strings.TrimSpace(string(yamlData)) + "\n"
 
 		if err := os.WriteFile(configPath, []byte(cleanedContent), 0644); err != nil {
-			fmt.Printf("⚠️  Could not write %s: %v\n", configPath, err)
+			fmt.Fprintf(w, "⚠️  Could not write %s: %v\n", configPath, err)
 			return
 		}
 
-		fmt.Printf("\n✨ Created %s with detected services\n", configPath)
+		fmt.Fprintf(w, "\n✨ Created %s with detected services\n", configPath)
+	}
+}
+
+// configProjectName is the root key of the project in parascope.yml: the custom name
+// if provided, otherwise the name of the config's directory
+func configProjectName(configPath, customProjectName string) string {
+	if customProjectName != "" {
+		return customProjectName
+	}
+	projectDir := filepath.Dir(configPath)
+	projectName := filepath.Base(projectDir)
+	if projectDir == "." {
+		if cwd, err := os.Getwd(); err == nil {
+			projectName = filepath.Base(cwd)
+		}
 	}
+	return projectName
 }
 
-// ServicesDependenciesAdapter adapts existing functions to detectors interface
-type ServicesDependenciesAdapter struct {
-	stackData    *StackDependencyFiles
-	servicesData map[string]*ServiceData
+// configEntryName is the key a result is written under in parascope.yml: the
+// repository under repositoryName, docs_site and Ingress hosts as they are, others
+// by display name
+func configEntryName(key, value, repositoryName string) string {
+	if key == detectors.RepoKey {
+		return repositoryName
+	}
+	if key == detectors.DocsSiteKey || strings.HasPrefix(key, detectors.IngressPrefix) {
+		return key
+	}
+	return getTechnologyDisplayName(key, value)
 }
 
-func (a *ServicesDependenciesAdapter) DetectProjectLanguages(projectPath string) []string {
-	return detectProjectLanguages(projectPath, a.stackData)
+// isRepositoryKey reports whether a config key is a spelling of the repository key:
+// repo, Repository or the project's own name for it
+func isRepositoryKey(key, repositoryName string) bool {
+	return strings.EqualFold(key, detectors.RepoKey) || strings.EqualFold(key, parascan.DefaultRepositoryName) || key == repositoryName
 }
 
-func (a *ServicesDependenciesAdapter) AnalyzeProjectDependencies(projectPath string, languages []string) []detectors.ProjectResult {
-	results := analyzeProjectDependencies(projectPath, languages, a.stackData, a.servicesData)
+// isRepositoryResult reports whether a result key is an address of the repository
+// rather than a service: its URL or its upstream's
+func isRepositoryResult(key string) bool {
+	return key == detectors.RepoKey || key == detectors.UpstreamKey
+}
 
-	// Convert to detectors format
-	var detectorResults []detectors.ProjectResult
-	for _, result := range results {
-		var services []detectors.ServiceResult
-		for _, service := range result.Services {
-			services = append(services, detectors.ServiceResult{
-				Name: service.Name,
-			})
+// mergeRepositoryKeys rewrites the repository entries of a project section into one
+// repositoryName entry in place of the first, holding url or the value found there.
+// found reports whether the section had a repository entry.
+func mergeRepositoryKeys(section, repositoryName, url string) (merged string, found bool) {
+	lines := strings.Split(section, "\n")
+	kept := lines[:0]
+	for i, line := range lines {
+		trimmed := strings.TrimLeft(line, " \t")
+		key, value, ok := strings.Cut(trimmed, ":")
+		if i == 0 || trimmed == line || !ok || !isRepositoryKey(strings.Trim(strings.TrimSpace(key), `"'`), repositoryName) {
+			kept = append(kept, line)
+			continue
 		}
-		detectorResults = append(detectorResults, detectors.ProjectResult{
-			Language: result.Language,
-			Services: services,
-		})
+		if found {
+			continue
+		}
+		found = true
+		value, comment := splitLineComment(value)
+		if url == "" {
+			url = strings.Trim(strings.TrimSpace(value), `"'`)
+		}
+		entry, err := yaml.Marshal(map[string]string{repositoryName: url})
+		if err != nil {
+			kept = append(kept, line)
+			continue
+		}
+		kept = append(kept, line[:len(line)-len(trimmed)]+strings.TrimSpace(string(entry))+comment)
+	}
+	return strings.Join(kept, "\n"), found
+}
+
+// replaceSectionValues sets the value of the project section's entries named in
+// values that hold another one, returning the names of the entries it updated, sorted
+func replaceSectionValues(section string, values map[string]string) (string, []string) {
+	var updated []string
+	lines := strings.Split(section, "\n")
+	for i, line := range lines {
+		trimmed := strings.TrimLeft(line, " \t")
+		key, current, ok := strings.Cut(trimmed, ":")
+		if i == 0 || trimmed == line || !ok {
+			continue
+		}
+		name := strings.Trim(strings.TrimSpace(key), `"'`)
+		current, comment := splitLineComment(current)
+		value, found := values[name]
+		if !found || urlKey(strings.Trim(strings.TrimSpace(current), `"'`)) == urlKey(value) || containsFold(updated, name) {
+			continue
+		}
+		entry, err := yaml.Marshal(map[string]string{name: value})
+		if err != nil {
+			continue
+		}
+		lines[i] = line[:len(line)-len(trimmed)] + strings.TrimSpace(string(entry)) + comment
+		updated = append(updated, name)
+	}
+	sort.Strings(updated)
+	return strings.Join(lines, "\n"), updated
+}
+
+// outputJSONFormat writes detection results in rich JSON format
+func outputJSONFormat(w io.Writer, response SniffResponse) {
+	// Output JSON to stdout
+	jsonData, err := json.MarshalIndent(response, "", "  ")
+	if err != nil {
+		response.Status = "fail"
+		response.ErrorDetails = fmt.Sprintf("Error marshaling JSON: %v", err)
+		response.Services = nil
+		response.Evidence = nil
+		response.Inferred = nil
+		response.Deprecations = nil
+		response.Warnings = nil
+		response.Attention = nil
+		response.Graph = nil
+		response.Lang = ""
+		response.PackageManager = ""
+		response.Langs = nil
+
+		// Try to marshal error response
+		errorJSON, _ := json.MarshalIndent(response, "", "  ")
+		fmt.Fprintln(w, string(errorJSON))
+		return
 	}
 
-	return detectorResults
+	fmt.Fprintln(w, string(jsonData))
 }
 
-// outputJSONFormat outputs detection results in rich JSON format
-func outputJSONFormat(allResults map[string]string, detectedLanguages []string, stackData *StackDependencyFiles) {
+// buildSniffResponse assembles the JSON result shared by json output and post-scan hooks
+func buildSniffResponse(allResults map[string]string, detectedLanguages []string, lowConfidence bool, languageFiles []parascan.ProjectLanguage, stackData *parascan.StackDependencyFiles, graph *ComponentGraph) SniffResponse {
 	response := SniffResponse{
 		Status:   "ok",
 		Services: make(map[string]string),
+		Langs:    languageFiles,
 	}
 
 	// Determine primary language and package manager
 	if len(detectedLanguages) > 0 {
 		primaryLang := detectedLanguages[0]
 		response.Lang = primaryLang
+		if lowConfidence {
+			response.LangConfidence = "low"
+		}
 
-		// Determine package manager for the primary language
+		// Determine package manager for the primary language from its files
 		if langData, exists := stackData.Languages[primaryLang]; exists {
-			packageManager := determinePackageManager(primaryLang, langData)
-			if packageManager != "" {
+			var found []parascan.PackageManagerFiles
+			for _, language := range languageFiles {
+				if language.Name == primaryLang {
+					found = language.PackageManagers
+				}
+			}
+			if packageManager := determinePackageManager(primaryLang, langData, found); packageManager != "" {
 				response.PackageManager = packageManager
 			}
 		}
 	}
 
-	// Add services to response (excluding repo)
+	// Add services to response (excluding the repository, see Repository)
 	for key, value := range allResults {
-		if key != "repo" {
+		if !isRepositoryResult(key) {
 			response.Services[key] = value
 		}
 	}
 
-	// Output JSON to stdout
-	jsonData, err := json.MarshalIndent(response, "", "  ")
-	if err != nil {
-		response.Status = "fail"
-		response.ErrorDetails = fmt.Sprintf("Error marshaling JSON: %v", err)
-		response.Services = nil
-		response.Lang = ""
-		response.PackageManager = ""
-
-		// Try to marshal error response
-		errorJSON, _ := json.MarshalIndent(response, "", "  ")
-		fmt.Println(string(errorJSON))
-		return
+	// Include internal dependency graph only for multi-component projects
+	if graph != nil && graph.hasInternalStructure() {
+		response.Graph = graph
 	}
 
-	fmt.Println(string(jsonData))
+	return response
 }
 
-// determinePackageManager determines the primary package manager for a language
-func determinePackageManager(language string, langData Language) string {
-	// Priority order for package managers
-	priorityOrder := map[string][]string{
-		"python": {"pip", "poetry", "pipenv", "setuptools", "conda"},
-		"nodejs": {"npm", "yarn", "pnpm"},
-		"java":   {"maven", "gradle"},
-		"dotnet": {"nuget", "dotnet_core"},
-		"go":     {"go_modules", "dep"},
-		"php":    {"composer"},
-		"ruby":   {"bundler", "gemspec"},
-	}
-
-	if priorities, exists := priorityOrder[language]; exists {
-		for _, pm := range priorities {
-			if _, hasPM := langData.PackageManagers[pm]; hasPM {
-				return pm
-			}
+// packageManagerPriority breaks ties between package managers found in a project
+var packageManagerPriority = map[string][]string{
+	"python": {"pip", "poetry", "pipenv", "setuptools", "conda"},
+	"nodejs": {"npm", "yarn", "pnpm"},
+	"java":   {"maven", "gradle"},
+	"dotnet": {"nuget", "dotnet_core"},
+	"go":     {"go_modules", "dep"},
+	"php":    {"composer"},
+	"ruby":   {"bundler", "gemspec"},
+}
+
+// determinePackageManager picks the primary package manager of a language among
+// those whose files the project has: one with a lockfile (yarn.lock, poetry.lock)
+// first, then by packageManagerPriority and by name. Without found files every
+// package manager of the language is a candidate.
+func determinePackageManager(language string, langData parascan.Language, found []parascan.PackageManagerFiles) string {
+	candidates := append([]parascan.PackageManagerFiles(nil), found...)
+	if len(candidates) == 0 {
+		for pm := range langData.PackageManagers {
+			candidates = append(candidates, parascan.PackageManagerFiles{Name: pm})
 		}
 	}
-
-	// If no priority order defined, return the first available package manager
-	for pm := range langData.PackageManagers {
-		return pm
+	if len(candidates) == 0 {
+		return ""
 	}
 
-	return ""
-}
-
-func (a *ServicesDependenciesAdapter) GetServicesData() map[string]*detectors.ServiceInfo {
-	result := make(map[string]*detectors.ServiceInfo)
-	for key, service := range a.servicesData {
-		result[key] = &detectors.ServiceInfo{
-			Name: service.Name,
-			URL:  service.URL,
+	priorities := packageManagerPriority[language]
+	rank := func(pm string) int {
+		for i, name := range priorities {
+			if name == pm {
+				return i
+			}
 		}
+		return len(priorities)
 	}
-	return result
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.HasLockfile() != b.HasLockfile() {
+			return a.HasLockfile()
+		}
+		if rank(a.Name) != rank(b.Name) {
+			return rank(a.Name) < rank(b.Name)
+		}
+		return a.Name < b.Name
+	})
+	return candidates[0].Name
 }
 
-func displayDetailedResults(projectPath string, detectedLanguages []string, stackData *StackDependencyFiles, servicesData map[string]*ServiceData, allResults map[string]string) {
+func displayDetailedResults(projectPath string, detectedLanguages []string, lowConfidence bool, stackData *parascan.StackDependencyFiles, servicesData map[string]*parascan.ServiceData, filter *detectors.PathFilter, maxDepth int, allResults map[string]string, evidence map[string][]parascan.ServiceEvidence) {
 	fmt.Printf("🔍 Detailed Detection Analysis\n")
 	fmt.Printf("═══════════════════════════════\n\n")
 
 	// Show detected languages
-	if len(detectedLanguages) > 0 {
+	if lowConfidence {
+		fmt.Printf("📝 Languages guessed from source files (low confidence): %s\n", strings.Join(detectedLanguages, ", "))
+		fmt.Printf("└── No dependency files to analyze\n\n")
+	} else if len(detectedLanguages) > 0 {
 		fmt.Printf("📝 Languages detected: %s\n\n", strings.Join(detectedLanguages, ", "))
 
 		// Analyze project dependencies with detailed output
-		results := analyzeProjectDependencies(projectPath, detectedLanguages, stackData, servicesData)
+		results := parascan.AnalyzeProjectDependencies(projectPath, detectedLanguages, stackData, servicesData, filter, maxDepth)
 
 		for _, result := range results {
 			fmt.Printf("🔧 %s Analysis:\n", strings.Title(result.Language))
@@ -1219,8 +1380,13 @@ func displayDetailedResults(projectPath string, detectedLanguages []string, stac
 			for _, file := range result.Files {
 				fmt.Printf("│   ├── %s\n", file)
 
+				if pack := parascan.MissingLanguagePack(file); pack != "" {
+					fmt.Printf("│   │   └── Not read, the %s language pack isn't in this build\n", pack)
+					continue
+				}
+
 				// Show packages found in this file
-				fileServices := analyzeFile(file, result.Language, servicesData)
+				fileServices := parascan.AnalyzeFile(file, result.Language, servicesData)
 				if len(fileServices) > 0 {
 					for _, service := range fileServices {
 						fmt.Printf("│   │   └── %s service detected\n", service.Name)
@@ -1258,14 +1424,20 @@ func displayDetailedResults(projectPath string, detectedLanguages []string, stac
 	}
 
 	// Show repository information
-	if repo, hasRepo := allResults["repo"]; hasRepo {
-		fmt.Printf("📁 Repository: %s\n\n", repo)
+	if repo, hasRepo := allResults[detectors.RepoKey]; hasRepo {
+		fmt.Printf("📁 Repository: %s\n", repo)
+		if upstream, hasUpstream := allResults[detectors.UpstreamKey]; hasUpstream {
+			fmt.Printf("📁 Upstream: %s\n", upstream)
+		}
+		fmt.Println()
 	}
 
 	// Show final summary
-	serviceCount := len(allResults)
-	if _, hasRepo := allResults["repo"]; hasRepo {
-		serviceCount-- // Don't count repo as a service
+	serviceCount := 0
+	for key := range allResults {
+		if !isRepositoryResult(key) {
+			serviceCount++ // Don't count the repository as a service
+		}
 	}
 
 	if serviceCount > 0 {
@@ -1275,7 +1447,7 @@ func displayDetailedResults(projectPath string, detectedLanguages []string, stac
 		// Show services in sorted order
 		var keys []string
 		for key := range allResults {
-			if key != "repo" {
+			if !isRepositoryResult(key) {
 				keys = append(keys, key)
 			}
 		}
@@ -1297,6 +1469,40 @@ func displayDetailedResults(projectPath string, detectedLanguages []string, stac
 			}
 
 			fmt.Printf("  🔗 %s → %s\n", displayName, value)
+			for _, item := range evidence[key] {
+				source, detail := item.Language, item.Package
+				if item.Detector == parascan.ContentDetector {
+					source, detail = "content", item.Pattern
+				} else if item.Pattern != "" {
+					source, detail = "file", item.Pattern
+				} else if item.Framework != "" {
+					source = item.Framework + " config"
+				} else if item.Keyword != "" && item.Detector == "files" {
+					source, detail = "badge", item.Keyword
+				} else if item.Keyword != "" {
+					source, detail = "docs", item.Keyword
+				} else if item.Resource != "" {
+					source, detail = "state", item.Resource
+				} else if item.Chart != "" {
+					source, detail = "chart", item.Chart
+				} else if item.Variable != "" {
+					source, detail = "env", item.Variable
+				} else if item.Import != "" {
+					source, detail = item.Language+" import", item.Import
+				}
+				if source == "" && detail == "" {
+					fmt.Printf("     └── %s detector\n", item.Detector)
+					continue
+				}
+				file := item.File
+				if item.Line > 0 {
+					file += fmt.Sprintf(":%d", item.Line)
+				}
+				if item.Indirect {
+					file += ", indirect"
+				}
+				fmt.Printf("     └── %s: %s (%s, %s detector)\n", source, detail, file, item.Detector)
+			}
 		}
 	} else {
 		fmt.Printf("❌ No services detected\n")
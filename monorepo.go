@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"parascan/detectors"
+	"parascan/pkg/parascan"
+)
+
+// findSubprojects lists the directories below the project root holding their own
+// dependency files (e.g. packages/web with a package.json), relative to the root
+// and sorted. Each one is a sub-project of a monorepo; files further down belong to
+// the nearest sub-project above them.
+func findSubprojects(projectPath string, stackData *parascan.StackDependencyFiles, filter *detectors.PathFilter, maxDepth int) []string {
+	var subprojects []string
+	for _, dir := range parascan.ProjectDirs(projectPath, filter, maxDepth) {
+		if dir != "." && hasDependencyFiles(projectPath, dir, stackData, filter) {
+			subprojects = append(subprojects, filepath.ToSlash(dir))
+		}
+	}
+	sort.Strings(subprojects)
+	return subprojects
+}
+
+// hasDependencyFiles reports whether the directory holds a dependency file of any language
+func hasDependencyFiles(projectPath, dir string, stackData *parascan.StackDependencyFiles, filter *detectors.PathFilter) bool {
+	for _, lang := range stackData.Languages {
+		for _, pm := range lang.PackageManagers {
+			for _, pattern := range pm.Files {
+				if len(parascan.FindDependencyFiles(projectPath, []string{dir}, pattern, filter)) > 0 {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// subprojectSettings scopes the repo's settings to a sub-project ("." for the root):
+// ignore and include patterns are made relative to it and nested sub-projects are
+// ignored, they get their own scan
+func subprojectSettings(settings *parascan.ProjectSettings, dir string, subprojects []string) *parascan.ProjectSettings {
+	scoped := *settings
+	within := (&detectors.PathFilter{Ignore: settings.Ignore, Include: settings.Include}).Within(dir)
+	scoped.Ignore, scoped.Include = within.Ignore, within.Include
+
+	prefix := ""
+	if dir != "." {
+		prefix = dir + "/"
+	}
+	for _, subproject := range subprojects {
+		if subproject != dir && strings.HasPrefix(subproject, prefix) {
+			scoped.Ignore = append(scoped.Ignore, "/"+strings.TrimPrefix(subproject, prefix)+"/")
+		}
+	}
+	return &scoped
+}
+
+// scanSubprojects runs detection in each sub-project. Reports are named after the
+// sub-project's path, which becomes its root key in parascope.yml. Scanning stops
+// at the first sub-project going over --max-memory.
+func scanSubprojects(projectPath, configPath string, catalog *parascan.Catalog, settings *parascan.ProjectSettings, subprojects []string, opts ScanOptions) ([]*ScanReport, error) {
+	var reports []*ScanReport
+	for _, dir := range subprojects {
+		subSettings := subprojectSettings(settings, dir, subprojects)
+		subPath := filepath.Join(projectPath, filepath.FromSlash(dir))
+
+		// The repository belongs to the root section
+		subOpts := opts
+		subOpts.SkipGit = true
+		subOpts.Settings = subSettings
+		scan := runScan(subPath, catalog, subOpts)
+		if err := memoryLimitError(scan); err != nil {
+			return reports, fmt.Errorf("%s: %v", dir, err)
+		}
+
+		reports = append(reports, subprojectReport(subPath, configPath, dir, scan, subSettings, catalog))
+	}
+	return reports, nil
+}
+
+// subprojectReport is the report of a scan of a part of the repository, a
+// sub-project or an app, with the results its settings keep
+func subprojectReport(projectPath, configPath, projectName string, scan *parascan.Report, settings *parascan.ProjectSettings, catalog *parascan.Catalog) *ScanReport {
+	return &ScanReport{
+		ProjectPath:    projectPath,
+		ConfigPath:     configPath,
+		ProjectName:    projectName,
+		RepositoryName: settings.RepositoryName(),
+		Languages:      scan.Languages,
+		LowConfidence:  scan.LowConfidence,
+		LanguageFiles:  scan.LanguageFiles,
+		Results:        settings.ApplyToResults(scan.Results),
+		Evidence:       settings.ApplyToEvidence(scan.Evidence),
+		Inferred:       scan.Inferred,
+		Deprecations:   scan.Deprecations,
+		Warnings:       scan.Warnings,
+		Attention:      scan.Attention,
+		Kubernetes:     scan.Kubernetes,
+		Repository:     scan.Repository,
+		Helm:           scan.Helm,
+		Serverless:     scan.Serverless,
+		Fingerprint:    scan.Fingerprint,
+		Tasks:          scan.Tasks,
+		LanguageStats:  scan.LanguageStats,
+		BudgetSkipped:  scan.BudgetSkipped,
+		Stack:          catalog.Stack,
+		Filter:         settings.PathFilter(),
+	}
+}
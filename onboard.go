@@ -0,0 +1,253 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"parascan/detectors"
+	"parascan/pkg/parascan"
+)
+
+// Onboarding is what a new joiner needs to know about a project, drafted from a scan
+type Onboarding struct {
+	ProjectName string
+	Repository  string
+	Languages   []LanguageVersion
+	Run         []parascan.Task  // Procfile processes and the usual setup, run and test tasks
+	Tasks       []parascan.Task  // the other tasks
+	Services    []OnboardingLink // services to get accounts for
+	CI          []OnboardingLink
+}
+
+// LanguageVersion is a detected language and the version the project pins, if any
+type LanguageVersion struct {
+	Language string
+	Version  string
+}
+
+// OnboardingLink is a detected service or CI with its dashboard URL
+type OnboardingLink struct {
+	Name string
+	URL  string
+}
+
+// Task names worth telling a new joiner about, in the order they usually run them
+var onboardingTasks = []string{"setup", "bootstrap", "install", "dev", "start", "run", "serve", "test", "lint", "build"}
+
+// buildOnboarding drafts the onboarding summary from the scan results
+func buildOnboarding(projectPath, projectName string, catalog *parascan.Catalog, scan *parascan.Report, results map[string]string, settings *parascan.ProjectSettings) Onboarding {
+	onboarding := Onboarding{ProjectName: projectName, Repository: results[detectors.RepoKey]}
+
+	// Versions come from the runtime tools of each language
+	toolNames := make([]string, 0, len(catalog.Tooling.Tools))
+	for name := range catalog.Tooling.Tools {
+		toolNames = append(toolNames, name)
+	}
+	sort.Strings(toolNames)
+	for _, language := range scan.Languages {
+		entry := LanguageVersion{Language: language}
+		for _, name := range toolNames {
+			tool := catalog.Tooling.Tools[name]
+			if containsFold(tool.DetectedBy, language) {
+				entry.Version = toolVersion(projectPath, tool.Version, settings)
+				break
+			}
+		}
+		onboarding.Languages = append(onboarding.Languages, entry)
+	}
+
+	// Common tasks in the order a new joiner runs them, then the rest as found
+	onboarding.Run = parascan.ProcfileProcesses(projectPath)
+	for _, name := range onboardingTasks {
+		for _, task := range scan.Tasks {
+			if task.Name == name {
+				onboarding.Run = append(onboarding.Run, task)
+			}
+		}
+	}
+	for _, task := range scan.Tasks {
+		if !containsFold(onboardingTasks, task.Name) {
+			onboarding.Tasks = append(onboarding.Tasks, task)
+		}
+	}
+
+	// CI is told apart by its file detector category
+	ci := make(map[string]bool)
+	if catalog.FileDetectors != nil {
+		for techKey, tech := range catalog.FileDetectors.Technologies {
+			if tech.Category == "ci" {
+				ci[strings.ToLower(techKey)] = true
+				ci[strings.ToLower(tech.DisplayName)] = true
+			}
+		}
+	}
+	for key, value := range filterGitHubByRepository(results) {
+		if isRepositoryResult(key) {
+			continue
+		}
+		displayName := getTechnologyDisplayName(key, value)
+		if service, ok := catalog.Services[key]; ok && service.Name != "" {
+			displayName = service.Name
+		}
+		link := OnboardingLink{Name: displayName, URL: value}
+		if ci[strings.ToLower(key)] {
+			onboarding.CI = append(onboarding.CI, link)
+		} else {
+			onboarding.Services = append(onboarding.Services, link)
+		}
+	}
+	sortLinks(onboarding.Services)
+	sortLinks(onboarding.CI)
+
+	return onboarding
+}
+
+func sortLinks(links []OnboardingLink) {
+	sort.Slice(links, func(i, j int) bool { return strings.ToLower(links[i].Name) < strings.ToLower(links[j].Name) })
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// writeOnboarding renders the summary as Markdown
+func writeOnboarding(w io.Writer, onboarding Onboarding) {
+	fmt.Fprintf(w, "# Onboarding to %s\n\n", onboarding.ProjectName)
+	fmt.Fprintf(w, "_Draft generated by `para onboard` from a scan of the repository, review it before committing._\n")
+	if onboarding.Repository != "" {
+		fmt.Fprintf(w, "\nRepository: %s\n", onboarding.Repository)
+	}
+
+	fmt.Fprintf(w, "\n## Languages\n\n")
+	if len(onboarding.Languages) == 0 {
+		fmt.Fprintf(w, "_None detected._\n")
+	}
+	for _, language := range onboarding.Languages {
+		if language.Version != "" {
+			fmt.Fprintf(w, "- %s %s\n", strings.Title(language.Language), language.Version)
+		} else {
+			fmt.Fprintf(w, "- %s (version not pinned)\n", strings.Title(language.Language))
+		}
+	}
+	if len(onboarding.Languages) > 0 {
+		fmt.Fprintf(w, "\nInstall the tools with `para bootstrap -o Brewfile && brew bundle`.\n")
+	}
+
+	fmt.Fprintf(w, "\n## Running the project\n\n")
+	if len(onboarding.Run) == 0 {
+		fmt.Fprintf(w, "_No Procfile, Makefile, justfile or package.json scripts found._\n")
+	}
+	for _, task := range onboarding.Run {
+		fmt.Fprintf(w, "- `%s` (%s `%s`)\n", task.Command, task.Source, task.Name)
+	}
+	if len(onboarding.Tasks) > 0 {
+		fmt.Fprintf(w, "\nOther tasks:\n\n")
+		for _, task := range onboarding.Tasks {
+			fmt.Fprintf(w, "- `%s` (%s)\n", task.Command, task.Source)
+		}
+	}
+
+	fmt.Fprintf(w, "\n## Accounts you'll need\n\n")
+	if len(onboarding.Services) == 0 {
+		fmt.Fprintf(w, "_None detected._\n")
+	}
+	for _, service := range onboarding.Services {
+		fmt.Fprintf(w, "- [%s](%s)\n", service.Name, service.URL)
+	}
+
+	fmt.Fprintf(w, "\n## CI\n\n")
+	if len(onboarding.CI) == 0 {
+		fmt.Fprintf(w, "_None detected._\n")
+	}
+	for _, ci := range onboarding.CI {
+		fmt.Fprintf(w, "- [%s](%s)\n", ci.Name, ci.URL)
+	}
+}
+
+func handleOnboard() {
+	projectPath := "."
+	var outputPath string
+	var force bool
+
+	flags := newCommandFlags("onboard", showOnboardHelp)
+	flags.StringVar(&outputPath, "output", "", "")
+	flags.StringVar(&outputPath, "o", "", "")
+	flags.BoolVar(&force, "force", false, "")
+	args := flags.parse(os.Args[2:])
+	flags.maxArgs(args, 1)
+	if len(args) == 1 {
+		projectPath = args[0]
+	}
+	if outputPath == "" {
+		outputPath = filepath.Join(projectPath, "ONBOARDING.md")
+	}
+
+	settings, err := parascan.LoadProjectSettings(projectPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		os.Exit(1)
+	}
+	globalConfig, err := loadGlobalConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		os.Exit(1)
+	}
+	catalog, _, err := resolveCatalog(projectPath, "", settings, globalConfig.Trust, false)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		os.Exit(1)
+	}
+
+	projectName := settings.Output.ProjectName
+	if projectName == "" {
+		if abs, err := filepath.Abs(projectPath); err == nil {
+			projectName = filepath.Base(abs)
+		}
+	}
+
+	scan := runScan(projectPath, catalog, ScanOptions{Settings: settings})
+	onboarding := buildOnboarding(projectPath, projectName, catalog, scan, settings.ApplyToResults(scan.Results), settings)
+
+	if outputPath == "-" {
+		writeOnboarding(os.Stdout, onboarding)
+		return
+	}
+	// The draft is meant to be edited, don't overwrite someone's edits
+	if _, err := os.Stat(outputPath); err == nil && !force {
+		fmt.Fprintf(os.Stderr, "❌ %s already exists, use --force to overwrite or -o - to print it\n", outputPath)
+		os.Exit(1)
+	}
+	file, err := os.Create(outputPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		os.Exit(1)
+	}
+	defer file.Close()
+	writeOnboarding(file, onboarding)
+	fmt.Printf("📝 Wrote onboarding draft to %s\n", outputPath)
+}
+
+func showOnboardHelp() {
+	fmt.Println(`Usage: para onboard [path] [--output file] [--force]
+
+Draft an ONBOARDING.md for new joiners from a scan: languages and pinned versions,
+how to run the project (Procfile, Makefile targets, package.json scripts), services
+to get accounts for and CI, with links.
+
+Options:
+  --output, -o     Write to a file, - for stdout (default ONBOARDING.md in the project)
+  --force          Overwrite an existing file
+
+Examples:
+  para onboard
+  para onboard ./my-project -o - | less`)
+}
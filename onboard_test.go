@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"parascan/pkg/parascan"
+)
+
+func TestOnboardingDraft(t *testing.T) {
+	skipWithoutPack(t, "package.json")
+	projectPath := filepath.Join("testdata", "onboarding-project")
+	catalog, err := parascan.EmbeddedCatalog()
+	if err != nil {
+		t.Fatalf("Failed to load catalog: %v", err)
+	}
+	settings := &parascan.ProjectSettings{}
+	scan := runScan(projectPath, catalog, ScanOptions{SkipGit: true, Settings: settings})
+	onboarding := buildOnboarding(projectPath, "storefront", catalog, scan, scan.Results, settings)
+
+	var draft bytes.Buffer
+	writeOnboarding(&draft, onboarding)
+	for _, expected := range []string{
+		"# Onboarding to storefront\n",
+		"- Nodejs 20.11.0\n",
+		// Procfile first, then tasks in the order they are usually run
+		"- `npm start` (Procfile `web`)\n- `node worker.js` (Procfile `worker`)\n- `make setup` (Makefile `setup`)\n- `npm run dev` (package.json `dev`)\n",
+		"Other tasks:\n\n- `make clean` (Makefile)\n- `just seed` (justfile)\n",
+		"- [Stripe](https://dashboard.stripe.com)\n",
+		"## CI\n\n- [GitHub Actions](",
+	} {
+		if !strings.Contains(draft.String(), expected) {
+			t.Errorf("Expected %q in onboarding draft:\n%s", expected, draft.String())
+		}
+	}
+	// CI is listed on its own, not as an account to create
+	if strings.Contains(strings.Split(draft.String(), "## CI")[0], "GitHub Actions") {
+		t.Errorf("Expected GitHub Actions only under CI:\n%s", draft.String())
+	}
+}
+
+func TestDetectTasks(t *testing.T) {
+	tasks := parascan.DetectTasks(filepath.Join("testdata", "onboarding-project"))
+
+	// Variable assignments, special targets, just settings and aliases aren't tasks
+	var commands []string
+	for _, task := range tasks {
+		commands = append(commands, task.Command)
+	}
+	expected := []string{
+		"make setup", "make test", "make clean",
+		"just seed", "just deploy",
+		"npm run build", "npm run dev", "npm run postinstall", "npm start", "npm test",
+	}
+	if !equalStringSlices(commands, expected) {
+		t.Errorf("Expected tasks %v, got %v", expected, commands)
+	}
+}
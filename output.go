@@ -0,0 +1,515 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"parascan/detectors"
+	"parascan/pkg/parascan"
+)
+
+// Output formats accepted by --format and --output
+var outputFormats = []string{"yml-config", "json-stdout", "json", "dot", "sarif", "vscode", "patch"}
+
+// OutputTarget is a format and the file it is written to ("-" for stdout)
+type OutputTarget struct {
+	Format string
+	Path   string
+}
+
+// toStdout reports whether the target writes machine-readable output to stdout.
+// yml-config always goes to the config file, patch is the changes it would make.
+func (t OutputTarget) toStdout() bool {
+	return t.Format != "yml-config" && (t.Path == "" || t.Path == "-")
+}
+
+// parseOutputTarget parses a --output value: format=path, or just format for stdout
+func parseOutputTarget(value string) (OutputTarget, error) {
+	format, path, _ := strings.Cut(value, "=")
+	target := OutputTarget{Format: format, Path: path}
+	if !isOutputFormat(format) {
+		return target, fmt.Errorf("unknown format: %s. Supported formats: %s", format, strings.Join(outputFormats, ", "))
+	}
+	return target, nil
+}
+
+func isOutputFormat(format string) bool {
+	for _, supported := range outputFormats {
+		if format == supported {
+			return true
+		}
+	}
+	return false
+}
+
+// ScanReport holds everything output formats are built from
+type ScanReport struct {
+	ProjectPath    string
+	ConfigPath     string
+	ProjectName    string
+	RepositoryName string // parascope.yml key of the repository URL, Repository by default
+	Languages      []string
+	LowConfidence  bool
+	LanguageFiles  []parascan.ProjectLanguage
+	Results        map[string]string
+	Evidence       map[string][]parascan.ServiceEvidence
+	Inferred       []string
+	Deprecations   []parascan.Deprecation
+	Warnings       []parascan.ConfigWarning
+	Attention      []parascan.AttentionFinding
+	Kubernetes     *parascan.KubernetesManifests
+	Repository     *parascan.Repository
+	Helm           *parascan.HelmCharts
+	Serverless     []parascan.ServerlessProject
+	Fingerprint    string // identifies the project across clones and machines
+	Tasks          []parascan.Task
+	LanguageStats  []parascan.LanguageShare
+	BudgetSkipped  []string        // steps left out to meet --budget
+	Stats          *parascan.Stats // with --stats
+	Stack          *parascan.StackDependencyFiles
+	Filter         *detectors.PathFilter
+	Subprojects    []*ScanReport         // per sub-project reports in --monorepo mode
+	Apps           []*ScanReport         // per app reports with --per-app, each with its own ConfigPath
+	AppDir         string                // of an app report, relative to the repository root
+	Provenance     *Provenance           // recorded in parascope.yml with --provenance
+	Annotation     *Annotation           // comments on what the scan changed in parascope.yml, with --annotate
+	Verification   *Verification         // re-checks the entries of parascope.yml, with --verify
+	Redactor       *redactor             // applied to shared outputs with --redact
+	Health         *parascan.HealthScore // inventory completeness, with --health
+	DataVersion    string                // version of the catalog the scan used
+
+	componentGraph *ComponentGraph
+}
+
+// graph builds the component graph once for all outputs that need it
+func (r *ScanReport) graph() *ComponentGraph {
+	if r.componentGraph == nil {
+		r.componentGraph = buildComponentGraph(r.ProjectPath, r.Filter)
+	}
+	return r.componentGraph
+}
+
+// writeProvenance records the scan at the end of a project section, with --provenance
+func (r *ScanReport) writeProvenance(configPath, projectName string, results map[string]string) error {
+	if r.Provenance == nil {
+		return nil
+	}
+	return writeProvenance(configPath, configProjectName(configPath, projectName), results[detectors.RepoKey], r.RepositoryName, *r.Provenance)
+}
+
+// verify re-checks the entries of a project section against the scan's results,
+// with --verify
+func (r *ScanReport) verify(w io.Writer, configPath, projectName string, results map[string]string) error {
+	if r.Verification == nil {
+		return nil
+	}
+	repositoryName := r.RepositoryName
+	if repositoryName == "" {
+		repositoryName = parascan.DefaultRepositoryName
+	}
+	filtered := dedupeResults(filterGitHubByRepository(results))
+	scanned := make(map[string]string)
+	for key, value := range filtered {
+		scanned[configEntryName(key, value, repositoryName)] = value
+	}
+	result, err := writeVerification(configPath, configProjectName(configPath, projectName), filtered[detectors.RepoKey], repositoryName, scanned, r.Verification)
+	if err != nil || result == nil {
+		return err
+	}
+	writeSectionVerification(w, configPath, result)
+	return nil
+}
+
+// response is the JSON result of the scan as printed by json-stdout
+func (r *ScanReport) response() SniffResponse {
+	response := buildSniffResponse(r.Results, r.Languages, r.LowConfidence, r.LanguageFiles, r.Stack, r.graph())
+	response.Evidence = r.Evidence
+	response.Inferred = r.Inferred
+	response.Deprecations = r.Deprecations
+	response.Warnings = r.Warnings
+	response.Attention = r.Attention
+	response.Kubernetes = r.Kubernetes
+	response.Repository = r.Repository
+	response.Helm = r.Helm
+	response.Serverless = r.Serverless
+	response.Fingerprint = r.Fingerprint
+	response.Tasks = r.Tasks
+	response.LanguageStats = r.LanguageStats
+	response.BudgetSkipped = r.BudgetSkipped
+	response.Health = r.Health
+	response.DataVersion = r.DataVersion
+	if r.Stats != nil {
+		response.Stats = &ScanStats{
+			DurationMS:      r.Stats.Duration.Milliseconds(),
+			DependencyFiles: r.Stats.DependencyFiles,
+			PeakMemory:      r.Stats.PeakMemory,
+		}
+	}
+	if len(r.Subprojects) > 0 {
+		response.Subprojects = make(map[string]SniffResponse)
+		for _, subproject := range r.Subprojects {
+			response.Subprojects[subproject.ProjectName] = subproject.response()
+		}
+	}
+	if len(r.Apps) > 0 {
+		response.Apps = make(map[string]SniffResponse)
+		for _, app := range r.Apps {
+			response.Apps[app.AppDir] = app.response()
+		}
+	}
+	return response
+}
+
+// writeConfig writes the report to its parascope.yml at configPath, with --verify
+// and --provenance comments, and the reports of apps to theirs. Messages for people
+// go to w.
+func writeConfig(w io.Writer, configPath string, report *ScanReport) error {
+	writeConfigResults(w, configPath, report.Results, report.ProjectName, report.RepositoryName, report.Annotation.withSources(report.Evidence))
+	if err := report.verify(w, configPath, report.ProjectName, report.Results); err != nil {
+		return err
+	}
+	if err := report.writeProvenance(configPath, report.ProjectName, report.Results); err != nil {
+		return err
+	}
+	// One root key per sub-project, those without detections are left out
+	for _, subproject := range report.Subprojects {
+		if len(subproject.Results) > 0 {
+			writeConfigResults(w, configPath, subproject.Results, subproject.ProjectName, report.RepositoryName, report.Annotation.withSources(subproject.Evidence))
+			if err := report.verify(w, configPath, subproject.ProjectName, subproject.Results); err != nil {
+				return err
+			}
+			if err := report.writeProvenance(configPath, subproject.ProjectName, subproject.Results); err != nil {
+				return err
+			}
+		}
+	}
+	// Apps keep their own parascope.yml whatever path the root config is written to
+	for _, app := range report.Apps {
+		if err := writeConfig(w, app.ConfigPath, app); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeOutput renders the report in the target's format
+func writeOutput(target OutputTarget, report *ScanReport) error {
+	if target.Format == "yml-config" {
+		configPath := report.ConfigPath
+		if target.Path != "" && target.Path != "-" {
+			configPath = target.Path
+		}
+		return writeConfig(os.Stdout, configPath, report)
+	}
+
+	var w io.Writer = os.Stdout
+	if !target.toStdout() {
+		file, err := os.Create(target.Path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+	// The patch is of the config, which is never redacted
+	if target.Format == "patch" {
+		return writeConfigPatch(w, report)
+	}
+
+	report = report.shared()
+	switch target.Format {
+	case "json-stdout", "json":
+		outputJSONFormat(w, report.response())
+	case "dot":
+		outputDotFormat(w, report.graph())
+	case "sarif":
+		return outputSARIFFormat(w, report.Results, report.Deprecations, report.Attention)
+	case "vscode":
+		return outputVSCodeFormat(w, report)
+	default:
+		return fmt.Errorf("unknown format: %s", target.Format)
+	}
+	return nil
+}
+
+// SARIF 2.1.0 structures, limited to what code scanning dashboards need
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	Name             string       `json:"name"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+	HelpURI          string       `json:"helpUri,omitempty"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations,omitempty"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+// attentionMessage is the message of a finding with the detections it is about
+func attentionMessage(finding parascan.AttentionFinding) string {
+	if len(finding.Keys) == 0 {
+		return finding.Message
+	}
+	return finding.Message + " (" + strings.Join(finding.Keys, ", ") + ")"
+}
+
+// severityColors are the ANSI colors of severities in console output
+var severityColors = map[string]string{
+	parascan.SeverityInfo:    "\033[36m", // cyan
+	parascan.SeverityWarning: "\033[33m", // yellow
+	parascan.SeverityError:   "\033[31m", // red
+}
+
+// severityLabel pads a severity to align console output, colored when color is set
+func severityLabel(severity string, color bool) string {
+	label := fmt.Sprintf("%-7s", severity)
+	if code, ok := severityColors[severity]; ok && color {
+		return code + label + "\033[0m"
+	}
+	return label
+}
+
+// useColor reports whether f is a terminal and NO_COLOR (no-color.org) isn't set
+func useColor(f *os.File) bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// sarifLevels maps attention severities to SARIF result levels
+var sarifLevels = map[string]string{
+	parascan.SeverityInfo:    "note",
+	parascan.SeverityWarning: "warning",
+	parascan.SeverityError:   "error",
+}
+
+// outputSARIFFormat writes each detected service as a note-level SARIF result,
+// deprecated ones as warnings, and each attention finding at the level of its
+// severity, located at its file
+func outputSARIFFormat(w io.Writer, allResults map[string]string, deprecations []parascan.Deprecation, attention []parascan.AttentionFinding) error {
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           "parascan",
+			Version:        Version,
+			InformationURI: "https://github.com/Parascope/parascan",
+			Rules:          []sarifRule{},
+		}},
+		Results: []sarifResult{},
+	}
+
+	var keys []string
+	for key := range allResults {
+		if !isRepositoryResult(key) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		displayName := getTechnologyDisplayName(key, allResults[key])
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{
+			ID:               key,
+			Name:             displayName,
+			ShortDescription: sarifMessage{Text: fmt.Sprintf("Project uses %s", displayName)},
+			HelpURI:          allResults[key],
+		})
+		result := sarifResult{
+			RuleID:  key,
+			Level:   "note",
+			Message: sarifMessage{Text: fmt.Sprintf("Detected %s (%s)", displayName, allResults[key])},
+		}
+		for _, deprecation := range deprecations {
+			if deprecation.Key != key {
+				continue
+			}
+			result.Level = "warning"
+			result.Message.Text += " - deprecated"
+			if deprecation.Successor != "" {
+				result.Message.Text += ", consider migrating to " + deprecation.Successor
+			}
+		}
+		run.Results = append(run.Results, result)
+	}
+
+	// Attention rules are prefixed so they don't collide with service keys
+	ruled := make(map[string]bool)
+	for _, finding := range attention {
+		ruleID := "attention/" + finding.Rule
+		if !ruled[ruleID] {
+			ruled[ruleID] = true
+			run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{
+				ID:               ruleID,
+				Name:             finding.Rule,
+				ShortDescription: sarifMessage{Text: finding.Message},
+			})
+		}
+		result := sarifResult{RuleID: ruleID, Level: sarifLevels[finding.Severity], Message: sarifMessage{Text: attentionMessage(finding)}}
+		if finding.File != "" {
+			location := sarifLocation{PhysicalLocation: sarifPhysicalLocation{ArtifactLocation: sarifArtifactLocation{URI: finding.File}}}
+			if finding.Line > 0 {
+				location.PhysicalLocation.Region = &sarifRegion{StartLine: finding.Line}
+			}
+			result.Locations = []sarifLocation{location}
+		}
+		run.Results = append(run.Results, result)
+	}
+
+	log := sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{run},
+	}
+	data, err := json.MarshalIndent(log, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(data))
+	return err
+}
+
+// outputVSCodeFormat writes one "file:line:column: severity: message" problem per
+// detected service, located at its first evidence, a warning per outdated
+// configuration and a problem per attention finding, followed by a .vscode/settings.json
+// snippet with the dashboard links. Problem matchers skip the snippet lines.
+func outputVSCodeFormat(w io.Writer, report *ScanReport) error {
+	var keys []string
+	for key := range report.Results {
+		if !isRepositoryResult(key) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	// Services without file evidence point at the config they end up in
+	configFile := filepath.Base(report.ConfigPath)
+	if rel, err := filepath.Rel(report.ProjectPath, report.ConfigPath); err == nil {
+		configFile = filepath.ToSlash(rel)
+	}
+
+	links := make(map[string]string)
+	for _, key := range keys {
+		url := report.Results[key]
+		displayName := getTechnologyDisplayName(key, url)
+		links[displayName] = url
+
+		file, line := configFile, 1
+		if evidence := report.Evidence[key]; len(evidence) > 0 && evidence[0].File != "" {
+			file = evidence[0].File
+			line = evidence[0].Line
+			if line == 0 {
+				line = findLine(filepath.Join(report.ProjectPath, file), evidence[0].Package+evidence[0].Keyword+evidence[0].Variable)
+			}
+		}
+
+		severity, message := "info", fmt.Sprintf("%s detected → %s", displayName, url)
+		for _, deprecation := range report.Deprecations {
+			if deprecation.Key != key {
+				continue
+			}
+			severity = "warning"
+			message += " - deprecated"
+			if deprecation.Successor != "" {
+				message += ", consider migrating to " + deprecation.Successor
+			}
+		}
+		if _, err := fmt.Fprintf(w, "%s:%d:1: %s: %s\n", file, line, severity, message); err != nil {
+			return err
+		}
+	}
+
+	// Outdated configuration is reported where it was found
+	for _, warning := range report.Warnings {
+		line := warning.Line
+		if line == 0 {
+			line = 1
+		}
+		if _, err := fmt.Fprintf(w, "%s:%d:1: warning: %s\n", warning.File, line, warning.Message); err != nil {
+			return err
+		}
+	}
+
+	// Attention findings at their severity, those without a file at the config
+	for _, finding := range report.Attention {
+		file, line := finding.File, finding.Line
+		if file == "" {
+			file = configFile
+		}
+		if line == 0 {
+			line = 1
+		}
+		if _, err := fmt.Fprintf(w, "%s:%d:1: %s: %s\n", file, line, finding.Severity, attentionMessage(finding)); err != nil {
+			return err
+		}
+	}
+
+	settings, err := json.MarshalIndent(map[string]interface{}{"parascan.services": links}, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "\n// .vscode/settings.json\n%s\n", settings)
+	return err
+}
+
+// findLine returns the 1-based line of the first occurrence of text in a file, 1 if not found
+func findLine(path, text string) int {
+	content, err := os.ReadFile(path)
+	if err != nil || text == "" {
+		return 1
+	}
+	if i := strings.Index(string(content), text); i >= 0 {
+		return strings.Count(string(content[:i]), "\n") + 1
+	}
+	return 1
+}
@@ -0,0 +1,321 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"parascan/pkg/parascan"
+)
+
+func TestParseOutputTarget(t *testing.T) {
+	target, err := parseOutputTarget("sarif=reports/scan.sarif")
+	if err != nil || target.Format != "sarif" || target.Path != "reports/scan.sarif" {
+		t.Errorf("Expected sarif target to reports/scan.sarif, got %+v (%v)", target, err)
+	}
+	if target, _ := parseOutputTarget("json"); !target.toStdout() {
+		t.Errorf("Expected target without path to write to stdout")
+	}
+	if target, _ := parseOutputTarget("yml-config"); target.toStdout() {
+		t.Errorf("Expected yml-config to write the config file, not stdout")
+	}
+	if _, err := parseOutputTarget("xml=out.xml"); err == nil {
+		t.Errorf("Expected unknown format to be rejected")
+	}
+}
+
+func TestWriteMultipleOutputs(t *testing.T) {
+	skipWithoutPack(t, "requirements.txt")
+	projectPath := filepath.Join("testdata", "settings-project")
+	catalog, err := parascan.EmbeddedCatalog()
+	if err != nil {
+		t.Fatalf("Failed to load catalog: %v", err)
+	}
+	scan := runScan(projectPath, catalog, ScanOptions{SkipGit: true})
+
+	dir := t.TempDir()
+	report := &ScanReport{
+		ProjectPath: projectPath,
+		ConfigPath:  filepath.Join(dir, "parascope.yml"),
+		ProjectName: "billing",
+		Languages:   scan.Languages,
+		Results:     scan.Results,
+		Stack:       catalog.Stack,
+	}
+	targets := []OutputTarget{
+		{Format: "yml-config", Path: "-"},
+		{Format: "json", Path: filepath.Join(dir, "report.json")},
+		{Format: "sarif", Path: filepath.Join(dir, "scan.sarif")},
+	}
+	for _, target := range targets {
+		if err := writeOutput(target, report); err != nil {
+			t.Fatalf("Failed to write %s output: %v", target.Format, err)
+		}
+	}
+
+	config, err := os.ReadFile(report.ConfigPath)
+	if err != nil || !strings.Contains(string(config), "billing:") {
+		t.Errorf("Expected parascope.yml with billing project, got %q (%v)", config, err)
+	}
+
+	var response SniffResponse
+	content, _ := os.ReadFile(filepath.Join(dir, "report.json"))
+	if err := json.Unmarshal(content, &response); err != nil || response.Services["stripe"] == "" {
+		t.Errorf("Expected JSON report with stripe, got %s (%v)", content, err)
+	}
+
+	var sarif sarifLog
+	content, _ = os.ReadFile(filepath.Join(dir, "scan.sarif"))
+	if err := json.Unmarshal(content, &sarif); err != nil || sarif.Version != "2.1.0" || len(sarif.Runs) != 1 {
+		t.Fatalf("Expected SARIF 2.1.0 log with one run, got %s (%v)", content, err)
+	}
+	if len(sarif.Runs[0].Results) != len(scan.Results) || len(sarif.Runs[0].Tool.Driver.Rules) != len(scan.Results) {
+		t.Errorf("Expected one SARIF result and rule per detected service %v, got %+v", scan.Results, sarif.Runs[0])
+	}
+}
+
+func TestVSCodeOutput(t *testing.T) {
+	projectPath := filepath.Join("testdata", "badges-project")
+	catalog, err := parascan.EmbeddedCatalog()
+	if err != nil {
+		t.Fatalf("Failed to load catalog: %v", err)
+	}
+	scan := runScan(projectPath, catalog, ScanOptions{SkipGit: true})
+
+	dir := t.TempDir()
+	report := &ScanReport{
+		ProjectPath:  projectPath,
+		ConfigPath:   filepath.Join(projectPath, "parascope.yml"),
+		Results:      scan.Results,
+		Evidence:     scan.Evidence,
+		Deprecations: scan.Deprecations,
+		Attention:    scan.Attention,
+		Stack:        catalog.Stack,
+	}
+	if err := writeOutput(OutputTarget{Format: "vscode", Path: filepath.Join(dir, "problems.txt")}, report); err != nil {
+		t.Fatalf("Failed to write vscode output: %v", err)
+	}
+	content, _ := os.ReadFile(filepath.Join(dir, "problems.txt"))
+
+	// Problems point at the README line of each badge, deprecated services are
+	// warnings and attention findings without a file point at the config
+	for _, problem := range []string{
+		"README.md:3:1: info: GitHub Actions detected → https://github.com/acme/widgets/actions",
+		"README.md:4:1: warning: Travis CI detected → https://app.travis-ci.com/github/acme/widgets - deprecated, consider migrating to GitHub Actions",
+		"parascope.yml:1:1: warning: Several CI systems are configured, remove the ones that no longer run (GitHub Actions, Travis CI)",
+	} {
+		if !strings.Contains(string(content), problem+"\n") {
+			t.Errorf("Expected problem %q in %s", problem, content)
+		}
+	}
+	if !strings.Contains(string(content), `"Codecov": "https://app.codecov.io/gh/acme/widgets"`) {
+		t.Errorf("Expected settings snippet with Codecov link, got %s", content)
+	}
+}
+
+func TestRepositoryKeyMerge(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "parascope.yml")
+	legacy := "billing:\n  repo: https://github.com/acme/old\n  Stripe: https://dashboard.stripe.com\n  Repository: https://github.com/acme/old\n"
+	if err := os.WriteFile(configPath, []byte(legacy), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Both legacy spellings become one entry holding the detected URL
+	results := map[string]string{"repo": "https://github.com/acme/billing"}
+	createConfigFromDetectorResults(configPath, results, "billing", "", nil)
+	content, _ := os.ReadFile(configPath)
+	expected := "billing:\n  Repository: https://github.com/acme/billing\n  Stripe: https://dashboard.stripe.com\n"
+	if string(content) != expected {
+		t.Errorf("Expected merged repository key:\n%s\ngot:\n%s", expected, content)
+	}
+
+	// Renaming the key through names migrates the existing entry
+	createConfigFromDetectorResults(configPath, results, "billing", "Source", nil)
+	content, _ = os.ReadFile(configPath)
+	expected = "billing:\n  Source: https://github.com/acme/billing\n  Stripe: https://dashboard.stripe.com\n"
+	if string(content) != expected {
+		t.Errorf("Expected repository key renamed to Source:\n%s\ngot:\n%s", expected, content)
+	}
+}
+
+func TestURLDedup(t *testing.T) {
+	for raw, expected := range map[string]string{
+		"https://Sentry.IO/":                     "https://sentry.io",
+		"https://app.datadoghq.com:443/apm/":     "https://app.datadoghq.com/apm",
+		"https://app.storyblok.com/#/me/spaces/": "https://app.storyblok.com/#/me/spaces/",
+		"https://app.netlify.com/sites/x/?tab=1": "https://app.netlify.com/sites/x/?tab=1",
+		"Sentry":                                 "Sentry",
+	} {
+		if got := normalizeURL(raw); got != expected {
+			t.Errorf("Expected %s normalized to %s, got %s", raw, expected, got)
+		}
+	}
+
+	// Entries differing by a slash or www. are written once, a rescan adds nothing
+	configPath := filepath.Join(t.TempDir(), "parascope.yml")
+	results := map[string]string{"sentry": "https://sentry.io/", "Sentry": "https://www.sentry.io", "stripe": "https://dashboard.stripe.com/"}
+	createConfigFromDetectorResults(configPath, results, "billing", "", nil)
+	content, _ := os.ReadFile(configPath)
+	expected := "billing:\n  Sentry: https://www.sentry.io\n  Stripe: https://dashboard.stripe.com\n"
+	if string(content) != expected {
+		t.Errorf("Expected deduplicated config:\n%s\ngot:\n%s", expected, content)
+	}
+	createConfigFromDetectorResults(configPath, map[string]string{"sentry": "https://SENTRY.io"}, "billing", "", nil)
+	if rescanned, _ := os.ReadFile(configPath); string(rescanned) != expected {
+		t.Errorf("Expected the rescan to keep the config, got:\n%s", rescanned)
+	}
+
+	// Near-duplicates written by older versions are removed, the first entry stays
+	legacy := "billing:\n  Stripe: https://dashboard.stripe.com/\n  stripe: https://dashboard.stripe.com\n  # payments\n  Sentry: https://sentry.io\n"
+	if err := os.WriteFile(configPath, []byte(legacy), 0644); err != nil {
+		t.Fatal(err)
+	}
+	createConfigFromDetectorResults(configPath, map[string]string{"stripe": "https://dashboard.stripe.com"}, "billing", "", nil)
+	content, _ = os.ReadFile(configPath)
+	expected = "billing:\n  Stripe: https://dashboard.stripe.com/\n  # payments\n  Sentry: https://sentry.io\n"
+	if string(content) != expected {
+		t.Errorf("Expected the duplicate stripe entry removed:\n%s\ngot:\n%s", expected, content)
+	}
+}
+
+func TestRedactedOutput(t *testing.T) {
+	skipWithoutPack(t, "package.json", "go.mod")
+	projectPath := filepath.Join("testdata", "monorepo-project")
+	catalog, err := parascan.EmbeddedCatalog()
+	if err != nil {
+		t.Fatalf("Failed to load catalog: %v", err)
+	}
+	scan := runScan(projectPath, catalog, ScanOptions{SkipGit: true})
+	scan.Results["sentry"] = "https://acme.sentry.io/issues/"
+
+	dir := t.TempDir()
+	report := &ScanReport{
+		ProjectPath: projectPath,
+		ConfigPath:  filepath.Join(dir, "parascope.yml"),
+		ProjectName: "acme-billing",
+		Languages:   scan.Languages,
+		Results:     scan.Results,
+		Evidence:    scan.Evidence,
+		Stack:       catalog.Stack,
+		Redactor:    newRedactor(catalog),
+	}
+	for _, target := range []OutputTarget{{Format: "yml-config"}, {Format: "json", Path: filepath.Join(dir, "report.json")}} {
+		if err := writeOutput(target, report); err != nil {
+			t.Fatalf("Failed to write %s output: %v", target.Format, err)
+		}
+	}
+
+	// parascope.yml is the project's own, it isn't redacted
+	config, _ := os.ReadFile(report.ConfigPath)
+	if !strings.Contains(string(config), "acme-billing:") || !strings.Contains(string(config), "https://acme.sentry.io/issues") {
+		t.Errorf("Expected an unredacted parascope.yml, got:\n%s", config)
+	}
+
+	content, _ := os.ReadFile(filepath.Join(dir, "report.json"))
+	for _, leak := range []string{"acme", "services/api", "packages/web", `"web"`} {
+		if strings.Contains(string(content), leak) {
+			t.Errorf("Expected %s redacted from the JSON output, got %s", leak, content)
+		}
+	}
+	var response SniffResponse
+	if err := json.Unmarshal(content, &response); err != nil {
+		t.Fatalf("Invalid JSON output: %v", err)
+	}
+	// Catalog URLs and file names are kept, the same path always gets the same hash
+	if response.Services["stripe"] != scan.Results["stripe"] {
+		t.Errorf("Expected the catalog's Stripe URL kept, got %s", response.Services["stripe"])
+	}
+	if sentry := response.Services["sentry"]; !strings.HasPrefix(sentry, "https://sentry.io/redacted-") {
+		t.Errorf("Expected the Sentry deep link cut down to its domain, got %s", sentry)
+	}
+	expectedFile := redactedName("services") + "/" + redactedName("api") + "/go.mod"
+	var files []string
+	for _, evidence := range response.Evidence["stripe"] {
+		files = append(files, evidence.File)
+	}
+	sort.Strings(files)
+	expected := []string{expectedFile, redactedName("packages") + "/" + redactedName("web") + "/package.json"}
+	sort.Strings(expected)
+	if !equalStringSlices(files, expected) {
+		t.Errorf("Expected redacted directories with the file names kept, got %v", files)
+	}
+	if response.Graph == nil || len(response.Graph.Components) == 0 {
+		t.Errorf("Expected the redacted component graph in the output, got %s", content)
+	}
+	if report.Results["sentry"] != "https://acme.sentry.io/issues/" || strings.HasPrefix(report.Evidence["stripe"][0].File, "redacted-") {
+		t.Errorf("Expected the report itself left unredacted")
+	}
+	if got := (&redactor{}).path("billing.gemspec"); got != redactedName("billing")+".gemspec" {
+		t.Errorf("Expected file names chosen by the project redacted with their extension, got %s", got)
+	}
+}
+
+func TestSARIFAttention(t *testing.T) {
+	attention := []parascan.AttentionFinding{
+		{Rule: "dotenv-secrets", Severity: parascan.SeverityError, Message: "secrets", File: "api/.env", Line: 2},
+		{Rule: "multiple-ci", Severity: parascan.SeverityWarning, Message: "two CI systems", Keys: []string{"GitHub Actions", "Travis CI"}},
+		{Rule: "dotenv-secrets", Severity: parascan.SeverityError, Message: "secrets", File: "web/.env"},
+	}
+	var buf strings.Builder
+	if err := outputSARIFFormat(&buf, map[string]string{"stripe": "https://dashboard.stripe.com"}, nil, attention); err != nil {
+		t.Fatalf("Failed to write SARIF: %v", err)
+	}
+	var sarif sarifLog
+	if err := json.Unmarshal([]byte(buf.String()), &sarif); err != nil {
+		t.Fatalf("Invalid SARIF %s: %v", buf.String(), err)
+	}
+
+	// One rule per service and attention rule, results at the severity's level
+	run := sarif.Runs[0]
+	if len(run.Tool.Driver.Rules) != 3 || run.Tool.Driver.Rules[1].ID != "attention/dotenv-secrets" {
+		t.Errorf("Expected stripe and two attention rules, got %+v", run.Tool.Driver.Rules)
+	}
+	if len(run.Results) != 4 {
+		t.Fatalf("Expected four results, got %+v", run.Results)
+	}
+	secrets, ci, unlined := run.Results[1], run.Results[2], run.Results[3]
+	if secrets.Level != "error" || len(secrets.Locations) != 1 || secrets.Locations[0].PhysicalLocation.ArtifactLocation.URI != "api/.env" || secrets.Locations[0].PhysicalLocation.Region.StartLine != 2 {
+		t.Errorf("Expected an error at api/.env:2, got %+v", secrets)
+	}
+	if ci.Level != "warning" || len(ci.Locations) != 0 || ci.Message.Text != "two CI systems (GitHub Actions, Travis CI)" {
+		t.Errorf("Expected a warning about both CI systems without location, got %+v", ci)
+	}
+	if unlined.Locations[0].PhysicalLocation.Region != nil {
+		t.Errorf("Expected no region for a finding without line, got %+v", unlined.Locations[0])
+	}
+	if severityLabel("error", false) != "error  " || severityLabel("error", true) != "\033[31merror  \033[0m" {
+		t.Errorf("Expected padded severity labels, colored on request")
+	}
+}
+
+func TestPackageManagerFromLockfiles(t *testing.T) {
+	catalog, err := parascan.EmbeddedCatalog()
+	if err != nil {
+		t.Fatalf("Failed to load catalog: %v", err)
+	}
+	packageManager := func(files ...string) string {
+		projectPath := t.TempDir()
+		for _, file := range files {
+			os.WriteFile(filepath.Join(projectPath, file), []byte("\n"), 0644)
+		}
+		scan := runScan(projectPath, catalog, ScanOptions{SkipGit: true})
+		report := &ScanReport{ProjectPath: projectPath, Languages: scan.Languages, LanguageFiles: scan.LanguageFiles, Results: scan.Results, Stack: catalog.Stack}
+		return report.response().PackageManager
+	}
+
+	// The lockfile tells, the priority list breaks ties
+	for expected, files := range map[string][]string{
+		"poetry": {"requirements.txt", "pyproject.toml", "poetry.lock"},
+		"pip":    {"requirements.txt", "pyproject.toml"},
+		"yarn":   {"package.json", "yarn.lock"},
+		"pnpm":   {"package.json", "pnpm-lock.yaml"},
+		"npm":    {"package.json"},
+	} {
+		if pm := packageManager(files...); pm != expected {
+			t.Errorf("Expected %s for %v, got %s", expected, files, pm)
+		}
+	}
+}
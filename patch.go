@@ -0,0 +1,205 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// patchContext is how many unchanged lines surround each change of a patch, as in
+// git diff
+const patchContext = 3
+
+// writeConfigPatch writes the changes the yml-config output would make to the report's
+// parascope.yml, and to those of its apps, as a unified diff for git apply or a pull
+// request. The configs are left as they are: the output is written to copies.
+func writeConfigPatch(w io.Writer, report *ScanReport) error {
+	dir, err := os.MkdirTemp("", "parascope-patch-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	var configs, copies []string
+	stage := func(r *ScanReport) (*ScanReport, error) {
+		staged := *r
+		// The section is named after the config's directory, not the copy's
+		staged.ProjectName = configProjectName(r.ConfigPath, r.ProjectName)
+		staged.ConfigPath = filepath.Join(dir, strconv.Itoa(len(configs)), filepath.Base(r.ConfigPath))
+		if err := os.MkdirAll(filepath.Dir(staged.ConfigPath), 0755); err != nil {
+			return nil, err
+		}
+		content, err := os.ReadFile(r.ConfigPath)
+		if err == nil {
+			err = os.WriteFile(staged.ConfigPath, content, 0644)
+		}
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		configs = append(configs, r.ConfigPath)
+		copies = append(copies, staged.ConfigPath)
+		return &staged, nil
+	}
+
+	root, err := stage(report)
+	if err != nil {
+		return err
+	}
+	root.Apps = nil
+	for _, app := range report.Apps {
+		stagedApp, err := stage(app)
+		if err != nil {
+			return err
+		}
+		root.Apps = append(root.Apps, stagedApp)
+	}
+	if err := writeConfig(io.Discard, root.ConfigPath, root); err != nil {
+		return err
+	}
+
+	for i, configPath := range configs {
+		before, err := os.ReadFile(configPath)
+		created := os.IsNotExist(err)
+		if err != nil && !created {
+			return err
+		}
+		after, err := os.ReadFile(copies[i])
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		writeUnifiedDiff(w, patchPath(configPath), string(before), string(after), created)
+	}
+	return nil
+}
+
+// patchPath is a config's path in the headers of the patch, relative to the working
+// directory when it's below it and to the root otherwise
+func patchPath(path string) string {
+	if filepath.IsAbs(path) {
+		if cwd, err := os.Getwd(); err == nil {
+			if rel, err := filepath.Rel(cwd, path); err == nil && !strings.HasPrefix(rel, "..") {
+				path = rel
+			}
+		}
+	}
+	return strings.TrimPrefix(filepath.ToSlash(filepath.Clean(path)), "/")
+}
+
+// diffOp is a line of a diff: kept (' '), removed ('-') or added ('+')
+type diffOp struct {
+	kind byte
+	line string
+}
+
+// diffLines returns the shortest edit from a to b, by their longest common subsequence.
+// Configs are small enough for the quadratic table.
+func diffLines(a, b []string) []diffOp {
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var ops []diffOp
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			ops = append(ops, diffOp{' ', a[i]})
+			i++
+			j++
+		case j == len(b) || (i < len(a) && lcs[i+1][j] >= lcs[i][j+1]):
+			ops = append(ops, diffOp{'-', a[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', b[j]})
+			j++
+		}
+	}
+	return ops
+}
+
+// splitLines splits content into lines, each with its newline but a last one without
+func splitLines(content string) []string {
+	lines := strings.SplitAfter(content, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// writeUnifiedDiff writes the changes from before to after as a unified diff of path,
+// nothing when there are none. A created file is diffed against /dev/null.
+func writeUnifiedDiff(w io.Writer, path, before, after string, created bool) {
+	ops := diffLines(splitLines(before), splitLines(after))
+
+	// Changes with their context, those whose context overlaps in one hunk
+	var hunks [][2]int
+	for k, op := range ops {
+		if op.kind == ' ' {
+			continue
+		}
+		start, end := max(k-patchContext, 0), min(k+patchContext+1, len(ops))
+		if n := len(hunks); n > 0 && start <= hunks[n-1][1] {
+			hunks[n-1][1] = end
+		} else {
+			hunks = append(hunks, [2]int{start, end})
+		}
+	}
+	if len(hunks) == 0 {
+		return
+	}
+
+	if created {
+		fmt.Fprintf(w, "--- /dev/null\n")
+	} else {
+		fmt.Fprintf(w, "--- a/%s\n", path)
+	}
+	fmt.Fprintf(w, "+++ b/%s\n", path)
+
+	// Lines of before and after ahead of each op
+	oldLine, newLine := make([]int, len(ops)+1), make([]int, len(ops)+1)
+	for k, op := range ops {
+		oldLine[k+1], newLine[k+1] = oldLine[k], newLine[k]
+		if op.kind != '+' {
+			oldLine[k+1]++
+		}
+		if op.kind != '-' {
+			newLine[k+1]++
+		}
+	}
+	for _, hunk := range hunks {
+		start, end := hunk[0], hunk[1]
+		fmt.Fprintf(w, "@@ -%s +%s @@\n", hunkRange(oldLine[start], oldLine[end]-oldLine[start]), hunkRange(newLine[start], newLine[end]-newLine[start]))
+		for _, op := range ops[start:end] {
+			fmt.Fprintf(w, "%c%s", op.kind, op.line)
+			if !strings.HasSuffix(op.line, "\n") {
+				fmt.Fprintf(w, "\n\\ No newline at end of file\n")
+			}
+		}
+	}
+}
+
+// hunkRange renders the lines of a hunk in one file: the first and the count, the
+// line before for none
+func hunkRange(before, count int) string {
+	switch count {
+	case 0:
+		return fmt.Sprintf("%d,0", before)
+	case 1:
+		return strconv.Itoa(before + 1)
+	}
+	return fmt.Sprintf("%d,%d", before+1, count)
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConfigPatch(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "billing")
+	if err := os.MkdirAll(filepath.Join(dir, "apps", "web"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	configPath := filepath.Join(dir, "parascope.yml")
+	config := "billing:\n  Sentry: https://sentry.io/acme\n  a: 1\n  b: 2\n  c: 3\n  d: 4\n  e: 5\n  f: 6\n  g: 7\n\nother:\n  Notion: https://notion.so"
+	if err := os.WriteFile(configPath, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+	appConfig := filepath.Join(dir, "apps", "web", "parascope.yml")
+	report := &ScanReport{
+		ConfigPath: configPath,
+		Results:    map[string]string{"sentry": "https://sentry.io/billing", "stripe": "https://dashboard.stripe.com"},
+		Apps:       []*ScanReport{{ConfigPath: appConfig, Results: map[string]string{"vercel": "https://vercel.com/dashboard"}}},
+	}
+
+	var patch bytes.Buffer
+	if err := writeConfigPatch(&patch, report); err != nil {
+		t.Fatalf("Failed to write the patch: %v", err)
+	}
+
+	// The section is named after the config's directory, changes far apart get
+	// their own hunk, the missing newline at the end is added
+	root := patchPath(configPath)
+	expected := "--- a/" + root + "\n+++ b/" + root + "\n" +
+		"@@ -1,5 +1,5 @@\n billing:\n-  Sentry: https://sentry.io/acme\n+  Sentry: https://sentry.io/billing\n   a: 1\n   b: 2\n   c: 3\n" +
+		"@@ -7,6 +7,7 @@\n   e: 5\n   f: 6\n   g: 7\n+  Stripe: https://dashboard.stripe.com\n \n other:\n-  Notion: https://notion.so\n\\ No newline at end of file\n+  Notion: https://notion.so\n"
+	if !strings.HasPrefix(patch.String(), expected) {
+		t.Fatalf("Expected the config's changes:\n%s\ngot:\n%s", expected, patch.String())
+	}
+	app := patchPath(appConfig)
+	if !strings.HasSuffix(patch.String(), "--- /dev/null\n+++ b/"+app+"\n@@ -0,0 +1,2 @@\n+web:\n+  Vercel: https://vercel.com/dashboard\n") {
+		t.Errorf("Expected the app's config created, got:\n%s", patch.String())
+	}
+
+	// The configs are left as they were
+	if content, _ := os.ReadFile(configPath); string(content) != config {
+		t.Errorf("Expected the config unchanged, got:\n%s", content)
+	}
+	if _, err := os.Stat(appConfig); !os.IsNotExist(err) {
+		t.Errorf("Expected no app config written, got %v", err)
+	}
+
+	// Once applied, there's nothing left to change
+	writeConfig(&bytes.Buffer{}, configPath, report)
+	patch.Reset()
+	if err := writeConfigPatch(&patch, report); err != nil || patch.Len() != 0 {
+		t.Errorf("Expected an empty patch for an up to date config, got %v:\n%s", err, patch.String())
+	}
+}
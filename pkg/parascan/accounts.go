@@ -0,0 +1,39 @@
+package parascan
+
+import (
+	"regexp"
+	"strings"
+)
+
+// accountPlaceholder is a {prompt:name} placeholder of a service's url_template,
+// answered once per project and kept in the accounts of .parascan.yml
+var accountPlaceholder = regexp.MustCompile(`\{prompt:(\w+)\}`)
+
+// AccountPrompts lists the placeholders of the service's url_template in order,
+// each once
+func (s *ServiceData) AccountPrompts() []string {
+	var prompts []string
+	for _, match := range accountPlaceholder.FindAllStringSubmatch(s.URLTemplate, -1) {
+		if !containsString(prompts, match[1]) {
+			prompts = append(prompts, match[1])
+		}
+	}
+	return prompts
+}
+
+// AccountURL expands the service's url_template with the project's answers,
+// failing without a template or with a placeholder left unanswered
+func (s *ServiceData) AccountURL(answers map[string]string) (string, bool) {
+	if s.URLTemplate == "" {
+		return "", false
+	}
+	ok := true
+	url := accountPlaceholder.ReplaceAllStringFunc(s.URLTemplate, func(placeholder string) string {
+		value := strings.TrimSpace(answers[accountPlaceholder.FindStringSubmatch(placeholder)[1]])
+		if value == "" {
+			ok = false
+		}
+		return value
+	})
+	return url, ok
+}
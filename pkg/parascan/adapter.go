@@ -0,0 +1,138 @@
+package parascan
+
+import (
+	"path/filepath"
+
+	"parascan/detectors"
+)
+
+// ServicesAdapter adapts dependency file analysis to the services detector
+type ServicesAdapter struct {
+	stackData    *StackDependencyFiles
+	servicesData map[string]*ServiceData
+	filter       *detectors.PathFilter
+	maxDepth     int                          // directory levels searched for dependency files, 0 is the default
+	evidence     map[string][]ServiceEvidence // collected while detecting, by service key
+	skipFile     func(file string) bool       // dependency files left out, e.g. lockfiles over budget
+	linkType     string                       // service URL reported, the dashboard by default
+	accounts     map[string]map[string]string // url_template answers by service key
+	skipIndirect bool                         // leave out packages that are dependencies of dependencies
+}
+
+// NewServicesAdapter analyzes the catalog's dependency files for the services detector
+func NewServicesAdapter(stackData *StackDependencyFiles, servicesData map[string]*ServiceData) *ServicesAdapter {
+	return &ServicesAdapter{stackData: stackData, servicesData: servicesData}
+}
+
+// ServicesDetector is the name of the detector of catalog packages and framework config
+const ServicesDetector = "services"
+
+// ServiceEvidence is one reason a result was detected: a package in a dependency
+// file, a framework config entry, a file matching a catalog pattern or a mention in
+// docs. Results of detectors that don't tell why only name the detector.
+type ServiceEvidence struct {
+	Detector  string `json:"detector"` // e.g. services, files or a plugin
+	Language  string `json:"language,omitempty"`
+	Package   string `json:"package,omitempty"`
+	Framework string `json:"framework,omitempty"`
+	Keyword   string `json:"keyword,omitempty"`  // service name or badge URL found in docs
+	Resource  string `json:"resource,omitempty"` // resource type in IaC state, e.g. aws_s3_bucket
+	Chart     string `json:"chart,omitempty"`    // Helm chart dependency, e.g. redis
+	Variable  string `json:"variable,omitempty"` // environment variable name, e.g. STRIPE_SECRET_KEY
+	Import    string `json:"import,omitempty"`   // source import of the package in deep mode, e.g. stripe/lib/resources
+	Pattern   string `json:"pattern,omitempty"`  // file detector pattern the file matched, e.g. .github/workflows/*.yml, or content rule
+	File      string `json:"file,omitempty"`     // relative to the project
+	Line      int    `json:"line,omitempty"`     // 1-based line a content rule matched
+	Indirect  bool   `json:"indirect,omitempty"` // the package is a dependency of dependencies
+}
+
+func (a *ServicesAdapter) addEvidence(projectPath, key string, evidence ServiceEvidence) {
+	if rel, err := filepath.Rel(projectPath, evidence.File); err == nil {
+		evidence.File = filepath.ToSlash(rel)
+	}
+	if a.evidence == nil {
+		a.evidence = make(map[string][]ServiceEvidence)
+	}
+	for _, existing := range a.evidence[key] {
+		if existing == evidence {
+			return
+		}
+	}
+	a.evidence[key] = append(a.evidence[key], evidence)
+}
+
+func (a *ServicesAdapter) DetectProjectLanguages(projectPath string) []string {
+	return DetectProjectLanguages(projectPath, a.stackData, a.filter, a.maxDepth)
+}
+
+func (a *ServicesAdapter) AnalyzeProjectDependencies(projectPath string, languages []string) []detectors.ProjectResult {
+	results := analyzeProjectDependencies(projectPath, languages, a.stackData, a.servicesData, a.filter, a.maxDepth, a.skipFile)
+
+	// Convert to detectors format
+	var detectorResults []detectors.ProjectResult
+	for _, result := range results {
+		var services []detectors.ServiceResult
+		for _, service := range result.Services {
+			var packages []PackageInfo
+			for _, pkg := range service.Packages {
+				if !pkg.Indirect || !a.skipIndirect {
+					packages = append(packages, pkg)
+				}
+			}
+			if len(packages) == 0 {
+				continue
+			}
+			services = append(services, detectors.ServiceResult{
+				Name: service.Name,
+			})
+			for _, pkg := range packages {
+				a.addEvidence(projectPath, service.Name, ServiceEvidence{Detector: ServicesDetector, Language: result.Language, Package: pkg.Name, File: pkg.File, Indirect: pkg.Indirect})
+			}
+		}
+		detectorResults = append(detectorResults, detectors.ProjectResult{
+			Language: result.Language,
+			Services: services,
+		})
+	}
+
+	return detectorResults
+}
+
+func (a *ServicesAdapter) GetServicesData() map[string]*detectors.ServiceInfo {
+	result := make(map[string]*detectors.ServiceInfo)
+	for key, service := range a.servicesData {
+		result[key] = &detectors.ServiceInfo{
+			Name:    service.Name,
+			URL:     a.serviceURL(key, service),
+			Aliases: service.Aliases,
+		}
+	}
+	return result
+}
+
+// serviceURL is the service's link of the scan's type. The dashboard is a deep link
+// into the project's account when the project answered the service's prompts.
+func (a *ServicesAdapter) serviceURL(key string, service *ServiceData) string {
+	if a.linkType == "" || a.linkType == "dashboard" {
+		if url, ok := service.AccountURL(a.accounts[key]); ok {
+			return url
+		}
+	}
+	return service.Link(a.linkType)
+}
+
+// FrameworkHints looks for framework config in every project directory, e.g. a
+// Rails app in backend/, and records it as evidence with its path from the root
+func (a *ServicesAdapter) FrameworkHints(projectPath string) []detectors.FrameworkHint {
+	var hints []detectors.FrameworkHint
+	for _, dir := range ProjectDirs(projectPath, a.filter, a.maxDepth) {
+		hints = append(hints, detectors.DetectFrameworkHints(filepath.Join(projectPath, dir), a.filter.Within(dir), detectors.DefaultFrameworkAdapters())...)
+	}
+	services := a.GetServicesData()
+	for _, hint := range hints {
+		if key, ok := detectors.MatchFrameworkHint(hint.Hint, services); ok {
+			a.addEvidence(projectPath, key, ServiceEvidence{Detector: ServicesDetector, Framework: hint.Framework, Package: hint.Hint, File: hint.File})
+		}
+	}
+	return hints
+}
@@ -0,0 +1,216 @@
+package parascan
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+
+	"parascan/detectors"
+)
+
+// Severities of attention rules, least pressing first. SeverityOff turns a
+// catalog rule off from rules.
+const (
+	SeverityInfo    = "info"
+	SeverityWarning = "warning"
+	SeverityError   = "error"
+	SeverityOff     = "off"
+)
+
+// severityRanks orders severities, unknown ones rank below info
+var severityRanks = map[string]int{SeverityInfo: 1, SeverityWarning: 2, SeverityError: 3}
+
+// SeverityRank orders severities: error is the highest, off and unknown ones 0
+func SeverityRank(severity string) int {
+	return severityRanks[severity]
+}
+
+// Attention is the content of attention.yml, rules by id
+type Attention struct {
+	Rules map[string]AttentionRule `yaml:"rules"`
+}
+
+// AttentionRule flags a state of the project someone should act on, e.g. secrets
+// in a .env file or two CI systems configured. A rule with several conditions
+// needs all of them.
+type AttentionRule struct {
+	Severity string   `yaml:"severity"` // info, warning or error
+	Message  string   `yaml:"message"`
+	Files    []string `yaml:"files,omitempty"`     // globs relative to the project, one of them exists
+	Pattern  string   `yaml:"pattern,omitempty"`   // regexp matched line by line in the files
+	Detected []string `yaml:"detected,omitempty"`  // service or file detector keys, one of them is detected
+	Category string   `yaml:"category,omitempty"`  // service or file detector category, e.g. ci
+	MinCount int      `yaml:"min_count,omitempty"` // detections of the category needed, 1 by default
+}
+
+// AttentionFinding is an attention rule matched by a project
+type AttentionFinding struct {
+	Rule     string   `json:"rule"`
+	Severity string   `json:"severity"`
+	Message  string   `json:"message"`
+	Keys     []string `json:"keys,omitempty"` // results the rule matched, keyed by the project's names
+	File     string   `json:"file,omitempty"` // relative to the project, for rules with files
+	Line     int      `json:"line,omitempty"`
+}
+
+// Location is where the finding applies, file:line, the file as a whole or ""
+func (f AttentionFinding) Location() string {
+	if f.Line == 0 {
+		return f.File
+	}
+	return fmt.Sprintf("%s:%d", f.File, f.Line)
+}
+
+// ParseAttention parses attention.yml, rejecting rules without a known severity,
+// a message or a condition
+func ParseAttention(content []byte) (*Attention, error) {
+	var attention Attention
+	if err := yaml.Unmarshal(content, &attention); err != nil {
+		return nil, err
+	}
+	for id, rule := range attention.Rules {
+		switch {
+		case rule.Severity == SeverityOff:
+			continue
+		case SeverityRank(rule.Severity) == 0:
+			return nil, fmt.Errorf("rule %s: unknown severity %q, expected info, warning, error or off", id, rule.Severity)
+		case rule.Message == "":
+			return nil, fmt.Errorf("rule %s has no message", id)
+		case len(rule.Files) == 0 && len(rule.Detected) == 0 && rule.Category == "":
+			return nil, fmt.Errorf("rule %s has no condition", id)
+		case rule.Pattern != "" && len(rule.Files) == 0:
+			return nil, fmt.Errorf("rule %s has a pattern but no files", id)
+		}
+		if _, err := regexp.Compile(rule.Pattern); err != nil {
+			return nil, fmt.Errorf("rule %s: %v", id, err)
+		}
+	}
+	return &attention, nil
+}
+
+// FindAttention matches the catalog's attention rules against a project and its
+// results. Rules with files find each matching file once, at its first matching
+// line. Findings are sorted by severity, most pressing first, then by rule.
+func FindAttention(catalog *Catalog, projectPath string, results map[string]string, filter *detectors.PathFilter) []AttentionFinding {
+	if catalog.Attention == nil {
+		return nil
+	}
+	var findings []AttentionFinding
+	for id, rule := range catalog.Attention.Rules {
+		if SeverityRank(rule.Severity) == 0 {
+			continue
+		}
+		keys, ok := attentionKeys(catalog, rule, results)
+		if !ok {
+			continue
+		}
+		finding := AttentionFinding{Rule: id, Severity: rule.Severity, Message: rule.Message, Keys: keys}
+		if len(rule.Files) == 0 {
+			findings = append(findings, finding)
+			continue
+		}
+		var re *regexp.Regexp
+		if rule.Pattern != "" {
+			re = regexp.MustCompile(rule.Pattern) // checked by ParseAttention
+		}
+		seen := make(map[string]bool)
+		for _, pattern := range rule.Files {
+			for _, path := range filter.Glob(projectPath, pattern) {
+				rel, err := filepath.Rel(projectPath, path)
+				if err != nil || seen[rel] {
+					continue
+				}
+				seen[rel] = true
+				if line, ok := detectors.MatchingLine(path, re); ok {
+					finding.File, finding.Line = filepath.ToSlash(rel), line
+					findings = append(findings, finding)
+				}
+			}
+		}
+	}
+
+	sort.Slice(findings, func(i, j int) bool {
+		a, b := findings[i], findings[j]
+		if a.Severity != b.Severity {
+			return SeverityRank(a.Severity) > SeverityRank(b.Severity)
+		}
+		if a.Rule != b.Rule {
+			return a.Rule < b.Rule
+		}
+		return a.File < b.File
+	})
+	return findings
+}
+
+// attentionKeys checks the detection conditions of a rule, returning the sorted
+// result keys that met them
+func attentionKeys(catalog *Catalog, rule AttentionRule, results map[string]string) ([]string, bool) {
+	var detected, inCategory []string
+	for key := range results {
+		if key == detectors.RepoKey || key == detectors.UpstreamKey {
+			continue
+		}
+		for _, name := range rule.Detected {
+			if resultIs(catalog, key, name) {
+				detected = append(detected, key)
+				break
+			}
+		}
+		if rule.Category != "" && resultCategory(catalog, key) == rule.Category {
+			inCategory = append(inCategory, key)
+		}
+	}
+	if len(rule.Detected) > 0 && len(detected) == 0 {
+		return nil, false
+	}
+	minCount := rule.MinCount
+	if minCount == 0 {
+		minCount = 1
+	}
+	if rule.Category != "" && len(inCategory) < minCount {
+		return nil, false
+	}
+
+	keys := append(detected, inCategory...)
+	sort.Strings(keys)
+	unique := keys[:0]
+	for i, key := range keys {
+		if i == 0 || key != keys[i-1] {
+			unique = append(unique, key)
+		}
+	}
+	return unique, true
+}
+
+// resultIs reports whether a result key is the service or file detector named,
+// file detector results are keyed by display name
+func resultIs(catalog *Catalog, key, name string) bool {
+	if strings.EqualFold(key, name) {
+		return true
+	}
+	if catalog.FileDetectors == nil {
+		return false
+	}
+	tech, ok := catalog.FileDetectors.Technologies[name]
+	return ok && strings.EqualFold(key, tech.DisplayName)
+}
+
+// resultCategory is the category of a service or file detector result, "" if it has none
+func resultCategory(catalog *Catalog, key string) string {
+	if service, ok := catalog.Services[key]; ok && service.Category != "" {
+		return service.Category
+	}
+	if catalog.FileDetectors == nil {
+		return ""
+	}
+	for techKey, tech := range catalog.FileDetectors.Technologies {
+		if strings.EqualFold(key, tech.DisplayName) || strings.EqualFold(key, techKey) {
+			return tech.Category
+		}
+	}
+	return ""
+}
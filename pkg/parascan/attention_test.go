@@ -0,0 +1,76 @@
+package parascan
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+func TestFindAttention(t *testing.T) {
+	projectPath := t.TempDir()
+	os.MkdirAll(filepath.Join(projectPath, "api"), 0755)
+	os.WriteFile(filepath.Join(projectPath, ".env"), []byte("DEBUG=1\nPORT=3000\n"), 0644)
+	os.WriteFile(filepath.Join(projectPath, "api", ".env"), []byte("PORT=3000\nexport STRIPE_SECRET_KEY=sk_test_123\n"), 0644)
+
+	catalog, err := EmbeddedCatalog()
+	if err != nil {
+		t.Fatalf("Failed to load catalog: %v", err)
+	}
+	results := map[string]string{
+		"GitHub Actions": "https://github.com/acme/widgets/actions",
+		"Travis CI":      "https://app.travis-ci.com/github/acme/widgets",
+		"stripe":         "https://dashboard.stripe.com",
+	}
+
+	// The .env without secrets is left alone, the error comes first
+	findings := FindAttention(catalog, projectPath, results, nil)
+	if len(findings) != 2 {
+		t.Fatalf("Expected the secrets and CI findings, got %+v", findings)
+	}
+	if f := findings[0]; f.Rule != "dotenv-secrets" || f.Severity != SeverityError || f.Location() != "api/.env:2" {
+		t.Errorf("Expected secrets in api/.env:2 as an error, got %+v", f)
+	}
+	if f := findings[1]; f.Rule != "multiple-ci" || f.Severity != SeverityWarning || len(f.Keys) != 2 || f.Keys[0] != "GitHub Actions" || f.Location() != "" {
+		t.Errorf("Expected both CI systems as a warning, got %+v", f)
+	}
+	delete(results, "Travis CI")
+	if findings := FindAttention(catalog, projectPath, results, nil); len(findings) != 1 {
+		t.Errorf("Expected one CI system to be fine, got %+v", findings)
+	}
+
+	// Rules turn catalog rules off and add their own
+	rules := fstest.MapFS{"attention.yml": {Data: []byte(`rules:
+  dotenv-secrets:
+    severity: off
+  stripe-keys:
+    severity: info
+    message: Stripe is billed per project
+    detected: [stripe]
+`)}}
+	merged, err := catalog.WithRules(rules, "rules")
+	if err != nil {
+		t.Fatalf("Failed to merge rules: %v", err)
+	}
+	findings = FindAttention(merged, projectPath, results, nil)
+	if len(findings) != 1 || findings[0].Rule != "stripe-keys" || findings[0].Severity != SeverityInfo || findings[0].Keys[0] != "stripe" {
+		t.Errorf("Expected only the rule's finding, got %+v", findings)
+	}
+	if len(catalog.Attention.Rules) != 2 {
+		t.Errorf("Expected rules to leave the catalog's alone, got %v", catalog.Attention.Rules)
+	}
+}
+
+func TestParseAttention(t *testing.T) {
+	for _, content := range []string{
+		"rules:\n  a:\n    severity: critical\n    message: m\n    category: ci\n",
+		"rules:\n  a:\n    severity: error\n    category: ci\n",
+		"rules:\n  a:\n    severity: error\n    message: m\n",
+		"rules:\n  a:\n    severity: error\n    message: m\n    files: [.env]\n    pattern: '('\n",
+		"rules:\n  a:\n    severity: error\n    message: m\n    pattern: x\n    category: ci\n",
+	} {
+		if _, err := ParseAttention([]byte(content)); err == nil {
+			t.Errorf("Expected an error for %q", content)
+		}
+	}
+}
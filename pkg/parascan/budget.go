@@ -0,0 +1,40 @@
+package parascan
+
+import (
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// budget is the time a scan run with WithBudget should take. Expensive steps
+// only run while enough of it is left, the others are reported as skipped.
+type budget struct {
+	limit time.Duration // zero is unlimited
+	start time.Time
+}
+
+func newBudget(limit time.Duration) budget {
+	return budget{limit: limit, start: time.Now()}
+}
+
+// deadline is when only the given fraction of the budget is left, zero when unlimited
+func (b budget) deadline(fraction float64) time.Time {
+	if b.limit <= 0 {
+		return time.Time{}
+	}
+	return b.start.Add(b.limit - time.Duration(float64(b.limit)*fraction))
+}
+
+// allows reports whether more than the given fraction of the budget is left
+func (b budget) allows(fraction float64) bool {
+	deadline := b.deadline(fraction)
+	return deadline.IsZero() || time.Now().Before(deadline)
+}
+
+// isLockfile reports whether a dependency file is a lockfile (yarn.lock,
+// pnpm-lock.yaml, package-lock.json, go.sum, poetry.lock), large and listing what
+// manifests already do
+func isLockfile(path string) bool {
+	name := filepath.Base(path)
+	return strings.HasSuffix(name, ".lock") || strings.HasSuffix(name, ".lockb") || strings.Contains(name, "-lock.") || name == "go.sum"
+}
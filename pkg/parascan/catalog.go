@@ -0,0 +1,373 @@
+package parascan
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+
+	"parascan/data"
+	"parascan/detectors"
+)
+
+// Catalog is the detection knowledge base: dependency files per language,
+// service definitions and file detectors
+type Catalog struct {
+	Stack         *StackDependencyFiles
+	Services      map[string]*ServiceData
+	FileDetectors *detectors.FileDetectors
+	Tooling       *Tooling   // developer tools implied by detections, empty without tooling.yml
+	Attention     *Attention // findings that need attention, nil without attention.yml
+	Health        *Health    // rubric of the inventory completeness score, nil without health.yml
+	Version       string     // from catalog.yml, empty for unversioned catalogs
+	Hash          string     // content hash of all catalog files
+	Source        string     // "embedded" or the catalog directory
+	Rules         []string   // rules merged over the catalog, see WithRules
+}
+
+// CatalogMeta is the content of catalog.yml
+type CatalogMeta struct {
+	Version string `yaml:"version"`
+}
+
+// Deprecation flags a detected service whose catalog entry is deprecated
+type Deprecation struct {
+	Key       string `json:"key"` // result key the deprecation applies to
+	Service   string `json:"service"`
+	Successor string `json:"successor,omitempty"`
+}
+
+// ConfigWarning flags project configuration a detected technology no longer
+// supports, e.g. a CI workflow running on a removed runner image
+type ConfigWarning struct {
+	Key     string `json:"key"`  // result key the warning applies to
+	File    string `json:"file"` // relative to the project
+	Line    int    `json:"line,omitempty"`
+	Message string `json:"message"`
+}
+
+// Location is where the warning applies, file:line or the file as a whole
+func (w ConfigWarning) Location() string {
+	if w.Line == 0 {
+		return w.File
+	}
+	return fmt.Sprintf("%s:%d", w.File, w.Line)
+}
+
+// FindDeprecations returns deprecated catalog entries among the detection results,
+// looked up by service key or file detector name
+func FindDeprecations(catalog *Catalog, results map[string]string) []Deprecation {
+	var deprecations []Deprecation
+	for key := range results {
+		if service, ok := catalog.Services[key]; ok && service.Deprecated {
+			deprecations = append(deprecations, Deprecation{Key: key, Service: service.Name, Successor: service.Successor})
+			continue
+		}
+		if catalog.FileDetectors == nil {
+			continue
+		}
+		for techKey, tech := range catalog.FileDetectors.Technologies {
+			if tech.Deprecated && (strings.EqualFold(key, tech.DisplayName) || strings.EqualFold(key, techKey)) {
+				deprecations = append(deprecations, Deprecation{Key: key, Service: tech.DisplayName, Successor: tech.Successor})
+				break
+			}
+		}
+	}
+
+	sort.Slice(deprecations, func(i, j int) bool {
+		return deprecations[i].Service < deprecations[j].Service
+	})
+	return deprecations
+}
+
+// EmbeddedCatalog loads the catalog compiled into the binary
+func EmbeddedCatalog() (*Catalog, error) {
+	return LoadCatalogFS(data.FS, "embedded")
+}
+
+// LoadCatalogFS loads a catalog laid out like data/, source names it in reports
+func LoadCatalogFS(fsys fs.FS, source string) (*Catalog, error) {
+	stackContent, err := fs.ReadFile(fsys, "stack-dependency-files.yml")
+	if err != nil {
+		return nil, fmt.Errorf("Error loading stack data: %v", err)
+	}
+	stackData, err := ParseStackDependencyFiles(stackContent)
+	if err != nil {
+		return nil, fmt.Errorf("Error loading stack data: %v", err)
+	}
+
+	servicesData, err := LoadServicesFS(fsys, "services")
+	if err != nil {
+		return nil, fmt.Errorf("Error loading services data: %v", err)
+	}
+
+	detectorsContent, err := fs.ReadFile(fsys, "file-detectors.yml")
+	if err != nil {
+		return nil, fmt.Errorf("Error loading file detectors data: %v", err)
+	}
+	fileDetectors, err := ParseFileDetectors(detectorsContent)
+	if err != nil {
+		return nil, fmt.Errorf("Error loading file detectors data: %v", err)
+	}
+
+	// tooling.yml, attention.yml, health.yml and catalog.yml are optional for external catalogs
+	tooling := &Tooling{}
+	if content, err := fs.ReadFile(fsys, "tooling.yml"); err == nil {
+		if err := yaml.Unmarshal(content, tooling); err != nil {
+			return nil, fmt.Errorf("Error loading tooling data: %v", err)
+		}
+	}
+
+	var attention *Attention
+	if content, err := fs.ReadFile(fsys, "attention.yml"); err == nil {
+		if attention, err = ParseAttention(content); err != nil {
+			return nil, fmt.Errorf("Error loading attention rules: %v", err)
+		}
+	}
+
+	var health *Health
+	if content, err := fs.ReadFile(fsys, "health.yml"); err == nil {
+		if health, err = ParseHealth(content); err != nil {
+			return nil, fmt.Errorf("Error loading health rubric: %v", err)
+		}
+	}
+
+	var meta CatalogMeta
+	if content, err := fs.ReadFile(fsys, "catalog.yml"); err == nil {
+		if err := yaml.Unmarshal(content, &meta); err != nil {
+			return nil, fmt.Errorf("Error loading catalog metadata: %v", err)
+		}
+	}
+
+	hash, err := HashCatalogFS(fsys)
+	if err != nil {
+		return nil, fmt.Errorf("Error hashing catalog: %v", err)
+	}
+
+	return &Catalog{
+		Stack:         stackData,
+		Services:      servicesData,
+		FileDetectors: fileDetectors,
+		Tooling:       tooling,
+		Attention:     attention,
+		Health:        health,
+		Version:       meta.Version,
+		Hash:          hash,
+		Source:        source,
+	}, nil
+}
+
+// OptionalCatalogFiles may be missing from external catalogs
+var OptionalCatalogFiles = map[string]bool{"catalog.yml": true, "tooling.yml": true, "attention.yml": true, "health.yml": true}
+
+// CatalogFiles lists the data files of a catalog in a stable order
+func CatalogFiles(fsys fs.FS) ([]string, error) {
+	files := []string{"attention.yml", "catalog.yml", "file-detectors.yml", "health.yml", "stack-dependency-files.yml", "tooling.yml"}
+	services, err := fs.Glob(fsys, "services/*.yml")
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(services)
+	return append(files, services...), nil
+}
+
+// HashCatalogFS computes a content hash over the detection data (catalog.yml excluded),
+// so the same data yields the same hash wherever it is loaded from
+func HashCatalogFS(fsys fs.FS) (string, error) {
+	files, err := CatalogFiles(fsys)
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha256.New()
+	for _, name := range files {
+		if name == "catalog.yml" {
+			continue
+		}
+		content, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			if OptionalCatalogFiles[name] && errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return "", err
+		}
+		fmt.Fprintf(hash, "%s\n%d\n", name, len(content))
+		hash.Write(content)
+	}
+	return "sha256:" + hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// StackDependencyFiles is the content of stack-dependency-files.yml
+type StackDependencyFiles struct {
+	Languages        map[string]Language `yaml:"languages"`
+	SourceExtensions map[string][]string `yaml:"source_extensions"`
+}
+
+type Language struct {
+	API             API                       `yaml:"api"`
+	PackageManagers map[string]PackageManager `yaml:"package_managers"`
+}
+
+type API struct {
+	CheckURL     string  `yaml:"check_url"`
+	DelaySeconds float64 `yaml:"delay_seconds"`
+}
+
+type PackageManager struct {
+	Files   []string `yaml:"files"`
+	Markers []string `yaml:"markers,omitempty"` // files telling the package manager is used, not read for packages, e.g. poetry.lock
+}
+
+// Link types a service can have a URL for. The url of a service is its dashboard,
+// links holds the others.
+var LinkTypes = []string{"dashboard", "docs", "status", "admin"}
+
+// IsLinkType reports whether linkType is one of LinkTypes
+func IsLinkType(linkType string) bool {
+	for _, known := range LinkTypes {
+		if linkType == known {
+			return true
+		}
+	}
+	return false
+}
+
+// ServiceData is a service definition from services/*.yml
+type ServiceData struct {
+	Name        string              `yaml:"name"`
+	URL         string              `yaml:"url"`
+	Links       map[string]string   `yaml:"links,omitempty"`        // URLs by link type other than the dashboard, e.g. status
+	URLTemplate string              `yaml:"url_template,omitempty"` // dashboard deep link with {prompt:name} placeholders, e.g. https://{prompt:org}.sentry.io
+	Prompts     map[string]string   `yaml:"prompts,omitempty"`      // question asked for each placeholder
+	Category    string              `yaml:"category,omitempty"`     // e.g. api_gateway, same categories as file detectors
+	Deprecated  bool                `yaml:"deprecated,omitempty"`
+	Successor   string              `yaml:"successor,omitempty"` // what to migrate to when deprecated
+	Aliases     []string            `yaml:"aliases,omitempty"`   // names used in framework configs (initializers, INSTALLED_APPS, services.php)
+	IaC         []string            `yaml:"iac,omitempty"`       // resource type prefixes in Terraform (aws_) and Pulumi (aws:) state
+	Helm        []string            `yaml:"helm,omitempty"`      // names of its Helm charts as chart dependencies, e.g. redis
+	Env         []string            `yaml:"env,omitempty"`       // environment variable name patterns, e.g. STRIPE_* or SENTRY_DSN
+	Content     []ContentRule       `yaml:"content,omitempty"`   // regexps matched in project files, e.g. api\.stripe\.com
+	Exclude     ServiceExclusions   `yaml:"exclude,omitempty"`   // negative rules against false positives
+	Stacks      map[string][]string `yaml:"stacks"`
+}
+
+// ServiceExclusions are negative rules of a service, for packages that name it
+// without the project using it
+type ServiceExclusions struct {
+	DevOnly []string `yaml:"dev_only,omitempty"` // packages that don't count when the project only needs them for development, * for all of the service's
+}
+
+// excludesDevOnly reports whether the service's dev_only rule lists a package of the language
+func (s *ServiceData) excludesDevOnly(language, pkg string) bool {
+	for _, candidate := range s.Exclude.DevOnly {
+		if candidate == "*" || packageMatches(language, candidate, pkg) {
+			return true
+		}
+	}
+	return false
+}
+
+// listsPackage reports whether a stack of the service lists a package, by name or pattern
+func (s *ServiceData) listsPackage(pkg string) bool {
+	for language, packages := range s.Stacks {
+		for _, candidate := range packages {
+			if candidate == pkg || packageMatches(language, candidate, pkg) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Link returns the service's URL of the given link type. The dashboard, "" and
+// types the service has no link for give its url.
+func (s *ServiceData) Link(linkType string) string {
+	if url := s.Links[linkType]; url != "" {
+		return url
+	}
+	return s.URL
+}
+
+// ParseStackDependencyFiles parses stack-dependency-files.yml
+func ParseStackDependencyFiles(data []byte) (*StackDependencyFiles, error) {
+	var stackData StackDependencyFiles
+	err := yaml.Unmarshal(data, &stackData)
+	if err != nil {
+		return nil, err
+	}
+
+	return &stackData, nil
+}
+
+// LoadServicesFS loads the service definitions in dir, keyed by file name
+func LoadServicesFS(fsys fs.FS, dir string) (map[string]*ServiceData, error) {
+	servicesData := make(map[string]*ServiceData)
+
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".yml") {
+			data, err := fs.ReadFile(fsys, dir+"/"+entry.Name())
+			if err != nil {
+				continue
+			}
+
+			var service ServiceData
+			err = yaml.Unmarshal(data, &service)
+			if err != nil {
+				continue
+			}
+
+			serviceName := entry.Name()[:len(entry.Name())-4] // remove .yml extension
+			servicesData[serviceName] = &service
+		}
+	}
+
+	return servicesData, nil
+}
+
+// ParseFileDetectors parses file-detectors.yml
+func ParseFileDetectors(data []byte) (*detectors.FileDetectors, error) {
+	var fileData detectors.FileDetectors
+	err := yaml.Unmarshal(data, &fileData)
+	if err != nil {
+		return nil, err
+	}
+	for key, tech := range fileData.Technologies {
+		for _, file := range tech.Files {
+			if _, err := detectors.ParseFileRule(file); err != nil {
+				return nil, fmt.Errorf("%s: %v", key, err)
+			}
+		}
+	}
+
+	return &fileData, nil
+}
+
+// Tooling is the content of tooling.yml: developer tools implied by detections
+type Tooling struct {
+	Tools map[string]Tool `yaml:"tools"`
+}
+
+// Tool is a command-line tool contributors need to work on a project
+type Tool struct {
+	Brew       string       `yaml:"brew,omitempty"` // Homebrew formula
+	Cask       string       `yaml:"cask,omitempty"` // Homebrew cask, for tools without a formula
+	Asdf       string       `yaml:"asdf,omitempty"` // asdf plugin, written to .tool-versions
+	DetectedBy []string     `yaml:"detected_by"`    // languages, service keys or file detector keys
+	Version    *ToolVersion `yaml:"version,omitempty"`
+}
+
+// ToolVersion reads the version the project uses from its files
+type ToolVersion struct {
+	Files   []string `yaml:"files"`
+	Pattern string   `yaml:"pattern"` // regexp, the first group is the version
+}
@@ -0,0 +1,67 @@
+//go:build !slim || pack_php
+
+package parascan
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// The php language pack reads composer.json and composer.lock
+func init() {
+	registerPack("php", map[string]dependencyParser{
+		"composer.json": composerDependencies,
+		"composer.lock": composerLockDependencies,
+	})
+}
+
+// composerDependencies reads the vendor/package names of a composer.json's require
+// and require-dev, e.g. stripe/stripe-php, the latter for development only.
+// Composer compares package names regardless of case. Platform requirements such as
+// php and ext-curl are never catalog packages.
+func composerDependencies(r io.Reader, wanted *packageSet, found map[string]bool) error {
+	var manifest struct {
+		Require    map[string]string `json:"require"`
+		RequireDev map[string]string `json:"require-dev"`
+	}
+	if err := json.NewDecoder(r).Decode(&manifest); err != nil {
+		return err
+	}
+
+	for i, requires := range []map[string]string{manifest.Require, manifest.RequireDev} {
+		for pkg := range requires {
+			if name, ok := wanted.lookupFold(pkg); ok {
+				found[name] = true
+				wanted.setScope(name, i == 1)
+			}
+		}
+	}
+	return nil
+}
+
+// composerLockDependencies reads the packages and packages-dev arrays of a
+// composer.lock. The lock lists every installed package without telling which
+// ones the project requires, so they count as indirect; composer.json next to it
+// has the direct ones. Packages of packages-dev are only installed for development.
+func composerLockDependencies(r io.Reader, wanted *packageSet, found map[string]bool) error {
+	type lockedPackage struct {
+		Name string `json:"name"`
+	}
+	var lock struct {
+		Packages    []lockedPackage `json:"packages"`
+		PackagesDev []lockedPackage `json:"packages-dev"`
+	}
+	if err := json.NewDecoder(r).Decode(&lock); err != nil {
+		return err
+	}
+
+	for i, packages := range [][]lockedPackage{lock.Packages, lock.PackagesDev} {
+		for _, pkg := range packages {
+			if name, ok := wanted.lookupFold(pkg.Name); ok {
+				found[name] = false
+				wanted.setScope(name, i == 1)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package parascan
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"parascan/detectors"
+)
+
+// ContentDetector is the name of the content rule detector in settings and errors
+const ContentDetector = "content"
+
+// ContentRule finds a service by what the project's files say rather than by a
+// package, for integrations over plain HTTP: an API host such as api.stripe.com,
+// or a DSN in config. Match is a regexp tried on each line of the files.
+type ContentRule struct {
+	Match string   `yaml:"match"`
+	Files []string `yaml:"files,omitempty"` // path patterns, e.g. *.py or config/**/*.yml; source files of the project's languages by default
+}
+
+// contentRule is a service's content rule ready to match
+type contentRule struct {
+	key string
+	ContentRule
+	re *regexp.Regexp
+}
+
+// compileContentRules returns the content rules of the services sorted by service
+func compileContentRules(servicesData map[string]*ServiceData) ([]contentRule, error) {
+	keys := make([]string, 0, len(servicesData))
+	for key := range servicesData {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var rules []contentRule
+	for _, key := range keys {
+		for _, rule := range servicesData[key].Content {
+			re, err := regexp.Compile(rule.Match)
+			if err != nil {
+				return nil, fmt.Errorf("%s: content rule %q: %v", key, rule.Match, err)
+			}
+			rules = append(rules, contentRule{key: key, ContentRule: rule, re: re})
+		}
+	}
+	return rules, nil
+}
+
+// applies reports whether the rule reads a file, given by its path relative to the
+// project, and whether the file has a source extension of the project's languages
+func (r *contentRule) applies(rel string, source bool) bool {
+	if len(r.Files) == 0 {
+		return source
+	}
+	for _, pattern := range r.Files {
+		if detectors.MatchPath(pattern, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// detectContent finds services by the content rules of the catalog in the files
+// each rule is scoped to. Every rule is evidence once per file, at its first
+// matching line; the rule is the evidence rather than the matched text, which may
+// hold a secret.
+func (a *ServicesAdapter) detectContent(projectPath string, languages []string) (map[string]string, error) {
+	results := make(map[string]string)
+	rules, err := compileContentRules(a.servicesData)
+	if err != nil || len(rules) == 0 {
+		return results, err
+	}
+	sourceExtensions := make(map[string]bool)
+	for _, language := range languages {
+		for _, ext := range a.stackData.SourceExtensions[language] {
+			sourceExtensions[ext] = true
+		}
+	}
+
+	var files []string
+	filepath.WalkDir(projectPath, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || path == projectPath {
+			return nil
+		}
+		if entry.IsDir() {
+			if SkipWalkDir(entry.Name()) || a.filter.SkipPath(projectPath, path) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if entry.Type().IsRegular() && !strings.Contains(entry.Name(), ".min.") && !a.filter.SkipPath(projectPath, path) {
+			files = append(files, path)
+		}
+		return nil
+	})
+	sort.Strings(files)
+
+	for _, path := range files {
+		rel, err := filepath.Rel(projectPath, path)
+		if err != nil {
+			continue
+		}
+		rel = filepath.ToSlash(rel)
+		source := sourceExtensions[filepath.Ext(path)]
+		var pending []*contentRule
+		for i := range rules {
+			if rules[i].applies(rel, source) {
+				pending = append(pending, &rules[i])
+			}
+		}
+		if len(pending) == 0 {
+			continue
+		}
+		if info, err := os.Stat(path); err != nil || info.Size() > maxSourceFileSize {
+			continue
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		for i, line := range strings.Split(string(content), "\n") {
+			for j := 0; j < len(pending); j++ {
+				rule := pending[j]
+				if !rule.re.MatchString(line) {
+					continue
+				}
+				results[rule.key] = a.serviceURL(rule.key, a.servicesData[rule.key])
+				a.addEvidence(projectPath, rule.key, ServiceEvidence{Detector: ContentDetector, Pattern: rule.Match, File: path, Line: i + 1})
+				pending = append(pending[:j], pending[j+1:]...)
+				j--
+			}
+			if len(pending) == 0 {
+				break
+			}
+		}
+	}
+	return results, nil
+}
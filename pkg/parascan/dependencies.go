@@ -0,0 +1,560 @@
+package parascan
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"parascan/detectors"
+)
+
+// DetectionResult lists a language's dependency files and the services found in them
+type DetectionResult struct {
+	Language string
+	Files    []string
+	Services []ServiceDetection
+}
+
+type ServiceDetection struct {
+	Name     string
+	Language string
+	Packages []PackageInfo
+}
+
+type PackageInfo struct {
+	Name     string
+	File     string
+	Indirect bool   // a dependency of dependencies, e.g. // indirect in go.mod
+	Scope    string // DevScope or RuntimeScope when the file tells, empty otherwise
+}
+
+// Scopes of a package in a dependency file
+const (
+	DevScope     = "dev"     // needed for development only, e.g. devDependencies or a Maven test scope
+	RuntimeScope = "runtime" // needed by the running project
+)
+
+// DefaultMaxDepth is how deep dependency files are searched for: the root and
+// three directory levels below it (e.g. services/api/package.json)
+const DefaultMaxDepth = 4
+
+// ProjectDirs lists the project root and its subdirectories up to maxDepth levels
+// (1 is the root only, like find -maxdepth), relative to the project.
+// Hidden, vendored and ignored directories are skipped.
+func ProjectDirs(projectPath string, filter *detectors.PathFilter, maxDepth int) []string {
+	dirs, _ := projectDirs(projectPath, filter, maxDepth, time.Time{})
+	return dirs
+}
+
+// projectDirs lists the project directories like ProjectDirs, stopping at the
+// deadline unless it's zero. It reports whether the walk completed.
+func projectDirs(projectPath string, filter *detectors.PathFilter, maxDepth int, deadline time.Time) ([]string, bool) {
+	if maxDepth <= 0 {
+		maxDepth = DefaultMaxDepth
+	}
+
+	dirs := []string{"."}
+	complete := true
+	filepath.WalkDir(projectPath, func(path string, d os.DirEntry, err error) error {
+		if !deadline.IsZero() && time.Now().After(deadline) {
+			complete = false
+			return filepath.SkipAll
+		}
+		if err != nil || !d.IsDir() || path == projectPath {
+			return nil
+		}
+		if SkipWalkDir(d.Name()) || filter.SkipPath(projectPath, path) {
+			return filepath.SkipDir
+		}
+		rel, err := filepath.Rel(projectPath, path)
+		if err != nil {
+			return filepath.SkipDir
+		}
+		depth := strings.Count(rel, string(filepath.Separator)) + 2
+		if depth > maxDepth {
+			return filepath.SkipDir
+		}
+		dirs = append(dirs, rel)
+		return nil
+	})
+	return dirs, complete
+}
+
+// SkipWalkDir reports whether a directory never holds the project's own files:
+// dependencies, build output and hidden directories
+func SkipWalkDir(name string) bool {
+	return detectors.SkipWalkDir(name)
+}
+
+// ProjectLanguage is a language of the project with the dependency files and
+// markers that revealed it, by package manager
+type ProjectLanguage struct {
+	Name            string                `json:"name"`
+	Confidence      string                `json:"confidence,omitempty"` // low when guessed from source file extensions
+	PackageManagers []PackageManagerFiles `json:"package_managers,omitempty"`
+}
+
+// PackageManagerFiles are the dependency files and markers of a package manager in the project
+type PackageManagerFiles struct {
+	Name  string   `json:"name"`
+	Files []string `json:"files"` // relative to the project, sorted
+}
+
+// HasLockfile reports whether one of the files is a lockfile, e.g. yarn.lock
+func (f PackageManagerFiles) HasLockfile() bool {
+	for _, file := range f.Files {
+		if isLockfile(file) {
+			return true
+		}
+	}
+	return false
+}
+
+// DetectProjectLanguages returns the languages with dependency files in the project,
+// in the order of FindProjectLanguages
+func DetectProjectLanguages(projectPath string, stackData *StackDependencyFiles, filter *detectors.PathFilter, maxDepth int) []string {
+	var names []string
+	for _, language := range FindProjectLanguages(projectPath, stackData, filter, maxDepth) {
+		names = append(names, language.Name)
+	}
+	return names
+}
+
+// FindProjectLanguages returns the languages with dependency files in the project,
+// the one with the most files first, then by name. Files several package managers
+// read, such as package.json, count for those with files of their own (yarn with a
+// yarn.lock) or, when none has, for the first package manager by name.
+func FindProjectLanguages(projectPath string, stackData *StackDependencyFiles, filter *detectors.PathFilter, maxDepth int) []ProjectLanguage {
+	dirs := ProjectDirs(projectPath, filter, maxDepth)
+
+	var languages []ProjectLanguage
+	fileCounts := make(map[string]int)
+	for name, lang := range stackData.Languages {
+		found := make(map[string][]string) // files by package manager
+		readers := make(map[string]int)    // package managers by file
+		for pmName, pm := range lang.PackageManagers {
+			seen := make(map[string]bool)
+			patterns := append(append([]string(nil), pm.Files...), pm.Markers...)
+			for _, pattern := range patterns {
+				for _, match := range FindDependencyFiles(projectPath, dirs, pattern, filter) {
+					rel, err := filepath.Rel(projectPath, match)
+					if err != nil || seen[rel] {
+						continue
+					}
+					seen[rel] = true
+					found[pmName] = append(found[pmName], filepath.ToSlash(rel))
+					readers[filepath.ToSlash(rel)]++
+				}
+			}
+		}
+		if len(found) == 0 {
+			continue
+		}
+
+		var managers []string
+		for pmName, files := range found {
+			for _, file := range files {
+				if readers[file] == 1 {
+					managers = append(managers, pmName)
+					break
+				}
+			}
+		}
+		if len(managers) == 0 {
+			for pmName := range found {
+				if len(managers) == 0 || pmName < managers[0] {
+					managers = []string{pmName}
+				}
+			}
+		}
+		sort.Strings(managers)
+
+		language := ProjectLanguage{Name: name}
+		for _, pmName := range managers {
+			files := found[pmName]
+			sort.Strings(files)
+			language.PackageManagers = append(language.PackageManagers, PackageManagerFiles{Name: pmName, Files: files})
+		}
+		languages = append(languages, language)
+		fileCounts[name] = len(readers)
+	}
+
+	sort.Slice(languages, func(i, j int) bool {
+		a, b := languages[i].Name, languages[j].Name
+		if fileCounts[a] != fileCounts[b] {
+			return fileCounts[a] > fileCounts[b]
+		}
+		return a < b
+	})
+	return languages
+}
+
+// DetectLanguagesByExtension guesses languages by counting source file extensions.
+// Used as a low-confidence fallback for repos without dependency files.
+// Languages are returned ordered by number of matching files.
+func DetectLanguagesByExtension(projectPath string, stackData *StackDependencyFiles, filter *detectors.PathFilter) []string {
+	const maxDepth = 3
+
+	extToLanguage := make(map[string]string)
+	for lang, extensions := range stackData.SourceExtensions {
+		for _, ext := range extensions {
+			extToLanguage[strings.ToLower(ext)] = lang
+		}
+	}
+
+	counts := make(map[string]int)
+	filepath.WalkDir(projectPath, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			if path == projectPath {
+				return nil
+			}
+			if SkipWalkDir(d.Name()) || filter.SkipPath(projectPath, path) {
+				return filepath.SkipDir
+			}
+			if rel, err := filepath.Rel(projectPath, path); err == nil && strings.Count(rel, string(filepath.Separator)) >= maxDepth-1 {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if filter.SkipPath(projectPath, path) {
+			return nil
+		}
+		if lang, ok := extToLanguage[strings.ToLower(filepath.Ext(path))]; ok {
+			counts[lang]++
+		}
+		return nil
+	})
+
+	var languages []string
+	for lang := range counts {
+		languages = append(languages, lang)
+	}
+	sort.Slice(languages, func(i, j int) bool {
+		if counts[languages[i]] != counts[languages[j]] {
+			return counts[languages[i]] > counts[languages[j]]
+		}
+		return languages[i] < languages[j]
+	})
+
+	return languages
+}
+
+// FindDependencyFiles matches a dependency file pattern (e.g. "requirements/*.txt")
+// in each of the project directories
+func FindDependencyFiles(projectPath string, dirs []string, pattern string, filter *detectors.PathFilter) []string {
+	var matches []string
+
+	// A ** pattern is searched for once, its matches count in the project directories
+	if strings.Contains(pattern, "**") {
+		searched := make(map[string]bool)
+		for _, dir := range dirs {
+			searched[filepath.Clean(dir)] = true
+		}
+		for _, match := range filter.Glob(projectPath, pattern) {
+			if rel, err := filepath.Rel(projectPath, filepath.Dir(match)); err == nil && searched[rel] {
+				matches = append(matches, match)
+			}
+		}
+		return matches
+	}
+
+	for _, dir := range dirs {
+		matches = append(matches, filter.Glob(projectPath, filepath.Join(dir, pattern))...)
+	}
+	return matches
+}
+
+// AnalyzeProjectDependencies finds catalog services in the dependency files of each language
+func AnalyzeProjectDependencies(projectPath string, languages []string, stackData *StackDependencyFiles, servicesData map[string]*ServiceData, filter *detectors.PathFilter, maxDepth int) []DetectionResult {
+	return analyzeProjectDependencies(projectPath, languages, stackData, servicesData, filter, maxDepth, nil)
+}
+
+// analyzeProjectDependencies analyzes dependency files like AnalyzeProjectDependencies,
+// except those skip reports true for when it's asked right before analyzing them
+func analyzeProjectDependencies(projectPath string, languages []string, stackData *StackDependencyFiles, servicesData map[string]*ServiceData, filter *detectors.PathFilter, maxDepth int, skip func(file string) bool) []DetectionResult {
+	var results []DetectionResult
+	dirs := ProjectDirs(projectPath, filter, maxDepth)
+
+	for _, language := range languages {
+		langData := stackData.Languages[language]
+		foundFilesMap := make(map[string]bool)
+		servicesMap := make(map[string]*ServiceDetection)
+
+		// Collect all dependency files for this language (without duplicates)
+		for _, packageManager := range langData.PackageManagers {
+			for _, filePattern := range packageManager.Files {
+				for _, match := range FindDependencyFiles(projectPath, dirs, filePattern, filter) {
+					foundFilesMap[match] = true
+				}
+			}
+		}
+
+		// Convert map to slice
+		var foundFiles []string
+		for file := range foundFilesMap {
+			foundFiles = append(foundFiles, file)
+		}
+		sort.Strings(foundFiles)
+
+		// Analyze found files only once each
+		analyzedFiles := make(map[string]bool)
+		for _, file := range foundFiles {
+			if !analyzedFiles[file] {
+				analyzedFiles[file] = true
+				if skip != nil && skip(file) {
+					continue
+				}
+				fileServices := AnalyzeFile(file, language, servicesData)
+				for _, service := range fileServices {
+					if existing, exists := servicesMap[service.Name]; exists {
+						// Merge packages, avoiding duplicates. The same package in
+						// several dependency files (e.g. two apps of a monorepo) is kept per file.
+						packageMap := make(map[PackageInfo]bool)
+						for _, pkg := range existing.Packages {
+							packageMap[pkg] = true
+						}
+						for _, pkg := range service.Packages {
+							packageMap[pkg] = true
+						}
+
+						var mergedPackages []PackageInfo
+						for pkg := range packageMap {
+							mergedPackages = append(mergedPackages, pkg)
+						}
+						existing.Packages = mergedPackages
+					} else {
+						// Create a copy to avoid pointer issues
+						serviceCopy := ServiceDetection{
+							Name:     service.Name,
+							Language: service.Language,
+							Packages: service.Packages,
+						}
+						servicesMap[service.Name] = &serviceCopy
+					}
+				}
+			}
+		}
+
+		// Packages the project only needs for development don't count for services
+		// that exclude them
+		for name, service := range servicesMap {
+			if service.Packages = excludeDevOnly(servicesData[name], language, service.Packages); len(service.Packages) == 0 {
+				delete(servicesMap, name)
+			}
+		}
+
+		// Convert map to slice
+		var services []ServiceDetection
+		for _, service := range servicesMap {
+			services = append(services, *service)
+		}
+
+		if len(foundFiles) > 0 || len(services) > 0 {
+			result := DetectionResult{
+				Language: language,
+				Files:    foundFiles,
+				Services: services,
+			}
+			results = append(results, result)
+		}
+	}
+
+	return results
+}
+
+// excludeDevOnly drops the packages a service's dev_only rule lists when the project
+// declares them for development only: in a development scope somewhere and for
+// runtime nowhere. Files that don't tell, such as yarn.lock, count neither way.
+func excludeDevOnly(service *ServiceData, language string, packages []PackageInfo) []PackageInfo {
+	if service == nil || len(service.Exclude.DevOnly) == 0 {
+		return packages
+	}
+	dev, runtime := make(map[string]bool), make(map[string]bool)
+	for _, pkg := range packages {
+		switch pkg.Scope {
+		case DevScope:
+			dev[pkg.Name] = true
+		case RuntimeScope:
+			runtime[pkg.Name] = true
+		}
+	}
+	var kept []PackageInfo
+	for _, pkg := range packages {
+		if dev[pkg.Name] && !runtime[pkg.Name] && service.excludesDevOnly(language, pkg.Name) {
+			continue
+		}
+		kept = append(kept, pkg)
+	}
+	return kept
+}
+
+// AnalyzeFile finds catalog services in a single dependency file
+func AnalyzeFile(filePath, language string, servicesData map[string]*ServiceData) []ServiceDetection {
+	var detections []ServiceDetection
+
+	// The packages of all services are looked up in a single pass over the file
+	wanted := make(map[string]bool)
+	for _, serviceData := range servicesData {
+		for _, pkg := range serviceData.Stacks[language] {
+			wanted[pkg] = true
+		}
+	}
+	if len(wanted) == 0 {
+		return detections
+	}
+	found, scopes, err := readDependencyFile(filePath, wanted)
+	if err != nil {
+		return detections
+	}
+	owners := patternOwners(language, wanted, found)
+	names := make([]string, 0, len(owners))
+	for name := range owners {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for serviceName, serviceData := range servicesData {
+		if packages, exists := serviceData.Stacks[language]; exists {
+			var foundPackages []PackageInfo
+
+			for _, pkg := range packages {
+				if strings.HasSuffix(pkg, "*") {
+					for _, name := range names {
+						if owners[name] == pkg {
+							foundPackages = append(foundPackages, PackageInfo{
+								Name:     name,
+								File:     filePath,
+								Indirect: !found[name],
+								Scope:    scopes[name],
+							})
+						}
+					}
+					continue
+				}
+				if direct, ok := found[pkg]; ok {
+					foundPackages = append(foundPackages, PackageInfo{
+						Name:     pkg,
+						File:     filePath,
+						Indirect: !direct,
+						Scope:    scopes[pkg],
+					})
+				}
+			}
+
+			if len(foundPackages) > 0 {
+				detection := ServiceDetection{
+					Name:     serviceName,
+					Language: language,
+					Packages: foundPackages,
+				}
+				detections = append(detections, detection)
+			}
+		}
+	}
+
+	return detections
+}
+
+// maxLineLength bounds the lines read from dependency files. Longer lines, such as
+// minified JSON or embedded hashes, never declare a dependency and are skipped.
+const maxLineLength = 64 * 1024
+
+// findPackagesInFile streams a dependency file, parsed according to its type, and
+// returns the wanted packages it declares, true for direct dependencies and false
+// for indirect ones. Files are never read into memory whole. Files of a language
+// pack left out of the build are an error rather than searched line by line.
+func findPackagesInFile(filePath string, packages map[string]bool) (map[string]bool, error) {
+	found, _, err := readDependencyFile(filePath, packages)
+	return found, err
+}
+
+// readDependencyFile is findPackagesInFile also returning the scope of the packages
+// found, for the files that tell development dependencies apart
+func readDependencyFile(filePath string, packages map[string]bool) (map[string]bool, map[string]string, error) {
+	if pack := MissingLanguagePack(filePath); pack != "" {
+		return nil, nil, fmt.Errorf("%s: the %s language pack isn't in this build", filePath, pack)
+	}
+	parse, compiled := dependencyParsers[packFileKey(filepath.Base(filePath))]
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	found := make(map[string]bool)
+	wanted := newPackageSet(packages)
+	if compiled {
+		err := parse(file, wanted, found)
+		return found, wanted.scopes, err
+	}
+	// For other files, use line-based search with word boundaries
+	return found, wanted.scopes, eachLine(file, func(line string) {
+		for _, word := range strings.Fields(line) {
+			// Clean word from common punctuation
+			if cleanWord := strings.Trim(word, `"',:;()[]{}`); wanted.has(cleanWord) {
+				found[cleanWord] = true
+			}
+		}
+	})
+}
+
+// eachLine calls fn with every line of r up to maxLineLength, without line endings
+func eachLine(r io.Reader, fn func(line string)) error {
+	reader := bufio.NewReaderSize(r, maxLineLength)
+	overlong := false
+	for {
+		line, isPrefix, err := reader.ReadLine()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		// The rest of an overlong line comes in further chunks, all of them are skipped
+		if !overlong && !isPrefix {
+			fn(string(line))
+		}
+		overlong = isPrefix
+	}
+}
+
+func expectDelim(decoder *json.Decoder, delim json.Delim) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	if token != delim {
+		return fmt.Errorf("expected %v, got %v", delim, token)
+	}
+	return nil
+}
+
+// skipJSONValue reads past the next value, nested objects and arrays included
+func skipJSONValue(decoder *json.Decoder) error {
+	depth := 0
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		switch token {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+		if depth == 0 {
+			return nil
+		}
+	}
+}
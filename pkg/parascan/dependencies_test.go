@@ -0,0 +1,870 @@
+package parascan
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// skipWithoutPack skips the rest of a test when the language pack of one of the
+// dependency files isn't in this build, e.g. with go test -tags slim
+func skipWithoutPack(t *testing.T, files ...string) {
+	t.Helper()
+	for _, file := range files {
+		if pack := MissingLanguagePack(file); pack != "" {
+			t.Skipf("the %s language pack isn't in this build", pack)
+		}
+	}
+}
+
+func TestFindPackagesInFile(t *testing.T) {
+	dir := t.TempDir()
+	wanted := map[string]bool{"stripe": true, "@sentry/node": true, "left-pad": true}
+
+	cases := []struct {
+		file, content string
+		expected      []string
+	}{
+		{"package.json", `{"name": "shop", "config": {"deps": {"left-pad": "1"}}, "keywords": ["stripe"],
+			"dependencies": {"stripe": "^14.0.0", "express": {"version": "4"}}, "devDependencies": {"@sentry/node": "7"}}`,
+			[]string{"@sentry/node", "stripe"}},
+		// Invalid JSON falls back to quoted names anywhere
+		{"package.json", `{"dependencies": {"stripe": "^14.0.0",}}`, []string{"stripe"}},
+		{"requirements.txt", "# stripe\nstripe>=7.0\n" + strings.Repeat("x", maxLineLength+10) + " left-pad\n", []string{"stripe"}},
+	}
+	for _, c := range cases {
+		if MissingLanguagePack(c.file) != "" {
+			continue
+		}
+		path := filepath.Join(dir, c.file)
+		if err := os.WriteFile(path, []byte(c.content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		found, err := findPackagesInFile(path, wanted)
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", c.file, err)
+		}
+		var names []string
+		for name := range found {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		if strings.Join(names, ",") != strings.Join(c.expected, ",") {
+			t.Errorf("Expected %v in %s, got %v", c.expected, c.content, names)
+		}
+	}
+}
+
+func TestFindProjectLanguages(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "web"), 0755)
+	for _, file := range []string{"requirements.txt", "setup.py", "pyproject.toml", "web/package.json", "web/yarn.lock"} {
+		os.WriteFile(filepath.Join(dir, filepath.FromSlash(file)), []byte("\n"), 0644)
+	}
+	catalog, err := EmbeddedCatalog()
+	if err != nil {
+		t.Fatalf("Failed to load catalog: %v", err)
+	}
+
+	// The language with the most files comes first, package.json counts for yarn
+	languages := FindProjectLanguages(dir, catalog.Stack, nil, 0)
+	if len(languages) != 2 || languages[0].Name != "python" || languages[1].Name != "nodejs" {
+		t.Fatalf("Expected python then nodejs, got %+v", languages)
+	}
+	var managers []string
+	for _, pm := range languages[0].PackageManagers {
+		managers = append(managers, pm.Name)
+	}
+	if strings.Join(managers, ",") != "pip,poetry,setuptools" {
+		t.Errorf("Expected the python package managers by name, got %v", managers)
+	}
+	if pms := languages[1].PackageManagers; len(pms) != 1 || pms[0].Name != "yarn" || strings.Join(pms[0].Files, ",") != "web/package.json,web/yarn.lock" {
+		t.Errorf("Expected yarn with both files, got %+v", pms)
+	}
+
+	// A package.json of its own is npm's, the first by name
+	os.Remove(filepath.Join(dir, "web", "yarn.lock"))
+	languages = FindProjectLanguages(dir, catalog.Stack, nil, 0)
+	if pms := languages[1].PackageManagers; len(pms) != 1 || pms[0].Name != "npm" {
+		t.Errorf("Expected npm for a lone package.json, got %+v", pms)
+	}
+}
+
+func TestGoModules(t *testing.T) {
+	skipWithoutPack(t, "go.mod")
+	dir := t.TempDir()
+	wanted := map[string]bool{
+		"github.com/stripe/stripe-go":    true,
+		"github.com/aws/aws-sdk-go-v2":   true,
+		"github.com/aws/aws-sdk-go":      true,
+		"github.com/getsentry/sentry-go": true,
+		"github.com/slack-go/slack":      true,
+		"github.com/twilio/twilio-go":    true,
+	}
+	goMod := `module example.com/billing
+
+go 1.21
+
+require github.com/stripe/stripe-go/v76 v76.8.0 // a comment
+
+require (
+	github.com/aws/aws-sdk-go-v2/service/s3 v1.47.0
+	"github.com/getsentry/sentry-go" v0.25.0 // indirect
+	example.com/notify v1.0.0
+	github.com/twilio/twilio-go-fork v1.0.0
+)
+
+replace example.com/notify => github.com/slack-go/slack v0.12.3
+
+replace (
+	github.com/twilio/twilio-go-fork => ../twilio
+)
+
+exclude github.com/twilio/twilio-go v1.0.0
+`
+	path := filepath.Join(dir, "go.mod")
+	if err := os.WriteFile(path, []byte(goMod), 0644); err != nil {
+		t.Fatal(err)
+	}
+	found, err := findPackagesInFile(path, wanted)
+	if err != nil {
+		t.Fatalf("Failed to read go.mod: %v", err)
+	}
+	// Major versions and nested modules match, aws-sdk-go isn't aws-sdk-go-v2, replacements count
+	expected := map[string]bool{
+		"github.com/stripe/stripe-go":    true,
+		"github.com/aws/aws-sdk-go-v2":   true,
+		"github.com/getsentry/sentry-go": false,
+		"github.com/slack-go/slack":      true,
+	}
+	if len(found) != len(expected) {
+		t.Errorf("Expected %v in go.mod, got %v", expected, found)
+	}
+	for pkg, direct := range expected {
+		if got, ok := found[pkg]; !ok || got != direct {
+			t.Errorf("Expected %s found with direct=%v, got %v (found %v)", pkg, direct, got, ok)
+		}
+	}
+
+	// go.sum doesn't tell direct dependencies apart
+	path = filepath.Join(dir, "go.sum")
+	goSum := "github.com/stripe/stripe-go/v76 v76.8.0 h1:abc=\ngithub.com/stripe/stripe-go/v76 v76.8.0/go.mod h1:def=\n"
+	if err := os.WriteFile(path, []byte(goSum), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if found, err := findPackagesInFile(path, wanted); err != nil || len(found) != 1 || found["github.com/stripe/stripe-go"] {
+		t.Errorf("Expected stripe-go as an indirect dependency in go.sum, got %v (%v)", found, err)
+	}
+}
+
+func TestGemfileLock(t *testing.T) {
+	skipWithoutPack(t, "Gemfile.lock")
+	projectPath := t.TempDir()
+	gemfile := "source 'https://rubygems.org'\n\ngem 'rails'\ngem 'sentry-ruby'\ngem 'billing_client', path: 'gems/billing_client'\n"
+	lockfile := `PATH
+  remote: gems/billing_client
+  specs:
+    billing_client (0.1.0)
+      stripe (>= 10.0)
+
+GEM
+  remote: https://rubygems.org/
+  specs:
+    rails (7.1.2)
+    sentry-ruby (5.15.0)
+      concurrent-ruby (~> 1.0, >= 1.0.2)
+    stripe (10.3.0)
+    twilio-ruby (6.9.0)
+      nokogiri (>= 1.6, < 2.0)
+
+PLATFORMS
+  ruby
+
+DEPENDENCIES
+  billing_client!
+  rails (~> 7.1)
+  sentry-ruby
+
+BUNDLED WITH
+   2.4.22
+`
+	for name, content := range map[string]string{"Gemfile": gemfile, "Gemfile.lock": lockfile} {
+		if err := os.WriteFile(filepath.Join(projectPath, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Gems the Gemfile lists are direct, the rest of the specs came along with them
+	found, err := findPackagesInFile(filepath.Join(projectPath, "Gemfile.lock"), map[string]bool{"stripe": true, "sentry-ruby": true, "twilio-ruby": true, "nokogiri": true})
+	if err != nil {
+		t.Fatalf("Failed to read Gemfile.lock: %v", err)
+	}
+	expected := map[string]bool{"stripe": false, "sentry-ruby": true, "twilio-ruby": false}
+	if len(found) != len(expected) {
+		t.Errorf("Expected %v in Gemfile.lock, got %v", expected, found)
+	}
+	for pkg, direct := range expected {
+		if got, ok := found[pkg]; !ok || got != direct {
+			t.Errorf("Expected %s found with direct=%v, got %v (found %v)", pkg, direct, got, ok)
+		}
+	}
+
+	scanner, err := New(WithoutGit())
+	if err != nil {
+		t.Fatalf("Failed to create scanner: %v", err)
+	}
+	report, err := scanner.Scan(context.Background(), projectPath)
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if evidence := report.Evidence["stripe"]; len(evidence) != 1 || evidence[0].File != "Gemfile.lock" || !evidence[0].Indirect {
+		t.Errorf("Expected stripe as an indirect gem of Gemfile.lock, got %v", evidence)
+	}
+
+	// Transitive matches can be left out, services the Gemfile lists stay
+	for _, scanner := range []*Scanner{
+		mustScanner(t, WithoutGit(), WithoutTransitive()),
+		mustScanner(t, WithoutGit(), WithSettings(&ProjectSettings{ExcludeTransitive: true})),
+	} {
+		report, err := scanner.Scan(context.Background(), projectPath)
+		if err != nil {
+			t.Fatalf("Scan failed: %v", err)
+		}
+		if _, found := report.Results["stripe"]; found {
+			t.Errorf("Expected no transitive stripe, got %v", report.Results)
+		}
+		if evidence := report.Evidence["sentry"]; len(evidence) != 2 {
+			t.Errorf("Expected sentry from the Gemfile and Gemfile.lock, got %v", evidence)
+		}
+	}
+}
+
+func mustScanner(t *testing.T, opts ...Option) *Scanner {
+	t.Helper()
+	scanner, err := New(opts...)
+	if err != nil {
+		t.Fatalf("Failed to create scanner: %v", err)
+	}
+	return scanner
+}
+
+func TestNodeLockfiles(t *testing.T) {
+	skipWithoutPack(t, "package-lock.json")
+	dir := t.TempDir()
+	wanted := map[string]bool{"stripe": true, "@stripe/stripe-js": true, "@sentry/node": true, "twilio": true, "left-pad": true}
+
+	packageLock := `{
+  "name": "shop",
+  "lockfileVersion": 3,
+  "packages": {
+    "": {"name": "shop", "dependencies": {"@stripe/stripe-js": "^2.1.0"}, "devDependencies": {"left-pad": "1.3.0"}},
+    "apps/admin": {"dependencies": {"@sentry/node": "^7.0.0"}},
+    "node_modules/@stripe/stripe-js": {"version": "2.1.0"},
+    "node_modules/@sentry/node": {"version": "7.80.0", "dependencies": {"twilio": "*"}},
+    "node_modules/billing-sdk/node_modules/stripe": {"version": "14.0.0"}
+  }
+}`
+	pnpmV6 := `lockfileVersion: '6.0'
+
+importers:
+  .:
+    dependencies:
+      '@stripe/stripe-js':
+        specifier: ^2.1.0
+        version: 2.1.0
+  apps/admin:
+    devDependencies:
+      '@sentry/node':
+        specifier: ^7.0.0
+        version: 7.80.0
+
+packages:
+  /@stripe/stripe-js@2.1.0:
+    resolution: {integrity: sha512-abc}
+  /@sentry/node@7.80.0:
+    dependencies:
+      stripe: 14.0.0
+  /stripe@14.0.0(debug@4.3.4):
+    resolution: {integrity: sha512-def}
+`
+	pnpmV9 := `lockfileVersion: '9.0'
+
+importers:
+  .:
+    dependencies:
+      '@stripe/stripe-js':
+        specifier: ^2.1.0
+        version: 2.1.0
+
+packages:
+  '@stripe/stripe-js@2.1.0':
+    resolution: {integrity: sha512-abc}
+  stripe@14.0.0:
+    resolution: {integrity: sha512-def}
+
+snapshots:
+  '@sentry/node@7.80.0': {}
+`
+	pnpmV5 := `lockfileVersion: 5.4
+
+dependencies:
+  '@stripe/stripe-js': 2.1.0
+
+packages:
+  /@stripe/stripe-js/2.1.0:
+    resolution: {integrity: sha512-abc}
+  /stripe/14.0.0_debug@4.3.4:
+    resolution: {integrity: sha512-def}
+`
+	yarnClassic := `# THIS IS AN AUTOGENERATED FILE. DO NOT EDIT THIS FILE DIRECTLY.
+# yarn lockfile v1
+
+
+"@stripe/stripe-js@^2.0.0", "@stripe/stripe-js@^2.1.0":
+  version "2.1.0"
+  resolved "https://registry.yarnpkg.com/@stripe/stripe-js/-/stripe-js-2.1.0.tgz#abc"
+  dependencies:
+    left-pad "^1.3.0"
+
+stripe@^14.0.0:
+  version "14.0.0"
+`
+	yarnBerry := `__metadata:
+  version: 8
+  cacheKey: 10c0
+
+"@sentry/node@npm:^7.0.0":
+  version: 7.80.0
+  resolution: "@sentry/node@npm:7.80.0"
+  dependencies:
+    stripe: "npm:^14.0.0"
+  languageName: node
+  linkType: hard
+
+"@stripe/stripe-js@npm:^2.0.0, @stripe/stripe-js@npm:^2.1.0":
+  version: 2.1.0
+  resolution: "@stripe/stripe-js@npm:2.1.0"
+
+"shop@workspace:.":
+  version: 0.0.0-use.local
+  resolution: "shop@workspace:."
+  dependencies:
+    "@sentry/node": "npm:^7.0.0"
+    "@stripe/stripe-js": "npm:^2.1.0"
+  languageName: unknown
+  linkType: soft
+
+"stripe@npm:^14.0.0":
+  version: 14.0.0
+  resolution: "stripe@npm:14.0.0"
+`
+
+	cases := []struct {
+		file, content string
+		expected      map[string]bool
+	}{
+		// Workspaces declare direct dependencies too, nested node_modules are installed packages
+		{"package-lock.json", packageLock, map[string]bool{"@stripe/stripe-js": true, "@sentry/node": true, "stripe": false}},
+		{"pnpm-lock.yaml", pnpmV6, map[string]bool{"@stripe/stripe-js": true, "@sentry/node": true, "stripe": false}},
+		{"pnpm-lock.yaml", pnpmV9, map[string]bool{"@stripe/stripe-js": true, "@sentry/node": false, "stripe": false}},
+		{"pnpm-lock.yaml", pnpmV5, map[string]bool{"@stripe/stripe-js": true, "stripe": false}},
+		// Scoped descriptors split at the range's @, only Berry knows the workspace's own dependencies
+		{"yarn.lock", yarnClassic, map[string]bool{"@stripe/stripe-js": false, "stripe": false}},
+		{"yarn.lock", yarnBerry, map[string]bool{"@stripe/stripe-js": true, "@sentry/node": true, "stripe": false}},
+	}
+	for _, c := range cases {
+		path := filepath.Join(dir, c.file)
+		if err := os.WriteFile(path, []byte(c.content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		found, err := findPackagesInFile(path, wanted)
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", c.file, err)
+		}
+		if len(found) != len(c.expected) {
+			t.Errorf("Expected %v in %s, got %v", c.expected, c.content, found)
+			continue
+		}
+		for pkg, direct := range c.expected {
+			if got, ok := found[pkg]; !ok || got != direct {
+				t.Errorf("Expected %s found with direct=%v in %s, got %v (found %v)", pkg, direct, c.content, got, ok)
+			}
+		}
+	}
+}
+
+func TestJVMBuildFiles(t *testing.T) {
+	skipWithoutPack(t, "pom.xml")
+	dir := t.TempDir()
+	wanted := map[string]bool{
+		"com.stripe:stripe-java":               true,
+		"com.twilio.sdk:twilio":                true,
+		"io.sentry:sentry-spring-boot-starter": true,
+		"software.amazon.awssdk:bom":           true,
+		"com.acme:billing-client":              true,
+		"com.google.gms:google-services":       true,
+	}
+
+	pom := `<?xml version="1.0" encoding="UTF-8"?>
+<project xmlns="http://maven.apache.org/POM/4.0.0">
+  <parent>
+    <groupId>com.acme</groupId>
+    <artifactId>platform</artifactId>
+    <version>1.4.0</version>
+  </parent>
+  <artifactId>billing</artifactId>
+  <properties>
+    <stripe.group>com.stripe</stripe.group>
+    <stripe.version>24.0.0</stripe.version>
+  </properties>
+  <dependencyManagement>
+    <dependencies>
+      <dependency>
+        <groupId>software.amazon.awssdk</groupId>
+        <artifactId>bom</artifactId>
+        <version>2.21.0</version>
+        <type>pom</type>
+        <scope>import</scope>
+      </dependency>
+    </dependencies>
+  </dependencyManagement>
+  <dependencies>
+    <dependency>
+      <groupId>${stripe.group}</groupId>
+      <artifactId>stripe-java</artifactId>
+      <version>${stripe.version}</version>
+    </dependency>
+    <dependency>
+      <groupId>${project.groupId}</groupId>
+      <artifactId>billing-client</artifactId>
+      <version>${project.version}</version>
+    </dependency>
+  </dependencies>
+  <build>
+    <plugins>
+      <plugin>
+        <groupId>com.twilio.sdk</groupId>
+        <artifactId>twilio</artifactId>
+      </plugin>
+    </plugins>
+  </build>
+  <profiles>
+    <profile>
+      <id>monitoring</id>
+      <dependencies>
+        <dependency>
+          <groupId>io.sentry</groupId>
+          <artifactId>sentry-spring-boot-starter</artifactId>
+        </dependency>
+      </dependencies>
+    </profile>
+  </profiles>
+</project>
+`
+	gradle := `buildscript {
+    repositories { maven { url 'https://maven.google.com' } }
+    dependencies {
+        classpath 'com.google.gms:google-services:4.4.0' // Firebase
+    }
+}
+
+// implementation 'com.acme:billing-client:1.0'
+def twilio = 'com.twilio.sdk:twilio:9.0.0'
+
+dependencies {
+    implementation "com.stripe:stripe-java:${stripeVersion}"
+    implementation group: 'io.sentry', name: 'sentry-spring-boot-starter', version: '6.0.0'
+    testImplementation('org.junit.jupiter:junit-jupiter')
+}
+`
+	gradleKts := `plugins {
+    id("org.springframework.boot") version "3.2.0"
+}
+
+dependencies {
+    implementation(platform("software.amazon.awssdk:bom:2.21.0"))
+    implementation("com.twilio.sdk:twilio:9.0.0")
+    implementation(group = "com.stripe", name = "stripe-java", version = "24.0.0")
+}
+`
+
+	cases := []struct {
+		file, content string
+		expected      map[string]bool
+	}{
+		// Properties and the parent's groupId are interpolated, plugins aren't dependencies
+		{"pom.xml", pom, map[string]bool{"com.stripe:stripe-java": true, "com.acme:billing-client": true, "io.sentry:sentry-spring-boot-starter": true, "software.amazon.awssdk:bom": false}},
+		// Only dependencies blocks count, commented out declarations and variables don't
+		{"build.gradle", gradle, map[string]bool{"com.google.gms:google-services": true, "com.stripe:stripe-java": true, "io.sentry:sentry-spring-boot-starter": true}},
+		{"build.gradle.kts", gradleKts, map[string]bool{"software.amazon.awssdk:bom": true, "com.twilio.sdk:twilio": true, "com.stripe:stripe-java": true}},
+	}
+	for _, c := range cases {
+		path := filepath.Join(dir, c.file)
+		if err := os.WriteFile(path, []byte(c.content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		found, err := findPackagesInFile(path, wanted)
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", c.file, err)
+		}
+		if len(found) != len(c.expected) {
+			t.Errorf("Expected %v in %s, got %v", c.expected, c.file, found)
+			continue
+		}
+		for pkg, direct := range c.expected {
+			if got, ok := found[pkg]; !ok || got != direct {
+				t.Errorf("Expected %s found with direct=%v in %s, got %v (found %v)", pkg, direct, c.file, got, ok)
+			}
+		}
+	}
+}
+
+func TestNuGetPackages(t *testing.T) {
+	skipWithoutPack(t, "Billing.csproj")
+	dir := t.TempDir()
+	wanted := map[string]bool{"Stripe.net": true, "AWSSDK.S3": true, "Twilio": true, "Sentry.AspNetCore": true}
+
+	csproj := `<Project Sdk="Microsoft.NET.Sdk.Web">
+  <PropertyGroup>
+    <TargetFramework>net8.0</TargetFramework>
+  </PropertyGroup>
+  <ItemGroup>
+    <PackageReference Include="stripe.net" Version="43.0.0" />
+    <PackageReference Include="AWSSDK.S3">
+      <Version>3.7.300</Version>
+    </PackageReference>
+    <PackageReference Update="Twilio" Version="7.0.0" />
+    <!-- <PackageReference Include="Sentry.AspNetCore" Version="4.0.0" /> -->
+    <ProjectReference Include="..\Twilio\Twilio.csproj" />
+  </ItemGroup>
+</Project>
+`
+	packagesProps := `<Project>
+  <ItemGroup>
+    <PackageVersion Include="Twilio" Version="7.0.0" />
+  </ItemGroup>
+</Project>
+`
+	packagesConfig := `<?xml version="1.0" encoding="utf-8"?>
+<packages>
+  <package id="Stripe.net" version="39.0.0" targetFramework="net48" />
+</packages>
+`
+	lock := `{
+  "version": 1,
+  "dependencies": {
+    "net8.0": {
+      "Stripe.net": {"type": "Direct", "requested": "[43.0.0, )", "resolved": "43.0.0"},
+      "AWSSDK.Core": {"type": "Transitive", "resolved": "3.7.300"},
+      "awssdk.s3": {"type": "Transitive", "resolved": "3.7.300"},
+      "Twilio": {"type": "Project"}
+    },
+    "net6.0": {
+      "AWSSDK.S3": {"type": "CentralTransitive", "requested": "[3.7.300, )", "resolved": "3.7.300"}
+    }
+  }
+}`
+
+	cases := []struct {
+		file, content string
+		expected      map[string]bool
+	}{
+		// IDs are case insensitive, updates, comments and project references aren't packages
+		{"Billing.csproj", csproj, map[string]bool{"Stripe.net": true, "AWSSDK.S3": true}},
+		{"Directory.Packages.props", packagesProps, map[string]bool{"Twilio": false}},
+		{"packages.config", packagesConfig, map[string]bool{"Stripe.net": true}},
+		{"packages.lock.json", lock, map[string]bool{"Stripe.net": true, "AWSSDK.S3": false}},
+	}
+	for _, c := range cases {
+		path := filepath.Join(dir, c.file)
+		if err := os.WriteFile(path, []byte(c.content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		found, err := findPackagesInFile(path, wanted)
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", c.file, err)
+		}
+		if len(found) != len(c.expected) {
+			t.Errorf("Expected %v in %s, got %v", c.expected, c.file, found)
+			continue
+		}
+		for pkg, direct := range c.expected {
+			if got, ok := found[pkg]; !ok || got != direct {
+				t.Errorf("Expected %s found with direct=%v in %s, got %v (found %v)", pkg, direct, c.file, got, ok)
+			}
+		}
+	}
+}
+
+func TestComposerPackages(t *testing.T) {
+	skipWithoutPack(t, "composer.json")
+	dir := t.TempDir()
+	wanted := map[string]bool{"stripe/stripe-php": true, "aws/aws-sdk-php": true, "sentry/sentry-laravel": true, "twilio/sdk": true}
+
+	manifest := `{
+  "name": "acme/billing",
+  "description": "Uses twilio/sdk through the notifier",
+  "require": {
+    "php": "^8.2",
+    "ext-curl": "*",
+    "Stripe/Stripe-PHP": "^13.0",
+    "stripe/stripe-php-extras": "^1.0"
+  },
+  "require-dev": {
+    "sentry/sentry-laravel": "^4.0"
+  },
+  "suggest": {
+    "aws/aws-sdk-php": "For S3 uploads"
+  }
+}`
+	lock := `{
+  "content-hash": "0123456789abcdef",
+  "packages": [
+    {"name": "stripe/stripe-php", "version": "v13.0.0"},
+    {"name": "aws/aws-sdk-php", "version": "3.300.0", "require": {"twilio/sdk": "*"}}
+  ],
+  "packages-dev": [
+    {"name": "sentry/sentry-laravel", "version": "4.0.0"}
+  ]
+}`
+
+	cases := []struct {
+		file, content string
+		expected      map[string]bool
+	}{
+		// Names are exact and case insensitive, suggestions and descriptions aren't requirements
+		{"composer.json", manifest, map[string]bool{"stripe/stripe-php": true, "sentry/sentry-laravel": true}},
+		{"composer.lock", lock, map[string]bool{"stripe/stripe-php": false, "aws/aws-sdk-php": false, "sentry/sentry-laravel": false}},
+	}
+	for _, c := range cases {
+		path := filepath.Join(dir, c.file)
+		if err := os.WriteFile(path, []byte(c.content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		found, err := findPackagesInFile(path, wanted)
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", c.file, err)
+		}
+		if len(found) != len(c.expected) {
+			t.Errorf("Expected %v in %s, got %v", c.expected, c.file, found)
+			continue
+		}
+		for pkg, direct := range c.expected {
+			if got, ok := found[pkg]; !ok || got != direct {
+				t.Errorf("Expected %s found with direct=%v in %s, got %v (found %v)", pkg, direct, c.file, got, ok)
+			}
+		}
+	}
+}
+
+func TestInternalPackagePatterns(t *testing.T) {
+	catalog, err := EmbeddedCatalog()
+	if err != nil {
+		t.Fatalf("Failed to load catalog: %v", err)
+	}
+	rules := fstest.MapFS{
+		"services/acme-platform.yml": {Data: []byte(`name: Acme Platform
+url: https://platform.intranet.acme.com
+stacks:
+  nodejs: ["@acme/*"]
+  go: [go.acme.com/*]
+  java: ["com.acme:*"]
+  dotnet: [Acme.*]
+`)},
+		"services/acme-billing.yml": {Data: []byte(`name: Acme Billing
+url: https://billing.intranet.acme.com
+stacks:
+  nodejs: ["@acme/billing-*", "@acme/invoices"]
+`)},
+	}
+	merged, err := catalog.WithRules(rules, "rules")
+	if err != nil {
+		t.Fatalf("Failed to merge rules: %v", err)
+	}
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"package.json": `{"dependencies": {"@acme/auth": "1", "@acme/billing-client": "2", "@acme/invoices": "1", "@acmecorp/ui": "1", "stripe": "14"},
+			"devDependencies": {"@acme/eslint-config": "1"}}`,
+		"go.mod":      "module go.acme.com/shop\n\nrequire (\n\tgo.acme.com/auth/v2 v2.1.0\n\tgithub.com/stripe/stripe-go/v76 v76.0.0\n)\n",
+		"pom.xml":     `<project><dependencies><dependency><groupId>com.acme</groupId><artifactId>ledger</artifactId></dependency></dependencies></project>`,
+		"Shop.csproj": `<Project><ItemGroup><PackageReference Include="acme.Telemetry" Version="1.0" /></ItemGroup></Project>`,
+	}
+	cases := []struct {
+		file, language string
+		expected       map[string][]string // packages by service
+	}{
+		// The most specific pattern or name wins, other scopes don't match
+		{"package.json", "nodejs", map[string][]string{
+			"acme-platform": {"@acme/auth", "@acme/eslint-config"},
+			"acme-billing":  {"@acme/billing-client", "@acme/invoices"},
+			"stripe":        {"stripe"},
+		}},
+		{"go.mod", "go", map[string][]string{"acme-platform": {"go.acme.com/auth/v2"}, "stripe": {"github.com/stripe/stripe-go"}}},
+		{"pom.xml", "java", map[string][]string{"acme-platform": {"com.acme:ledger"}}},
+		// NuGet names are case insensitive, patterns too
+		{"Shop.csproj", "dotnet", map[string][]string{"acme-platform": {"acme.Telemetry"}}},
+	}
+	for _, c := range cases {
+		if MissingLanguagePack(c.file) != "" {
+			continue
+		}
+		path := filepath.Join(dir, c.file)
+		if err := os.WriteFile(path, []byte(files[c.file]), 0644); err != nil {
+			t.Fatal(err)
+		}
+		got := make(map[string][]string)
+		for _, detection := range AnalyzeFile(path, c.language, merged.Services) {
+			for _, pkg := range detection.Packages {
+				got[detection.Name] = append(got[detection.Name], pkg.Name)
+			}
+		}
+		for service, packages := range c.expected {
+			sort.Strings(got[service])
+			if strings.Join(got[service], ",") != strings.Join(packages, ",") {
+				t.Errorf("Expected %s to find %v in %s, got %v", service, packages, c.file, got[service])
+			}
+		}
+		if len(got) != len(c.expected) {
+			t.Errorf("Expected services %v in %s, got %v", c.expected, c.file, got)
+		}
+	}
+
+	// Lookups list every service whose names or patterns match
+	for pkg, expected := range map[string]string{"@acme/search": "acme-platform", "@acme/billing-api": "acme-billing,acme-platform", "@acmecorp/ui": ""} {
+		var keys []string
+		for _, service := range merged.LookupPackage("nodejs", pkg) {
+			keys = append(keys, service.Key)
+		}
+		if strings.Join(keys, ",") != expected {
+			t.Errorf("Expected %s to belong to %q, got %v", pkg, expected, keys)
+		}
+	}
+}
+
+func TestScannerMemoryLimit(t *testing.T) {
+	projectPath := filepath.Join("..", "..", "testdata", "settings-project")
+	scanner, err := New(WithoutGit(), WithMemoryLimit(1))
+	if err != nil {
+		t.Fatalf("Failed to create scanner: %v", err)
+	}
+	var limitErr *MemoryLimitError
+	if _, err := scanner.Scan(context.Background(), projectPath); !errors.As(err, &limitErr) {
+		t.Errorf("Expected a MemoryLimitError, got %v", err)
+	}
+
+	scanner, err = New(WithoutGit())
+	if err != nil {
+		t.Fatalf("Failed to create scanner: %v", err)
+	}
+	report, err := scanner.Scan(context.Background(), projectPath)
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if report.Stats.DependencyFiles == 0 || report.Stats.PeakMemory == 0 {
+		t.Errorf("Expected dependency files and peak memory in the stats, got %+v", report.Stats)
+	}
+}
+
+func TestDevOnlyExclusion(t *testing.T) {
+	catalog, err := EmbeddedCatalog()
+	if err != nil {
+		t.Fatalf("Failed to load catalog: %v", err)
+	}
+	rules := fstest.MapFS{
+		"services/acme-sandbox.yml": {Data: []byte(`name: Acme Sandbox
+url: https://sandbox.acme.com
+exclude:
+  dev_only: [acme-sandbox-mock, com.acme:sandbox-testkit]
+stacks:
+  nodejs: [acme-sandbox-mock, acme-sandbox]
+  ruby: [acme-sandbox-mock]
+  java: ["com.acme:sandbox-testkit"]
+`)},
+	}
+	merged, err := catalog.WithRules(rules, "rules")
+	if err != nil {
+		t.Fatalf("Failed to merge rules: %v", err)
+	}
+
+	// Files that tell development dependencies apart give the scope of each package
+	dir := t.TempDir()
+	files := map[string]string{
+		"package.json": `{"dependencies": {"stripe": "14"}, "devDependencies": {"acme-sandbox-mock": "1"}}`,
+		"Gemfile":      "gem 'stripe'\ngroup :development, :test do\n  gem 'acme-sandbox-mock'\nend\ngem 'sentry-ruby', group: :test\n",
+		"pom.xml":      `<project><dependencies><dependency><groupId>com.acme</groupId><artifactId>sandbox-testkit</artifactId><scope>test</scope></dependency></dependencies></project>`,
+		"build.gradle": "dependencies {\n  implementation 'com.stripe:stripe-java:24.0.0'\n  testImplementation 'com.acme:sandbox-testkit:1.0'\n}\n",
+		"package-lock.json": `{"lockfileVersion": 3, "packages": {"": {"dependencies": {"stripe": "14"}, "devDependencies": {"acme-sandbox-mock": "1"}},
+			"node_modules/stripe": {"version": "14.0.0"}, "node_modules/acme-sandbox-mock": {"version": "1.0.0", "dev": true}}}`,
+		"composer.json": `{"require": {"stripe/stripe-php": "^13"}, "require-dev": {"sentry/sentry": "^4"}}`,
+	}
+	cases := []struct {
+		file, language string
+		expected       map[string]string // scope by package
+	}{
+		{"package.json", "nodejs", map[string]string{"stripe": RuntimeScope, "acme-sandbox-mock": DevScope}},
+		{"Gemfile", "ruby", map[string]string{"stripe": RuntimeScope, "acme-sandbox-mock": DevScope, "sentry-ruby": DevScope}},
+		{"pom.xml", "java", map[string]string{"com.acme:sandbox-testkit": DevScope}},
+		{"build.gradle", "java", map[string]string{"com.stripe:stripe-java": RuntimeScope, "com.acme:sandbox-testkit": DevScope}},
+		{"package-lock.json", "nodejs", map[string]string{"stripe": RuntimeScope, "acme-sandbox-mock": DevScope}},
+		{"composer.json", "php", map[string]string{"stripe/stripe-php": RuntimeScope, "sentry/sentry": DevScope}},
+	}
+	for _, c := range cases {
+		if MissingLanguagePack(c.file) != "" {
+			continue
+		}
+		path := filepath.Join(dir, c.file)
+		if err := os.WriteFile(path, []byte(files[c.file]), 0644); err != nil {
+			t.Fatal(err)
+		}
+		got := make(map[string]string)
+		for _, detection := range AnalyzeFile(path, c.language, merged.Services) {
+			for _, pkg := range detection.Packages {
+				got[pkg.Name] = pkg.Scope
+			}
+		}
+		for pkg, scope := range c.expected {
+			if got[pkg] != scope {
+				t.Errorf("Expected %s in %s scoped %q, got %q", pkg, c.file, scope, got[pkg])
+			}
+		}
+	}
+
+	// A package the project only needs for development doesn't find the service
+	skipWithoutPack(t, "package.json", "Gemfile", "pom.xml", "composer.json")
+	detected := func() map[string]bool {
+		services := make(map[string]bool)
+		for _, result := range AnalyzeProjectDependencies(dir, []string{"nodejs", "ruby", "java", "php"}, merged.Stack, merged.Services, nil, 0) {
+			for _, service := range result.Services {
+				services[service.Name] = true
+			}
+		}
+		return services
+	}
+	if services := detected(); services["acme-sandbox"] || !services["stripe"] {
+		t.Errorf("Expected acme-sandbox excluded, got %v", services)
+	}
+
+	// Needed for runtime anywhere, e.g. by another app, it counts
+	app := filepath.Join(dir, "apps", "web")
+	if err := os.MkdirAll(app, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(app, "package.json"), []byte(`{"dependencies": {"acme-sandbox-mock": "1"}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if services := detected(); !services["acme-sandbox"] {
+		t.Errorf("Expected acme-sandbox found for runtime, got %v", services)
+	}
+
+	// A rule can only exclude the service's own packages
+	rules["services/acme-sandbox.yml"].Data = []byte("name: Acme Sandbox\nurl: https://sandbox.acme.com\nexclude:\n  dev_only: [left-pad]\nstacks:\n  nodejs: [acme-sandbox]\n")
+	if _, err := catalog.WithRules(rules, "rules"); err == nil || !strings.Contains(err.Error(), "dev_only lists left-pad") {
+		t.Errorf("Expected an error for a foreign dev_only package, got %v", err)
+	}
+}
@@ -0,0 +1,113 @@
+package parascan
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// EnvDetector is the name of the environment variable detector in settings and
+// errors, DotenvDetector the opt-in setting that extends it to .env files
+const (
+	EnvDetector    = "env"
+	DotenvDetector = "dotenv"
+)
+
+// Templates of the environment a project needs, committed without real values
+var envTemplateFiles = []string{".env.example", ".env.sample", ".env.template", "env.example"}
+
+// Prefixes frameworks require for variables exposed to the browser, e.g.
+// NEXT_PUBLIC_STRIPE_PUBLISHABLE_KEY is STRIPE_PUBLISHABLE_KEY
+var envExposurePrefixes = []string{"NEXT_PUBLIC_", "NUXT_PUBLIC_", "REACT_APP_", "VITE_", "EXPO_PUBLIC_", "GATSBY_", "PUBLIC_"}
+
+// NAME=value or export NAME=value, the value isn't captured
+var envAssignment = regexp.MustCompile(`^\s*(?:export\s+)?([A-Za-z_][A-Za-z0-9_]*)\s*=`)
+
+// findEnvFiles lists the environment templates of the project directories, and
+// .env files with dotenv
+func findEnvFiles(projectPath string, dirs []string, dotenv bool) []string {
+	names := envTemplateFiles
+	if dotenv {
+		names = append([]string{".env"}, names...)
+	}
+	var files []string
+	for _, dir := range dirs {
+		for _, name := range names {
+			file := filepath.Join(projectPath, dir, name)
+			if info, err := os.Stat(file); err == nil && info.Mode().IsRegular() {
+				files = append(files, file)
+			}
+		}
+	}
+	sort.Strings(files)
+	return files
+}
+
+// readEnvNames returns the names of the variables a file assigns. .env files hold
+// secrets: values are never kept, and commented out lines are skipped.
+func readEnvNames(file string) ([]string, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	seen := make(map[string]bool)
+	for _, line := range strings.Split(string(content), "\n") {
+		if match := envAssignment.FindStringSubmatch(line); match != nil && !seen[match[1]] {
+			seen[match[1]] = true
+			names = append(names, match[1])
+		}
+	}
+	return names, nil
+}
+
+// envServices returns the catalog services whose env patterns match a variable,
+// regardless of case and of framework exposure prefixes, e.g. SENTRY_* for
+// VITE_SENTRY_DSN
+func envServices(name string, servicesData map[string]*ServiceData) []string {
+	name = strings.ToUpper(name)
+	for _, prefix := range envExposurePrefixes {
+		if trimmed := strings.TrimPrefix(name, prefix); trimmed != name && trimmed != "" {
+			name = trimmed
+			break
+		}
+	}
+
+	var keys []string
+	for key, service := range servicesData {
+		for _, pattern := range service.Env {
+			if ok, _ := path.Match(strings.ToUpper(pattern), name); ok {
+				keys = append(keys, key)
+				break
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// detectEnvVars finds services among the variable names of the project's
+// environment templates, and of .env files with dotenv. Services reached over plain
+// HTTP have no package to find, their credentials still need a variable.
+func (a *ServicesAdapter) detectEnvVars(projectPath string, dotenv bool) map[string]string {
+	results := make(map[string]string)
+	for _, file := range findEnvFiles(projectPath, ProjectDirs(projectPath, a.filter, a.maxDepth), dotenv) {
+		if a.filter.SkipPath(projectPath, file) {
+			continue
+		}
+		names, err := readEnvNames(file)
+		if err != nil {
+			continue
+		}
+		for _, name := range names {
+			for _, key := range envServices(name, a.servicesData) {
+				results[key] = a.serviceURL(key, a.servicesData[key])
+				a.addEvidence(projectPath, key, ServiceEvidence{Detector: EnvDetector, Variable: name, File: file})
+			}
+		}
+	}
+	return results
+}
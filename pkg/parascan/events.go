@@ -0,0 +1,78 @@
+package parascan
+
+import (
+	"path/filepath"
+	"sort"
+)
+
+// EventType is a step of a scan in progress, see WithEvents
+type EventType string
+
+const (
+	EventDetectorStarted  EventType = "detector_started"
+	EventDetectorFinished EventType = "detector_finished"
+	EventFinding          EventType = "finding"
+	EventFileAnalyzed     EventType = "file_analyzed"
+)
+
+// Event is a step of a scan in progress. Detector events and findings name their
+// detector, findings carry the result's Key and Value, file events the dependency
+// File relative to the project. A detector that failed finishes with its Err.
+type Event struct {
+	Type     EventType `json:"type"`
+	Detector string    `json:"detector,omitempty"`
+	Key      string    `json:"key,omitempty"`
+	Value    string    `json:"value,omitempty"`
+	File     string    `json:"file,omitempty"`
+	Err      error     `json:"-"`
+}
+
+// WithEvents calls handler at each step of a scan: detectors starting and
+// finishing, results they find and dependency files they analyze, so embedding
+// UIs can show progress and partial results before the report. Findings are keyed
+// as detected, before project settings rename them, and a later detector may
+// replace one with a more specific URL or min_evidence may drop it; the report is
+// final. The handler runs on the goroutine calling Scan and holds it up, hand
+// events to a buffered channel for slow consumers.
+func WithEvents(handler func(Event)) Option {
+	return func(s *Scanner) { s.events = handler }
+}
+
+// emit passes an event to the handler of WithEvents, if any
+func (s *Scanner) emit(event Event) {
+	if s.events != nil {
+		s.events(event)
+	}
+}
+
+// emitStarted reports a detector about to run
+func (s *Scanner) emitStarted(detector string) {
+	s.emit(Event{Type: EventDetectorStarted, Detector: detector})
+}
+
+// emitFinished reports the results of a detector, sorted by key, and that it's done
+func (s *Scanner) emitFinished(detector string, results map[string]string, err error) {
+	if s.events == nil {
+		return
+	}
+	keys := make([]string, 0, len(results))
+	for key := range results {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		s.events(Event{Type: EventFinding, Detector: detector, Key: key, Value: results[key]})
+	}
+	s.events(Event{Type: EventDetectorFinished, Detector: detector, Err: err})
+}
+
+// emitFile reports a dependency file about to be analyzed
+func (s *Scanner) emitFile(projectPath, file string) {
+	if s.events == nil {
+		return
+	}
+	if rel, err := filepath.Rel(projectPath, file); err == nil {
+		file = filepath.ToSlash(rel)
+	}
+	s.events(Event{Type: EventFileAnalyzed, Detector: ServicesDetector, File: file})
+}
@@ -0,0 +1,77 @@
+package parascan
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"parascan/detectors"
+)
+
+// Fingerprint identifies a project regardless of the machine or directory it's
+// checked out in: the SHA-256 of its repository URL and of the path and content of
+// every dependency file the stack knows, manifests and lockfiles alike. Clones of
+// the same commit, over https or ssh, get the same fingerprint, any dependency
+// change gives a new one. Line endings don't count, so a checkout with CRLF
+// conversion matches one without. Projects without a repository or dependency
+// files have none.
+func Fingerprint(projectPath, repoURL string, stack *StackDependencyFiles, filter *detectors.PathFilter, maxDepth int) string {
+	files := make(map[string]string) // relative path -> absolute path
+	if stack != nil {
+		dirs := ProjectDirs(projectPath, filter, maxDepth)
+		for _, language := range stack.Languages {
+			for _, manager := range language.PackageManagers {
+				for _, pattern := range manager.Files {
+					for _, match := range FindDependencyFiles(projectPath, dirs, pattern, filter) {
+						if rel, err := filepath.Rel(projectPath, match); err == nil {
+							files[filepath.ToSlash(rel)] = match
+						}
+					}
+				}
+			}
+		}
+	}
+	repo := canonicalRepoURL(repoURL)
+	if repo == "" && len(files) == 0 {
+		return ""
+	}
+
+	paths := make([]string, 0, len(files))
+	for rel := range files {
+		paths = append(paths, rel)
+	}
+	sort.Strings(paths)
+
+	hash := sha256.New()
+	fmt.Fprintf(hash, "repo %s\n", repo)
+	for _, rel := range paths {
+		content, err := os.ReadFile(files[rel])
+		if err != nil {
+			continue
+		}
+		fmt.Fprintf(hash, "%s %x\n", rel, sha256.Sum256(bytes.ReplaceAll(content, []byte("\r\n"), []byte("\n"))))
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// canonicalRepoURL reduces the ways of writing a repository's address to one, e.g.
+// git@github.com:Acme/shop.git and https://github.com/acme/shop/ to
+// github.com/acme/shop
+func canonicalRepoURL(repoURL string) string {
+	repo := strings.ToLower(strings.TrimSpace(repoURL))
+	if i := strings.Index(repo, "://"); i >= 0 {
+		repo = repo[i+3:]
+	} else if host, path, ok := strings.Cut(repo, ":"); ok && !strings.Contains(host, "/") {
+		// scp-like ssh address
+		repo = host + "/" + path
+	}
+	if i := strings.Index(repo, "@"); i >= 0 && i < strings.Index(repo+"/", "/") {
+		repo = repo[i+1:]
+	}
+	return strings.TrimSuffix(strings.TrimSuffix(repo, "/"), ".git")
+}
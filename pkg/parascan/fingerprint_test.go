@@ -0,0 +1,62 @@
+package parascan
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFingerprint(t *testing.T) {
+	catalog, err := EmbeddedCatalog()
+	if err != nil {
+		t.Fatalf("Failed to load catalog: %v", err)
+	}
+	clone := func(packageJSON string) string {
+		dir := t.TempDir()
+		for name, content := range map[string]string{
+			"package.json": packageJSON,
+			"api/go.mod":   "module github.com/acme/shop/api\n",
+			"README.md":    "# Shop\n",
+			"src/index.js": "console.log('hi')\n",
+		} {
+			path := filepath.Join(dir, filepath.FromSlash(name))
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return dir
+	}
+	fingerprint := func(dir, repoURL string) string {
+		return Fingerprint(dir, repoURL, catalog.Stack, nil, DefaultMaxDepth)
+	}
+
+	// Clones in other directories, over ssh or with CRLF line endings are the same project
+	base := fingerprint(clone("{\"name\": \"shop\"}\n"), "https://github.com/acme/shop")
+	if len(base) != 64 {
+		t.Fatalf("Expected a SHA-256 fingerprint, got %q", base)
+	}
+	if got := fingerprint(clone("{\"name\": \"shop\"}\r\n"), "git@github.com:Acme/shop.git"); got != base {
+		t.Errorf("Expected the same fingerprint for another clone, got %s and %s", base, got)
+	}
+	// Files other than dependency files don't count
+	other := clone("{\"name\": \"shop\"}\n")
+	if err := os.WriteFile(filepath.Join(other, "README.md"), []byte("# Shop, renamed\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got := fingerprint(other, "https://github.com/acme/shop.git/"); got != base {
+		t.Errorf("Expected the README not to change the fingerprint, got %s and %s", base, got)
+	}
+
+	if got := fingerprint(clone("{\"name\": \"shop\", \"dependencies\": {\"stripe\": \"^14.0.0\"}}\n"), "https://github.com/acme/shop"); got == base {
+		t.Error("Expected a dependency change to change the fingerprint")
+	}
+	if got := fingerprint(clone("{\"name\": \"shop\"}\n"), "https://github.com/someone/shop"); got == base {
+		t.Error("Expected another repository to change the fingerprint")
+	}
+	if got := fingerprint(t.TempDir(), ""); got != "" {
+		t.Errorf("Expected no fingerprint without repository and dependency files, got %s", got)
+	}
+}
@@ -0,0 +1,123 @@
+//go:build !slim || pack_ruby
+
+package parascan
+
+import (
+	"io"
+	"regexp"
+	"strings"
+)
+
+// The ruby language pack reads the Gemfile, Gemfile.lock and gemspecs
+func init() {
+	registerPack("ruby", map[string]dependencyParser{
+		"Gemfile":      gemfileDependencies,
+		"Gemfile.lock": gemLockDependencies,
+		"*.gemspec":    gemspecDependencies,
+	})
+}
+
+// gemLockDependencies reads the specs of a Gemfile.lock's GEM, GIT and PATH sections
+// and its DEPENDENCIES, the gems of the Gemfile. A wanted gem among the specs is
+// direct when the Gemfile lists it and indirect otherwise, e.g. stripe pulled in by
+// an internal gem from PATH.
+func gemLockDependencies(r io.Reader, wanted *packageSet, found map[string]bool) error {
+	specs := make(map[string]bool)
+	direct := make(map[string]bool)
+	section := ""
+
+	err := eachLine(r, func(line string) {
+		if strings.TrimSpace(line) == "" {
+			return
+		}
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+		if indent == 0 {
+			section = strings.TrimSpace(line)
+			return
+		}
+		name := strings.TrimSuffix(strings.Fields(line)[0], "!")
+
+		switch section {
+		case "GEM", "GIT", "PATH":
+			// Specs are indented by four spaces, their own dependencies by six
+			if indent == 4 && wanted.has(name) {
+				specs[name] = true
+			}
+		case "DEPENDENCIES":
+			if indent == 2 {
+				direct[name] = true
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	for name := range specs {
+		found[name] = direct[name]
+	}
+	for name := range direct {
+		if wanted.has(name) {
+			found[name] = true
+		}
+	}
+	return nil
+}
+
+var (
+	gemfileGroupBlock  = regexp.MustCompile(`^group\s*\(?(.+?)\)?\s+do\b`)
+	gemfileGroupOption = regexp.MustCompile(`(?:\bgroups?:|:groups?\s*=>)\s*(\[[^\]]*\]|:\w+|["']\w+["'])`)
+)
+
+// devGemGroups reports whether a list of Bundler groups, e.g. `:development, :test`,
+// has only development and test ones
+func devGemGroups(groups string) bool {
+	names := strings.FieldsFunc(groups, func(r rune) bool { return strings.ContainsRune(" ,[]:\"'%iw()", r) })
+	for _, name := range names {
+		if name != "development" && name != "test" {
+			return false
+		}
+	}
+	return len(names) > 0
+}
+
+// gemfileDependencies reads the gem declarations of a Gemfile. Gems of development
+// and test groups, in a group block or with a group: option, are for development
+// only.
+func gemfileDependencies(r io.Reader, wanted *packageSet, found map[string]bool) error {
+	var blocks []bool // enclosing do blocks, true for development groups
+	return eachLine(r, func(line string) {
+		line = strings.TrimSpace(line)
+		dev := len(blocks) > 0 && blocks[len(blocks)-1]
+		switch {
+		case line == "end":
+			if len(blocks) > 0 {
+				blocks = blocks[:len(blocks)-1]
+			}
+		case strings.HasPrefix(line, "group"):
+			if match := gemfileGroupBlock.FindStringSubmatch(line); match != nil {
+				blocks = append(blocks, dev || devGemGroups(match[1]))
+			}
+		case strings.HasPrefix(line, "gem "):
+			// Look for gem declarations: gem 'package-name' or gem "package-name"
+			if match := gemfileGroupOption.FindStringSubmatch(line); match != nil {
+				dev = dev || devGemGroups(match[1])
+			}
+			wanted.findQuotedScoped(line, dev, found)
+		case strings.HasSuffix(line, " do") || strings.Contains(line, " do |"):
+			// e.g. platforms or source blocks, in the group around them
+			blocks = append(blocks, dev)
+		}
+	})
+}
+
+// gemspecDependencies reads the dependency declarations of a gemspec,
+// add_development_dependency ones for development only
+func gemspecDependencies(r io.Reader, wanted *packageSet, found map[string]bool) error {
+	return eachLine(r, func(line string) {
+		// Look for dependency declarations
+		if strings.Contains(line, "add_dependency") || strings.Contains(line, "add_runtime_dependency") || strings.Contains(line, "add_development_dependency") {
+			wanted.findQuotedScoped(line, strings.Contains(line, "add_development_dependency"), found)
+		}
+	})
+}
@@ -0,0 +1,107 @@
+//go:build !slim || pack_go
+
+package parascan
+
+import (
+	"io"
+	"strconv"
+	"strings"
+)
+
+// The go language pack reads go.mod and go.sum
+func init() {
+	registerPack("go", map[string]dependencyParser{
+		"go.mod": goModDependencies,
+		"go.sum": goSumDependencies,
+	})
+}
+
+// markModule marks the wanted packages the module matches, direct ones win over
+// indirect. A module matched by a pattern is found under its own path.
+func markModule(module string, direct bool, wanted *packageSet, found map[string]bool) {
+	for pkg := range wanted.names {
+		if modulePathMatches(module, pkg) {
+			found[pkg] = found[pkg] || direct
+		}
+	}
+	if wanted.matchesPattern(module) {
+		found[module] = found[module] || direct
+	}
+}
+
+// goModDependencies reads the require and replace directives of a go.mod. Modules
+// marked // indirect are found as indirect. A replaced module is also found under
+// its replacement, e.g. a fork of an SDK; replacements by local directories aren't.
+func goModDependencies(r io.Reader, wanted *packageSet, found map[string]bool) error {
+	required := make(map[string]bool) // module path -> direct
+	replaced := make(map[string]string)
+	block := ""
+
+	err := eachLine(r, func(line string) {
+		line, comment, _ := strings.Cut(line, "//")
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			return
+		}
+		if block != "" && fields[0] == ")" {
+			block = ""
+			return
+		}
+
+		directive := block
+		if block == "" {
+			directive, fields = fields[0], fields[1:]
+			if len(fields) == 1 && fields[0] == "(" {
+				block = directive
+				return
+			}
+		}
+		if len(fields) == 0 {
+			return
+		}
+
+		switch directive {
+		case "require":
+			indirect := strings.HasPrefix(strings.TrimSpace(comment), "indirect")
+			module := unquoteModulePath(fields[0])
+			required[module] = required[module] || !indirect
+		case "replace":
+			old, replacement, ok := strings.Cut(strings.Join(fields, " "), "=>")
+			oldFields, newFields := strings.Fields(old), strings.Fields(replacement)
+			if !ok || len(oldFields) == 0 || len(newFields) == 0 {
+				return
+			}
+			if path := unquoteModulePath(newFields[0]); !strings.HasPrefix(path, ".") && !strings.HasPrefix(path, "/") {
+				replaced[unquoteModulePath(oldFields[0])] = path
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	for module, direct := range required {
+		markModule(module, direct, wanted, found)
+		if replacement, ok := replaced[module]; ok {
+			markModule(replacement, direct, wanted, found)
+		}
+	}
+	return nil
+}
+
+// goSumDependencies reads the modules of a go.sum. It lists the whole build graph
+// without telling direct dependencies apart, so they're all found as indirect.
+func goSumDependencies(r io.Reader, wanted *packageSet, found map[string]bool) error {
+	return eachLine(r, func(line string) {
+		if fields := strings.Fields(line); len(fields) == 3 {
+			markModule(fields[0], false, wanted, found)
+		}
+	})
+}
+
+func unquoteModulePath(path string) string {
+	if unquoted, err := strconv.Unquote(path); err == nil {
+		return unquoted
+	}
+	return path
+}
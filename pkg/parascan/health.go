@@ -0,0 +1,115 @@
+package parascan
+
+import (
+	"fmt"
+	"sort"
+
+	"gopkg.in/yaml.v2"
+)
+
+// ConfigCurrent is the config condition of a health criterion: the project's section
+// of parascope.yml lists every service the scan detects, at its URL
+const ConfigCurrent = "current"
+
+// Health is the content of health.yml, the rubric of the inventory completeness
+// score, criteria by id
+type Health struct {
+	Criteria map[string]HealthCriterion `yaml:"criteria"`
+}
+
+// HealthCriterion is worth its weight in points when the project meets one of its
+// conditions, e.g. an error tracker detected or parascope.yml up to date
+type HealthCriterion struct {
+	Description string   `yaml:"description"`
+	Weight      int      `yaml:"weight"`             // points, 0 turns a catalog criterion off from rules
+	Detected    []string `yaml:"detected,omitempty"` // service or file detector keys, one of them is detected
+	Category    string   `yaml:"category,omitempty"` // service or file detector category, e.g. ci
+	Config      string   `yaml:"config,omitempty"`   // current, see ConfigCurrent
+}
+
+// HealthScore is a project's inventory completeness: the points of the criteria it
+// meets out of all points, from 0 to 100 rounded down
+type HealthScore struct {
+	Score    int           `json:"score"`
+	Criteria []HealthCheck `json:"criteria"` // by id
+}
+
+// HealthCheck is how a project fares on a criterion of the rubric
+type HealthCheck struct {
+	ID          string `json:"id"`
+	Description string `json:"description"`
+	Weight      int    `json:"weight"`
+	Met         bool   `json:"met"`
+}
+
+// ParseHealth parses health.yml, rejecting criteria without a description or a
+// condition and negative weights
+func ParseHealth(content []byte) (*Health, error) {
+	var health Health
+	if err := yaml.UnmarshalStrict(content, &health); err != nil {
+		return nil, err
+	}
+	for id, criterion := range health.Criteria {
+		switch {
+		case criterion.Weight < 0:
+			return nil, fmt.Errorf("criterion %s: negative weight %d", id, criterion.Weight)
+		case criterion.Weight == 0:
+			continue
+		case criterion.Description == "":
+			return nil, fmt.Errorf("criterion %s has no description", id)
+		case criterion.Config != "" && criterion.Config != ConfigCurrent:
+			return nil, fmt.Errorf("criterion %s: unknown config %q, expected %s", id, criterion.Config, ConfigCurrent)
+		case len(criterion.Detected) == 0 && criterion.Category == "" && criterion.Config == "":
+			return nil, fmt.Errorf("criterion %s has no condition", id)
+		}
+	}
+	return &health, nil
+}
+
+// ScoreHealth scores a project's results against the catalog's rubric. configCurrent
+// tells whether its parascope.yml lists everything the scan detects. It returns nil
+// without a rubric or criteria worth points.
+func ScoreHealth(catalog *Catalog, results map[string]string, configCurrent bool) *HealthScore {
+	if catalog.Health == nil {
+		return nil
+	}
+	ids := make([]string, 0, len(catalog.Health.Criteria))
+	for id := range catalog.Health.Criteria {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	score := &HealthScore{}
+	total, met := 0, 0
+	for _, id := range ids {
+		criterion := catalog.Health.Criteria[id]
+		if criterion.Weight <= 0 {
+			continue
+		}
+		result := HealthCheck{ID: id, Description: criterion.Description, Weight: criterion.Weight}
+		result.Met = criterion.Config == ConfigCurrent && configCurrent
+		for key := range results {
+			if result.Met {
+				break
+			}
+			if criterion.Category != "" && resultCategory(catalog, key) == criterion.Category {
+				result.Met = true
+			}
+			for _, name := range criterion.Detected {
+				if resultIs(catalog, key, name) {
+					result.Met = true
+				}
+			}
+		}
+		total += criterion.Weight
+		if result.Met {
+			met += criterion.Weight
+		}
+		score.Criteria = append(score.Criteria, result)
+	}
+	if total == 0 {
+		return nil
+	}
+	score.Score = met * 100 / total
+	return score
+}
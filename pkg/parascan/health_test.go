@@ -0,0 +1,64 @@
+package parascan
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestScoreHealth(t *testing.T) {
+	catalog, err := EmbeddedCatalog()
+	if err != nil {
+		t.Fatalf("Failed to load catalog: %v", err)
+	}
+	results := map[string]string{
+		"repo":           "https://github.com/acme/billing",
+		"GitHub Actions": "https://github.com/acme/billing/actions",
+		"rollbar":        "https://rollbar.com",
+	}
+	score := ScoreHealth(catalog, results, false)
+	if score == nil || score.Score != 60 {
+		t.Fatalf("Expected 60 points for the repository, CI and error tracking, got %+v", score)
+	}
+	met := make(map[string]bool)
+	for _, check := range score.Criteria {
+		met[check.ID] = check.Met
+	}
+	if !met["repository"] || !met["ci"] || !met["error-tracking"] || met["docs"] || met["config-current"] {
+		t.Errorf("Expected the docs and config criteria missed, got %+v", score.Criteria)
+	}
+	if score := ScoreHealth(catalog, results, true); score.Score != 80 {
+		t.Errorf("Expected 80 points with a current config, got %d", score.Score)
+	}
+
+	// Rules turn criteria off and weigh their own
+	rules := fstest.MapFS{"health.yml": {Data: []byte(`criteria:
+  docs:
+    weight: 0
+  payments:
+    description: Payments go through a provider
+    weight: 40
+    detected: [stripe, paddle]
+`)}}
+	merged, err := catalog.WithRules(rules, "rules")
+	if err != nil {
+		t.Fatalf("Failed to merge rules: %v", err)
+	}
+	if score := ScoreHealth(merged, results, false); score.Score != 50 || len(score.Criteria) != 5 {
+		t.Errorf("Expected 60 of 120 points without docs, got %+v", score)
+	}
+	if score := ScoreHealth(&Catalog{}, results, true); score != nil {
+		t.Errorf("Expected no score without a rubric, got %+v", score)
+	}
+
+	for _, content := range []string{
+		"criteria:\n  a:\n    weight: 10\n    detected: [sentry]\n",
+		"criteria:\n  a:\n    description: d\n    weight: 10\n",
+		"criteria:\n  a:\n    description: d\n    weight: -5\n    category: ci\n",
+		"criteria:\n  a:\n    description: d\n    weight: 10\n    config: fresh\n",
+		"criteria:\n  a:\n    description: d\n    weight: 10\n    detects: [sentry]\n",
+	} {
+		if _, err := ParseHealth([]byte(content)); err == nil {
+			t.Errorf("Expected an error for %q", content)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package parascan
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// HelmDetector is the name of the Helm chart detector in settings and errors
+const HelmDetector = "helm"
+
+// HelmCharts is what the project's Helm charts tell about its deployment
+type HelmCharts struct {
+	Charts       []string         `json:"charts"` // Chart.yaml files, relative to the project
+	Dependencies []HelmDependency `json:"dependencies,omitempty"`
+	Images       []string         `json:"images,omitempty"` // from values.yaml, e.g. ghcr.io/acme/api:1.4.2
+}
+
+// HelmDependency is a chart a chart depends on
+type HelmDependency struct {
+	Name       string `json:"name"`
+	Repository string `json:"repository,omitempty"` // e.g. https://charts.bitnami.com/bitnami or oci://...
+	Version    string `json:"version,omitempty"`
+}
+
+// chartFile is what is read of a Chart.yaml, or of the requirements.yaml of
+// apiVersion v1 charts
+type chartFile struct {
+	Dependencies []HelmDependency `yaml:"dependencies"`
+}
+
+// findCharts lists the Chart.yaml files of the project directories, e.g. the root,
+// chart/ and charts/api/. Subcharts vendored in a chart's charts/ are included.
+func findCharts(projectPath string, dirs []string) []string {
+	var charts []string
+	for _, dir := range dirs {
+		path := filepath.Join(projectPath, dir, "Chart.yaml")
+		if _, err := os.Stat(path); err == nil {
+			charts = append(charts, path)
+		}
+	}
+	sort.Strings(charts)
+	return charts
+}
+
+// readChartDependencies returns the dependencies of a chart from its Chart.yaml and
+// requirements.yaml
+func readChartDependencies(chartPath string) []HelmDependency {
+	var dependencies []HelmDependency
+	for _, name := range []string{"Chart.yaml", "requirements.yaml"} {
+		content, err := os.ReadFile(filepath.Join(filepath.Dir(chartPath), name))
+		if err != nil {
+			continue
+		}
+		var chart chartFile
+		if err := yaml.Unmarshal(content, &chart); err != nil {
+			continue
+		}
+		for _, dependency := range chart.Dependencies {
+			if dependency.Name != "" {
+				dependencies = append(dependencies, dependency)
+			}
+		}
+	}
+	return dependencies
+}
+
+// readValuesImages returns the images a chart's values.yaml sets, anywhere in it:
+// image: repo:tag strings and image maps with registry, repository and tag
+func readValuesImages(chartPath string) []string {
+	content, err := os.ReadFile(filepath.Join(filepath.Dir(chartPath), "values.yaml"))
+	if err != nil {
+		return nil
+	}
+	var values interface{}
+	if err := yaml.Unmarshal(content, &values); err != nil {
+		return nil
+	}
+	var images []string
+	var walk func(value interface{})
+	walk = func(value interface{}) {
+		switch v := value.(type) {
+		case map[interface{}]interface{}:
+			for key, child := range v {
+				if key == "image" {
+					if image := valuesImage(child); image != "" {
+						images = append(images, image)
+						continue
+					}
+				}
+				walk(child)
+			}
+		case []interface{}:
+			for _, child := range v {
+				walk(child)
+			}
+		}
+	}
+	walk(values)
+	return images
+}
+
+// valuesImage returns the image an image value of values.yaml names, "" for values
+// that are no image or leave the repository to templates
+func valuesImage(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		if v != "" && !strings.ContainsAny(v, "{} ") {
+			return v
+		}
+	case map[interface{}]interface{}:
+		repository, _ := v["repository"].(string)
+		if repository == "" || strings.ContainsAny(repository, "{} ") {
+			return ""
+		}
+		if registry, _ := v["registry"].(string); registry != "" {
+			repository = registry + "/" + repository
+		}
+		// Tags such as 16 are numbers in YAML
+		if tag, ok := v["tag"]; ok && tag != nil && tag != "" {
+			repository += ":" + fmt.Sprint(tag)
+		}
+		return repository
+	}
+	return ""
+}
+
+// helmServices returns the catalog services whose helm charts include a chart
+// dependency, e.g. redis for bitnami's redis chart
+func helmServices(dependency HelmDependency, servicesData map[string]*ServiceData) []string {
+	var keys []string
+	for key, service := range servicesData {
+		for _, chart := range service.Helm {
+			if strings.EqualFold(chart, dependency.Name) {
+				keys = append(keys, key)
+				break
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// detectHelmCharts reads the project's charts for their dependencies and images and
+// finds services among the dependencies. Charts that don't parse are skipped.
+func (a *ServicesAdapter) detectHelmCharts(projectPath string) (map[string]string, *HelmCharts) {
+	results := make(map[string]string)
+	charts := findCharts(projectPath, ProjectDirs(projectPath, a.filter, a.maxDepth))
+	if len(charts) == 0 {
+		return results, nil
+	}
+
+	found := &HelmCharts{}
+	seenDependencies := make(map[HelmDependency]bool)
+	seenImages := make(map[string]bool)
+	for _, chart := range charts {
+		if rel, err := filepath.Rel(projectPath, chart); err == nil {
+			found.Charts = append(found.Charts, filepath.ToSlash(rel))
+		}
+		for _, dependency := range readChartDependencies(chart) {
+			if !seenDependencies[dependency] {
+				seenDependencies[dependency] = true
+				found.Dependencies = append(found.Dependencies, dependency)
+			}
+			for _, key := range helmServices(dependency, a.servicesData) {
+				results[key] = a.serviceURL(key, a.servicesData[key])
+				a.addEvidence(projectPath, key, ServiceEvidence{Detector: HelmDetector, Chart: dependency.Name, File: chart})
+			}
+		}
+		for _, image := range readValuesImages(chart) {
+			if !seenImages[image] {
+				seenImages[image] = true
+				found.Images = append(found.Images, image)
+			}
+		}
+	}
+	sort.Slice(found.Dependencies, func(i, j int) bool { return found.Dependencies[i].Name < found.Dependencies[j].Name })
+	sort.Strings(found.Images)
+	return results, found
+}
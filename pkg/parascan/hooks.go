@@ -0,0 +1,32 @@
+package parascan
+
+import "context"
+
+// HookDetector names the failures of hooks in a report's Errors
+const HookDetector = "hook"
+
+// Hook post-processes a finished scan, e.g. to create tickets or update a catalog
+type Hook func(ctx context.Context, report *Report) error
+
+// WithHook runs hook once a scan is complete, with the final report. Hooks run in
+// the order they were added on the goroutine calling Scan. A failing hook doesn't
+// stop the others or the scan: its error is recorded in the report's Errors under
+// HookDetector.
+func WithHook(hook Hook) Option {
+	return func(s *Scanner) { s.hooks = append(s.hooks, hook) }
+}
+
+// RunHooks runs every hook with the report, collecting failures instead of stopping
+// at the first one. Hooks after a cancelled context are left out.
+func RunHooks(ctx context.Context, report *Report, hooks ...Hook) []error {
+	var errs []error
+	for _, hook := range hooks {
+		if err := ctx.Err(); err != nil {
+			return append(errs, err)
+		}
+		if err := hook(ctx, report); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
@@ -0,0 +1,59 @@
+package parascan
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestScannerHooks(t *testing.T) {
+	projectPath := t.TempDir()
+	if err := os.WriteFile(filepath.Join(projectPath, "netlify.toml"), []byte("[build]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var order []string
+	var seen map[string]string
+	scanner, err := New(WithoutGit(),
+		WithHook(func(ctx context.Context, report *Report) error {
+			order = append(order, "tickets")
+			seen = report.Results
+			return errors.New("ticket tracker unavailable")
+		}),
+		WithHook(func(ctx context.Context, report *Report) error {
+			order = append(order, "catalog")
+			return nil
+		}))
+	if err != nil {
+		t.Fatalf("Failed to create scanner: %v", err)
+	}
+
+	// Hooks see the final report in order, a failing one is recorded and the
+	// next still runs
+	report, err := scanner.Scan(context.Background(), projectPath)
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if len(order) != 2 || order[0] != "tickets" || order[1] != "catalog" {
+		t.Errorf("Expected both hooks in order, got %v", order)
+	}
+	if seen["Netlify"] == "" {
+		t.Errorf("Expected the hook to see Netlify, got %v", seen)
+	}
+	if len(report.Errors) != 1 || report.Errors[0].Detector != HookDetector || report.Errors[0].Err.Error() != "ticket tracker unavailable" {
+		t.Errorf("Expected the failing hook in the errors, got %v", report.Errors)
+	}
+
+	// Nothing runs after the context is cancelled
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	order = nil
+	if errs := RunHooks(ctx, report, func(context.Context, *Report) error {
+		order = append(order, "late")
+		return nil
+	}); len(errs) != 1 || len(order) != 0 {
+		t.Errorf("Expected the cancelled context and no hook run, got %v %v", errs, order)
+	}
+}
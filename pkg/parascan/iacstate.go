@@ -0,0 +1,132 @@
+package parascan
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// IaCStateDetector is the name of the opt-in IaC state detector in settings and errors
+const IaCStateDetector = "iac_state"
+
+// stateResource is all that is ever decoded from a state file. State holds secrets
+// (passwords, keys, connection strings), which stay in the attributes, inputs and
+// outputs the decoder skips.
+type stateResource struct {
+	Type     string `json:"type"`
+	Provider string `json:"provider"`
+}
+
+// stateFile holds the resources of Terraform state (resources), a Pulumi local
+// backend checkpoint (checkpoint.latest.resources) or `pulumi stack export`
+// (deployment.resources)
+type stateFile struct {
+	Resources  []stateResource `json:"resources"`
+	Checkpoint struct {
+		Latest struct {
+			Resources []stateResource `json:"resources"`
+		} `json:"latest"`
+	} `json:"checkpoint"`
+	Deployment struct {
+		Resources []stateResource `json:"resources"`
+	} `json:"deployment"`
+}
+
+// State file patterns in every project directory: Terraform state, its workspaces,
+// and the stacks of Pulumi's local backend
+var stateFilePatterns = []string{
+	"*.tfstate",
+	"terraform.tfstate.d/*/terraform.tfstate",
+	".pulumi/stacks/*.json",
+	".pulumi/stacks/*/*.json",
+}
+
+// findStateFiles lists the state files of the project directories
+func findStateFiles(projectPath string, dirs []string) []string {
+	var files []string
+	for _, dir := range dirs {
+		for _, pattern := range stateFilePatterns {
+			matches, _ := filepath.Glob(filepath.Join(projectPath, dir, filepath.FromSlash(pattern)))
+			files = append(files, matches...)
+		}
+	}
+	sort.Strings(files)
+	return files
+}
+
+// readStateResources decodes the resource types and providers of a state file
+func readStateResources(path string) ([]stateResource, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var state stateFile
+	if err := json.NewDecoder(file).Decode(&state); err != nil {
+		return nil, err
+	}
+	resources := append(state.Resources, state.Checkpoint.Latest.Resources...)
+	return append(resources, state.Deployment.Resources...), nil
+}
+
+// Provider names in state: provider["registry.terraform.io/hashicorp/aws"] or
+// provider.aws in Terraform, urn:pulumi:stack::project::pulumi:providers:aws::name in Pulumi
+var (
+	terraformProvider = regexp.MustCompile(`^provider(?:\["(?:[^"]*/)?([\w-]+)"\]|\.([\w-]+))`)
+	pulumiProvider    = regexp.MustCompile(`::pulumi:providers:([\w-]+)::`)
+)
+
+// stateProviderName returns the provider's short name, e.g. aws
+func stateProviderName(provider string) string {
+	if match := terraformProvider.FindStringSubmatch(provider); match != nil {
+		return match[1] + match[2]
+	}
+	if match := pulumiProvider.FindStringSubmatch(provider); match != nil {
+		return match[1]
+	}
+	return ""
+}
+
+// stateServices maps a resource to the catalog services whose iac prefixes match
+// its type or, for Pulumi provider resources and resources without a known type,
+// its provider
+func stateServices(resource stateResource, servicesData map[string]*ServiceData) []string {
+	provider := stateProviderName(resource.Provider)
+	var keys []string
+	for key, service := range servicesData {
+		for _, prefix := range service.IaC {
+			if strings.HasPrefix(resource.Type, prefix) || provider != "" && (prefix == provider+"_" || prefix == provider+":") {
+				keys = append(keys, key)
+				break
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// detectIaCState finds services among the resources of the project's state files.
+// Unreadable or malformed state files are skipped: they're optional input.
+func (a *ServicesAdapter) detectIaCState(projectPath string) map[string]string {
+	results := make(map[string]string)
+	for _, path := range findStateFiles(projectPath, ProjectDirs(projectPath, a.filter, a.maxDepth)) {
+		if a.filter.SkipPath(projectPath, path) {
+			continue
+		}
+		resources, err := readStateResources(path)
+		if err != nil {
+			continue
+		}
+		for _, resource := range resources {
+			for _, key := range stateServices(resource, a.servicesData) {
+				results[key] = a.serviceURL(key, a.servicesData[key])
+				a.addEvidence(projectPath, key, ServiceEvidence{Detector: IaCStateDetector, Resource: resource.Type, File: path})
+			}
+		}
+	}
+	return results
+}
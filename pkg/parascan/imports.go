@@ -0,0 +1,168 @@
+package parascan
+
+import (
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DeepDetector is the name of the opt-in source import scanning in settings
+const DeepDetector = "deep"
+
+// Source files larger than this are generated or bundled, not written by hand
+const maxSourceFileSize = 1 << 20
+
+// Languages of the source files whose imports are read
+var importLanguages = map[string]string{
+	".go":  "go",
+	".py":  "python",
+	".js":  "nodejs",
+	".jsx": "nodejs",
+	".mjs": "nodejs",
+	".cjs": "nodejs",
+	".ts":  "nodejs",
+	".tsx": "nodejs",
+	".mts": "nodejs",
+	".cts": "nodejs",
+}
+
+var (
+	// import a.b, c as d and from a.b import c; relative imports start with a dot
+	pythonImport     = regexp.MustCompile(`(?m)^[ \t]*import[ \t]+([\w.]+(?:[ \t]+as[ \t]+\w+)?(?:[ \t]*,[ \t]*[\w.]+(?:[ \t]+as[ \t]+\w+)?)*)`)
+	pythonFromImport = regexp.MustCompile(`(?m)^[ \t]*from[ \t]+([\w.]+)[ \t]+import\b`)
+	// import x from 'a', import 'a', export * from 'a', require('a') and import('a')
+	jsImport = regexp.MustCompile(`(?:\bfrom|\bimport|\brequire\s*\(|\bimport\s*\()\s*["']([^"'\s]+)["']`)
+)
+
+// sourceImports returns what a source file imports: Go import paths, Python
+// module paths and JavaScript module specifiers
+func sourceImports(path, language string, content []byte) []string {
+	var imports []string
+	switch language {
+	case "go":
+		file, err := parser.ParseFile(token.NewFileSet(), path, content, parser.ImportsOnly)
+		if err != nil {
+			return nil
+		}
+		for _, spec := range file.Imports {
+			if value, err := strconv.Unquote(spec.Path.Value); err == nil {
+				imports = append(imports, value)
+			}
+		}
+	case "python":
+		for _, match := range pythonImport.FindAllSubmatch(content, -1) {
+			for _, module := range strings.Split(string(match[1]), ",") {
+				if fields := strings.Fields(module); len(fields) > 0 {
+					imports = append(imports, fields[0])
+				}
+			}
+		}
+		for _, match := range pythonFromImport.FindAllSubmatch(content, -1) {
+			imports = append(imports, string(match[1]))
+		}
+	case "nodejs":
+		for _, match := range jsImport.FindAllSubmatch(content, -1) {
+			imports = append(imports, string(match[1]))
+		}
+	}
+	return imports
+}
+
+// importPackages returns the package names an import can come from: the module
+// path and its parents in Python (google.cloud.storage is google-cloud-storage),
+// the package of a JavaScript specifier (@sentry/node/tracing is @sentry/node) and
+// the import path itself in Go, whose modules match by prefix
+func importPackages(language, imported string) []string {
+	switch language {
+	case "python":
+		var packages []string
+		parts := strings.Split(imported, ".")
+		for i := len(parts); i > 0; i-- {
+			packages = append(packages, strings.Join(parts[:i], "."))
+		}
+		return packages
+	case "nodejs":
+		if strings.HasPrefix(imported, ".") || strings.HasPrefix(imported, "/") || strings.HasPrefix(imported, "node:") {
+			return nil
+		}
+		parts := strings.Split(imported, "/")
+		if strings.HasPrefix(imported, "@") && len(parts) > 1 {
+			return []string{parts[0] + "/" + parts[1]}
+		}
+		return []string{parts[0]}
+	}
+	return []string{imported}
+}
+
+// importServices returns the catalog services a package of the language belongs
+// to by key, with the catalog's name of the package
+func importServices(language, pkg string, servicesData map[string]*ServiceData) map[string]string {
+	services := make(map[string]string)
+	for key, service := range servicesData {
+		for _, candidate := range service.Stacks[language] {
+			if packageMatches(language, candidate, pkg) {
+				services[key] = candidate
+				if strings.HasSuffix(candidate, "*") {
+					services[key] = pkg
+				}
+				break
+			}
+		}
+	}
+	return services
+}
+
+// detectImports finds services among the imports of the project's Go, Python and
+// JavaScript/TypeScript source files, catching SDKs that are vendored or installed
+// globally rather than listed in dependency files. Each package is evidence once,
+// from the first file importing it.
+func (a *ServicesAdapter) detectImports(projectPath string) map[string]string {
+	results := make(map[string]string)
+	seen := make(map[string]bool) // service and package
+	var files []string
+	filepath.WalkDir(projectPath, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || path == projectPath {
+			return nil
+		}
+		if entry.IsDir() {
+			if SkipWalkDir(entry.Name()) || a.filter.SkipPath(projectPath, path) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if _, ok := importLanguages[filepath.Ext(path)]; ok && !strings.Contains(entry.Name(), ".min.") && !a.filter.SkipPath(projectPath, path) {
+			files = append(files, path)
+		}
+		return nil
+	})
+	sort.Strings(files)
+
+	for _, path := range files {
+		if info, err := os.Stat(path); err != nil || info.Size() > maxSourceFileSize {
+			continue
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		language := importLanguages[filepath.Ext(path)]
+		for _, imported := range sourceImports(path, language, content) {
+			for _, pkg := range importPackages(language, imported) {
+				for key, name := range importServices(language, pkg, a.servicesData) {
+					results[key] = a.serviceURL(key, a.servicesData[key])
+					if !seen[key+"\x00"+name] {
+						seen[key+"\x00"+name] = true
+						a.addEvidence(projectPath, key, ServiceEvidence{Detector: DeepDetector, Language: language, Package: name, Import: imported, File: path})
+					}
+				}
+			}
+		}
+	}
+	return results
+}
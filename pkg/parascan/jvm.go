@@ -0,0 +1,191 @@
+//go:build !slim || pack_jvm
+
+package parascan
+
+import (
+	"encoding/xml"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// The jvm language pack reads Maven and Gradle builds
+func init() {
+	registerPack("jvm", map[string]dependencyParser{
+		"pom.xml":          pomDependencies,
+		"build.gradle":     gradleDependencies,
+		"build.gradle.kts": gradleDependencies,
+	})
+}
+
+// pomDependency is a dependency of a pom.xml, its coordinates possibly ${properties}
+type pomDependency struct {
+	GroupID    string `xml:"groupId"`
+	ArtifactID string `xml:"artifactId"`
+	Scope      string `xml:"scope"`
+}
+
+// pomProperties collects the elements of a <properties> section by name
+type pomProperties map[string]string
+
+func (p *pomProperties) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	if *p == nil {
+		*p = make(pomProperties)
+	}
+	for {
+		token, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			var value string
+			if err := d.DecodeElement(&value, &t); err != nil {
+				return err
+			}
+			(*p)[t.Name.Local] = strings.TrimSpace(value)
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+// pomProject is what is read of a pom.xml: the project's own coordinates and
+// properties for interpolation, its dependencies and those of its profiles
+type pomProject struct {
+	GroupID string `xml:"groupId"`
+	Version string `xml:"version"`
+	Parent  struct {
+		GroupID string `xml:"groupId"`
+		Version string `xml:"version"`
+	} `xml:"parent"`
+	Properties   pomProperties   `xml:"properties"`
+	Dependencies []pomDependency `xml:"dependencies>dependency"`
+	Managed      []pomDependency `xml:"dependencyManagement>dependencies>dependency"`
+	Profiles     []struct {
+		Properties   pomProperties   `xml:"properties"`
+		Dependencies []pomDependency `xml:"dependencies>dependency"`
+	} `xml:"profiles>profile"`
+}
+
+var pomProperty = regexp.MustCompile(`\$\{([^}]+)\}`)
+
+// interpolate replaces the ${properties} of a value. Properties may refer to other
+// properties; unknown ones are left as they are.
+func (p pomProperties) interpolate(value string) string {
+	for i := 0; i < 8 && strings.Contains(value, "${"); i++ {
+		replaced := pomProperty.ReplaceAllStringFunc(value, func(ref string) string {
+			if v, ok := p[ref[2:len(ref)-1]]; ok {
+				return v
+			}
+			return ref
+		})
+		if replaced == value {
+			break
+		}
+		value = replaced
+	}
+	return value
+}
+
+// pomDependencies reads the dependencies of a pom.xml as groupId:artifactId, e.g.
+// com.stripe:stripe-java, with ${properties} in their coordinates interpolated from
+// the project's and its profiles' properties; versions don't matter. Entries of
+// dependencyManagement only pin versions, e.g. BOMs, and count as indirect. Those of
+// the test scope are for development only.
+func pomDependencies(r io.Reader, wanted *packageSet, found map[string]bool) error {
+	var project pomProject
+	if err := xml.NewDecoder(r).Decode(&project); err != nil {
+		return err
+	}
+
+	properties := pomProperties{
+		"project.groupId":        project.GroupID,
+		"project.version":        project.Version,
+		"project.parent.groupId": project.Parent.GroupID,
+		"project.parent.version": project.Parent.Version,
+	}
+	if project.GroupID == "" {
+		// A module inherits its parent's groupId
+		properties["project.groupId"] = project.Parent.GroupID
+	}
+	if project.Version == "" {
+		properties["project.version"] = project.Parent.Version
+	}
+	for name, value := range project.Properties {
+		properties[name] = value
+	}
+	dependencies := project.Dependencies
+	for _, profile := range project.Profiles {
+		for name, value := range profile.Properties {
+			if _, ok := properties[name]; !ok {
+				properties[name] = value
+			}
+		}
+		dependencies = append(dependencies, profile.Dependencies...)
+	}
+
+	mark := func(dependencies []pomDependency, direct bool) {
+		for _, dependency := range dependencies {
+			name := properties.interpolate(strings.TrimSpace(dependency.GroupID)) + ":" + properties.interpolate(strings.TrimSpace(dependency.ArtifactID))
+			if wanted.has(name) {
+				found[name] = found[name] || direct
+				if direct {
+					wanted.setScope(name, strings.TrimSpace(dependency.Scope) == "test")
+				}
+			}
+		}
+	}
+	mark(dependencies, true)
+	mark(project.Managed, false)
+	return nil
+}
+
+// Dependency notations of Gradle build files, Groovy or Kotlin: "group:name:version"
+// strings and group/name maps or named arguments
+var (
+	gradleQuoted     = regexp.MustCompile(`["']([^"'\s]+)["']`)
+	gradleGroupName  = regexp.MustCompile(`\bgroup\s*[:=]\s*["']([^"']+)["']\s*,\s*name\s*[:=]\s*["']([^"']+)["']`)
+	gradleDepsHeader = regexp.MustCompile(`^\s*dependencies\s*(\{|$)`)
+	gradleComment    = regexp.MustCompile(`(^|\s)//.*$`)
+	gradleTestConfig = regexp.MustCompile(`^\s*"?(test|androidTest|testFixtures|integrationTest)\w*"?\s*[( '"]`)
+)
+
+// gradleDependencies reads the dependencies blocks of a build.gradle or
+// build.gradle.kts, including the buildscript's, without evaluating the script:
+// implementation 'com.stripe:stripe-java:24.0.0', implementation("com.stripe:stripe-java:$v")
+// and group: 'com.stripe', name: 'stripe-java' are all com.stripe:stripe-java.
+// Dependencies declared through variables or version catalogs aren't found. Those of
+// test configurations, e.g. testImplementation, are for development only.
+func gradleDependencies(r io.Reader, wanted *packageSet, found map[string]bool) error {
+	depth, blockDepth := 0, -1
+
+	return eachLine(r, func(line string) {
+		line = gradleComment.ReplaceAllString(line, "")
+		if blockDepth < 0 && gradleDepsHeader.MatchString(line) {
+			blockDepth = depth
+		}
+		if blockDepth >= 0 {
+			dev := gradleTestConfig.MatchString(line)
+			for _, match := range gradleGroupName.FindAllStringSubmatch(line, -1) {
+				if name := match[1] + ":" + match[2]; wanted.has(name) {
+					found[name] = true
+					wanted.setScope(name, dev)
+				}
+			}
+			for _, match := range gradleQuoted.FindAllStringSubmatch(line, -1) {
+				if parts := strings.Split(match[1], ":"); len(parts) >= 2 {
+					if name := parts[0] + ":" + parts[1]; wanted.has(name) {
+						found[name] = true
+						wanted.setScope(name, dev)
+					}
+				}
+			}
+		}
+
+		depth += strings.Count(line, "{") - strings.Count(line, "}")
+		if blockDepth >= 0 && depth <= blockDepth && strings.Contains(line, "}") {
+			blockDepth = -1
+		}
+	})
+}
@@ -0,0 +1,217 @@
+package parascan
+
+import (
+	"bytes"
+	"context"
+	"io/fs"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"parascan/detectors"
+)
+
+// LanguageStatsDetector is the name of the opt-in language statistics in settings
+const LanguageStatsDetector = "language_stats"
+
+// LanguageShare is a language's part of the project's source code, by size
+type LanguageShare struct {
+	Language string  `json:"language"` // e.g. Go or TypeScript
+	Bytes    int64   `json:"bytes"`
+	Percent  float64 `json:"percent"` // rounded to one decimal
+}
+
+// Languages of source files by extension, named like GitHub's linguist. Data,
+// prose and config formats (JSON, YAML, Markdown, SQL) aren't counted.
+var languageExtensions = map[string]string{
+	".go":     "Go",
+	".ts":     "TypeScript",
+	".tsx":    "TypeScript",
+	".mts":    "TypeScript",
+	".cts":    "TypeScript",
+	".js":     "JavaScript",
+	".jsx":    "JavaScript",
+	".mjs":    "JavaScript",
+	".cjs":    "JavaScript",
+	".py":     "Python",
+	".rb":     "Ruby",
+	".java":   "Java",
+	".kt":     "Kotlin",
+	".kts":    "Kotlin",
+	".scala":  "Scala",
+	".groovy": "Groovy",
+	".cs":     "C#",
+	".fs":     "F#",
+	".vb":     "Visual Basic .NET",
+	".php":    "PHP",
+	".rs":     "Rust",
+	".c":      "C",
+	".h":      "C",
+	".cc":     "C++",
+	".cpp":    "C++",
+	".cxx":    "C++",
+	".hh":     "C++",
+	".hpp":    "C++",
+	".m":      "Objective-C",
+	".swift":  "Swift",
+	".dart":   "Dart",
+	".ex":     "Elixir",
+	".exs":    "Elixir",
+	".erl":    "Erlang",
+	".hs":     "Haskell",
+	".clj":    "Clojure",
+	".lua":    "Lua",
+	".r":      "R",
+	".pl":     "Perl",
+	".sh":     "Shell",
+	".bash":   "Shell",
+	".zsh":    "Shell",
+	".ps1":    "PowerShell",
+	".html":   "HTML",
+	".htm":    "HTML",
+	".css":    "CSS",
+	".scss":   "SCSS",
+	".sass":   "Sass",
+	".less":   "Less",
+	".vue":    "Vue",
+	".svelte": "Svelte",
+	".tf":     "HCL",
+	".hcl":    "HCL",
+	".zig":    "Zig",
+	".jl":     "Julia",
+	".ml":     "OCaml",
+}
+
+// Languages of files known by name rather than extension
+var languageFileNames = map[string]string{
+	"Dockerfile":  "Dockerfile",
+	"Makefile":    "Makefile",
+	"GNUmakefile": "Makefile",
+}
+
+// fileLanguage returns the language of a source file, "" for other files. Minified
+// bundles are build output.
+func fileLanguage(path string) string {
+	name := filepath.Base(path)
+	if language, ok := languageFileNames[name]; ok {
+		return language
+	}
+	if strings.Contains(name, ".min.") {
+		return ""
+	}
+	return languageExtensions[strings.ToLower(filepath.Ext(name))]
+}
+
+// LanguageStats measures the share of each language in the project's source files,
+// largest first, like GitHub's language bar. The files are those git tracks, or
+// every file for projects that aren't repositories; vendored, hidden and ignored
+// paths don't count. Counting stops at the deadline unless it's zero, the shares are
+// then those of the files counted so far and complete is false.
+func LanguageStats(projectPath string, filter *detectors.PathFilter, deadline time.Time) (shares []LanguageShare, complete bool) {
+	sizes := make(map[string]int64)
+	complete = true
+	count := func(rel string) bool {
+		if !deadline.IsZero() && time.Now().After(deadline) {
+			complete = false
+			return false
+		}
+		language := fileLanguage(rel)
+		if language == "" || vendoredPath(rel) {
+			return true
+		}
+		path := filepath.Join(projectPath, filepath.FromSlash(rel))
+		if filter.SkipPath(projectPath, path) {
+			return true
+		}
+		if info, err := os.Lstat(path); err == nil && info.Mode().IsRegular() {
+			sizes[language] += info.Size()
+		}
+		return true
+	}
+
+	files, err := trackedFiles(projectPath, deadline)
+	if err != nil {
+		// Not a repository, or no git: every file counts
+		filepath.WalkDir(projectPath, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil || path == projectPath {
+				return nil
+			}
+			if entry.IsDir() {
+				if SkipWalkDir(entry.Name()) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			rel, err := filepath.Rel(projectPath, path)
+			if err != nil {
+				return nil
+			}
+			if !count(filepath.ToSlash(rel)) {
+				return filepath.SkipAll
+			}
+			return nil
+		})
+	} else {
+		for _, rel := range files {
+			if !count(rel) {
+				break
+			}
+		}
+	}
+
+	var total int64
+	for _, size := range sizes {
+		total += size
+	}
+	if total == 0 {
+		return nil, complete
+	}
+	for language, size := range sizes {
+		percent := math.Round(float64(size)*1000/float64(total)) / 10
+		shares = append(shares, LanguageShare{Language: language, Bytes: size, Percent: percent})
+	}
+	sort.Slice(shares, func(i, j int) bool {
+		if shares[i].Bytes != shares[j].Bytes {
+			return shares[i].Bytes > shares[j].Bytes
+		}
+		return shares[i].Language < shares[j].Language
+	})
+	return shares, complete
+}
+
+// trackedFiles lists the files git tracks below the project, relative to it
+func trackedFiles(projectPath string, deadline time.Time) ([]string, error) {
+	ctx := context.Background()
+	if !deadline.IsZero() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, deadline)
+		defer cancel()
+	}
+	output, err := exec.CommandContext(ctx, "git", "-C", projectPath, "ls-files", "-z").Output()
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, file := range bytes.Split(output, []byte{0}) {
+		if len(file) > 0 {
+			files = append(files, string(file))
+		}
+	}
+	return files, nil
+}
+
+// vendoredPath reports whether a path is inside a directory of dependencies, build
+// output or hidden files, which tracked files can be too
+func vendoredPath(rel string) bool {
+	dirs := strings.Split(rel, "/")
+	for _, dir := range dirs[:len(dirs)-1] {
+		if SkipWalkDir(dir) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,69 @@
+package parascan
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLanguageStats(t *testing.T) {
+	dir := t.TempDir()
+	for name, size := range map[string]int{
+		"main.go":                 700,
+		"web/app.ts":              250,
+		"web/app.min.js":          5000,
+		"scripts/deploy.sh":       50,
+		"README.md":               3000,
+		"node_modules/x/index.js": 9000,
+		"untracked.py":            4000,
+	} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(strings.Repeat("x", size)), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	format := func(shares []LanguageShare) string {
+		var parts []string
+		for _, share := range shares {
+			parts = append(parts, fmt.Sprintf("%.1f%% %s", share.Percent, share.Language))
+		}
+		return strings.Join(parts, ", ")
+	}
+
+	// Without git every file counts, except dependencies, bundles and prose
+	shares, complete := LanguageStats(dir, nil, time.Time{})
+	if got := format(shares); got != "80.0% Python, 14.0% Go, 5.0% TypeScript, 1.0% Shell" || !complete {
+		t.Errorf("Unexpected stats without git: %s (complete %v)", got, complete)
+	}
+
+	// In a repository only tracked files count, even tracked dependencies don't
+	git := exec.Command("git", "init", "-q")
+	git.Dir = dir
+	if err := git.Run(); err != nil {
+		t.Skipf("git is not available: %v", err)
+	}
+	add := exec.Command("git", "add", "-f", "main.go", "web", "scripts", "README.md", "node_modules")
+	add.Dir = dir
+	if output, err := add.CombinedOutput(); err != nil {
+		t.Fatalf("git add failed: %v\n%s", err, output)
+	}
+	shares, _ = LanguageStats(dir, nil, time.Time{})
+	if got := format(shares); got != "70.0% Go, 25.0% TypeScript, 5.0% Shell" {
+		t.Errorf("Unexpected stats of tracked files: %s", got)
+	}
+	if shares[0].Bytes != 700 {
+		t.Errorf("Expected the bytes of Go files, got %d", shares[0].Bytes)
+	}
+
+	// Past the deadline nothing is counted
+	if shares, complete := LanguageStats(dir, nil, time.Now().Add(-time.Second)); len(shares) != 0 || complete {
+		t.Errorf("Expected incomplete stats past the deadline, got %v (complete %v)", shares, complete)
+	}
+}
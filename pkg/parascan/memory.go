@@ -0,0 +1,83 @@
+package parascan
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/metrics"
+	"time"
+)
+
+// Stats describe the work done by a scan
+type Stats struct {
+	Duration        time.Duration
+	DependencyFiles int    // dependency files analyzed
+	PeakMemory      uint64 // heap in use, in bytes, sampled between files and detectors
+}
+
+// MemoryLimitError stops a scan using more memory than WithMemoryLimit allows
+type MemoryLimitError struct {
+	Limit uint64
+	Used  uint64
+}
+
+func (e *MemoryLimitError) Error() string {
+	return fmt.Sprintf("scan stopped at %s of memory, over the %s limit", FormatBytes(e.Used), FormatBytes(e.Limit))
+}
+
+// FormatBytes formats a byte count for people, e.g. 12.5 MB
+func FormatBytes(bytes uint64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	value, suffix := float64(bytes)/unit, "KB"
+	for _, next := range []string{"MB", "GB", "TB"} {
+		if value < unit {
+			break
+		}
+		value, suffix = value/unit, next
+	}
+	return fmt.Sprintf("%.1f %s", value, suffix)
+}
+
+// heapObjectsMetric is the memory of live and not yet swept heap objects
+const heapObjectsMetric = "/memory/classes/heap/objects:bytes"
+
+// memoryMonitor tracks the peak heap of a scan and fails it over the limit
+type memoryMonitor struct {
+	limit  uint64 // zero is unlimited
+	peak   uint64
+	err    error
+	sample []metrics.Sample
+}
+
+func newMemoryMonitor(limit uint64) *memoryMonitor {
+	return &memoryMonitor{limit: limit, sample: []metrics.Sample{{Name: heapObjectsMetric}}}
+}
+
+func (m *memoryMonitor) used() uint64 {
+	metrics.Read(m.sample)
+	if m.sample[0].Value.Kind() != metrics.KindUint64 {
+		return 0
+	}
+	return m.sample[0].Value.Uint64()
+}
+
+// check samples the heap. Once over the limit, even after collecting garbage,
+// it returns a MemoryLimitError and keeps returning it.
+func (m *memoryMonitor) check() error {
+	if m.err != nil {
+		return m.err
+	}
+	used := m.used()
+	if used > m.peak {
+		m.peak = used
+	}
+	if m.limit > 0 && used > m.limit {
+		runtime.GC()
+		if used = m.used(); used > m.limit {
+			m.err = &MemoryLimitError{Limit: m.limit, Used: used}
+		}
+	}
+	return m.err
+}
@@ -0,0 +1,315 @@
+//go:build !slim || pack_node
+
+package parascan
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+)
+
+// The node language pack reads package.json and the lockfiles of npm, pnpm and yarn
+func init() {
+	registerPack("node", map[string]dependencyParser{
+		"package.json":      packageJSONOrQuoted,
+		"package-lock.json": packageLockDependencies,
+		"pnpm-lock.yaml":    pnpmLockDependencies,
+		"yarn.lock":         yarnLockDependencies,
+	})
+}
+
+// packageJSONOrQuoted reads a package.json, or searches its lines for quoted package
+// names when it isn't valid JSON
+func packageJSONOrQuoted(r io.Reader, wanted *packageSet, found map[string]bool) error {
+	if err := packageJSONDependencies(r, wanted, found); err == nil {
+		return nil
+	}
+	// Fallback to simple search if JSON parsing fails
+	if _, err := r.(io.Seeker).Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	for name := range found {
+		delete(found, name)
+		delete(wanted.scopes, name)
+	}
+	return eachLine(r, func(line string) {
+		wanted.findQuoted(line, found)
+	})
+}
+
+// packageJSONDependencies walks package.json token by token and marks the wanted
+// packages among the keys of dependencies and devDependencies
+func packageJSONDependencies(r io.Reader, wanted *packageSet, found map[string]bool) error {
+	decoder := json.NewDecoder(r)
+	if err := expectDelim(decoder, '{'); err != nil {
+		return err
+	}
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		key := token.(string)
+		if key != "dependencies" && key != "devDependencies" {
+			if err := skipJSONValue(decoder); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := expectDelim(decoder, '{'); err != nil {
+			return err
+		}
+		for decoder.More() {
+			token, err := decoder.Token()
+			if err != nil {
+				return err
+			}
+			if name := token.(string); wanted.has(name) {
+				found[name] = true
+				wanted.setScope(name, key == "devDependencies")
+			}
+			if err := skipJSONValue(decoder); err != nil {
+				return err
+			}
+		}
+		if err := expectDelim(decoder, '}'); err != nil {
+			return err
+		}
+	}
+	return expectDelim(decoder, '}')
+}
+
+// packageLockDependencies walks a package-lock.json. In lockfiles v2 and v3 every
+// installed package is a node_modules/ path of the packages map, e.g.
+// node_modules/@stripe/stripe-js or node_modules/a/node_modules/stripe; the
+// dependencies of the root and workspace entries are direct. Lockfiles v1 only have
+// a dependencies tree, its packages count as indirect.
+func packageLockDependencies(r io.Reader, wanted *packageSet, found map[string]bool) error {
+	installed := make(map[string]bool)
+	direct := make(map[string]bool)
+	runtime := make(map[string]bool)
+
+	decoder := json.NewDecoder(r)
+	if err := expectDelim(decoder, '{'); err != nil {
+		return err
+	}
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		key, _ := token.(string)
+		if key != "packages" && key != "dependencies" {
+			if err := skipJSONValue(decoder); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := expectDelim(decoder, '{'); err != nil {
+			return err
+		}
+		for decoder.More() {
+			token, err := decoder.Token()
+			if err != nil {
+				return err
+			}
+			path, _ := token.(string)
+			if key == "dependencies" {
+				installed[path] = true
+			} else if i := strings.LastIndex(path, "node_modules/"); i >= 0 {
+				installed[path[i+len("node_modules/"):]] = true
+			} else {
+				// The root ("") and workspaces declare the project's own dependencies
+				var manifest struct {
+					Dependencies         map[string]string `json:"dependencies"`
+					DevDependencies      map[string]string `json:"devDependencies"`
+					OptionalDependencies map[string]string `json:"optionalDependencies"`
+				}
+				if err := decoder.Decode(&manifest); err != nil {
+					return err
+				}
+				for _, deps := range []map[string]string{manifest.Dependencies, manifest.DevDependencies, manifest.OptionalDependencies} {
+					for name := range deps {
+						direct[name] = true
+					}
+				}
+				for _, deps := range []map[string]string{manifest.Dependencies, manifest.OptionalDependencies} {
+					for name := range deps {
+						runtime[name] = true
+					}
+				}
+				continue
+			}
+			if err := skipJSONValue(decoder); err != nil {
+				return err
+			}
+		}
+		if err := expectDelim(decoder, '}'); err != nil {
+			return err
+		}
+	}
+
+	if err := expectDelim(decoder, '}'); err != nil {
+		return err
+	}
+	markLockfilePackages(installed, direct, runtime, wanted, found)
+	return nil
+}
+
+// pnpmLockDependencies reads a pnpm-lock.yaml line by line. Packages are the keys of
+// packages (and snapshots in lockfile v9): /stripe/10.0.0 in v5, /stripe@10.0.0
+// in v6, stripe@10.0.0 in v9, scoped ones quoted or not. Direct dependencies are
+// listed in the top-level dependencies sections of v5 and v6 or per importer.
+func pnpmLockDependencies(r io.Reader, wanted *packageSet, found map[string]bool) error {
+	installed := make(map[string]bool)
+	direct := make(map[string]bool)
+	runtime := make(map[string]bool)
+	section, importerSection := "", ""
+
+	err := eachLine(r, func(line string) {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			return
+		}
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+		key, ok := yamlKey(trimmed)
+		if !ok {
+			return
+		}
+
+		switch {
+		case indent == 0:
+			section = key
+		case section == "packages" || section == "snapshots":
+			if indent == 2 {
+				if name := pnpmPackageName(key); name != "" {
+					installed[name] = true
+				}
+			}
+		case isDependenciesKey(section):
+			if indent == 2 {
+				direct[key] = true
+				runtime[key] = runtime[key] || section != "devDependencies"
+			}
+		case section == "importers":
+			if indent == 4 {
+				importerSection = key
+			} else if indent == 6 && isDependenciesKey(importerSection) {
+				direct[key] = true
+				runtime[key] = runtime[key] || importerSection != "devDependencies"
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	markLockfilePackages(installed, direct, runtime, wanted, found)
+	return nil
+}
+
+// yarnLockDependencies reads a yarn.lock line by line, classic (v1) or Berry (v2 and
+// later). Entries start with their descriptors, e.g. "@stripe/stripe-js@^1.0.0",
+// stripe@^10.0.0 in classic and "stripe@npm:^10.0.0" in Berry. Berry lists the
+// project's own workspaces, their dependencies are direct; classic lockfiles don't
+// tell direct dependencies apart, all their packages count as indirect.
+func yarnLockDependencies(r io.Reader, wanted *packageSet, found map[string]bool) error {
+	installed := make(map[string]bool)
+	direct := make(map[string]bool)
+	workspace, inDependencies := false, false
+
+	err := eachLine(r, func(line string) {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			return
+		}
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+
+		switch {
+		case indent == 0:
+			workspace, inDependencies = false, false
+			for _, descriptor := range strings.Split(strings.TrimSuffix(trimmed, ":"), ",") {
+				descriptor = strings.Trim(strings.TrimSpace(descriptor), `"'`)
+				if name := yarnPackageName(descriptor); name != "" {
+					installed[name] = true
+					workspace = workspace || strings.Contains(descriptor, "@workspace:")
+				}
+			}
+		case indent == 2:
+			inDependencies = trimmed == "dependencies:"
+		case indent == 4 && workspace && inDependencies:
+			if key, ok := yamlKey(trimmed); ok {
+				direct[key] = true
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	markLockfilePackages(installed, direct, nil, wanted, found)
+	return nil
+}
+
+// yarnPackageName returns the package of a yarn.lock descriptor: the name before
+// the @ of the range, the scope's @ excluded
+func yarnPackageName(descriptor string) string {
+	if len(descriptor) < 2 {
+		return ""
+	}
+	if at := strings.IndexByte(descriptor[1:], '@'); at > 0 {
+		return descriptor[:at+1]
+	}
+	return ""
+}
+
+func isDependenciesKey(key string) bool {
+	return key == "dependencies" || key == "devDependencies" || key == "optionalDependencies"
+}
+
+// yamlKey returns the key of a `key:` or `key: value` YAML line, unquoted
+func yamlKey(line string) (string, bool) {
+	if quote := line[0]; quote == '\'' || quote == '"' {
+		end := strings.IndexByte(line[1:], quote)
+		if end < 0 || !strings.HasPrefix(line[end+2:], ":") {
+			return "", false
+		}
+		return line[1 : end+1], true
+	}
+	key, _, ok := strings.Cut(line, ":")
+	return strings.TrimSpace(key), ok && !strings.HasPrefix(line, "- ")
+}
+
+// pnpmPackageName returns the package name of a pnpm packages key, the scope included
+func pnpmPackageName(key string) string {
+	key = strings.TrimPrefix(key, "/")
+	scope := ""
+	if strings.HasPrefix(key, "@") {
+		slash := strings.IndexByte(key, '/')
+		if slash < 0 {
+			return ""
+		}
+		scope, key = key[:slash+1], key[slash+1:]
+	}
+	// The name ends at the version, after @ (v6 and later) or / (v5)
+	if end := strings.IndexAny(key, "@/"); end > 0 {
+		return scope + key[:end]
+	}
+	return ""
+}
+
+// markLockfilePackages marks the wanted packages among those a lockfile installs,
+// direct when the project declares them itself. Lockfiles that tell the project's
+// devDependencies apart give the direct ones it declares for runtime, nil otherwise.
+func markLockfilePackages(installed, direct, runtime map[string]bool, wanted *packageSet, found map[string]bool) {
+	for name := range installed {
+		if wanted.has(name) {
+			found[name] = found[name] || direct[name]
+			if direct[name] && runtime != nil {
+				wanted.setScope(name, !runtime[name])
+			}
+		}
+	}
+}
@@ -0,0 +1,94 @@
+//go:build !slim || pack_dotnet
+
+package parascan
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"strings"
+)
+
+// The dotnet language pack reads MSBuild projects, packages.config and NuGet lockfiles
+func init() {
+	registerPack("dotnet", map[string]dependencyParser{
+		"packages.lock.json": nugetLockDependencies,
+		"packages.config":    msbuildDependencies,
+		"*.props":            msbuildDependencies,
+		"*.csproj":           msbuildDependencies,
+		"*.fsproj":           msbuildDependencies,
+		"*.vbproj":           msbuildDependencies,
+	})
+}
+
+// msbuildDependencies reads the packages of an MSBuild project (*.csproj, *.fsproj,
+// *.vbproj and Directory.Build.props) from its <PackageReference Include="..."/>
+// elements, or of a packages.config from <package id="..."/>. PackageVersion
+// elements of central package management (Directory.Packages.props) only pin
+// versions and count as indirect.
+func msbuildDependencies(r io.Reader, wanted *packageSet, found map[string]bool) error {
+	decoder := xml.NewDecoder(r)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		element, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		var attr string
+		direct := true
+		switch element.Name.Local {
+		case "PackageReference":
+			attr = "Include"
+		case "package":
+			attr = "id"
+		case "PackageVersion":
+			attr, direct = "Include", false
+		default:
+			continue
+		}
+		for _, a := range element.Attr {
+			if a.Name.Local != attr {
+				continue
+			}
+			// Include may list several packages separated by semicolons
+			for _, id := range strings.Split(a.Value, ";") {
+				if name, ok := wanted.lookupFold(strings.TrimSpace(id)); ok {
+					found[name] = found[name] || direct
+				}
+			}
+		}
+	}
+}
+
+// nugetLockDependencies reads a packages.lock.json, whose packages are listed per
+// target framework with their type: Direct ones are referenced by the project,
+// Transitive and CentralTransitive ones by other packages. Project references
+// aren't packages.
+func nugetLockDependencies(r io.Reader, wanted *packageSet, found map[string]bool) error {
+	var lock struct {
+		Dependencies map[string]map[string]struct {
+			Type string `json:"type"`
+		} `json:"dependencies"`
+	}
+	if err := json.NewDecoder(r).Decode(&lock); err != nil {
+		return err
+	}
+
+	for _, packages := range lock.Dependencies {
+		for id, pkg := range packages {
+			name, ok := wanted.lookupFold(id)
+			if !ok || pkg.Type == "Project" {
+				continue
+			}
+			found[name] = found[name] || pkg.Type == "Direct"
+		}
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package parascan
+
+import (
+	"regexp"
+	"strings"
+)
+
+// packageSet is the packages looked for in dependency files: the catalog's package
+// names, and patterns ending in * for internal services covering a whole org, e.g.
+// @acme/* for an npm scope or com.acme:* for a Maven group. A package matched by a
+// pattern is found under its own name, so the evidence says which one it was.
+type packageSet struct {
+	names    map[string]bool
+	folded   map[string]string // names by lowercase, for case insensitive package managers
+	patterns []string          // prefixes of the patterns, without the *
+	scopes   map[string]string // DevScope or RuntimeScope of the found packages, when the file tells
+}
+
+func newPackageSet(wanted map[string]bool) *packageSet {
+	set := &packageSet{names: make(map[string]bool), folded: make(map[string]string), scopes: make(map[string]string)}
+	for name := range wanted {
+		if prefix, ok := strings.CutSuffix(name, "*"); ok {
+			set.patterns = append(set.patterns, prefix)
+			continue
+		}
+		set.names[name] = true
+		set.folded[strings.ToLower(name)] = name
+	}
+	return set
+}
+
+// setScope records whether the file needs a found package for development only,
+// e.g. in devDependencies. Runtime wins when the file lists the package twice.
+func (s *packageSet) setScope(name string, dev bool) {
+	if s.scopes[name] == RuntimeScope {
+		return
+	}
+	s.scopes[name] = RuntimeScope
+	if dev {
+		s.scopes[name] = DevScope
+	}
+}
+
+// has reports whether a package is wanted by name or pattern
+func (s *packageSet) has(name string) bool {
+	return s.names[name] || s.matchesPattern(name)
+}
+
+// matchesPattern reports whether a package is wanted by a pattern
+func (s *packageSet) matchesPattern(name string) bool {
+	for _, prefix := range s.patterns {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// lookupFold is has for package managers whose names are case insensitive: NuGet's
+// stripe.net is the catalog's Stripe.net. It returns the name to find the package
+// under, the catalog's for package names and the package's own for patterns.
+func (s *packageSet) lookupFold(name string) (string, bool) {
+	if catalogName, ok := s.folded[strings.ToLower(name)]; ok {
+		return catalogName, true
+	}
+	for _, prefix := range s.patterns {
+		if len(name) >= len(prefix) && strings.EqualFold(name[:len(prefix)], prefix) {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+var quotedName = regexp.MustCompile(`["']([^"'\s]+)["']`)
+
+// findQuoted marks the wanted packages quoted in the line, 'name' or "name"
+func (s *packageSet) findQuoted(line string, found map[string]bool) {
+	for pkg := range s.names {
+		if strings.Contains(line, `'`+pkg+`'`) || strings.Contains(line, `"`+pkg+`"`) {
+			found[pkg] = true
+		}
+	}
+	if len(s.patterns) == 0 {
+		return
+	}
+	for _, match := range quotedName.FindAllStringSubmatch(line, -1) {
+		if s.matchesPattern(match[1]) {
+			found[match[1]] = true
+		}
+	}
+}
+
+// findQuotedScoped is findQuoted for files that tell development dependencies apart
+func (s *packageSet) findQuotedScoped(line string, dev bool, found map[string]bool) {
+	inLine := make(map[string]bool)
+	s.findQuoted(line, inLine)
+	for name := range inLine {
+		found[name] = true
+		s.setScope(name, dev)
+	}
+}
+
+// majorVersionSuffix is the /vN of Go modules from v2 on, or the .vN of gopkg.in paths
+var majorVersionSuffix = regexp.MustCompile(`(/v[2-9]|/v[1-9][0-9]+|\.v[0-9]+)$`)
+
+// modulePathMatches reports whether a required module is the catalog's module or
+// one nested in it, whatever their major versions: github.com/stripe/stripe-go/v76
+// matches github.com/stripe/stripe-go, github.com/aws/aws-sdk-go-v2/service/s3
+// matches github.com/aws/aws-sdk-go-v2
+func modulePathMatches(module, pkg string) bool {
+	module = majorVersionSuffix.ReplaceAllString(module, "")
+	pkg = majorVersionSuffix.ReplaceAllString(pkg, "")
+	return module == pkg || strings.HasPrefix(module, pkg+"/")
+}
+
+// packageMatches reports whether a package of the language is the catalog's
+// candidate, compared as containsPackage does, or one of the packages the
+// candidate covers when it's a pattern
+func packageMatches(language, candidate, pkg string) bool {
+	if prefix, ok := strings.CutSuffix(candidate, "*"); ok {
+		return strings.HasPrefix(normalizePackage(language, pkg), normalizePackage(language, prefix))
+	}
+	if language == "go" && modulePathMatches(pkg, candidate) {
+		return true
+	}
+	return normalizePackage(language, candidate) == normalizePackage(language, pkg)
+}
+
+// patternOwners assigns the packages found by patterns to the most specific one:
+// a package a service lists by name belongs to that service only, otherwise the
+// longest matching pattern wins, so @acme/billing-* takes @acme/billing-client
+// from @acme/*. Packages found by name aren't in the result.
+func patternOwners(language string, wanted map[string]bool, found map[string]bool) map[string]string {
+	owners := make(map[string]string)
+	for name := range found {
+		if wanted[name] {
+			continue
+		}
+		for pattern := range wanted {
+			if strings.HasSuffix(pattern, "*") && packageMatches(language, pattern, name) && len(pattern) > len(owners[name]) {
+				owners[name] = pattern
+			}
+		}
+	}
+	return owners
+}
@@ -0,0 +1,110 @@
+package parascan
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// dependencyParser reads the wanted packages a dependency file declares into found,
+// true for direct dependencies and false for indirect ones. The reader is the open
+// file, an io.Seeker for parsers that fall back to another reading.
+type dependencyParser func(r io.Reader, wanted *packageSet, found map[string]bool) error
+
+// packFiles maps the dependency files with a parser of their own to the language
+// pack holding it, by file name or by * and a suffix of the name. The parsers of an
+// ecosystem, with their XML, lockfile and other formats, are compiled in as a pack:
+// every pack by default, only those named with pack_<name> in a slim build, e.g.
+// go build -tags slim,pack_node,pack_ruby.
+var packFiles = map[string]string{
+	"package.json":       "node",
+	"package-lock.json":  "node",
+	"pnpm-lock.yaml":     "node",
+	"yarn.lock":          "node",
+	"go.mod":             "go",
+	"go.sum":             "go",
+	"pom.xml":            "jvm",
+	"build.gradle":       "jvm",
+	"build.gradle.kts":   "jvm",
+	"packages.config":    "dotnet",
+	"packages.lock.json": "dotnet",
+	"*.props":            "dotnet",
+	"*.csproj":           "dotnet",
+	"*.fsproj":           "dotnet",
+	"*.vbproj":           "dotnet",
+	"composer.json":      "php",
+	"composer.lock":      "php",
+	"Gemfile":            "ruby",
+	"Gemfile.lock":       "ruby",
+	"*.gemspec":          "ruby",
+	"*requirements.txt":  "python",
+}
+
+// dependencyParsers are the parsers of the compiled in packs by packFiles key,
+// registered by the packs' files
+var dependencyParsers = make(map[string]dependencyParser)
+
+// compiledPacks are the names of the language packs in this build
+var compiledPacks = make(map[string]bool)
+
+// registerPack adds the parsers of a language pack, from the init of its file
+func registerPack(name string, parsers map[string]dependencyParser) {
+	compiledPacks[name] = true
+	for key, parse := range parsers {
+		if packFiles[key] != name {
+			panic(fmt.Sprintf("language pack %s: %s isn't one of its files", name, key))
+		}
+		dependencyParsers[key] = parse
+	}
+}
+
+// packFileKey returns the packFiles key of a dependency file by its base name, an
+// empty string for files read by the line search
+func packFileKey(baseFileName string) string {
+	if _, ok := packFiles[baseFileName]; ok {
+		return baseFileName
+	}
+	for key := range packFiles {
+		if strings.HasPrefix(key, "*") && strings.HasSuffix(baseFileName, key[1:]) {
+			return key
+		}
+	}
+	return ""
+}
+
+// MissingLanguagePack returns the language pack that parses a dependency file when
+// it isn't in this build, an empty string otherwise
+func MissingLanguagePack(filePath string) string {
+	key := packFileKey(filepath.Base(filePath))
+	if _, compiled := dependencyParsers[key]; key == "" || compiled {
+		return ""
+	}
+	return packFiles[key]
+}
+
+// AllLanguagePacks lists every language pack, compiled in or not
+func AllLanguagePacks() []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, name := range packFiles {
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LanguagePacks lists the language packs compiled into this build. Dependency files
+// of the others are skipped, their languages are still detected.
+func LanguagePacks() []string {
+	names := make([]string, 0, len(compiledPacks))
+	for name := range compiledPacks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
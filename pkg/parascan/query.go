@@ -0,0 +1,115 @@
+package parascan
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"parascan/detectors"
+)
+
+// Service is a catalog service as returned by catalog queries
+type Service struct {
+	Key string // e.g. stripe, the name of its services/*.yml
+	ServiceData
+}
+
+// AllServices returns the catalog's services sorted by key. The Services map holds
+// the same definitions by key.
+func (c *Catalog) AllServices() []Service {
+	keys := make([]string, 0, len(c.Services))
+	for key := range c.Services {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	services := make([]Service, 0, len(keys))
+	for _, key := range keys {
+		services = append(services, Service{Key: key, ServiceData: *c.Services[key]})
+	}
+	return services
+}
+
+var pythonNameSeparators = regexp.MustCompile(`[-_.]+`)
+
+// normalizePackage puts a package name in the form its package manager compares
+// names in: NuGet and Composer ignore case, PyPI also treats runs of -, _ and . alike
+func normalizePackage(language, name string) string {
+	switch language {
+	case "dotnet", "php":
+		return strings.ToLower(name)
+	case "python":
+		return pythonNameSeparators.ReplaceAllString(strings.ToLower(name), "-")
+	}
+	return name
+}
+
+// LookupPackage returns the services a package of the language belongs to, e.g.
+// ("nodejs", "@sentry/node") gives Sentry, without scanning a project. An empty
+// language looks the package up in every language. Names are compared the way the
+// language's package manager does; Go modules also match by major version and
+// nested module, e.g. github.com/stripe/stripe-go/v76.
+func (c *Catalog) LookupPackage(language, pkg string) []Service {
+	var services []Service
+	for _, service := range c.AllServices() {
+		for stack, packages := range service.Stacks {
+			if language != "" && stack != language {
+				continue
+			}
+			if containsPackage(stack, packages, pkg) {
+				services = append(services, service)
+				break
+			}
+		}
+	}
+	return services
+}
+
+func containsPackage(language string, packages []string, pkg string) bool {
+	for _, candidate := range packages {
+		if packageMatches(language, candidate, pkg) {
+			return true
+		}
+	}
+	return false
+}
+
+// FilesFor returns the file patterns a service can be detected in: the dependency
+// files of the languages it has packages for and the files of the file detectors
+// named like it. The service is given by key or name, regardless of case; unknown
+// services have none.
+func (c *Catalog) FilesFor(service string) []string {
+	seen := make(map[string]bool)
+	var files []string
+	add := func(patterns []string) {
+		for _, pattern := range patterns {
+			if !seen[pattern] {
+				seen[pattern] = true
+				files = append(files, pattern)
+			}
+		}
+	}
+
+	for key, data := range c.Services {
+		if !strings.EqualFold(key, service) && !strings.EqualFold(data.Name, service) {
+			continue
+		}
+		if c.Stack != nil {
+			for language := range data.Stacks {
+				for _, manager := range c.Stack.Languages[language].PackageManagers {
+					add(manager.Files)
+				}
+			}
+		}
+	}
+	if c.FileDetectors != nil {
+		for key, tech := range c.FileDetectors.Technologies {
+			if strings.EqualFold(key, service) || strings.EqualFold(tech.DisplayName, service) {
+				add(detectors.RulePatterns(tech.Files))
+			}
+		}
+	}
+
+	sort.Strings(files)
+	return files
+}
@@ -0,0 +1,62 @@
+package parascan
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCatalogQueries(t *testing.T) {
+	catalog, err := EmbeddedCatalog()
+	if err != nil {
+		t.Fatalf("Failed to load catalog: %v", err)
+	}
+
+	services := catalog.AllServices()
+	if len(services) != len(catalog.Services) {
+		t.Errorf("Expected %d services, got %d", len(catalog.Services), len(services))
+	}
+	for i := 1; i < len(services); i++ {
+		if services[i-1].Key >= services[i].Key {
+			t.Fatalf("Expected services sorted by key, got %s before %s", services[i-1].Key, services[i].Key)
+		}
+	}
+
+	lookups := []struct {
+		language, pkg string
+		expected      string
+	}{
+		{"nodejs", "@sentry/node", "sentry"},
+		{"dotnet", "stripe.NET", "stripe"},
+		{"python", "Sentry_SDK", "sentry"},
+		{"", "@stripe/stripe-js", "stripe"},
+		{"go", "github.com/stripe/stripe-go/v76", "stripe"},
+		// Names are exact otherwise, and per language
+		{"nodejs", "@Sentry/node", ""},
+		{"ruby", "@sentry/node", ""},
+		{"nodejs", "left-pad", ""},
+	}
+	for _, lookup := range lookups {
+		var keys []string
+		for _, service := range catalog.LookupPackage(lookup.language, lookup.pkg) {
+			keys = append(keys, service.Key)
+		}
+		if strings.Join(keys, ",") != lookup.expected {
+			t.Errorf("Expected LookupPackage(%q, %q) to give %q, got %v", lookup.language, lookup.pkg, lookup.expected, keys)
+		}
+	}
+
+	files := strings.Join(catalog.FilesFor("Sentry"), " ")
+	for _, file := range []string{"package.json", "requirements.txt", "go.mod"} {
+		if !strings.Contains(files, file) {
+			t.Errorf("Expected %s among Sentry's files, got %s", file, files)
+		}
+	}
+	// File detectors sharing a display name all count: Codecov's config and CI uploads
+	files = strings.Join(catalog.FilesFor("codecov"), " ")
+	if !strings.Contains(files, "codecov.yml") || !strings.Contains(files, ".github/workflows/*.yml") {
+		t.Errorf("Expected Codecov's config and CI files, got %s", files)
+	}
+	if files := catalog.FilesFor("no-such-service"); files != nil {
+		t.Errorf("Expected no files for an unknown service, got %v", files)
+	}
+}
@@ -0,0 +1,34 @@
+//go:build !slim || pack_python
+
+package parascan
+
+import (
+	"io"
+	"strings"
+)
+
+// The python language pack reads pip requirements files
+func init() {
+	registerPack("python", map[string]dependencyParser{
+		"*requirements.txt": requirementsDependencies,
+	})
+}
+
+// requirementsDependencies reads the package names of a requirements.txt, e.g.
+// stripe>=7.0
+func requirementsDependencies(r io.Reader, wanted *packageSet, found map[string]bool) error {
+	return eachLine(r, func(line string) {
+		line = strings.TrimSpace(line)
+		// Skip comments and empty lines
+		if line == "" || strings.HasPrefix(line, "#") {
+			return
+		}
+		// Package name should be at the beginning of line (before version specifiers)
+		parts := strings.FieldsFunc(line, func(r rune) bool {
+			return r == '=' || r == '>' || r == '<' || r == '!' || r == ' ' || r == '~'
+		})
+		if len(parts) > 0 && wanted.has(parts[0]) {
+			found[parts[0]] = true
+		}
+	})
+}
@@ -0,0 +1,246 @@
+package parascan
+
+import (
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DiffDependency is a catalog package a diff adds to a dependency file
+type DiffDependency struct {
+	Service  string `json:"service"` // catalog key, e.g. stripe
+	Name     string `json:"name"`    // e.g. Stripe
+	URL      string `json:"url"`
+	Package  string `json:"package"`
+	Language string `json:"language"`
+	File     string `json:"file"`
+	Line     int    `json:"line"`              // in the new version of the file
+	Updated  bool   `json:"updated,omitempty"` // also on a removed line of the file, e.g. a version bump
+}
+
+// Lockfiles repeat what their manifest adds, with every transitive package on top
+var reviewSkippedFiles = map[string]bool{
+	"package-lock.json":  true,
+	"yarn.lock":          true,
+	"pnpm-lock.yaml":     true,
+	"composer.lock":      true,
+	"Gemfile.lock":       true,
+	"packages.lock.json": true,
+	"poetry.lock":        true,
+	"Pipfile.lock":       true,
+	"go.sum":             true,
+}
+
+var (
+	hunkHeader  = regexp.MustCompile(`^@@ -\d+(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+	pomGroupID  = regexp.MustCompile(`<groupId>\s*([^<\s]+)\s*</groupId>`)
+	pomArtifact = regexp.MustCompile(`<artifactId>\s*([^<\s]+)\s*</artifactId>`)
+)
+
+// diffLine is an added or removed line of a dependency file
+type diffLine struct {
+	text  string
+	line  int    // in the new file for added lines
+	group string // last Maven groupId seen in the hunk, for pom.xml
+}
+
+// diffFile is what a diff changes in one file
+type diffFile struct {
+	path           string
+	added, removed []diffLine
+}
+
+// ReviewDiff reads a unified diff, as git diff or a forge's PR diff prints it, and
+// returns the catalog packages it adds to dependency files, sorted by file and
+// line. A package also removed from the same file is marked Updated rather than
+// introduced. Lockfiles are skipped, their manifest has the direct dependencies.
+func (c *Catalog) ReviewDiff(diff string) []DiffDependency {
+	var dependencies []DiffDependency
+	for _, file := range parseDiff(diff) {
+		for _, language := range c.dependencyFileLanguages(file.path) {
+			removed := make(map[string]bool)
+			for _, line := range file.removed {
+				for _, match := range c.linePackages(language, file.path, line) {
+					removed[match.Service+"\x00"+match.Package] = true
+				}
+			}
+
+			seen := make(map[string]bool)
+			for _, line := range file.added {
+				for _, match := range c.linePackages(language, file.path, line) {
+					id := match.Service + "\x00" + match.Package
+					if seen[id] {
+						continue
+					}
+					seen[id] = true
+					match.Language, match.File, match.Line = language, file.path, line.line
+					match.Updated = removed[id]
+					dependencies = append(dependencies, match)
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(dependencies, func(i, j int) bool {
+		if dependencies[i].File != dependencies[j].File {
+			return dependencies[i].File < dependencies[j].File
+		}
+		return dependencies[i].Line < dependencies[j].Line
+	})
+	return dependencies
+}
+
+// parseDiff splits a unified diff into the added and removed lines of each file.
+// Deleted files add nothing and are left out.
+func parseDiff(diff string) []*diffFile {
+	var files []*diffFile
+	var file *diffFile
+	oldLeft, newLeft, newLine := 0, 0, 0
+	group := ""
+
+	for _, text := range strings.Split(diff, "\n") {
+		text = strings.TrimSuffix(text, "\r")
+		if oldLeft > 0 || newLeft > 0 {
+			switch {
+			case strings.HasPrefix(text, "+"):
+				if file != nil {
+					file.added = append(file.added, diffLine{text: text[1:], line: newLine, group: group})
+				}
+				newLine++
+				newLeft--
+			case strings.HasPrefix(text, "-"):
+				if file != nil {
+					file.removed = append(file.removed, diffLine{text: text[1:], group: group})
+				}
+				oldLeft--
+			case strings.HasPrefix(text, `\`): // \ No newline at end of file
+				continue
+			default:
+				newLine++
+				oldLeft--
+				newLeft--
+			}
+			if m := pomGroupID.FindStringSubmatch(text); m != nil {
+				group = m[1]
+			}
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(text, "diff --git "):
+			file = nil
+		case strings.HasPrefix(text, "+++ "):
+			name := strings.TrimSpace(strings.TrimPrefix(text, "+++ "))
+			if name, _, _ = strings.Cut(name, "\t"); name == "/dev/null" {
+				file = nil
+				continue
+			}
+			file = &diffFile{path: strings.TrimPrefix(name, "b/")}
+			files = append(files, file)
+		case strings.HasPrefix(text, "@@ "):
+			m := hunkHeader.FindStringSubmatch(text)
+			if m == nil {
+				continue
+			}
+			oldLeft, newLeft = hunkLength(m[1]), hunkLength(m[3])
+			newLine, _ = strconv.Atoi(m[2])
+			group = ""
+		}
+	}
+	return files
+}
+
+// hunkLength reads the line count of a hunk range, which is 1 when left out
+func hunkLength(count string) int {
+	if count == "" {
+		return 1
+	}
+	n, _ := strconv.Atoi(count)
+	return n
+}
+
+// dependencyFileLanguages returns the languages whose package managers read the
+// file, sorted; none for lockfiles and other files
+func (c *Catalog) dependencyFileLanguages(filePath string) []string {
+	if c.Stack == nil || reviewSkippedFiles[path.Base(filePath)] {
+		return nil
+	}
+	var languages []string
+	for language, config := range c.Stack.Languages {
+		if languageReadsFile(config, filePath) {
+			languages = append(languages, language)
+		}
+	}
+	sort.Strings(languages)
+	return languages
+}
+
+// languageReadsFile matches the file's last path segments against the language's
+// dependency file patterns, e.g. requirements/*.txt against api/requirements/dev.txt
+func languageReadsFile(config Language, filePath string) bool {
+	segments := strings.Split(filePath, "/")
+	for _, manager := range config.PackageManagers {
+		for _, pattern := range manager.Files {
+			n := strings.Count(pattern, "/") + 1
+			if n > len(segments) {
+				continue
+			}
+			if ok, _ := path.Match(pattern, strings.Join(segments[len(segments)-n:], "/")); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// linePackages returns the catalog packages of the language named on a diff line,
+// one per service and package
+func (c *Catalog) linePackages(language, filePath string, line diffLine) []DiffDependency {
+	var matches []DiffDependency
+	seen := make(map[string]bool)
+	for _, candidate := range packageCandidates(filePath, line) {
+		for _, service := range c.LookupPackage(language, candidate) {
+			if seen[service.Key] {
+				continue
+			}
+			seen[service.Key] = true
+			matches = append(matches, DiffDependency{Service: service.Key, Name: service.Name, URL: service.URL, Package: candidate})
+		}
+	}
+	return matches
+}
+
+// packageCandidates splits a dependency file line into the words that could be
+// package names: quoted names of package.json and Gemfiles, requirement names
+// before their version specifier, group:artifact of Gradle coordinates, module
+// paths of go.mod and groupId:artifactId of pom.xml dependencies
+func packageCandidates(filePath string, line diffLine) []string {
+	var candidates []string
+	if path.Base(filePath) == "pom.xml" {
+		if m := pomArtifact.FindStringSubmatch(line.text); m != nil && line.group != "" {
+			candidates = append(candidates, line.group+":"+m[1])
+		}
+	}
+
+	words := strings.FieldsFunc(line.text, func(r rune) bool {
+		return strings.ContainsRune(" \t\"'`,;()[]{}<>=~!^|*", r)
+	})
+	for _, word := range words {
+		word = strings.TrimSuffix(word, ":")
+		if !strings.ContainsAny(word, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ") {
+			continue
+		}
+		candidates = append(candidates, word)
+		// com.stripe:stripe-java:24.0.0
+		if parts := strings.Split(word, ":"); len(parts) > 2 {
+			candidates = append(candidates, parts[0]+":"+parts[1])
+		}
+		// stripe@^14.0.0 and @stripe/stripe-js@2 as yarn and pnpm name them
+		if i := strings.LastIndex(word, "@"); i > 0 {
+			candidates = append(candidates, word[:i])
+		}
+	}
+	return candidates
+}
@@ -0,0 +1,81 @@
+package parascan
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+const reviewPatch = `diff --git a/package.json b/package.json
+index 3b18e51..a9c2f1d 100644
+--- a/package.json
++++ b/package.json
+@@ -10,7 +10,9 @@
+   "dependencies": {
+-    "@sentry/node": "^7.0.0",
++    "@sentry/node": "^8.0.0",
+     "express": "^4.18.2",
++    "stripe": "^14.0.0",
++    "twilio": "^4.19.0",
+     "zod": "^3.22.0"
+   }
+ }
+diff --git a/package-lock.json b/package-lock.json
+--- a/package-lock.json
++++ b/package-lock.json
+@@ -1,1 +1,2 @@
+ {
++    "node_modules/@datadog/browser-rum": {},
+diff --git a/services/api/pom.xml b/services/api/pom.xml
+--- a/services/api/pom.xml
++++ b/services/api/pom.xml
+@@ -20,2 +20,6 @@
+     <dependencies>
++        <dependency>
++            <groupId>com.stripe</groupId>
++            <artifactId>stripe-java</artifactId>
++        </dependency>
+     </dependencies>
+diff --git a/go.mod b/go.mod
+--- a/go.mod
++++ b/go.mod
+@@ -3,1 +3,2 @@ require (
+ 	github.com/gin-gonic/gin v1.9.1
++	github.com/stripe/stripe-go/v76 v76.8.0
+diff --git a/requirements.txt b/requirements.txt
+deleted file mode 100644
+--- a/requirements.txt
++++ /dev/null
+@@ -1,1 +0,0 @@
+-stripe==7.0.0
+diff --git a/README.md b/README.md
+--- a/README.md
++++ b/README.md
+@@ -1,1 +1,2 @@
+ # Shop
++Payments with stripe
+`
+
+func TestReviewDiff(t *testing.T) {
+	catalog, err := EmbeddedCatalog()
+	if err != nil {
+		t.Fatalf("Failed to load catalog: %v", err)
+	}
+
+	// The lockfile, the deleted file and the README add nothing; sentry moves to a
+	// new major version
+	var found []string
+	for _, dependency := range catalog.ReviewDiff(reviewPatch) {
+		found = append(found, fmt.Sprintf("%s %s %s:%d %v", dependency.Service, dependency.Package, dependency.File, dependency.Line, dependency.Updated))
+	}
+	expected := []string{
+		"stripe github.com/stripe/stripe-go/v76 go.mod:4 false",
+		"sentry @sentry/node package.json:11 true",
+		"stripe stripe package.json:13 false",
+		"twilio twilio package.json:14 false",
+		"stripe com.stripe:stripe-java services/api/pom.xml:23 false",
+	}
+	if strings.Join(found, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected\n%s\ngot\n%s", strings.Join(expected, "\n"), strings.Join(found, "\n"))
+	}
+}
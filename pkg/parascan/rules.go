@@ -0,0 +1,200 @@
+package parascan
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+
+	"parascan/detectors"
+)
+
+// WithRules returns a copy of the catalog with rules merged over it. Rules are
+// laid out like a catalog, every file optional: services/*.yml add or replace
+// services by key, file-detectors.yml technologies, tooling.yml tools,
+// attention.yml rules by id (severity off turns one off), health.yml criteria by
+// id (weight 0 turns one off) and
+// stack-dependency-files.yml package managers and source extensions by language.
+// The hash stays the one of the catalog, source names the rules in Rules.
+func (c *Catalog) WithRules(fsys fs.FS, source string) (*Catalog, error) {
+	merged := *c
+	merged.Rules = append(append([]string(nil), c.Rules...), source)
+
+	services, err := loadRuleServices(fsys)
+	if err != nil {
+		return nil, fmt.Errorf("rules in %s: %v", source, err)
+	}
+	if len(services) > 0 {
+		merged.Services = make(map[string]*ServiceData, len(c.Services)+len(services))
+		for key, service := range c.Services {
+			merged.Services[key] = service
+		}
+		for key, service := range services {
+			merged.Services[key] = service
+		}
+	}
+
+	if content, err := fs.ReadFile(fsys, "stack-dependency-files.yml"); err == nil {
+		stack, err := ParseStackDependencyFiles(content)
+		if err != nil {
+			return nil, fmt.Errorf("rules in %s: stack-dependency-files.yml: %v", source, err)
+		}
+		merged.Stack = mergeStack(c.Stack, stack)
+	}
+
+	if content, err := fs.ReadFile(fsys, "file-detectors.yml"); err == nil {
+		fileDetectors, err := ParseFileDetectors(content)
+		if err != nil {
+			return nil, fmt.Errorf("rules in %s: file-detectors.yml: %v", source, err)
+		}
+		technologies := make(map[string]detectors.TechnologyConfig)
+		if c.FileDetectors != nil {
+			for key, tech := range c.FileDetectors.Technologies {
+				technologies[key] = tech
+			}
+		}
+		for key, tech := range fileDetectors.Technologies {
+			technologies[key] = tech
+		}
+		merged.FileDetectors = &detectors.FileDetectors{Technologies: technologies}
+	}
+
+	if content, err := fs.ReadFile(fsys, "tooling.yml"); err == nil {
+		var tooling Tooling
+		if err := yaml.Unmarshal(content, &tooling); err != nil {
+			return nil, fmt.Errorf("rules in %s: tooling.yml: %v", source, err)
+		}
+		tools := make(map[string]Tool)
+		if c.Tooling != nil {
+			for name, tool := range c.Tooling.Tools {
+				tools[name] = tool
+			}
+		}
+		for name, tool := range tooling.Tools {
+			tools[name] = tool
+		}
+		merged.Tooling = &Tooling{Tools: tools}
+	}
+
+	if content, err := fs.ReadFile(fsys, "attention.yml"); err == nil {
+		attention, err := ParseAttention(content)
+		if err != nil {
+			return nil, fmt.Errorf("rules in %s: attention.yml: %v", source, err)
+		}
+		rules := make(map[string]AttentionRule)
+		if c.Attention != nil {
+			for id, rule := range c.Attention.Rules {
+				rules[id] = rule
+			}
+		}
+		for id, rule := range attention.Rules {
+			rules[id] = rule
+		}
+		merged.Attention = &Attention{Rules: rules}
+	}
+
+	if content, err := fs.ReadFile(fsys, "health.yml"); err == nil {
+		health, err := ParseHealth(content)
+		if err != nil {
+			return nil, fmt.Errorf("rules in %s: health.yml: %v", source, err)
+		}
+		criteria := make(map[string]HealthCriterion)
+		if c.Health != nil {
+			for id, criterion := range c.Health.Criteria {
+				criteria[id] = criterion
+			}
+		}
+		for id, criterion := range health.Criteria {
+			criteria[id] = criterion
+		}
+		merged.Health = &Health{Criteria: criteria}
+	}
+
+	return &merged, nil
+}
+
+// loadRuleServices reads services/*.yml of a rules directory. Unlike the catalog's,
+// a broken rule is an error: it's being written by the person scanning.
+func loadRuleServices(fsys fs.FS) (map[string]*ServiceData, error) {
+	names, err := fs.Glob(fsys, "services/*.yml")
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+
+	services := make(map[string]*ServiceData)
+	for _, name := range names {
+		content, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+		var service ServiceData
+		if err := yaml.Unmarshal(content, &service); err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+		if service.Name == "" {
+			return nil, fmt.Errorf("%s: a service needs a name", name)
+		}
+		for linkType := range service.Links {
+			if !IsLinkType(linkType) {
+				return nil, fmt.Errorf("%s: unknown link type %s, expected one of %s", name, linkType, strings.Join(LinkTypes, ", "))
+			}
+		}
+		for _, rule := range service.Content {
+			if rule.Match == "" {
+				return nil, fmt.Errorf("%s: a content rule needs a match", name)
+			}
+			if _, err := regexp.Compile(rule.Match); err != nil {
+				return nil, fmt.Errorf("%s: content rule %q: %v", name, rule.Match, err)
+			}
+		}
+		for _, pkg := range service.Exclude.DevOnly {
+			if pkg != "*" && !service.listsPackage(pkg) {
+				return nil, fmt.Errorf("%s: dev_only lists %s, which isn't one of its packages", name, pkg)
+			}
+		}
+		services[strings.TrimSuffix(path.Base(name), ".yml")] = &service
+	}
+	return services, nil
+}
+
+// mergeStack merges rule languages over the catalog's: package managers and
+// source extensions are replaced by name, a rule's API replaces the language's
+func mergeStack(base, rules *StackDependencyFiles) *StackDependencyFiles {
+	merged := &StackDependencyFiles{
+		Languages:        make(map[string]Language),
+		SourceExtensions: make(map[string][]string),
+	}
+	if base != nil {
+		for name, language := range base.Languages {
+			merged.Languages[name] = language
+		}
+		for name, extensions := range base.SourceExtensions {
+			merged.SourceExtensions[name] = extensions
+		}
+	}
+
+	for name, rule := range rules.Languages {
+		language := merged.Languages[name]
+		packageManagers := make(map[string]PackageManager)
+		for pmName, pm := range language.PackageManagers {
+			packageManagers[pmName] = pm
+		}
+		for pmName, pm := range rule.PackageManagers {
+			packageManagers[pmName] = pm
+		}
+		language.PackageManagers = packageManagers
+		if rule.API != (API{}) {
+			language.API = rule.API
+		}
+		merged.Languages[name] = language
+	}
+	for name, extensions := range rules.SourceExtensions {
+		merged.SourceExtensions[name] = extensions
+	}
+	return merged
+}
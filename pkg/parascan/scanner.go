@@ -0,0 +1,699 @@
+// Package parascan detects a project's languages, services and tooling. It is the
+// engine behind the para command and can be embedded by other Go tools:
+//
+//	scanner, err := parascan.New(parascan.WithoutGit())
+//	if err != nil {
+//		return err
+//	}
+//	report, err := scanner.Scan(ctx, "./billing")
+//	if err != nil {
+//		return err
+//	}
+//	for key, url := range report.Results {
+//		fmt.Println(key, url)
+//	}
+package parascan
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"parascan/detectors"
+)
+
+// Scanner runs detection against projects with a fixed catalog and options.
+// Scans may run concurrently, each passes its project's options to copies of the
+// plugins. Handlers of WithEvents and WithHook are then called concurrently too.
+type Scanner struct {
+	catalog      *Catalog
+	settings     *ProjectSettings
+	plugins      []*detectors.ExecDetector
+	skipGit      bool
+	gitRemote    string
+	repoURL      string
+	events       func(Event)
+	hooks        []Hook
+	docs         bool
+	maxDepth     int
+	budget       time.Duration
+	memory       uint64
+	linkType     string
+	iacState     bool
+	dotenv       bool
+	langStats    bool
+	deep         bool
+	skipIndirect bool // leave out packages that are dependencies of dependencies
+}
+
+// Option configures a Scanner
+type Option func(*Scanner)
+
+// WithCatalog scans with the given catalog instead of the embedded one
+func WithCatalog(catalog *Catalog) Option {
+	return func(s *Scanner) { s.catalog = catalog }
+}
+
+// WithSettings applies project settings (ignored paths, disabled detectors, min_evidence).
+// Without it, .parascan.yml isn't read: use LoadProjectSettings for the project's own settings.
+func WithSettings(settings *ProjectSettings) Option {
+	return func(s *Scanner) { s.settings = settings }
+}
+
+// WithPlugins runs external detectors alongside the built-in ones
+func WithPlugins(plugins ...*detectors.ExecDetector) Option {
+	return func(s *Scanner) { s.plugins = append(s.plugins, plugins...) }
+}
+
+// WithoutGit doesn't read git metadata, e.g. for exported source trees
+func WithoutGit() Option {
+	return func(s *Scanner) { s.skipGit = true }
+}
+
+// WithGitRemote reads the repository URL from the named git remote instead of origin
+func WithGitRemote(name string) Option {
+	return func(s *Scanner) { s.gitRemote = name }
+}
+
+// WithRepository reports url as the repository instead of reading it from git,
+// e.g. for trees exported from a bare repository
+func WithRepository(url string) Option {
+	return func(s *Scanner) { s.repoURL = url }
+}
+
+// WithDocs also infers services from README and docs, with low confidence
+func WithDocs() Option {
+	return func(s *Scanner) { s.docs = true }
+}
+
+// WithoutTransitive leaves out packages that are only dependencies of dependencies,
+// such as // indirect requirements in go.mod or gems of Gemfile.lock the Gemfile
+// doesn't list. The exclude_transitive setting does the same.
+func WithoutTransitive() Option {
+	return func(s *Scanner) { s.skipIndirect = true }
+}
+
+// WithIaCState also reads resource types from Terraform and Pulumi state files.
+// State holds secrets: nothing but resource types and providers is decoded.
+func WithIaCState() Option {
+	return func(s *Scanner) { s.iacState = true }
+}
+
+// WithDotenv also reads variable names from .env files, not only from templates
+// such as .env.example. Values are never read.
+func WithDotenv() Option {
+	return func(s *Scanner) { s.dotenv = true }
+}
+
+// WithLanguageStats also measures the share of each language in the source files
+// git tracks. Within a budget, counting stops when it runs out.
+func WithLanguageStats() Option {
+	return func(s *Scanner) { s.langStats = true }
+}
+
+// WithDeep also reads the imports of Go, Python and JavaScript/TypeScript source
+// files, finding SDKs that no dependency file lists
+func WithDeep() Option {
+	return func(s *Scanner) { s.deep = true }
+}
+
+// WithMaxDepth limits the directory levels searched for dependency files, 1 is the
+// project root only. Zero keeps DefaultMaxDepth.
+func WithMaxDepth(depth int) Option {
+	return func(s *Scanner) { s.maxDepth = depth }
+}
+
+// WithBudget makes the scan skip expensive steps to finish in about the given
+// time: the deep directory walk, lockfiles, plugins, docs, tasks and language
+// statistics, in that order of need. Skipped steps are listed in the report's BudgetSkipped.
+func WithBudget(budget time.Duration) Option {
+	return func(s *Scanner) { s.budget = budget }
+}
+
+// WithMemoryLimit fails the scan with a MemoryLimitError once the heap grows
+// over the given number of bytes. Dependency files are streamed, so it's mostly
+// reached by huge numbers of them.
+func WithMemoryLimit(bytes uint64) Option {
+	return func(s *Scanner) { s.memory = bytes }
+}
+
+// WithLinkType reports services with their URL of the given type, one of LinkTypes,
+// e.g. "status" for status pages. Services without one keep their dashboard URL.
+func WithLinkType(linkType string) Option {
+	return func(s *Scanner) { s.linkType = linkType }
+}
+
+// New creates a Scanner, loading the embedded catalog unless WithCatalog is given
+func New(opts ...Option) (*Scanner, error) {
+	scanner := &Scanner{}
+	for _, opt := range opts {
+		opt(scanner)
+	}
+	if scanner.catalog == nil {
+		catalog, err := EmbeddedCatalog()
+		if err != nil {
+			return nil, err
+		}
+		scanner.catalog = catalog
+	}
+	if scanner.settings == nil {
+		scanner.settings = &ProjectSettings{}
+	}
+	if scanner.linkType != "" && !IsLinkType(scanner.linkType) {
+		return nil, fmt.Errorf("unknown link type: %s. Supported link types: %s", scanner.linkType, strings.Join(LinkTypes, ", "))
+	}
+	return scanner, nil
+}
+
+// Catalog returns the catalog the scanner detects with
+func (s *Scanner) Catalog() *Catalog {
+	return s.catalog
+}
+
+// Report is the outcome of running all detectors against a project
+type Report struct {
+	Languages     []string
+	LowConfidence bool                         // languages were guessed from source files
+	LanguageFiles []ProjectLanguage            // Languages with their package managers and dependency files
+	Results       map[string]string            // key -> value for parascope.yml
+	Evidence      map[string][]ServiceEvidence // packages, files and detectors behind each result
+	Deprecations  []Deprecation                // deprecated catalog entries among results, keyed by the project's names
+	Warnings      []ConfigWarning              // outdated configuration of detected technologies, e.g. removed CI runners
+	Attention     []AttentionFinding           // attention rules the project matched, most pressing first
+	Kubernetes    *KubernetesManifests         // images, hosts, Secrets and ConfigMaps of Kubernetes manifests
+	Repository    *Repository                  // remotes, hosting and default branch of the git repository
+	Helm          *HelmCharts                  // charts with their dependencies and images
+	Serverless    []ServerlessProject          // Serverless Framework, SAM, CDK and Pulumi configs and where they deploy
+	Fingerprint   string                       // same for every clone of the project, see Fingerprint
+	Skipped       []string                     // services dropped for having fewer signals than min_evidence
+	Inferred      []string                     // services only mentioned in docs, keyed by the project's names
+	Tasks         []Task                       // Makefile targets, justfile recipes and package.json scripts
+	LanguageStats []LanguageShare              // largest first, with WithLanguageStats
+	BudgetSkipped []string                     // steps skipped to stay within WithBudget, e.g. "deep scan"
+	Stats         Stats
+	Errors        []DetectorError
+}
+
+// DetectorError records a detector that failed without aborting the scan
+type DetectorError struct {
+	Detector string
+	Err      error
+}
+
+// Repository is what the git remotes of the project tell about its repository
+type Repository struct {
+	URL           string            `json:"url"`                      // web address, the repo result
+	Upstream      string            `json:"upstream,omitempty"`       // web address of the upstream remote, e.g. of a fork
+	Host          string            `json:"host,omitempty"`           // github, gitlab, bitbucket, codeberg, gitea or azure
+	DefaultBranch string            `json:"default_branch,omitempty"` // of the remote, when the clone recorded it
+	Remotes       map[string]string `json:"remotes,omitempty"`        // web address by remote name
+}
+
+// KubernetesManifests is what the project's Kubernetes manifests and kustomizations
+// tell about its deployment
+type KubernetesManifests struct {
+	Files      []string `json:"files"` // relative to the project
+	Images     []string `json:"images,omitempty"`
+	Hosts      []string `json:"hosts,omitempty"` // Ingress and HTTPRoute hostnames
+	Secrets    []string `json:"secrets,omitempty"`
+	ConfigMaps []string `json:"config_maps,omitempty"`
+}
+
+// ServerlessProject is a project deployed with Serverless Framework, AWS SAM, AWS
+// CDK or Pulumi, and where it deploys to as configured
+type ServerlessProject struct {
+	Tool      string   `json:"tool"`
+	File      string   `json:"file"` // relative to the project
+	Name      string   `json:"name,omitempty"`
+	Provider  string   `json:"provider,omitempty"` // aws, azure, google, ...
+	Region    string   `json:"region,omitempty"`
+	Project   string   `json:"project,omitempty"`  // Google Cloud project
+	Runtimes  []string `json:"runtimes,omitempty"` // e.g. nodejs20.x, or the language of CDK and Pulumi programs
+	Functions int      `json:"functions,omitempty"`
+}
+
+// Scan detects languages and runs detectors in two phases: simple detectors
+// first, then context-aware ones that can use their results. Failing detectors
+// are recorded in the report's Errors, an error is returned for a missing
+// project, a cancelled context or a MemoryLimitError. Hooks of WithHook run last.
+func (s *Scanner) Scan(ctx context.Context, projectPath string) (*Report, error) {
+	if info, err := os.Stat(projectPath); err != nil {
+		return nil, err
+	} else if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", projectPath)
+	}
+
+	catalog, settings := s.catalog, s.settings
+	filter := settings.PathFilter()
+	scan := &Report{Results: make(map[string]string)}
+
+	// On a budget, dependency files are only searched below the root when the
+	// directory walk takes at most a quarter of it
+	budget := newBudget(s.budget)
+	memory := newMemoryMonitor(s.memory)
+	maxDepth := s.maxDepth
+	if s.budget > 0 && maxDepth != 1 {
+		if _, complete := projectDirs(projectPath, filter, maxDepth, budget.deadline(0.75)); !complete {
+			maxDepth = 1
+			scan.BudgetSkipped = append(scan.BudgetSkipped, "deep scan")
+		}
+	}
+
+	// Phase 1: Simple detectors (don't need context)
+	var phase1Detectors []detectors.Detector
+
+	// Create adapter for services dependencies
+	adapter := &ServicesAdapter{
+		stackData:    catalog.Stack,
+		servicesData: catalog.Services,
+		filter:       filter,
+		maxDepth:     maxDepth,
+		linkType:     s.linkType,
+		accounts:     s.settings.Accounts,
+		skipIndirect: s.skipIndirect || settings.ExcludeTransitive,
+	}
+
+	// Lockfiles mostly repeat the manifests, they're analyzed while half the budget is left
+	adapter.skipFile = func(file string) bool {
+		// Over the memory limit the remaining files are skipped, the scan fails after the detector
+		if memory.check() != nil {
+			return true
+		}
+		if isLockfile(file) && !budget.allows(0.5) {
+			if !containsString(scan.BudgetSkipped, "lockfiles") {
+				scan.BudgetSkipped = append(scan.BudgetSkipped, "lockfiles")
+			}
+			return true
+		}
+		scan.Stats.DependencyFiles++
+		s.emitFile(projectPath, file)
+		return false
+	}
+
+	// Add Services detector (simple)
+	servicesDetector := detectors.NewServicesDetector(adapter)
+	phase1Detectors = append(phase1Detectors, detectors.NewSimpleDetectorAdapter(servicesDetector))
+
+	// Add Git detector (simple), the remotes it read are part of the report
+	var gitDetector *detectors.GitRepositoryDetector
+	if !s.skipGit && s.repoURL == "" {
+		gitDetector = &detectors.GitRepositoryDetector{Remote: s.gitRemote}
+		phase1Detectors = append(phase1Detectors, detectors.NewSimpleDetectorAdapter(gitDetector))
+	}
+
+	// Phase 2: Context-aware detectors
+	var phase2Detectors []detectors.Detector
+
+	// Add Files detector (needs context for URL building)
+	filesDetector := detectors.NewFilesDetector(catalog.FileDetectors)
+	phase2Detectors = append(phase2Detectors, filesDetector)
+
+	// Add Docs site detector (needs the repository URL for github.io addresses)
+	phase2Detectors = append(phase2Detectors, detectors.NewDocsSiteDetector())
+
+	// Add Kubernetes detector, the manifests it read are part of the report
+	kubernetesDetector := detectors.NewKubernetesDetector()
+	phase2Detectors = append(phase2Detectors, kubernetesDetector)
+
+	// Add Serverless detector, the deployments it read are part of the report
+	serverlessDetector := detectors.NewServerlessDetector()
+	phase2Detectors = append(phase2Detectors, serverlessDetector)
+
+	// Add external plugins to the phase they declare. They run processes, so on a
+	// budget they're left out when half of it is gone already.
+	for _, plugin := range s.plugins {
+		if !budget.allows(0.5) {
+			scan.BudgetSkipped = append(scan.BudgetSkipped, "plugin "+plugin.Name())
+			continue
+		}
+		// A copy with this project's options, the plugins may be shared by parallel scans
+		plugin = plugin.WithOptions(settings.Detectors[plugin.Name()].Options)
+		if plugin.Phase() == 1 {
+			phase1Detectors = append(phase1Detectors, plugin)
+		} else {
+			phase2Detectors = append(phase2Detectors, plugin)
+		}
+	}
+
+	// Detect languages up front so context-aware detectors can use them.
+	// Fall back to counting source file extensions when no dependency files exist.
+	scan.LanguageFiles = FindProjectLanguages(projectPath, catalog.Stack, filter, maxDepth)
+	for _, language := range scan.LanguageFiles {
+		scan.Languages = append(scan.Languages, language.Name)
+	}
+	if len(scan.Languages) == 0 {
+		scan.Languages = DetectLanguagesByExtension(projectPath, catalog.Stack, filter)
+		scan.LowConfidence = len(scan.Languages) > 0
+		for _, name := range scan.Languages {
+			scan.LanguageFiles = append(scan.LanguageFiles, ProjectLanguage{Name: name, Confidence: "low"})
+		}
+	}
+
+	// Run phase 1 detectors
+	detectionCtx := &detectors.DetectionContext{
+		ProjectPath: projectPath,
+		Languages:   scan.Languages,
+		Filter:      filter,
+		Results:     make(map[string]string),
+		Variables:   settings.Variables,
+	}
+
+	// The detector that reported each result, for those without other evidence
+	origins := make(map[string]string)
+
+	// The repository of an exported tree is known up front
+	if s.repoURL != "" {
+		scan.Results[detectors.RepoKey] = s.repoURL
+		detectionCtx.Results[detectors.RepoKey] = s.repoURL
+		origins[detectors.RepoKey] = "git"
+	}
+
+	for _, detector := range phase1Detectors {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if err := memory.check(); err != nil {
+			return nil, err
+		}
+		if !settings.DetectorEnabled(detector.Name()) {
+			continue
+		}
+		s.emitStarted(detector.Name())
+		results, err := detector.Detect(detectionCtx)
+		if err != nil {
+			scan.Errors = append(scan.Errors, DetectorError{Detector: detector.Name(), Err: err})
+			s.emitFinished(detector.Name(), nil, err)
+			continue
+		}
+		s.emitFinished(detector.Name(), results, nil)
+
+		// Merge results
+		for key, value := range results {
+			scan.Results[key] = value
+			detectionCtx.Results[key] = value // Update context for next phase
+			origins[key] = detector.Name()
+		}
+	}
+
+	// Resources in IaC state are deployed services, state can be large so it's read
+	// while half the budget is left
+	iacState := s.iacState || settings.DetectorOptedIn(IaCStateDetector)
+	if iacState && !budget.allows(0.5) {
+		scan.BudgetSkipped = append(scan.BudgetSkipped, "iac state")
+	} else if iacState {
+		s.emitStarted(IaCStateDetector)
+		results := adapter.detectIaCState(projectPath)
+		s.emitFinished(IaCStateDetector, results, nil)
+		for key, value := range results {
+			scan.Results[key] = value
+			detectionCtx.Results[key] = value
+		}
+	}
+
+	// Variables of environment templates name the services the project has
+	// credentials for, also those without a package
+	if settings.DetectorEnabled(EnvDetector) {
+		s.emitStarted(EnvDetector)
+		results := adapter.detectEnvVars(projectPath, s.dotenv || settings.DetectorOptedIn(DotenvDetector))
+		s.emitFinished(EnvDetector, results, nil)
+		for key, value := range results {
+			scan.Results[key] = value
+			detectionCtx.Results[key] = value
+		}
+	}
+
+	// Content rules find integrations over plain HTTP, without a package. They read
+	// source files too, on a budget they need half of it left.
+	if settings.DetectorEnabled(ContentDetector) && !budget.allows(0.5) {
+		scan.BudgetSkipped = append(scan.BudgetSkipped, "content rules")
+	} else if settings.DetectorEnabled(ContentDetector) {
+		s.emitStarted(ContentDetector)
+		results, err := adapter.detectContent(projectPath, scan.Languages)
+		s.emitFinished(ContentDetector, results, err)
+		if err != nil {
+			scan.Errors = append(scan.Errors, DetectorError{Detector: ContentDetector, Err: err})
+		}
+		for key, value := range results {
+			scan.Results[key] = value
+			detectionCtx.Results[key] = value
+		}
+	}
+
+	// Reading every source file is the slowest step there is, on a budget it needs
+	// half of it left
+	deep := s.deep || settings.DetectorOptedIn(DeepDetector)
+	if deep && !budget.allows(0.5) {
+		scan.BudgetSkipped = append(scan.BudgetSkipped, "deep imports")
+	} else if deep {
+		s.emitStarted(DeepDetector)
+		results := adapter.detectImports(projectPath)
+		s.emitFinished(DeepDetector, results, nil)
+		for key, value := range results {
+			scan.Results[key] = value
+			detectionCtx.Results[key] = value
+		}
+	}
+
+	// Chart dependencies of Helm charts are services deployed alongside the project
+	if settings.DetectorEnabled(HelmDetector) {
+		s.emitStarted(HelmDetector)
+		results, charts := adapter.detectHelmCharts(projectPath)
+		s.emitFinished(HelmDetector, results, nil)
+		for key, value := range results {
+			scan.Results[key] = value
+			detectionCtx.Results[key] = value
+		}
+		scan.Helm = charts
+	}
+
+	// Drop services with too little evidence before file detection can build on them
+	if settings.MinEvidence > 1 {
+		for key, evidence := range adapter.evidence {
+			if _, found := scan.Results[key]; found && weakEvidence(evidence, settings.MinEvidence) {
+				delete(scan.Results, key)
+				delete(detectionCtx.Results, key)
+				scan.Skipped = append(scan.Skipped, key)
+			}
+		}
+		sort.Strings(scan.Skipped)
+	}
+
+	// Run phase 2 detectors with context, url templates may need the default branch
+	if info := gitDetector.Repository(); info != nil {
+		detectionCtx.DefaultBranch = info.DefaultBranch
+	}
+	for _, detector := range phase2Detectors {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if err := memory.check(); err != nil {
+			return nil, err
+		}
+		if !settings.DetectorEnabled(detector.Name()) {
+			continue
+		}
+		s.emitStarted(detector.Name())
+		results, err := detector.Detect(detectionCtx)
+		if err != nil {
+			scan.Errors = append(scan.Errors, DetectorError{Detector: detector.Name(), Err: err})
+			s.emitFinished(detector.Name(), nil, err)
+			continue
+		}
+		s.emitFinished(detector.Name(), results, nil)
+
+		// Merge results
+		for key, value := range results {
+			scan.Results[key] = value
+			origins[key] = detector.Name()
+		}
+	}
+
+	// Keep evidence of services that made it into the results
+	for key, evidence := range adapter.evidence {
+		if _, found := scan.Results[key]; found {
+			sort.Slice(evidence, func(i, j int) bool {
+				if evidence[i].File != evidence[j].File {
+					return evidence[i].File < evidence[j].File
+				}
+				if evidence[i].Package != evidence[j].Package {
+					return evidence[i].Package < evidence[j].Package
+				}
+				if evidence[i].Resource != evidence[j].Resource {
+					return evidence[i].Resource < evidence[j].Resource
+				}
+				if evidence[i].Pattern != evidence[j].Pattern {
+					return evidence[i].Pattern < evidence[j].Pattern
+				}
+				return evidence[i].Variable < evidence[j].Variable
+			})
+			for _, item := range evidence {
+				scan.addEvidence(key, item)
+			}
+		}
+	}
+
+	// README badges corroborate file-detected technologies, and come first as they
+	// point at the project's page of the technology
+	for _, badge := range filesDetector.Badges() {
+		if _, found := scan.Results[badge.Key]; found {
+			scan.addEvidence(badge.Key, ServiceEvidence{Detector: filesDetector.Name(), Keyword: badge.Keyword, File: badge.File})
+		}
+	}
+	for _, match := range filesDetector.Matches() {
+		if _, found := scan.Results[match.Key]; found {
+			scan.addEvidence(match.Key, ServiceEvidence{Detector: filesDetector.Name(), Pattern: match.Pattern, File: match.File})
+		}
+	}
+
+	// Other detectors only tell that they found a result, e.g. git the repository
+	for key, detector := range origins {
+		if _, found := scan.Results[key]; found && len(scan.Evidence[key]) == 0 {
+			scan.addEvidence(key, ServiceEvidence{Detector: detector})
+		}
+	}
+
+	if s.repoURL != "" {
+		scan.Repository = &Repository{URL: s.repoURL, Host: detectors.RepositoryHost(s.repoURL)}
+	} else if info := gitDetector.Repository(); info != nil && info.URL != "" {
+		repository := Repository(*info)
+		scan.Repository = &repository
+	}
+
+	if manifests := kubernetesDetector.Manifests(); len(manifests.Files) > 0 {
+		scan.Kubernetes = &KubernetesManifests{
+			Files:      manifests.Files,
+			Images:     manifests.Images,
+			Hosts:      manifests.Hosts,
+			Secrets:    manifests.Secrets,
+			ConfigMaps: manifests.ConfigMaps,
+		}
+	}
+
+	for _, project := range serverlessDetector.Projects() {
+		scan.Serverless = append(scan.Serverless, ServerlessProject(project))
+	}
+
+	// Documentation is opt-in and only fills gaps left by real evidence
+	docs := s.docs || settings.DetectorOptedIn("docs")
+	if docs && !budget.allows(0.25) {
+		scan.BudgetSkipped = append(scan.BudgetSkipped, "docs")
+	} else if docs {
+		servicesInfo := adapter.GetServicesData()
+		docsDetector := detectors.NewDocsDetector(servicesInfo, filesDetector)
+		docsCtx := &detectors.DetectionContext{
+			ProjectPath:   projectPath,
+			Languages:     scan.Languages,
+			Filter:        filter,
+			Results:       scan.Results,
+			DefaultBranch: detectionCtx.DefaultBranch,
+			Variables:     settings.Variables,
+		}
+		s.emitStarted(docsDetector.Name())
+		results, err := docsDetector.Detect(docsCtx)
+		if err != nil {
+			scan.Errors = append(scan.Errors, DetectorError{Detector: docsDetector.Name(), Err: err})
+		}
+		inferred := make(map[string]string)
+		for _, finding := range docsDetector.Findings() {
+			inferred[finding.Key] = results[finding.Key]
+		}
+		s.emitFinished(docsDetector.Name(), inferred, err)
+		for _, finding := range docsDetector.Findings() {
+			scan.Results[finding.Key] = results[finding.Key]
+			scan.addEvidence(finding.Key, ServiceEvidence{Detector: docsDetector.Name(), Keyword: finding.Keyword, File: finding.File})
+			if !settings.ServiceDisabled(finding.Key) {
+				scan.Inferred = append(scan.Inferred, settings.ResultName(finding.Key))
+			}
+		}
+	}
+
+	// The fingerprint ignores --max-depth and the budget, scans of the same project
+	// from other machines have to get the same one
+	scan.Fingerprint = Fingerprint(projectPath, scan.Results[detectors.RepoKey], catalog.Stack, filter, DefaultMaxDepth)
+
+	if budget.allows(0) {
+		scan.Tasks = DetectTasks(projectPath)
+	} else {
+		scan.BudgetSkipped = append(scan.BudgetSkipped, "tasks")
+	}
+
+	// Language statistics use what's left of the budget, partial ones are still reported
+	if s.langStats || settings.DetectorOptedIn(LanguageStatsDetector) {
+		var complete bool
+		scan.LanguageStats, complete = LanguageStats(projectPath, filter, budget.deadline(0))
+		if !complete {
+			scan.BudgetSkipped = append(scan.BudgetSkipped, "language stats")
+		}
+	}
+
+	// Flag deprecated catalog entries, outdated configuration and findings that need
+	// attention, except for services the project disabled
+	for _, deprecation := range FindDeprecations(catalog, scan.Results) {
+		if !settings.ServiceDisabled(deprecation.Key) {
+			deprecation.Key = settings.ResultName(deprecation.Key)
+			scan.Deprecations = append(scan.Deprecations, deprecation)
+		}
+	}
+	for _, warning := range filesDetector.Warnings() {
+		if _, found := scan.Results[warning.Key]; found && !settings.ServiceDisabled(warning.Key) {
+			scan.Warnings = append(scan.Warnings, ConfigWarning{Key: settings.ResultName(warning.Key), File: warning.File, Line: warning.Line, Message: warning.Message})
+		}
+	}
+	enabled := make(map[string]string)
+	for key, value := range scan.Results {
+		if !settings.ServiceDisabled(key) {
+			enabled[key] = value
+		}
+	}
+	for _, finding := range FindAttention(catalog, projectPath, enabled, filter) {
+		for i, key := range finding.Keys {
+			finding.Keys[i] = settings.ResultName(key)
+		}
+		scan.Attention = append(scan.Attention, finding)
+	}
+
+	if err := memory.check(); err != nil {
+		return nil, err
+	}
+	scan.Stats.Duration = time.Since(budget.start)
+	scan.Stats.PeakMemory = memory.peak
+
+	for _, err := range RunHooks(ctx, scan, s.hooks...) {
+		scan.Errors = append(scan.Errors, DetectorError{Detector: HookDetector, Err: err})
+	}
+	return scan, nil
+}
+
+// addEvidence records a reason for a result
+func (r *Report) addEvidence(key string, evidence ServiceEvidence) {
+	if r.Evidence == nil {
+		r.Evidence = make(map[string][]ServiceEvidence)
+	}
+	r.Evidence[key] = append(r.Evidence[key], evidence)
+}
+
+// weakEvidence reports whether a service has fewer than minEvidence independent signals.
+// Every dependency file listing the service is one signal, framework config and IaC
+// state are enough on their own.
+func weakEvidence(evidence []ServiceEvidence, minEvidence int) bool {
+	files := make(map[string]bool)
+	for _, item := range evidence {
+		if item.Framework != "" || item.Resource != "" {
+			return false
+		}
+		files[item.File] = true
+	}
+	return len(files) < minEvidence
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
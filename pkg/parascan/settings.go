@@ -0,0 +1,195 @@
+package parascan
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+
+	"parascan/detectors"
+)
+
+// SettingsFile holds the project settings in the project root
+const SettingsFile = ".parascan.yml"
+
+// ProjectSettings is the per-repo configuration in .parascan.yml, checked into
+// the repo so every contributor and CI job scans identically
+type ProjectSettings struct {
+	Catalog           CatalogPin                   `yaml:"catalog,omitempty"`
+	Ignore            []string                     `yaml:"ignore,omitempty"`             // paths skipped during detection
+	Include           []string                     `yaml:"include,omitempty"`            // when set, only these paths are scanned
+	Apps              []string                     `yaml:"apps,omitempty"`               // app directory patterns, each app with its own parascope.yml
+	DisabledServices  []string                     `yaml:"disabled_services,omitempty"`  // keys or display names never reported
+	Names             map[string]string            `yaml:"names,omitempty"`              // detected key -> name written to the config
+	MinEvidence       int                          `yaml:"min_evidence,omitempty"`       // dependency files a package-only service must appear in
+	GlobDepth         int                          `yaml:"glob_depth,omitempty"`         // directory levels a ** in catalog patterns spans
+	ExcludeTransitive bool                         `yaml:"exclude_transitive,omitempty"` // leave out services only found as dependencies of dependencies
+	Detectors         map[string]DetectorSettings  `yaml:"detectors,omitempty"`          // per-detector options by detector name
+	Accounts          map[string]map[string]string `yaml:"accounts,omitempty"`           // url_template answers by service key, e.g. sentry: {org: acme}
+	Variables         map[string]string            `yaml:"variables,omitempty"`          // url_template values of file detectors, e.g. org: acme
+	Output            OutputSettings               `yaml:"output,omitempty"`
+}
+
+// DetectorSettings configures a single built-in detector or plugin
+type DetectorSettings struct {
+	Enabled *bool             `yaml:"enabled,omitempty"`
+	Options map[string]string `yaml:"options,omitempty"` // passed to plugins in the request
+}
+
+// OutputSettings are defaults for scan flags, command line flags take precedence
+type OutputSettings struct {
+	Format      string `yaml:"format,omitempty"`
+	ConfigPath  string `yaml:"config_path,omitempty"` // relative to the project
+	ProjectName string `yaml:"project_name,omitempty"`
+	Verbose     bool   `yaml:"verbose,omitempty"`
+	LinkType    string `yaml:"link_type,omitempty"`   // service URL written, see LinkTypes
+	Provenance  bool   `yaml:"provenance,omitempty"`  // record the scan in parascope.yml for para validate
+	Annotate    bool   `yaml:"annotate,omitempty"`    // comment what scans add to an existing parascope.yml
+	Verify      bool   `yaml:"verify,omitempty"`      // re-check the entries of parascope.yml on each scan
+	PruneAfter  int    `yaml:"prune_after,omitempty"` // scans an entry can go unverified before it's a pruning candidate
+	Redact      bool   `yaml:"redact,omitempty"`      // hash names, paths and project URLs in shared output
+	Health      bool   `yaml:"health,omitempty"`      // score the inventory's completeness
+}
+
+// DetectorEnabled reports whether the detector isn't switched off in the settings
+func (s *ProjectSettings) DetectorEnabled(name string) bool {
+	if detector, ok := s.Detectors[name]; ok && detector.Enabled != nil {
+		return *detector.Enabled
+	}
+	return true
+}
+
+// DetectorOptedIn reports whether an opt-in detector is switched on in the settings
+func (s *ProjectSettings) DetectorOptedIn(name string) bool {
+	detector, ok := s.Detectors[name]
+	return ok && detector.Enabled != nil && *detector.Enabled
+}
+
+// PathFilter returns the filter for the ignore and include patterns and glob depth
+func (s *ProjectSettings) PathFilter() *detectors.PathFilter {
+	if len(s.Ignore) == 0 && len(s.Include) == 0 && s.GlobDepth == 0 {
+		return nil
+	}
+	return &detectors.PathFilter{Ignore: s.Ignore, Include: s.Include, GlobDepth: s.GlobDepth}
+}
+
+// ServiceDisabled reports whether the project turned a result key off
+func (s *ProjectSettings) ServiceDisabled(key string) bool {
+	for _, name := range s.DisabledServices {
+		if strings.EqualFold(name, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// DefaultRepositoryName is the key the repository URL is written under in parascope.yml
+const DefaultRepositoryName = "Repository"
+
+// RepositoryName is the key the repository URL is written under in parascope.yml,
+// renamed like other keys through names
+func (s *ProjectSettings) RepositoryName() string {
+	if name := s.Names[detectors.RepoKey]; name != "" {
+		return name
+	}
+	return DefaultRepositoryName
+}
+
+// ResultName applies the project's name override to a result key. The repository
+// keeps its key, RepositoryName names it in the config.
+func (s *ProjectSettings) ResultName(key string) string {
+	if name, ok := s.Names[key]; ok && name != "" && key != detectors.RepoKey {
+		return name
+	}
+	return key
+}
+
+// ApplyToResults drops disabled services and applies name overrides
+func (s *ProjectSettings) ApplyToResults(results map[string]string) map[string]string {
+	applied := make(map[string]string)
+	for key, value := range results {
+		if s.ServiceDisabled(key) {
+			continue
+		}
+		applied[s.ResultName(key)] = value
+	}
+	return applied
+}
+
+// ApplyToEvidence keys evidence the same way as ApplyToResults
+func (s *ProjectSettings) ApplyToEvidence(evidence map[string][]ServiceEvidence) map[string][]ServiceEvidence {
+	applied := make(map[string][]ServiceEvidence)
+	for key, items := range evidence {
+		if !s.ServiceDisabled(key) {
+			applied[s.ResultName(key)] = items
+		}
+	}
+	return applied
+}
+
+// CatalogPin fixes the catalog used for scans of the project
+type CatalogPin struct {
+	Version string `yaml:"version,omitempty"`
+	Hash    string `yaml:"hash,omitempty"`
+	Path    string `yaml:"path,omitempty"` // vendored catalog directory, relative to the project
+}
+
+// Matches reports whether the catalog satisfies the pin
+func (p CatalogPin) Matches(catalog *Catalog) bool {
+	if p.Hash != "" {
+		return p.Hash == catalog.Hash
+	}
+	return p.Version == "" || p.Version == catalog.Version
+}
+
+// LoadProjectSettings reads .parascan.yml from the project root, a missing file means defaults.
+// Patterns from .parascopeignore are added to the ignored paths.
+func LoadProjectSettings(projectPath string) (*ProjectSettings, error) {
+	var settings ProjectSettings
+
+	data, err := os.ReadFile(filepath.Join(projectPath, SettingsFile))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err == nil {
+		if err := yaml.Unmarshal(data, &settings); err != nil {
+			return nil, fmt.Errorf("invalid %s: %v", SettingsFile, err)
+		}
+	}
+
+	ignore, err := loadIgnoreFile(projectPath)
+	if err != nil {
+		return nil, err
+	}
+	settings.Ignore = append(settings.Ignore, ignore...)
+	return &settings, nil
+}
+
+// IgnoreFile lists more ignored paths in gitignore syntax
+const IgnoreFile = ".parascopeignore"
+
+// loadIgnoreFile reads the patterns of .parascopeignore (gitignore syntax), a missing file means none
+func loadIgnoreFile(projectPath string) ([]string, error) {
+	data, err := os.ReadFile(filepath.Join(projectPath, IgnoreFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var patterns []string
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, `\#`) {
+			line = line[1:] // escaped file name starting with #
+		}
+		patterns = append(patterns, line)
+	}
+	return patterns, nil
+}
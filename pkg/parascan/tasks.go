@@ -0,0 +1,130 @@
+package parascan
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Task is a way to build, test or run the project declared in its root
+type Task struct {
+	Name    string `json:"name"`
+	Command string `json:"command"` // how to run it, e.g. "make test"
+	Source  string `json:"source"`  // file the task was found in
+}
+
+// Files tasks are read from, in the order they are listed
+var (
+	makefileNames = []string{"GNUmakefile", "makefile", "Makefile"}
+	justfileNames = []string{"justfile", "Justfile", ".justfile"}
+)
+
+// makeTargetPattern matches rule targets, not variable assignments (NAME := value)
+// or special targets (.PHONY)
+var makeTargetPattern = regexp.MustCompile(`(?m)^([A-Za-z0-9][\w.-]*)\s*:(?:[^=]|$)`)
+
+// justRecipePattern matches recipe headers with optional parameters ("@build target:"),
+// not settings, aliases or assignments (":=")
+var justRecipePattern = regexp.MustCompile(`(?m)^@?([A-Za-z_][\w-]*)(?:\s[^:\n]*)?:(?:[^=]|$)`)
+
+// DetectTasks lists Makefile targets and justfile recipes in file order, then
+// package.json scripts by name. Only the project root is read.
+func DetectTasks(projectPath string) []Task {
+	var tasks []Task
+
+	if name, content := readFirst(projectPath, makefileNames); content != nil {
+		for _, target := range uniqueMatches(makeTargetPattern, content) {
+			tasks = append(tasks, Task{Name: target, Command: "make " + target, Source: name})
+		}
+	}
+
+	if name, content := readFirst(projectPath, justfileNames); content != nil {
+		for _, recipe := range uniqueMatches(justRecipePattern, content) {
+			tasks = append(tasks, Task{Name: recipe, Command: "just " + recipe, Source: name})
+		}
+	}
+
+	if content, err := os.ReadFile(filepath.Join(projectPath, "package.json")); err == nil {
+		var pkg struct {
+			Scripts map[string]string `json:"scripts"`
+		}
+		if json.Unmarshal(content, &pkg) == nil {
+			scripts := make([]string, 0, len(pkg.Scripts))
+			for script := range pkg.Scripts {
+				scripts = append(scripts, script)
+			}
+			sort.Strings(scripts)
+			runner := npmRunner(projectPath)
+			for _, script := range scripts {
+				tasks = append(tasks, Task{Name: script, Command: runner(script), Source: "package.json"})
+			}
+		}
+	}
+
+	return tasks
+}
+
+// ProcfileProcesses lists the processes of the Procfile, what production runs
+func ProcfileProcesses(projectPath string) []Task {
+	file, err := os.Open(filepath.Join(projectPath, "Procfile"))
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	var processes []Task
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		name, command, found := strings.Cut(scanner.Text(), ":")
+		name, command = strings.TrimSpace(name), strings.TrimSpace(command)
+		if found && name != "" && command != "" && !strings.HasPrefix(name, "#") {
+			processes = append(processes, Task{Name: name, Command: command, Source: "Procfile"})
+		}
+	}
+	return processes
+}
+
+// readFirst reads the first of the files that exists in the project root
+func readFirst(projectPath string, names []string) (string, []byte) {
+	for _, name := range names {
+		if content, err := os.ReadFile(filepath.Join(projectPath, name)); err == nil {
+			return name, content
+		}
+	}
+	return "", nil
+}
+
+// uniqueMatches returns the first group of each match, without duplicates, in order
+func uniqueMatches(pattern *regexp.Regexp, content []byte) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, match := range pattern.FindAllSubmatch(content, -1) {
+		name := string(bytes.TrimSpace(match[1]))
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// npmRunner returns how scripts are run with the package manager the lockfile belongs to
+func npmRunner(projectPath string) func(script string) string {
+	for _, lockfile := range []struct{ file, manager string }{{"yarn.lock", "yarn"}, {"pnpm-lock.yaml", "pnpm"}, {"bun.lockb", "bun"}} {
+		if _, err := os.Stat(filepath.Join(projectPath, lockfile.file)); err == nil {
+			manager := lockfile.manager
+			return func(script string) string { return manager + " " + script }
+		}
+	}
+	return func(script string) string {
+		if script == "start" || script == "test" {
+			return "npm " + script
+		}
+		return "npm run " + script
+	}
+}
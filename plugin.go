@@ -0,0 +1,568 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"parascan/detectors"
+	"parascan/pluginsdk"
+)
+
+// SDK source is copied into scaffolded plugins so they build without fetching parascan
+//
+//go:embed pluginsdk/sdk.go
+var pluginSDKSource []byte
+
+// The plugin half of the test harness is copied too, for the scaffolded test
+//
+//go:embed detectortest/detectortest.go
+var pluginTestSource []byte
+
+const pluginsRegistryFile = "plugins.yml"
+
+// Plugin archives are downloaded within the timeout, archives larger than
+// maxPluginArchiveSize or unpacking to more than maxPluginUnpackedSize fail
+const (
+	pluginDownloadTimeout = 2 * time.Minute
+	maxPluginArchiveSize  = 256 << 20
+	maxPluginUnpackedSize = 512 << 20
+)
+
+// pathPluginPrefix names executables on PATH that run as plugins without a manifest,
+// e.g. parascan-detector-billing is the billing plugin
+const pathPluginPrefix = "parascan-detector-"
+
+// PluginsRegistry lists installed plugins in the global config directory
+type PluginsRegistry struct {
+	Plugins []RegisteredPlugin `yaml:"plugins"`
+}
+
+// RegisteredPlugin points to the manifest of an installed plugin
+type RegisteredPlugin struct {
+	Name     string `yaml:"name"`
+	Manifest string `yaml:"manifest"`
+}
+
+func handlePlugin() {
+	if len(os.Args) < 3 {
+		showPluginHelp()
+		return
+	}
+
+	args := os.Args[3:]
+	var err error
+	if !pluginsEnabled && os.Args[2] != "help" && os.Args[2] != "--help" && os.Args[2] != "-h" {
+		fmt.Printf("❌ %v\n", errPluginsDisabled)
+		os.Exit(1)
+	}
+	switch os.Args[2] {
+	case "init":
+		flags := newCommandFlags("plugin init", showPluginHelp)
+		args = flags.parse(args)
+		flags.maxArgs(args, 2)
+		if len(args) < 1 {
+			fmt.Println("Usage: para plugin init <name> [dir]")
+			os.Exit(1)
+		}
+		dir := args[0]
+		if len(args) > 1 {
+			dir = args[1]
+		}
+		err = scaffoldPlugin(args[0], dir)
+	case "install":
+		skipVerify := false
+		flags := newCommandFlags("plugin install", showPluginHelp)
+		flags.BoolVar(&skipVerify, "insecure-skip-verify", false, "")
+		args = flags.parse(args)
+		flags.maxArgs(args, 1)
+		if len(args) == 0 {
+			fmt.Println("Usage: para plugin install <path|url> [--insecure-skip-verify]")
+			os.Exit(1)
+		}
+		err = installPlugin(args[0], skipVerify)
+	case "list":
+		err = listPlugins()
+	case "help", "--help", "-h":
+		showPluginHelp()
+	default:
+		fmt.Println("Unknown plugin command:", os.Args[2])
+		showPluginHelp()
+		os.Exit(1)
+	}
+
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func showPluginHelp() {
+	fmt.Println(`Usage: para plugin <command>
+
+Commands:
+  init <name> [dir]       Scaffold an external detector project
+  install <path|url>      Register a plugin directory, manifest or .tar.gz archive
+  list                    Show installed plugins and parascan-detector-* executables on PATH`)
+}
+
+// scaffoldPlugin creates a buildable Go detector project using the stdio protocol
+func scaffoldPlugin(name, dir string) error {
+	if _, err := os.Stat(dir); err == nil {
+		return fmt.Errorf("%s already exists", dir)
+	}
+
+	manifest, err := yaml.Marshal(pluginsdk.Manifest{
+		Name:        name,
+		Description: "Detects " + name + " usage",
+		Phase:       1,
+		Command:     "./" + name,
+	})
+	if err != nil {
+		return err
+	}
+
+	// The harness imports the SDK copy of the plugin's module
+	testSource := strings.Replace(string(pluginTestSource), `"parascan/pluginsdk"`, strconv.Quote(name+"/internal/pluginsdk"), 1)
+	files := map[string]string{
+		"go.mod":                                fmt.Sprintf("module %s\n\ngo 1.21\n", name),
+		"main.go":                               fmt.Sprintf(pluginMainTemplate, name),
+		"main_test.go":                          fmt.Sprintf(pluginTestTemplate, name),
+		"internal/pluginsdk/sdk.go":             string(pluginSDKSource),
+		"internal/detectortest/detectortest.go": testSource,
+		pluginsdk.ManifestFile:                  string(manifest),
+		"README.md":                             fmt.Sprintf(pluginReadmeTemplate, name),
+	}
+
+	for path, content := range files {
+		fullPath := filepath.Join(dir, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("✨ Created plugin %s in %s\n", name, dir)
+	fmt.Printf("   cd %s && go build -o %s . && para plugin install .\n", dir, name)
+	return nil
+}
+
+// installPlugin registers a local plugin or downloads and unpacks a .tar.gz archive
+func installPlugin(source string, skipVerify bool) error {
+	configDir, err := parascopeConfigDir()
+	if err != nil {
+		return err
+	}
+
+	// Downloaded plugins are verified before they replace an installed copy
+	verify := func(manifestPath string) error {
+		if skipVerify {
+			return nil
+		}
+		manifest, err := readPluginManifest(manifestPath)
+		if err != nil {
+			return err
+		}
+		globalConfig, err := loadGlobalConfig()
+		if err != nil {
+			return err
+		}
+		command := detectors.NewExecDetector(*manifest, filepath.Dir(manifestPath)).Command()
+		if err := verifyPluginExecutable(command, globalConfig.Trust); err != nil {
+			return fmt.Errorf("plugin verification failed: %v", err)
+		}
+		return nil
+	}
+
+	var manifestPath string
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		manifestPath, err = downloadPlugin(source, filepath.Join(configDir, "plugins"), verify)
+		if err != nil {
+			return err
+		}
+	} else {
+		manifestPath = source
+		if info, err := os.Stat(source); err != nil {
+			return err
+		} else if info.IsDir() {
+			manifestPath = filepath.Join(source, pluginsdk.ManifestFile)
+		}
+		if manifestPath, err = filepath.Abs(manifestPath); err != nil {
+			return err
+		}
+		if err := verify(manifestPath); err != nil {
+			return err
+		}
+	}
+
+	manifest, err := readPluginManifest(manifestPath)
+	if err != nil {
+		return err
+	}
+
+	registry, err := loadPluginsRegistry()
+	if err != nil {
+		return err
+	}
+
+	replaced := false
+	for i, plugin := range registry.Plugins {
+		if plugin.Name == manifest.Name {
+			registry.Plugins[i].Manifest = manifestPath
+			replaced = true
+		}
+	}
+	if !replaced {
+		registry.Plugins = append(registry.Plugins, RegisteredPlugin{Name: manifest.Name, Manifest: manifestPath})
+	}
+
+	data, err := yaml.Marshal(registry)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(configDir, 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(configDir, pluginsRegistryFile), data, 0644); err != nil {
+		return err
+	}
+
+	fmt.Printf("✨ Installed plugin %s (%s)\n", manifest.Name, manifestPath)
+	return nil
+}
+
+// downloadPlugin unpacks a .tar.gz plugin archive into pluginsDir and returns its manifest
+// path. The plugin's directory is named after the archive, without the query of its URL.
+// The archive is unpacked and checked by verify next to the directory, which is only
+// replaced once both succeeded.
+func downloadPlugin(archiveURL, pluginsDir string, verify func(manifestPath string) error) (string, error) {
+	if !networkEnabled {
+		return "", errNetworkDisabled
+	}
+	parsed, err := url.Parse(archiveURL)
+	if err != nil {
+		return "", err
+	}
+	name := strings.TrimSuffix(strings.TrimSuffix(path.Base(parsed.Path), ".gz"), ".tar")
+	name = strings.TrimSuffix(name, ".tgz")
+	if name == "" || name == "." || name == "/" || name == ".." {
+		return "", fmt.Errorf("download %s: no archive name in the URL", archiveURL)
+	}
+
+	client := &http.Client{Timeout: pluginDownloadTimeout}
+	resp, err := client.Get(archiveURL)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("download %s: %s", archiveURL, resp.Status)
+	}
+
+	if err := os.MkdirAll(pluginsDir, 0755); err != nil {
+		return "", err
+	}
+	tmp, err := os.MkdirTemp(pluginsDir, "."+name+".tmp-")
+	if err != nil {
+		return "", err
+	}
+	if err := os.Chmod(tmp, 0755); err == nil {
+		err = unpackPlugin(resp.Body, tmp, maxPluginUnpackedSize)
+	}
+	if err == nil {
+		if _, err = os.Stat(filepath.Join(tmp, pluginsdk.ManifestFile)); err != nil {
+			err = fmt.Errorf("archive has no %s", pluginsdk.ManifestFile)
+		}
+	}
+	if err == nil && verify != nil {
+		err = verify(filepath.Join(tmp, pluginsdk.ManifestFile))
+	}
+	targetDir := filepath.Join(pluginsDir, name)
+	if err == nil {
+		if err = removeCacheEntry(targetDir); err == nil {
+			err = os.Rename(tmp, targetDir)
+		}
+	}
+	if err != nil {
+		os.RemoveAll(tmp)
+		return "", err
+	}
+	return filepath.Join(targetDir, pluginsdk.ManifestFile), nil
+}
+
+// unpackPlugin extracts the regular files of a .tar.gz archive flat into dir, reading
+// at most maxPluginArchiveSize compressed and writing at most maxSize bytes in all
+func unpackPlugin(archive io.Reader, dir string, maxSize int64) error {
+	gz, err := gzip.NewReader(io.LimitReader(archive, maxPluginArchiveSize))
+	if err != nil {
+		return fmt.Errorf("plugin archive must be .tar.gz: %v", err)
+	}
+	defer gz.Close()
+
+	remaining := maxSize
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		// Flatten archive structure and refuse path traversal
+		target := filepath.Join(dir, filepath.Base(header.Name))
+		file, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(header.Mode)&0755)
+		if err != nil {
+			return err
+		}
+		written, err := io.Copy(file, io.LimitReader(tr, remaining+1))
+		file.Close()
+		if err != nil {
+			return err
+		}
+		if remaining -= written; remaining < 0 {
+			return fmt.Errorf("plugin archive unpacks to more than %d bytes", maxSize)
+		}
+	}
+}
+
+func listPlugins() error {
+	registry, err := loadPluginsRegistry()
+	if err != nil {
+		return err
+	}
+	pathPlugins := findPathPlugins()
+	if len(registry.Plugins) == 0 && len(pathPlugins) == 0 {
+		fmt.Println("No plugins installed")
+		return nil
+	}
+	for _, plugin := range registry.Plugins {
+		fmt.Printf("  🔌 %s → %s\n", plugin.Name, plugin.Manifest)
+	}
+	for _, manifest := range pathPlugins {
+		if !registry.has(manifest.Name) {
+			fmt.Printf("  🔌 %s → %s (PATH)\n", manifest.Name, manifest.Command)
+		}
+	}
+	return nil
+}
+
+// has reports whether a plugin of that name is installed
+func (r *PluginsRegistry) has(name string) bool {
+	for _, plugin := range r.Plugins {
+		if plugin.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// findPathPlugins returns manifests for the parascan-detector-* executables on PATH,
+// sorted by name. Like for commands, the first one on PATH wins. They run in phase 1
+// without results of other detectors, plugins needing them are installed with a manifest.
+func findPathPlugins() []pluginsdk.Manifest {
+	found := make(map[string]bool)
+	var manifests []pluginsdk.Manifest
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		if dir == "" {
+			continue
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			name := strings.TrimSuffix(entry.Name(), ".exe")
+			if !strings.HasPrefix(name, pathPluginPrefix) || name == pathPluginPrefix {
+				continue
+			}
+			name = strings.TrimPrefix(name, pathPluginPrefix)
+			path := filepath.Join(dir, entry.Name())
+			if found[name] {
+				continue
+			}
+			if _, err := exec.LookPath(path); err != nil {
+				continue // not an executable file
+			}
+			found[name] = true
+			manifests = append(manifests, pluginsdk.Manifest{Name: name, Phase: 1, Command: path})
+		}
+	}
+	sort.Slice(manifests, func(i, j int) bool { return manifests[i].Name < manifests[j].Name })
+	return manifests
+}
+
+// verifyPluginExecutable checks the signature of the plugin binary (<binary>.minisig)
+func verifyPluginExecutable(command string, policy TrustPolicy) error {
+	path, err := exec.LookPath(command)
+	if err != nil {
+		return err
+	}
+	return verifyArtifact(path, policy)
+}
+
+func readPluginManifest(path string) (*pluginsdk.Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var manifest pluginsdk.Manifest
+	if err := yaml.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("invalid manifest %s: %v", path, err)
+	}
+	if manifest.Name == "" || manifest.Command == "" {
+		return nil, fmt.Errorf("manifest %s must declare name and command", path)
+	}
+	return &manifest, nil
+}
+
+func loadPluginsRegistry() (*PluginsRegistry, error) {
+	var registry PluginsRegistry
+
+	configDir, err := parascopeConfigDir()
+	if err != nil {
+		return &registry, nil
+	}
+	data, err := os.ReadFile(filepath.Join(configDir, pluginsRegistryFile))
+	if os.IsNotExist(err) {
+		return &registry, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(data, &registry); err != nil {
+		return nil, fmt.Errorf("invalid %s: %v", pluginsRegistryFile, err)
+	}
+	return &registry, nil
+}
+
+// loadPluginDetectors creates detectors for installed plugins and parascan-detector-*
+// executables on PATH whose executables pass the trust policy. An installed plugin
+// takes precedence over one on PATH with the same name. A plugin that can't be read
+// or verified is left out, the others still load; the error joins those failures.
+func loadPluginDetectors(policy TrustPolicy, skipVerify bool) ([]*detectors.ExecDetector, error) {
+	if !pluginsEnabled {
+		return nil, nil
+	}
+	registry, err := loadPluginsRegistry()
+	if err != nil {
+		return nil, err
+	}
+
+	var plugins []*detectors.ExecDetector
+	var errs []error
+	for _, plugin := range registry.Plugins {
+		manifest, err := readPluginManifest(plugin.Manifest)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("plugin %s: %v", plugin.Name, err))
+			continue
+		}
+		detector := detectors.NewExecDetector(*manifest, filepath.Dir(plugin.Manifest))
+		if !skipVerify {
+			if err := verifyPluginExecutable(detector.Command(), policy); err != nil {
+				errs = append(errs, fmt.Errorf("plugin %s: %v", manifest.Name, err))
+				continue
+			}
+		}
+		plugins = append(plugins, detector)
+	}
+
+	for _, manifest := range findPathPlugins() {
+		if registry.has(manifest.Name) {
+			continue
+		}
+		detector := detectors.NewExecDetector(manifest, "")
+		if !skipVerify {
+			if err := verifyPluginExecutable(detector.Command(), policy); err != nil {
+				errs = append(errs, fmt.Errorf("plugin %s: %v", manifest.Name, err))
+				continue
+			}
+		}
+		plugins = append(plugins, detector)
+	}
+	return plugins, errors.Join(errs...)
+}
+
+const pluginMainTemplate = `package main
+
+import (
+	"os"
+	"path/filepath"
+
+	"%[1]s/internal/pluginsdk"
+)
+
+func main() {
+	pluginsdk.Serve(detect)
+}
+
+// detect receives the scanned project and returns key -> value pairs for parascope.yml
+func detect(req *pluginsdk.Request) (map[string]string, error) {
+	results := make(map[string]string)
+
+	if _, err := os.Stat(filepath.Join(req.ProjectPath, ".%[1]s.yml")); err == nil {
+		results["%[1]s"] = "https://example.com/%[1]s"
+	}
+
+	return results, nil
+}
+`
+
+const pluginTestTemplate = `package main
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"%[1]s/internal/detectortest"
+)
+
+func TestDetect(t *testing.T) {
+	detectortest.RunPlugin(t, detect, fstest.MapFS{
+		".%[1]s.yml": {Data: []byte("enabled: true\n")},
+	}, map[string]string{"%[1]s": "https://example.com/%[1]s"})
+
+	// Projects without the config file have nothing to report
+	detectortest.RunPlugin(t, detect, fstest.MapFS{"README.md": {}}, nil)
+}
+`
+
+const pluginReadmeTemplate = `# %[1]s
+
+External detector plugin for parascan.
+
+parascan runs the command from parascan-plugin.yml, writes a JSON request
+(project_path, languages and the results listed in "needs") to stdin and
+reads {"results": {...}} or {"error": "..."} from stdout.
+
+main_test.go runs detect against small projects with internal/detectortest,
+the harness parascan's own detectors are tested with.
+
+## Build and install
+
+` + "```sh" + `
+go test ./...
+go build -o %[1]s .
+para plugin install .
+` + "```" + `
+`
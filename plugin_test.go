@@ -0,0 +1,357 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"parascan/detectors"
+	"parascan/pkg/parascan"
+	"parascan/pluginsdk"
+)
+
+func TestPathPlugins(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("plugin scripts need a POSIX shell")
+	}
+	binDir := t.TempDir()
+	t.Setenv("PATH", binDir)
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	// A shell script speaks the protocol well enough: it ignores the request on stdin
+	script := "#!/bin/sh\ncat > /dev/null\necho '{\"results\": {\"billing\": \"https://billing.internal.example.com\"}}'\n"
+	if err := os.WriteFile(filepath.Join(binDir, "parascan-detector-billing"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	// Not executable, so not a plugin
+	if err := os.WriteFile(filepath.Join(binDir, "parascan-detector-notes"), []byte(script), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	manifests := findPathPlugins()
+	if len(manifests) != 1 || manifests[0].Name != "billing" || manifests[0].Phase != 1 {
+		t.Fatalf("Expected the billing plugin from PATH, got %+v", manifests)
+	}
+
+	plugins, err := loadPluginDetectors(TrustPolicy{}, false)
+	if err != nil {
+		t.Fatalf("Failed to load plugins: %v", err)
+	}
+	if !pluginsEnabled {
+		// Builds without plugins find them but never run them
+		if len(plugins) > 0 {
+			t.Errorf("Expected no plugins loaded in a noplugins build, got %v", plugins)
+		}
+		return
+	}
+	catalog, err := parascan.EmbeddedCatalog()
+	if err != nil {
+		t.Fatalf("Failed to load catalog: %v", err)
+	}
+	scan := runScan(filepath.Join("testdata", "settings-project"), catalog, ScanOptions{Plugins: plugins, SkipGit: true})
+	if len(scan.Errors) > 0 {
+		t.Fatalf("Expected plugins to run, got %v", scan.Errors)
+	}
+	if scan.Results["billing"] != "https://billing.internal.example.com" {
+		t.Errorf("Expected billing from the PATH plugin, got %v", scan.Results)
+	}
+}
+
+func TestLoadPluginDetectorsSkipsFailures(t *testing.T) {
+	if !pluginsEnabled {
+		t.Skip("plugins are left out of this build")
+	}
+	if runtime.GOOS == "windows" {
+		t.Skip("plugin scripts need a POSIX shell")
+	}
+	binDir := t.TempDir()
+	t.Setenv("PATH", binDir)
+	configHome := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configHome)
+	for _, name := range []string{"billing", "ledger"} {
+		if err := os.WriteFile(filepath.Join(binDir, "parascan-detector-"+name), []byte("#!/bin/sh\necho '{}'\n"), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// ledger's signature doesn't match, the installed flags plugin lost its manifest
+	if err := os.WriteFile(filepath.Join(binDir, "parascan-detector-ledger"+signatureSuffix), []byte("untrusted comment: x\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	registry := "plugins:\n  - name: flags\n    manifest: " + filepath.Join(configHome, "missing", pluginsdk.ManifestFile) + "\n"
+	os.MkdirAll(filepath.Join(configHome, "parascope"), 0755)
+	if err := os.WriteFile(filepath.Join(configHome, "parascope", pluginsRegistryFile), []byte(registry), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	plugins, err := loadPluginDetectors(TrustPolicy{PublicKeys: []string{"not-a-key"}}, false)
+	if len(plugins) != 1 || plugins[0].Name() != "billing" {
+		t.Errorf("Expected the billing plugin to load despite the others, got %v", plugins)
+	}
+	for _, name := range []string{"plugin flags", "plugin ledger"} {
+		if err == nil || !strings.Contains(err.Error(), name) {
+			t.Errorf("Expected an error for %s, got %v", name, err)
+		}
+	}
+}
+
+func TestScaffoldPlugin(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "billing")
+	if err := scaffoldPlugin("billing", dir); err != nil {
+		t.Fatalf("Failed to scaffold plugin: %v", err)
+	}
+	manifest, err := readPluginManifest(filepath.Join(dir, pluginsdk.ManifestFile))
+	if err != nil || manifest.Name != "billing" || manifest.Command != "./billing" || manifest.Phase != 1 {
+		t.Fatalf("Expected the billing manifest, got %+v (%v)", manifest, err)
+	}
+	if err := scaffoldPlugin("billing", dir); err == nil {
+		t.Errorf("Expected an existing directory to be left alone")
+	}
+
+	goTool, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("building the scaffolded plugin needs the go tool")
+	}
+	// The plugin builds on its own and its test passes with the copied harness
+	cmd := exec.Command(goTool, "test", "./...")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOWORK=off", "GOFLAGS=")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Errorf("Expected the scaffolded plugin's test to pass, got %v\n%s", err, output)
+	}
+}
+
+// writePluginManifest writes a manifest for the named plugin into dir
+func writePluginManifest(t *testing.T, dir, name string) string {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, pluginsdk.ManifestFile)
+	if err := os.WriteFile(path, []byte("name: "+name+"\ncommand: ./"+name+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestInstallPlugin(t *testing.T) {
+	configHome := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configHome)
+	dir := t.TempDir()
+
+	// A directory registers its manifest, by absolute path
+	first := writePluginManifest(t, filepath.Join(dir, "v1"), "billing")
+	if err := installPlugin(filepath.Join(dir, "v1"), true); err != nil {
+		t.Fatalf("Failed to install plugin: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(configHome, "parascope", pluginsRegistryFile)); err != nil {
+		t.Fatalf("Expected %s to be created: %v", pluginsRegistryFile, err)
+	}
+	registry, err := loadPluginsRegistry()
+	if err != nil || len(registry.Plugins) != 1 || registry.Plugins[0] != (RegisteredPlugin{Name: "billing", Manifest: first}) {
+		t.Fatalf("Expected the billing plugin registered, got %+v (%v)", registry, err)
+	}
+
+	// Installing the same name again replaces it, another name is added
+	second := writePluginManifest(t, filepath.Join(dir, "v2"), "billing")
+	if err := installPlugin(second, true); err != nil {
+		t.Fatalf("Failed to reinstall plugin: %v", err)
+	}
+	writePluginManifest(t, filepath.Join(dir, "audit"), "audit")
+	if err := installPlugin(filepath.Join(dir, "audit"), true); err != nil {
+		t.Fatalf("Failed to install another plugin: %v", err)
+	}
+	registry, err = loadPluginsRegistry()
+	if err != nil || len(registry.Plugins) != 2 || registry.Plugins[0].Manifest != second || registry.Plugins[1].Name != "audit" {
+		t.Errorf("Expected billing updated and audit added, got %+v (%v)", registry, err)
+	}
+
+	// Manifests need a name and a command
+	invalid := filepath.Join(dir, "invalid", pluginsdk.ManifestFile)
+	if err := os.MkdirAll(filepath.Dir(invalid), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(invalid, []byte("name: nameless\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := installPlugin(invalid, true); err == nil {
+		t.Errorf("Expected a manifest without a command to be rejected")
+	}
+}
+
+func TestDownloadPlugin(t *testing.T) {
+	if !networkEnabled {
+		t.Skip("downloads need the network build")
+	}
+	configHome := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configHome)
+
+	archive := pluginArchive(t, []pluginArchiveEntry{
+		{tar.Header{Name: "billing/", Typeflag: tar.TypeDir, Mode: 0755}, ""},
+		{tar.Header{Name: "billing/" + pluginsdk.ManifestFile, Typeflag: tar.TypeReg, Mode: 0644}, "name: billing\ncommand: ./billing\n"},
+		{tar.Header{Name: "billing/billing", Typeflag: tar.TypeReg, Mode: 0755}, "#!/bin/sh\n"},
+		{tar.Header{Name: "../x", Typeflag: tar.TypeReg, Mode: 0644}, "outside"},
+		{tar.Header{Name: "billing/passwd", Typeflag: tar.TypeSymlink, Linkname: "/etc/passwd"}, ""},
+	})
+	broken := pluginArchive(t, []pluginArchiveEntry{
+		{tar.Header{Name: "billing/billing", Typeflag: tar.TypeReg, Mode: 0755}, "#!/bin/sh\n"},
+	})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/releases/billing.tar.gz" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.URL.Query().Has("broken") {
+			w.Write(broken)
+			return
+		}
+		w.Write(archive)
+	}))
+	defer server.Close()
+
+	// The directory is named after the archive without the query
+	if err := installPlugin(server.URL+"/releases/billing.tar.gz?token=secret", true); err != nil {
+		t.Fatalf("Failed to install the archive: %v", err)
+	}
+	pluginsDir := filepath.Join(configHome, "parascope", "plugins")
+	entriesFound, err := os.ReadDir(pluginsDir)
+	if err != nil || len(entriesFound) != 1 || entriesFound[0].Name() != "billing" {
+		t.Fatalf("Expected only the billing directory in %s, got %v (%v)", pluginsDir, entriesFound, err)
+	}
+	targetDir := filepath.Join(pluginsDir, "billing")
+
+	// Entries are flattened into it, traversal stays inside, links are skipped
+	if info, err := os.Stat(filepath.Join(targetDir, "billing")); err != nil || info.Mode().Perm() != 0755 {
+		t.Errorf("Expected the executable unpacked, got %v (%v)", info, err)
+	}
+	if content, err := os.ReadFile(filepath.Join(targetDir, "x")); err != nil || string(content) != "outside" {
+		t.Errorf("Expected ../x flattened into the plugin directory, got %q (%v)", content, err)
+	}
+	for _, escaped := range []string{filepath.Join(pluginsDir, "x"), filepath.Join(configHome, "parascope", "x")} {
+		if _, err := os.Stat(escaped); !os.IsNotExist(err) {
+			t.Errorf("Expected nothing written to %s, got %v", escaped, err)
+		}
+	}
+	if _, err := os.Lstat(filepath.Join(targetDir, "passwd")); !os.IsNotExist(err) {
+		t.Errorf("Expected the symlink skipped, got %v", err)
+	}
+	registry, err := loadPluginsRegistry()
+	if err != nil || len(registry.Plugins) != 1 || registry.Plugins[0].Manifest != filepath.Join(targetDir, pluginsdk.ManifestFile) {
+		t.Errorf("Expected the downloaded manifest registered, got %+v (%v)", registry, err)
+	}
+
+	if err := installPlugin(server.URL+"/releases/missing.tar.gz", true); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("Expected the missing archive to fail, got %v", err)
+	}
+
+	// A failed update leaves the installed plugin alone and nothing half unpacked
+	if err := installPlugin(server.URL+"/releases/billing.tar.gz?broken", true); err == nil {
+		t.Error("Expected an archive without manifest to fail")
+	}
+	if _, err := os.Stat(filepath.Join(targetDir, pluginsdk.ManifestFile)); err != nil {
+		t.Errorf("Expected the installed plugin kept, got %v", err)
+	}
+	if entriesFound, _ := os.ReadDir(pluginsDir); len(entriesFound) != 1 {
+		t.Errorf("Expected the temporary directory removed, got %v", entriesFound)
+	}
+}
+
+func TestUnpackPluginLimit(t *testing.T) {
+	// Highly compressible entries count against the unpacked size together
+	archive := pluginArchive(t, []pluginArchiveEntry{
+		{tar.Header{Name: "a", Typeflag: tar.TypeReg, Mode: 0644}, strings.Repeat("0", 600)},
+		{tar.Header{Name: "b", Typeflag: tar.TypeReg, Mode: 0644}, strings.Repeat("0", 600)},
+	})
+	if err := unpackPlugin(bytes.NewReader(archive), t.TempDir(), 1000); err == nil || !strings.Contains(err.Error(), "more than 1000 bytes") {
+		t.Errorf("Expected the unpacked size limit to apply across entries, got %v", err)
+	}
+	if err := unpackPlugin(bytes.NewReader(archive), t.TempDir(), 1200); err != nil {
+		t.Errorf("Expected an archive at the limit to unpack, got %v", err)
+	}
+}
+
+type pluginArchiveEntry struct {
+	header  tar.Header
+	content string
+}
+
+// pluginArchive builds a .tar.gz archive of the entries
+func pluginArchive(t *testing.T, entries []pluginArchiveEntry) []byte {
+	t.Helper()
+	var archive bytes.Buffer
+	gz := gzip.NewWriter(&archive)
+	tw := tar.NewWriter(gz)
+	for _, entry := range entries {
+		entry.header.Size = int64(len(entry.content))
+		if err := tw.WriteHeader(&entry.header); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(entry.content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return archive.Bytes()
+}
+
+func TestExecDetector(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("plugin scripts need a POSIX shell")
+	}
+	dir := t.TempDir()
+	requestPath := filepath.Join(dir, "request.json")
+	detector := func(name, script string, needs ...string) *detectors.ExecDetector {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"+script), 0755); err != nil {
+			t.Fatal(err)
+		}
+		return detectors.NewExecDetector(pluginsdk.Manifest{Name: name, Needs: needs, Command: "./" + name}, dir)
+	}
+	ctx := &detectors.DetectionContext{
+		ProjectPath: "testdata",
+		Languages:   []string{"go"},
+		Results:     map[string]string{"repo": "https://github.com/acme/billing", "stripe": "https://dashboard.stripe.com"},
+	}
+
+	// The request has the absolute project path, the options and only the needed results
+	results, err := detector("billing", "cat > "+requestPath+"\necho '{\"results\": {\"billing\": \"https://billing.example.com\"}}'\n", "repo").
+		WithOptions(map[string]string{"region": "eu"}).Detect(ctx)
+	if err != nil || results["billing"] != "https://billing.example.com" {
+		t.Fatalf("Expected the plugin's results, got %v (%v)", results, err)
+	}
+	var request pluginsdk.Request
+	content, _ := os.ReadFile(requestPath)
+	if err := json.Unmarshal(content, &request); err != nil {
+		t.Fatalf("Invalid request %s: %v", content, err)
+	}
+	absPath, _ := filepath.Abs("testdata")
+	if request.Protocol != pluginsdk.ProtocolVersion || request.ProjectPath != absPath || request.Options["region"] != "eu" ||
+		len(request.Results) != 1 || request.Results["repo"] == "" || len(request.Languages) != 1 {
+		t.Errorf("Unexpected request %s", content)
+	}
+
+	for _, tc := range []struct {
+		name, script, expected string
+	}{
+		{"failing", "cat > /dev/null\necho '{\"error\": \"no credentials\"}'\n", "plugin error: no credentials"},
+		{"garbled", "cat > /dev/null\necho 'not json'\n", "invalid plugin response"},
+		{"crashing", "cat > /dev/null\necho 'out of memory' >&2\nexit 3\n", "plugin failed: exit status 3 out of memory"},
+	} {
+		if _, err := detector(tc.name, tc.script).Detect(ctx); err == nil || !strings.Contains(err.Error(), tc.expected) {
+			t.Errorf("%s: expected an error with %q, got %v", tc.name, tc.expected, err)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+// Package pluginsdk implements the JSON-over-stdio protocol spoken between
+// parascan and external detector plugins.
+//
+// A plugin is an executable that reads a single Request from stdin and
+// writes a single Response to stdout. It is described by a manifest file
+// (parascan-plugin.yml) declaring its name, phase and the results it needs.
+package pluginsdk
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// ProtocolVersion is the version of the stdio protocol
+const ProtocolVersion = 1
+
+// ManifestFile is the file name of the plugin manifest
+const ManifestFile = "parascan-plugin.yml"
+
+// Manifest describes an external detector plugin
+type Manifest struct {
+	Name        string   `yaml:"name" json:"name"`
+	Description string   `yaml:"description,omitempty" json:"description,omitempty"`
+	Phase       int      `yaml:"phase,omitempty" json:"phase,omitempty"` // 1 - runs with built-in simple detectors, 2 - runs after them with context
+	Needs       []string `yaml:"needs,omitempty" json:"needs,omitempty"` // result keys from previous detectors passed to the plugin (e.g. repo)
+	Command     string   `yaml:"command" json:"command"`                 // executable, relative to the manifest directory
+}
+
+// Request is sent by parascan to the plugin on stdin
+type Request struct {
+	Protocol    int               `json:"protocol"`
+	ProjectPath string            `json:"project_path"`
+	Languages   []string          `json:"languages,omitempty"`
+	Results     map[string]string `json:"results,omitempty"`
+	Options     map[string]string `json:"options,omitempty"` // from the project's .parascan.yml
+}
+
+// Response is written by the plugin to stdout
+type Response struct {
+	Results map[string]string `json:"results,omitempty"` // key -> value for parascope.yml
+	Error   string            `json:"error,omitempty"`
+}
+
+// DetectFunc is implemented by plugin authors
+type DetectFunc func(req *Request) (map[string]string, error)
+
+// Serve runs detect against the request on stdin and writes the response to stdout.
+// It exits with a non-zero status if the request cannot be read or detect fails.
+func Serve(detect DetectFunc) {
+	if err := ServeIO(os.Stdin, os.Stdout, detect); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// ServeIO is Serve with explicit input and output, useful in plugin tests
+func ServeIO(in io.Reader, out io.Writer, detect DetectFunc) error {
+	var req Request
+	if err := json.NewDecoder(in).Decode(&req); err != nil {
+		return writeResponse(out, Response{Error: fmt.Sprintf("invalid request: %v", err)})
+	}
+	if req.Protocol > ProtocolVersion {
+		return writeResponse(out, Response{Error: fmt.Sprintf("unsupported protocol version %d", req.Protocol)})
+	}
+
+	results, err := detect(&req)
+	if err != nil {
+		return writeResponse(out, Response{Error: err.Error()})
+	}
+
+	return writeResponse(out, Response{Results: results})
+}
+
+func writeResponse(out io.Writer, resp Response) error {
+	if err := json.NewEncoder(out).Encode(resp); err != nil {
+		return err
+	}
+	if resp.Error != "" {
+		return fmt.Errorf("%s", resp.Error)
+	}
+	return nil
+}
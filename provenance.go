@@ -0,0 +1,309 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Exit codes of para validate, 1 is an error
+const (
+	validateExitVerified = 0
+	validateExitEdited   = 2
+)
+
+// Provenance records which scan wrote a project section of parascope.yml. It's
+// kept as a comment at the end of the section, so the config stays plain YAML:
+//
+//	# provenance: tool=v0.8.0 catalog=2024.10.1 scanned=2026-10-15T09:30:00Z hash=sha256:9f86d0...
+type Provenance struct {
+	Tool    string    `json:"tool"`
+	Catalog string    `json:"catalog"`
+	Scanned time.Time `json:"scanned"`
+	Hash    string    `json:"hash"` // of the section's entries as the scan wrote them
+}
+
+var provenanceComment = regexp.MustCompile(`^\s*# provenance: (.*)$`)
+
+// comment renders the provenance line of a section, indented like its entries
+func (p Provenance) comment() string {
+	return fmt.Sprintf("  # provenance: tool=%s catalog=%s scanned=%s hash=%s", p.Tool, p.Catalog, p.Scanned.UTC().Format(time.RFC3339), p.Hash)
+}
+
+// parseProvenance reads a provenance comment line
+func parseProvenance(line string) (Provenance, bool) {
+	match := provenanceComment.FindStringSubmatch(line)
+	if match == nil {
+		return Provenance{}, false
+	}
+	var p Provenance
+	for _, field := range strings.Fields(match[1]) {
+		key, value, _ := strings.Cut(field, "=")
+		switch key {
+		case "tool":
+			p.Tool = value
+		case "catalog":
+			p.Catalog = value
+		case "scanned":
+			p.Scanned, _ = time.Parse(time.RFC3339, value)
+		case "hash":
+			p.Hash = value
+		}
+	}
+	return p, p.Hash != ""
+}
+
+// entriesHash is the content hash of a section's entries: names and values,
+// independent of their order, comments and formatting
+func entriesHash(entries map[string]string) string {
+	hash := sha256.New()
+	for _, name := range sortedKeys(entries) {
+		fmt.Fprintf(hash, "%s\t%s\n", name, entries[name])
+	}
+	return "sha256:" + hex.EncodeToString(hash.Sum(nil))
+}
+
+// splitConfigSections splits parascope.yml at its root keys. Each section starts
+// with its root key line, except for comments before the first one.
+func splitConfigSections(content string) []string {
+	var sections, current []string
+	for _, line := range strings.Split(content, "\n") {
+		if len(line) > 0 && line[0] != ' ' && line[0] != '\t' && line[0] != '#' && strings.HasSuffix(strings.TrimSpace(line), ":") {
+			if len(current) > 0 {
+				sections = append(sections, strings.Join(current, "\n"))
+			}
+			current = []string{line}
+		} else {
+			current = append(current, line)
+		}
+	}
+	if len(current) > 0 {
+		sections = append(sections, strings.Join(current, "\n"))
+	}
+	return sections
+}
+
+// joinConfigSections joins sections into a config, separated by empty lines
+func joinConfigSections(sections []string) string {
+	var nonEmpty []string
+	for _, section := range sections {
+		if trimmed := strings.TrimSpace(section); trimmed != "" {
+			nonEmpty = append(nonEmpty, trimmed)
+		}
+	}
+	if len(nonEmpty) == 0 {
+		return ""
+	}
+	return strings.Join(nonEmpty, "\n\n") + "\n"
+}
+
+// sectionRootKey returns the root key a section starts with, "" for leading comments
+func sectionRootKey(section string) string {
+	line, _, _ := strings.Cut(section, "\n")
+	if line == "" || line[0] == ' ' || line[0] == '\t' || line[0] == '#' {
+		return ""
+	}
+	return strings.TrimSuffix(strings.TrimSpace(line), ":")
+}
+
+// sectionEntries parses the entries of a section, nil when it isn't a YAML mapping.
+// The ignore list isn't an entry.
+func sectionEntries(section string) map[string]string {
+	var data map[string]map[string]interface{}
+	if err := yaml.Unmarshal([]byte(section), &data); err != nil {
+		return nil
+	}
+	entries := make(map[string]string)
+	for _, values := range data {
+		for name, value := range values {
+			if name != ignoreKey {
+				entries[name] = fmt.Sprint(value)
+			}
+		}
+	}
+	return entries
+}
+
+// findConfigSection returns the index of the project's section: the last one named
+// projectName, or else the first listing repoURL under a repository key. -1 if none.
+func findConfigSection(sections []string, projectName, repoURL, repositoryName string) int {
+	found := -1
+	for i, section := range sections {
+		if sectionRootKey(section) == projectName {
+			found = i
+		}
+	}
+	if found >= 0 || repoURL == "" {
+		return found
+	}
+	for i, section := range sections {
+		for name, value := range sectionEntries(section) {
+			if isRepositoryKey(name, repositoryName) && urlKey(value) == urlKey(repoURL) {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// sectionProvenance splits the provenance comment off a section
+func sectionProvenance(section string) (string, *Provenance) {
+	var provenance *Provenance
+	lines := strings.Split(section, "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		if p, ok := parseProvenance(line); ok {
+			provenance = &p
+			continue
+		}
+		kept = append(kept, line)
+	}
+	return strings.Join(kept, "\n"), provenance
+}
+
+// writeProvenance records the scan at the end of the project's section, replacing
+// the provenance of earlier scans
+func writeProvenance(configPath, projectName, repoURL, repositoryName string, provenance Provenance) error {
+	content, err := os.ReadFile(configPath)
+	if err != nil {
+		return err
+	}
+	sections := splitConfigSections(string(content))
+	i := findConfigSection(sections, projectName, repoURL, repositoryName)
+	if i < 0 {
+		return nil
+	}
+	section, _ := sectionProvenance(sections[i])
+	provenance.Hash = entriesHash(sectionEntries(section))
+	sections[i] = strings.TrimRight(section, "\n") + "\n" + provenance.comment()
+	return os.WriteFile(configPath, []byte(joinConfigSections(sections)), 0644)
+}
+
+// SectionValidation is the provenance check of one project section
+type SectionValidation struct {
+	Project    string      `json:"project"`
+	Status     string      `json:"status"` // verified, edited or unknown (no provenance)
+	Provenance *Provenance `json:"provenance,omitempty"`
+}
+
+// validateConfig checks the entries of every section against their provenance
+func validateConfig(configPath string) ([]SectionValidation, error) {
+	content, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, err
+	}
+	content = readRecoveredConfig(configPath, content)
+	var config map[string]map[string]interface{}
+	if err := yaml.Unmarshal(content, &config); err != nil {
+		return nil, fmt.Errorf("%s: %v", configPath, err)
+	}
+
+	var validations []SectionValidation
+	for _, section := range splitConfigSections(string(content)) {
+		project := sectionRootKey(section)
+		if project == "" {
+			continue
+		}
+		validations = append(validations, validateSection(project, section))
+	}
+	sort.SliceStable(validations, func(i, j int) bool { return validations[i].Project < validations[j].Project })
+	return validations, nil
+}
+
+func validateSection(project, section string) SectionValidation {
+	section, provenance := sectionProvenance(section)
+	validation := SectionValidation{Project: project, Status: "unknown", Provenance: provenance}
+	if provenance == nil {
+		return validation
+	}
+	validation.Status = "edited"
+	if entriesHash(sectionEntries(section)) == provenance.Hash {
+		validation.Status = "verified"
+	}
+	return validation
+}
+
+// writeValidation prints the provenance checks for people
+func writeValidation(w io.Writer, configPath string, validations []SectionValidation) {
+	for _, v := range validations {
+		switch v.Status {
+		case "verified":
+			fmt.Fprintf(w, "✅ %s: as written by the scan of %s (parascan %s, catalog %s)\n", v.Project, v.Provenance.Scanned.Format(time.RFC3339), v.Provenance.Tool, v.Provenance.Catalog)
+		case "edited":
+			fmt.Fprintf(w, "✏️  %s: edited by hand since the scan of %s\n", v.Project, v.Provenance.Scanned.Format(time.RFC3339))
+		default:
+			fmt.Fprintf(w, "⚪ %s: no provenance, run `para scan --provenance` to record it\n", v.Project)
+		}
+	}
+	if len(validations) == 0 {
+		fmt.Fprintf(w, "⚪ %s has no project sections\n", configPath)
+	}
+}
+
+func handleValidate() {
+	configPath := "parascope.yml"
+	format := "text"
+
+	flags := newCommandFlags("validate", showValidateHelp)
+	flags.StringVar(&format, "format", format, "")
+	flags.StringVar(&format, "f", format, "")
+	args := flags.parse(os.Args[2:])
+	flags.maxArgs(args, 1)
+	if len(args) == 1 {
+		// A directory is validated through its parascope.yml, like para scan
+		if strings.HasSuffix(args[0], ".yml") || strings.HasSuffix(args[0], ".yaml") {
+			configPath = args[0]
+		} else {
+			configPath = filepath.Join(args[0], "parascope.yml")
+		}
+	}
+	if format != "text" && format != "json" {
+		flags.fail("Unknown format: %s. Supported formats: text, json", format)
+	}
+
+	validations, err := validateConfig(configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		os.Exit(1)
+	}
+	if format == "json" {
+		data, _ := json.MarshalIndent(map[string]interface{}{"config": configPath, "sections": validations}, "", "  ")
+		fmt.Println(string(data))
+	} else {
+		writeValidation(os.Stdout, configPath, validations)
+	}
+	for _, v := range validations {
+		if v.Status == "edited" {
+			os.Exit(validateExitEdited)
+		}
+	}
+	os.Exit(validateExitVerified)
+}
+
+func showValidateHelp() {
+	fmt.Println(`Usage: para validate [path] [--format text|json]
+
+Check parascope.yml against the provenance recorded by para scan --provenance:
+each project section whose entries still hash to the value the scan wrote is
+verified, others were edited by hand since. Sections without provenance are
+listed but don't fail.
+
+Exit codes: 0 when no section was edited, 2 when one was, 1 on errors.
+
+Options:
+  --format, -f     text (default) or json
+
+Examples:
+  para validate
+  para validate ./my-project/parascope.yml -f json`)
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProvenance(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "parascope.yml")
+	scanned := time.Date(2026, 10, 15, 9, 30, 0, 0, time.UTC)
+	report := &ScanReport{
+		ConfigPath:  configPath,
+		ProjectName: "billing",
+		Results:     map[string]string{"stripe": "https://dashboard.stripe.com", "sentry": "https://sentry.io"},
+		Provenance:  &Provenance{Tool: "v0.8.0", Catalog: "2024.10.1", Scanned: scanned},
+	}
+	if err := writeOutput(OutputTarget{Format: "yml-config"}, report); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	// A rescan replaces the provenance instead of adding another
+	report.Provenance.Scanned = scanned.Add(time.Hour)
+	if err := writeOutput(OutputTarget{Format: "yml-config"}, report); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	content, _ := os.ReadFile(configPath)
+	if strings.Count(string(content), "# provenance:") != 1 || !strings.Contains(string(content), "  # provenance: tool=v0.8.0 catalog=2024.10.1 scanned=2026-10-15T10:30:00Z hash=sha256:") {
+		t.Fatalf("Expected one provenance comment in the section, got:\n%s", content)
+	}
+
+	validations, err := validateConfig(configPath)
+	if err != nil || len(validations) != 1 || validations[0].Status != "verified" {
+		t.Fatalf("Expected the scanned section verified, got %+v (%v)", validations, err)
+	}
+	if !validations[0].Provenance.Scanned.Equal(scanned.Add(time.Hour)) {
+		t.Errorf("Expected the time of the last scan, got %v", validations[0].Provenance.Scanned)
+	}
+
+	// Reformatting isn't an edit, changing an entry is
+	reordered := "billing:\n  Sentry: https://sentry.io\n  # payments\n  Stripe: https://dashboard.stripe.com\n" + strings.SplitAfter(string(content), "Stripe: https://dashboard.stripe.com\n")[1]
+	if err := os.WriteFile(configPath, []byte(reordered), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if validations, _ := validateConfig(configPath); validations[0].Status != "verified" {
+		t.Errorf("Expected reordered entries verified, got %+v", validations)
+	}
+	edited := strings.Replace(reordered, "https://sentry.io", "https://acme.sentry.io", 1) + "\ndocs:\n  Notion: https://notion.so\n"
+	if err := os.WriteFile(configPath, []byte(edited), 0644); err != nil {
+		t.Fatal(err)
+	}
+	validations, _ = validateConfig(configPath)
+	if len(validations) != 2 || validations[0].Status != "edited" || validations[1].Project != "docs" || validations[1].Status != "unknown" {
+		t.Errorf("Expected billing edited and docs without provenance, got %+v", validations)
+	}
+}
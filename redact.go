@@ -0,0 +1,245 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"path"
+	"strings"
+
+	"parascan/detectors"
+	"parascan/pkg/parascan"
+)
+
+// redactor replaces what identifies a project in shared output with stable hashes:
+// project and component names, file paths and project-specific URLs such as
+// https://acme.sentry.io or repository links. The same value always gets the same
+// hash, so redacted results can still be compared.
+type redactor struct {
+	public    map[string]bool // catalog URLs, the same for every project
+	wellKnown map[string]bool // file names of the catalog, e.g. package.json
+}
+
+func newRedactor(catalog *parascan.Catalog) *redactor {
+	r := &redactor{public: make(map[string]bool), wellKnown: make(map[string]bool)}
+	for _, service := range catalog.Services {
+		r.public[urlKey(service.URL)] = true
+		for _, link := range service.Links {
+			r.public[urlKey(link)] = true
+		}
+	}
+	addFile := func(pattern string) {
+		// Patterns such as *.gemspec match names chosen by the project
+		if base := path.Base(pattern); !strings.ContainsAny(base, "*?[") {
+			r.wellKnown[base] = true
+		}
+	}
+	if catalog.Stack != nil {
+		for _, language := range catalog.Stack.Languages {
+			for _, manager := range language.PackageManagers {
+				for _, file := range manager.Files {
+					addFile(file)
+				}
+			}
+		}
+	}
+	if catalog.FileDetectors != nil {
+		for _, technology := range catalog.FileDetectors.Technologies {
+			if technology.URLTemplate == "" && !strings.Contains(technology.FallbackURL, "{") {
+				r.public[urlKey(technology.FallbackURL)] = true
+			}
+			for _, file := range detectors.RulePatterns(technology.Files) {
+				addFile(file)
+			}
+		}
+	}
+	return r
+}
+
+// redactedName is the stable hash standing in for a name
+func redactedName(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return "redacted-" + hex.EncodeToString(sum[:4])
+}
+
+// name redacts a project or component name, "" stays empty
+func (r *redactor) name(value string) string {
+	if value == "" {
+		return ""
+	}
+	return redactedName(value)
+}
+
+// path redacts the directories of a relative path and file names the catalog
+// doesn't know, keeping their extension: services/billing/package.json becomes
+// redacted-1a2b3c4d/redacted-5e6f7a8b/package.json
+func (r *redactor) path(value string) string {
+	if value == "" || value == "." {
+		return value
+	}
+	parts := strings.Split(value, "/")
+	for i, part := range parts {
+		switch {
+		case i == len(parts)-1 && r.wellKnown[part]:
+		case i == len(parts)-1:
+			ext := path.Ext(part)
+			parts[i] = redactedName(strings.TrimSuffix(part, ext)) + ext
+		default:
+			parts[i] = redactedName(part)
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+// url keeps catalog URLs and reduces others to the service's domain and a hash:
+// https://acme.sentry.io/issues becomes https://sentry.io/redacted-1a2b3c4d
+func (r *redactor) url(value string) string {
+	if value == "" || r.public[urlKey(value)] {
+		return value
+	}
+	u, err := url.Parse(value)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return redactedName(value)
+	}
+	labels := strings.Split(u.Hostname(), ".")
+	if len(labels) > 2 {
+		labels = labels[len(labels)-2:]
+	}
+	return u.Scheme + "://" + strings.Join(labels, ".") + "/" + redactedName(urlKey(value))
+}
+
+// image keeps official images such as postgres:16 and redacts those of a registry
+// or organization, e.g. ghcr.io/acme/api:1.4.2
+func (r *redactor) image(value string) string {
+	if !strings.Contains(value, "/") {
+		return value
+	}
+	return redactedName(value)
+}
+
+// each redacts every value of a list
+func (r *redactor) each(values []string, redact func(string) string) []string {
+	var redacted []string
+	for _, value := range values {
+		redacted = append(redacted, redact(value))
+	}
+	return redacted
+}
+
+// report returns a redacted copy of a scan report, the report itself is unchanged
+func (r *redactor) report(report *ScanReport) *ScanReport {
+	redacted := *report
+	redacted.ProjectName = r.name(report.ProjectName)
+	redacted.AppDir = r.path(report.AppDir)
+
+	redacted.Results = make(map[string]string, len(report.Results))
+	for key, value := range report.Results {
+		if host := strings.TrimPrefix(key, detectors.IngressPrefix); host != key {
+			key = detectors.IngressPrefix + redactedName(host)
+		}
+		redacted.Results[key] = r.url(value)
+	}
+	redacted.Evidence = make(map[string][]parascan.ServiceEvidence, len(report.Evidence))
+	for key, evidence := range report.Evidence {
+		if host := strings.TrimPrefix(key, detectors.IngressPrefix); host != key {
+			key = detectors.IngressPrefix + redactedName(host)
+		}
+		for _, e := range evidence {
+			e.File = r.path(e.File)
+			if strings.Contains(e.Keyword, "://") {
+				e.Keyword = r.url(e.Keyword)
+			}
+			redacted.Evidence[key] = append(redacted.Evidence[key], e)
+		}
+	}
+	redacted.LanguageFiles = nil
+	for _, language := range report.LanguageFiles {
+		var managers []parascan.PackageManagerFiles
+		for _, pm := range language.PackageManagers {
+			managers = append(managers, parascan.PackageManagerFiles{Name: pm.Name, Files: r.each(pm.Files, r.path)})
+		}
+		language.PackageManagers = managers
+		redacted.LanguageFiles = append(redacted.LanguageFiles, language)
+	}
+	redacted.Warnings = nil
+	for _, warning := range report.Warnings {
+		warning.File = r.path(warning.File)
+		redacted.Warnings = append(redacted.Warnings, warning)
+	}
+	redacted.Attention = nil
+	for _, finding := range report.Attention {
+		finding.File = r.path(finding.File)
+		redacted.Attention = append(redacted.Attention, finding)
+	}
+	if manifests := report.Kubernetes; manifests != nil {
+		redacted.Kubernetes = &parascan.KubernetesManifests{
+			Files:      r.each(manifests.Files, r.path),
+			Images:     r.each(manifests.Images, r.image),
+			Hosts:      r.each(manifests.Hosts, r.name),
+			Secrets:    r.each(manifests.Secrets, r.name),
+			ConfigMaps: r.each(manifests.ConfigMaps, r.name),
+		}
+	}
+	if charts := report.Helm; charts != nil {
+		redacted.Helm = &parascan.HelmCharts{
+			Charts: r.each(charts.Charts, r.path),
+			Images: r.each(charts.Images, r.image),
+		}
+		for _, dependency := range charts.Dependencies {
+			dependency.Repository = r.url(dependency.Repository)
+			redacted.Helm.Dependencies = append(redacted.Helm.Dependencies, dependency)
+		}
+	}
+	// The repository's hosting stays, its addresses and branch are the project's own
+	if repository := report.Repository; repository != nil {
+		redacted.Repository = &parascan.Repository{URL: r.url(repository.URL), Upstream: r.url(repository.Upstream), Host: repository.Host, DefaultBranch: r.name(repository.DefaultBranch)}
+		for name, remote := range repository.Remotes {
+			if redacted.Repository.Remotes == nil {
+				redacted.Repository.Remotes = make(map[string]string)
+			}
+			redacted.Repository.Remotes[name] = r.url(remote)
+		}
+	}
+	redacted.Serverless = nil
+	for _, project := range report.Serverless {
+		project.File = r.path(project.File)
+		project.Name = r.name(project.Name)
+		project.Project = r.name(project.Project)
+		redacted.Serverless = append(redacted.Serverless, project)
+	}
+	redacted.Tasks = nil
+	for _, task := range report.Tasks {
+		task.Source = r.path(task.Source)
+		redacted.Tasks = append(redacted.Tasks, task)
+	}
+
+	graph := report.graph()
+	redactedGraph := &ComponentGraph{}
+	for _, component := range graph.Components {
+		redactedGraph.Components = append(redactedGraph.Components, Component{Name: r.name(component.Name), Path: r.path(component.Path), Kind: component.Kind})
+	}
+	for _, edge := range graph.Edges {
+		redactedGraph.Edges = append(redactedGraph.Edges, ComponentEdge{From: r.name(edge.From), To: r.name(edge.To), Type: edge.Type})
+	}
+	redacted.componentGraph = redactedGraph
+
+	redacted.Subprojects = nil
+	for _, subproject := range report.Subprojects {
+		redacted.Subprojects = append(redacted.Subprojects, r.report(subproject))
+	}
+	redacted.Apps = nil
+	for _, app := range report.Apps {
+		redacted.Apps = append(redacted.Apps, r.report(app))
+	}
+	redacted.Redactor = nil
+	return &redacted
+}
+
+// shared is the report as written to outputs other than parascope.yml and passed to
+// hooks: redacted with --redact
+func (r *ScanReport) shared() *ScanReport {
+	if r.Redactor == nil {
+		return r
+	}
+	return r.Redactor.report(r)
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"parascan/pkg/parascan"
+)
+
+// PR diffs larger than this are cut off, dependency changes come with small ones
+const maxReviewDiffSize = 16 << 20
+
+// owner/repo#12, the short form of a GitHub pull request
+var shortPullRequest = regexp.MustCompile(`^([\w.-]+)/([\w.-]+)#(\d+)$`)
+
+// ReviewResponse is the JSON output of para review
+type ReviewResponse struct {
+	Introduced   []string                  `json:"introduced"` // names of services the diff adds
+	Dependencies []parascan.DiffDependency `json:"dependencies"`
+}
+
+// pullRequestDiffURL returns the API URL of a GitHub pull request's diff, given its
+// URL (github.com or GitHub Enterprise) or owner/repo#number
+func pullRequestDiffURL(ref string) (string, error) {
+	if m := shortPullRequest.FindStringSubmatch(ref); m != nil {
+		return fmt.Sprintf("https://api.github.com/repos/%s/%s/pulls/%s", m[1], m[2], m[3]), nil
+	}
+	u, err := url.Parse(ref)
+	if err == nil && u.Host != "" {
+		parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+		if len(parts) >= 4 && parts[2] == "pull" {
+			api := "https://api.github.com"
+			if u.Host != "github.com" {
+				api = u.Scheme + "://" + u.Host + "/api/v3"
+			}
+			return fmt.Sprintf("%s/repos/%s/%s/pulls/%s", api, parts[0], parts[1], parts[3]), nil
+		}
+	}
+	return "", fmt.Errorf("unknown pull request %s, expected https://github.com/owner/repo/pull/12 or owner/repo#12", ref)
+}
+
+// fetchPullRequestDiff downloads a pull request as a unified diff. The token is
+// needed for private repositories.
+func fetchPullRequestDiff(client *http.Client, diffURL, token string) (string, error) {
+	req, err := http.NewRequest(http.MethodGet, diffURL, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", "application/vnd.github.v3.diff")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("could not fetch %s: %s", diffURL, resp.Status)
+	}
+	diff, err := io.ReadAll(io.LimitReader(resp.Body, maxReviewDiffSize))
+	return string(diff), err
+}
+
+// reviewResponse lists the services the dependencies introduce, in order of first
+// appearance. Services only updated by the diff aren't new.
+func reviewResponse(dependencies []parascan.DiffDependency) ReviewResponse {
+	response := ReviewResponse{Introduced: []string{}, Dependencies: dependencies}
+	if response.Dependencies == nil {
+		response.Dependencies = []parascan.DiffDependency{}
+	}
+	seen := make(map[string]bool)
+	for _, dependency := range dependencies {
+		if !dependency.Updated && !seen[dependency.Service] {
+			seen[dependency.Service] = true
+			response.Introduced = append(response.Introduced, dependency.Name)
+		}
+	}
+	return response
+}
+
+// joinNames lists names in a sentence: Stripe, Sentry and Twilio
+func joinNames(names []string) string {
+	if len(names) < 2 {
+		return strings.Join(names, "")
+	}
+	return strings.Join(names[:len(names)-1], ", ") + " and " + names[len(names)-1]
+}
+
+// writeReviewComment writes the review as Markdown, ready to post as a PR comment
+func writeReviewComment(w io.Writer, response ReviewResponse) {
+	fmt.Fprintln(w, "### Third-party services")
+	fmt.Fprintln(w)
+	if len(response.Introduced) == 0 {
+		fmt.Fprintln(w, "This PR introduces no service from the catalog.")
+	} else {
+		bold := make([]string, len(response.Introduced))
+		for i, name := range response.Introduced {
+			bold[i] = "**" + name + "**"
+		}
+		fmt.Fprintf(w, "This PR introduces %s.\n", joinNames(bold))
+	}
+	if len(response.Dependencies) == 0 {
+		return
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "| Service | Package | File | |")
+	fmt.Fprintln(w, "| --- | --- | --- | --- |")
+	for _, dependency := range response.Dependencies {
+		change := "added"
+		if dependency.Updated {
+			change = "updated"
+		}
+		fmt.Fprintf(w, "| [%s](%s) | `%s` | `%s:%d` | %s |\n", dependency.Name, dependency.URL, dependency.Package, dependency.File, dependency.Line, change)
+	}
+}
+
+func handleReview() {
+	projectPath := "."
+	var diffPath, pullRequest, outputPath string
+	token := os.Getenv("GITHUB_TOKEN")
+	format := "markdown"
+	timeout := 30 * time.Second
+
+	flags := newCommandFlags("review", showReviewHelp)
+	flags.StringVar(&diffPath, "diff", "", "")
+	flags.StringVar(&pullRequest, "pr", "", "")
+	flags.StringVar(&token, "token", token, "")
+	flags.StringVar(&format, "format", format, "")
+	flags.StringVar(&format, "f", format, "")
+	flags.StringVar(&outputPath, "output", "", "")
+	flags.StringVar(&outputPath, "o", "", "")
+	args := flags.parse(os.Args[2:])
+	flags.maxArgs(args, 1)
+	if len(args) == 1 {
+		projectPath = args[0]
+	}
+	if (diffPath == "") == (pullRequest == "") {
+		flags.fail("para review needs either --diff or --pr")
+	}
+	if format != "markdown" && format != "json" {
+		flags.fail("Unknown format: %s. Supported formats: markdown, json", format)
+	}
+
+	var diff string
+	if pullRequest != "" {
+		diffURL, err := pullRequestDiffURL(pullRequest)
+		if err == nil && !networkEnabled {
+			err = errNetworkDisabled
+		}
+		if err == nil {
+			diff, err = fetchPullRequestDiff(&http.Client{Timeout: timeout}, diffURL, token)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+			os.Exit(1)
+		}
+	} else {
+		var content []byte
+		var err error
+		if diffPath == "-" {
+			content, err = io.ReadAll(os.Stdin)
+		} else {
+			content, err = os.ReadFile(diffPath)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+			os.Exit(1)
+		}
+		diff = string(content)
+	}
+
+	settings, err := parascan.LoadProjectSettings(projectPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		os.Exit(1)
+	}
+	globalConfig, err := loadGlobalConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		os.Exit(1)
+	}
+	catalog, _, err := resolveCatalog(projectPath, "", settings, globalConfig.Trust, false)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		os.Exit(1)
+	}
+
+	var dependencies []parascan.DiffDependency
+	for _, dependency := range catalog.ReviewDiff(diff) {
+		if !settings.ServiceDisabled(dependency.Service) {
+			dependencies = append(dependencies, dependency)
+		}
+	}
+	response := reviewResponse(dependencies)
+
+	w := os.Stdout
+	if outputPath != "" && outputPath != "-" {
+		if w, err = os.Create(outputPath); err != nil {
+			fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+			os.Exit(1)
+		}
+		defer w.Close()
+	}
+	if format == "json" {
+		data, _ := json.MarshalIndent(response, "", "  ")
+		fmt.Fprintln(w, string(data))
+		return
+	}
+	writeReviewComment(w, response)
+}
+
+func showReviewHelp() {
+	fmt.Println(`Usage: para review [path] (--diff file | --pr pull-request) [--format markdown|json]
+
+Summarize the third-party services a change brings in: added lines of dependency
+files in the diff are matched against the catalog of the project at path, and the
+summary ("This PR introduces Stripe and Twilio") is ready to post as a PR comment.
+Packages the diff also removes from the same file are version updates, not new
+services. Lockfiles are skipped.
+
+Options:
+  --diff           Unified diff to review, - for stdin (e.g. git diff main... > changes.patch)
+  --pr             GitHub pull request, as its URL or owner/repo#12 (GitHub Enterprise URLs work too)
+  --token          Token for private repositories (default $GITHUB_TOKEN)
+  --format, -f     markdown (default) or json
+  --output, -o     Write the summary to a file instead of stdout
+
+Examples:
+  git diff origin/main... | para review --diff -
+  para review --pr https://github.com/acme/shop/pull/42 -o comment.md
+  gh pr comment 42 --body "$(para review --pr acme/shop#42)"`)
+}
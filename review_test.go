@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"parascan/pkg/parascan"
+)
+
+func TestReview(t *testing.T) {
+	for ref, expected := range map[string]string{
+		"acme/shop#42":                                 "https://api.github.com/repos/acme/shop/pulls/42",
+		"https://github.com/acme/shop/pull/42":         "https://api.github.com/repos/acme/shop/pulls/42",
+		"https://ghe.acme.com/acme/shop/pull/42/files": "https://ghe.acme.com/api/v3/repos/acme/shop/pulls/42",
+	} {
+		if got, err := pullRequestDiffURL(ref); err != nil || got != expected {
+			t.Errorf("Expected %s for %s, got %s (%v)", expected, ref, got, err)
+		}
+	}
+	if _, err := pullRequestDiffURL("https://github.com/acme/shop/issues/42"); err == nil {
+		t.Error("Expected an error for an issue URL")
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept") != "application/vnd.github.v3.diff" || r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, "Not Found", http.StatusNotFound)
+			return
+		}
+		w.Write([]byte("diff --git a/Gemfile b/Gemfile\n--- a/Gemfile\n+++ b/Gemfile\n@@ -1,1 +1,3 @@\n source 'https://rubygems.org'\n+gem 'stripe', '~> 10.0'\n+gem 'twilio-ruby'\n"))
+	}))
+	defer server.Close()
+	client := &http.Client{Timeout: time.Second}
+	if _, err := fetchPullRequestDiff(client, server.URL, ""); err == nil {
+		t.Error("Expected an error without the token")
+	}
+	diff, err := fetchPullRequestDiff(client, server.URL, "secret")
+	if err != nil {
+		t.Fatalf("Failed to fetch the diff: %v", err)
+	}
+
+	catalog, err := parascan.EmbeddedCatalog()
+	if err != nil {
+		t.Fatalf("Failed to load catalog: %v", err)
+	}
+	var out bytes.Buffer
+	writeReviewComment(&out, reviewResponse(catalog.ReviewDiff(diff)))
+	for _, expected := range []string{
+		"This PR introduces **Stripe** and **Twilio**.\n",
+		"| [Stripe](https://dashboard.stripe.com) | `stripe` | `Gemfile:2` | added |\n",
+		"| [Twilio](https://console.twilio.com) | `twilio-ruby` | `Gemfile:3` | added |\n",
+	} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("Expected %q in the comment, got\n%s", expected, out.String())
+		}
+	}
+
+	out.Reset()
+	writeReviewComment(&out, reviewResponse(nil))
+	if !strings.Contains(out.String(), "This PR introduces no service from the catalog.") || strings.Contains(out.String(), "|") {
+		t.Errorf("Expected a comment without a table, got\n%s", out.String())
+	}
+}
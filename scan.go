@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"parascan/detectors"
+	"parascan/pkg/parascan"
+)
+
+// ScanOptions tune a single detection run
+type ScanOptions struct {
+	Plugins   []*detectors.ExecDetector // external detectors to run alongside built-in ones
+	SkipGit   bool                      // don't read git metadata (e.g. when scanning fixtures)
+	GitRemote string                    // git remote the repository URL is read from, "" for origin
+	RepoURL   string                    // repository URL of an exported tree, instead of reading it from git
+	Docs      bool                      // infer services from README and docs, also enabled by the docs detector setting
+	MaxDepth  int                       // directory levels searched for dependency files, 0 is parascan.DefaultMaxDepth
+	Budget    time.Duration             // skip expensive steps to finish in about this time, 0 is unlimited
+	MaxMemory uint64                    // fail the scan over this many bytes of heap, 0 is unlimited
+	Settings  *parascan.ProjectSettings // project settings (ignored paths, disabled detectors), may be nil
+	LinkType  string                    // service URL reported, one of parascan.LinkTypes, "" for the dashboard
+	IaCState  bool                      // read resource types from IaC state files, also enabled by the iac_state detector setting
+	Dotenv    bool                      // read variable names from .env files, also enabled by the dotenv detector setting
+	LangStats bool                      // measure the share of each language, also enabled by the language_stats detector setting
+	Deep      bool                      // read source imports, also enabled by the deep detector setting
+}
+
+// runScan runs the scanner with the command's options. A project that can't be
+// scanned yields an empty report with the error, like a failing detector.
+func runScan(projectPath string, catalog *parascan.Catalog, opts ScanOptions) *parascan.Report {
+	scanOpts := []parascan.Option{
+		parascan.WithCatalog(catalog),
+		parascan.WithSettings(opts.Settings),
+		parascan.WithPlugins(opts.Plugins...),
+		parascan.WithMaxDepth(opts.MaxDepth),
+		parascan.WithBudget(opts.Budget),
+		parascan.WithMemoryLimit(opts.MaxMemory),
+		parascan.WithLinkType(opts.LinkType),
+	}
+	if opts.SkipGit {
+		scanOpts = append(scanOpts, parascan.WithoutGit())
+	}
+	if opts.GitRemote != "" {
+		scanOpts = append(scanOpts, parascan.WithGitRemote(opts.GitRemote))
+	}
+	if opts.RepoURL != "" {
+		scanOpts = append(scanOpts, parascan.WithRepository(opts.RepoURL))
+	}
+	if opts.Docs {
+		scanOpts = append(scanOpts, parascan.WithDocs())
+	}
+	if opts.IaCState {
+		scanOpts = append(scanOpts, parascan.WithIaCState())
+	}
+	if opts.Dotenv {
+		scanOpts = append(scanOpts, parascan.WithDotenv())
+	}
+	if opts.LangStats {
+		scanOpts = append(scanOpts, parascan.WithLanguageStats())
+	}
+	if opts.Deep {
+		scanOpts = append(scanOpts, parascan.WithDeep())
+	}
+
+	scanner, err := parascan.New(scanOpts...)
+	if err == nil {
+		var report *parascan.Report
+		if report, err = scanner.Scan(context.Background(), projectPath); err == nil {
+			return report
+		}
+	}
+	return &parascan.Report{
+		Results: make(map[string]string),
+		Errors:  []parascan.DetectorError{{Detector: "scan", Err: err}},
+	}
+}
+
+// memoryLimitError returns the error of a scan stopped by --max-memory, if any
+func memoryLimitError(scan *parascan.Report) error {
+	for _, detectorErr := range scan.Errors {
+		var limitErr *parascan.MemoryLimitError
+		if errors.As(detectorErr.Err, &limitErr) {
+			return fmt.Errorf("%v, exclude large directories with --exclude or raise --max-memory", limitErr)
+		}
+	}
+	return nil
+}
+
+// parseByteSize parses sizes such as 512MB, 2GB or 1048576 (bytes), units are powers of 1024
+func parseByteSize(value string) (uint64, error) {
+	number := strings.ToUpper(strings.TrimSpace(value))
+	multiplier := uint64(1)
+	for _, unit := range []struct {
+		suffix     string
+		multiplier uint64
+	}{{"KB", 1 << 10}, {"MB", 1 << 20}, {"GB", 1 << 30}, {"K", 1 << 10}, {"M", 1 << 20}, {"G", 1 << 30}, {"B", 1}} {
+		if strings.HasSuffix(number, unit.suffix) {
+			number, multiplier = strings.TrimSpace(strings.TrimSuffix(number, unit.suffix)), unit.multiplier
+			break
+		}
+	}
+	size, err := strconv.ParseUint(number, 10, 64)
+	if err != nil || size == 0 {
+		return 0, fmt.Errorf("invalid size %s, expected e.g. 512MB or 2GB", value)
+	}
+	return size * multiplier, nil
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+	yamlv3 "gopkg.in/yaml.v3"
+
+	"parascan/pkg/parascan"
+)
+
+const globalConfigFile = "config.yml"
+
+// GlobalConfig is the user-level configuration in ~/.config/parascope/config.yml
+type GlobalConfig struct {
+	Trust TrustPolicy `yaml:"trust"`
+}
+
+// parascopeConfigDir returns the global config directory (~/.config/parascope)
+func parascopeConfigDir() (string, error) {
+	if xdg := os.Getenv("XDG_CONFIG_HOME"); xdg != "" {
+		return filepath.Join(xdg, "parascope"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".config", "parascope"), nil
+}
+
+// loadGlobalConfig reads the global config, a missing file means defaults
+func loadGlobalConfig() (*GlobalConfig, error) {
+	var config GlobalConfig
+
+	configDir, err := parascopeConfigDir()
+	if err != nil {
+		return &config, nil
+	}
+	data, err := os.ReadFile(filepath.Join(configDir, globalConfigFile))
+	if os.IsNotExist(err) {
+		return &config, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("invalid %s: %v", globalConfigFile, err)
+	}
+	return &config, nil
+}
+
+// updateProjectSettings rewrites a single top-level key of .parascan.yml. Only the
+// lines of that key's entry change, so the other keys keep their comments, blank
+// lines and formatting; a missing key is appended.
+func updateProjectSettings(projectPath, key string, value interface{}) error {
+	path := filepath.Join(projectPath, parascan.SettingsFile)
+
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	var doc yamlv3.Node
+	if err := yamlv3.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("invalid %s: %v", parascan.SettingsFile, err)
+	}
+	var root *yamlv3.Node
+	if len(doc.Content) > 0 {
+		if root = doc.Content[0]; root.Kind != yamlv3.MappingNode {
+			return fmt.Errorf("invalid %s: not a mapping of settings", parascan.SettingsFile)
+		}
+	}
+
+	entry := &yamlv3.Node{Kind: yamlv3.MappingNode}
+	keyNode := &yamlv3.Node{Kind: yamlv3.ScalarNode, Value: key}
+	valueNode := &yamlv3.Node{}
+	if err := valueNode.Encode(value); err != nil {
+		return err
+	}
+	entry.Content = []*yamlv3.Node{keyNode, valueNode}
+
+	// The entry runs from its key to the line before the next key, less the blank
+	// lines and comments at the start of a line in between, which go with the next key
+	lines := strings.SplitAfter(string(data), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	first, last := len(lines), len(lines)
+	if root != nil {
+		for i := 0; i+1 < len(root.Content); i += 2 {
+			if root.Content[i].Value != key {
+				continue
+			}
+			keyNode.LineComment = root.Content[i].LineComment
+			first, last = root.Content[i].Line-1, len(lines)
+			if i+2 < len(root.Content) {
+				last = root.Content[i+2].Line - 1
+			}
+			for last > first+1 && (strings.TrimSpace(lines[last-1]) == "" || strings.HasPrefix(lines[last-1], "#")) {
+				last--
+			}
+			break
+		}
+	}
+
+	var buf bytes.Buffer
+	encoder := yamlv3.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(entry); err != nil {
+		return err
+	}
+	if err := encoder.Close(); err != nil {
+		return err
+	}
+
+	var out strings.Builder
+	for _, line := range lines[:first] {
+		out.WriteString(line)
+	}
+	if first > 0 && !strings.HasSuffix(lines[first-1], "\n") {
+		out.WriteString("\n")
+	}
+	out.Write(buf.Bytes())
+	for _, line := range lines[last:] {
+		out.WriteString(line)
+	}
+	return os.WriteFile(path, []byte(out.String()), 0644)
+}
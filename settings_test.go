@@ -0,0 +1,63 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"parascan/pkg/parascan"
+)
+
+func TestUpdateProjectSettingsKeepsComments(t *testing.T) {
+	projectPath := t.TempDir()
+	path := filepath.Join(projectPath, parascan.SettingsFile)
+	original := "# Settings for para scan\n" +
+		"\n" +
+		"# Generated code isn't ours\n" +
+		"ignore:\n" +
+		"  - gen/ # protobuf output\n" +
+		"\n" +
+		"catalog: # pinned for CI\n" +
+		"  version: 2025.9.0\n" +
+		"  # vendored copy\n" +
+		"  path: vendor-catalog\n" +
+		"\n" +
+		"# Packages alone need two files\n" +
+		"min_evidence: 2\n"
+	if err := os.WriteFile(path, []byte(original), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the lines of the updated key change
+	if err := updateProjectSettings(projectPath, "catalog", parascan.CatalogPin{Version: "2026.10.1", Hash: "sha256:abc"}); err != nil {
+		t.Fatalf("Failed to update settings: %v", err)
+	}
+	expected := "# Settings for para scan\n" +
+		"\n" +
+		"# Generated code isn't ours\n" +
+		"ignore:\n" +
+		"  - gen/ # protobuf output\n" +
+		"\n" +
+		"catalog: # pinned for CI\n" +
+		"  version: 2026.10.1\n" +
+		"  hash: sha256:abc\n" +
+		"\n" +
+		"# Packages alone need two files\n" +
+		"min_evidence: 2\n"
+	if content, _ := os.ReadFile(path); string(content) != expected {
+		t.Errorf("Expected\n%s\ngot\n%s", expected, content)
+	}
+
+	// A new key is appended, the settings still load
+	if err := updateProjectSettings(projectPath, "accounts", map[string]map[string]string{"sentry": {"org": "acme"}}); err != nil {
+		t.Fatalf("Failed to add accounts: %v", err)
+	}
+	expected += "accounts:\n  sentry:\n    org: acme\n"
+	if content, _ := os.ReadFile(path); string(content) != expected {
+		t.Errorf("Expected\n%s\ngot\n%s", expected, content)
+	}
+	settings, err := parascan.LoadProjectSettings(projectPath)
+	if err != nil || settings.Catalog.Version != "2026.10.1" || settings.MinEvidence != 2 || settings.Accounts["sentry"]["org"] != "acme" {
+		t.Errorf("Expected the updated settings to load, got %+v (%v)", settings, err)
+	}
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"parascan/pkg/parascan"
+)
+
+// statuspage.io pages serve their current status as JSON under this path
+const statusPageAPIPath = "/api/v2/status.json"
+
+// Requests to status pages are spaced out by this much, they're hammered during incidents
+const statusRequestInterval = 100 * time.Millisecond
+
+// ServiceStatus is the current health of a detected service from its status page
+type ServiceStatus struct {
+	Key         string `json:"key"`
+	Service     string `json:"service"`
+	URL         string `json:"url"`
+	Indicator   string `json:"indicator"` // none, minor, major, critical, or unknown without a readable status
+	Description string `json:"description,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+// StatusResponse is the JSON output of para status
+type StatusResponse struct {
+	Project  string          `json:"project"`
+	Services []ServiceStatus `json:"services"`
+	NoPage   []string        `json:"no_status_page,omitempty"` // detected services without a status link in the catalog
+}
+
+// rateLimitedClient spaces out requests by a minimum interval, whichever goroutine sends them
+type rateLimitedClient struct {
+	client   *http.Client
+	interval time.Duration
+
+	mu   sync.Mutex
+	next time.Time
+}
+
+func newRateLimitedClient(timeout, interval time.Duration) *rateLimitedClient {
+	return &rateLimitedClient{client: &http.Client{Timeout: timeout}, interval: interval}
+}
+
+// Get waits for the request's turn, then sends it
+func (c *rateLimitedClient) Get(url string) (*http.Response, error) {
+	c.mu.Lock()
+	now := time.Now()
+	start := c.next
+	if start.Before(now) {
+		start = now
+	}
+	c.next = start.Add(c.interval)
+	c.mu.Unlock()
+
+	time.Sleep(start.Sub(now))
+	return c.client.Get(url)
+}
+
+// statusServices lists the detected services with a status page in the catalog,
+// sorted by key, and the names of those without one
+func statusServices(catalog *parascan.Catalog, results map[string]string, settings *parascan.ProjectSettings) ([]ServiceStatus, []string) {
+	keys := make([]string, 0, len(results))
+	for key := range results {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var statuses []ServiceStatus
+	var noPage []string
+	for _, key := range keys {
+		service, ok := catalog.Services[key]
+		if !ok || settings.ServiceDisabled(key) {
+			continue
+		}
+		if url := service.Links["status"]; url != "" {
+			statuses = append(statuses, ServiceStatus{Key: key, Service: service.Name, URL: url})
+		} else {
+			noPage = append(noPage, service.Name)
+		}
+	}
+	return statuses, noPage
+}
+
+// fetchStatuses reads the statuspage.io status of every service concurrently.
+// Pages that aren't statuspage.io, or can't be reached, are left unknown.
+func fetchStatuses(client *rateLimitedClient, statuses []ServiceStatus) {
+	var wg sync.WaitGroup
+	for i := range statuses {
+		wg.Add(1)
+		go func(status *ServiceStatus) {
+			defer wg.Done()
+			indicator, description, err := fetchStatusPage(client, status.URL)
+			if err != nil {
+				status.Indicator = "unknown"
+				status.Error = err.Error()
+				return
+			}
+			status.Indicator, status.Description = indicator, description
+		}(&statuses[i])
+	}
+	wg.Wait()
+}
+
+// fetchStatusPage reads the status indicator and description of a statuspage.io page
+func fetchStatusPage(client *rateLimitedClient, pageURL string) (string, string, error) {
+	resp, err := client.Get(strings.TrimSuffix(pageURL, "/") + statusPageAPIPath)
+	if err != nil {
+		return "", "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", "", fmt.Errorf("no statuspage.io status (%s)", resp.Status)
+	}
+
+	var page struct {
+		Status struct {
+			Indicator   string `json:"indicator"`
+			Description string `json:"description"`
+		} `json:"status"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&page); err != nil || page.Status.Indicator == "" {
+		return "", "", fmt.Errorf("no statuspage.io status")
+	}
+	return page.Status.Indicator, page.Status.Description, nil
+}
+
+// statusIcon shows how bad an indicator is at a glance
+func statusIcon(indicator string) string {
+	switch indicator {
+	case "none":
+		return "✅"
+	case "minor":
+		return "⚠️ "
+	case "major", "critical":
+		return "🔴"
+	default:
+		return "❔"
+	}
+}
+
+// writeStatus prints the aggregated health view
+func writeStatus(w io.Writer, response StatusResponse) {
+	if len(response.Services) == 0 {
+		fmt.Fprintf(w, "🚦 No detected service of %s has a status page in the catalog\n", response.Project)
+	} else {
+		fmt.Fprintf(w, "🚦 Status of the services %s depends on:\n", response.Project)
+	}
+	for _, status := range response.Services {
+		if status.Indicator == "unknown" {
+			fmt.Fprintf(w, "  %s %s: unknown (%s), see %s\n", statusIcon(status.Indicator), status.Service, status.Error, status.URL)
+		} else {
+			fmt.Fprintf(w, "  %s %s: %s\n", statusIcon(status.Indicator), status.Service, status.Description)
+		}
+	}
+	if len(response.NoPage) > 0 {
+		fmt.Fprintf(w, "\nNo status page in the catalog for: %s\n", strings.Join(response.NoPage, ", "))
+	}
+}
+
+func handleStatus() {
+	projectPath := "."
+	format := "text"
+	timeout := 10 * time.Second
+
+	flags := newCommandFlags("status", showStatusHelp)
+	flags.StringVar(&format, "format", format, "")
+	flags.StringVar(&format, "f", format, "")
+	flags.DurationVar(&timeout, "timeout", timeout, "")
+	args := flags.parse(os.Args[2:])
+	flags.maxArgs(args, 1)
+	if len(args) == 1 {
+		projectPath = args[0]
+	}
+	if timeout <= 0 {
+		flags.fail("Invalid --timeout: %s, expected a duration such as 5s", timeout)
+	}
+	if format != "text" && format != "json" {
+		flags.fail("Unknown format: %s. Supported formats: text, json", format)
+	}
+	if !networkEnabled {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", errNetworkDisabled)
+		os.Exit(1)
+	}
+
+	settings, err := parascan.LoadProjectSettings(projectPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		os.Exit(1)
+	}
+	globalConfig, err := loadGlobalConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		os.Exit(1)
+	}
+	catalog, _, err := resolveCatalog(projectPath, "", settings, globalConfig.Trust, false)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		os.Exit(1)
+	}
+
+	projectName := settings.Output.ProjectName
+	if projectName == "" {
+		if abs, err := filepath.Abs(projectPath); err == nil {
+			projectName = filepath.Base(abs)
+		}
+	}
+
+	scan := runScan(projectPath, catalog, ScanOptions{SkipGit: true, Settings: settings})
+	statuses, noPage := statusServices(catalog, scan.Results, settings)
+	fetchStatuses(newRateLimitedClient(timeout, statusRequestInterval), statuses)
+	response := StatusResponse{Project: projectName, Services: statuses, NoPage: noPage}
+
+	if format == "json" {
+		if response.Services == nil {
+			response.Services = []ServiceStatus{}
+		}
+		data, _ := json.MarshalIndent(response, "", "  ")
+		fmt.Println(string(data))
+		return
+	}
+	writeStatus(os.Stdout, response)
+}
+
+func showStatusHelp() {
+	fmt.Println(`Usage: para status [path] [--format text|json] [--timeout duration]
+
+Show the current health of the project's third-party services, read from the
+status pages the catalog lists for detected services (statuspage.io JSON where
+available). Handy during incidents.
+
+Options:
+  --format, -f     text (default) or json
+  --timeout        Time to wait for each status page (default 10s)
+
+Examples:
+  para status
+  para status ./my-project -f json | jq '.services[] | select(.indicator != "none")'`)
+}
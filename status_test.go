@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"parascan/pkg/parascan"
+)
+
+func TestServiceStatus(t *testing.T) {
+	catalog, err := parascan.EmbeddedCatalog()
+	if err != nil {
+		t.Fatalf("Failed to load catalog: %v", err)
+	}
+	results := map[string]string{"stripe": "", "github": "", "mailgun": "", "openai": ""}
+	settings := &parascan.ProjectSettings{DisabledServices: []string{"openai"}}
+	statuses, noPage := statusServices(catalog, results, settings)
+	if len(statuses) != 2 || statuses[0].Key != "github" || statuses[1].Key != "stripe" {
+		t.Fatalf("Expected github and stripe with status pages, got %+v", statuses)
+	}
+	if len(noPage) != 1 || noPage[0] != "Mailgun" {
+		t.Errorf("Expected Mailgun without a status page, got %v", noPage)
+	}
+
+	// One statuspage.io page, one page without the JSON API
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/stripe"+statusPageAPIPath {
+			w.Write([]byte(`{"page": {"name": "Stripe"}, "status": {"indicator": "minor", "description": "Partially Degraded Service"}}`))
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+	statuses[0].URL = server.URL + "/github"
+	statuses[1].URL = server.URL + "/stripe/"
+
+	start := time.Now()
+	fetchStatuses(newRateLimitedClient(time.Second, 50*time.Millisecond), statuses)
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("Expected requests to be spaced out, both were sent within %s", elapsed)
+	}
+	if statuses[0].Indicator != "unknown" || statuses[0].Error == "" {
+		t.Errorf("Expected an unknown status for a page without the JSON API, got %+v", statuses[0])
+	}
+	if statuses[1].Indicator != "minor" || statuses[1].Description != "Partially Degraded Service" {
+		t.Errorf("Expected Stripe's statuspage.io status, got %+v", statuses[1])
+	}
+
+	var out bytes.Buffer
+	writeStatus(&out, StatusResponse{Project: "billing", Services: statuses, NoPage: noPage})
+	for _, expected := range []string{
+		"🚦 Status of the services billing depends on:\n",
+		"  ⚠️  Stripe: Partially Degraded Service\n",
+		"  ❔ Github: unknown (no statuspage.io status (404 Not Found)), see " + server.URL + "/github\n",
+		"No status page in the catalog for: Mailgun\n",
+	} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("Expected %q in:\n%s", expected, out.String())
+		}
+	}
+}
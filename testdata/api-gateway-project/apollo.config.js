@@ -0,0 +1,5 @@
+module.exports = {
+  client: {
+    service: 'graph-gateway@current',
+  },
+};
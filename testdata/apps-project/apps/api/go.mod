@@ -0,0 +1,8 @@
+module example.com/acme/api
+
+go 1.21
+
+require (
+	github.com/getsentry/sentry-go v0.25.0
+	github.com/stripe/stripe-go/v76 v76.8.0
+)
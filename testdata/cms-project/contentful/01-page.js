@@ -0,0 +1,3 @@
+module.exports = function (migration) {
+  migration.createContentType("page").name("Page")
+}
@@ -0,0 +1,7 @@
+import { defineConfig } from "sanity"
+
+export default defineConfig({
+  name: "marketing",
+  projectId: "x7k2m9qp",
+  dataset: "production",
+})
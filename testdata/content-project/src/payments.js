@@ -0,0 +1,13 @@
+// Payments go straight to the Stripe API, without the SDK
+const STRIPE_API = "https://api.stripe.com/v1";
+
+async function createPaymentIntent(amount, currency) {
+  const response = await fetch(`${STRIPE_API}/payment_intents`, {
+    method: "POST",
+    headers: { Authorization: `Bearer ${process.env.PAYMENTS_KEY}` },
+    body: new URLSearchParams({ amount, currency }),
+  });
+  return response.json();
+}
+
+module.exports = { createPaymentIntent };
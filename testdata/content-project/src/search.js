@@ -0,0 +1,8 @@
+const SEARCH_URL = "https://search.acme.internal/v2/query";
+
+async function search(query) {
+  const response = await fetch(`${SEARCH_URL}?q=${encodeURIComponent(query)}`);
+  return response.json();
+}
+
+module.exports = { search };
@@ -0,0 +1,14 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/stripe/stripe-go/v76"
+	"github.com/stripe/stripe-go/v76/customer"
+)
+
+func main() {
+	stripe.Key = "sk_test"
+	c, _ := customer.Get("cus_123", nil)
+	fmt.Println(c.Email)
+}
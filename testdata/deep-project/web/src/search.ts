@@ -0,0 +1,6 @@
+import { liteClient } from 'algoliasearch/lite'
+import './search.css'
+import type { Hit } from "./types"
+
+export const client = liteClient(process.env.ALGOLIA_APP_ID!, process.env.ALGOLIA_KEY!)
+export const analytics = () => import("posthog-js").then((m) => m.default)
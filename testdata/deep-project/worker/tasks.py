@@ -0,0 +1,10 @@
+"""Background jobs."""
+import os, sentry_sdk as sentry
+from twilio.rest import Client
+from . import utils
+
+sentry.init(dsn=os.environ["SENTRY_DSN"])
+
+
+def notify(number):
+    Client().messages.create(to=number, body=utils.greeting())
@@ -0,0 +1,13 @@
+import os
+
+import sentry_sdk
+
+sentry_sdk.init(dsn=os.environ.get("SENTRY_DSN"))
+
+INSTALLED_APPS = [
+    "django.contrib.admin",
+    "django.contrib.auth",
+    "djstripe",
+    "cloudinary_storage",
+    "shop.orders",
+]
@@ -0,0 +1,17 @@
+// @ts-check
+
+/** @type {import('@docusaurus/types').Config} */
+const config = {
+  title: 'Widgets',
+  url: 'https://acme.github.io',
+  baseUrl: '/widgets/',
+  organizationName: 'acme',
+  projectName: 'widgets',
+  themeConfig: {
+    navbar: {
+      items: [{href: 'https://github.com/acme/widgets', label: 'GitHub'}],
+    },
+  },
+};
+
+module.exports = config;
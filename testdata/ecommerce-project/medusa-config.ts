@@ -0,0 +1,7 @@
+import { defineConfig } from "@medusajs/utils"
+
+export default defineConfig({
+  projectConfig: {
+    databaseUrl: process.env.DATABASE_URL,
+  },
+})
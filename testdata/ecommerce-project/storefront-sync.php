@@ -0,0 +1,8 @@
+<?php
+/**
+ * Plugin Name: Storefront Sync
+ * Description: Syncs WooCommerce products with the storefront.
+ * Version: 1.0.0
+ * WC requires at least: 8.0
+ * WC tested up to: 8.5
+ */
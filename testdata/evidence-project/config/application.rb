@@ -0,0 +1,9 @@
+require_relative "boot"
+
+require "rails/all"
+
+module EvidenceProject
+  class Application < Rails::Application
+    config.load_defaults 7.0
+  end
+end
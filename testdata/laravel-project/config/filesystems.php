@@ -0,0 +1,13 @@
+<?php
+
+return [
+    'disks' => [
+        'local' => [
+            'driver' => 'local',
+        ],
+        's3' => [
+            'driver' => 's3',
+            'bucket' => env('AWS_BUCKET'),
+        ],
+    ],
+];
@@ -0,0 +1,10 @@
+module example.com/acme/api
+
+go 1.21
+
+require (
+	example.com/acme/shared v0.0.0
+	github.com/stripe/stripe-go/v76 v76.8.0
+)
+
+replace example.com/acme/shared => ../../libs/shared
@@ -0,0 +1,11 @@
+terraform {
+  required_providers {
+    opsgenie = {
+      source = "opsgenie/opsgenie"
+    }
+  }
+}
+
+resource "opsgenie_team" "checkout" {
+  name = "checkout"
+}
@@ -0,0 +1 @@
+Rails.application.config.filter_parameters += [:password]
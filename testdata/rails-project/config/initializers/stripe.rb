@@ -0,0 +1 @@
+Stripe.api_key = Rails.application.credentials.stripe[:secret_key]
@@ -0,0 +1,3 @@
+#!/bin/sh
+set -eu
+terraform apply -auto-approve
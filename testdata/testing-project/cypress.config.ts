@@ -0,0 +1,8 @@
+import { defineConfig } from "cypress"
+
+export default defineConfig({
+  projectId: "a7bq2k",
+  e2e: {
+    baseUrl: "http://localhost:3000",
+  },
+})
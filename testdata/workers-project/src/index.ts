@@ -0,0 +1,8 @@
+export { RateLimiter } from "./rate-limiter";
+
+export default {
+  async fetch(request: Request, env: Env): Promise<Response> {
+    const session = await env.SESSIONS.get(request.headers.get("cookie") ?? "");
+    return new Response(session ?? "anonymous");
+  },
+};
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+
+	"parascan/detectors"
+)
+
+// normalizeURL is the form URLs are written to parascope.yml in: lowercase scheme
+// and host, no default port and no trailing slash. Values that aren't absolute URLs
+// are kept as they are.
+func normalizeURL(raw string) string {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return raw
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	if (u.Scheme == "https" && u.Port() == "443") || (u.Scheme == "http" && u.Port() == "80") {
+		u.Host = u.Hostname()
+	}
+	// A slash before a query or fragment, e.g. https://app.storyblok.com/#/me, is left alone
+	if u.RawQuery == "" && u.Fragment == "" {
+		u.Path = strings.TrimRight(u.Path, "/")
+		u.RawPath = strings.TrimRight(u.RawPath, "/")
+	}
+	return u.String()
+}
+
+// urlKey identifies the address a URL points to: URLs that only differ in case,
+// trailing slashes or a www. prefix have the same key
+func urlKey(raw string) string {
+	normalized := normalizeURL(raw)
+	u, err := url.Parse(normalized)
+	if err != nil || u.Host == "" {
+		return normalized
+	}
+	u.Host = strings.TrimPrefix(u.Host, "www.")
+	return u.String()
+}
+
+// dedupeResults normalizes the URLs of results and keeps one result per address:
+// the repository, then the first key in sorted order
+func dedupeResults(results map[string]string) map[string]string {
+	keys := make([]string, 0, len(results))
+	for key := range results {
+		if key != detectors.RepoKey {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	if _, found := results[detectors.RepoKey]; found {
+		keys = append([]string{detectors.RepoKey}, keys...)
+	}
+
+	deduped := make(map[string]string, len(results))
+	seen := make(map[string]bool)
+	for _, key := range keys {
+		value := normalizeURL(results[key])
+		if seen[urlKey(value)] {
+			continue
+		}
+		seen[urlKey(value)] = true
+		deduped[key] = value
+	}
+	return deduped
+}
+
+// dedupeSectionValues removes the entries of a project section whose URL points to
+// the same address as an earlier entry's, returning the names it removed
+func dedupeSectionValues(section string) (string, []string) {
+	var removed []string
+	seen := make(map[string]bool)
+	lines := strings.Split(section, "\n")
+	kept := lines[:0]
+	for i, line := range lines {
+		trimmed := strings.TrimLeft(line, " \t")
+		if i == 0 || trimmed == line || strings.HasPrefix(trimmed, "#") {
+			kept = append(kept, line)
+			continue
+		}
+		var entry map[string]string
+		if err := yaml.Unmarshal([]byte(trimmed), &entry); err != nil || len(entry) != 1 {
+			kept = append(kept, line)
+			continue
+		}
+		for name, value := range entry {
+			if value != "" && seen[urlKey(value)] {
+				removed = append(removed, name)
+				continue
+			}
+			if value != "" {
+				seen[urlKey(value)] = true
+			}
+			kept = append(kept, line)
+		}
+	}
+	return strings.Join(kept, "\n"), removed
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultPruneAfter is how many --verify scans in a row an entry can go without
+// being found before it's a candidate for pruning
+const defaultPruneAfter = 3
+
+// Verification re-checks the entries of a project section against each scan run
+// with --verify. Entries a scan doesn't find are marked unverified rather than
+// deleted, and those unverified for PruneAfter scans in a row are reported as
+// candidates for pruning. The state is kept as comments at the end of the section,
+// like provenance:
+//
+//	# verified: 2026-10-15
+//	# unverified: Mailgun, 3 scans, last corroborated 2026-09-01
+type Verification struct {
+	Date       time.Time
+	PruneAfter int
+}
+
+// UnverifiedEntry is an entry of parascope.yml recent --verify scans didn't find
+type UnverifiedEntry struct {
+	Name             string `json:"name"`
+	Scans            int    `json:"scans"`                       // --verify scans in a row that didn't find it
+	LastCorroborated string `json:"last_corroborated,omitempty"` // day of the last --verify scan that did, if any
+	Prune            bool   `json:"prune"`                       // unverified for PruneAfter scans or more
+}
+
+// SectionVerification is the outcome of a --verify scan for a project section
+type SectionVerification struct {
+	Project      string            `json:"project"`
+	Entries      int               `json:"entries"` // entries checked, the repository and kept ones aren't
+	Corroborated int               `json:"corroborated"`
+	Unverified   []UnverifiedEntry `json:"unverified,omitempty"`
+}
+
+var (
+	verifiedComment   = regexp.MustCompile(`^\s*# verified: (\d{4}-\d{2}-\d{2})\s*$`)
+	unverifiedComment = regexp.MustCompile(`^\s*# unverified: (.+), (\d+) scans?(?:, last corroborated (\d{4}-\d{2}-\d{2}))?\s*$`)
+	keepComment       = regexp.MustCompile(`^\s*#\s*keep\b`)
+)
+
+func (v *Verification) day() string {
+	return v.Date.Format("2006-01-02")
+}
+
+// comments renders the verification lines of a section, indented like its entries
+func (v *Verification) comments(unverified []UnverifiedEntry) []string {
+	lines := []string{fmt.Sprintf("  # verified: %s", v.day())}
+	for _, entry := range unverified {
+		line := fmt.Sprintf("  # unverified: %s, %d scan", entry.Name, entry.Scans)
+		if entry.Scans != 1 {
+			line += "s"
+		}
+		if entry.LastCorroborated != "" {
+			line += ", last corroborated " + entry.LastCorroborated
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// sectionVerification removes the verification comments from a section and returns
+// the day of the last --verify scan with the entries it left unverified, by name
+func sectionVerification(section string) (string, string, map[string]UnverifiedEntry) {
+	lastVerified := ""
+	unverified := make(map[string]UnverifiedEntry)
+	lines := strings.Split(section, "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		if match := verifiedComment.FindStringSubmatch(line); match != nil {
+			lastVerified = match[1]
+			continue
+		}
+		if match := unverifiedComment.FindStringSubmatch(line); match != nil {
+			scans, _ := strconv.Atoi(match[2])
+			unverified[match[1]] = UnverifiedEntry{Name: match[1], Scans: scans, LastCorroborated: match[3]}
+			continue
+		}
+		kept = append(kept, line)
+	}
+	return strings.Join(kept, "\n"), lastVerified, unverified
+}
+
+// keptEntries lists the entries of a section marked with a # keep comment, which
+// are never verified, e.g. links written by hand that no scan can find
+func keptEntries(section string) map[string]bool {
+	kept := make(map[string]bool)
+	for _, line := range strings.Split(section, "\n") {
+		if !strings.HasPrefix(line, " ") {
+			continue
+		}
+		name, value, found := strings.Cut(strings.TrimSpace(line), ":")
+		if !found || strings.HasPrefix(name, "#") {
+			continue
+		}
+		if _, comment := splitLineComment(value); keepComment.MatchString(comment) {
+			kept[strings.Trim(strings.TrimSpace(name), `"'`)] = true
+		}
+	}
+	return kept
+}
+
+// verifySection checks each entry of a section against the entries the scan would
+// write, by name or by address as para diff does, and updates the unverified counts
+func (v *Verification) verifySection(project, section string, scanned map[string]string, repositoryName string) (string, SectionVerification) {
+	section, lastVerified, previous := sectionVerification(section)
+	result := SectionVerification{Project: project}
+
+	scannedURLs := make(map[string]bool)
+	for _, url := range scanned {
+		scannedURLs[urlKey(url)] = true
+	}
+	kept := keptEntries(section)
+	entries := sectionEntries(section)
+	for _, name := range sortedKeys(entries) {
+		if isRepositoryKey(name, repositoryName) || kept[name] {
+			continue
+		}
+		result.Entries++
+		if _, found := scanned[name]; found || scannedURLs[urlKey(entries[name])] {
+			result.Corroborated++
+			continue
+		}
+		entry, missed := previous[name]
+		if !missed {
+			// Found by the previous --verify scan, if there was one
+			entry = UnverifiedEntry{Name: name, LastCorroborated: lastVerified}
+		}
+		entry.Scans++
+		entry.Prune = entry.Scans >= v.PruneAfter
+		result.Unverified = append(result.Unverified, entry)
+	}
+
+	lines := v.comments(result.Unverified)
+	return strings.TrimRight(section, "\n") + "\n" + strings.Join(lines, "\n"), result
+}
+
+// writeVerification verifies the project's section of the config against the scan's
+// results, nothing is written when the config has no such section
+func writeVerification(configPath, projectName, repoURL, repositoryName string, scanned map[string]string, verification *Verification) (*SectionVerification, error) {
+	content, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, err
+	}
+	sections := splitConfigSections(string(content))
+	i := findConfigSection(sections, projectName, repoURL, repositoryName)
+	if i < 0 {
+		return nil, nil
+	}
+	section, result := verification.verifySection(sectionRootKey(sections[i]), sections[i], scanned, repositoryName)
+	sections[i] = section
+	if err := os.WriteFile(configPath, []byte(joinConfigSections(sections)), 0644); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// writeSectionVerification prints the outcome of a --verify scan for people
+func writeSectionVerification(w io.Writer, configPath string, result *SectionVerification) {
+	fmt.Fprintf(w, "\n🔎 Verified %d entries of %s (%s), %d found by the scan\n", result.Entries, configPath, result.Project, result.Corroborated)
+	unverified := append([]UnverifiedEntry(nil), result.Unverified...)
+	sort.SliceStable(unverified, func(i, j int) bool { return unverified[i].Scans > unverified[j].Scans })
+	for _, entry := range unverified {
+		since := "never corroborated by --verify"
+		if entry.LastCorroborated != "" {
+			since = "last corroborated " + entry.LastCorroborated
+		}
+		scans := "scan"
+		if entry.Scans != 1 {
+			scans = "scans"
+		}
+		if entry.Prune {
+			fmt.Fprintf(w, "  🗑️  %s: not found for %d %s (%s), candidate for pruning\n", entry.Name, entry.Scans, scans, since)
+		} else {
+			fmt.Fprintf(w, "  ❔ %s: not found for %d %s (%s)\n", entry.Name, entry.Scans, scans, since)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerification(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "parascope.yml")
+	config := `billing:
+  Repository: https://github.com/acme/billing
+  Stripe: https://dashboard.stripe.com
+  Mailgun: https://app.mailgun.com
+  Runbook: https://wiki.acme.internal/billing # keep
+  Errors: https://sentry.io/
+docs:
+  Notion: https://notion.so
+`
+	if err := os.WriteFile(configPath, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+	day := time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC)
+	report := &ScanReport{
+		ConfigPath:   configPath,
+		ProjectName:  "billing",
+		Results:      map[string]string{"repo": "https://github.com/acme/billing", "stripe": "https://dashboard.stripe.com", "sentry": "https://sentry.io"},
+		Verification: &Verification{Date: day, PruneAfter: 2},
+	}
+	scan := func() string {
+		t.Helper()
+		if err := writeOutput(OutputTarget{Format: "yml-config"}, report); err != nil {
+			t.Fatalf("Failed to write config: %v", err)
+		}
+		content, _ := os.ReadFile(configPath)
+		return string(content)
+	}
+
+	// Entries are found by name or address, the repository and kept entries aren't
+	// verified. Unverified ones stay in the config.
+	content := scan()
+	if !strings.Contains(content, "  Errors: https://sentry.io/\n  # verified: 2026-10-01\n  # unverified: Mailgun, 1 scan\n") {
+		t.Fatalf("Expected Mailgun unverified at the end of the section, got:\n%s", content)
+	}
+	if !strings.Contains(content, "  Mailgun: https://app.mailgun.com\n") || strings.Count(content, "# unverified") != 1 {
+		t.Errorf("Expected only Mailgun unverified and kept in the config, got:\n%s", content)
+	}
+	if strings.Contains(content, "docs:\n  Notion: https://notion.so\n  #") {
+		t.Errorf("Expected other sections left alone, got:\n%s", content)
+	}
+
+	// A second miss makes a pruning candidate, the comments are replaced
+	report.Verification.Date = day.AddDate(0, 0, 7)
+	content = scan()
+	if strings.Count(content, "# verified:") != 1 || !strings.Contains(content, "  # verified: 2026-10-08\n  # unverified: Mailgun, 2 scans\n") {
+		t.Fatalf("Expected the verification replaced, got:\n%s", content)
+	}
+	section, lastVerified, unverified := sectionVerification(splitConfigSections(content)[0])
+	if lastVerified != "2026-10-08" || unverified["Mailgun"].Scans != 2 || strings.Contains(section, "# unverified") {
+		t.Errorf("Expected the verification read back, got %s %+v", lastVerified, unverified)
+	}
+	verification := &Verification{Date: day, PruneAfter: 2}
+	if _, result := verification.verifySection("billing", splitConfigSections(content)[0], map[string]string{}, "Repository"); len(result.Unverified) != 3 || !result.Unverified[1].Prune || result.Unverified[1].Name != "Mailgun" {
+		t.Errorf("Expected Mailgun a candidate for pruning, got %+v", result.Unverified)
+	}
+
+	// Found again, the entry is verified and remembers when
+	report.Results["mailgun"] = "https://app.mailgun.com"
+	report.Verification.Date = day.AddDate(0, 0, 14)
+	content = scan()
+	if strings.Contains(content, "# unverified") || !strings.Contains(content, "# verified: 2026-10-15") {
+		t.Fatalf("Expected every entry verified, got:\n%s", content)
+	}
+	delete(report.Results, "mailgun")
+	report.Verification.Date = day.AddDate(0, 0, 21)
+	if content = scan(); !strings.Contains(content, "# unverified: Mailgun, 1 scan, last corroborated 2026-10-15\n") {
+		t.Errorf("Expected the last corroboration recorded, got:\n%s", content)
+	}
+}
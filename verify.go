@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+const (
+	signatureSuffix = ".minisig"
+	checksumsFile   = "SHA256SUMS"
+)
+
+// TrustPolicy controls signature checks for plugins and external catalogs
+type TrustPolicy struct {
+	RequireSignatures bool     `yaml:"require_signatures"`
+	PublicKeys        []string `yaml:"public_keys"` // minisign public keys (base64 line of the .pub file)
+}
+
+// errUnsigned is returned when an artifact has no signature file
+var errUnsigned = errors.New("no signature found")
+
+// verifyArtifact checks path against path.minisig using the trusted keys.
+// Unsigned artifacts are accepted unless the policy requires signatures.
+func verifyArtifact(path string, policy TrustPolicy) error {
+	err := verifyMinisign(path, path+signatureSuffix, policy.PublicKeys)
+	if errors.Is(err, errUnsigned) && !policy.RequireSignatures {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	return nil
+}
+
+// verifyCatalogDir checks the signed SHA256SUMS file of a catalog directory and
+// then every catalog file against it, so a single signature covers the whole catalog.
+func verifyCatalogDir(dir string, policy TrustPolicy) error {
+	sumsPath := filepath.Join(dir, checksumsFile)
+	if _, err := os.Stat(sumsPath); os.IsNotExist(err) {
+		if policy.RequireSignatures {
+			return fmt.Errorf("%s: catalog has no %s", dir, checksumsFile)
+		}
+		return nil
+	}
+
+	if err := verifyArtifact(sumsPath, policy); err != nil {
+		return err
+	}
+
+	sums, err := os.ReadFile(sumsPath)
+	if err != nil {
+		return err
+	}
+	listed := make(map[string]bool)
+	scanner := bufio.NewScanner(bytes.NewReader(sums))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		name := strings.TrimPrefix(fields[1], "*")
+		content, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+		if err != nil {
+			return fmt.Errorf("%s: %v", checksumsFile, err)
+		}
+		sum := sha256.Sum256(content)
+		if hex.EncodeToString(sum[:]) != fields[0] {
+			return fmt.Errorf("%s: checksum mismatch for %s", dir, name)
+		}
+		listed[filepath.ToSlash(name)] = true
+	}
+
+	// Every YAML file of the catalog must be covered by the signed checksums
+	return filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.HasSuffix(path, ".yml") {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, path)
+		if !listed[filepath.ToSlash(rel)] {
+			return fmt.Errorf("%s: %s is not listed in %s", dir, rel, checksumsFile)
+		}
+		return nil
+	})
+}
+
+// verifyMinisign verifies a minisign signature (both legacy "Ed" and prehashed "ED")
+func verifyMinisign(path, sigPath string, publicKeys []string) error {
+	sigData, err := os.ReadFile(sigPath)
+	if os.IsNotExist(err) {
+		return errUnsigned
+	}
+	if err != nil {
+		return err
+	}
+	if len(publicKeys) == 0 {
+		return errors.New("signature present but no trusted public keys configured")
+	}
+
+	lines := strings.Split(strings.ReplaceAll(string(sigData), "\r\n", "\n"), "\n")
+	if len(lines) < 4 {
+		return errors.New("malformed signature file")
+	}
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[1]))
+	if err != nil || len(sig) != 74 {
+		return errors.New("malformed signature")
+	}
+	trustedComment, ok := strings.CutPrefix(lines[2], "trusted comment: ")
+	if !ok {
+		return errors.New("malformed trusted comment")
+	}
+	globalSig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[3]))
+	if err != nil || len(globalSig) != ed25519.SignatureSize {
+		return errors.New("malformed global signature")
+	}
+
+	algorithm, keyID, signature := string(sig[:2]), sig[2:10], sig[10:]
+
+	message, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	switch algorithm {
+	case "Ed":
+	case "ED":
+		hash := blake2b.Sum512(message)
+		message = hash[:]
+	default:
+		return fmt.Errorf("unsupported signature algorithm %q", algorithm)
+	}
+
+	// A malformed key is skipped so the other trusted keys still verify
+	verifyErr := errors.New("signed with an untrusted key")
+	for _, encodedKey := range publicKeys {
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encodedKey))
+		if err != nil || len(key) != 42 || string(key[:2]) != "Ed" {
+			fmt.Fprintf(os.Stderr, "⚠️  Skipped malformed trusted public key %q\n", encodedKey)
+			continue
+		}
+		if !bytes.Equal(key[2:10], keyID) {
+			continue
+		}
+		publicKey := ed25519.PublicKey(key[10:])
+		if !ed25519.Verify(publicKey, message, signature) {
+			verifyErr = errors.New("invalid signature")
+			continue
+		}
+		if !ed25519.Verify(publicKey, append(append([]byte{}, signature...), trustedComment...), globalSig) {
+			verifyErr = errors.New("invalid trusted comment signature")
+			continue
+		}
+		return nil
+	}
+
+	return verifyErr
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// signMinisign writes path.minisig in minisign format and returns the public key line
+func signMinisign(t *testing.T, path string, prehashed bool) string {
+	t.Helper()
+
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyID := []byte("parascan")
+
+	message, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	algorithm := "Ed"
+	if prehashed {
+		algorithm = "ED"
+		hash := blake2b.Sum512(message)
+		message = hash[:]
+	}
+
+	signature := ed25519.Sign(privateKey, message)
+	trustedComment := "timestamp:1700000000"
+	globalSig := ed25519.Sign(privateKey, append(append([]byte{}, signature...), trustedComment...))
+
+	sigBlob := append(append([]byte(algorithm), keyID...), signature...)
+	content := fmt.Sprintf("untrusted comment: test\n%s\ntrusted comment: %s\n%s\n",
+		base64.StdEncoding.EncodeToString(sigBlob), trustedComment, base64.StdEncoding.EncodeToString(globalSig))
+	if err := os.WriteFile(path+signatureSuffix, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return base64.StdEncoding.EncodeToString(append(append([]byte("Ed"), keyID...), publicKey...))
+}
+
+func TestVerifyArtifact(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "parascan-detector-acme")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\necho '{}'\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// Unsigned artifacts pass only when signatures aren't required
+	if err := verifyArtifact(path, TrustPolicy{}); err != nil {
+		t.Errorf("Expected unsigned artifact to pass default policy, got %v", err)
+	}
+	if err := verifyArtifact(path, TrustPolicy{RequireSignatures: true}); err == nil {
+		t.Error("Expected unsigned artifact to fail when signatures are required")
+	}
+
+	for _, prehashed := range []bool{false, true} {
+		publicKey := signMinisign(t, path, prehashed)
+		policy := TrustPolicy{RequireSignatures: true, PublicKeys: []string{publicKey}}
+		if err := verifyArtifact(path, policy); err != nil {
+			t.Errorf("Expected valid signature (prehashed=%v) to pass, got %v", prehashed, err)
+		}
+		// A malformed key among the trusted ones doesn't stop the others
+		policy.PublicKeys = []string{"not-a-key", publicKey}
+		if err := verifyArtifact(path, policy); err != nil {
+			t.Errorf("Expected the valid key after a malformed one to pass (prehashed=%v), got %v", prehashed, err)
+		}
+
+		os.WriteFile(path+".other", []byte("other"), 0644)
+		otherKey := signMinisign(t, path+".other", prehashed)
+		if err := verifyArtifact(path, TrustPolicy{PublicKeys: []string{otherKey}}); err == nil {
+			t.Errorf("Expected signature by untrusted key (prehashed=%v) to fail", prehashed)
+		}
+	}
+
+	// Tampering with the artifact invalidates the signature
+	publicKey := signMinisign(t, path, true)
+	if err := os.WriteFile(path, []byte("#!/bin/sh\nrm -rf /\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := verifyArtifact(path, TrustPolicy{PublicKeys: []string{publicKey}}); err == nil {
+		t.Error("Expected tampered artifact to fail verification")
+	}
+}
+
+func TestVerifyCatalogDir(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"stack-dependency-files.yml": "languages: {}\n",
+		"file-detectors.yml":         "technologies: {}\n",
+		"services/acme.yml":          "name: Acme\nurl: https://acme.example.com\n",
+	}
+	var sums string
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		sum := sha256.Sum256([]byte(content))
+		sums += hex.EncodeToString(sum[:]) + "  " + name + "\n"
+	}
+	if err := os.WriteFile(filepath.Join(dir, checksumsFile), []byte(sums), 0644); err != nil {
+		t.Fatal(err)
+	}
+	policy := TrustPolicy{RequireSignatures: true, PublicKeys: []string{signMinisign(t, filepath.Join(dir, checksumsFile), true)}}
+
+	catalog, err := loadCatalogDir(dir, policy, false)
+	if err != nil {
+		t.Fatalf("Expected signed catalog to load, got %v", err)
+	}
+	if catalog.Services["acme"] == nil || catalog.Services["acme"].Name != "Acme" {
+		t.Errorf("Expected acme service from catalog, got %v", catalog.Services)
+	}
+
+	// Files not covered by the signed checksums are rejected
+	os.WriteFile(filepath.Join(dir, "services", "evil.yml"), []byte("name: Evil\n"), 0644)
+	if _, err := loadCatalogDir(dir, policy, false); err == nil {
+		t.Error("Expected catalog with unlisted file to fail verification")
+	}
+	if _, err := loadCatalogDir(dir, policy, true); err != nil {
+		t.Errorf("Expected --insecure-skip-verify to load catalog, got %v", err)
+	}
+}